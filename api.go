@@ -1,24 +1,79 @@
 package jmespath
 
-import "strconv"
+import (
+	"strconv"
+	"time"
+)
 
 // JMESPath is the representation of a compiled JMES path query. A JMESPath is
-// safe for concurrent use by multiple goroutines.
+// immutable once compiled and is safe for concurrent use by multiple
+// goroutines without any additional locking.
 type JMESPath struct {
-	ast  ASTNode
-	intr *treeInterpreter
+	// parsed is the AST of the expression as parsed, and ast the AST it is
+	// evaluated with, which is optimized for the runtime.
+	parsed ASTNode
+	ast    ASTNode
+	eval   evalFunc
+	pred   *lazyPredicate
+	intr   *treeInterpreter
+	// expression is the source of the expression, empty if it was
+	// compiled from an AST.
+	expression string
+}
+
+func newJMESPath(ast ASTNode, intr *treeInterpreter) *JMESPath {
+	if intr.interpreted() {
+		return newInterpretedJMESPath(ast, intr)
+	}
+	optimized := optimize(ast, intr)
+	return &JMESPath{
+		parsed: ast,
+		ast:    optimized,
+		eval:   compileNode(optimized),
+		pred:   &lazyPredicate{ast: optimized},
+		intr:   intr,
+	}
+}
+
+// newInterpretedJMESPath returns a JMESPath that evaluates ast as written,
+// without optimizing or compiling it, for the modes the compiled closures
+// do not implement.
+func newInterpretedJMESPath(ast ASTNode, intr *treeInterpreter) *JMESPath {
+	eval := func(intr *treeInterpreter, value interface{}) (interface{}, error) {
+		return intr.Execute(ast, value)
+	}
+	if intr.tracer != nil {
+		eval = func(intr *treeInterpreter, value interface{}) (interface{}, error) {
+			root := ast
+			return newTraceWalker(intr).eval(&root, value)
+		}
+	}
+	return &JMESPath{
+		parsed: ast,
+		ast:    ast,
+		eval:   eval,
+		pred: &lazyPredicate{pred: func(intr *treeInterpreter, value interface{}) (bool, error) {
+			result, err := eval(intr, value)
+			return err == nil && !isFalse(result), err
+		}},
+		intr: intr,
+	}
 }
 
 // Compile parses a JMESPath expression and returns, if successful, a JMESPath
-// object that can be used to match against data.
-func Compile(expression string) (*JMESPath, error) {
+// object that can be used to match against data.  The parsed expression is
+// optimized and compiled once so that repeated searches are as cheap as
+// possible.
+func Compile(expression string) (_ *JMESPath, err error) {
+	defer recoverInternal(&err)
 	parser := NewParser()
 	ast, err := parser.Parse(expression)
 	if err != nil {
 		return nil, err
 	}
-	jmespath := &JMESPath{ast: ast, intr: newInterpreter()}
-	return jmespath, nil
+	jp := newJMESPath(ast, newInterpreter())
+	jp.expression = expression
+	return jp, nil
 }
 
 // MustCompile is like Compile but panics if the expression cannot be parsed.
@@ -33,17 +88,24 @@ func MustCompile(expression string) *JMESPath {
 }
 
 // Search evaluates a JMESPath expression against input data and returns the result.
-func (jp *JMESPath) Search(data interface{}) (interface{}, error) {
-	return jp.intr.Execute(jp.ast, data)
+func (jp *JMESPath) Search(data interface{}) (result interface{}, err error) {
+	if jp.intr.metrics != nil {
+		defer jp.observe(time.Now(), &err)
+	}
+	defer recoverInternal(&err)
+	result, err = jp.eval(jp.intr, data)
+	return jp.intr.render(result), locateError(jp.expression, err)
 }
 
 // Search evaluates a JMESPath expression against input data and returns the result.
-func Search(expression string, data interface{}) (interface{}, error) {
+func Search(expression string, data interface{}) (result interface{}, err error) {
+	defer recoverInternal(&err)
 	intr := newInterpreter()
 	parser := NewParser()
 	ast, err := parser.Parse(expression)
 	if err != nil {
 		return nil, err
 	}
-	return intr.Execute(ast, data)
+	result, err = intr.Execute(ast, data)
+	return result, locateError(expression, err)
 }
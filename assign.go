@@ -0,0 +1,313 @@
+package jmespath
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+/* Assignments convert the expression into a list of steps, each of which
+   selects one or more children of the current value, and then rebuild the
+   document from the bottom up along the selected children.  Like patches,
+   they copy only the objects and arrays on the way to a changed location
+   and never modify the document they are applied to.
+*/
+
+// Set returns a copy of data in which the locations the expression refers
+// to are set to value.  The expression must be an assignable expression:
+// a chain of fields and indexes, such as "a.b[2].c", that may also
+// contain projections such as "[*]", "*", filters and slices to set every
+// location they select, as in "people[?age > `30`].senior".  Objects
+// missing on the way to a field are created, but indexes must be within
+// their array.  Set never modifies data.
+func Set(data interface{}, expression string, value interface{}) (interface{}, error) {
+	jp, err := Compile(expression)
+	if err != nil {
+		return nil, err
+	}
+	return jp.Set(data, value)
+}
+
+// Delete returns a copy of data without the locations the expression
+// refers to, which must be an assignable expression as for Set.  Elements
+// deleted from an array are removed from it, and locations that do not
+// exist are ignored.  Delete never modifies data.
+func Delete(data interface{}, expression string) (interface{}, error) {
+	jp, err := Compile(expression)
+	if err != nil {
+		return nil, err
+	}
+	return jp.Delete(data)
+}
+
+// Set returns a copy of data in which the locations the expression refers
+// to are set to value, see Set.
+func (jp *JMESPath) Set(data interface{}, value interface{}) (_ interface{}, err error) {
+	defer recoverInternal(&err)
+	steps, err := assignmentSteps(jp.ast)
+	if err != nil {
+		return nil, err
+	}
+	a := assignment{intr: jp.intr, value: value}
+	return a.apply(data, steps)
+}
+
+// Delete returns a copy of data without the locations the expression
+// refers to, see Delete.
+func (jp *JMESPath) Delete(data interface{}) (_ interface{}, err error) {
+	defer recoverInternal(&err)
+	steps, err := assignmentSteps(jp.ast)
+	if err != nil {
+		return nil, err
+	}
+	if len(steps) == 0 {
+		return nil, errors.New("cannot delete the whole document")
+	}
+	a := assignment{intr: jp.intr, delete: true}
+	return a.apply(data, steps)
+}
+
+// assignmentStep selects the children of a value: the field or index
+// node, or the elements selected by a projection, a filter or a slice
+// node.
+type assignmentStep struct {
+	node ASTNode
+}
+
+// assignmentSteps converts node into the steps that select the locations
+// it refers to.
+func assignmentSteps(node ASTNode) ([]assignmentStep, error) {
+	switch node.nodeType {
+	case ASTCurrentNode, ASTIdentity:
+		return nil, nil
+	case ASTField, ASTIndex, ASTSlice:
+		return []assignmentStep{{node}}, nil
+	case ASTSubexpression, ASTIndexExpression:
+		var steps []assignmentStep
+		for _, child := range node.children {
+			childSteps, err := assignmentSteps(child)
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, childSteps...)
+		}
+		return steps, nil
+	case ASTProjection, ASTFilterProjection, ASTValueProjection:
+		left, err := assignmentSteps(node.children[0])
+		if err != nil {
+			return nil, err
+		}
+		right, err := assignmentSteps(node.children[1])
+		if err != nil {
+			return nil, err
+		}
+		// A slice already selects the elements the projection
+		// projects.
+		if node.nodeType != ASTProjection || len(left) == 0 || left[len(left)-1].node.nodeType != ASTSlice {
+			left = append(left, assignmentStep{node})
+		}
+		return append(left, right...), nil
+	}
+	return nil, fmt.Errorf("cannot assign to %s", FormatAST(node))
+}
+
+type assignment struct {
+	intr *treeInterpreter
+	// value is the value to set, unless delete is set.
+	value  interface{}
+	delete bool
+}
+
+// apply returns a copy of value with the assignment applied to the
+// locations steps select.
+func (a *assignment) apply(value interface{}, steps []assignmentStep) (interface{}, error) {
+	if len(steps) == 0 {
+		return a.value, nil
+	}
+	node, rest := steps[0].node, steps[1:]
+	switch node.nodeType {
+	case ASTField:
+		key, err := a.intr.fieldKey(node.value.(string), value)
+		if err != nil {
+			return nil, err
+		}
+		if value == nil && !a.delete {
+			if a.intr.ordered {
+				value = NewOrderedMap()
+			} else {
+				value = map[string]interface{}{}
+			}
+		}
+		if !isObject(value) {
+			if a.delete {
+				return value, nil
+			}
+			return nil, fmt.Errorf("cannot set field '%s' of %s", key, jsonType(value))
+		}
+		child, exists := childValue(value, key)
+		if a.delete && !exists {
+			return value, nil
+		}
+		if a.delete && len(rest) == 0 {
+			return withoutChild(value, key)
+		}
+		updated, err := a.apply(child, rest)
+		if err != nil {
+			return nil, err
+		}
+		return withChild(value, key, updated, true)
+	case ASTIndex:
+		array, ok := value.([]interface{})
+		index := node.value.(int)
+		if ok && index < 0 {
+			index += len(array)
+		}
+		if !ok || index < 0 || index >= len(array) {
+			if a.delete {
+				return value, nil
+			}
+			if !ok {
+				return nil, fmt.Errorf("cannot set index %d of %s", node.value.(int), jsonType(value))
+			}
+			return nil, fmt.Errorf("index %d out of range for array of length %d", node.value.(int), len(array))
+		}
+		return a.applyToElements(value, []string{strconv.Itoa(index)}, rest)
+	}
+	keys, err := a.selected(node, value)
+	if err != nil {
+		return nil, err
+	}
+	return a.applyToElements(value, keys, rest)
+}
+
+// applyToElements applies the remaining steps to the elements keys of the
+// array or object value.
+func (a *assignment) applyToElements(value interface{}, keys []string, rest []assignmentStep) (interface{}, error) {
+	if a.delete && len(rest) == 0 {
+		return withoutChildren(value, keys)
+	}
+	updated := make([]interface{}, len(keys))
+	for i, key := range keys {
+		child, _ := childValue(value, key)
+		var err error
+		if updated[i], err = a.apply(child, rest); err != nil {
+			return nil, err
+		}
+	}
+	return withChildren(value, keys, updated), nil
+}
+
+// selected returns the keys of the elements of value that the projection,
+// filter or slice node selects.
+func (a *assignment) selected(node ASTNode, value interface{}) ([]string, error) {
+	var keys []string
+	switch node.nodeType {
+	case ASTValueProjection:
+		object, ok := a.intr.toObject(value)
+		if !ok || !isObject(value) {
+			return nil, nil
+		}
+		return objectKeys(value, object), nil
+	case ASTSlice:
+		if _, ok := value.(string); ok && !a.delete {
+			return nil, errors.New("cannot assign to a string slice")
+		}
+		array, ok := value.([]interface{})
+		if !ok {
+			return nil, nil
+		}
+		bounds, err := a.intr.sliceBounds(node, value, len(array))
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < bounds.Len(); i++ {
+			keys = append(keys, strconv.Itoa(bounds.Index(i)))
+		}
+		return keys, nil
+	}
+	array, ok := value.([]interface{})
+	if !ok {
+		return nil, nil
+	}
+	for i, element := range array {
+		if node.nodeType == ASTFilterProjection {
+			matched, err := a.intr.Execute(node.children[2], element)
+			if err != nil {
+				return nil, err
+			}
+			if isFalse(matched) {
+				continue
+			}
+		}
+		keys = append(keys, strconv.Itoa(i))
+	}
+	return keys, nil
+}
+
+// isObject reports whether value is an object that assignments can copy.
+func isObject(value interface{}) bool {
+	switch value.(type) {
+	case map[string]interface{}, *OrderedMap:
+		return true
+	}
+	return false
+}
+
+// withChildren returns a copy of the object or array container with the
+// existing elements keys set to the corresponding values.
+func withChildren(container interface{}, keys []string, values []interface{}) interface{} {
+	switch c := container.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(c))
+		for key, element := range c {
+			copied[key] = element
+		}
+		for i, key := range keys {
+			copied[key] = values[i]
+		}
+		return copied
+	case *OrderedMap:
+		copied := NewOrderedMap()
+		for _, key := range c.keys {
+			copied.Set(key, c.values[key])
+		}
+		for i, key := range keys {
+			copied.Set(key, values[i])
+		}
+		return copied
+	case []interface{}:
+		copied := append([]interface{}(nil), c...)
+		for i, key := range keys {
+			index, _ := strconv.Atoi(key)
+			copied[index] = values[i]
+		}
+		return copied
+	}
+	return container
+}
+
+// withoutChildren returns a copy of the object or array container without
+// the elements keys.
+func withoutChildren(container interface{}, keys []string) (interface{}, error) {
+	array, ok := container.([]interface{})
+	if !ok {
+		var err error
+		for _, key := range keys {
+			if container, err = withoutChild(container, key); err != nil {
+				return nil, err
+			}
+		}
+		return container, nil
+	}
+	deleted := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		deleted[key] = true
+	}
+	kept := make([]interface{}, 0, len(array))
+	for i, element := range array {
+		if !deleted[strconv.Itoa(i)] {
+			kept = append(kept, element)
+		}
+	}
+	return kept, nil
+}
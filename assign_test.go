@@ -0,0 +1,135 @@
+package jmespath
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+var assignmentDocument = `{
+	"a": {"b": [0, 1, {"c": 1}]},
+	"people": [{"name": "x", "age": 20}, {"name": "y", "age": 40}, {"name": "z", "age": 50}],
+	"m": {"k1": {"v": 1}, "k2": {"v": 2}}
+}`
+
+func TestSet(t *testing.T) {
+	assert := assert.New(t)
+	cases := []struct {
+		expression string
+		value      interface{}
+		expected   string
+	}{
+		{"a.b[2].c", 2.0, `{"b": [0, 1, {"c": 2}]}`},
+		{"a.b[-1].d", "new", `{"b": [0, 1, {"c": 1, "d": "new"}]}`},
+		{"a.x.y", true, `{"b": [0, 1, {"c": 1}], "x": {"y": true}}`},
+		{"a.b[0]", nil, `{"b": [null, 1, {"c": 1}]}`},
+		{"a.b[0:2]", "s", `{"b": ["s", "s", {"c": 1}]}`},
+		{"@.a.b[1]", 5.0, `{"b": [0, 5, {"c": 1}]}`},
+	}
+	for _, tt := range cases {
+		data := decodeJSON(assignmentDocument)
+		result, err := Set(data, tt.expression, tt.value)
+		if assert.Nil(err, tt.expression) {
+			assert.Equal(decodeJSON(tt.expected), result.(map[string]interface{})["a"], tt.expression)
+		}
+		assert.Equal(decodeJSON(assignmentDocument), data, "the document must not be modified")
+	}
+}
+
+func TestSetProjections(t *testing.T) {
+	assert := assert.New(t)
+	data := decodeJSON(assignmentDocument)
+	result, err := Set(data, "people[?age > `30`].senior", true)
+	assert.Nil(err)
+	senior, _ := Search("people[?senior].name", result)
+	assert.Equal([]interface{}{"y", "z"}, senior)
+
+	result, err = Set(data, "people[*].age", 0.0)
+	assert.Nil(err)
+	ages, _ := Search("people[*].age", result)
+	assert.Equal([]interface{}{0.0, 0.0, 0.0}, ages)
+
+	result, err = Set(data, "m.*.v", "all")
+	assert.Nil(err)
+	assert.Equal(decodeJSON(`{"k1": {"v": "all"}, "k2": {"v": "all"}}`), result.(map[string]interface{})["m"])
+
+	result, err = Set(nil, "a.b", 1.0)
+	assert.Nil(err)
+	assert.Equal(decodeJSON(`{"a": {"b": 1}}`), result)
+
+	result, err = Set(data, "@", "replaced")
+	assert.Nil(err)
+	assert.Equal("replaced", result)
+	assert.Equal(decodeJSON(assignmentDocument), data)
+}
+
+func TestDelete(t *testing.T) {
+	assert := assert.New(t)
+	cases := []struct {
+		expression string
+		expected   string
+	}{
+		{"a.b[2].c", `{"a": {"b": [0, 1, {}]}}`},
+		{"a.b[0]", `{"a": {"b": [1, {"c": 1}]}}`},
+		{"a.b[0:2]", `{"a": {"b": [{"c": 1}]}}`},
+		{"a.b", `{"a": {}}`},
+		{"a.missing.x", `{"a": {"b": [0, 1, {"c": 1}]}}`},
+		{"a.b[10]", `{"a": {"b": [0, 1, {"c": 1}]}}`},
+	}
+	for _, tt := range cases {
+		data := decodeJSON(`{"a": {"b": [0, 1, {"c": 1}]}}`)
+		result, err := Delete(data, tt.expression)
+		if assert.Nil(err, tt.expression) {
+			assert.Equal(decodeJSON(tt.expected), result, tt.expression)
+		}
+		assert.Equal(decodeJSON(`{"a": {"b": [0, 1, {"c": 1}]}}`), data)
+	}
+
+	result, err := Delete(decodeJSON(assignmentDocument), "people[?age > `30`]")
+	assert.Nil(err)
+	names, _ := Search("people[*].name", result)
+	assert.Equal([]interface{}{"x"}, names)
+
+	result, err = Delete(decodeJSON(assignmentDocument), "m.*.v")
+	assert.Nil(err)
+	assert.Equal(decodeJSON(`{"k1": {}, "k2": {}}`), result.(map[string]interface{})["m"])
+}
+
+func TestAssignmentErrors(t *testing.T) {
+	assert := assert.New(t)
+	data := decodeJSON(assignmentDocument)
+	for _, expression := range []string{"length(a)", "a || b", "a[]", "[a, b]", "{a: a}", "a | b", "`1`"} {
+		_, err := Set(data, expression, 1)
+		assert.NotNil(err, expression)
+		_, err = Delete(data, expression)
+		assert.NotNil(err, expression)
+	}
+	_, err := Set(data, "a.b[5]", 1)
+	assert.Equal("index 5 out of range for array of length 3", err.Error())
+	_, err = Set(data, "a.b.c", 1)
+	assert.Equal("cannot set field 'c' of array", err.Error())
+	_, err = Set(data, "a[0]", 1)
+	assert.Equal("cannot set index 0 of object", err.Error())
+	_, err = Delete(data, "@")
+	assert.NotNil(err)
+	_, err = Set(map[string]interface{}{"s": "abc"}, "s[0:2]", "x")
+	assert.EqualError(err, "cannot assign to a string slice")
+	_, err = Set(map[string]interface{}{"s": "abc"}, "s[::-1].x", "x")
+	assert.EqualError(err, "cannot assign to a string slice")
+	_, err = Set(data, "a.", 1)
+	assert.IsType(SyntaxError{}, err)
+}
+
+func TestSetOrdered(t *testing.T) {
+	assert := assert.New(t)
+	rt := NewRuntime()
+	rt.SetOrderedObjects(true)
+	jp, err := rt.Compile("b.new.x")
+	assert.Nil(err)
+	data, _ := UnmarshalOrdered([]byte(`{"b": {"z": 1, "a": 2}, "a": 3}`))
+	result, err := jp.Set(data, 1)
+	assert.Nil(err)
+	encoded, _ := json.Marshal(result)
+	assert.Equal(`{"b":{"z":1,"a":2,"new":{"x":1}},"a":3}`, string(encoded))
+}
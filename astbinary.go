@@ -0,0 +1,338 @@
+package jmespath
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+/* ASTs are serialized in binary as a version byte followed by the root
+   node.  A node is its type, as an index in binaryNodeTypes, then its
+   value, then the number of its children and the children themselves.
+   Counts, lengths and type indexes are uvarints.  Values depend on the
+   node type: strings are a length and their bytes, indexes are varints,
+   comparators are their symbol as a string, flatten depths and the parts
+   of slices are a byte telling whether they are set followed by a varint,
+   and literals are their JSON encoding as a string.  Compiled expressions
+   are serialized as a version byte, the source of the expression as a
+   string, and the root node.
+*/
+
+// astBinaryVersion is the version of the binary encoding, the first byte
+// of every encoded AST and expression.
+const astBinaryVersion = 1
+
+// binaryNodeTypes are the node types in the order of their codes in the
+// binary encoding.  New node types must be appended, so that encoded ASTs
+// remain readable when node types are added.
+var binaryNodeTypes = []astNodeType{
+	ASTComparator, ASTCurrentNode, ASTExpRef, ASTFunctionExpression,
+	ASTField, ASTFilterProjection, ASTFlatten, ASTIdentity, ASTIndex,
+	ASTIndexExpression, ASTKeyValPair, ASTLiteral, ASTMultiSelectHash,
+	ASTMultiSelectList, ASTOrExpression, ASTAndExpression, ASTNotExpression,
+	ASTPipe, ASTProjection, ASTSubexpression, ASTSlice, ASTValueProjection,
+	ASTParameter, ASTDescendant,
+}
+
+var binaryNodeCodes = func() map[astNodeType]uint64 {
+	codes := make(map[astNodeType]uint64, len(binaryNodeTypes))
+	for code, nodeType := range binaryNodeTypes {
+		codes[nodeType] = uint64(code)
+	}
+	return codes
+}()
+
+// MarshalBinary encodes the AST in a compact binary form, which can be
+// decoded with UnmarshalBinary.  ASTs are also encoded this way by
+// encoding/gob.
+func (node ASTNode) MarshalBinary() ([]byte, error) {
+	return appendBinaryNode([]byte{astBinaryVersion}, node)
+}
+
+// UnmarshalBinary decodes an AST encoded by MarshalBinary.  As for
+// UnmarshalJSON, it is an error for the decoded AST to be malformed.
+func (node *ASTNode) UnmarshalBinary(data []byte) error {
+	d, err := newBinaryDecoder(data)
+	if err != nil {
+		return err
+	}
+	decoded, err := d.readNode(0)
+	if err != nil {
+		return err
+	}
+	if err := d.end(); err != nil {
+		return err
+	}
+	*node = decoded
+	return nil
+}
+
+// MarshalBinary encodes the expression with its parsed AST in a compact
+// binary form, so that it can be loaded with CompileBinary without being
+// parsed again.  Expressions are also encoded this way by encoding/gob.
+func (jp *JMESPath) MarshalBinary() ([]byte, error) {
+	data := appendBinaryString([]byte{astBinaryVersion}, jp.expression)
+	return appendBinaryNode(data, jp.parsed)
+}
+
+// UnmarshalBinary loads an expression encoded by MarshalBinary into jp,
+// which is evaluated with the built-in functions as if it was compiled
+// with CompileBinary.
+func (jp *JMESPath) UnmarshalBinary(data []byte) error {
+	compiled, err := CompileBinary(data)
+	if err != nil {
+		return err
+	}
+	*jp = *compiled
+	return nil
+}
+
+// CompileBinary returns a JMESPath object for an expression encoded by
+// JMESPath.MarshalBinary, as Compile does for its source.  The AST is
+// checked to be well formed, as by CompileAST, but not parsed again.
+func CompileBinary(data []byte) (*JMESPath, error) {
+	return compileBinary(data, newInterpreter())
+}
+
+// CompileBinary is like the package level CompileBinary, but evaluates the
+// expression with the functions available in this runtime.
+func (rt *Runtime) CompileBinary(data []byte) (*JMESPath, error) {
+	return compileBinary(data, rt.newInterpreter())
+}
+
+func compileBinary(data []byte, intr *treeInterpreter) (_ *JMESPath, err error) {
+	defer recoverInternal(&err)
+	d, err := newBinaryDecoder(data)
+	if err != nil {
+		return nil, err
+	}
+	expression, err := d.readString()
+	if err != nil {
+		return nil, err
+	}
+	ast, err := d.readNode(0)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.end(); err != nil {
+		return nil, err
+	}
+	jp := newJMESPath(ast, intr)
+	jp.expression = expression
+	return jp, nil
+}
+
+func appendBinaryNode(data []byte, node ASTNode) ([]byte, error) {
+	code, ok := binaryNodeCodes[node.nodeType]
+	if !ok {
+		return nil, fmt.Errorf("cannot encode %s node", node.nodeType)
+	}
+	if err := validateNode(node); err != nil {
+		return nil, err
+	}
+	data = appendUvarint(data, code)
+	switch node.nodeType {
+	case ASTField, ASTFunctionExpression, ASTKeyValPair, ASTParameter, ASTDescendant:
+		data = appendBinaryString(data, node.value.(string))
+	case ASTIndex:
+		data = appendVarint(data, int64(node.value.(int)))
+	case ASTFlatten:
+		if depth, ok := node.value.(int); ok {
+			data = appendOptionalInt(data, &depth)
+		} else {
+			data = appendOptionalInt(data, nil)
+		}
+	case ASTComparator:
+		data = appendBinaryString(data, comparatorSymbol(node.value.(tokType)))
+	case ASTSlice:
+		for _, part := range node.value.([]*int) {
+			data = appendOptionalInt(data, part)
+		}
+	case ASTLiteral:
+		literal, err := json.Marshal(node.value)
+		if err != nil {
+			return nil, err
+		}
+		data = appendBinaryString(data, string(literal))
+	}
+	data = appendUvarint(data, uint64(len(node.children)))
+	for _, child := range node.children {
+		var err error
+		if data, err = appendBinaryNode(data, child); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+func appendUvarint(data []byte, n uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(data, buf[:binary.PutUvarint(buf[:], n)]...)
+}
+
+func appendVarint(data []byte, n int64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(data, buf[:binary.PutVarint(buf[:], n)]...)
+}
+
+func appendBinaryString(data []byte, s string) []byte {
+	return append(appendUvarint(data, uint64(len(s))), s...)
+}
+
+func appendOptionalInt(data []byte, n *int) []byte {
+	if n == nil {
+		return append(data, 0)
+	}
+	return appendVarint(append(data, 1), int64(*n))
+}
+
+var errTruncatedBinary = errors.New("truncated binary AST")
+
+// binaryDecoder reads an encoded AST from the start of data.
+type binaryDecoder struct {
+	data []byte
+}
+
+func newBinaryDecoder(data []byte) (*binaryDecoder, error) {
+	if len(data) == 0 {
+		return nil, errTruncatedBinary
+	}
+	if data[0] != astBinaryVersion {
+		return nil, fmt.Errorf("unsupported binary AST version %d", data[0])
+	}
+	return &binaryDecoder{data: data[1:]}, nil
+}
+
+func (d *binaryDecoder) end() error {
+	if len(d.data) > 0 {
+		return errors.New("unexpected data after binary AST")
+	}
+	return nil
+}
+
+func (d *binaryDecoder) readUvarint() (uint64, error) {
+	n, size := binary.Uvarint(d.data)
+	if size <= 0 {
+		return 0, errTruncatedBinary
+	}
+	d.data = d.data[size:]
+	return n, nil
+}
+
+func (d *binaryDecoder) readInt() (int, error) {
+	n, size := binary.Varint(d.data)
+	if size <= 0 || int64(int(n)) != n {
+		return 0, errTruncatedBinary
+	}
+	d.data = d.data[size:]
+	return int(n), nil
+}
+
+func (d *binaryDecoder) readByte() (byte, error) {
+	if len(d.data) == 0 {
+		return 0, errTruncatedBinary
+	}
+	b := d.data[0]
+	d.data = d.data[1:]
+	return b, nil
+}
+
+func (d *binaryDecoder) readString() (string, error) {
+	n, err := d.readUvarint()
+	if err != nil {
+		return "", err
+	}
+	if n > uint64(len(d.data)) {
+		return "", errTruncatedBinary
+	}
+	s := string(d.data[:n])
+	d.data = d.data[n:]
+	return s, nil
+}
+
+// readOptionalInt reads a flatten depth or a part of a slice.
+func (d *binaryDecoder) readOptionalInt() (*int, error) {
+	set, err := d.readByte()
+	if err != nil || set == 0 {
+		return nil, err
+	}
+	n, err := d.readInt()
+	return &n, err
+}
+
+// readNode reads a node at the given depth of the AST.
+func (d *binaryDecoder) readNode(depth int) (ASTNode, error) {
+	if depth > maxNesting {
+		return ASTNode{}, errors.New("binary AST is nested too deeply")
+	}
+	code, err := d.readUvarint()
+	if err != nil {
+		return ASTNode{}, err
+	}
+	if code >= uint64(len(binaryNodeTypes)) {
+		return ASTNode{}, fmt.Errorf("invalid binary AST node type %d", code)
+	}
+	node := ASTNode{nodeType: binaryNodeTypes[code]}
+	if node.value, err = d.readValue(node.nodeType); err != nil {
+		return ASTNode{}, err
+	}
+	count, err := d.readUvarint()
+	if err != nil {
+		return ASTNode{}, err
+	}
+	// Every child takes at least two bytes.
+	if count > uint64(len(d.data))/2 {
+		return ASTNode{}, errTruncatedBinary
+	}
+	for i := uint64(0); i < count; i++ {
+		child, err := d.readNode(depth + 1)
+		if err != nil {
+			return ASTNode{}, err
+		}
+		node.children = append(node.children, child)
+	}
+	return node, validateNode(node)
+}
+
+func (d *binaryDecoder) readValue(nodeType astNodeType) (interface{}, error) {
+	switch nodeType {
+	case ASTField, ASTFunctionExpression, ASTKeyValPair, ASTParameter, ASTDescendant:
+		return d.readString()
+	case ASTIndex:
+		return d.readInt()
+	case ASTFlatten:
+		depth, err := d.readOptionalInt()
+		if depth == nil || err != nil {
+			return nil, err
+		}
+		return *depth, nil
+	case ASTComparator:
+		symbol, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		op, ok := comparatorsBySymbol[symbol]
+		if !ok {
+			return nil, fmt.Errorf("unknown comparator %q", symbol)
+		}
+		return op, nil
+	case ASTSlice:
+		parts := make([]*int, 3)
+		for i := range parts {
+			part, err := d.readOptionalInt()
+			if err != nil {
+				return nil, err
+			}
+			parts[i] = part
+		}
+		return parts, nil
+	case ASTLiteral:
+		literal, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		return decodeNodeValue(ASTLiteral, json.RawMessage(literal))
+	}
+	return nil, nil
+}
@@ -0,0 +1,175 @@
+package jmespath
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+// TestASTBinaryRoundTrip checks that compiled expressions loaded from their
+// binary encoding have the same AST as the originals and give the same
+// results, for the compliance test suite.
+func TestASTBinaryRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+	forEachComplianceCase(assert, func(given interface{}, testcase TestCase, msg string) {
+		jp, err := Compile(testcase.Expression)
+		if !assert.Nil(err, msg) {
+			return
+		}
+		encoded, err := jp.MarshalBinary()
+		if !assert.Nil(err, msg) {
+			return
+		}
+		loaded, err := CompileBinary(encoded)
+		if !assert.Nil(err, msg) {
+			return
+		}
+		assert.Equal(jp.AST(), loaded.AST(), msg)
+		actual, err := loaded.Search(given)
+		if assert.Nil(err, msg) {
+			assert.Equal(testcase.Result, actual, msg)
+		}
+	})
+}
+
+func TestASTMarshalBinary(t *testing.T) {
+	assert := assert.New(t)
+	rt := NewRuntime()
+	rt.SetRecursiveDescent(true)
+	for _, expression := range []string{
+		"foo[?a == `1`].bar[0]",
+		"a[1:-1:2].b[::-1] | [0]",
+		"a[][][]",
+		"{x: 'lit', y: `{\"k\": [1, null]}`, z: :param}",
+		"..name[?status not in `[\"gone\"]`]",
+		"sort_by(@, &a)",
+		"!a || b && c",
+	} {
+		parser := NewParser()
+		parser.recursiveDescent = true
+		ast, err := parser.Parse(expression)
+		if !assert.Nil(err, expression) {
+			continue
+		}
+		encoded, err := ast.MarshalBinary()
+		assert.Nil(err, expression)
+		var decoded ASTNode
+		assert.Nil(decoded.UnmarshalBinary(encoded), expression)
+		assert.Equal(ast, decoded, expression)
+		jsonEncoded, err := json.Marshal(ast)
+		assert.Nil(err)
+		assert.True(len(encoded) < len(jsonEncoded), expression)
+	}
+	_, err := ASTNode{}.MarshalBinary()
+	assert.NotNil(err)
+	_, err = ASTNode{nodeType: ASTField, value: 1}.MarshalBinary()
+	assert.NotNil(err)
+}
+
+func TestCompileBinary(t *testing.T) {
+	assert := assert.New(t)
+	jp := MustCompile("people[?age > `30`].name | upper(@)")
+	encoded, err := jp.MarshalBinary()
+	assert.Nil(err)
+
+	rt := NewRuntime()
+	assert.Nil(rt.RegisterFunction("upper", func(ctx CallContext, args []interface{}) (interface{}, error) {
+		return fmt.Sprint(args[0]), nil
+	}))
+	loaded, err := rt.CompileBinary(encoded)
+	assert.Nil(err)
+	result, err := loaded.Search(decodeJSON(`{"people": [{"name": "a", "age": 40}]}`))
+	assert.Nil(err)
+	assert.Equal("[a]", result)
+
+	// Errors are located in the source of the expression.
+	loaded, err = CompileBinary(encoded)
+	assert.Nil(err)
+	_, err = loaded.Search(decodeJSON(`{"people": []}`))
+	var evalErr *EvalError
+	if assert.True(errors.As(err, &evalErr)) {
+		assert.Equal("people[?age > `30`].name | upper(@)", evalErr.Expression)
+		assert.Equal(27, evalErr.Offset)
+	}
+}
+
+func TestCompileBinaryOptimizesForRuntime(t *testing.T) {
+	assert := assert.New(t)
+	jp := MustCompile("'a' < 'b'")
+	assert.Equal(ASTComparator, jp.AST().nodeType)
+	encoded, err := jp.MarshalBinary()
+	assert.Nil(err)
+	rt := NewRuntime()
+	rt.SetLegacyComparisons(true)
+	loaded, err := rt.CompileBinary(encoded)
+	assert.Nil(err)
+	result, err := loaded.Search(nil)
+	assert.Nil(err)
+	assert.Nil(result)
+}
+
+func TestCompiledExpressionGob(t *testing.T) {
+	assert := assert.New(t)
+	queries := map[string]*JMESPath{
+		"names": MustCompile("people[*].name"),
+		"count": MustCompile("length(people)"),
+	}
+	var b bytes.Buffer
+	assert.Nil(gob.NewEncoder(&b).Encode(queries))
+	var decoded map[string]*JMESPath
+	assert.Nil(gob.NewDecoder(&b).Decode(&decoded))
+	data := decodeJSON(`{"people": [{"name": "a"}, {"name": "b"}]}`)
+	result, err := decoded["names"].Search(data)
+	assert.Nil(err)
+	assert.Equal([]interface{}{"a", "b"}, result)
+	result, err = decoded["count"].Search(data)
+	assert.Nil(err)
+	assert.Equal(2.0, result)
+}
+
+func TestCompileBinaryRejectsMalformedData(t *testing.T) {
+	assert := assert.New(t)
+	encoded, err := MustCompile("foo[?a == `1`].bar[0]").MarshalBinary()
+	assert.Nil(err)
+	for i := 0; i < len(encoded); i++ {
+		_, err := CompileBinary(encoded[:i])
+		assert.NotNil(err, i)
+	}
+	_, err = CompileBinary(append(encoded, 0))
+	assert.NotNil(err)
+	corrupted := append([]byte{}, encoded...)
+	corrupted[0] = 2
+	_, err = CompileBinary(corrupted)
+	assert.NotNil(err)
+	for _, data := range [][]byte{
+		{astBinaryVersion, 0, 200, 0},                     // unknown node type
+		{astBinaryVersion, 0, 4, 5, 'a'},                  // truncated field name
+		{astBinaryVersion, 0, 0, 2, '=', '~', 0},          // unknown comparator
+		{astBinaryVersion, 0, 13, 255, 255, 255, 255, 15}, // too many children
+	} {
+		_, err := CompileBinary(data)
+		assert.NotNil(err, data)
+	}
+	for _, depth := range []int{-5, 0, maxNesting + 1} {
+		flatten := appendOptionalInt([]byte{astBinaryVersion, 0, 6}, &depth)
+		flatten = append(flatten, 1, 7, 0) // Identity
+		_, err = CompileBinary(flatten)
+		assert.NotNil(err, depth)
+	}
+	valid := maxNesting
+	flatten := appendOptionalInt([]byte{astBinaryVersion, 0, 6}, &valid)
+	_, err = CompileBinary(append(flatten, 1, 7, 0))
+	assert.Nil(err)
+	nested := []byte{astBinaryVersion, 0}
+	for i := 0; i <= maxNesting+1; i++ {
+		nested = append(nested, 16, 1) // NotExpression
+	}
+	nested = append(nested, 7, 0) // Identity
+	_, err = CompileBinary(nested)
+	assert.NotNil(err)
+}
@@ -0,0 +1,232 @@
+package jmespath
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+/* ASTs are serialized as nested JSON objects of the form
+
+       {"type": "Comparator", "value": "==", "children": [...]}
+
+   where type is the node type without its "AST" prefix, and value and
+   children are omitted when the node has none.  Values are strings for
+   fields, function names, keys, parameters and the keys of recursive
+   descents, numbers for indexes and
+   flatten depths, the comparator symbol for comparators, an array of
+   [start, stop, step] for slices, and the JSON value itself for literals.
+*/
+
+type astNodeJSON struct {
+	Type     string          `json:"type"`
+	Value    json.RawMessage `json:"value,omitempty"`
+	Children []ASTNode       `json:"children,omitempty"`
+}
+
+var astNodeTypesByName = func() map[string]astNodeType {
+	types := make(map[string]astNodeType)
+	for t := ASTEmpty; t <= ASTDescendant; t++ {
+		types[strings.TrimPrefix(t.String(), "AST")] = t
+	}
+	return types
+}()
+
+var comparatorsBySymbol = map[string]tokType{
+	"==": tEQ, "!=": tNE, "<": tLT, "<=": tLTE, ">": tGT, ">=": tGTE,
+	"in": tIn, "not in": tNotIn,
+}
+
+// MarshalJSON encodes the AST as JSON.  The encoding can be decoded with
+// UnmarshalJSON and compiled with CompileAST, so that an analyzed AST can
+// be stored or transported without being turned back into an expression.
+func (node ASTNode) MarshalJSON() ([]byte, error) {
+	encoded := astNodeJSON{
+		Type:     strings.TrimPrefix(node.nodeType.String(), "AST"),
+		Children: node.children,
+	}
+	value := node.value
+	if op, ok := value.(tokType); ok {
+		value = comparatorSymbol(op)
+	}
+	if value != nil || node.nodeType == ASTLiteral {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		encoded.Value = raw
+	}
+	return json.Marshal(encoded)
+}
+
+// UnmarshalJSON decodes an AST encoded by MarshalJSON.  It is an error for
+// the decoded AST to be malformed, for example for a comparator to have a
+// single child.
+func (node *ASTNode) UnmarshalJSON(data []byte) error {
+	var decoded astNodeJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	nodeType, ok := astNodeTypesByName[decoded.Type]
+	if !ok || nodeType == ASTEmpty {
+		return fmt.Errorf("invalid AST node type %q", decoded.Type)
+	}
+	result := ASTNode{nodeType: nodeType, children: decoded.Children}
+	if len(decoded.Value) > 0 {
+		value, err := decodeNodeValue(nodeType, decoded.Value)
+		if err != nil {
+			return fmt.Errorf("invalid value for %s node: %s", decoded.Type, err)
+		}
+		result.value = value
+	}
+	if err := validateNode(result); err != nil {
+		return err
+	}
+	*node = result
+	return nil
+}
+
+func decodeNodeValue(nodeType astNodeType, raw json.RawMessage) (interface{}, error) {
+	switch nodeType {
+	case ASTField, ASTFunctionExpression, ASTKeyValPair, ASTParameter, ASTDescendant:
+		var s string
+		err := json.Unmarshal(raw, &s)
+		return s, err
+	case ASTIndex, ASTFlatten:
+		var n int
+		err := json.Unmarshal(raw, &n)
+		return n, err
+	case ASTComparator:
+		var symbol string
+		if err := json.Unmarshal(raw, &symbol); err != nil {
+			return nil, err
+		}
+		op, ok := comparatorsBySymbol[symbol]
+		if !ok {
+			return nil, fmt.Errorf("unknown comparator %q", symbol)
+		}
+		return op, nil
+	case ASTSlice:
+		var parts []*int
+		if err := json.Unmarshal(raw, &parts); err != nil {
+			return nil, err
+		}
+		if len(parts) != 3 {
+			return nil, errors.New("expected [start, stop, step]")
+		}
+		return parts, nil
+	case ASTLiteral:
+		var literal interface{}
+		err := json.Unmarshal(raw, &literal)
+		return literal, err
+	}
+	return nil, errors.New("node type does not take a value")
+}
+
+// astArity is the number of children of each node type that has a fixed
+// number of children.
+var astArity = map[astNodeType]int{
+	ASTComparator:       2,
+	ASTCurrentNode:      0,
+	ASTExpRef:           1,
+	ASTField:            0,
+	ASTFilterProjection: 3,
+	ASTFlatten:          1,
+	ASTIdentity:         0,
+	ASTIndex:            0,
+	ASTIndexExpression:  2,
+	ASTKeyValPair:       1,
+	ASTLiteral:          0,
+	ASTOrExpression:     2,
+	ASTAndExpression:    2,
+	ASTNotExpression:    1,
+	ASTProjection:       2,
+	ASTSubexpression:    2,
+	ASTSlice:            0,
+	ASTValueProjection:  2,
+	ASTParameter:        0,
+	ASTDescendant:       1,
+}
+
+// validateNode checks that node, but not its children, is well formed, so
+// that evaluating it cannot panic.
+func validateNode(node ASTNode) error {
+	name := strings.TrimPrefix(node.nodeType.String(), "AST")
+	if arity, ok := astArity[node.nodeType]; ok && len(node.children) != arity {
+		return fmt.Errorf("%s node must have %d children, found %d", name, arity, len(node.children))
+	}
+	var valid bool
+	switch node.nodeType {
+	case ASTField, ASTFunctionExpression, ASTKeyValPair, ASTParameter, ASTDescendant:
+		_, valid = node.value.(string)
+	case ASTIndex:
+		_, valid = node.value.(int)
+	case ASTFlatten:
+		// The optimizer merges at most one flatten for each level of
+		// nesting of an expression.
+		depth, ok := node.value.(int)
+		valid = ok && depth >= 1 && depth <= maxNesting || node.value == nil
+	case ASTComparator:
+		_, valid = node.value.(tokType)
+	case ASTSlice:
+		parts, ok := node.value.([]*int)
+		valid = ok && len(parts) == 3
+	case ASTPipe:
+		valid = len(node.children) >= 2
+	case ASTMultiSelectHash:
+		valid = true
+		for _, child := range node.children {
+			valid = valid && child.nodeType == ASTKeyValPair
+		}
+	case ASTEmpty:
+		valid = false
+	default:
+		valid = true
+	}
+	if !valid {
+		return fmt.Errorf("malformed %s node", name)
+	}
+	return nil
+}
+
+// validateAST checks that node and all of its descendants are well formed.
+func validateAST(node ASTNode) error {
+	if err := validateNode(node); err != nil {
+		return err
+	}
+	for _, child := range node.children {
+		if err := validateAST(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CompileAST returns a JMESPath object for an AST returned by Parser.Parse,
+// JMESPath.AST or ASTNode.UnmarshalJSON, as Compile does for an expression.
+// It is an error for the AST to be malformed.
+func CompileAST(node ASTNode) (_ *JMESPath, err error) {
+	defer recoverInternal(&err)
+	if err := validateAST(node); err != nil {
+		return nil, err
+	}
+	return newJMESPath(node, newInterpreter()), nil
+}
+
+// CompileAST is like the package level CompileAST, but evaluates the
+// expression with the functions available in this runtime.
+func (rt *Runtime) CompileAST(node ASTNode) (_ *JMESPath, err error) {
+	defer recoverInternal(&err)
+	if err := validateAST(node); err != nil {
+		return nil, err
+	}
+	return newJMESPath(node, rt.newInterpreter()), nil
+}
+
+// AST returns the AST of the expression as it was parsed.  The AST is
+// optimized for the runtime an expression is compiled with, so CompileAST
+// optimizes it again for its own runtime.
+func (jp *JMESPath) AST() ASTNode {
+	return jp.parsed
+}
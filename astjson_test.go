@@ -0,0 +1,114 @@
+package jmespath
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func TestASTMarshalJSON(t *testing.T) {
+	assert := assert.New(t)
+	ast, err := NewParser().Parse("foo[?a == `1`].bar[0]")
+	assert.Nil(err)
+	encoded, err := json.Marshal(ast)
+	assert.Nil(err)
+	expected := `{"type":"FilterProjection","children":[` +
+		`{"type":"Field","value":"foo"},` +
+		`{"type":"IndexExpression","children":[{"type":"Field","value":"bar"},{"type":"Index","value":0}]},` +
+		`{"type":"Comparator","value":"==","children":[{"type":"Field","value":"a"},{"type":"Literal","value":1}]}]}`
+	assert.Equal(expected, string(encoded))
+}
+
+func TestASTUnmarshalJSONErrors(t *testing.T) {
+	assert := assert.New(t)
+	for _, data := range []string{
+		`{"type": "Unknown"}`,
+		`{"type": "Empty"}`,
+		`{"type": "Field"}`,
+		`{"type": "Field", "value": 1}`,
+		`{"type": "Comparator", "value": "=~", "children": [{"type": "Identity"}, {"type": "Identity"}]}`,
+		`{"type": "Comparator", "value": "==", "children": [{"type": "Identity"}]}`,
+		`{"type": "Slice", "value": [1, 2]}`,
+		`{"type": "Identity", "value": "x"}`,
+		`{"type": "Pipe", "children": [{"type": "Identity"}]}`,
+		`{"type": "MultiSelectHash", "children": [{"type": "Identity"}]}`,
+		`{"type": "Flatten", "value": -5, "children": [{"type": "Identity"}]}`,
+		`{"type": "Flatten", "value": 0, "children": [{"type": "Identity"}]}`,
+		`{"type": "Flatten", "value": 100000000, "children": [{"type": "Identity"}]}`,
+	} {
+		var node ASTNode
+		assert.NotNil(json.Unmarshal([]byte(data), &node), data)
+	}
+}
+
+func TestCompileASTRejectsMalformedAST(t *testing.T) {
+	assert := assert.New(t)
+	_, err := CompileAST(ASTNode{})
+	assert.NotNil(err)
+	_, err = CompileAST(ASTNode{nodeType: ASTNotExpression})
+	assert.NotNil(err)
+	identity := []ASTNode{{nodeType: ASTIdentity}}
+	for _, depth := range []int{-5, 0, maxNesting + 1} {
+		_, err = CompileAST(ASTNode{nodeType: ASTFlatten, value: depth, children: identity})
+		assert.NotNil(err, depth)
+	}
+
+	// Flattening stops once there are no more arrays to flatten.
+	jp, err := CompileAST(ASTNode{nodeType: ASTFlatten, value: maxNesting, children: identity})
+	if assert.Nil(err) {
+		result, err := jp.Search([]interface{}{[]interface{}{1.0, []interface{}{2.0}}, 3.0})
+		assert.Nil(err)
+		assert.Equal([]interface{}{1.0, 2.0, 3.0}, result)
+	}
+}
+
+// TestASTJSONRoundTrip checks that ASTs decoded from their JSON encoding
+// are identical to the originals and compile to expressions giving the same
+// results, for the compliance test suite.
+func TestASTJSONRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+	parser := NewParser()
+	forEachComplianceCase(assert, func(given interface{}, testcase TestCase, msg string) {
+		ast, err := parser.Parse(testcase.Expression)
+		if !assert.Nil(err, msg) {
+			return
+		}
+		encoded, err := json.Marshal(ast)
+		if !assert.Nil(err, msg) {
+			return
+		}
+		var decoded ASTNode
+		if !assert.Nil(json.Unmarshal(encoded, &decoded), msg) {
+			return
+		}
+		assert.Equal(ast, decoded, msg)
+		jp, err := CompileAST(decoded)
+		if !assert.Nil(err, msg) {
+			return
+		}
+		actual, err := jp.Search(given)
+		if assert.Nil(err, msg) {
+			assert.Equal(testcase.Result, actual, msg)
+		}
+	})
+}
+
+func TestCompiledASTRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+	jp := MustCompile("foo[][].bar | [0]")
+	encoded, err := json.Marshal(jp.AST())
+	assert.Nil(err)
+	var decoded ASTNode
+	assert.Nil(json.Unmarshal(encoded, &decoded))
+	assert.Equal(jp.AST(), decoded)
+}
+
+func TestASTIsParsed(t *testing.T) {
+	assert := assert.New(t)
+	parsed, err := NewParser().Parse("foo | @ | [0]")
+	assert.Nil(err)
+	jp := MustCompile("foo | @ | [0]")
+	assert.Equal(parsed, jp.AST())
+	assert.NotEqual(parsed, jp.ast)
+}
@@ -0,0 +1,242 @@
+package jmespath
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"time"
+)
+
+// BatchResult is the result of evaluating an expression against one
+// document of a batch, see JMESPath.SearchEach.
+type BatchResult struct {
+	// Index is the position of the document in the batch, starting at 0.
+	Index int
+	// Line is the line of the document in NDJSON input, starting at 1, or
+	// 0 for documents passed to SearchEach.
+	Line int
+	// Value is the result of the expression, nil if it failed.
+	Value interface{}
+	// Err is the error evaluating the expression failed with, or the
+	// error decoding the document.
+	Err error
+	// Duration is the time it took to evaluate the expression, not
+	// counting the time it took to decode the document.
+	Duration time.Duration
+}
+
+// BatchReport aggregates the results of evaluating an expression against a
+// batch of documents.
+type BatchReport struct {
+	// Results holds the result for every document, in order.  It is only
+	// filled in by SearchEach, SearchEachNDJSON passes the results to its
+	// callback instead so that its memory use does not grow with the
+	// input.
+	Results []BatchResult
+	// Documents is the number of documents evaluated.
+	Documents int
+	// Failed is the number of documents for which the expression failed.
+	Failed int
+	// Duration is the total time spent evaluating the expression, and
+	// MaxDuration the longest it took for a single document.
+	Duration    time.Duration
+	MaxDuration time.Duration
+}
+
+// Values returns the results of the documents for which the expression did
+// not fail, in order.
+func (r *BatchReport) Values() []interface{} {
+	values := make([]interface{}, 0, len(r.Results)-r.Failed)
+	for _, result := range r.Results {
+		if result.Err == nil {
+			values = append(values, result.Value)
+		}
+	}
+	return values
+}
+
+// Err returns the error of the first document for which the expression
+// failed, or nil.
+func (r *BatchReport) Err() error {
+	for _, result := range r.Results {
+		if result.Err != nil {
+			return result.Err
+		}
+	}
+	return nil
+}
+
+func (r *BatchReport) add(result BatchResult) {
+	r.Documents++
+	if result.Err != nil {
+		r.Failed++
+	}
+	r.Duration += result.Duration
+	if result.Duration > r.MaxDuration {
+		r.MaxDuration = result.Duration
+	}
+}
+
+// SearchEach evaluates the expression against each of the documents and
+// returns a report of the results, the errors and the time each evaluation
+// took.  Unlike Search, an error for one document does not stop the
+// others from being evaluated.
+func (jp *JMESPath) SearchEach(documents []interface{}) *BatchReport {
+	report := &BatchReport{Results: make([]BatchResult, len(documents))}
+	for i, document := range documents {
+		result := jp.searchTimed(document)
+		result.Index = i
+		report.Results[i] = result
+		report.add(result)
+	}
+	return report
+}
+
+// SearchEach compiles a JMESPath expression once and evaluates it against
+// each of the documents, see JMESPath.SearchEach.
+func SearchEach(expression string, documents []interface{}) (*BatchReport, error) {
+	jp, err := Compile(expression)
+	if err != nil {
+		return nil, err
+	}
+	return jp.SearchEach(documents), nil
+}
+
+// SearchEachNDJSON evaluates the expression against each document of the
+// newline delimited JSON read from r, one document per line, decoded like
+// SearchBytes decodes documents, and calls each with the result for every
+// document as it is evaluated.  Blank lines are skipped.  A line that is
+// not valid JSON is reported to each with the decoding error as its Err,
+// and counts as failed.  If each returns an error, SearchEachNDJSON stops
+// and returns it, as it does errors reading r.  The returned report does
+// not hold the results.
+func (jp *JMESPath) SearchEachNDJSON(r io.Reader, each func(BatchResult) error) (*BatchReport, error) {
+	report := &BatchReport{}
+	reader := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return report, err
+		}
+		if len(bytes.TrimSpace(data)) > 0 {
+			var result BatchResult
+			if document, decodeErr := jp.intr.decode(data); decodeErr != nil {
+				result.Err = decodeErr
+			} else {
+				result = jp.searchTimed(document)
+			}
+			result.Index, result.Line = report.Documents, line
+			report.add(result)
+			if eachErr := each(result); eachErr != nil {
+				return report, eachErr
+			}
+		}
+		if err == io.EOF {
+			return report, nil
+		}
+	}
+}
+
+// SearchEachNDJSON compiles a JMESPath expression once and evaluates it
+// against each document of the newline delimited JSON read from r, see
+// JMESPath.SearchEachNDJSON.
+func SearchEachNDJSON(expression string, r io.Reader, each func(BatchResult) error) (*BatchReport, error) {
+	jp, err := Compile(expression)
+	if err != nil {
+		return nil, err
+	}
+	return jp.SearchEachNDJSON(r, each)
+}
+
+// searchTimed evaluates the expression against document and measures how
+// long it takes.
+func (jp *JMESPath) searchTimed(document interface{}) BatchResult {
+	start := time.Now()
+	value, err := jp.Search(document)
+	return BatchResult{Value: value, Err: err, Duration: time.Since(start)}
+}
+
+// maxLineErrors is the number of line errors a LinesError holds.
+const maxLineErrors = 100
+
+// LineError is the error the expression failed with for a line of newline
+// delimited JSON, or the error decoding the line.
+type LineError struct {
+	// Line is the line of the document, starting at 1.
+	Line int
+	Err  error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+}
+
+// Unwrap returns the error of the line.
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// LinesError is the error SearchLines returns when the expression failed
+// for some of the lines.
+type LinesError struct {
+	// Errors holds the errors of the first lines that failed, up to 100,
+	// so that the memory used does not grow with the input.
+	Errors []*LineError
+	// Failed is the number of lines that failed.
+	Failed int
+}
+
+func (e *LinesError) Error() string {
+	if e.Failed == 1 {
+		return e.Errors[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more lines failed)", e.Errors[0], e.Failed-1)
+}
+
+// Unwrap returns the error of the first line that failed.
+func (e *LinesError) Unwrap() error {
+	return e.Errors[0]
+}
+
+// SearchLines evaluates the expression against each document of the
+// newline delimited JSON read from r, see SearchEachNDJSON, and writes the
+// result for each document to w as compact JSON on a line of its own, as
+// an Encoder does, in the number format of the runtime.  Nothing is
+// written for a line that is not valid JSON or for which the expression
+// fails; SearchLines carries on with the next line, and once the input is
+// exhausted returns a *LinesError for the lines that failed.  Errors
+// reading r or writing w stop it and are returned as they are.
+func (jp *JMESPath) SearchLines(r io.Reader, w io.Writer) error {
+	encoder := NewEncoder(w)
+	encoder.SetNumberFormat(jp.intr.numbers)
+	failed := &LinesError{}
+	_, err := jp.SearchEachNDJSON(r, func(result BatchResult) error {
+		if result.Err == nil {
+			return encoder.Encode(result.Value)
+		}
+		if failed.Failed < maxLineErrors {
+			failed.Errors = append(failed.Errors, &LineError{Line: result.Line, Err: result.Err})
+		}
+		failed.Failed++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if failed.Failed > 0 {
+		return failed
+	}
+	return nil
+}
+
+// SearchLines compiles a JMESPath expression once and evaluates it against
+// each document of the newline delimited JSON read from r, writing the
+// results to w, see JMESPath.SearchLines.
+func SearchLines(expression string, r io.Reader, w io.Writer) error {
+	jp, err := Compile(expression)
+	if err != nil {
+		return err
+	}
+	return jp.SearchLines(r, w)
+}
@@ -0,0 +1,144 @@
+package jmespath
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func TestSearchEach(t *testing.T) {
+	assert := assert.New(t)
+	documents := []interface{}{
+		map[string]interface{}{"name": "a", "tags": []interface{}{"x"}},
+		map[string]interface{}{"name": "b", "tags": 5.0},
+		map[string]interface{}{"name": "c"},
+	}
+	report, err := SearchEach("length(tags)", documents)
+	assert.Nil(err)
+	assert.Equal(3, report.Documents)
+	assert.Equal(2, report.Failed)
+	if assert.Len(report.Results, 3) {
+		assert.Equal(0, report.Results[0].Index)
+		assert.Equal(1.0, report.Results[0].Value)
+		assert.Nil(report.Results[0].Err)
+		assert.Equal(1, report.Results[1].Index)
+		assert.Equal(0, report.Results[1].Line)
+		assert.NotNil(report.Results[1].Err)
+		assert.NotNil(report.Results[2].Err)
+	}
+	assert.Equal([]interface{}{1.0}, report.Values())
+	assert.Equal(report.Results[1].Err, report.Err())
+	assert.True(report.MaxDuration <= report.Duration)
+
+	report = MustCompile("name").SearchEach(documents)
+	assert.Equal(0, report.Failed)
+	assert.Nil(report.Err())
+	assert.Equal([]interface{}{"a", "b", "c"}, report.Values())
+
+	_, err = SearchEach("a[", documents)
+	assert.IsType(SyntaxError{}, err)
+}
+
+func TestSearchEachNDJSON(t *testing.T) {
+	assert := assert.New(t)
+	input := "{\"a\": 1}\n\n{\"a\": \"x\"}\n{not json\n  {\"a\": 3}"
+	var results []BatchResult
+	report, err := SearchEachNDJSON("abs(a)", strings.NewReader(input), func(result BatchResult) error {
+		results = append(results, result)
+		return nil
+	})
+	assert.Nil(err)
+	assert.Nil(report.Results)
+	assert.Equal(4, report.Documents)
+	assert.Equal(2, report.Failed)
+	if assert.Len(results, 4) {
+		assert.Equal(BatchResult{Index: 0, Line: 1, Value: 1.0, Duration: results[0].Duration}, results[0])
+		assert.Equal(1, results[1].Index)
+		assert.Equal(3, results[1].Line)
+		assert.NotNil(results[1].Err)
+		assert.Equal(4, results[2].Line)
+		assert.NotNil(results[2].Err)
+		assert.Equal(3, results[3].Index)
+		assert.Equal(5, results[3].Line)
+		assert.Equal(3.0, results[3].Value)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	report, err = MustCompile("a").SearchEachNDJSON(strings.NewReader(input), func(result BatchResult) error {
+		calls++
+		return stop
+	})
+	assert.Equal(stop, err)
+	assert.Equal(1, calls)
+	assert.Equal(1, report.Documents)
+
+	_, err = SearchEachNDJSON("a[", strings.NewReader(input), nil)
+	assert.IsType(SyntaxError{}, err)
+}
+
+func TestSearchEachNDJSONOrdered(t *testing.T) {
+	assert := assert.New(t)
+	rt := NewRuntime()
+	rt.SetOrderedObjects(true)
+	jp, err := rt.Compile("keys(@)")
+	assert.Nil(err)
+	var values []interface{}
+	_, err = jp.SearchEachNDJSON(strings.NewReader("{\"b\": 1, \"a\": 2}\n"), func(result BatchResult) error {
+		values = append(values, result.Value)
+		return result.Err
+	})
+	assert.Nil(err)
+	assert.Equal([]interface{}{[]interface{}{"b", "a"}}, values)
+}
+
+func TestSearchLines(t *testing.T) {
+	assert := assert.New(t)
+	input := "{\"a\": 1, \"b\": [1, 2]}\n{\"a\": \"x\"}\n\n{not json\n{\"b\": []}\n{\"a\": -2.5}"
+	var output strings.Builder
+	err := SearchLines("{a: abs(a), b: b}", strings.NewReader(input), &output)
+	assert.Equal("{\"a\":1,\"b\":[1,2]}\n{\"a\":2.5,\"b\":null}\n", output.String())
+	var linesErr *LinesError
+	if assert.True(errors.As(err, &linesErr)) {
+		assert.Equal(3, linesErr.Failed)
+		if assert.Len(linesErr.Errors, 3) {
+			assert.Equal(2, linesErr.Errors[0].Line)
+			assert.Equal(4, linesErr.Errors[1].Line)
+			assert.Equal(5, linesErr.Errors[2].Line)
+		}
+		assert.Contains(err.Error(), "line 2: ")
+		assert.Contains(err.Error(), "(and 2 more lines failed)")
+	}
+	var evalErr *EvalError
+	assert.True(errors.As(err, &evalErr))
+
+	output.Reset()
+	assert.Nil(SearchLines("a", strings.NewReader("{\"a\": null}\n{\"a\": \"x\"}\n"), &output))
+	assert.Equal("null\n\"x\"\n", output.String())
+
+	assert.IsType(SyntaxError{}, SearchLines("a[", strings.NewReader(""), &output))
+}
+
+func TestSearchLinesLimitsErrors(t *testing.T) {
+	assert := assert.New(t)
+	input := strings.Repeat("oops\n", maxLineErrors+5)
+	err := SearchLines("a", strings.NewReader(input), &strings.Builder{})
+	var linesErr *LinesError
+	if assert.True(errors.As(err, &linesErr)) {
+		assert.Equal(maxLineErrors+5, linesErr.Failed)
+		assert.Len(linesErr.Errors, maxLineErrors)
+	}
+}
+
+func TestSearchLinesNumberFormat(t *testing.T) {
+	assert := assert.New(t)
+	rt := NewRuntime()
+	rt.SetNumberFormat(NumberFormat{JSONNumbers: true})
+	jp, err := rt.Compile("price")
+	assert.Nil(err)
+	var output strings.Builder
+	assert.Nil(jp.SearchLines(strings.NewReader("{\"price\": 1.10}\n{\"price\": 1e21}\n"), &output))
+	assert.Equal("1.10\n1e21\n", output.String())
+}
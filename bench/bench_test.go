@@ -0,0 +1,86 @@
+package bench
+
+import (
+	"testing"
+
+	"github.com/jmespath/go-jmespath"
+)
+
+func BenchmarkCorpus(b *testing.B) {
+	for _, c := range Corpus() {
+		c := c
+		jp, err := jmespath.Compile(c.Expression)
+		if err != nil {
+			b.Fatalf("%s: %s", c.Name, err)
+		}
+		b.Run(c.Name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := jp.Search(c.Document); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// nodeTypeCases isolate each AST node type, so that the allocations
+// reported for them are those of the node type.
+var nodeTypeCases = []struct {
+	nodeType   string
+	expression string
+}{
+	{"Field", "name"},
+	{"Subexpression", "attrs.color"},
+	{"Index", "tags[0]"},
+	{"Slice", "tags[0:1]"},
+	{"Projection", "tags[*]"},
+	{"FilterProjection", "tags[?@ == 'red']"},
+	{"ValueProjection", "attrs.*"},
+	{"Flatten", "tags[]"},
+	{"Comparator", "price == `1`"},
+	{"OrExpression", "missing || name"},
+	{"AndExpression", "name && id"},
+	{"NotExpression", "!in_stock"},
+	{"Pipe", "attrs | color"},
+	{"MultiSelectList", "[id, name]"},
+	{"MultiSelectHash", "{i: id, n: name}"},
+	{"FunctionExpression", "length(tags)"},
+	{"ExpRef", "sort_by(tags[*].{t: @}, &t)"},
+	{"Literal", "`[1, 2]`"},
+	{"CurrentNode", "@"},
+}
+
+func BenchmarkNodeType(b *testing.B) {
+	items := WideDocument(1).(map[string]interface{})["items"].([]interface{})
+	document := items[0]
+	for _, c := range nodeTypeCases {
+		c := c
+		jp, err := jmespath.Compile(c.expression)
+		if err != nil {
+			b.Fatalf("%s: %s", c.nodeType, err)
+		}
+		b.Run(c.nodeType, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := jp.Search(document); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkCompile(b *testing.B) {
+	for _, c := range Corpus() {
+		c := c
+		b.Run(c.Name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := jmespath.Compile(c.Expression); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
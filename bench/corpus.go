@@ -0,0 +1,87 @@
+// Package bench provides representative expressions and documents for
+// benchmarking JMESPath evaluation.  The benchmarks themselves run with
+//
+//	go test -run '^$' -bench . -benchmem ./bench/
+//
+// and include one benchmark per AST node type, so that the allocations
+// reported with -benchmem can be attributed to the node type responsible.
+package bench
+
+import "fmt"
+
+// Case is an expression to benchmark along with the document it is
+// searched against.
+type Case struct {
+	Name       string
+	Expression string
+	Document   interface{}
+}
+
+// Corpus returns the benchmark cases.  The documents are generated rather
+// than loaded from files so that their shapes are easy to scale.
+func Corpus() []Case {
+	deep := DeepDocument(50)
+	wide := WideDocument(10000)
+	return []Case{
+		{"DeepField", DeepExpression(50), deep},
+		{"DeepPipe", DeepPipeExpression(50), deep},
+		{"WideProjection", "items[*].name", wide},
+		{"WideFlatten", "items[].tags[]", wide},
+		{"WideValueProjection", "items[*].attrs.*", wide},
+		{"WideSlice", "items[::2].id", wide},
+		{"FilterSimple", "items[?price > `50`].id", wide},
+		{"FilterCompound", "items[?price > `50` && in_stock && contains(tags, 'red')].name", wide},
+		{"FilterNested", "items[?attrs.size == 'L' || attrs.color == 'blue'].{id: id, name: name}", wide},
+		{"FilterThenSort", "sort_by(items[?in_stock], &price)[:10].name", wide},
+		{"Aggregate", "{total: sum(items[].price), max: max(items[].price), n: length(items)}", wide},
+		{"MultiSelect", "items[*].[id, name, price]", wide},
+	}
+}
+
+// DeepDocument returns an object nested depth levels deep through the key
+// "a", with the value "leaf" at the bottom.
+func DeepDocument(depth int) interface{} {
+	var doc interface{} = "leaf"
+	for i := 0; i < depth; i++ {
+		doc = map[string]interface{}{"a": doc}
+	}
+	return doc
+}
+
+// DeepExpression returns the expression "a.a. ... .a" with depth fields.
+func DeepExpression(depth int) string {
+	expression := "a"
+	for i := 1; i < depth; i++ {
+		expression += ".a"
+	}
+	return expression
+}
+
+// DeepPipeExpression returns the expression "a | a | ... | a" with depth
+// fields.
+func DeepPipeExpression(depth int) string {
+	expression := "a"
+	for i := 1; i < depth; i++ {
+		expression += " | a"
+	}
+	return expression
+}
+
+// WideDocument returns an object with an "items" array of n objects, each
+// with scalar, array and object fields.
+func WideDocument(n int) interface{} {
+	colors := []string{"red", "green", "blue"}
+	sizes := []string{"S", "M", "L"}
+	items := make([]interface{}, n)
+	for i := range items {
+		items[i] = map[string]interface{}{
+			"id":       float64(i),
+			"name":     fmt.Sprintf("item-%d", i),
+			"price":    float64(i % 100),
+			"in_stock": i%3 != 0,
+			"tags":     []interface{}{colors[i%3], sizes[i%3]},
+			"attrs":    map[string]interface{}{"color": colors[i%3], "size": sizes[(i/3)%3]},
+		}
+	}
+	return map[string]interface{}{"items": items}
+}
@@ -0,0 +1,281 @@
+/*Command jpfmt checks and formats the JMESPath expressions embedded in Go
+source files.
+
+It looks for string literals passed as the expression argument of the
+package level functions of github.com/jmespath/go-jmespath, such as
+jmespath.Compile and jmespath.Search, reports the expressions that do not
+parse, and rewrites the rest in the canonical form of jmespath.Format.
+Calls are recognized by the name the file imports the package under, so
+methods such as Runtime.Search, whose receiver cannot be resolved without
+type checking, and calls to functions of other packages are left alone.
+Expressions using syntax that depends on the configuration of a Runtime,
+such as macros and the recursive descent operator, are left unchanged and
+not reported.
+
+Usage:
+
+    jpfmt [flags] [path ...]
+
+Directories are walked recursively.  Without -l or -w the formatted source
+is written to standard output.  The exit status is 2 if any expression is
+invalid.
+
+Examples:
+
+List the files whose expressions would change:
+
+    jpfmt -l ./...
+
+Format the expressions in place:
+
+    jpfmt -w .
+
+*/
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jmespath/go-jmespath"
+)
+
+var (
+	list  = flag.Bool("l", false, "List files whose expressions would change.")
+	write = flag.Bool("w", false, "Write the result to the source file instead of standard output.")
+)
+
+// importPath is the import path of the jmespath package.
+const importPath = "github.com/jmespath/go-jmespath"
+
+// expressionFuncs are the functions of the jmespath package taking an
+// expression as their first argument.
+var expressionFuncs = map[string]bool{
+	"Compile":           true,
+	"CompileWith":       true,
+	"CompileWithSchema": true,
+	"Lookup":            true,
+	"MustCompile":       true,
+	"MustSearchAs":      true,
+	"ReferencedPaths":   true,
+	"Search":            true,
+	"SearchAs":          true,
+	"SearchBytes":       true,
+	"SearchDiff":        true,
+	"SearchPaths":       true,
+	"SearchReader":      true,
+	"SearchWith":        true,
+	"SearchWithParams":  true,
+}
+
+type edit struct {
+	start, end int
+	text       string
+}
+
+func main() {
+	flag.Parse()
+	paths := flag.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	status := 0
+	for _, path := range paths {
+		path = strings.TrimSuffix(path, "/...")
+		err := filepath.Walk(path, func(name string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				base := info.Name()
+				if name != path && (strings.HasPrefix(base, ".") || base == "vendor" || base == "testdata") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !strings.HasSuffix(name, ".go") {
+				return nil
+			}
+			invalid, err := processFile(name)
+			if invalid && status == 0 {
+				status = 2
+			}
+			return err
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 1
+		}
+	}
+	os.Exit(status)
+}
+
+// processFile formats the expressions in the named file.  It reports
+// whether the file contains an invalid expression.
+func processFile(name string) (bool, error) {
+	src, err := ioutil.ReadFile(name)
+	if err != nil {
+		return false, err
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, name, src, 0)
+	if err != nil {
+		return false, err
+	}
+	qualifier, ok := packageName(file)
+	if !ok {
+		return false, nil
+	}
+	invalid := false
+	var edits []edit
+	ast.Inspect(file, func(n ast.Node) bool {
+		lit := expressionLiteral(n, qualifier)
+		if lit == nil {
+			return true
+		}
+		expression, err := strconv.Unquote(lit.Value)
+		if err != nil {
+			return true
+		}
+		formatted, err := jmespath.Format(expression)
+		if err != nil && runtimeSyntax(expression) {
+			return true
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: invalid expression %s: %s\n", fset.Position(lit.Pos()), lit.Value, err)
+			invalid = true
+			return true
+		}
+		if formatted != expression {
+			edits = append(edits, edit{
+				start: fset.Position(lit.Pos()).Offset,
+				end:   fset.Position(lit.End()).Offset,
+				text:  quote(formatted, lit.Value[0] == '`'),
+			})
+		}
+		return true
+	})
+	if len(edits) == 0 && (*list || *write) {
+		return invalid, nil
+	}
+	result := applyEdits(src, edits)
+	switch {
+	case *list:
+		fmt.Println(name)
+	case *write:
+		return invalid, ioutil.WriteFile(name, result, 0644)
+	default:
+		os.Stdout.Write(result)
+	}
+	return invalid, nil
+}
+
+// packageName returns the name the calls to the jmespath package are
+// qualified with in file, which is empty for the package itself and for dot
+// imports.  It returns false if file does not use the package.
+func packageName(file *ast.File) (string, bool) {
+	if file.Name.Name == "jmespath" {
+		return "", true
+	}
+	for _, spec := range file.Imports {
+		if path, err := strconv.Unquote(spec.Path.Value); err != nil || path != importPath {
+			continue
+		}
+		if spec.Name == nil {
+			return "jmespath", true
+		}
+		if spec.Name.Name == "." {
+			return "", true
+		}
+		if spec.Name.Name != "_" {
+			return spec.Name.Name, true
+		}
+	}
+	return "", false
+}
+
+// expressionLiteral returns the string literal passed as the expression to
+// a call to one of expressionFuncs qualified with qualifier, or nil if n is
+// not such a call.
+func expressionLiteral(n ast.Node, qualifier string) *ast.BasicLit {
+	call, ok := n.(*ast.CallExpr)
+	if !ok || len(call.Args) == 0 {
+		return nil
+	}
+	fun := call.Fun
+	// Instantiations of generic functions, such as SearchAs[int].
+	if index, ok := fun.(*ast.IndexExpr); ok {
+		fun = index.X
+	}
+	var name string
+	switch fun := fun.(type) {
+	case *ast.SelectorExpr:
+		// A package name has no object in the file, unlike a variable
+		// declared with the same name.
+		if x, ok := fun.X.(*ast.Ident); ok && qualifier != "" && x.Name == qualifier && x.Obj == nil {
+			name = fun.Sel.Name
+		}
+	case *ast.Ident:
+		if qualifier == "" {
+			name = fun.Name
+		}
+	}
+	if !expressionFuncs[name] {
+		return nil
+	}
+	lit, ok := call.Args[0].(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return nil
+	}
+	return lit
+}
+
+// runtimeSyntax reports whether expression uses syntax that only parses
+// with a Runtime configured for it: macros, which need their definitions,
+// and the operators and literals a Runtime has to enable.
+func runtimeSyntax(expression string) bool {
+	lexer := jmespath.NewLexer()
+	lexer.Reset(expression)
+	for t := lexer.NextToken(); t.Kind != jmespath.TokenEOF; t = lexer.NextToken() {
+		if t.Kind == jmespath.TokenMacro {
+			return true
+		}
+	}
+	rt := jmespath.NewRuntime()
+	rt.SetRecursiveDescent(true)
+	rt.SetLegacyLiterals(true)
+	_, err := rt.Compile(expression)
+	return err == nil
+}
+
+// quote returns a Go string literal for s, using a raw string literal if
+// the original was one and s can be written as one.
+func quote(s string, raw bool) string {
+	if raw && !strings.Contains(s, "`") {
+		return "`" + s + "`"
+	}
+	return strconv.Quote(s)
+}
+
+func applyEdits(src []byte, edits []edit) []byte {
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+	var b bytes.Buffer
+	last := 0
+	for _, e := range edits {
+		b.Write(src[last:e.start])
+		b.WriteString(e.text)
+		last = e.end
+	}
+	b.Write(src[last:])
+	return b.Bytes()
+}
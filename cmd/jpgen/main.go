@@ -0,0 +1,67 @@
+/*Command jpgen generates Go functions that implement JMESPath expressions,
+so that programs with a fixed set of queries need not parse and interpret
+them at run time.
+
+Usage:
+
+    jpgen [flags] Name=expression ...
+
+Each argument declares a function Name that evaluates expression against
+its argument, see package jpgen for the expressions it supports.  The
+generated source is written to standard output, or to the file named by
+-o.  The exit status is 2 if an expression is invalid or unsupported.
+
+Examples:
+
+Generate a file from a go:generate directive:
+
+    //go:generate jpgen -package queries -o queries.go ActiveNames=users[?active].name
+
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/jmespath/go-jmespath/jpgen"
+)
+
+var (
+	pkg    = flag.String("package", "main", "The package of the generated file.")
+	output = flag.String("o", "", "Write the generated file to this path instead of standard output.")
+)
+
+func main() {
+	flag.Parse()
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: jpgen [flags] Name=expression ...")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+	funcs := make([]jpgen.Func, flag.NArg())
+	for i, arg := range flag.Args() {
+		eq := strings.Index(arg, "=")
+		if eq < 0 {
+			fmt.Fprintf(os.Stderr, "jpgen: %q is not of the form Name=expression\n", arg)
+			os.Exit(2)
+		}
+		funcs[i] = jpgen.Func{Name: arg[:eq], Expression: arg[eq+1:]}
+	}
+	source, err := jpgen.Generate(*pkg, funcs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "jpgen: %s\n", err)
+		os.Exit(2)
+	}
+	if *output == "" {
+		os.Stdout.Write(source)
+		return
+	}
+	if err := ioutil.WriteFile(*output, source, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "jpgen: %s\n", err)
+		os.Exit(1)
+	}
+}
@@ -6,10 +6,36 @@ Only print the AST for the expression:
 
     jp.go -ast "foo.bar.baz"
 
+Print the evaluation plan and estimated cost for the expression:
+
+    jp.go -explain "foo[?bar > `1`].baz"
+
+Report which parts of the expression are exercised by each document in a
+JSON array of documents:
+
+    jp.go -coverage -input /tmp/corpus.json "foo || bar"
+
+Print the expression in canonical form:
+
+    jp.go -fmt "foo[?bar>`1`]  |  [0]"
+
 Evaluate the JMESPath expression against JSON data from a file:
 
     jp.go -input /tmp/data.json "foo.bar.baz"
 
+Print the names in an array result one per line, without quotes:
+
+    jp.go -ndjson -raw -input /tmp/data.json "people[*].name"
+
+Evaluate the expression against each line of a newline delimited JSON log,
+printing one result per line and reporting the lines that fail on stderr:
+
+    jp.go -lines -input /tmp/events.ndjson "{level: level, msg: message}"
+
+Keep 64-bit IDs exact, and print those beyond 2^53 as strings:
+
+    jp.go -integers string -input /tmp/data.json "items[?id > `1234567890123456789`].id"
+
 This program can also be used as an executable to the jp-compliance
 runner (github.com/jmespath/jmespath.test).
 
@@ -19,6 +45,7 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 )
@@ -29,6 +56,11 @@ import (
 	"github.com/jmespath/go-jmespath"
 )
 
+// decoder decodes the input documents.  Builds that need faster decoding
+// can replace it, from a file of their own, with a jmespath.Decoder that
+// uses jsoniter, go-json or sonic.
+var decoder jmespath.Decoder = jmespath.DecoderFunc(json.Unmarshal)
+
 func errMsg(msg string, a ...interface{}) int {
 	fmt.Fprintf(os.Stderr, msg, a...)
 	fmt.Fprintln(os.Stderr)
@@ -38,7 +70,17 @@ func errMsg(msg string, a ...interface{}) int {
 func run() int {
 
 	astOnly := flag.Bool("ast", false, "Print the AST for the input expression and exit.")
+	explain := flag.Bool("explain", false, "Print the evaluation plan for the input expression and exit.")
+	format := flag.Bool("fmt", false, "Print the input expression in canonical form and exit.")
+	coverage := flag.Bool("coverage", false, "Treat the input as an array of documents, search each, and print a coverage report for the expression.")
+	strict := flag.Bool("strict", false, "Report missing fields and values of the wrong type as errors instead of evaluating them to null.")
 	inputFile := flag.String("input", "", "Filename containing JSON data to search. If not provided, data is read from stdin.")
+	integers := flag.String("integers", "float", "How integers beyond 2^53 are handled: float rounds them, exact keeps them exact, and string keeps them exact and prints them as strings.")
+	compact := flag.Bool("compact", false, "Print the result as compact JSON instead of indenting it.")
+	raw := flag.Bool("raw", false, "Print string results without quotes or escapes.")
+	sortKeys := flag.Bool("sort-keys", false, "Print the keys of every object in sorted order.")
+	ndjson := flag.Bool("ndjson", false, "Print each element of an array result as compact JSON on a line of its own.")
+	lines := flag.Bool("lines", false, "Treat the input as newline delimited JSON, one document per line, and print the result for each document as compact JSON on a line of its own. Lines that are not valid JSON or for which the expression fails are reported on stderr.")
 
 	flag.Parse()
 	args := flag.Args()
@@ -48,6 +90,16 @@ func run() int {
 		return errMsg("\nError: expected a single argument (the JMESPath expression).")
 	}
 
+	integerModes := map[string]jmespath.IntegerMode{
+		"float":  jmespath.IntegersAsFloat,
+		"exact":  jmespath.IntegersExact,
+		"string": jmespath.IntegersAsString,
+	}
+	integerMode, ok := integerModes[*integers]
+	if !ok {
+		return errMsg("Error: -integers must be float, exact or string, got %q.", *integers)
+	}
+
 	expression := args[0]
 	parser := jmespath.NewParser()
 	parsed, err := parser.Parse(expression)
@@ -62,6 +114,32 @@ func run() int {
 		fmt.Printf("%s\n", parsed)
 		return 0
 	}
+	if *format {
+		fmt.Println(jmespath.FormatAST(parsed))
+		return 0
+	}
+	if *explain {
+		compiled, err := jmespath.Compile(expression)
+		if err != nil {
+			return errMsg("%s", err)
+		}
+		fmt.Print(compiled.ExplainPlan())
+		return 0
+	}
+
+	rt := jmespath.NewRuntime()
+	rt.SetStrict(*strict)
+	rt.SetIntegerMode(integerMode)
+	encoder := jmespath.NewEncoder(os.Stdout)
+	if !*compact && !*lines {
+		encoder.SetIndent("  ")
+	}
+	encoder.SetRawStrings(*raw)
+	encoder.SetSortKeys(*sortKeys)
+	encoder.SetNDJSON(*ndjson)
+	if *lines {
+		return searchLines(rt, expression, *inputFile, integerMode, encoder)
+	}
 
 	var inputData []byte
 	if *inputFile != "" {
@@ -78,18 +156,80 @@ func run() int {
 		}
 	}
 	var data interface{}
-	if err := json.Unmarshal(inputData, &data); err != nil {
+	if err := decoder.Unmarshal(inputData, &data); err != nil {
 		return errMsg("Invalid input JSON: %s", err)
 	}
-	result, err := jmespath.Search(expression, data)
+	if *coverage {
+		docs, ok := data.([]interface{})
+		if !ok {
+			return errMsg("Input must be a JSON array of documents when using -coverage")
+		}
+		compiled, err := rt.Compile(expression)
+		if err != nil {
+			return errMsg("%s", err)
+		}
+		report := jmespath.NewCoverage(compiled)
+		for i, doc := range docs {
+			if _, err := report.Search(doc); err != nil {
+				return errMsg("Error executing expression against document %d: %s", i, err)
+			}
+		}
+		fmt.Print(report)
+		return 0
+	}
+	var result interface{}
+	if integerMode == jmespath.IntegersAsFloat {
+		result, err = rt.Search(expression, data)
+	} else {
+		// The decoded data has lost the precision of large integers, so
+		// the input is decoded again by the runtime.
+		result, err = rt.SearchBytes(expression, inputData)
+	}
 	if err != nil {
 		return errMsg("Error executing expression: %s", err)
 	}
-	toJSON, err := json.MarshalIndent(result, "", "  ")
-	if err != nil {
+	if err := encoder.Encode(result); err != nil {
 		return errMsg("Error serializing result to JSON: %s", err)
 	}
-	fmt.Println(string(toJSON))
+	return 0
+}
+
+// searchLines evaluates the expression against each line of the newline
+// delimited JSON read from inputFile, or stdin, and prints the results as
+// they are evaluated.  It reports the lines that fail on stderr and
+// carries on, but exits with an error status if any did.
+func searchLines(rt *jmespath.Runtime, expression, inputFile string, integerMode jmespath.IntegerMode, encoder *jmespath.Encoder) int {
+	var input io.Reader = os.Stdin
+	if inputFile != "" {
+		file, err := os.Open(inputFile)
+		if err != nil {
+			return errMsg("Error loading file %s: %s", inputFile, err)
+		}
+		defer file.Close()
+		input = file
+	}
+	if integerMode == jmespath.IntegersAsFloat {
+		// Otherwise the runtime decodes the lines itself to keep large
+		// integers exact.
+		rt.SetDecoder(decoder)
+	}
+	compiled, err := rt.Compile(expression)
+	if err != nil {
+		return errMsg("%s", err)
+	}
+	report, err := compiled.SearchEachNDJSON(input, func(result jmespath.BatchResult) error {
+		if result.Err != nil {
+			fmt.Fprintf(os.Stderr, "line %d: %s\n", result.Line, result.Err)
+			return nil
+		}
+		return encoder.Encode(result.Value)
+	})
+	if err != nil {
+		return errMsg("Error: %s", err)
+	}
+	if report.Failed > 0 {
+		return 1
+	}
 	return 0
 }
 
@@ -0,0 +1,79 @@
+package jmespath
+
+import (
+	"errors"
+
+	"github.com/jmespath/go-jmespath/jputil"
+)
+
+// Collation compares two strings for sorting.  It returns a negative
+// number if a sorts before b, zero if they are equal and a positive number
+// if a sorts after b.  The CompareString method of a
+// golang.org/x/text/collate Collator is a locale-aware Collation.
+type Collation func(a, b string) int
+
+// builtinCollations are the collations every runtime knows.
+var builtinCollations = map[string]Collation{
+	"binary":  nil,
+	"natural": jputil.NaturalCompare,
+}
+
+// RegisterCollation makes collation available to sort() and sort_by() in
+// expressions compiled after the call, which select it by name with an
+// optional last argument, as in "sort_by(people, &name, 'de')".  The
+// built-in collations are "binary", which compares strings byte-wise and
+// is used by default, and "natural", which compares runs of digits by
+// their numeric value so that "item2" sorts before "item10", see
+// jputil.NaturalCompare.  It is an error to register a collation with the
+// name of an existing collation.
+func (rt *Runtime) RegisterCollation(name string, collation Collation) error {
+	if name == "" {
+		return errors.New("collation name cannot be empty")
+	}
+	if collation == nil {
+		return errors.New("collation cannot be nil: " + name)
+	}
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	if _, ok := builtinCollations[name]; ok {
+		return errors.New("collation already defined: " + name)
+	}
+	if _, ok := rt.collations[name]; ok {
+		return errors.New("collation already defined: " + name)
+	}
+	// The map is shared with interpreters, so it is replaced by a
+	// modified copy rather than modified.
+	collations := make(map[string]Collation, len(rt.collations)+1)
+	for n, c := range rt.collations {
+		collations[n] = c
+	}
+	collations[name] = collation
+	rt.collations = collations
+	return nil
+}
+
+// SetCollation sets the collation sort() and sort_by() use to order strings
+// in expressions compiled after the call when no collation is named in the
+// call, or restores byte-wise ordering if collation is nil.
+func (rt *Runtime) SetCollation(collation Collation) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.collation = collation
+}
+
+// collationArg returns the collation named by the optional collation
+// argument of a sorting function, or the default collation if there is
+// none.  A nil collation compares strings byte-wise.
+func (intr *treeInterpreter) collationArg(arguments []interface{}) (Collation, error) {
+	if len(arguments) == 0 {
+		return intr.collation, nil
+	}
+	name := arguments[0].(string)
+	if collation, ok := builtinCollations[name]; ok {
+		return collation, nil
+	}
+	if collation, ok := intr.collations[name]; ok {
+		return collation, nil
+	}
+	return nil, errors.New("unknown collation: " + name)
+}
@@ -0,0 +1,97 @@
+package jmespath
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func foldedCompare(a, b string) int {
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+func TestSortCollations(t *testing.T) {
+	assert := assert.New(t)
+	data := map[string]interface{}{
+		"files": []interface{}{"file10", "File3", "file2", "file1"},
+		"people": []interface{}{
+			map[string]interface{}{"name": "b10"},
+			map[string]interface{}{"name": "B2"},
+			map[string]interface{}{"name": "a1"},
+		},
+	}
+	rt := NewRuntime()
+	assert.Nil(rt.RegisterCollation("folded", foldedCompare))
+	cases := []struct {
+		expression string
+		expected   interface{}
+	}{
+		{"sort(files)", []interface{}{"File3", "file1", "file10", "file2"}},
+		{"sort(files, 'binary')", []interface{}{"File3", "file1", "file10", "file2"}},
+		{"sort(files, 'natural')", []interface{}{"File3", "file1", "file2", "file10"}},
+		{"sort(files, 'folded')", []interface{}{"file1", "file10", "file2", "File3"}},
+		{"sort(`[3, 1, 2]`, 'folded')", []interface{}{1.0, 2.0, 3.0}},
+		{"sort_by(people, &name)[*].name", []interface{}{"B2", "a1", "b10"}},
+		{"sort_by(people, &name, 'natural')[*].name", []interface{}{"B2", "a1", "b10"}},
+		{"sort_by(people, &name, 'folded')[*].name", []interface{}{"a1", "b10", "B2"}},
+	}
+	for _, tt := range cases {
+		jp, err := rt.Compile(tt.expression)
+		if !assert.Nil(err, tt.expression) {
+			continue
+		}
+		result, err := jp.Search(data)
+		assert.Nil(err, tt.expression)
+		assert.Equal(tt.expected, result, tt.expression)
+	}
+
+	_, err := rt.Search("sort(files, 'klingon')", data)
+	if assert.NotNil(err) {
+		assert.Contains(err.Error(), "unknown collation: klingon")
+	}
+	_, err = Search("sort(files, 'folded')", data)
+	assert.NotNil(err, "collations are registered per runtime")
+	_, err = Search("sort(files, 'natural', 'binary')", data)
+	assert.NotNil(err)
+	_, err = Search("sort(files, `1`)", data)
+	assert.NotNil(err)
+}
+
+func TestSetCollation(t *testing.T) {
+	assert := assert.New(t)
+	data := []interface{}{"x10", "x9", "X1", "a"}
+	rt := NewRuntime()
+	rt.SetCollation(foldedCompare)
+	result, err := rt.Search("sort(@)", data)
+	assert.Nil(err)
+	assert.Equal([]interface{}{"a", "X1", "x10", "x9"}, result)
+	result, err = rt.Search("sort(@, 'binary')", data)
+	assert.Nil(err)
+	assert.Equal([]interface{}{"X1", "a", "x10", "x9"}, result)
+	result, err = rt.Search("sort(@, 'natural')", data)
+	assert.Nil(err)
+	assert.Equal([]interface{}{"X1", "a", "x9", "x10"}, result)
+
+	rt.SetCollation(nil)
+	result, err = rt.Search("sort(reverse(@))", data)
+	assert.Nil(err)
+	assert.Equal([]interface{}{"X1", "a", "x10", "x9"}, result)
+}
+
+func TestRegisterCollation(t *testing.T) {
+	assert := assert.New(t)
+	rt := NewRuntime()
+	assert.Nil(rt.RegisterCollation("folded", foldedCompare))
+	assert.NotNil(rt.RegisterCollation("folded", foldedCompare))
+	assert.NotNil(rt.RegisterCollation("natural", foldedCompare))
+	assert.NotNil(rt.RegisterCollation("", foldedCompare))
+	assert.NotNil(rt.RegisterCollation("nothing", nil))
+
+	jp, err := rt.Compile("sort(@, 'folded')")
+	assert.Nil(err)
+	assert.Nil(rt.RegisterCollation("later", foldedCompare))
+	result, err := jp.Search([]interface{}{"b", "A"})
+	assert.Nil(err)
+	assert.Equal([]interface{}{"A", "b"}, result)
+}
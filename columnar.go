@@ -0,0 +1,199 @@
+package jmespath
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+/* Columnar evaluation is experimental.  A table is given as a map from
+   column name to column values, all of the same length, and an expression
+   is evaluated once per row with the row's columns as its fields.
+
+   Field references, literals, comparators and the logical operators are
+   evaluated a column at a time.  Any other expression is evaluated row by
+   row against a map built for each row, which gives the same results but
+   none of the speedup.
+*/
+
+// Bitmap is the set of rows selected by FilterColumns.
+type Bitmap struct {
+	words []uint64
+	n     int
+}
+
+func newBitmap(n int) Bitmap {
+	return Bitmap{words: make([]uint64, (n+63)/64), n: n}
+}
+
+func (b Bitmap) set(i int) {
+	b.words[i/64] |= 1 << uint(i%64)
+}
+
+// Len returns the number of rows in the table the bitmap was created for.
+func (b Bitmap) Len() int {
+	return b.n
+}
+
+// Get reports whether row i is selected.
+func (b Bitmap) Get(i int) bool {
+	return b.words[i/64]&(1<<uint(i%64)) != 0
+}
+
+// Count returns the number of selected rows.
+func (b Bitmap) Count() int {
+	count := 0
+	for _, word := range b.words {
+		count += bits.OnesCount64(word)
+	}
+	return count
+}
+
+// Indices returns the indexes of the selected rows in increasing order.
+func (b Bitmap) Indices() []int {
+	indices := make([]int, 0, b.Count())
+	for i := 0; i < b.n; i++ {
+		if b.Get(i) {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+// EvalColumns evaluates the expression against every row of the table
+// columns and returns the results as a column.
+//
+// This API is experimental and may change.
+func (jp *JMESPath) EvalColumns(columns map[string][]interface{}) (_ []interface{}, err error) {
+	defer recoverInternal(&err)
+	n, err := tableLength(columns)
+	if err != nil {
+		return nil, err
+	}
+	if result, ok := jp.intr.evalColumn(jp.ast, columns, n); ok {
+		return result, nil
+	}
+	result := make([]interface{}, n)
+	for i := 0; i < n; i++ {
+		// Each row gets its own map, as the result may be the row.
+		row := make(map[string]interface{}, len(columns))
+		for name, column := range columns {
+			row[name] = column[i]
+		}
+		result[i], err = jp.Search(row)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %s", i, err)
+		}
+	}
+	return result, nil
+}
+
+// FilterColumns evaluates the expression against every row of the table
+// columns, like EvalColumns, and returns the set of rows for which the
+// result is truthy.
+//
+// This API is experimental and may change.
+func (jp *JMESPath) FilterColumns(columns map[string][]interface{}) (Bitmap, error) {
+	result, err := jp.EvalColumns(columns)
+	if err != nil {
+		return Bitmap{}, err
+	}
+	selected := newBitmap(len(result))
+	for i, value := range result {
+		if !isFalse(value) {
+			selected.set(i)
+		}
+	}
+	return selected, nil
+}
+
+func tableLength(columns map[string][]interface{}) (int, error) {
+	n := -1
+	for name, column := range columns {
+		if n == -1 {
+			n = len(column)
+		} else if len(column) != n {
+			return 0, fmt.Errorf("column %q has %d rows, expected %d", name, len(column), n)
+		}
+	}
+	if n == -1 {
+		return 0, nil
+	}
+	return n, nil
+}
+
+// evalColumn evaluates node a column at a time.  It returns false if the
+// node contains an expression that can only be evaluated row by row.
+func (intr *treeInterpreter) evalColumn(node ASTNode, columns map[string][]interface{}, n int) ([]interface{}, bool) {
+	switch node.nodeType {
+	case ASTField:
+		if intr.strict {
+			// Missing columns are errors, which the rows report.
+			return nil, false
+		}
+		result := make([]interface{}, n)
+		key := node.value.(string)
+		if intr.foldKeys {
+			m := keyMatch{field: key}
+			for name := range columns {
+				m.add(name)
+			}
+			if m.found {
+				key = m.key
+			}
+		}
+		copy(result, columns[key])
+		return result, true
+	case ASTLiteral:
+		result := make([]interface{}, n)
+		for i := range result {
+			result[i] = node.value
+		}
+		return result, true
+	case ASTComparator:
+		left, ok := intr.evalColumn(node.children[0], columns, n)
+		if !ok {
+			return nil, false
+		}
+		right, ok := intr.evalColumn(node.children[1], columns, n)
+		if !ok {
+			return nil, false
+		}
+		op := node.value.(tokType)
+		result := make([]interface{}, n)
+		for i := range result {
+			result[i] = compare(op, left[i], right[i], !intr.legacyCompare)
+		}
+		return result, true
+	case ASTAndExpression, ASTOrExpression:
+		left, ok := intr.evalColumn(node.children[0], columns, n)
+		if !ok {
+			return nil, false
+		}
+		right, ok := intr.evalColumn(node.children[1], columns, n)
+		if !ok {
+			return nil, false
+		}
+		result := make([]interface{}, n)
+		for i := range result {
+			// Both sides have been evaluated already, so choosing
+			// between them gives the same result as short-circuiting.
+			if isFalse(left[i]) == (node.nodeType == ASTAndExpression) {
+				result[i] = left[i]
+			} else {
+				result[i] = right[i]
+			}
+		}
+		return result, true
+	case ASTNotExpression:
+		child, ok := intr.evalColumn(node.children[0], columns, n)
+		if !ok {
+			return nil, false
+		}
+		result := make([]interface{}, n)
+		for i := range result {
+			result[i] = isFalse(child[i])
+		}
+		return result, true
+	}
+	return nil, false
+}
@@ -0,0 +1,106 @@
+package jmespath
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+var table = map[string][]interface{}{
+	"name":   {"a", "b", "c", "d"},
+	"price":  {5.0, 20.0, 15.0, nil},
+	"region": {"us", "us", "eu", "us"},
+}
+
+func TestFilterColumns(t *testing.T) {
+	assert := assert.New(t)
+	selected, err := MustCompile("price > `10` && region == 'us' || name == 'd'").FilterColumns(table)
+	assert.Nil(err)
+	assert.Equal(4, selected.Len())
+	assert.Equal(2, selected.Count())
+	assert.Equal([]int{1, 3}, selected.Indices())
+	assert.True(selected.Get(1))
+	assert.False(selected.Get(0))
+}
+
+func TestEvalColumns(t *testing.T) {
+	assert := assert.New(t)
+	result, err := MustCompile("!(price < `10`)").EvalColumns(table)
+	assert.Nil(err)
+	assert.Equal([]interface{}{false, true, true, true}, result)
+
+	result, err = MustCompile("missing").EvalColumns(table)
+	assert.Nil(err)
+	assert.Equal([]interface{}{nil, nil, nil, nil}, result)
+}
+
+func TestEvalColumnsFallsBackToRows(t *testing.T) {
+	assert := assert.New(t)
+	result, err := MustCompile("join('-', [name, region])").EvalColumns(table)
+	assert.Nil(err)
+	assert.Equal([]interface{}{"a-us", "b-us", "c-eu", "d-us"}, result)
+
+	_, err = MustCompile("abs(name)").EvalColumns(table)
+	assert.NotNil(err)
+
+	result, err = MustCompile("@").EvalColumns(table)
+	assert.Nil(err)
+	assert.Equal([]interface{}{
+		map[string]interface{}{"name": "a", "price": 5.0, "region": "us"},
+		map[string]interface{}{"name": "b", "price": 20.0, "region": "us"},
+		map[string]interface{}{"name": "c", "price": 15.0, "region": "eu"},
+		map[string]interface{}{"name": "d", "price": nil, "region": "us"},
+	}, result)
+	result, err = MustCompile("to_array(@)").EvalColumns(table)
+	assert.Nil(err)
+	assert.Equal([]interface{}{map[string]interface{}{"name": "a", "price": 5.0, "region": "us"}}, result[0])
+}
+
+func TestEvalColumnsStrict(t *testing.T) {
+	assert := assert.New(t)
+	rt := NewRuntime()
+	rt.SetStrict(true)
+	for _, expression := range []string{"name == 'a'", "missing == 'us'"} {
+		jp, err := rt.Compile(expression)
+		assert.Nil(err)
+		result, err := jp.EvalColumns(table)
+		// The columns must give the results of the rows.
+		var rows []interface{}
+		var rowErr error
+		for i := range table["name"] {
+			row := map[string]interface{}{}
+			for name, column := range table {
+				row[name] = column[i]
+			}
+			value, err := jp.Search(row)
+			if err != nil {
+				rowErr = err
+				break
+			}
+			rows = append(rows, value)
+		}
+		if rowErr != nil {
+			var strictErr *StrictError
+			assert.True(errors.As(rowErr, &strictErr), expression)
+			if assert.NotNil(err, expression) {
+				assert.Contains(err.Error(), rowErr.Error(), expression)
+			}
+			continue
+		}
+		assert.Nil(err, expression)
+		assert.Equal(rows, result, expression)
+	}
+}
+
+func TestColumnsMustHaveSameLength(t *testing.T) {
+	assert := assert.New(t)
+	_, err := MustCompile("a").EvalColumns(map[string][]interface{}{
+		"a": {1.0, 2.0},
+		"b": {1.0},
+	})
+	assert.NotNil(err)
+	result, err := MustCompile("a").EvalColumns(nil)
+	assert.Nil(err)
+	assert.Equal([]interface{}{}, result)
+}
@@ -0,0 +1,24 @@
+package jmespath
+
+// SetLegacyComparisons sets whether expressions compiled after the call
+// only order numbers, as JMESPath used to.  By default the ordering
+// comparators "<", "<=", ">" and ">=" also order strings by code point,
+// so that "[?name >= 'm']" selects the names from "m" on, as the
+// community JMESPath specification allows.  In legacy mode comparing
+// strings with them returns null, as comparing values of other types
+// still does, and is an error in strict mode.
+func (rt *Runtime) SetLegacyComparisons(legacy bool) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.legacyCompare = legacy
+}
+
+// stringPair returns left and right if both are strings.
+func stringPair(left, right interface{}) (string, string, bool) {
+	l, ok := left.(string)
+	if !ok {
+		return "", "", false
+	}
+	r, ok := right.(string)
+	return l, r, ok
+}
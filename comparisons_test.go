@@ -0,0 +1,98 @@
+package jmespath
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+var comparisonsData = map[string]interface{}{
+	"users": []interface{}{
+		map[string]interface{}{"name": "alice"},
+		map[string]interface{}{"name": "mallory"},
+		map[string]interface{}{"name": "zoë"},
+		map[string]interface{}{"name": "Zed"},
+		map[string]interface{}{"name": 42.0},
+	},
+}
+
+func TestStringOrdering(t *testing.T) {
+	assert := assert.New(t)
+	cases := []struct {
+		expression string
+		expected   interface{}
+	}{
+		{"users[?name >= 'm'].name", []interface{}{"mallory", "zoë"}},
+		{"users[?name < 'm'].name", []interface{}{"alice", "Zed"}},
+		{"users[?name > 'zo'].name", []interface{}{"zoë"}},
+		{"users[?name <= 'alice'].name", []interface{}{"alice", "Zed"}},
+		{"'a' < 'b'", true},
+		{"'b' <= 'a'", false},
+		{"'' < 'a'", true},
+		{"'é' > 'z'", true},
+		{"'a' < `1`", nil},
+		{"`1` >= 'a'", nil},
+		{"'a' < `null`", nil},
+	}
+	traced := NewRuntime()
+	traced.SetTracer(&recordingTracer{})
+	for _, tt := range cases {
+		result, err := Search(tt.expression, comparisonsData)
+		assert.Nil(err, tt.expression)
+		assert.Equal(tt.expected, result, tt.expression)
+		result, err = traced.Search(tt.expression, comparisonsData)
+		assert.Nil(err, tt.expression)
+		assert.Equal(tt.expected, result, tt.expression)
+	}
+}
+
+func TestLegacyComparisons(t *testing.T) {
+	assert := assert.New(t)
+	rt := NewRuntime()
+	rt.SetLegacyComparisons(true)
+	result, err := rt.Search("users[?name >= 'm'].name", comparisonsData)
+	assert.Nil(err)
+	assert.Equal([]interface{}{}, result)
+	result, err = rt.Search("'a' < 'b'", nil)
+	assert.Nil(err)
+	assert.Nil(result)
+	result, err = rt.Search("`1` < `2`", nil)
+	assert.Nil(err)
+	assert.Equal(true, result)
+
+	result, err = SearchWith("'a' < 'b'", nil, WithLegacyComparisons(true))
+	assert.Nil(err)
+	assert.Nil(result)
+}
+
+func TestStrictStringOrdering(t *testing.T) {
+	assert := assert.New(t)
+	rt := NewRuntime()
+	rt.SetStrict(true)
+	result, err := rt.Search("'a' < 'b'", nil)
+	assert.Nil(err)
+	assert.Equal(true, result)
+	_, err = rt.Search("'a' < `1`", nil)
+	assert.True(errors.Is(err, ErrIncomparable))
+
+	rt.SetLegacyComparisons(true)
+	_, err = rt.Search("'a' < 'b'", nil)
+	assert.True(errors.Is(err, ErrIncomparable))
+}
+
+func TestEvalColumnsStringOrdering(t *testing.T) {
+	assert := assert.New(t)
+	columns := map[string][]interface{}{"name": {"alice", "mallory", 1.0}}
+	result, err := MustCompile("name >= 'm'").EvalColumns(columns)
+	assert.Nil(err)
+	assert.Equal([]interface{}{false, true, nil}, result)
+
+	rt := NewRuntime()
+	rt.SetLegacyComparisons(true)
+	jp, err := rt.Compile("name >= 'm'")
+	assert.Nil(err)
+	result, err = jp.EvalColumns(columns)
+	assert.Nil(err)
+	assert.Equal([]interface{}{nil, nil, nil}, result)
+}
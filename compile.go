@@ -0,0 +1,317 @@
+package jmespath
+
+/* This is a closure compiler.  It turns an AST into a tree of Go closures
+   once, at Compile time, so that evaluating a compiled expression does not
+   have to dispatch on the node type of every AST node it visits.
+
+   Each closure implements the common case for the JSON types produced by
+   encoding/json directly.  Anything else, such as user defined structs or
+   the rarer node types, falls back to the tree interpreter for that node,
+   which keeps the results of both engines identical.
+*/
+
+type evalFunc func(intr *treeInterpreter, value interface{}) (interface{}, error)
+
+// compileNode returns a closure that evaluates node.
+func compileNode(node ASTNode) evalFunc {
+	switch node.nodeType {
+	case ASTLiteral:
+		literal := node.value
+		return func(intr *treeInterpreter, value interface{}) (interface{}, error) {
+			return literal, nil
+		}
+	case ASTIdentity, ASTCurrentNode:
+		return func(intr *treeInterpreter, value interface{}) (interface{}, error) {
+			return value, nil
+		}
+	case ASTField:
+		key := node.value.(string)
+		return func(intr *treeInterpreter, value interface{}) (interface{}, error) {
+			if m, ok := value.(map[string]interface{}); ok {
+				return m[key], nil
+			}
+			return intr.Execute(node, value)
+		}
+	case ASTIndex:
+		index := node.value.(int)
+		return func(intr *treeInterpreter, value interface{}) (interface{}, error) {
+			if sliceType, ok := value.([]interface{}); ok {
+				i := index
+				if i < 0 {
+					i += len(sliceType)
+				}
+				if i < len(sliceType) && i >= 0 {
+					return sliceType[i], nil
+				}
+				return nil, nil
+			}
+			return intr.Execute(node, value)
+		}
+	case ASTSubexpression, ASTIndexExpression, ASTPipe:
+		stages := compileChildren(node)
+		return func(intr *treeInterpreter, value interface{}) (interface{}, error) {
+			result := value
+			var err error
+			for _, stage := range stages {
+				result, err = stage(intr, result)
+				if err != nil {
+					return nil, err
+				}
+			}
+			return result, nil
+		}
+	case ASTComparator:
+		op := node.value.(tokType)
+		left, right := compileNode(node.children[0]), compileNode(node.children[1])
+		if op == tIn || op == tNotIn {
+			if set, ok := newValueSet(node.children[1].value); ok && node.children[1].nodeType == ASTLiteral {
+				return func(intr *treeInterpreter, value interface{}) (interface{}, error) {
+					l, err := left(intr, value)
+					if err != nil {
+						return nil, err
+					}
+					if l, err = intr.resolveAll(l); err != nil {
+						return nil, err
+					}
+					return set.contains(l) == (op == tIn), nil
+				}
+			}
+		}
+		return func(intr *treeInterpreter, value interface{}) (interface{}, error) {
+			l, err := left(intr, value)
+			if err != nil {
+				return nil, err
+			}
+			r, err := right(intr, value)
+			if err != nil {
+				return nil, err
+			}
+			if l, r, err = intr.resolveOperands(l, r); err != nil {
+				return nil, err
+			}
+			return compare(op, l, r, !intr.legacyCompare), nil
+		}
+	case ASTOrExpression:
+		left, right := compileNode(node.children[0]), compileNode(node.children[1])
+		return func(intr *treeInterpreter, value interface{}) (interface{}, error) {
+			matched, err := left(intr, value)
+			if err != nil {
+				return nil, err
+			}
+			if isFalse(matched) {
+				return right(intr, value)
+			}
+			return matched, nil
+		}
+	case ASTAndExpression:
+		left, right := compileNode(node.children[0]), compileNode(node.children[1])
+		return func(intr *treeInterpreter, value interface{}) (interface{}, error) {
+			matched, err := left(intr, value)
+			if err != nil {
+				return nil, err
+			}
+			if isFalse(matched) {
+				return matched, nil
+			}
+			return right(intr, value)
+		}
+	case ASTNotExpression:
+		child := compileNode(node.children[0])
+		return func(intr *treeInterpreter, value interface{}) (interface{}, error) {
+			matched, err := child(intr, value)
+			if err != nil {
+				return nil, err
+			}
+			return isFalse(matched), nil
+		}
+	case ASTProjection:
+		return compileProjection(node, compileNode(node.children[0]), compileNode(node.children[1]))
+	case ASTFilterProjection:
+		return compileFilterProjection(node, compileNode(node.children[0]),
+			compileNode(node.children[1]), compilePredicate(node.children[2]))
+	case ASTDescendant:
+		child, key := compileNode(node.children[0]), node.value.(string)
+		return func(intr *treeInterpreter, value interface{}) (interface{}, error) {
+			left, err := child(intr, value)
+			if err != nil {
+				return nil, err
+			}
+			if left, err = intr.resolveAll(left); err != nil {
+				return nil, err
+			}
+			return intr.descendants(key, left), nil
+		}
+	case ASTFlatten:
+		child := compileNode(node.children[0])
+		depth := flattenDepth(node)
+		return func(intr *treeInterpreter, value interface{}) (interface{}, error) {
+			left, err := child(intr, value)
+			if err != nil {
+				return nil, nil
+			}
+			if left, err = intr.resolveNested(left, depth); err != nil {
+				return nil, err
+			}
+			return intr.flattenDepth(left, depth), nil
+		}
+	case ASTMultiSelectList:
+		children := compileChildren(node)
+		return func(intr *treeInterpreter, value interface{}) (interface{}, error) {
+			if value == nil {
+				return nil, nil
+			}
+			collected := make([]interface{}, 0, len(children))
+			for _, child := range children {
+				current, err := child(intr, value)
+				if err != nil {
+					return nil, err
+				}
+				collected = append(collected, current)
+			}
+			return collected, nil
+		}
+	case ASTMultiSelectHash:
+		keys := make([]string, len(node.children))
+		values := make([]evalFunc, len(node.children))
+		for i, child := range node.children {
+			keys[i] = child.value.(string)
+			values[i] = compileNode(child.children[0])
+		}
+		return func(intr *treeInterpreter, value interface{}) (interface{}, error) {
+			if value == nil {
+				return nil, nil
+			}
+			if intr.ordered {
+				return intr.Execute(node, value)
+			}
+			collected := make(map[string]interface{}, len(keys))
+			for i, child := range values {
+				current, err := child(intr, value)
+				if err != nil {
+					return nil, err
+				}
+				collected[keys[i]] = current
+			}
+			return collected, nil
+		}
+	case ASTFunctionExpression:
+		name := node.value.(string)
+		args := compileChildren(node)
+		return func(intr *treeInterpreter, value interface{}) (interface{}, error) {
+			resolvedArgs, err := evalArgs(intr, node, value, func(i int) (interface{}, error) {
+				return args[i](intr, value)
+			})
+			if err != nil {
+				return nil, err
+			}
+			result, err := intr.fCall.CallFunction(name, resolvedArgs, intr)
+			return result, callError(node, err)
+		}
+	}
+	return func(intr *treeInterpreter, value interface{}) (interface{}, error) {
+		return intr.Execute(node, value)
+	}
+}
+
+// compileProjection returns a closure that evaluates the projection node
+// from the already compiled closures for its children.
+func compileProjection(node ASTNode, left, right evalFunc) evalFunc {
+	return func(intr *treeInterpreter, value interface{}) (interface{}, error) {
+		l, err := left(intr, value)
+		if err != nil {
+			return nil, err
+		}
+		if s, ok := slicedString(node, l); ok {
+			return right(intr, s)
+		}
+		if source, ok := l.(DataSource); ok {
+			return intr.querySource(node, source)
+		}
+		if l, err = intr.resolve(l); err != nil {
+			return nil, err
+		}
+		sliceType, ok := l.([]interface{})
+		if !ok {
+			if isSliceType(l) {
+				return intr.projectWithReflection(node, l)
+			}
+			return nil, nil
+		}
+		if intr.parallel(len(sliceType)) {
+			return intr.projectParallel(sliceType, right)
+		}
+		collected := newCollector()
+		for _, element := range sliceType {
+			current, err := right(intr, element)
+			if err != nil {
+				collected.release()
+				return nil, err
+			}
+			if current != nil {
+				collected.add(current)
+			}
+		}
+		return collected.result(), nil
+	}
+}
+
+// compileFilterProjection returns a closure that evaluates the filter
+// projection node from the already compiled closures for its children.
+func compileFilterProjection(node ASTNode, left, right evalFunc, condition predFunc) evalFunc {
+	return func(intr *treeInterpreter, value interface{}) (interface{}, error) {
+		l, err := left(intr, value)
+		if err != nil {
+			return nil, nil
+		}
+		if source, ok := l.(DataSource); ok {
+			return intr.querySource(node, source)
+		}
+		if l, err = intr.resolve(l); err != nil {
+			return nil, err
+		}
+		sliceType, ok := l.([]interface{})
+		if !ok {
+			if isSliceType(l) {
+				return intr.filterProjectionWithReflection(node, l)
+			}
+			return nil, nil
+		}
+		if intr.parallel(len(sliceType)) {
+			return intr.projectParallel(sliceType, func(intr *treeInterpreter, element interface{}) (interface{}, error) {
+				matched, err := condition(intr, element)
+				if err != nil || !matched {
+					return nil, err
+				}
+				return right(intr, element)
+			})
+		}
+		collected := newCollector()
+		for _, element := range sliceType {
+			matched, err := condition(intr, element)
+			if err != nil {
+				collected.release()
+				return nil, err
+			}
+			if !matched {
+				continue
+			}
+			current, err := right(intr, element)
+			if err != nil {
+				collected.release()
+				return nil, err
+			}
+			if current != nil {
+				collected.add(current)
+			}
+		}
+		return collected.result(), nil
+	}
+}
+
+func compileChildren(node ASTNode) []evalFunc {
+	compiled := make([]evalFunc, len(node.children))
+	for i, child := range node.children {
+		compiled[i] = compileNode(child)
+	}
+	return compiled
+}
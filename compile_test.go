@@ -0,0 +1,71 @@
+package jmespath
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+var compiledEngineTests = []string{
+	"foo.bar",
+	"foo[0].bar",
+	"foo[-1]",
+	"foo[*].bar",
+	"foo[?bar > `1`].bar",
+	"foo[].bar | [0]",
+	"foo[*].[bar, baz]",
+	"foo[*].{b: bar}",
+	"length(foo) > `1` && !empty || 'fallback'",
+	"sort_by(foo, &bar)[*].bar",
+	"foo[1:].bar",
+	"*.bar",
+	"nested[][]",
+}
+
+func TestCompiledEngineMatchesInterpreter(t *testing.T) {
+	assert := assert.New(t)
+	var data interface{}
+	err := json.Unmarshal([]byte(`{
+		"foo": [{"bar": 3, "baz": "a"}, {"bar": 1}, {"bar": 2, "baz": "c"}],
+		"empty": [],
+		"nested": [[[1]], [[2, 3]]],
+		"obj": {"bar": 1}
+	}`), &data)
+	assert.Nil(err)
+	structData := sliceType{A: "foo", B: []scalars{{"f1", "b1"}, {"correct", "b2"}}}
+	for _, expression := range compiledEngineTests {
+		for _, input := range []interface{}{data, structData, nil} {
+			parsed, err := NewParser().Parse(expression)
+			assert.Nil(err)
+			expected, expectedErr := newInterpreter().Execute(parsed, input)
+			expectedErr = locateError(expression, expectedErr)
+			actual, actualErr := MustCompile(expression).Search(input)
+			assert.Equal(expectedErr, actualErr, expression)
+			assert.Equal(expected, actual, expression)
+		}
+	}
+}
+
+func BenchmarkCompiledNestedMaps(b *testing.B) {
+	var data interface{}
+	json.Unmarshal([]byte(`{"a": [{"b": {"c": 1}}, {"b": {"c": 2}}, {"b": {"c": 3}}]}`), &data)
+	jp := MustCompile("a[?b.c > `1`].b.c")
+	for i := 0; i < b.N; i++ {
+		if _, err := jp.Search(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkInterpretedNestedMaps(b *testing.B) {
+	var data interface{}
+	json.Unmarshal([]byte(`{"a": [{"b": {"c": 1}}, {"b": {"c": 2}}, {"b": {"c": 3}}]}`), &data)
+	ast, _ := NewParser().Parse("a[?b.c > `1`].b.c")
+	intr := newInterpreter()
+	for i := 0; i < b.N; i++ {
+		if _, err := intr.Execute(ast, data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package jmespath
+
+import (
+	"sort"
+	"strings"
+)
+
+/* Completions are found by lexing the expression up to the cursor.  The
+   token before the cursor tells whether an operator or an expression is
+   expected there.  To find the fields an expression may continue with,
+   the partial word at the cursor is replaced by a placeholder field, the
+   brackets left open are closed, and the result is parsed and evaluated
+   against the sample document, or checked against the schema, to find the
+   values the placeholder would be read from.
+*/
+
+// completionPlaceholder is the field name standing for the word being
+// completed.
+const completionPlaceholder = "jmespathCompletionPlaceholder"
+
+// CompletionKind is the kind of a completion candidate.
+type CompletionKind int
+
+const (
+	// CompletionField is a field name, quoted if it needs to be.
+	CompletionField CompletionKind = iota
+	// CompletionFunction is the name of a function.
+	CompletionFunction
+	// CompletionOperator is an operator, such as "|" or "[?".
+	CompletionOperator
+)
+
+// Completion is a candidate to complete an expression with, as returned by
+// Complete.
+type Completion struct {
+	Kind CompletionKind
+	// Text replaces the expression from Start to the cursor.
+	Text string
+	// Detail is the JSON type of a field, the signature of a function,
+	// such as "length(string|array|object)", or a description of an
+	// operator.
+	Detail string
+	// Start is the offset of the partial word the candidate completes, or
+	// the cursor if there is none.
+	Start int
+}
+
+// operatorCompletions are the operators that may follow an expression.
+var operatorCompletions = []Completion{
+	{Text: ".", Detail: "subexpression"},
+	{Text: "[", Detail: "index, slice or multiselect list"},
+	{Text: "[*]", Detail: "projection"},
+	{Text: "[]", Detail: "flatten"},
+	{Text: "[?", Detail: "filter"},
+	{Text: "|", Detail: "pipe"},
+	{Text: "||", Detail: "or"},
+	{Text: "&&", Detail: "and"},
+	{Text: "==", Detail: "equal"},
+	{Text: "!=", Detail: "not equal"},
+	{Text: "<", Detail: "less than"},
+	{Text: "<=", Detail: "less than or equal"},
+	{Text: ">", Detail: "greater than"},
+	{Text: ">=", Detail: "greater than or equal"},
+	{Text: "in", Detail: "membership"},
+	{Text: "not in", Detail: "non-membership"},
+}
+
+// Complete returns the candidates to complete expression with at cursor,
+// a byte offset in expression, for editors.  Where an expression may
+// start or continue after a ".", the candidates are the functions and the
+// fields of the values the expression would read there in document, or of
+// the objects schema describes there, either of which may be nil.  After
+// an expression, they are the operators that may follow it.  Only the
+// candidates starting with the partial word before the cursor, if any,
+// are returned, sorted by kind and then text.
+func Complete(expression string, cursor int, document interface{}, schema *Schema) ([]Completion, error) {
+	return NewRuntime().Complete(expression, cursor, document, schema)
+}
+
+// Complete is like the package-level Complete, but completes the functions
+// available in this runtime and evaluates document with its settings.
+func (rt *Runtime) Complete(expression string, cursor int, document interface{}, schema *Schema) (_ []Completion, err error) {
+	defer recoverInternal(&err)
+	if cursor < 0 || cursor > len(expression) {
+		cursor = len(expression)
+	}
+	prefix := expression[:cursor]
+	tokens, err := NewLexer().tokenize(prefix)
+	if err != nil {
+		// The cursor is within a literal or a quoted identifier.
+		return nil, nil
+	}
+	tokens = membershipOperators(tokens[:len(tokens)-1]) // without tEOF
+	partial, start := "", cursor
+	if n := len(tokens); n > 0 && tokens[n-1].tokenType == tUnquotedIdentifier &&
+		tokens[n-1].position+tokens[n-1].length == cursor {
+		partial, start = tokens[n-1].value, tokens[n-1].position
+		tokens = tokens[:n-1]
+	}
+	var completions []Completion
+	switch previous := lastTokenType(tokens); {
+	case previous == tDotDot, previous == tNumber, previous == tColon && innermostOpen(tokens) != tLbrace,
+		previous == tStar && followsLbracket(tokens):
+		return nil, nil
+	case previous == tFlatten || endsExpression(previous):
+		completions = append(completions, operatorCompletions...)
+		if rt.recursiveDescentEnabled() {
+			completions = append(completions, Completion{Text: "..", Detail: "recursive descent"})
+		}
+		for i := range completions {
+			completions[i].Kind = CompletionOperator
+		}
+	default:
+		intr := rt.newInterpreter()
+		fields := rt.completeFields(prefix[:start]+completionPlaceholder+closeBrackets(tokens), intr, document, schema)
+		completions = append(fields, functionCompletions(intr.fCall)...)
+	}
+	var result []Completion
+	for _, completion := range completions {
+		if strings.HasPrefix(completion.Text, partial) {
+			completion.Start = start
+			result = append(result, completion)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Kind != result[j].Kind {
+			return result[i].Kind < result[j].Kind
+		}
+		return result[i].Text < result[j].Text
+	})
+	return result, nil
+}
+
+func (rt *Runtime) recursiveDescentEnabled() bool {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	return rt.descent
+}
+
+// completeFields returns the fields the placeholder field of expression
+// may be read from in document or according to schema.
+func (rt *Runtime) completeFields(expression string, intr *treeInterpreter, document interface{}, schema *Schema) []Completion {
+	ast, err := rt.newParser(intr).Parse(expression)
+	if err != nil {
+		return nil
+	}
+	fields := make(map[string]string)
+	if document != nil {
+		w := &walker{intr: intr, enter: func(node *ASTNode, value interface{}) {
+			if node.nodeType != ASTField || node.value != completionPlaceholder {
+				return
+			}
+			if object, ok := intr.toObject(value); ok {
+				for key, v := range object {
+					if _, ok := fields[key]; !ok {
+						fields[key] = jsonType(v)
+					}
+				}
+			}
+		}}
+		// The fields read before an error are still completed.
+		w.eval(&ast, document)
+	}
+	if schema != nil {
+		c := schemaChecker{schema: schema, functions: intr.fCall, offsets: map[string]int{}}
+		c.onField = func(name string, current schemaShape) {
+			if name != completionPlaceholder {
+				return
+			}
+			for _, alternative := range current {
+				properties, _ := alternative["properties"].(map[string]interface{})
+				for key, value := range properties {
+					if _, ok := fields[key]; !ok {
+						fields[key] = describeShape(c.alternatives(value, 0))
+					}
+				}
+			}
+		}
+		c.check(ast, "", c.alternatives(schema.root, 0))
+	}
+	var completions []Completion
+	for name, detail := range fields {
+		completions = append(completions, Completion{Kind: CompletionField, Text: formatIdentifier(name), Detail: detail})
+	}
+	return completions
+}
+
+// functionCompletions returns the functions of caller with their
+// signatures.
+func functionCompletions(caller *functionCaller) []Completion {
+	var completions []Completion
+	for name, entry := range caller.functionTable {
+		completions = append(completions, Completion{
+			Kind:   CompletionFunction,
+			Text:   name,
+			Detail: entry.signature(name).String(),
+		})
+	}
+	return completions
+}
+
+func lastTokenType(tokens []token) tokType {
+	if len(tokens) == 0 {
+		return tEOF
+	}
+	return tokens[len(tokens)-1].tokenType
+}
+
+// openBrackets returns the types of the brackets, braces and parentheses
+// left open by tokens, innermost last.  Filters count as brackets.
+func openBrackets(tokens []token) []tokType {
+	var open []tokType
+	for _, t := range tokens {
+		switch t.tokenType {
+		case tLbracket, tFilter, tLbrace, tLparen:
+			open = append(open, t.tokenType)
+		case tRbracket, tRbrace, tRparen:
+			if len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+		}
+	}
+	return open
+}
+
+func innermostOpen(tokens []token) tokType {
+	open := openBrackets(tokens)
+	if len(open) == 0 {
+		return tEOF
+	}
+	return open[len(open)-1]
+}
+
+// followsLbracket reports whether the last token follows a "[", as the
+// "*" of "[*" does, which does not end an expression.
+func followsLbracket(tokens []token) bool {
+	return len(tokens) > 1 && tokens[len(tokens)-2].tokenType == tLbracket
+}
+
+// closeBrackets returns the text closing the brackets tokens leave open.
+func closeBrackets(tokens []token) string {
+	var closing strings.Builder
+	open := openBrackets(tokens)
+	for i := len(open) - 1; i >= 0; i-- {
+		switch open[i] {
+		case tLbrace:
+			closing.WriteString("}")
+		case tLparen:
+			closing.WriteString(")")
+		default:
+			closing.WriteString("]")
+		}
+	}
+	return closing.String()
+}
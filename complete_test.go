@@ -0,0 +1,129 @@
+package jmespath
+
+import (
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+// completionTexts returns the texts of the completions of the given kind.
+func completionTexts(completions []Completion, kind CompletionKind) []string {
+	var texts []string
+	for _, completion := range completions {
+		if completion.Kind == kind {
+			texts = append(texts, completion.Text)
+		}
+	}
+	return texts
+}
+
+func TestCompleteFieldsFromDocument(t *testing.T) {
+	assert := assert.New(t)
+	data := decodeJSON(`{
+		"instances": [
+			{"state": "running", "id": "i-1", "tags": {"env": "prod"}},
+			{"state": "stopped", "id": "i-2", "stateReason": "user", "first name": "x"}
+		],
+		"stats": {"count": 2}
+	}`)
+	cases := []struct {
+		expression string
+		expected   []string
+	}{
+		{"", []string{"instances", "stats"}},
+		{"st", []string{"stats"}},
+		{"stats.", []string{"count"}},
+		{"instances[*].st", []string{"state", "stateReason"}},
+		{"instances[0].", []string{"id", "state", "tags"}},
+		{"instances[].", []string{"\"first name\"", "id", "state", "stateReason", "tags"}},
+		{"instances[?", []string{"\"first name\"", "id", "state", "stateReason", "tags"}},
+		{"instances[?state == 'running'].tags.", []string{"env"}},
+		{"instances | [0].", []string{"id", "state", "tags"}},
+		{"length(st", []string{"stats"}},
+		{"{n: stats.c", []string{"count"}},
+		{"stats.missing.", nil},
+	}
+	for _, tt := range cases {
+		completions, err := Complete(tt.expression, len(tt.expression), data, nil)
+		assert.Nil(err, tt.expression)
+		assert.Equal(tt.expected, completionTexts(completions, CompletionField), tt.expression)
+	}
+}
+
+func TestCompleteFieldsFromSchema(t *testing.T) {
+	assert := assert.New(t)
+	schema, err := NewSchema([]byte(testSchema))
+	assert.Nil(err)
+	completions, err := Complete("instance.st", 11, nil, schema)
+	assert.Nil(err)
+	assert.Equal(Completion{Kind: CompletionField, Text: "state", Detail: "string", Start: 9}, completions[0])
+	assert.Equal([]string{"starts_with"}, completionTexts(completions, CompletionFunction))
+
+	completions, err = Complete("sort_by(instances, &", 20, nil, schema)
+	assert.Nil(err)
+	assert.Equal([]string{"id", "ports", "state"}, completionTexts(completions, CompletionField))
+	assert.Equal("ports", completions[1].Text)
+	assert.Equal("array", completions[1].Detail)
+}
+
+func TestCompleteFunctions(t *testing.T) {
+	assert := assert.New(t)
+	completions, err := Complete("sort", 4, nil, nil)
+	assert.Nil(err)
+	assert.Equal([]Completion{
+		{Kind: CompletionFunction, Text: "sort", Detail: "sort(array[string]|array[number], [string])", Start: 0},
+		{Kind: CompletionFunction, Text: "sort_by", Detail: "sort_by(array, expref, [string])", Start: 0},
+	}, completions)
+	completions, err = Complete("merge", 5, nil, nil)
+	assert.Nil(err)
+	assert.Equal("merge(object...)", completions[0].Detail)
+
+	rt := NewRuntime()
+	assert.Nil(rt.RegisterFunction("shout", func(ctx CallContext, args []interface{}) (interface{}, error) {
+		return args[0], nil
+	}))
+	completions, err = rt.Complete("a.sh", 4, nil, nil)
+	assert.Nil(err)
+	assert.Equal([]Completion{{Kind: CompletionFunction, Text: "shout", Detail: "shout(...)", Start: 2}}, completions)
+}
+
+func TestCompleteOperators(t *testing.T) {
+	assert := assert.New(t)
+	completions, err := Complete("foo ", 4, nil, nil)
+	assert.Nil(err)
+	texts := completionTexts(completions, CompletionOperator)
+	assert.Contains(texts, "|")
+	assert.Contains(texts, "[?")
+	assert.NotContains(texts, "..")
+	assert.Empty(completionTexts(completions, CompletionField))
+	assert.Equal(4, completions[0].Start)
+
+	completions, err = Complete("status i", 8, nil, nil)
+	assert.Nil(err)
+	assert.Equal([]Completion{{Kind: CompletionOperator, Text: "in", Detail: "membership", Start: 7}}, completions)
+
+	rt := NewRuntime()
+	rt.SetRecursiveDescent(true)
+	completions, err = rt.Complete("foo[0]", 6, nil, nil)
+	assert.Nil(err)
+	assert.Contains(completionTexts(completions, CompletionOperator), "..")
+}
+
+func TestCompleteCursor(t *testing.T) {
+	assert := assert.New(t)
+	data := decodeJSON(`{"a": {"b": 1, "bc": 2}, "c": 3}`)
+	completions, err := Complete("a.b | c", 3, data, nil)
+	assert.Nil(err)
+	assert.Equal([]string{"b", "bc"}, completionTexts(completions, CompletionField))
+	assert.Equal(2, completions[0].Start)
+
+	completions, err = Complete("status in ", 10, data, nil)
+	assert.Nil(err)
+	assert.Equal([]string{"a", "c"}, completionTexts(completions, CompletionField))
+
+	for _, expression := range []string{"'abc", "foo[0", "foo[*", "foo[1:"} {
+		completions, err := Complete(expression, len(expression), data, nil)
+		assert.Nil(err, expression)
+		assert.Empty(completions, expression)
+	}
+}
@@ -0,0 +1,259 @@
+//go:build go1.16
+// +build go1.16
+
+/*
+Package compliance runs the JMESPath compliance test suite against an
+implementation of JMESPath search, so that forks and runtimes with
+registered or overridden functions can check that they still follow the
+specification.
+
+The test files are embedded in the package.  The files at the top level
+are the official compliance tests of the specification, and the files in
+the "extensions" directory test the functions go-jmespath provides in
+addition to the ones defined by the specification.  To update to a new
+version of the specification, replace the top level files with the ones of
+the jmespath.test repository.
+
+A runtime is checked with a test such as
+
+	func TestCompliance(t *testing.T) {
+		rt := jmespath.NewRuntime()
+		rt.RegisterFunction(...)
+		compliance.Test(t, rt.Search, compliance.SpecFiles()...)
+	}
+
+and a JSON decoder plugged in with Runtime.SetDecoder is checked by
+passing rt.SearchBytes to TestBytes.
+*/
+package compliance
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+//go:embed *.json extensions/*.json
+var files embed.FS
+
+// SearchFunc evaluates expression against data, as jmespath.Search and
+// Runtime.Search do.
+type SearchFunc func(expression string, data interface{}) (interface{}, error)
+
+// SearchBytesFunc decodes the JSON document data and evaluates expression
+// against it, as jmespath.SearchBytes and Runtime.SearchBytes do.  It is
+// used to run the test suite against a plugged in JSON decoder as well.
+type SearchBytesFunc func(expression string, data []byte) (interface{}, error)
+
+// Suite is a group of test cases sharing the document they are evaluated
+// against.
+type Suite struct {
+	Given   interface{}
+	Comment string
+	Cases   []Case
+}
+
+// Case is a single compliance test.  It expects Expression either to
+// evaluate to Result or, if Error is set, to fail.
+type Case struct {
+	Comment    string
+	Expression string
+	Result     interface{}
+	// Error is the kind of error the specification requires, such as
+	// "syntax" or "invalid-type".  Any error is accepted for it.
+	Error string
+}
+
+// Failure describes a case that failed.
+type Failure struct {
+	File   string
+	Given  interface{}
+	Case   Case
+	Result interface{} // The result of the search.
+	Err    error       // The error of the search.
+}
+
+func (f Failure) String() string {
+	if f.Case.Error != "" {
+		return fmt.Sprintf("%s: %s: expected %s error, got result %s", f.File, f.Case.Expression, f.Case.Error, toJSON(f.Result))
+	}
+	if f.Err != nil {
+		return fmt.Sprintf("%s: %s: expected %s, got error: %s", f.File, f.Case.Expression, toJSON(f.Case.Result), f.Err)
+	}
+	return fmt.Sprintf("%s: %s: expected %s, got %s", f.File, f.Case.Expression, toJSON(f.Case.Result), toJSON(f.Result))
+}
+
+// Files returns the names of all embedded test files, such as
+// "basic.json" and "extensions/functions.json", in sorted order.
+func Files() []string {
+	var names []string
+	fs.WalkDir(files, ".", func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			names = append(names, path)
+		}
+		return err
+	})
+	sort.Strings(names)
+	return names
+}
+
+// SpecFiles returns the names of the embedded files of the official
+// compliance test suite, leaving out the tests of extension functions.
+func SpecFiles() []string {
+	var names []string
+	for _, name := range Files() {
+		if !strings.Contains(name, "/") {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Load returns the suites of the embedded test file name.
+func Load(name string) ([]Suite, error) {
+	data, err := files.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var suites []Suite
+	if err := json.Unmarshal(data, &suites); err != nil {
+		return nil, fmt.Errorf("%s: %s", name, err)
+	}
+	return suites, nil
+}
+
+// Run runs the cases of the named test files, or of all files if no names
+// are given, against search and returns the cases that failed.  Results
+// are compared as JSON, so search may return any value that encodes to
+// the expected JSON, such as a *jmespath.OrderedMap.
+func Run(search SearchFunc, names ...string) ([]Failure, error) {
+	return run(func(expression string, given interface{}) (interface{}, error) {
+		return search(expression, copyJSON(given))
+	}, names)
+}
+
+// RunBytes is like Run, but passes the documents to search as JSON.
+func RunBytes(search SearchBytesFunc, names ...string) ([]Failure, error) {
+	return run(func(expression string, given interface{}) (interface{}, error) {
+		data, err := json.Marshal(given)
+		if err != nil {
+			return nil, err
+		}
+		return search(expression, data)
+	}, names)
+}
+
+func run(search SearchFunc, names []string) ([]Failure, error) {
+	if len(names) == 0 {
+		names = Files()
+	}
+	var failures []Failure
+	for _, name := range names {
+		suites, err := Load(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, suite := range suites {
+			for _, c := range suite.Cases {
+				if failure, failed := runCase(search, name, suite.Given, c); failed {
+					failures = append(failures, failure)
+				}
+			}
+		}
+	}
+	return failures, nil
+}
+
+// Test runs the named test files, or all files if no names are given,
+// against search as subtests of t, one per file.
+func Test(t *testing.T, search SearchFunc, names ...string) {
+	test(t, func(name string) ([]Failure, error) {
+		return Run(search, name)
+	}, names)
+}
+
+// TestBytes is like Test, but passes the documents to search as JSON.
+func TestBytes(t *testing.T, search SearchBytesFunc, names ...string) {
+	test(t, func(name string) ([]Failure, error) {
+		return RunBytes(search, name)
+	}, names)
+}
+
+func test(t *testing.T, run func(name string) ([]Failure, error), names []string) {
+	if len(names) == 0 {
+		names = Files()
+	}
+	for _, name := range names {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			failures, err := run(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, failure := range failures {
+				t.Error(failure)
+			}
+		})
+	}
+}
+
+func runCase(search SearchFunc, name string, given interface{}, c Case) (failure Failure, failed bool) {
+	failure = Failure{File: name, Given: given, Case: c}
+	defer func() {
+		if r := recover(); r != nil {
+			failure.Err = fmt.Errorf("panic: %v", r)
+			failed = true
+		}
+	}()
+	failure.Result, failure.Err = search(c.Expression, given)
+	if c.Error != "" {
+		return failure, failure.Err == nil
+	}
+	return failure, failure.Err != nil || !equalJSON(c.Result, failure.Result)
+}
+
+// copyJSON returns a deep copy of a document decoded by encoding/json, so
+// that a search modifying its input cannot affect other cases.
+func copyJSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, element := range v {
+			copied[key] = copyJSON(element)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, element := range v {
+			copied[i] = copyJSON(element)
+		}
+		return copied
+	}
+	return value
+}
+
+// equalJSON reports whether actual encodes to the JSON value expected.
+func equalJSON(expected, actual interface{}) bool {
+	data, err := json.Marshal(actual)
+	if err != nil {
+		return false
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(expected, decoded)
+}
+
+func toJSON(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%#v", value)
+	}
+	return string(data)
+}
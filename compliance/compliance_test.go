@@ -0,0 +1,69 @@
+//go:build go1.16
+// +build go1.16
+
+package compliance_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jmespath/go-jmespath"
+	"github.com/jmespath/go-jmespath/compliance"
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func TestSearch(t *testing.T) {
+	compliance.Test(t, jmespath.Search)
+}
+
+func TestSearchBytes(t *testing.T) {
+	compliance.TestBytes(t, jmespath.SearchBytes)
+}
+
+func TestCompiledOrderedRuntime(t *testing.T) {
+	rt := jmespath.NewRuntime()
+	rt.SetOrderedObjects(true)
+	compliance.Test(t, func(expression string, data interface{}) (interface{}, error) {
+		jp, err := rt.Compile(expression)
+		if err != nil {
+			return nil, err
+		}
+		return jp.Search(data)
+	})
+}
+
+func TestFiles(t *testing.T) {
+	assert := assert.New(t)
+	assert.Contains(compliance.Files(), "extensions/functions.json")
+	assert.Contains(compliance.SpecFiles(), "basic.json")
+	assert.NotContains(compliance.SpecFiles(), "extensions/functions.json")
+
+	suites, err := compliance.Load("basic.json")
+	assert.Nil(err)
+	assert.Equal("foo", suites[0].Cases[0].Expression)
+	_, err = compliance.Load("missing.json")
+	assert.NotNil(err)
+}
+
+func TestRunReportsFailures(t *testing.T) {
+	assert := assert.New(t)
+	failures, err := compliance.Run(func(expression string, data interface{}) (interface{}, error) {
+		if strings.HasPrefix(expression, "foo.bar") {
+			return nil, errors.New("unsupported")
+		}
+		return jmespath.Search(expression, data)
+	}, "basic.json")
+	assert.Nil(err)
+	if assert.NotEmpty(failures) {
+		assert.Equal("basic.json", failures[0].File)
+		assert.Equal("foo.bar", failures[0].Case.Expression)
+		assert.Equal(`basic.json: foo.bar: expected {"baz":"correct"}, got error: unsupported`, failures[0].String())
+	}
+
+	failures, err = compliance.Run(func(expression string, data interface{}) (interface{}, error) {
+		panic("broken")
+	}, "syntax.json")
+	assert.Nil(err)
+	assert.NotEmpty(failures)
+}
@@ -40,6 +40,7 @@ var whiteListed = []string{
 	"compliance/unicode.json",
 	"compliance/wildcard.json",
 	"compliance/boolean.json",
+	"compliance/extensions/functions.json",
 }
 
 func allowed(path string) bool {
@@ -51,6 +52,26 @@ func allowed(path string) bool {
 	return false
 }
 
+// forEachComplianceCase calls each with the cases of the compliance test
+// suite that do not expect an error, the document they are evaluated
+// against and a message identifying the case.
+func forEachComplianceCase(assert *assert.Assertions, each func(given interface{}, testcase TestCase, msg string)) {
+	for _, filename := range whiteListed {
+		var testSuites []TestSuite
+		data, err := ioutil.ReadFile(filename)
+		if !assert.Nil(err) || !assert.Nil(json.Unmarshal(data, &testSuites)) {
+			continue
+		}
+		for _, suite := range testSuites {
+			for _, testcase := range suite.TestCases {
+				if testcase.Error == "" {
+					each(suite.Given, testcase, fmt.Sprintf("(%s) Expression: %s", filename, testcase.Expression))
+				}
+			}
+		}
+	}
+}
+
 func TestCompliance(t *testing.T) {
 	assert := assert.New(t)
 
@@ -0,0 +1,193 @@
+package jmespath
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+// These tests are most useful when run with the race detector:
+//
+//	go test -race -run Concurrent
+
+const concurrentWorkers = 8
+
+func TestConcurrentSearchOnCompiledExpression(t *testing.T) {
+	assert := assert.New(t)
+	var data interface{}
+	err := json.Unmarshal([]byte(`{"items": [{"n": 3, "tags": ["a"]}, {"n": 1, "tags": ["b", "c"]}, {"n": 2}]}`), &data)
+	assert.Nil(err)
+	jp := MustCompile("{sorted: sort_by(items, &n)[*].n, tags: items[].tags[], big: items[?n > `1`] | length(@)}")
+	expected, err := jp.Search(data)
+	assert.Nil(err)
+
+	var wg sync.WaitGroup
+	results := make(chan interface{}, concurrentWorkers*50)
+	for i := 0; i < concurrentWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				result, err := jp.Search(data)
+				if err != nil {
+					results <- err
+					continue
+				}
+				if _, err := jp.Match(data); err != nil {
+					results <- err
+					continue
+				}
+				results <- result
+			}
+		}()
+	}
+	wg.Wait()
+	close(results)
+	for result := range results {
+		assert.Equal(expected, result)
+	}
+}
+
+func TestConcurrentRuntimeChangesDoNotAffectCompiledExpressions(t *testing.T) {
+	assert := assert.New(t)
+	rt := NewRuntime()
+	err := rt.RegisterFunction("answer", func(ctx CallContext, args []interface{}) (interface{}, error) {
+		return 42.0, nil
+	})
+	assert.Nil(err)
+	jp, err := rt.Compile("answer()")
+	assert.Nil(err)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, concurrentWorkers*2)
+	for i := 0; i < concurrentWorkers; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				name := fmt.Sprintf("f%d_%d", i, j)
+				if err := rt.RegisterFunction(name, func(ctx CallContext, args []interface{}) (interface{}, error) {
+					return name, nil
+				}); err != nil {
+					errs <- err
+					return
+				}
+				rt.SetMultiValueMode(MultiValueMode(j % 2))
+				if _, err := rt.Search(name+"()", nil); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				result, err := jp.Search(nil)
+				if err != nil || result != 42.0 {
+					errs <- fmt.Errorf("unexpected result %v: %v", result, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.Nil(err)
+	}
+
+	// Functions registered after compiling are not visible to the
+	// compiled expression.
+	_, err = rt.Compile("f0_0()")
+	assert.Nil(err)
+	assert.Nil(rt.RegisterFunction("late", func(ctx CallContext, args []interface{}) (interface{}, error) {
+		return nil, nil
+	}))
+	_, err = jp.intr.fCall.CallFunction("late", nil, jp.intr)
+	assert.NotNil(err)
+}
+
+// tenantRuntime returns a Runtime configured differently for each tenant:
+// tenant() returns the name of the tenant, and slow() sleeps for 20ms
+// under a time limit that only the tenant "b" sets.
+func tenantRuntime(t *testing.T, name string) *Runtime {
+	rt := NewRuntime()
+	assert.Nil(t, rt.RegisterFunction("tenant", func(ctx CallContext, args []interface{}) (interface{}, error) {
+		return name, nil
+	}))
+	assert.Nil(t, rt.RegisterFunction("slow", func(ctx CallContext, args []interface{}) (interface{}, error) {
+		time.Sleep(20 * time.Millisecond)
+		return name, nil
+	}))
+	if name == "b" {
+		assert.Nil(t, rt.SetFunctionTimeout("slow", time.Millisecond))
+	} else {
+		rt.SetMultiValueMode(MultiValueFirst)
+		rt.SetOrderedObjects(true)
+	}
+	return rt
+}
+
+func TestConcurrentRuntimesAreIsolated(t *testing.T) {
+	assert := assert.New(t)
+	data := map[string]interface{}{"q": map[string][]string{"k": {"1", "2"}}}
+	tenants := map[string]*Runtime{"a": tenantRuntime(t, "a"), "b": tenantRuntime(t, "b")}
+	check := func(name string, rt *Runtime) error {
+		result, err := rt.Search("tenant()", nil)
+		if err != nil || result != name {
+			return fmt.Errorf("%s: tenant() returned %v: %v", name, result, err)
+		}
+		result, err = rt.Search("q.k", data)
+		if expected := map[string]interface{}{"a": "1", "b": []interface{}{"1", "2"}}[name]; err != nil || !reflect.DeepEqual(expected, result) {
+			return fmt.Errorf("%s: q.k returned %v: %v", name, result, err)
+		}
+		result, err = rt.Search("{x: tenant()}", data)
+		if _, ordered := result.(*OrderedMap); err != nil || ordered != (name == "a") {
+			return fmt.Errorf("%s: multiselect hash returned %T: %v", name, result, err)
+		}
+		result, err = rt.Search("slow()", nil)
+		if timedOut := errors.Is(err, ErrFunctionTimeout); timedOut != (name == "b") {
+			return fmt.Errorf("%s: slow() returned %v: %v", name, result, err)
+		}
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, concurrentWorkers*len(tenants))
+	for i := 0; i < concurrentWorkers; i++ {
+		for name, rt := range tenants {
+			wg.Add(1)
+			go func(name string, rt *Runtime) {
+				defer wg.Done()
+				for j := 0; j < 5; j++ {
+					if err := check(name, rt); err != nil {
+						errs <- err
+						return
+					}
+				}
+			}(name, rt)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.Nil(err)
+	}
+
+	// Neither tenant's configuration is visible to the package level
+	// functions.
+	_, err := Search("tenant()", nil)
+	assert.NotNil(err)
+	result, err := Search("q.k", data)
+	assert.Nil(err)
+	assert.Equal([]interface{}{"1", "2"}, result)
+	result, err = Search("{x: q}", data)
+	assert.Nil(err)
+	assert.IsType(map[string]interface{}{}, result)
+}
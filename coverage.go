@@ -0,0 +1,118 @@
+package jmespath
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Coverage records which parts of an expression are exercised while
+// searching a corpus of documents, such as a test suite for a stored
+// expression.  A node of the expression that is never evaluated is dead
+// for that corpus: for example the right side of an "||" whose left side
+// is always truthy, or the projected expression of a filter that never
+// matches.
+//
+// The contents of expression references, "&expr", are evaluated by the
+// functions they are passed to and are not tracked.  A Coverage is not
+// safe for concurrent use.
+type Coverage struct {
+	jp     *JMESPath
+	walker walker
+	counts map[*ASTNode]*NodeCoverage
+	order  []*NodeCoverage
+}
+
+// NodeCoverage is the coverage of a single node of an expression.
+type NodeCoverage struct {
+	// Depth is the depth of the node in the expression, zero for the
+	// root.
+	Depth int
+	// Label names the role of the node in its parent, such as "left"
+	// or "condition", and is empty if the role is obvious.
+	Label string
+	// Description describes the node, as in ExplainPlan.
+	Description string
+	// Evaluated is the number of times the node was evaluated.
+	Evaluated int
+	// Truthy is the number of times the node evaluated to a truthy
+	// value.
+	Truthy int
+}
+
+// NewCoverage returns a Coverage for jp in which no node has been
+// evaluated.
+func NewCoverage(jp *JMESPath) *Coverage {
+	c := &Coverage{jp: jp, counts: make(map[*ASTNode]*NodeCoverage)}
+	c.walker = walker{intr: jp.intr, exit: c.record}
+	c.register(&jp.ast, "", 0)
+	return c
+}
+
+func (c *Coverage) register(node *ASTNode, label string, depth int) {
+	nc := &NodeCoverage{Depth: depth, Label: label, Description: describeNode(*node)}
+	c.counts[node] = nc
+	c.order = append(c.order, nc)
+	if node.nodeType == ASTExpRef {
+		return
+	}
+	for i := range node.children {
+		c.register(&node.children[i], childLabel(*node, i), depth+1)
+	}
+}
+
+// Search evaluates the expression against data, like JMESPath.Search, and
+// records the nodes it evaluated.
+func (c *Coverage) Search(data interface{}) (result interface{}, err error) {
+	defer recoverInternal(&err)
+	result, err = c.walker.eval(&c.jp.ast, data)
+	return result, locateError(c.jp.expression, err)
+}
+
+func (c *Coverage) record(node *ASTNode, value, result interface{}, err error) (interface{}, error) {
+	nc := c.counts[node]
+	nc.Evaluated++
+	if err == nil && !isFalse(result) {
+		nc.Truthy++
+	}
+	return result, err
+}
+
+// Nodes returns the coverage of every node of the expression in the order
+// they are listed by String.
+func (c *Coverage) Nodes() []NodeCoverage {
+	nodes := make([]NodeCoverage, len(c.order))
+	for i, nc := range c.order {
+		nodes[i] = *nc
+	}
+	return nodes
+}
+
+// Unexercised returns the coverage of the nodes that were never evaluated.
+func (c *Coverage) Unexercised() []NodeCoverage {
+	var nodes []NodeCoverage
+	for _, nc := range c.order {
+		if nc.Evaluated == 0 {
+			nodes = append(nodes, *nc)
+		}
+	}
+	return nodes
+}
+
+// String returns a report listing every node of the expression, indented
+// as in ExplainPlan, with the number of times it was evaluated and the
+// number of times it was truthy.
+func (c *Coverage) String() string {
+	var b strings.Builder
+	for _, nc := range c.order {
+		b.WriteString(strings.Repeat("  ", nc.Depth))
+		if nc.Label != "" {
+			b.WriteString(nc.Label + ": ")
+		}
+		fmt.Fprintf(&b, "%s (evaluated=%d, truthy=%d)", nc.Description, nc.Evaluated, nc.Truthy)
+		if nc.Evaluated == 0 {
+			b.WriteString(" NOT EXERCISED")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,80 @@
+package jmespath
+
+import (
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func TestCoverageReportsUntakenBranches(t *testing.T) {
+	assert := assert.New(t)
+	coverage := NewCoverage(MustCompile("items[?price > `100`].name || fallback"))
+	docs := []interface{}{
+		map[string]interface{}{"items": []interface{}{
+			map[string]interface{}{"name": "a", "price": 10.0},
+			map[string]interface{}{"name": "b", "price": 20.0},
+		}},
+		map[string]interface{}{"items": []interface{}{}},
+	}
+	for _, doc := range docs {
+		_, err := coverage.Search(doc)
+		assert.Nil(err)
+	}
+	var unexercised []string
+	for _, nc := range coverage.Unexercised() {
+		unexercised = append(unexercised, nc.Label+": "+nc.Description)
+	}
+	assert.Equal([]string{`each: Field "name"`}, unexercised)
+
+	nodes := coverage.Nodes()
+	assert.Equal("Or, short-circuiting on a truthy left side", nodes[0].Description)
+	assert.Equal(2, nodes[0].Evaluated)
+	// The filter never matches, so the fallback is always taken.
+	assert.Equal(`right: Field "fallback"`, nodes[len(nodes)-1].Label+": "+nodes[len(nodes)-1].Description)
+	assert.Equal(2, nodes[len(nodes)-1].Evaluated)
+	assert.Contains(coverage.String(), "NOT EXERCISED")
+}
+
+func TestCoverageCountsTruthyResults(t *testing.T) {
+	assert := assert.New(t)
+	coverage := NewCoverage(MustCompile("a && b"))
+	for _, doc := range []interface{}{
+		map[string]interface{}{"a": true, "b": true},
+		map[string]interface{}{"a": true, "b": false},
+		map[string]interface{}{"a": false},
+	} {
+		_, err := coverage.Search(doc)
+		assert.Nil(err)
+	}
+	nodes := coverage.Nodes()
+	assert.Equal(3, nodes[1].Evaluated)
+	assert.Equal(2, nodes[1].Truthy)
+	assert.Equal(2, nodes[2].Evaluated)
+	assert.Equal(1, nodes[2].Truthy)
+	assert.Empty(coverage.Unexercised())
+}
+
+func TestCoverageDoesNotTrackExpressionReferences(t *testing.T) {
+	assert := assert.New(t)
+	coverage := NewCoverage(MustCompile("sort_by(@, &a)"))
+	_, err := coverage.Search([]interface{}{})
+	assert.Nil(err)
+	assert.Len(coverage.Nodes(), 3)
+	assert.Empty(coverage.Unexercised())
+}
+
+// TestCoverageMatchesSearch checks that searching through a Coverage gives
+// the same results as Search for the compliance test suite.
+func TestCoverageMatchesSearch(t *testing.T) {
+	assert := assert.New(t)
+	forEachComplianceCase(assert, func(given interface{}, testcase TestCase, msg string) {
+		jp, err := Compile(testcase.Expression)
+		if !assert.Nil(err, msg) {
+			return
+		}
+		actual, err := NewCoverage(jp).Search(given)
+		if assert.Nil(err, msg) {
+			assert.Equal(testcase.Result, actual, msg)
+		}
+	})
+}
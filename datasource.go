@@ -0,0 +1,121 @@
+package jmespath
+
+import "fmt"
+
+/* A DataSource stands in for an array whose elements live in a backing
+   store, such as a database table, a key/value bucket or an object store
+   with a query API.  When a projection or filter projection is applied to
+   a data source, its filter and projection are offered to the source,
+   which evaluates whatever parts of them it can natively, and the
+   interpreter evaluates the rest against the elements it returns.  Any
+   other use of a data source, such as passing it to a function, loads all
+   of its elements with an empty Query.
+*/
+
+// Query is the part of an expression offered to a DataSource.  The nodes
+// can be rendered with FormatAST, or encoded with MarshalJSON, to be
+// inspected by the source.
+type Query struct {
+	// Filter is the condition of a filter projection, such as
+	// status == 'failed' in [?status == 'failed'].name, or nil if
+	// every element is selected.
+	Filter *ASTNode
+	// Projection is evaluated against each selected element, such as
+	// name in [?status == 'failed'].name, or nil if the selected
+	// elements are projected unchanged.
+	Projection *ASTNode
+}
+
+// Pushed reports which parts of a Query a DataSource evaluated.
+type Pushed int
+
+const (
+	// PushedFilter means the returned elements are only those for which
+	// the filter is true.
+	PushedFilter Pushed = 1 << iota
+	// PushedProjection means the returned elements have already been
+	// projected.  A projection can only be pushed along with its
+	// filter, as the filter applies to the elements before they are
+	// projected.
+	PushedProjection
+)
+
+// DataSource is an array backed by a store that can evaluate filters and
+// projections itself.  Query returns the elements matching q, and reports
+// which parts of q were applied to them; those that were not are
+// evaluated by the interpreter, so a source that applies neither simply
+// returns all of its elements.  Results that are null after projection are
+// dropped by the interpreter, as with any projection, unless nulls are
+// kept, see Runtime.SetKeepNulls.
+type DataSource interface {
+	Query(q Query) (elements []interface{}, pushed Pushed, err error)
+}
+
+// newQuery returns the query offered to a data source by a projection or
+// filter projection node.  Identity projections are left out.
+func newQuery(node ASTNode) Query {
+	var q Query
+	if node.nodeType == ASTFilterProjection {
+		q.Filter = &node.children[2]
+	}
+	if node.children[1].nodeType != ASTIdentity {
+		q.Projection = &node.children[1]
+	}
+	return q
+}
+
+// querySource evaluates the projection or filter projection node against
+// the elements of source, evaluating the parts of the node the source did
+// not.
+func (intr *treeInterpreter) querySource(node ASTNode, source DataSource) (interface{}, error) {
+	q := newQuery(node)
+	elements, pushed, err := source.Query(q)
+	if err != nil {
+		return nil, err
+	}
+	if q.Filter != nil && pushed&PushedFilter == 0 {
+		if pushed&PushedProjection != 0 {
+			return nil, fmt.Errorf("data source %T projected elements without filtering them", source)
+		}
+		elements, err = intr.filterElements(*q.Filter, elements)
+		if err != nil {
+			return nil, err
+		}
+	}
+	collected := []interface{}{}
+	for _, element := range elements {
+		if q.Projection != nil && pushed&PushedProjection == 0 {
+			if element, err = intr.Execute(*q.Projection, element); err != nil {
+				return nil, err
+			}
+		}
+		if intr.keeps(element) {
+			collected = append(collected, element)
+		}
+	}
+	return collected, nil
+}
+
+// filterElements returns the elements for which condition is true.
+func (intr *treeInterpreter) filterElements(condition ASTNode, elements []interface{}) ([]interface{}, error) {
+	var filtered []interface{}
+	for _, element := range elements {
+		result, err := intr.Execute(condition, element)
+		if err != nil {
+			return nil, err
+		}
+		if !isFalse(result) {
+			filtered = append(filtered, element)
+		}
+	}
+	return filtered, nil
+}
+
+// loadSource returns all of the elements of source.
+func loadSource(source DataSource) ([]interface{}, error) {
+	elements, _, err := source.Query(Query{})
+	if elements == nil && err == nil {
+		elements = []interface{}{}
+	}
+	return elements, err
+}
@@ -0,0 +1,109 @@
+package jmespath
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+var jobs = []interface{}{
+	map[string]interface{}{"name": "a", "status": "failed"},
+	map[string]interface{}{"name": "b", "status": "done"},
+	map[string]interface{}{"name": "c", "status": "failed"},
+	map[string]interface{}{"status": "failed"},
+}
+
+// jobStore pushes down filters on status and projections of name, and
+// records the queries it receives.
+type jobStore struct {
+	queries []string
+	pushed  Pushed
+	err     error
+}
+
+func (s *jobStore) Query(q Query) ([]interface{}, Pushed, error) {
+	var filter, projection string
+	if q.Filter != nil {
+		filter = FormatAST(*q.Filter)
+	}
+	if q.Projection != nil {
+		projection = FormatAST(*q.Projection)
+	}
+	s.queries = append(s.queries, filter+"|"+projection)
+	if s.err != nil {
+		return nil, 0, s.err
+	}
+	if filter != "status == 'failed'" {
+		return jobs, 0, nil
+	}
+	var elements []interface{}
+	for _, job := range jobs {
+		job := job.(map[string]interface{})
+		if job["status"] != "failed" {
+			continue
+		}
+		if projection == "name" && s.pushed&PushedProjection != 0 {
+			elements = append(elements, job["name"])
+		} else {
+			elements = append(elements, job)
+		}
+	}
+	pushed := PushedFilter
+	if projection == "name" {
+		pushed |= s.pushed & PushedProjection
+	}
+	return elements, pushed, nil
+}
+
+func TestDataSourcePushdown(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		expression string
+		pushed     Pushed
+		queries    []string
+		expected   interface{}
+	}{
+		{"jobs[?status == 'failed'].name", PushedProjection, []string{"status == 'failed'|name"}, []interface{}{"a", "c"}},
+		{"jobs[?status == 'failed'].name", 0, []string{"status == 'failed'|name"}, []interface{}{"a", "c"}},
+		{"jobs[?status == 'failed']", 0, []string{"status == 'failed'|"}, []interface{}{jobs[0], jobs[2], jobs[3]}},
+		{"jobs[?status == 'done'].name", PushedProjection, []string{"status == 'done'|name"}, []interface{}{"b"}},
+		{"jobs[*].name", PushedProjection, []string{"|name"}, []interface{}{"a", "b", "c"}},
+		{"length(jobs)", 0, []string{"|"}, 4.0},
+		{"jobs[?status == 'failed'].name | [0]", PushedProjection, []string{"status == 'failed'|name"}, "a"},
+	}
+	for _, test := range tests {
+		for _, compiled := range []bool{false, true} {
+			store := &jobStore{pushed: test.pushed}
+			data := map[string]interface{}{"jobs": store}
+			var result interface{}
+			var err error
+			if compiled {
+				result, err = MustCompile(test.expression).Search(data)
+			} else {
+				result, err = Search(test.expression, data)
+			}
+			assert.Nil(err, test.expression)
+			assert.Equal(test.queries, store.queries, test.expression)
+			assert.Equal(test.expected, result, test.expression)
+		}
+	}
+}
+
+func TestDataSourceErrors(t *testing.T) {
+	assert := assert.New(t)
+	store := &jobStore{err: errors.New("store unavailable")}
+	_, err := Search("jobs[?status == 'failed']", map[string]interface{}{"jobs": store})
+	assert.EqualError(err, "store unavailable")
+	_, err = Search("length(jobs)", map[string]interface{}{"jobs": store})
+	assert.EqualError(err, "length(jobs) at offset 0: store unavailable")
+
+	_, err = Search("jobs[?name == 'a'].name", map[string]interface{}{"jobs": projectingSource{}})
+	assert.EqualError(err, "data source jmespath.projectingSource projected elements without filtering them")
+}
+
+type projectingSource struct{}
+
+func (projectingSource) Query(q Query) ([]interface{}, Pushed, error) {
+	return []interface{}{"a"}, PushedProjection, nil
+}
@@ -0,0 +1,85 @@
+package jmespath
+
+import "encoding/json"
+
+// Decoder decodes JSON documents for the functions that search JSON text,
+// such as SearchBytes, so that a faster JSON implementation than
+// encoding/json, such as jsoniter, go-json or sonic, can be plugged in.
+// Unmarshal is called with a pointer to an interface{} and must store the
+// same values in it as json.Unmarshal: map[string]interface{} for
+// objects, []interface{} for arrays, float64 for numbers, and string,
+// bool or nil for the other values.  The configurations of most JSON
+// libraries that are compatible with encoding/json implement Decoder.
+type Decoder interface {
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// DecoderFunc adapts a function such as json.Unmarshal to a Decoder.
+type DecoderFunc func(data []byte, v interface{}) error
+
+// Unmarshal calls f(data, v).
+func (f DecoderFunc) Unmarshal(data []byte, v interface{}) error {
+	return f(data, v)
+}
+
+// SetDecoder sets the Decoder used by expressions compiled after the call
+// to decode the documents passed to SearchBytes, or restores the default
+// if decoder is nil.  By default documents are decoded with encoding/json,
+// or with UnmarshalOrdered if ordered objects are enabled.
+func (rt *Runtime) SetDecoder(decoder Decoder) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.decoder = decoder
+}
+
+// SearchBytes decodes the JSON document data and evaluates the expression
+// against it.
+func (jp *JMESPath) SearchBytes(data []byte) (interface{}, error) {
+	document, err := jp.intr.decode(data)
+	if err != nil {
+		return nil, err
+	}
+	return jp.Search(document)
+}
+
+// SearchBytes decodes the JSON document data with encoding/json and
+// evaluates a JMESPath expression against it.
+func SearchBytes(expression string, data []byte) (interface{}, error) {
+	jp, err := Compile(expression)
+	if err != nil {
+		return nil, err
+	}
+	return jp.SearchBytes(data)
+}
+
+// SearchBytes decodes the JSON document data with the decoder of this
+// runtime and evaluates a JMESPath expression against it.
+func (rt *Runtime) SearchBytes(expression string, data []byte) (interface{}, error) {
+	jp, err := rt.Compile(expression)
+	if err != nil {
+		return nil, err
+	}
+	return jp.SearchBytes(data)
+}
+
+func (intr *treeInterpreter) decode(data []byte) (interface{}, error) {
+	var document interface{}
+	var err error
+	exact := intr.integers != IntegersAsFloat
+	switch {
+	case intr.decoder != nil:
+		err = intr.decoder.Unmarshal(data, &document)
+		if err == nil && exact && !intr.numbers.JSONNumbers {
+			document, err = exactNumbers(document)
+		}
+	case intr.ordered:
+		document, err = unmarshalOrdered(data, exact, intr.numbers.JSONNumbers)
+	case intr.numbers.JSONNumbers:
+		document, err = unmarshalJSONNumbers(data)
+	case exact:
+		document, err = unmarshalExact(data)
+	default:
+		err = json.Unmarshal(data, &document)
+	}
+	return document, err
+}
@@ -0,0 +1,54 @@
+package jmespath
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func TestSearchBytes(t *testing.T) {
+	assert := assert.New(t)
+	result, err := SearchBytes("foo[1]", []byte(`{"foo": [1, 2]}`))
+	assert.Nil(err)
+	assert.Equal(2.0, result)
+
+	_, err = SearchBytes("foo", []byte(`{"foo": `))
+	assert.NotNil(err)
+	_, err = SearchBytes("foo[", []byte(`{}`))
+	assert.IsType(SyntaxError{}, err)
+}
+
+func TestSearchBytesWithDecoder(t *testing.T) {
+	assert := assert.New(t)
+	decoded := 0
+	rt := NewRuntime()
+	rt.SetDecoder(DecoderFunc(func(data []byte, v interface{}) error {
+		decoded++
+		return json.Unmarshal(data, v)
+	}))
+	jp, err := rt.Compile("length(@)")
+	assert.Nil(err)
+	result, err := jp.SearchBytes([]byte(`[1, 2, 3]`))
+	assert.Nil(err)
+	assert.Equal(3.0, result)
+	result, err = rt.SearchBytes("@", []byte(`"x"`))
+	assert.Nil(err)
+	assert.Equal("x", result)
+	assert.Equal(2, decoded)
+
+	// Expressions keep the decoder they were compiled with.
+	rt.SetDecoder(nil)
+	_, err = jp.SearchBytes([]byte(`[]`))
+	assert.Nil(err)
+	assert.Equal(3, decoded)
+}
+
+func TestSearchBytesOrdered(t *testing.T) {
+	assert := assert.New(t)
+	rt := NewRuntime()
+	rt.SetOrderedObjects(true)
+	result, err := rt.SearchBytes("keys(@)", []byte(`{"b": 1, "a": 2}`))
+	assert.Nil(err)
+	assert.Equal([]interface{}{"b", "a"}, result)
+}
@@ -0,0 +1,33 @@
+package jmespath
+
+// descendants returns the values of key in value and in every array and
+// object nested in it, at any depth, for the recursive descent operator
+// "..".  Values are returned in document order, with a value found in an
+// object before the values nested in it and the keys of objects visited in
+// the order of objectKeys.  It returns nil if value is null and an empty
+// array if value is not an array or object.
+func (intr *treeInterpreter) descendants(key string, value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	found := intr.descend(key, located{value: value}, []located{})
+	return collect(found).value
+}
+
+// descend appends the values of key found at any depth of current to
+// found, located relative to current.
+func (intr *treeInterpreter) descend(key string, current located, found []located) []located {
+	if object, ok := intr.toObject(current.value); ok {
+		if value, ok := object[key]; ok {
+			found = append(found, located{value: value, path: current.child(key)})
+		}
+		for _, k := range objectKeys(current.value, object) {
+			found = intr.descend(key, located{value: object[k], path: current.child(k)}, found)
+		}
+	} else if items, ok := current.items(); ok {
+		for _, item := range items {
+			found = intr.descend(key, item, found)
+		}
+	}
+	return found
+}
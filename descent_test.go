@@ -0,0 +1,137 @@
+package jmespath
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func TestRecursiveDescent(t *testing.T) {
+	assert := assert.New(t)
+	data := decodeJSON(`{
+		"kind": "Deployment",
+		"spec": {
+			"template": {
+				"spec": {
+					"containers": [{"name": "app", "image": "app:1"}, {"name": "proxy"}],
+					"initContainers": [{"name": "init"}]
+				}
+			}
+		},
+		"items": [
+			{"name": "a", "children": [{"name": "b"}, {"id": 1}]},
+			{"nested": {"name": "c"}},
+			"name"
+		],
+		"zero": 0
+	}`)
+	rt := NewRuntime()
+	rt.SetRecursiveDescent(true)
+	traced := NewRuntime()
+	traced.SetRecursiveDescent(true)
+	traced.SetTracer(&recordingTracer{})
+	cases := []struct {
+		expression string
+		expected   interface{}
+	}{
+		{"..containers[].name", []interface{}{"app", "proxy"}},
+		{"..spec.containers[]", []interface{}{map[string]interface{}{"name": "app", "image": "app:1"}, map[string]interface{}{"name": "proxy"}}},
+		{"..spec | length(@)", 2.0},
+		{"..name", []interface{}{"a", "b", "c", "app", "proxy", "init"}},
+		{"items..name", []interface{}{"a", "b", "c"}},
+		{"items[0]..name", []interface{}{"a", "b"}},
+		{"items[*]..name", []interface{}{[]interface{}{"a", "b"}, []interface{}{"c"}, []interface{}{}}},
+		{"..children..name", []interface{}{[]interface{}{"b"}}},
+		{"..image", []interface{}{"app:1"}},
+		{"..missing", []interface{}{}},
+		{"..\"initContainers\"[0].name", []interface{}{"init"}},
+		{"..name | sort(@)[0]", "a"},
+		{"..name[0]", []interface{}{}},
+		{"..children[0].name", []interface{}{"b"}},
+		{"zero..name", []interface{}{}},
+		{"missing..name", nil},
+	}
+	for _, tt := range cases {
+		for _, r := range []*Runtime{rt, traced} {
+			result, err := r.Search(tt.expression, data)
+			assert.Nil(err, tt.expression)
+			assert.Equal(tt.expected, result, tt.expression)
+		}
+	}
+	_, err := rt.Compile("..[id]")
+	assert.NotNil(err)
+
+	strict := NewRuntime()
+	strict.SetRecursiveDescent(true)
+	strict.SetStrict(true)
+	result, err := strict.Search("..containers[].name", data)
+	assert.Nil(err)
+	assert.Equal([]interface{}{"app", "proxy"}, result)
+	_, err = strict.Search("..spec.containers", data)
+	assert.True(errors.Is(err, ErrMissingKey))
+}
+
+func TestRecursiveDescentDisabled(t *testing.T) {
+	assert := assert.New(t)
+	for _, expression := range []string{"..a", "foo..bar", "foo[*]..bar"} {
+		_, err := Compile(expression)
+		if assert.NotNil(err, expression) {
+			assert.Contains(err.Error(), "not enabled", expression)
+		}
+	}
+	_, err := NewRuntime().Compile("foo..bar")
+	assert.NotNil(err)
+}
+
+func TestRecursiveDescentPaths(t *testing.T) {
+	assert := assert.New(t)
+	data := decodeJSON(`{"a": {"name": "x", "b": [{"name": "y"}]}, "name": "z"}`)
+	rt := NewRuntime()
+	rt.SetRecursiveDescent(true)
+	jp, err := rt.Compile("..name")
+	assert.Nil(err)
+	located, err := jp.SearchPaths(data)
+	assert.Nil(err)
+	var paths []string
+	for _, l := range located {
+		paths = append(paths, l.Path)
+	}
+	assert.Equal([]string{"name", "a.name", "a.b[0].name"}, paths)
+	assert.Contains(jp.ExplainPlan(), `Recursive descent collecting key "name" at any depth`)
+}
+
+func TestFormatRecursiveDescent(t *testing.T) {
+	assert := assert.New(t)
+	for expression, expected := range map[string]string{
+		"..a":               "..a",
+		"foo..bar.baz":      "foo..bar.baz",
+		"foo .. bar [0]":    "foo..bar[0]",
+		"..\"a b\"":         "..\"a b\"",
+		"..a..b":            "..a..b",
+		"foo[*]..bar":       "foo[*]..bar",
+		"(a || b)..c":       "(a || b)..c",
+		"..spec | [0]":      "..spec | [0]",
+		"..items[].name":    "..items[].name",
+		"a.b..c[?d == `1`]": "a.b..c[?d == `1`]",
+	} {
+		parser := NewParser()
+		parser.recursiveDescent = true
+		ast, err := parser.Parse(expression)
+		if !assert.Nil(err, expression) {
+			continue
+		}
+		formatted := FormatAST(ast)
+		assert.Equal(expected, formatted, expression)
+		reparsed, err := parser.Parse(formatted)
+		assert.Nil(err, formatted)
+		assert.Equal(ast, reparsed, expression)
+
+		encoded, err := json.Marshal(ast)
+		assert.Nil(err)
+		var decoded ASTNode
+		assert.Nil(json.Unmarshal(encoded, &decoded), string(encoded))
+		assert.Equal(ast, decoded)
+	}
+}
@@ -0,0 +1,131 @@
+package jmespath
+
+// ChangeKind is the kind of a Change between two results.
+type ChangeKind int
+
+const (
+	// Added is a field or array element only the new result has.
+	Added ChangeKind = iota
+	// Removed is a field or array element only the old result has.
+	Removed
+	// Changed is a value that differs between the results.
+	Changed
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Changed:
+		return "changed"
+	}
+	return "unknown"
+}
+
+// Change is a difference between two results, see JMESPath.SearchDiff.
+type Change struct {
+	Kind ChangeKind
+	// Pointer is the location of the change in the results as a JSON
+	// Pointer (RFC 6901), for example "/pods/1/image", or "" for the whole
+	// result.
+	Pointer string
+	// Path is the location of the change as an expression on the
+	// results, for example "pods[1].image", or "@" for the whole result.
+	Path string
+	// Old is the value in the old result, nil if it was Added.
+	Old interface{}
+	// New is the value in the new result, nil if it was Removed.
+	New interface{}
+}
+
+// SearchDiff evaluates the expression against the documents oldData and
+// newData and returns the differences between the results, so that a
+// change to the part of a document an expression projects can be told
+// apart from changes elsewhere.  It returns no changes if the results are
+// equal.  See Diff for how the results are compared.
+func (jp *JMESPath) SearchDiff(oldData, newData interface{}) ([]Change, error) {
+	oldResult, err := jp.Search(oldData)
+	if err != nil {
+		return nil, err
+	}
+	newResult, err := jp.Search(newData)
+	if err != nil {
+		return nil, err
+	}
+	return Diff(oldResult, newResult), nil
+}
+
+// SearchDiff evaluates a JMESPath expression against oldData and newData
+// and returns the differences between the results, see
+// JMESPath.SearchDiff.
+func SearchDiff(expression string, oldData, newData interface{}) ([]Change, error) {
+	jp, err := Compile(expression)
+	if err != nil {
+		return nil, err
+	}
+	return jp.SearchDiff(oldData, newData)
+}
+
+// Diff returns the differences between two values.  Objects are compared
+// field by field, with the fields of OrderedMaps in order and those of
+// other objects in sorted order, and arrays element by element, so that an
+// element inserted in the middle of an array changes every element after
+// it.  Any other values that are not equal, as "==" compares them, are
+// Changed, as are values whose types differ.
+func Diff(oldValue, newValue interface{}) []Change {
+	var changes []Change
+	diffValues(&changes, []interface{}{}, oldValue, newValue)
+	return changes
+}
+
+func diffValues(changes *[]Change, path []interface{}, oldValue, newValue interface{}) {
+	if _, ok := oldValue.([]interface{}); !ok && isSliceType(oldValue) {
+		oldValue = toInterfaceSlice(oldValue)
+	}
+	if _, ok := newValue.([]interface{}); !ok && isSliceType(newValue) {
+		newValue = toInterfaceSlice(newValue)
+	}
+	oldArray, oldIsArray := oldValue.([]interface{})
+	newArray, newIsArray := newValue.([]interface{})
+	if oldIsArray && newIsArray {
+		for i := 0; i < len(oldArray) || i < len(newArray); i++ {
+			elementPath := append(path[:len(path):len(path)], i)
+			switch {
+			case i >= len(newArray):
+				*changes = append(*changes, newChange(Removed, elementPath, oldArray[i], nil))
+			case i >= len(oldArray):
+				*changes = append(*changes, newChange(Added, elementPath, nil, newArray[i]))
+			default:
+				diffValues(changes, elementPath, oldArray[i], newArray[i])
+			}
+		}
+		return
+	}
+	if isObject(oldValue) && isObject(newValue) {
+		oldObject, newObject := plainObject(oldValue), plainObject(newValue)
+		for _, key := range objectKeys(oldValue, oldObject) {
+			fieldPath := append(path[:len(path):len(path)], key)
+			if newField, ok := newObject[key]; ok {
+				diffValues(changes, fieldPath, oldObject[key], newField)
+			} else {
+				*changes = append(*changes, newChange(Removed, fieldPath, oldObject[key], nil))
+			}
+		}
+		for _, key := range objectKeys(newValue, newObject) {
+			if _, ok := oldObject[key]; !ok {
+				fieldPath := append(path[:len(path):len(path)], key)
+				*changes = append(*changes, newChange(Added, fieldPath, nil, newObject[key]))
+			}
+		}
+		return
+	}
+	if !objsEqual(oldValue, newValue) {
+		*changes = append(*changes, newChange(Changed, path, oldValue, newValue))
+	}
+}
+
+func newChange(kind ChangeKind, path []interface{}, oldValue, newValue interface{}) Change {
+	return Change{Kind: kind, Pointer: formatPointer(path), Path: formatLocation(path), Old: oldValue, New: newValue}
+}
@@ -0,0 +1,77 @@
+package jmespath
+
+import (
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	assert := assert.New(t)
+	oldValue := map[string]interface{}{
+		"name":   "web",
+		"labels": map[string]interface{}{"app": "web", "tier": "front"},
+		"images": []interface{}{"nginx:1.0", "sidecar:2"},
+		"port":   80.0,
+	}
+	newValue := map[string]interface{}{
+		"name":   "web",
+		"labels": map[string]interface{}{"app": "web", "team/owner": "ops"},
+		"images": []interface{}{"nginx:1.1"},
+		"port":   80,
+	}
+	assert.Equal([]Change{
+		{Kind: Changed, Pointer: "/images/0", Path: "images[0]", Old: "nginx:1.0", New: "nginx:1.1"},
+		{Kind: Removed, Pointer: "/images/1", Path: "images[1]", Old: "sidecar:2"},
+		{Kind: Removed, Pointer: "/labels/tier", Path: "labels.tier", Old: "front"},
+		{Kind: Added, Pointer: "/labels/team~1owner", Path: "labels.\"team/owner\"", New: "ops"},
+	}, Diff(oldValue, newValue))
+	assert.Nil(Diff(oldValue, oldValue))
+	assert.Equal([]Change{{Kind: Changed, Pointer: "", Path: "@", Old: "a", New: []interface{}{"a"}}},
+		Diff("a", []interface{}{"a"}))
+	assert.Equal([]Change{{Kind: Added, Pointer: "/2", Path: "[2]", New: 3}},
+		Diff([]int{1, 2}, []interface{}{1.0, 2.0, 3}))
+	assert.Equal("added", Added.String())
+	assert.Equal("removed", Removed.String())
+	assert.Equal("changed", Changed.String())
+}
+
+func TestDiffOrderedMaps(t *testing.T) {
+	assert := assert.New(t)
+	oldValue, err := UnmarshalOrdered([]byte(`{"b": 1, "a": 2, "c": 3}`))
+	assert.Nil(err)
+	newValue, err := UnmarshalOrdered([]byte(`{"z": 0, "b": 1, "a": 5}`))
+	assert.Nil(err)
+	assert.Equal([]Change{
+		{Kind: Changed, Pointer: "/a", Path: "a", Old: 2.0, New: 5.0},
+		{Kind: Removed, Pointer: "/c", Path: "c", Old: 3.0},
+		{Kind: Added, Pointer: "/z", Path: "z", New: 0.0},
+	}, Diff(oldValue, newValue))
+}
+
+func TestSearchDiff(t *testing.T) {
+	assert := assert.New(t)
+	pod := func(image string, restarts float64) interface{} {
+		return map[string]interface{}{
+			"spec":   map[string]interface{}{"containers": []interface{}{map[string]interface{}{"name": "app", "image": image}}},
+			"status": map[string]interface{}{"restarts": restarts},
+		}
+	}
+	jp := MustCompile("spec.containers[*].{name: name, image: image}")
+	changes, err := jp.SearchDiff(pod("app:1", 0), pod("app:1", 3))
+	assert.Nil(err)
+	assert.Empty(changes)
+	changes, err = jp.SearchDiff(pod("app:1", 0), pod("app:2", 0))
+	assert.Nil(err)
+	assert.Equal([]Change{{Kind: Changed, Pointer: "/0/image", Path: "[0].image", Old: "app:1", New: "app:2"}}, changes)
+
+	changes, err = SearchDiff("status", pod("app:1", 0), map[string]interface{}{})
+	assert.Nil(err)
+	assert.Equal([]Change{{Kind: Changed, Path: "@", Old: map[string]interface{}{"restarts": 0.0}}}, changes)
+	_, err = SearchDiff("length(spec)", pod("app:1", 0), pod("app:1", 0))
+	assert.Nil(err)
+	_, err = SearchDiff("length(status.restarts)", pod("app:1", 0), pod("app:1", 0))
+	assert.NotNil(err)
+	_, err = SearchDiff("spec.[", nil, nil)
+	assert.NotNil(err)
+}
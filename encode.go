@@ -0,0 +1,111 @@
+package jmespath
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// Encoder writes the results of expressions to a stream, as the jpgo
+// command prints them.  By default each result is written as compact JSON
+// followed by a newline, without escaping HTML characters.
+type Encoder struct {
+	w          io.Writer
+	indent     string
+	rawStrings bool
+	sortKeys   bool
+	ndjson     bool
+	numbers    NumberFormat
+}
+
+// NewEncoder returns an Encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// SetIndent makes the encoder write each element of arrays and objects on
+// a new line indented with indent for each level of nesting, or compact
+// JSON if indent is empty.
+func (e *Encoder) SetIndent(indent string) {
+	e.indent = indent
+}
+
+// SetRawStrings makes the encoder write string results as they are,
+// without quotes or escapes, like "jq -r".
+func (e *Encoder) SetRawStrings(raw bool) {
+	e.rawStrings = raw
+}
+
+// SetSortKeys makes the encoder write the keys of ordered objects
+// (*OrderedMap) in sorted order, like the keys of other objects, instead
+// of in their order.
+func (e *Encoder) SetSortKeys(sorted bool) {
+	e.sortKeys = sorted
+}
+
+// SetNDJSON makes the encoder write array results as newline-delimited
+// JSON: each element is written as compact JSON on a line of its own, and
+// an empty array writes nothing.  Other results are written on a single
+// line.  The raw strings setting applies to the elements.
+func (e *Encoder) SetNDJSON(ndjson bool) {
+	e.ndjson = ndjson
+}
+
+// SetNumberFormat makes the encoder write numbers in the given format,
+// see NumberFormat.
+func (e *Encoder) SetNumberFormat(format NumberFormat) {
+	e.numbers = format
+}
+
+// Encode writes result, the result of an expression, followed by a
+// newline.
+func (e *Encoder) Encode(result interface{}) error {
+	if !e.ndjson {
+		return e.encode(result, e.indent)
+	}
+	if !isSliceType(result) {
+		return e.encode(result, "")
+	}
+	for _, element := range toInterfaceSlice(result) {
+		if err := e.encode(element, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *Encoder) encode(value interface{}, indent string) error {
+	if s, ok := value.(string); ok && e.rawStrings {
+		_, err := io.WriteString(e.w, s+"\n")
+		return err
+	}
+	if e.sortKeys && containsOrderedMap(value) {
+		value = unordered(value)
+	}
+	if e.numbers.formats() {
+		value = e.numbers.formatNumbers(value)
+	}
+	encoder := json.NewEncoder(e.w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", indent)
+	return encoder.Encode(value)
+}
+
+// SearchAndEncode evaluates a JMESPath expression against data and writes
+// the result with enc.
+func SearchAndEncode(enc *Encoder, expression string, data interface{}) error {
+	result, err := Search(expression, data)
+	if err != nil {
+		return err
+	}
+	return enc.Encode(result)
+}
+
+// SearchAndEncode evaluates a JMESPath expression against data using the
+// functions available in this runtime and writes the result with enc.
+func (rt *Runtime) SearchAndEncode(enc *Encoder, expression string, data interface{}) error {
+	result, err := rt.Search(expression, data)
+	if err != nil {
+		return err
+	}
+	return enc.Encode(result)
+}
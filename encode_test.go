@@ -0,0 +1,65 @@
+package jmespath
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func TestEncoder(t *testing.T) {
+	assert := assert.New(t)
+	ordered := NewOrderedMap()
+	ordered.Set("z", 1.0)
+	ordered.Set("a", "<b>")
+	cases := []struct {
+		configure func(e *Encoder)
+		result    interface{}
+		expected  string
+	}{
+		{func(e *Encoder) {}, map[string]interface{}{"b": 1.0, "a": []interface{}{true, nil}}, `{"a":[true,null],"b":1}` + "\n"},
+		{func(e *Encoder) {}, "a<b", `"a<b"` + "\n"},
+		{func(e *Encoder) {}, nil, "null\n"},
+		{func(e *Encoder) { e.SetIndent("  ") }, []interface{}{1.0, ordered}, "[\n  1,\n  {\n    \"z\": 1,\n    \"a\": \"<b>\"\n  }\n]\n"},
+		{func(e *Encoder) { e.SetSortKeys(true) }, []interface{}{ordered}, `[{"a":"<b>","z":1}]` + "\n"},
+		{func(e *Encoder) { e.SetRawStrings(true) }, "line \"one\"\ttab", "line \"one\"\ttab\n"},
+		{func(e *Encoder) { e.SetRawStrings(true) }, []interface{}{"a"}, `["a"]` + "\n"},
+		{func(e *Encoder) { e.SetNDJSON(true) }, []interface{}{"a", map[string]interface{}{"b": 1.0}}, "\"a\"\n{\"b\":1}\n"},
+		{func(e *Encoder) { e.SetNDJSON(true); e.SetRawStrings(true) }, []string{"a", "b"}, "a\nb\n"},
+		{func(e *Encoder) { e.SetNDJSON(true); e.SetIndent("  ") }, map[string]interface{}{"b": 1.0}, "{\"b\":1}\n"},
+		{func(e *Encoder) { e.SetNDJSON(true) }, []interface{}{}, ""},
+	}
+	escaped, err := json.Marshal(ordered)
+	assert.Nil(err)
+	assert.Equal(`{"z":1,"a":"\u003cb\u003e"}`, string(escaped))
+	for i, tt := range cases {
+		var b bytes.Buffer
+		encoder := NewEncoder(&b)
+		tt.configure(encoder)
+		assert.Nil(encoder.Encode(tt.result), i)
+		assert.Equal(tt.expected, b.String(), i)
+	}
+}
+
+func TestSearchAndEncode(t *testing.T) {
+	assert := assert.New(t)
+	data := decodeJSON(`{"people": [{"name": "a"}, {"name": "b"}]}`)
+	var b bytes.Buffer
+	encoder := NewEncoder(&b)
+	encoder.SetNDJSON(true)
+	encoder.SetRawStrings(true)
+	assert.Nil(SearchAndEncode(encoder, "people[*].name", data))
+	assert.Equal("a\nb\n", b.String())
+
+	b.Reset()
+	rt := NewRuntime()
+	rt.SetOrderedObjects(true)
+	document, err := UnmarshalOrdered([]byte(`{"z": 1, "a": 2}`))
+	assert.Nil(err)
+	assert.Nil(rt.SearchAndEncode(NewEncoder(&b), "@", document))
+	assert.Equal(`{"z":1,"a":2}`+"\n", b.String())
+
+	assert.NotNil(SearchAndEncode(encoder, "people[", data))
+	assert.NotNil(rt.SearchAndEncode(encoder, "abs(people)", data))
+}
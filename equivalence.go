@@ -0,0 +1,283 @@
+package jmespath
+
+import "sort"
+
+/* Expressions are compared in a normal form: the AST is optimized, and
+   the operands of operators whose order does not matter are put in a
+   canonical order.  Operands are only reordered when none of them calls
+   a function, as functions can fail or have side effects that the
+   evaluation order guaranteed for "&&" and "||" would otherwise change.
+
+   - "==" and "!=" compare their operands symmetrically, and "<", "<=",
+     ">" and ">=" are mirrored by flipping the operator, so their operands
+     are sorted by their formatted expressions, with literals last.
+   - Where only the truthiness of a value matters, in filter conditions
+     and the operands of "!", a chain of "&&" or "||" is true for the same
+     values whatever the order of its operands, so they are sorted and
+     duplicates removed, and "!!x" is replaced by "x".
+   - Multiselect hashes build the same object whatever the order of their
+     keys, so the keys are sorted.
+*/
+
+// Normalize returns expression in a normal form, as an expression, so that
+// expressions that only differ in ways that cannot change their results,
+// such as "a == b" and "b == a", or "[?x && y]" and "[?y && x]", have the
+// same normal form.  Equivalent expressions may still have different
+// normal forms, as only such syntactic differences are recognized.  The
+// normal form is meant to deduplicate expressions and is evaluated like
+// expression by the default runtime; runtimes with ordered objects enabled
+// build objects with their keys in a different order.
+func Normalize(expression string) (_ string, err error) {
+	defer recoverInternal(&err)
+	ast, err := NewParser().Parse(expression)
+	if err != nil {
+		return "", err
+	}
+	return FormatAST(normalize(ast)), nil
+}
+
+// Equivalent reports whether the expressions x and y have the same normal
+// form, see Normalize, in which case they return the same results.
+func Equivalent(x, y string) (bool, error) {
+	nx, err := Normalize(x)
+	if err != nil {
+		return false, err
+	}
+	ny, err := Normalize(y)
+	if err != nil {
+		return false, err
+	}
+	return nx == ny, nil
+}
+
+// Subsumes reports whether the result of specific is always contained in
+// the result of general: either they are equivalent, or both are filter
+// projections of the same array, with the same expression applied to the
+// elements they select, and the condition of specific implies the
+// condition of general, so that general selects every element specific
+// selects.  A plain projection, "[*]", selects every element.  Only simple
+// implications are recognized: a condition implies itself, the "||" of
+// itself with other conditions, and is implied by the "&&" of itself with
+// other conditions, and comparisons of the same expression with literals
+// imply each other as their ranges do, as "price > `100`" implies
+// "price >= `50`".  Documents for which either expression fails are not
+// considered.
+func Subsumes(general, specific string) (_ bool, err error) {
+	defer recoverInternal(&err)
+	g, err := NewParser().Parse(general)
+	if err != nil {
+		return false, err
+	}
+	s, err := NewParser().Parse(specific)
+	if err != nil {
+		return false, err
+	}
+	return subsumes(normalize(g), normalize(s)), nil
+}
+
+// normalize returns the normal form of ast, see Normalize.
+func normalize(ast ASTNode) ASTNode {
+	return normalizeNode(optimize(ast, newInterpreter()), false)
+}
+
+// normalizeNode normalizes node bottom up.  truthy is set when only the
+// truthiness of the value of node matters.
+func normalizeNode(node ASTNode, truthy bool) ASTNode {
+	if len(node.children) > 0 {
+		children := make([]ASTNode, len(node.children))
+		for i, child := range node.children {
+			childTruthy := false
+			switch node.nodeType {
+			case ASTFilterProjection:
+				childTruthy = i == 2
+			case ASTNotExpression:
+				childTruthy = true
+			case ASTAndExpression, ASTOrExpression:
+				childTruthy = truthy
+			}
+			children[i] = normalizeNode(child, childTruthy)
+		}
+		node.children = children
+	}
+	switch node.nodeType {
+	case ASTComparator:
+		return orderComparator(node)
+	case ASTAndExpression, ASTOrExpression:
+		if truthy {
+			return orderOperands(node)
+		}
+	case ASTNotExpression:
+		if truthy && node.children[0].nodeType == ASTNotExpression {
+			return node.children[0].children[0]
+		}
+	case ASTMultiSelectHash:
+		return orderKeys(node)
+	}
+	return node
+}
+
+// mirroredComparators maps the ordering comparators to the ones that
+// compare the same operands the other way around.
+var mirroredComparators = map[tokType]tokType{
+	tLT:  tGT,
+	tLTE: tGTE,
+	tGT:  tLT,
+	tGTE: tLTE,
+	tEQ:  tEQ,
+	tNE:  tNE,
+}
+
+// orderComparator puts the operands of a comparator in canonical order,
+// see Normalize.
+func orderComparator(node ASTNode) ASTNode {
+	mirrored, ok := mirroredComparators[node.value.(tokType)]
+	left, right := node.children[0], node.children[1]
+	if !ok || hasFunctionCall(left) || hasFunctionCall(right) {
+		return node
+	}
+	leftLiteral, rightLiteral := left.nodeType == ASTLiteral, right.nodeType == ASTLiteral
+	if leftLiteral && !rightLiteral || leftLiteral == rightLiteral && FormatAST(left) > FormatAST(right) {
+		return ASTNode{nodeType: ASTComparator, value: mirrored, children: []ASTNode{right, left}}
+	}
+	return node
+}
+
+// orderOperands sorts the operands of a chain of "&&" or "||" whose
+// result is only used for its truthiness, and removes duplicates.
+func orderOperands(node ASTNode) ASTNode {
+	operands := chainOperands(node, nil)
+	for _, operand := range operands {
+		if hasFunctionCall(operand) {
+			return node
+		}
+	}
+	formatted := make(map[string]ASTNode, len(operands))
+	keys := make([]string, 0, len(operands))
+	for _, operand := range operands {
+		key := FormatAST(operand)
+		if _, ok := formatted[key]; !ok {
+			formatted[key] = operand
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	ordered := formatted[keys[0]]
+	for _, key := range keys[1:] {
+		ordered = ASTNode{nodeType: node.nodeType, children: []ASTNode{ordered, formatted[key]}}
+	}
+	return ordered
+}
+
+// chainOperands appends the operands of the chain of operators of the
+// type of node to operands.
+func chainOperands(node ASTNode, operands []ASTNode) []ASTNode {
+	for _, child := range node.children {
+		if child.nodeType == node.nodeType {
+			operands = chainOperands(child, operands)
+		} else {
+			operands = append(operands, child)
+		}
+	}
+	return operands
+}
+
+// orderKeys sorts the keys of a multiselect hash.  The order of duplicate
+// keys is kept, as the last one wins.
+func orderKeys(node ASTNode) ASTNode {
+	if hasFunctionCall(node) {
+		return node
+	}
+	pairs := append([]ASTNode{}, node.children...)
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return pairs[i].value.(string) < pairs[j].value.(string)
+	})
+	node.children = pairs
+	return node
+}
+
+func sameAST(x, y ASTNode) bool {
+	return FormatAST(x) == FormatAST(y)
+}
+
+// subsumes implements Subsumes for normalized ASTs.
+func subsumes(general, specific ASTNode) bool {
+	if sameAST(general, specific) {
+		return true
+	}
+	if specific.nodeType != ASTFilterProjection {
+		return false
+	}
+	switch general.nodeType {
+	case ASTFilterProjection, ASTProjection:
+	default:
+		return false
+	}
+	if !sameAST(general.children[0], specific.children[0]) || !sameAST(general.children[1], specific.children[1]) {
+		return false
+	}
+	return general.nodeType == ASTProjection || implies(specific.children[2], general.children[2])
+}
+
+// implies reports whether the truthiness of the condition x implies that
+// of y.
+func implies(x, y ASTNode) bool {
+	switch {
+	case sameAST(x, y):
+		return true
+	case x.nodeType == ASTOrExpression:
+		return implies(x.children[0], y) && implies(x.children[1], y)
+	case y.nodeType == ASTAndExpression:
+		return implies(x, y.children[0]) && implies(x, y.children[1])
+	case x.nodeType == ASTAndExpression:
+		return implies(x.children[0], y) || implies(x.children[1], y)
+	case y.nodeType == ASTOrExpression:
+		return implies(x, y.children[0]) || implies(x, y.children[1])
+	case x.nodeType == ASTComparator && y.nodeType == ASTComparator:
+		return impliesComparison(x, y)
+	}
+	return false
+}
+
+// impliesComparison reports whether comparing an expression with a
+// literal, as in "v > `1`", implies another comparison of the same
+// expression with a literal, as in "v >= `0`".
+func impliesComparison(x, y ASTNode) bool {
+	if x.children[1].nodeType != ASTLiteral || y.children[1].nodeType != ASTLiteral ||
+		!sameAST(x.children[0], y.children[0]) {
+		return false
+	}
+	xOp, yOp := x.value.(tokType), y.value.(tokType)
+	a, b := x.children[1].value, y.children[1].value
+	switch xOp {
+	case tEQ:
+		// The expression is a, so a must satisfy y.
+		return compare(yOp, a, b, true) == true
+	case tGT, tGTE, tLT, tLTE:
+	default:
+		return false
+	}
+	switch yOp {
+	case tNE:
+		// b must be outside the range of x.
+		return compare(xOp, b, a, true) == false
+	case tGT, tGTE:
+		if xOp == tLT || xOp == tLTE {
+			return false
+		}
+		op := tGTE
+		if xOp == tGTE && yOp == tGT {
+			op = tGT
+		}
+		return compare(op, a, b, true) == true
+	case tLT, tLTE:
+		if xOp == tGT || xOp == tGTE {
+			return false
+		}
+		op := tLTE
+		if xOp == tLTE && yOp == tLT {
+			op = tLT
+		}
+		return compare(op, a, b, true) == true
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package jmespath
+
+import (
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func TestNormalizeExpressions(t *testing.T) {
+	assert := assert.New(t)
+	cases := []struct {
+		expression string
+		expected   string
+	}{
+		{"a", "a"},
+		{"@.a | @", "a"},
+		{"b == a", "a == b"},
+		{"`1` < a", "a > `1`"},
+		{"b >= a", "a <= b"},
+		{"a != `null`", "a != `null`"},
+		{"b in a", "b in a"},
+		{"people[?b && a]", "people[?a && b]"},
+		{"people[?c || a || b || a]", "people[?a || b || c]"},
+		{"people[?(c && a) && (b && a)]", "people[?a && b && c]"},
+		{"people[?!!active]", "people[?active]"},
+		{"people[?!(b || a)]", "people[?!(a || b)]"},
+		{"b && a", "b && a"},
+		{"!!a", "!!a"},
+		{"people[?length(b) && a]", "people[?length(b) && a]"},
+		{"people[?length(b) == a]", "people[?length(b) == a]"},
+		{"{b: b, a: a}", "{a: a, b: b}"},
+		{"[:2].a", "[:2].a"},
+		{"@[:2]", "[:2]"},
+		{"{b: length(b), a: a}", "{b: length(b), a: a}"},
+		{"people[?age > `10` && 'x' == name].{n: name, a: age}", "people[?age > `10` && name == 'x'].{a: age, n: name}"},
+	}
+	for _, tt := range cases {
+		normalized, err := Normalize(tt.expression)
+		assert.Nil(err, tt.expression)
+		assert.Equal(tt.expected, normalized, tt.expression)
+	}
+	_, err := Normalize("a[")
+	assert.IsType(SyntaxError{}, err)
+}
+
+func TestNormalizePreservesResults(t *testing.T) {
+	assert := assert.New(t)
+	data := map[string]interface{}{
+		"a": 1.0, "b": 2.0,
+		"people": []interface{}{
+			map[string]interface{}{"name": "x", "age": 20.0, "active": true, "a": 1.0, "b": "y"},
+			map[string]interface{}{"name": "y", "age": 5.0, "active": false},
+			map[string]interface{}{"name": "z", "age": 30.0, "c": []interface{}{}},
+		},
+	}
+	expressions := []string{
+		"b >= a", "`1` < a", "b != a",
+		"people[?b && a].name", "people[?c || a || b].name", "people[?!!active].name",
+		"people[?age > `10` && 'x' == name].{n: name, a: age}",
+	}
+	for _, expression := range expressions {
+		normalized, err := Normalize(expression)
+		assert.Nil(err, expression)
+		expected, err := Search(expression, data)
+		assert.Nil(err, expression)
+		result, err := Search(normalized, data)
+		assert.Nil(err, normalized)
+		assert.Equal(expected, result, expression)
+	}
+}
+
+func TestNormalizeSlicesOfTheCurrentNode(t *testing.T) {
+	assert := assert.New(t)
+	data := []interface{}{
+		map[string]interface{}{"a": 1.0},
+		map[string]interface{}{"a": 2.0},
+		map[string]interface{}{"a": 3.0},
+	}
+	for _, expression := range []string{"[:2].a", "[:2]", "@[::-1].a", "[1:] | [0].a"} {
+		normalized, err := Normalize(expression)
+		assert.Nil(err, expression)
+		expected, err := Search(expression, data)
+		assert.Nil(err, expression)
+		result, err := Search(normalized, data)
+		assert.Nil(err, normalized)
+		assert.Equal(expected, result, expression)
+	}
+}
+
+func TestEquivalent(t *testing.T) {
+	assert := assert.New(t)
+	cases := []struct {
+		x, y       string
+		equivalent bool
+	}{
+		{"a.b", "a . b", true},
+		{"users[?active && age >= `18`].name", "users[?`18` <= age && active].name", true},
+		{"{id: id, name: name}", "{name: name, id: id}", true},
+		{"a && b", "b && a", false},
+		{"a[0]", "a[1]", false},
+		{"a < b", "a <= b", false},
+	}
+	for _, tt := range cases {
+		equivalent, err := Equivalent(tt.x, tt.y)
+		assert.Nil(err, tt.x)
+		assert.Equal(tt.equivalent, equivalent, tt.x+" and "+tt.y)
+	}
+	_, err := Equivalent("a", "a[")
+	assert.IsType(SyntaxError{}, err)
+}
+
+func TestSubsumes(t *testing.T) {
+	assert := assert.New(t)
+	cases := []struct {
+		general, specific string
+		subsumes          bool
+	}{
+		{"a.b", "a.b", true},
+		{"users[*].name", "users[?active].name", true},
+		{"users[?active].name", "users[*].name", false},
+		{"users[?active].name", "users[?active && admin].name", true},
+		{"users[?active || admin].name", "users[?admin].name", true},
+		{"users[?active].name", "users[?active || admin].name", false},
+		{"users[?active].name", "users[?active].id", false},
+		{"users[?active].name", "admins[?active].name", false},
+		{"users[?age > `18`]", "users[?age > `21`]", true},
+		{"users[?age >= `18`]", "users[?age > `18`]", true},
+		{"users[?age > `18`]", "users[?age >= `18`]", false},
+		{"users[?age > `18`]", "users[?age == `30`]", true},
+		{"users[?age > `18`]", "users[?age < `30`]", false},
+		{"users[?age < `30`]", "users[?`20` > age]", true},
+		{"users[?age != `10`]", "users[?age > `18`]", true},
+		{"users[?age != `20`]", "users[?age > `18`]", false},
+		{"users[?age > `18` && age < `65`]", "users[?age > `30` && age < `40` && active]", true},
+		{"users[?name >= 'm']", "users[?name > 'n']", true},
+		{"users[?name >= 'm']", "users[?name > `1`]", false},
+		{"users[?role in `[\"a\", \"b\"]`]", "users[?role == 'a']", true},
+		{"users[].name", "users[?active].name", false},
+	}
+	for _, tt := range cases {
+		subsumes, err := Subsumes(tt.general, tt.specific)
+		assert.Nil(err, tt.general)
+		assert.Equal(tt.subsumes, subsumes, tt.general+" and "+tt.specific)
+	}
+	_, err := Subsumes("a[", "a")
+	assert.IsType(SyntaxError{}, err)
+}
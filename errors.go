@@ -0,0 +1,164 @@
+package jmespath
+
+import (
+	"fmt"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+// EvalError is returned when a function call fails while an expression is
+// evaluated, for example because the function does not exist or an
+// argument has the wrong type.  Errors of user-registered functions that
+// panic or time out are returned as a FunctionError instead.
+type EvalError struct {
+	Expression    string // Expression being evaluated, empty if it was compiled from an AST.
+	SubExpression string // Function call that failed, in canonical form.
+	Offset        int    // Location of the call in Expression, or -1 if it is unknown.
+	Function      string // Name of the function that failed.
+	ValueType     string // JSON type of the offending argument, if the error is about one.
+	Err           error  // The error returned by the call.
+	node          ASTNode
+}
+
+func (e *EvalError) Error() string {
+	location := e.SubExpression
+	if e.Offset >= 0 {
+		location += " at offset " + strconv.Itoa(e.Offset)
+	}
+	return location + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *EvalError) Unwrap() error {
+	return e.Err
+}
+
+// HighlightLocation shows where the failed call is in the expression, as
+// SyntaxError.HighlightLocation does.  It returns SubExpression if the
+// location is unknown.
+func (e *EvalError) HighlightLocation() string {
+	if e.Offset < 0 {
+		return e.SubExpression
+	}
+	return e.Expression + "\n" + strings.Repeat(" ", e.Offset) + "^"
+}
+
+// InternalError is returned instead of panicking when compiling or
+// evaluating an expression panics, so that no expression or document can
+// crash the program searching it.  It is a bug in this package unless the
+// panic comes from a Tracer or ValueTransformer; panics of user-registered
+// functions are returned as a FunctionError.
+type InternalError struct {
+	Value interface{} // The value passed to panic.
+	Stack []byte      // Stack trace of the goroutine that panicked.
+}
+
+func (e *InternalError) Error() string {
+	return fmt.Sprintf("internal error: %v", e.Value)
+}
+
+// recoverInternal turns a panic into an InternalError stored in *err.  It
+// is deferred by the exported functions that parse, compile or evaluate
+// expressions.
+func recoverInternal(err *error) {
+	if r := recover(); r != nil {
+		*err = &InternalError{Value: r, Stack: debug.Stack()}
+	}
+}
+
+// callError returns the error to report for a failed call of the function
+// expression node.
+func callError(node ASTNode, err error) error {
+	switch e := err.(type) {
+	case nil, *FunctionError:
+		return err
+	case *EvalError:
+		if e.SubExpression != "" {
+			// The error comes from a call within an expression reference
+			// and was already attributed to it.
+			return err
+		}
+		e.Function = node.value.(string)
+		e.SubExpression = FormatAST(node)
+		e.Offset = -1
+		e.node = node
+		return e
+	}
+	return &EvalError{
+		SubExpression: FormatAST(node),
+		Offset:        -1,
+		Function:      node.value.(string),
+		Err:           err,
+		node:          node,
+	}
+}
+
+// locateError fills in the location in expression of an EvalError
+// returned by evaluating it.  The failed call is reported as it is written
+// in expression, as compiled expressions evaluate calls the optimizer may
+// have rewritten, so that both engines return the same error.
+func locateError(expression string, err error) error {
+	if e, ok := err.(*EvalError); ok && e.Expression == "" && expression != "" {
+		e.Expression = expression
+		call, offset, found := locateCall(expression, e.node)
+		if found {
+			e.SubExpression = FormatAST(call)
+			e.node = call
+		}
+		e.Offset = offset
+	}
+	return err
+}
+
+// locateCall returns the function call node as parsed from expression, and
+// its offset in expression.  If the call cannot be told apart from other
+// calls of the same function it returns the offset of the first of them
+// and false, and if there is no such call -1.  It is only called once
+// evaluating has failed, so that successful evaluations never pay for
+// tracking offsets.
+func locateCall(expression string, node ASTNode) (ASTNode, int, bool) {
+	parser := NewParser()
+	if _, err := parser.Parse(expression); err != nil {
+		return node, -1, false
+	}
+	intr := newInterpreter()
+	target := optimize(node, intr)
+	offset := -1
+	for _, call := range parser.calls {
+		if call.name != node.value {
+			continue
+		}
+		if offset < 0 {
+			offset = call.start
+		}
+		// Several calls of the same function are told apart by their
+		// arguments, which node may hold in optimized form.
+		parsed, err := NewParser().Parse(expression[call.start:call.end])
+		if err == nil && FormatAST(optimize(parsed, intr)) == FormatAST(target) {
+			return parsed, call.start, true
+		}
+	}
+	return node, offset, false
+}
+
+// jsonType returns the name of the JSON type of value, as returned by the
+// type() function, or "expref" for expression references.
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case *OrderedMap:
+		return "object"
+	case ExpRef:
+		return "expref"
+	}
+	if name, err := jpfType([]interface{}{value}); err == nil {
+		return name.(string)
+	}
+	if isSliceType(value) {
+		return "array"
+	}
+	if isStringKeyedMap(value) {
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
@@ -0,0 +1,168 @@
+package jmespath
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func TestEvalErrorLocatesCall(t *testing.T) {
+	assert := assert.New(t)
+	data := map[string]interface{}{
+		"a":     -1.0,
+		"b":     "x",
+		"items": []interface{}{"y", "z"},
+		"x":     []interface{}{[]interface{}{map[string]interface{}{"b": 1.0}}},
+	}
+	cases := []struct {
+		expression    string
+		subExpression string
+		offset        int
+		valueType     string
+	}{
+		{"length(a)", "length(a)", 0, "number"},
+		{"[abs(a), abs(b)]", "abs(b)", 9, "string"},
+		{"items | sort_by(@, &abs(@))", "abs(@)", 20, "string"},
+		{"b && nope(b)", "nope(b)", 5, ""},
+		{"length(a, b)", "length(a, b)", 0, ""},
+		// Compiled expressions report the calls as written, not as
+		// optimized.
+		{"x[?length(@[0].b)]", "length(@[0].b)", 3, "number"},
+		{"length(@.a)", "length(@.a)", 0, "number"},
+	}
+	for _, tt := range cases {
+		_, err := Search(tt.expression, data)
+		var evalErr *EvalError
+		if assert.True(errors.As(err, &evalErr), tt.expression) {
+			assert.Equal(tt.expression, evalErr.Expression)
+			assert.Equal(tt.subExpression, evalErr.SubExpression, tt.expression)
+			assert.Equal(tt.offset, evalErr.Offset, tt.expression)
+			assert.Equal(tt.valueType, evalErr.ValueType, tt.expression)
+		}
+
+		jp := MustCompile(tt.expression)
+		_, compiledErr := jp.Search(data)
+		assert.Equal(err, compiledErr, tt.expression)
+	}
+}
+
+func TestEvalErrorMessage(t *testing.T) {
+	assert := assert.New(t)
+	_, err := Search("foo | length(@)", map[string]interface{}{"foo": nil})
+	assert.Equal("length(@) at offset 6: invalid type for argument 1: expected string or array or object, got null", err.Error())
+	assert.Equal("foo | length(@)\n      ^", err.(*EvalError).HighlightLocation())
+
+	_, err = Search("foo(@)", nil)
+	assert.Equal("foo(@) at offset 0: unknown function: foo", err.Error())
+	assert.Equal("foo", err.(*EvalError).Function)
+}
+
+func TestEvalErrorWithoutExpression(t *testing.T) {
+	assert := assert.New(t)
+	ast, err := NewParser().Parse("abs(a)")
+	assert.Nil(err)
+	jp, err := CompileAST(ast)
+	assert.Nil(err)
+	_, err = jp.Search(map[string]interface{}{"a": "x"})
+	if assert.IsType(&EvalError{}, err) {
+		evalErr := err.(*EvalError)
+		assert.Equal("", evalErr.Expression)
+		assert.Equal(-1, evalErr.Offset)
+		assert.Equal("abs(a)", evalErr.HighlightLocation())
+	}
+}
+
+func TestEvalErrorKeepsFunctionErrors(t *testing.T) {
+	assert := assert.New(t)
+	rt := NewRuntime()
+	assert.Nil(rt.RegisterFunction("boom", func(ctx CallContext, args []interface{}) (interface{}, error) {
+		panic("boom")
+	}))
+	_, err := rt.Search("boom()", nil)
+	assert.IsType(&FunctionError{}, err)
+
+	failed := errors.New("failed")
+	assert.Nil(rt.RegisterFunction("fail", func(ctx CallContext, args []interface{}) (interface{}, error) {
+		return nil, failed
+	}))
+	_, err = rt.Search("fail()", nil)
+	assert.True(errors.Is(err, failed))
+	assert.Equal("fail() at offset 0: failed", err.Error())
+}
+
+func TestInternalErrorInsteadOfPanic(t *testing.T) {
+	assert := assert.New(t)
+	jp := MustCompile("a")
+	_, err := jp.SearchWithTransformer(map[string]interface{}{"a": "x"}, func(value interface{}) (interface{}, error) {
+		panic("transformer failed")
+	})
+	var internal *InternalError
+	if assert.True(errors.As(err, &internal)) {
+		assert.Equal("transformer failed", internal.Value)
+		assert.Equal("internal error: transformer failed", err.Error())
+		assert.NotEmpty(internal.Stack)
+	}
+}
+
+type adversarialStruct struct {
+	Name   string
+	Next   *adversarialStruct
+	Any    interface{}
+	hidden int
+}
+
+func TestAdversarialSearchesDoNotPanic(t *testing.T) {
+	var nilStruct *adversarialStruct
+	var nilMap map[string]interface{}
+	documents := []interface{}{
+		nil, nilStruct, nilMap, []interface{}(nil),
+		[]int{3, 1, 2},
+		[]string{"b", "a"},
+		[]error{nil},
+		[][]int{{1}, nil},
+		[3]int{1, 2, 3},
+		map[int]string{1: "a"},
+		map[string]int{"a": 1},
+		map[string]*adversarialStruct{"a": nil},
+		[]*adversarialStruct{nil, {Name: "x"}},
+		&adversarialStruct{Name: "n", Any: adversarialStruct{}, hidden: 1},
+		make(chan int),
+		func() {},
+		map[string]interface{}{"a": []interface{}{1, "x", nil, true, int64(3), float32(1)}},
+		"s", 1.5, true, 3,
+	}
+	expressions := []string{
+		"@", "a", "name", "hidden", "next.name", "any.name", "[0]", "[-1]",
+		"[::-1]", "[*]", "*", "[]", "[?@]", "a[?@ > `0`]", "{x: @}", "[@, @]",
+		"@ < @", "!@", "@ || a", "length(@)", "keys(@)", "values(@)",
+		"sort(@)", "max(@)", "sum(@)", "avg(@)", "reverse(@)", "to_array(@)",
+		"to_string(@)", "to_number(@)", "type(@)", "not_null(@)",
+		"join(',', @)", "contains(@, `1`)", "sort_by(@, &@)",
+		"max_by(@, &@)", "map(&@, @)", "merge(@)", "merge_deep(@)",
+		"keys_deep(@)", "abs(@)", "starts_with(@, 'a')",
+	}
+	ordered := NewRuntime()
+	ordered.SetOrderedObjects(true)
+	for _, rt := range []*Runtime{NewRuntime(), strictRuntime(), ordered} {
+		for _, expression := range expressions {
+			jp, err := rt.Compile(expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, document := range documents {
+				for _, search := range []func() error{
+					func() error { _, err := jp.Search(document); return err },
+					func() error { _, err := jp.Match(document); return err },
+					func() error { _, err := jp.ExplainNull(document); return err },
+					func() error { _, err := NewCoverage(jp).Search(document); return err },
+				} {
+					var internal *InternalError
+					if err := search(); errors.As(err, &internal) {
+						t.Errorf("%s on %#v: %s\n%s", expression, document, err, internal.Stack)
+					}
+				}
+			}
+		}
+	}
+}
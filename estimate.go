@@ -0,0 +1,275 @@
+package jmespath
+
+// DocumentStats summarizes the documents an expression is evaluated
+// against, for JMESPath.Estimate.
+type DocumentStats struct {
+	// Lengths maps the paths of arrays to their number of elements, and
+	// of objects whose values are projected with "*" to their number of
+	// keys.  A path is written as an expression selecting every value it
+	// describes: "@" is the document itself, "orders" its orders array,
+	// "orders[*].items" the items array of every order and "matrix[*]"
+	// the arrays nested in matrix.  Names that are not identifiers are
+	// quoted, as in "\"first name\"".  The values of objects projected
+	// with "*" have no path.
+	Lengths map[string]int
+	// DefaultLength is the length assumed for arrays and objects that
+	// are projected without an entry in Lengths.  Zero means 10, the
+	// length ExplainPlan assumes.
+	DefaultLength int
+}
+
+// CollectStats returns the DocumentStats of samples, giving each array
+// and object the greatest length it has in any of them.
+func CollectStats(samples ...interface{}) DocumentStats {
+	stats := DocumentStats{Lengths: make(map[string]int)}
+	for _, sample := range samples {
+		stats.collect("@", sample)
+	}
+	return stats
+}
+
+func (s DocumentStats) collect(path string, value interface{}) {
+	var elements []interface{}
+	switch v := value.(type) {
+	case []interface{}:
+		elements = v
+	case map[string]interface{}:
+		if len(v) > s.Lengths[path] {
+			s.Lengths[path] = len(v)
+		}
+		for key, field := range v {
+			s.collect(childStatsPath(path, "."+formatIdentifier(key)), field)
+		}
+		return
+	default:
+		if !isSliceType(value) {
+			return
+		}
+		elements = toInterfaceSlice(value)
+	}
+	if len(elements) > s.Lengths[path] {
+		s.Lengths[path] = len(elements)
+	}
+	for _, element := range elements {
+		s.collect(childStatsPath(path, "[*]"), element)
+	}
+}
+
+// childStatsPath returns the path of the values selected by step, such as
+// ".name" or "[*]", from the values at path, or "" if path is unknown.
+func childStatsPath(path, step string) string {
+	switch {
+	case path == "":
+		return ""
+	case path == "@" && step[0] == '.':
+		return step[1:]
+	}
+	return path + step
+}
+
+// Estimate is the estimated cost and size of evaluating an expression, see
+// JMESPath.Estimate.  They are floating point numbers as the estimates
+// for nested projections can exceed the range of an int.
+type Estimate struct {
+	// Cost is the number of evaluation steps, where looking up a field
+	// costs 1, as in ExplainPlan.
+	Cost float64
+	// Cardinality is the number of values in the result: the number of
+	// elements of an array or object result whose length is known,
+	// counting those of nested arrays and objects the expression builds,
+	// such as the results of nested projections, and 1 for any other
+	// value.
+	Cardinality float64
+}
+
+// Estimate estimates the cost and the cardinality of the result of
+// evaluating the expression against a document summarized by stats,
+// without evaluating it, so that expressions that would be too expensive,
+// such as cross products of large arrays in multiselects, can be rejected
+// before they run.  The estimates are upper bounds in that filters are
+// assumed to keep every element and "&&" and "||" to evaluate both sides,
+// but the lengths that are not in stats are only guesses.
+func (jp *JMESPath) Estimate(stats DocumentStats) Estimate {
+	e := estimator{lengths: stats.Lengths, fanout: float64(stats.DefaultLength)}
+	if e.fanout <= 0 {
+		e.fanout = explainFanout
+	}
+	result := e.estimate(jp.ast, e.at("@"))
+	return Estimate{Cost: result.cost, Cardinality: result.values}
+}
+
+type estimator struct {
+	lengths map[string]int
+	fanout  float64
+}
+
+// sizeEstimate is the estimated size of the value a node evaluates to.
+type sizeEstimate struct {
+	cost float64
+	// path is the path in DocumentStats of the value, such that the
+	// elements of an array are at path+"[*]", or "" if it is unknown.
+	path string
+	// container is set when the value is known to be an array or object
+	// with length elements.
+	container bool
+	length    float64
+	// values is the cardinality of the value.
+	values float64
+}
+
+// at returns the estimate for the value at path, without its cost.
+func (e *estimator) at(path string) sizeEstimate {
+	if n, ok := e.lengths[path]; ok && path != "" {
+		return sizeEstimate{path: path, container: true, length: float64(n), values: float64(n)}
+	}
+	return sizeEstimate{path: path, values: 1}
+}
+
+// elements returns the number of elements a projection over s iterates
+// over.
+func (e *estimator) elements(s sizeEstimate) float64 {
+	if s.container {
+		return s.length
+	}
+	return e.fanout
+}
+
+func (e *estimator) estimate(node ASTNode, current sizeEstimate) sizeEstimate {
+	switch node.nodeType {
+	case ASTField:
+		result := e.at(childStatsPath(current.path, "."+formatIdentifier(node.value.(string))))
+		result.cost = 1
+		return result
+	case ASTIndex:
+		result := e.at(childStatsPath(current.path, "[*]"))
+		result.cost = 1
+		return result
+	case ASTCurrentNode, ASTIdentity:
+		current.cost = 1
+		return current
+	case ASTSlice:
+		// The elements of a slice are elements of the sliced array, so
+		// the path is kept.
+		n := 0
+		if bounds, err := sliceBounds(node, int(e.elements(current))); err == nil {
+			n = bounds.Len()
+		}
+		return sizeEstimate{cost: 1, path: current.path, container: true, length: float64(n), values: float64(n)}
+	case ASTLiteral:
+		switch v := node.value.(type) {
+		case []interface{}:
+			return sizeEstimate{cost: 1, container: true, length: float64(len(v)), values: float64(len(v))}
+		case map[string]interface{}:
+			return sizeEstimate{cost: 1, container: true, length: float64(len(v)), values: float64(len(v))}
+		}
+		return sizeEstimate{cost: 1, values: 1}
+	case ASTSubexpression, ASTIndexExpression, ASTPipe:
+		cost := 1.0
+		for _, child := range node.children {
+			current = e.estimate(child, current)
+			cost += current.cost
+		}
+		current.cost = cost
+		return current
+	case ASTProjection, ASTValueProjection, ASTFilterProjection:
+		left := e.estimate(node.children[0], current)
+		n := e.elements(left)
+		element := e.at(childStatsPath(left.path, "[*]"))
+		if node.nodeType == ASTValueProjection {
+			element = e.at("")
+		}
+		each := e.estimate(node.children[1], element)
+		perElement := each.cost
+		if node.nodeType == ASTFilterProjection {
+			perElement += e.estimate(node.children[2], element).cost
+		}
+		return sizeEstimate{cost: left.cost + n*perElement, container: true, length: n, values: n * each.values}
+	case ASTFlatten:
+		left := e.estimate(node.children[0], current)
+		n := e.elements(left)
+		if left.path == "" && left.container {
+			// The values of an array the expression built, such as the
+			// result of a projection, count the elements of its nested
+			// arrays.
+			n = left.values
+		}
+		path := left.path
+		for i := 0; i < flattenDepth(node); i++ {
+			nested, ok := e.lengths[childStatsPath(path, "[*]")]
+			if !ok || path == "" {
+				break
+			}
+			n *= float64(nested)
+			path = childStatsPath(path, "[*]")
+		}
+		return sizeEstimate{cost: left.cost + n, path: path, container: true, length: n, values: n}
+	case ASTDescendant:
+		left := e.estimate(node.children[0], current)
+		return sizeEstimate{cost: left.cost + e.fanout, container: true, length: e.fanout, values: e.fanout}
+	case ASTMultiSelectList, ASTMultiSelectHash:
+		result := sizeEstimate{cost: 1, container: true, length: float64(len(node.children))}
+		for _, child := range node.children {
+			each := e.estimate(child, current)
+			result.cost += each.cost
+			result.values += each.values
+		}
+		return result
+	case ASTKeyValPair:
+		result := e.estimate(node.children[0], current)
+		result.cost++
+		return result
+	case ASTOrExpression, ASTAndExpression:
+		left := e.estimate(node.children[0], current)
+		right := e.estimate(node.children[1], current)
+		result := left
+		if right.values > left.values {
+			result = right
+		}
+		result.cost = 1 + left.cost + right.cost
+		return result
+	case ASTFunctionExpression:
+		return e.call(node, current)
+	}
+	cost := 1.0
+	for _, child := range node.children {
+		cost += e.estimate(child, current).cost
+	}
+	return sizeEstimate{cost: cost, values: 1}
+}
+
+// call estimates a function call.  Expression references are applied to
+// every element of the array argument they come with, and the functions
+// that return an array the size of their argument, or one of their
+// arguments, are assumed to return the largest.
+func (e *estimator) call(node ASTNode, current sizeEstimate) sizeEstimate {
+	result := sizeEstimate{cost: 1, values: 1}
+	// array is the largest argument, preferring arrays and objects.
+	var array sizeEstimate
+	var refs []ASTNode
+	for _, arg := range node.children {
+		if arg.nodeType == ASTExpRef {
+			refs = append(refs, arg.children[0])
+			continue
+		}
+		estimated := e.estimate(arg, current)
+		result.cost += estimated.cost
+		if estimated.container != array.container && estimated.container ||
+			estimated.container == array.container && estimated.values > array.values {
+			array = estimated
+		}
+	}
+	n := e.elements(array)
+	element := e.at(childStatsPath(array.path, "[*]"))
+	for _, ref := range refs {
+		result.cost += n * e.estimate(ref, element).cost
+	}
+	switch node.value.(string) {
+	case "sort", "sort_by", "reverse":
+		// The result has the same elements as the argument.
+		return sizeEstimate{cost: result.cost, path: array.path, container: true, length: n, values: array.values}
+	case "map", "keys", "values", "to_array", "deep_flatten", "map_values", "filter_values", "pick", "merge",
+		"merge_deep", "coalesce", "default", "first_of", "if", "not_null":
+		return sizeEstimate{cost: result.cost, container: array.container, length: array.length, values: array.values}
+	}
+	return result
+}
@@ -0,0 +1,81 @@
+package jmespath
+
+import (
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func TestEstimate(t *testing.T) {
+	assert := assert.New(t)
+	stats := DocumentStats{Lengths: map[string]int{
+		"orders":          1000,
+		"orders[*].items": 5,
+		"customers":       200,
+		"matrix":          3,
+		"matrix[*]":       4,
+		"groups":          6,
+		"@":               2,
+		"\"first name\"":  7,
+	}}
+	cases := []struct {
+		expression  string
+		cost        float64
+		cardinality float64
+	}{
+		{"name", 1, 1},
+		{"orders", 1, 1000},
+		{"orders[0]", 3, 1},
+		{"orders[*].id", 1 + 1000, 1000},
+		{"orders[*].items[*].sku", 1 + 1000*(1+5), 5000},
+		{"orders[].items[]", 1 + 1000 + 1000 + 5000 + 5000, 5000},
+		{"orders[?total > `10`].id", 1 + 1000*(1+3), 1000},
+		{"orders[:10].id", 3 + 10, 10},
+		{"matrix[][]", 1 + 12 + 12, 12},
+		{"groups.*.name", 1 + 6, 6},
+		{"\"first name\"", 1, 7},
+		{"@[*]", 1 + 2, 2},
+		{"unknown[*].id", 1 + 10, 10},
+		{"[orders, customers]", 3, 1200},
+		{"orders[*].[id, customers[*].id]", 1 + 1000*(1+1+1+10), 1000 * 11},
+		{"sort_by(orders, &total)[*].id", 1 + 1 + 1000 + 1000, 1000},
+		{"length(orders)", 2, 1},
+		{"`[1, 2, 3]`", 1, 3},
+		{"orders || customers", 3, 1000},
+	}
+	for _, tt := range cases {
+		estimate := MustCompile(tt.expression).Estimate(stats)
+		assert.Equal(Estimate{Cost: tt.cost, Cardinality: tt.cardinality}, estimate, tt.expression)
+	}
+	stats.DefaultLength = 100
+	assert.Equal(Estimate{Cost: 101, Cardinality: 100}, MustCompile("unknown[*].id").Estimate(stats))
+}
+
+func TestEstimateNestedProjections(t *testing.T) {
+	assert := assert.New(t)
+	stats := DocumentStats{Lengths: map[string]int{"a": 10000, "a[*].b": 10000}}
+	flat := MustCompile("a[*].id").Estimate(stats)
+	nested := MustCompile("a[*].b[*].[id, name]").Estimate(stats)
+	assert.Equal(10001.0, flat.Cost)
+	assert.True(nested.Cost > 1e8)
+	assert.Equal(2e8, nested.Cardinality)
+}
+
+func TestCollectStats(t *testing.T) {
+	assert := assert.New(t)
+	stats := CollectStats(
+		decodeJSON(`{"orders": [{"items": [1, 2]}, {"items": [1, 2, 3]}], "groups": {"a": 1, "b": 2}, "first name": "x"}`),
+		decodeJSON(`{"orders": [{"items": [[1], 2]}], "matrix": [[1, 2], [3]]}`),
+		decodeJSON(`[1, 2]`),
+	)
+	assert.Equal(map[string]int{
+		"@":                  3,
+		"orders":             2,
+		"orders[*]":          1,
+		"orders[*].items":    3,
+		"orders[*].items[*]": 1,
+		"groups":             2,
+		"matrix":             2,
+		"matrix[*]":          2,
+	}, stats.Lengths)
+}
@@ -0,0 +1,184 @@
+package jmespath
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// explainFanout is the number of elements a projection is assumed to
+// iterate over when estimating the cost of an expression.
+const explainFanout = 10
+
+// maxExplainCost is the largest cost ExplainPlan reports.  Costs grow
+// exponentially with the nesting of projections, so they are capped
+// rather than overflow.
+const maxExplainCost = math.MaxInt32
+
+// ExplainPlan returns a textual description of how the expression will be
+// evaluated, analogous to a SQL EXPLAIN.  Each line describes one
+// evaluation step along with its estimated cost, where a single field
+// lookup costs 1 and projections are assumed to iterate over 10 elements.
+// The format is intended for humans and may change between releases.
+func (jp *JMESPath) ExplainPlan() string {
+	var b strings.Builder
+	explainNode(&b, jp.ast, "", 0)
+	return b.String()
+}
+
+func explainNode(b *strings.Builder, node ASTNode, label string, indent int) {
+	b.WriteString(strings.Repeat("  ", indent))
+	if label != "" {
+		b.WriteString(label + ": ")
+	}
+	fmt.Fprintf(b, "%s (cost=%d)\n", describeNode(node), estimateCost(node))
+	for i, child := range node.children {
+		explainNode(b, child, childLabel(node, i), indent+1)
+	}
+}
+
+func describeNode(node ASTNode) string {
+	switch node.nodeType {
+	case ASTField:
+		return "Field " + strconv.Quote(node.value.(string))
+	case ASTIndex:
+		return fmt.Sprintf("Index [%d]", node.value)
+	case ASTSlice:
+		parts := make([]string, 3)
+		for i, part := range node.value.([]*int) {
+			if part != nil {
+				parts[i] = strconv.Itoa(*part)
+			}
+		}
+		return "Slice [" + strings.Join(parts, ":") + "]"
+	case ASTLiteral:
+		encoded, err := json.Marshal(node.value)
+		if err != nil {
+			return "Literal"
+		}
+		return "Literal " + string(encoded)
+	case ASTParameter:
+		return "Parameter :" + node.value.(string)
+	case ASTCurrentNode:
+		return "Current node @"
+	case ASTIdentity:
+		return "Identity"
+	case ASTComparator:
+		return "Compare " + comparatorSymbol(node.value.(tokType))
+	case ASTFunctionExpression:
+		return fmt.Sprintf("Call %s() with %d argument(s)", node.value, len(node.children))
+	case ASTExpRef:
+		return "Expression reference &"
+	case ASTFilterProjection:
+		return "Filter projection, keeping elements where the condition is truthy"
+	case ASTProjection:
+		return "List projection over array elements, dropping nulls"
+	case ASTValueProjection:
+		return "Object projection over object values, dropping nulls"
+	case ASTDescendant:
+		return "Recursive descent collecting key " + strconv.Quote(node.value.(string)) + " at any depth"
+	case ASTFlatten:
+		if depth := flattenDepth(node); depth > 1 {
+			return fmt.Sprintf("Flatten %d levels of nested arrays", depth)
+		}
+		return "Flatten one level of nested arrays"
+	case ASTSubexpression, ASTIndexExpression:
+		return "Subexpression, evaluating the right side against the left result"
+	case ASTPipe:
+		return "Pipe, stopping any projection on the left"
+	case ASTOrExpression:
+		return "Or, short-circuiting on a truthy left side"
+	case ASTAndExpression:
+		return "And, short-circuiting on a falsy left side"
+	case ASTNotExpression:
+		return "Not"
+	case ASTMultiSelectList:
+		return fmt.Sprintf("Multiselect list of %d expression(s)", len(node.children))
+	case ASTMultiSelectHash:
+		return fmt.Sprintf("Multiselect hash of %d key(s)", len(node.children))
+	case ASTKeyValPair:
+		return "Key " + strconv.Quote(node.value.(string))
+	}
+	return node.nodeType.String()
+}
+
+func childLabel(node ASTNode, i int) string {
+	switch node.nodeType {
+	case ASTProjection, ASTValueProjection:
+		return [...]string{"input", "each"}[i]
+	case ASTFilterProjection:
+		return [...]string{"input", "each", "condition"}[i]
+	case ASTSubexpression, ASTIndexExpression, ASTComparator, ASTOrExpression, ASTAndExpression:
+		return [...]string{"left", "right"}[i]
+	case ASTFunctionExpression:
+		return "arg " + strconv.Itoa(i)
+	case ASTPipe:
+		return "stage " + strconv.Itoa(i)
+	}
+	return ""
+}
+
+func comparatorSymbol(t tokType) string {
+	switch t {
+	case tEQ:
+		return "=="
+	case tNE:
+		return "!="
+	case tLT:
+		return "<"
+	case tLTE:
+		return "<="
+	case tGT:
+		return ">"
+	case tGTE:
+		return ">="
+	case tIn:
+		return "in"
+	case tNotIn:
+		return "not in"
+	}
+	return t.String()
+}
+
+// estimateCost returns the relative cost of evaluating node, assuming
+// every projection iterates over explainFanout elements, up to
+// maxExplainCost.
+func estimateCost(node ASTNode) int {
+	switch node.nodeType {
+	case ASTProjection, ASTValueProjection:
+		return addCost(estimateCost(node.children[0]), fanOutCost(estimateCost(node.children[1])))
+	case ASTFilterProjection:
+		perElement := addCost(estimateCost(node.children[1]), estimateCost(node.children[2]))
+		return addCost(estimateCost(node.children[0]), fanOutCost(perElement))
+	case ASTFlatten, ASTDescendant:
+		return addCost(estimateCost(node.children[0]), explainFanout)
+	case ASTExpRef:
+		// Functions taking expression references apply them once per
+		// element of an array argument.
+		return fanOutCost(estimateCost(node.children[0]))
+	}
+	cost := 1
+	for _, child := range node.children {
+		cost = addCost(cost, estimateCost(child))
+	}
+	return cost
+}
+
+// addCost returns the sum of two costs, up to maxExplainCost.
+func addCost(a, b int) int {
+	if a > maxExplainCost-b {
+		return maxExplainCost
+	}
+	return a + b
+}
+
+// fanOutCost returns the cost of evaluating something of the given cost
+// for explainFanout elements, up to maxExplainCost.
+func fanOutCost(cost int) int {
+	if cost > maxExplainCost/explainFanout {
+		return maxExplainCost
+	}
+	return cost * explainFanout
+}
@@ -0,0 +1,38 @@
+package jmespath
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func TestExplainPlan(t *testing.T) {
+	assert := assert.New(t)
+	jp := MustCompile("foo[?bar > `1`].baz")
+	expected := `Filter projection, keeping elements where the condition is truthy (cost=41)
+  input: Field "foo" (cost=1)
+  each: Field "baz" (cost=1)
+  condition: Compare > (cost=3)
+    left: Field "bar" (cost=1)
+    right: Literal 1 (cost=1)
+`
+	assert.Equal(expected, jp.ExplainPlan())
+}
+
+func TestExplainPlanCostGrowsWithNesting(t *testing.T) {
+	assert := assert.New(t)
+	flat := MustCompile("a[*].b")
+	nested := MustCompile("a[*].b[*].c")
+	assert.Equal(11, estimateCost(flat.ast))
+	assert.True(estimateCost(nested.ast) > estimateCost(flat.ast))
+}
+
+func TestExplainPlanCapsCost(t *testing.T) {
+	assert := assert.New(t)
+	deep := MustCompile(strings.Repeat("a[*].", 30) + "b")
+	assert.Equal(maxExplainCost, estimateCost(deep.ast))
+	assert.NotContains(deep.ExplainPlan(), "cost=-")
+	filters := MustCompile(strings.Repeat("a[?", 30) + "b" + strings.Repeat("]", 30))
+	assert.Equal(maxExplainCost, estimateCost(filters.ast))
+}
@@ -0,0 +1,100 @@
+package jmespath
+
+import "strings"
+
+// keyMatch finds the key of an object that a field matches when keys are
+// compared case-insensitively, see Runtime.SetCaseInsensitiveKeys.  A key
+// equal to the field wins, and otherwise the smallest of the keys equal to
+// it under Unicode case folding, in byte order, so that the match does not
+// depend on the order the keys are visited in.
+type keyMatch struct {
+	field string
+	key   string
+	found bool
+}
+
+// add considers key as a match for the field.
+func (m *keyMatch) add(key string) {
+	if m.found && (m.key == m.field || key >= m.key) && key != m.field {
+		return
+	}
+	if strings.EqualFold(key, m.field) {
+		m.key = key
+		m.found = true
+	}
+}
+
+// foldKey returns the key of the object value that the field key matches
+// case-insensitively, or key itself if value is not an object or has no
+// such key.
+func foldKey(key string, value interface{}) string {
+	m := keyMatch{field: key}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if _, ok := v[key]; ok {
+			return key
+		}
+		for k := range v {
+			m.add(k)
+		}
+	case map[string]string:
+		if _, ok := v[key]; ok {
+			return key
+		}
+		for k := range v {
+			m.add(k)
+		}
+	case *OrderedMap:
+		if _, ok := v.values[key]; ok {
+			return key
+		}
+		for _, k := range v.keys {
+			m.add(k)
+		}
+	default:
+		if !isStringKeyedMap(value) {
+			return key
+		}
+		mapEntries(value, func(k string, _ interface{}) {
+			m.add(k)
+		})
+	}
+	if m.found {
+		return m.key
+	}
+	return key
+}
+
+// fieldKey returns the key of the object value that the field key reads:
+// key itself, unless keys are matched case-insensitively.
+func (intr *treeInterpreter) fieldKey(key string, value interface{}) (string, error) {
+	if !intr.foldKeys {
+		return key, nil
+	}
+	if r, ok := value.(Resolver); ok {
+		return foldResolverKey(key, r)
+	}
+	return foldKey(key, value), nil
+}
+
+// foldResolverKey returns the key of the object resolver r that the field
+// key matches case-insensitively, or key itself if r does not list its
+// keys or has no such key.
+func foldResolverKey(key string, r Resolver) (string, error) {
+	keyed, ok := r.(KeyedResolver)
+	if !ok {
+		return key, nil
+	}
+	keys, err := keyed.Keys()
+	if err != nil {
+		return "", err
+	}
+	m := keyMatch{field: key}
+	for _, k := range keys {
+		m.add(k)
+	}
+	if m.found {
+		return m.key, nil
+	}
+	return key, nil
+}
@@ -0,0 +1,151 @@
+package jmespath
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func TestCaseInsensitiveKeys(t *testing.T) {
+	assert := assert.New(t)
+	var data interface{}
+	assert.Nil(json.Unmarshal([]byte(`{
+		"Events": [
+			{"EventID": 4624, "Level": "Information"},
+			{"eventId": 4625, "LEVEL": "Warning"},
+			{"EVENTID": 4634, "level": "Information", "Level": "Error"}
+		],
+		"name": "exact", "NAME": "upper", "Name": "title",
+		"Host": "web1", "HOST": "web2"
+	}`), &data))
+	rt := NewRuntime()
+	rt.SetCaseInsensitiveKeys(true)
+	cases := []struct {
+		expression string
+		expected   interface{}
+	}{
+		{"events[*].eventid", []interface{}{4624.0, 4625.0, 4634.0}},
+		{"events[?level == 'Information'].eventid", []interface{}{4624.0, 4634.0}},
+		{"events[2].Level", "Error"},
+		{"events[2].LEVEL", "Error"},
+		{"name", "exact"},
+		{"nAmE", "upper"},
+		{"host", "web2"},
+		{"Host", "web1"},
+		{"missing", nil},
+		{"{h: HOST, n: Name}", map[string]interface{}{"h": "web2", "n": "title"}},
+	}
+	for _, tt := range cases {
+		result, err := rt.Search(tt.expression, data)
+		assert.Nil(err, tt.expression)
+		assert.Equal(tt.expected, result, tt.expression)
+	}
+
+	result, err := Search("events[*].eventid", data)
+	assert.Nil(err)
+	assert.Nil(result, "keys are case-sensitive by default")
+	result, err = SearchWith("events[*].eventid", data, WithCaseInsensitiveKeys(true))
+	assert.Nil(err)
+	assert.Equal([]interface{}{4624.0, 4625.0, 4634.0}, result)
+	jp, err := CompileWith("events[0].level", WithCaseInsensitiveKeys(true), WithStrict(true))
+	if assert.Nil(err) {
+		result, err = jp.Search(data)
+		assert.Nil(err)
+		assert.Equal("Information", result)
+		_, err = jp.Search(map[string]interface{}{"events": []interface{}{map[string]interface{}{}}})
+		assert.True(errors.Is(err, ErrMissingKey))
+	}
+}
+
+func TestCaseInsensitiveKeysOfGoValues(t *testing.T) {
+	assert := assert.New(t)
+	rt := NewRuntime()
+	rt.SetCaseInsensitiveKeys(true)
+	ordered := NewOrderedMap()
+	ordered.Set("b", 1.0)
+	ordered.Set("Key", 2.0)
+	ordered.Set("KEY", 3.0)
+	cases := []struct {
+		data     interface{}
+		expected interface{}
+	}{
+		{map[string]string{"Key": "a", "kEY": "b"}, "a"},
+		{map[string][]string{"KEY": {"a"}}, []interface{}{"a"}},
+		{ordered, 3.0},
+	}
+	for _, tt := range cases {
+		result, err := rt.Search("key", tt.data)
+		assert.Nil(err)
+		assert.Equal(tt.expected, result)
+	}
+}
+
+func TestCaseInsensitiveKeysOfResolvers(t *testing.T) {
+	assert := assert.New(t)
+	var data interface{}
+	assert.Nil(json.Unmarshal([]byte(`{"Order": {"ID": 7}}`), &data))
+	root, resolved := newLazyValue(data)
+	result, err := SearchWith("order.id", root, WithCaseInsensitiveKeys(true))
+	assert.Nil(err)
+	assert.Equal(7.0, result)
+	assert.Equal([]string{".Order", ".Order.ID"}, *resolved)
+}
+
+func TestCaseInsensitiveColumns(t *testing.T) {
+	assert := assert.New(t)
+	jp, err := CompileWith("PRICE > `10`", WithCaseInsensitiveKeys(true))
+	if assert.Nil(err) {
+		result, err := jp.EvalColumns(table)
+		assert.Nil(err)
+		assert.Equal([]interface{}{false, true, true, nil}, result)
+	}
+}
+
+func TestCaseInsensitiveKeysFollowMatchedKeys(t *testing.T) {
+	assert := assert.New(t)
+	fold := WithCaseInsensitiveKeys(true)
+	data := decodeJSON(`{"Items": [{"ID": 1}]}`)
+
+	jp, err := CompileWith("items[*].id", fold)
+	assert.Nil(err)
+	located, err := jp.SearchPaths(data)
+	assert.Nil(err)
+	if assert.Len(located, 1) {
+		assert.Equal("/Items/0/ID", located[0].Pointer)
+		assert.Equal("Items[0].ID", located[0].Path)
+	}
+
+	updated, err := jp.Set(data, 2.0)
+	assert.Nil(err)
+	assert.Equal(decodeJSON(`{"Items": [{"ID": 2}]}`), updated)
+	deleted, err := jp.Delete(data)
+	assert.Nil(err)
+	assert.Equal(decodeJSON(`{"Items": [{}]}`), deleted)
+
+	jp, err = CompileWith("a.name", fold)
+	assert.Nil(err)
+	inc, err := jp.Incremental(decodeJSON(`{"a": {"Name": "x"}}`))
+	assert.Nil(err)
+	changed, err := inc.ApplyPatch([]byte(`[{"op": "replace", "path": "/a/Name", "value": "y"}]`))
+	assert.Nil(err)
+	assert.True(changed)
+	assert.Equal("y", inc.Result())
+
+	schema, err := NewSchema([]byte(`{
+		"type": "object",
+		"properties": {"state": {"type": "string"}},
+		"patternProperties": {"^x-": {"type": "number"}},
+		"additionalProperties": false
+	}`))
+	assert.Nil(err)
+	rt := NewRuntime()
+	_, err = rt.CompileWithSchema("STATE", schema)
+	assert.NotNil(err)
+	rt.SetCaseInsensitiveKeys(true)
+	_, err = rt.CompileWithSchema(`STATE || "X-count"`, schema)
+	assert.Nil(err)
+	_, err = rt.CompileWithSchema("stat", schema)
+	assert.NotNil(err)
+}
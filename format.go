@@ -0,0 +1,288 @@
+package jmespath
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/* The formatter renders an AST back into an expression.  Parentheses are
+   only added where the parser would otherwise build a different AST, which
+   is decided from the binding powers the parser uses:
+
+   - A node built by a led is only parsed as the right operand of an
+     operator if its led token binds tighter than the operator, see
+     ledPower.
+   - A node only stays the left operand of an operator if its own right
+     operand, which is parsed first, does not bind the operator, see the
+     absorb value returned by format.
+*/
+
+// unbound is the binding power of nodes that cannot be split by a
+// neighbouring operator, such as fields or nodes in parentheses.
+const unbound = 1 << 30
+
+// Format returns expression in canonical form, with insignificant
+// whitespace and parentheses removed, and single spaces around binary
+// operators and after commas and colons.  The result parses to the same
+// AST as expression.
+func Format(expression string) (_ string, err error) {
+	defer recoverInternal(&err)
+	parser := NewParser()
+	ast, err := parser.Parse(expression)
+	if err != nil {
+		return "", err
+	}
+	return FormatAST(ast), nil
+}
+
+// FormatAST renders an AST returned by Parser.Parse as an expression in
+// canonical form, see Format.  ASTs that have been optimized, such as the
+// normal forms of Normalize, are rendered as an equivalent expression.
+func FormatAST(node ASTNode) string {
+	formatted, _ := format(node)
+	if formatted == "" {
+		return "@"
+	}
+	return formatted
+}
+
+// format renders node and returns the lowest binding power any operator
+// following the rendered node must have to apply to the whole node.
+func format(node ASTNode) (string, int) {
+	switch node.nodeType {
+	case ASTField:
+		return formatIdentifier(node.value.(string)), unbound
+	case ASTLiteral:
+		return formatLiteral(node.value), unbound
+	case ASTParameter:
+		return ":" + node.value.(string), unbound
+	case ASTCurrentNode:
+		return "@", unbound
+	case ASTIdentity:
+		return "", unbound
+	case ASTIndex:
+		return fmt.Sprintf("[%d]", node.value), unbound
+	case ASTSlice:
+		parts := make([]string, 3)
+		for i, part := range node.value.([]*int) {
+			if part != nil {
+				parts[i] = strconv.Itoa(*part)
+			}
+		}
+		if parts[2] == "" {
+			parts = parts[:2]
+		}
+		return "[" + strings.Join(parts, ":") + "]", unbound
+	case ASTIndexExpression:
+		right, _ := format(node.children[1])
+		return formatLeft(node.children[0], bindingPowers[tLbracket]) + right, unbound
+	case ASTSubexpression:
+		right, absorb := format(node.children[1])
+		return formatLeft(node.children[0], bindingPowers[tDot]) + "." + right, min(bindingPowers[tDot], absorb)
+	case ASTProjection:
+		var left string
+		power := bindingPowers[tStar]
+		switch first := node.children[0]; first.nodeType {
+		case ASTFlatten:
+			left, power = formatFlatten(first), bindingPowers[tFlatten]
+		case ASTIndexExpression, ASTDescendant:
+			left, _ = format(first)
+		case ASTSlice:
+			// A slice of the current node whose "@" was optimized
+			// away.
+			left, _ = format(first)
+		default:
+			left = formatLeft(first, bindingPowers[tLbracket]) + "[*]"
+		}
+		right, absorb := formatProjectionRHS(node.children[1], power)
+		return left + right, absorb
+	case ASTFilterProjection:
+		condition, _ := format(node.children[2])
+		left := formatLeft(node.children[0], bindingPowers[tFilter]) + "[?" + condition + "]"
+		right, absorb := formatProjectionRHS(node.children[1], bindingPowers[tFilter])
+		return left + right, absorb
+	case ASTValueProjection:
+		left := "*"
+		power := bindingPowers[tStar]
+		if node.children[0].nodeType != ASTIdentity {
+			left = formatLeft(node.children[0], bindingPowers[tDot]) + ".*"
+			power = bindingPowers[tDot]
+		}
+		right, absorb := formatProjectionRHS(node.children[1], power)
+		return left + right, absorb
+	case ASTDescendant:
+		left := ""
+		if node.children[0].nodeType != ASTIdentity {
+			left = formatLeft(node.children[0], bindingPowers[tDotDot])
+		}
+		return left + ".." + formatIdentifier(node.value.(string)), unbound
+	case ASTFlatten:
+		return formatFlatten(node), bindingPowers[tFlatten]
+	case ASTComparator:
+		return formatBinary(node, bindingPowers[node.value.(tokType)], comparatorSymbol(node.value.(tokType)))
+	case ASTOrExpression:
+		return formatBinary(node, bindingPowers[tOr], "||")
+	case ASTAndExpression:
+		return formatBinary(node, bindingPowers[tAnd], "&&")
+	case ASTPipe:
+		power := bindingPowers[tPipe]
+		stages := []string{formatLeft(node.children[0], power)}
+		absorb := unbound
+		for i, child := range node.children[1:] {
+			var stage string
+			stage, absorb = formatOperand(child, power)
+			if i < len(node.children)-2 && absorb < power {
+				// The stage is also the left operand of the next pipe.
+				stage, absorb = "("+stage+")", unbound
+			}
+			stages = append(stages, stage)
+		}
+		return strings.Join(stages, " | "), min(power, absorb)
+	case ASTNotExpression:
+		operand, absorb := formatOperand(node.children[0], bindingPowers[tNot])
+		return "!" + operand, min(bindingPowers[tNot], absorb)
+	case ASTExpRef:
+		operand, _ := formatOperand(node.children[0], bindingPowers[tExpref])
+		if strings.HasPrefix(operand, "&") {
+			// "&&" would be read as the and operator.
+			operand = "(" + operand + ")"
+		}
+		return "&" + operand, bindingPowers[tExpref]
+	case ASTFunctionExpression:
+		return node.value.(string) + "(" + formatList(node.children) + ")", unbound
+	case ASTMultiSelectList:
+		return "[" + formatList(node.children) + "]", unbound
+	case ASTMultiSelectHash:
+		pairs := make([]string, len(node.children))
+		for i, child := range node.children {
+			value, _ := format(child.children[0])
+			pairs[i] = formatIdentifier(child.value.(string)) + ": " + value
+		}
+		return "{" + strings.Join(pairs, ", ") + "}", unbound
+	}
+	return node.nodeType.String(), unbound
+}
+
+// formatLeft renders node as the left operand of an operator with the
+// given binding power.
+func formatLeft(node ASTNode, power int) string {
+	formatted, absorb := format(node)
+	if absorb < power {
+		return "(" + formatted + ")"
+	}
+	return formatted
+}
+
+// formatOperand renders node as an operand parsed with the given binding
+// power, such as the right operand of a binary operator.
+func formatOperand(node ASTNode, power int) (string, int) {
+	formatted, absorb := format(node)
+	if ledPower(node) <= power {
+		return "(" + formatted + ")", unbound
+	}
+	return formatted, absorb
+}
+
+func formatBinary(node ASTNode, power int, operator string) (string, int) {
+	right, absorb := formatOperand(node.children[1], power)
+	return formatLeft(node.children[0], power) + " " + operator + " " + right, min(power, absorb)
+}
+
+func formatFlatten(node ASTNode) string {
+	return formatLeft(node.children[0], bindingPowers[tFlatten]) + strings.Repeat("[]", flattenDepth(node))
+}
+
+// formatProjectionRHS renders the expression applied to each element of a
+// projection whose operator has the given binding power, and returns the
+// absorb value of the whole projection.
+func formatProjectionRHS(node ASTNode, power int) (string, int) {
+	formatted, absorb := format(node)
+	switch {
+	case node.nodeType == ASTMultiSelectList || node.nodeType == ASTMultiSelectHash:
+		// The parser does not continue the expression after a
+		// multiselect that directly follows the dot of a projection, so
+		// nothing that follows belongs to the projection.
+		return "." + formatted, unbound
+	case formatted == "" || strings.HasPrefix(formatted, "[") || strings.HasPrefix(formatted, ".."):
+		return formatted, min(power, absorb)
+	}
+	return "." + formatted, min(power, absorb)
+}
+
+func formatList(nodes []ASTNode) string {
+	formatted := make([]string, len(nodes))
+	for i, node := range nodes {
+		formatted[i], _ = format(node)
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// ledPower returns the binding power of the token the parser builds node
+// with when it follows its left operand, or unbound for nodes that are
+// built when they start an expression.
+func ledPower(node ASTNode) int {
+	isIdentity := func(node ASTNode) bool {
+		return node.nodeType == ASTIdentity
+	}
+	switch node.nodeType {
+	case ASTPipe:
+		return bindingPowers[tPipe]
+	case ASTOrExpression:
+		return bindingPowers[tOr]
+	case ASTAndExpression:
+		return bindingPowers[tAnd]
+	case ASTComparator:
+		return bindingPowers[node.value.(tokType)]
+	case ASTSubexpression:
+		return bindingPowers[tDot]
+	case ASTIndexExpression:
+		if !isIdentity(node.children[0]) {
+			return bindingPowers[tLbracket]
+		}
+	case ASTProjection:
+		first := node.children[0]
+		switch first.nodeType {
+		case ASTFlatten:
+			return ledPower(first)
+		case ASTIndexExpression, ASTDescendant:
+			return ledPower(first)
+		case ASTSlice:
+			return unbound
+		}
+		if !isIdentity(first) {
+			return bindingPowers[tLbracket]
+		}
+	case ASTFlatten:
+		if !isIdentity(node.children[0]) {
+			return bindingPowers[tFlatten]
+		}
+	case ASTFilterProjection:
+		if !isIdentity(node.children[0]) {
+			return bindingPowers[tFilter]
+		}
+	case ASTDescendant:
+		if !isIdentity(node.children[0]) {
+			return bindingPowers[tDotDot]
+		}
+	case ASTValueProjection:
+		if !isIdentity(node.children[0]) {
+			return bindingPowers[tDot]
+		}
+	}
+	return unbound
+}
+
+func formatLiteral(value interface{}) string {
+	if s, ok := value.(string); ok && !strings.Contains(s, `\`) {
+		return "'" + strings.Replace(s, "'", `\'`, -1) + "'"
+	}
+	return "`" + strings.Replace(encodeJSON(value), "`", "\\`", -1) + "`"
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
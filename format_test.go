@@ -0,0 +1,95 @@
+package jmespath
+
+import (
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+var formatTests = []struct {
+	expression string
+	expected   string
+}{
+	{"foo.bar", "foo.bar"},
+	{"  foo . \"bar baz\"  ", `foo."bar baz"`},
+	{"((a||b))&&c", "(a || b) && c"},
+	{"a || (b && c)", "a || b && c"},
+	{"(a || b) || c", "a || b || c"},
+	{"a || (b || c)", "a || (b || c)"},
+	{"a|b|(c|d)", "a | b | (c | d)"},
+	{"!(a == b)", "!(a == b)"},
+	{"!a == b", "!a == b"},
+	{"foo[*].bar[0]", "foo[*].bar[0]"},
+	{"(foo[*].bar)[0]", "(foo[*].bar)[0]"},
+	{"foo[?a>`1`]|[0]", "foo[?a > `1`] | [0]"},
+	{"foo[].bar[]", "foo[].bar[]"},
+	{"foo[ 1 : : -1 ]", "foo[1::-1]"},
+	{"foo[:2]", "foo[:2]"},
+	{"*.*", "*.*"},
+	{"foo.*.bar", "foo.*.bar"},
+	{"foo[*].[a,b]", "foo[*].[a, b]"},
+	{"foo[*].[a,b].c", "foo[*].[a, b].c"},
+	{"foo[*].{a:a}[0]", "foo[*].{a: a}[0]"},
+	{"A[*][*].[A].{A:A}", "A[*][*].[A].{A: A}"},
+	{"{a:foo,\"b c\":bar}", `{a: foo, "b c": bar}`},
+	{"sort_by(people,&age)", "sort_by(people, &age)"},
+	{"map(& &a, b)", "map(&(&a), b)"},
+	{"`\"it's\"`", `'it\'s'`},
+	{`'a\'b'`, `'a\'b'`},
+	{"`\"a\\\\b\"`", "`\"a\\\\b\"`"},
+	{"`{\"a\": [1, 2.5, null]}`", "`{\"a\":[1,2.5,null]}`"},
+	{"`\"has \\` tick\"`", "'has ` tick'"},
+	{"`[\"a\\`b\"]`", "`[\"a\\`b\"]`"},
+	{"@", "@"},
+	{"[0]", "[0]"},
+	{"foo == :name", "foo == :name"},
+}
+
+func TestFormat(t *testing.T) {
+	assert := assert.New(t)
+	for _, tt := range formatTests {
+		actual, err := Format(tt.expression)
+		if assert.Nil(err, tt.expression) {
+			assert.Equal(tt.expected, actual, tt.expression)
+		}
+	}
+	_, err := Format("foo[")
+	assert.NotNil(err)
+}
+
+func TestFormatOptimizedAST(t *testing.T) {
+	assert := assert.New(t)
+	optimized := func(expression string) ASTNode {
+		ast, err := NewParser().Parse(expression)
+		assert.Nil(err, expression)
+		return optimize(ast, newInterpreter())
+	}
+	assert.Equal("foo[][][].bar | baz", FormatAST(optimized("foo[][][].bar | @ | baz")))
+	for expression, expected := range map[string]string{
+		"[:2].a":      "[:2].a",
+		"@[:2]":       "[:2]",
+		"a | @[::2]":  "a | [::2]",
+		"[1:].a || b": "[1:].a || b",
+	} {
+		assert.Equal(expected, FormatAST(optimized(expression)), expression)
+	}
+}
+
+// TestFormatRoundTrip checks that formatting an expression of the
+// compliance test suite gives an expression with the same AST.
+func TestFormatRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+	parser := NewParser()
+	forEachComplianceCase(assert, func(given interface{}, testcase TestCase, msg string) {
+		expected, err := parser.Parse(testcase.Expression)
+		if !assert.Nil(err, msg) {
+			return
+		}
+		formatted := FormatAST(expected)
+		msg += ", formatted: " + formatted
+		actual, err := parser.Parse(formatted)
+		if assert.Nil(err, msg) {
+			assert.Equal(expected, actual, msg)
+		}
+	})
+}
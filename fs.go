@@ -0,0 +1,51 @@
+//go:build go1.16
+// +build go1.16
+
+package jmespath
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+)
+
+// FilenameKey is the field SearchFS adds to each document to record the
+// name of the file it was read from.  As "$" cannot start an unquoted
+// identifier, it is referenced in expressions as "\"$filename\"".
+const FilenameKey = "$filename"
+
+// SearchFS reads the JSON files in fsys whose names match the pattern glob,
+// as understood by fs.Glob, and evaluates the expression against an array
+// of their contents in name order.  Files that contain an object get an
+// additional FilenameKey field holding the name of the file, so for example
+// the expression
+//
+//	[?replicas > `3`]."$filename"
+//
+// returns the names of the files that set more than three replicas.
+func SearchFS(fsys fs.FS, glob string, expression string) (interface{}, error) {
+	jp, err := Compile(expression)
+	if err != nil {
+		return nil, err
+	}
+	names, err := fs.Glob(fsys, glob)
+	if err != nil {
+		return nil, err
+	}
+	documents := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		var document interface{}
+		if err := json.Unmarshal(data, &document); err != nil {
+			return nil, fmt.Errorf("%s: %s", name, err)
+		}
+		if object, ok := document.(map[string]interface{}); ok {
+			object[FilenameKey] = name
+		}
+		documents = append(documents, document)
+	}
+	return jp.Search(documents)
+}
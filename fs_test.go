@@ -0,0 +1,41 @@
+//go:build go1.16
+// +build go1.16
+
+package jmespath
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func TestSearchFS(t *testing.T) {
+	assert := assert.New(t)
+	fsys := fstest.MapFS{
+		"deploy/web.json":    {Data: []byte(`{"replicas": 5}`)},
+		"deploy/worker.json": {Data: []byte(`{"replicas": 2}`)},
+		"deploy/api.json":    {Data: []byte(`{"replicas": 4}`)},
+		"deploy/list.json":   {Data: []byte(`[1, 2]`)},
+		"deploy/notes.txt":   {Data: []byte(`not json`)},
+	}
+	result, err := SearchFS(fsys, "deploy/*.json", "[?replicas > `3`].\"$filename\"")
+	assert.Nil(err)
+	assert.Equal([]interface{}{"deploy/api.json", "deploy/web.json"}, result)
+
+	result, err = SearchFS(fsys, "deploy/*.json", "length(@)")
+	assert.Nil(err)
+	assert.Equal(4.0, result)
+}
+
+func TestSearchFSErrors(t *testing.T) {
+	assert := assert.New(t)
+	fsys := fstest.MapFS{"bad.json": {Data: []byte(`{`)}}
+	_, err := SearchFS(fsys, "*.json", "@")
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "bad.json")
+	_, err = SearchFS(fsys, "[", "@")
+	assert.NotNil(err)
+	_, err = SearchFS(fsys, "*.json", "foo[")
+	assert.NotNil(err)
+}
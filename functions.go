@@ -1,19 +1,57 @@
 package jmespath
 
 import (
+	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
-	"reflect"
+	"math/big"
+	"net/url"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 	"unicode/utf8"
+
+	"github.com/jmespath/go-jmespath/internal/norm"
 )
 
 type jpFunction func(arguments []interface{}) (interface{}, error)
 
+// Function is the signature of a user-registered function.  The arguments
+// are passed as evaluated, except for expression references which are
+// passed as ExpRef values and can be evaluated with ctx.Apply.
+type Function func(ctx CallContext, arguments []interface{}) (interface{}, error)
+
+// CallContext is passed to user-registered functions and gives them access
+// to the interpreter that is evaluating the function call.
+type CallContext struct {
+	name string
+	intr *treeInterpreter
+	done <-chan struct{}
+}
+
+// Name returns the name the function was called by.
+func (ctx CallContext) Name() string {
+	return ctx.name
+}
+
+// Done returns a channel that is closed when the function has exceeded its
+// time limit and its result will be discarded.  Long running functions
+// should stop working when it is closed.  The channel is nil, and never
+// closed, for functions without a time limit.
+func (ctx CallContext) Done() <-chan struct{} {
+	return ctx.done
+}
+
+// Apply evaluates the expression reference ref against value and returns
+// the result.
+func (ctx CallContext) Apply(ref ExpRef, value interface{}) (interface{}, error) {
+	return ctx.intr.Execute(ref.ref, value)
+}
+
 type jpType string
 
 const (
@@ -31,90 +69,41 @@ type functionEntry struct {
 	name      string
 	arguments []argSpec
 	handler   jpFunction
+	// hasExpRef is set for functions that are passed the interpreter as
+	// their first argument, to evaluate expression references or to read
+	// its configuration.
 	hasExpRef bool
+	// lazy is how evalArgs evaluates the arguments of the built-in
+	// functions that do not evaluate all of them up front.  A function
+	// overriding one of them is passed its arguments evaluated as usual.
+	lazy    lazyArgs
+	custom  Function
+	timeout time.Duration
 }
 
+// lazyArgs is how the arguments of a built-in function are evaluated, see
+// evalArgs.
+type lazyArgs int
+
+const (
+	// eagerArgs evaluates every argument before the call.
+	eagerArgs lazyArgs = iota
+	// existsArgs replaces an expression reference by whether what it
+	// refers to exists, as exists() requires.
+	existsArgs
+	// firstArgs evaluates the arguments in order until one is not null,
+	// as first_of() requires.
+	firstArgs
+	// branchArgs evaluates the condition and then only the branch that
+	// is taken, as if() requires.
+	branchArgs
+)
+
 type argSpec struct {
 	types    []jpType
 	variadic bool
-}
-
-type byExprString struct {
-	intr     *treeInterpreter
-	node     ASTNode
-	items    []interface{}
-	hasError bool
-}
-
-func (a *byExprString) Len() int {
-	return len(a.items)
-}
-func (a *byExprString) Swap(i, j int) {
-	a.items[i], a.items[j] = a.items[j], a.items[i]
-}
-func (a *byExprString) Less(i, j int) bool {
-	first, err := a.intr.Execute(a.node, a.items[i])
-	if err != nil {
-		a.hasError = true
-		// Return a dummy value.
-		return true
-	}
-	ith, ok := first.(string)
-	if !ok {
-		a.hasError = true
-		return true
-	}
-	second, err := a.intr.Execute(a.node, a.items[j])
-	if err != nil {
-		a.hasError = true
-		// Return a dummy value.
-		return true
-	}
-	jth, ok := second.(string)
-	if !ok {
-		a.hasError = true
-		return true
-	}
-	return ith < jth
-}
-
-type byExprFloat struct {
-	intr     *treeInterpreter
-	node     ASTNode
-	items    []interface{}
-	hasError bool
-}
-
-func (a *byExprFloat) Len() int {
-	return len(a.items)
-}
-func (a *byExprFloat) Swap(i, j int) {
-	a.items[i], a.items[j] = a.items[j], a.items[i]
-}
-func (a *byExprFloat) Less(i, j int) bool {
-	first, err := a.intr.Execute(a.node, a.items[i])
-	if err != nil {
-		a.hasError = true
-		// Return a dummy value.
-		return true
-	}
-	ith, ok := first.(float64)
-	if !ok {
-		a.hasError = true
-		return true
-	}
-	second, err := a.intr.Execute(a.node, a.items[j])
-	if err != nil {
-		a.hasError = true
-		// Return a dummy value.
-		return true
-	}
-	jth, ok := second.(float64)
-	if !ok {
-		a.hasError = true
-		return true
-	}
-	return ith < jth
+	// optional is set on trailing arguments that may be omitted.
+	optional bool
 }
 
 type functionCaller struct {
@@ -184,7 +173,7 @@ func newFunctionCaller() *functionCaller {
 			handler: jpfFloor,
 		},
 		"map": {
-			name: "amp",
+			name: "map",
 			arguments: []argSpec{
 				{types: []jpType{jpExpref}},
 				{types: []jpType{jpArray}},
@@ -192,6 +181,29 @@ func newFunctionCaller() *functionCaller {
 			handler:   jpfMap,
 			hasExpRef: true,
 		},
+		"key": {
+			name:      "key",
+			handler:   jpfKey,
+			hasExpRef: true,
+		},
+		"map_values": {
+			name: "map_values",
+			arguments: []argSpec{
+				{types: []jpType{jpExpref}},
+				{types: []jpType{jpObject}},
+			},
+			handler:   jpfMapValues,
+			hasExpRef: true,
+		},
+		"filter_values": {
+			name: "filter_values",
+			arguments: []argSpec{
+				{types: []jpType{jpObject}},
+				{types: []jpType{jpExpref}},
+			},
+			handler:   jpfFilterValues,
+			hasExpRef: true,
+		},
 		"max": {
 			name: "max",
 			arguments: []argSpec{
@@ -206,6 +218,35 @@ func newFunctionCaller() *functionCaller {
 			},
 			handler: jpfMerge,
 		},
+		"merge_deep": {
+			name: "merge_deep",
+			arguments: []argSpec{
+				{types: []jpType{jpObject}, variadic: true},
+			},
+			handler: jpfMergeDeep,
+		},
+		"keys_deep": {
+			name: "keys_deep",
+			arguments: []argSpec{
+				{types: []jpType{jpObject}},
+			},
+			handler: jpfKeysDeep,
+		},
+		"deep_flatten": {
+			name: "deep_flatten",
+			arguments: []argSpec{
+				{types: []jpType{jpArray}},
+			},
+			handler: jpfDeepFlatten,
+		},
+		"pick": {
+			name: "pick",
+			arguments: []argSpec{
+				{types: []jpType{jpObject}},
+				{types: []jpType{jpArrayString}},
+			},
+			handler: jpfPick,
+		},
 		"max_by": {
 			name: "max_by",
 			arguments: []argSpec{
@@ -245,6 +286,56 @@ func newFunctionCaller() *functionCaller {
 			},
 			handler: jpfType,
 		},
+		"exists": {
+			name: "exists",
+			arguments: []argSpec{
+				{types: []jpType{jpAny}},
+			},
+			handler: jpfExists,
+			lazy:    existsArgs,
+		},
+		"is_string": {
+			name: "is_string",
+			arguments: []argSpec{
+				{types: []jpType{jpAny}},
+			},
+			handler: jpfIsType("string"),
+		},
+		"is_number": {
+			name: "is_number",
+			arguments: []argSpec{
+				{types: []jpType{jpAny}},
+			},
+			handler: jpfIsType("number"),
+		},
+		"is_boolean": {
+			name: "is_boolean",
+			arguments: []argSpec{
+				{types: []jpType{jpAny}},
+			},
+			handler: jpfIsType("boolean"),
+		},
+		"is_array": {
+			name: "is_array",
+			arguments: []argSpec{
+				{types: []jpType{jpAny}},
+			},
+			handler: jpfIsType("array"),
+		},
+		"is_object": {
+			name: "is_object",
+			arguments: []argSpec{
+				{types: []jpType{jpAny}},
+			},
+			handler: jpfIsType("object"),
+		},
+		"is_null": {
+			name: "is_null",
+			arguments: []argSpec{
+				{types: []jpType{jpAny}},
+			},
+			handler: jpfIsType("null"),
+		},
 		"keys": {
 			name: "keys",
 			arguments: []argSpec{
@@ -263,14 +354,17 @@ func newFunctionCaller() *functionCaller {
 			name: "sort",
 			arguments: []argSpec{
 				{types: []jpType{jpArrayString, jpArrayNumber}},
+				{types: []jpType{jpString}, optional: true},
 			},
-			handler: jpfSort,
+			handler:   jpfSort,
+			hasExpRef: true,
 		},
 		"sort_by": {
 			name: "sort_by",
 			arguments: []argSpec{
 				{types: []jpType{jpArray}},
 				{types: []jpType{jpExpref}},
+				{types: []jpType{jpString}, optional: true},
 			},
 			handler:   jpfSortBy,
 			hasExpRef: true,
@@ -308,9 +402,114 @@ func newFunctionCaller() *functionCaller {
 			name: "to_number",
 			arguments: []argSpec{
 				{types: []jpType{jpAny}},
+				{types: []jpType{jpNumber}, optional: true},
 			},
 			handler: jpfToNumber,
 		},
+		"to_number_strict": {
+			name: "to_number_strict",
+			arguments: []argSpec{
+				{types: []jpType{jpAny}},
+				{types: []jpType{jpNumber}, optional: true},
+			},
+			handler: jpfToNumberStrict,
+		},
+		"to_json": {
+			name: "to_json",
+			arguments: []argSpec{
+				{types: []jpType{jpAny}},
+			},
+			handler: jpfToJSON,
+		},
+		"from_json": {
+			name: "from_json",
+			arguments: []argSpec{
+				{types: []jpType{jpString}},
+			},
+			handler: jpfFromJSON,
+		},
+		"base64_encode": {
+			name: "base64_encode",
+			arguments: []argSpec{
+				{types: []jpType{jpString}},
+			},
+			handler: jpfBase64Encode,
+		},
+		"base64_decode": {
+			name: "base64_decode",
+			arguments: []argSpec{
+				{types: []jpType{jpString}},
+			},
+			handler: jpfBase64Decode,
+		},
+		"url_encode": {
+			name: "url_encode",
+			arguments: []argSpec{
+				{types: []jpType{jpString}},
+			},
+			handler: jpfURLEncode,
+		},
+		"url_decode": {
+			name: "url_decode",
+			arguments: []argSpec{
+				{types: []jpType{jpString}},
+			},
+			handler: jpfURLDecode,
+		},
+		"parse_size": {
+			name: "parse_size",
+			arguments: []argSpec{
+				{types: []jpType{jpString}},
+			},
+			handler: jpfParseSize,
+		},
+		"format_size": {
+			name: "format_size",
+			arguments: []argSpec{
+				{types: []jpType{jpNumber}},
+			},
+			handler: jpfFormatSize,
+		},
+		"parse_duration": {
+			name: "parse_duration",
+			arguments: []argSpec{
+				{types: []jpType{jpString}},
+			},
+			handler: jpfParseDuration,
+		},
+		"coalesce": {
+			name: "coalesce",
+			arguments: []argSpec{
+				{types: []jpType{jpAny}, variadic: true},
+			},
+			handler: jpfNotNull,
+		},
+		"default": {
+			name: "default",
+			arguments: []argSpec{
+				{types: []jpType{jpAny}},
+				{types: []jpType{jpAny}},
+			},
+			handler: jpfNotNull,
+		},
+		"first_of": {
+			name: "first_of",
+			arguments: []argSpec{
+				{types: []jpType{jpAny}, variadic: true},
+			},
+			handler: jpfNotNull,
+			lazy:    firstArgs,
+		},
+		"if": {
+			name: "if",
+			arguments: []argSpec{
+				{types: []jpType{jpAny}},
+				{types: []jpType{jpAny}},
+				{types: []jpType{jpAny}, optional: true},
+			},
+			handler: jpfIf,
+			lazy:    branchArgs,
+		},
 		"not_null": {
 			name: "not_null",
 			arguments: []argSpec{
@@ -318,29 +517,82 @@ func newFunctionCaller() *functionCaller {
 			},
 			handler: jpfNotNull,
 		},
+		"normalize": {
+			name: "normalize",
+			arguments: []argSpec{
+				{types: []jpType{jpString}},
+				{types: []jpType{jpString}},
+			},
+			handler: jpfNormalize,
+		},
 	}
 	return caller
 }
 
+// refersToCurrent reports whether the built-in function e evaluates the
+// expression references passed to it against the current node, as
+// first_of and exists do, rather than against values of its other
+// arguments.
+func (e functionEntry) refersToCurrent() bool {
+	return e.custom == nil && (e.lazy == firstArgs || e.lazy == existsArgs)
+}
+
+// with returns a copy of f with entry added to its function table,
+// replacing any existing entry of the same name.
+func (f *functionCaller) with(entry functionEntry) *functionCaller {
+	table := make(map[string]functionEntry, len(f.functionTable)+1)
+	for name, existing := range f.functionTable {
+		table[name] = existing
+	}
+	table[entry.name] = entry
+	return &functionCaller{functionTable: table}
+}
+
+// without returns a copy of f without the function name.
+func (f *functionCaller) without(name string) *functionCaller {
+	table := make(map[string]functionEntry, len(f.functionTable))
+	for existingName, existing := range f.functionTable {
+		if existingName != name {
+			table[existingName] = existing
+		}
+	}
+	return &functionCaller{functionTable: table}
+}
+
 func (e *functionEntry) resolveArgs(arguments []interface{}) ([]interface{}, error) {
 	if len(e.arguments) == 0 {
+		// Functions registered with RegisterFunction check their own
+		// arguments.
+		if e.custom == nil && len(arguments) > 0 {
+			return nil, errors.New("incorrect number of args")
+		}
 		return arguments, nil
 	}
-	if !e.arguments[len(e.arguments)-1].variadic {
-		if len(e.arguments) != len(arguments) {
+	last := len(e.arguments) - 1
+	if !e.arguments[last].variadic {
+		required := len(e.arguments)
+		for required > 0 && e.arguments[required-1].optional {
+			required--
+		}
+		if len(arguments) < required || len(arguments) > len(e.arguments) {
 			return nil, errors.New("incorrect number of args")
 		}
-		for i, spec := range e.arguments {
-			userArg := arguments[i]
-			err := spec.typeCheck(userArg)
-			if err != nil {
-				return nil, err
+	} else if len(arguments) < len(e.arguments) {
+		return nil, errors.New("invalid arity")
+	}
+	for i, userArg := range arguments {
+		// Variadic arguments are all checked against the last spec.
+		spec := e.arguments[last]
+		if i < last {
+			spec = e.arguments[i]
+		}
+		err := spec.typeCheck(userArg)
+		if err != nil {
+			return nil, &EvalError{
+				ValueType: jsonType(userArg),
+				Err:       fmt.Errorf("invalid type for argument %d: %s", i+1, err),
 			}
 		}
-		return arguments, nil
-	}
-	if len(arguments) < len(e.arguments) {
-		return nil, errors.New("invalid arity")
 	}
 	return arguments, nil
 }
@@ -349,7 +601,7 @@ func (a *argSpec) typeCheck(arg interface{}) error {
 	for _, t := range a.types {
 		switch t {
 		case jpNumber:
-			if _, ok := arg.(float64); ok {
+			if asNumber(arg).kind != notNumber {
 				return nil
 			}
 		case jpString:
@@ -364,6 +616,9 @@ func (a *argSpec) typeCheck(arg interface{}) error {
 			if _, ok := arg.(map[string]interface{}); ok {
 				return nil
 			}
+			if _, ok := arg.(*OrderedMap); ok {
+				return nil
+			}
 		case jpArrayNumber:
 			if _, ok := toArrayNum(arg); ok {
 				return nil
@@ -375,12 +630,16 @@ func (a *argSpec) typeCheck(arg interface{}) error {
 		case jpAny:
 			return nil
 		case jpExpref:
-			if _, ok := arg.(expRef); ok {
+			if _, ok := arg.(ExpRef); ok {
 				return nil
 			}
 		}
 	}
-	return fmt.Errorf("Invalid type for: %v, expected: %#v", arg, a.types)
+	expected := make([]string, len(a.types))
+	for i, t := range a.types {
+		expected[i] = string(t)
+	}
+	return fmt.Errorf("expected %s, got %s", strings.Join(expected, " or "), jsonType(arg))
 }
 
 func (f *functionCaller) CallFunction(name string, arguments []interface{}, intr *treeInterpreter) (interface{}, error) {
@@ -388,10 +647,70 @@ func (f *functionCaller) CallFunction(name string, arguments []interface{}, intr
 	if !ok {
 		return nil, errors.New("unknown function: " + name)
 	}
+	keepOrdered := false
+	exact := intr.integers != IntegersAsFloat
+	// The variants of built-in functions for the interpreter's modes do
+	// not apply to functions that override them.
+	if entry.custom == nil {
+		if ordered, ok := orderedFunctions[name]; ok && intr.ordered {
+			entry, keepOrdered = ordered, true
+		} else if sorted, ok := sortedKeyFunctions[name]; ok && intr.sortedKeys {
+			entry = sorted
+		}
+		if grapheme, ok := graphemeFunctions[name]; ok && intr.graphemes {
+			entry = grapheme
+		}
+		if exactEntry, ok := exactIntegerFunctions[name]; ok && exact {
+			entry = exactEntry
+		}
+		if formatted, ok := numberFormatFunctions[name]; ok && intr.numbers.formats() {
+			entry = formatted
+		}
+	}
+	for i, arg := range arguments {
+		arg, err := intr.resolveArgument(arg)
+		if err != nil {
+			return nil, err
+		}
+		arguments[i] = arg
+		switch v := arg.(type) {
+		case []interface{}:
+			arguments[i] = normalizeElements(v, exact)
+			continue
+		case map[string]interface{}, string, float64, bool, nil, ExpRef:
+			continue
+		case DataSource:
+			elements, err := loadSource(v)
+			if err != nil {
+				return nil, err
+			}
+			arguments[i] = normalizeElements(elements, exact)
+			continue
+		case *OrderedMap:
+			if keepOrdered {
+				continue
+			}
+		}
+		arg = dereference(arg)
+		if n, ok := normalizeNumber(arg, exact); ok {
+			arguments[i] = n
+		} else if s, ok := namedString(arg); ok {
+			arguments[i] = s
+		} else if obj, ok := intr.toObject(arg); ok {
+			arguments[i] = obj
+		} else if isSliceType(arg) || isArrayType(arg) {
+			arguments[i] = normalizeElements(toInterfaceSlice(arg), exact)
+		} else {
+			arguments[i] = arg
+		}
+	}
 	resolvedArgs, err := entry.resolveArgs(arguments)
 	if err != nil {
 		return nil, err
 	}
+	if entry.custom != nil {
+		return entry.callCustom(CallContext{name: name, intr: intr}, resolvedArgs)
+	}
 	if entry.hasExpRef {
 		var extra []interface{}
 		extra = append(extra, intr)
@@ -400,6 +719,155 @@ func (f *functionCaller) CallFunction(name string, arguments []interface{}, intr
 	return entry.handler(resolvedArgs)
 }
 
+// evalArgs evaluates the arguments of node, a call to a function, against
+// value with eval, which evaluates the argument at index i.  The
+// arguments of a call to if() are evaluated lazily: its condition is
+// evaluated first, and then only the branch that is taken, so that the
+// other branch may be an expression that would fail, as in
+// "if(type(a) == 'array', length(a), `0`)".  The argument of the branch
+// that is not taken is null.
+//
+// The arguments of a call to first_of() are evaluated in order until one
+// is not null, and the others are left null.  An argument that is an
+// expression reference is replaced by the result of the expression it
+// refers to.  An argument whose evaluation fails because a value has the
+// wrong type, an *EvalError about an argument's type or a *StrictError,
+// counts as null, so that an alternative may assume the shape of the data
+// it reads.
+//
+// The argument of exists() that is an expression reference is replaced by
+// true if what it refers to is in the document, as Lookup tells, and null
+// otherwise, so that a key whose value is null exists.  What it refers to
+// is looked up in non-strict mode, as a missing value is not an error.
+func evalArgs(intr *treeInterpreter, node ASTNode, value interface{}, eval func(i int) (interface{}, error)) ([]interface{}, error) {
+	lazy, n := intr.fCall.functionTable[node.value.(string)].lazy, len(node.children)
+	args := make([]interface{}, n)
+	if lazy == existsArgs && n == 1 {
+		current, err := eval(0)
+		if err != nil {
+			return nil, err
+		}
+		if ref, ok := current.(ExpRef); ok {
+			probe := *intr
+			probe.strict = false
+			found, err := probe.found(ref.ref, value)
+			if err != nil {
+				return nil, err
+			}
+			current = nil
+			if found {
+				current = true
+			}
+		}
+		args[0] = current
+		return args, nil
+	}
+	if lazy == firstArgs {
+		for i := range args {
+			current, err := eval(i)
+			if ref, ok := current.(ExpRef); ok && err == nil {
+				current, err = intr.Execute(ref.ref, value)
+			}
+			if err != nil && !isTypeError(err) {
+				return nil, err
+			}
+			if err == nil && current != nil {
+				args[i] = current
+				break
+			}
+		}
+		return args, nil
+	}
+	conditional := lazy == branchArgs && (n == 2 || n == 3)
+	for i := range args {
+		if conditional && i > 0 {
+			taken := 2
+			if !isFalse(args[0]) {
+				taken = 1
+			}
+			if i != taken {
+				continue
+			}
+		}
+		current, err := eval(i)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = current
+	}
+	return args, nil
+}
+
+// isTypeError reports whether err is due to a value of the wrong type.
+func isTypeError(err error) bool {
+	var evalErr *EvalError
+	if errors.As(err, &evalErr) && evalErr.ValueType != "" {
+		return true
+	}
+	var strictErr *StrictError
+	return errors.As(err, &strictErr)
+}
+
+// FunctionError is returned when a user-registered function panics or
+// exceeds the time limit set with Runtime.SetFunctionTimeout.
+type FunctionError struct {
+	Function string // Name of the function that failed.
+	Err      error  // ErrFunctionTimeout, or an error describing the panic.
+}
+
+// ErrFunctionTimeout is wrapped by a FunctionError when a function exceeds
+// its time limit.
+var ErrFunctionTimeout = errors.New("time limit exceeded")
+
+func (e *FunctionError) Error() string {
+	return "function " + e.Function + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *FunctionError) Unwrap() error {
+	return e.Err
+}
+
+// callCustom calls a user-registered function, converting panics into
+// errors and enforcing its time limit if it has one.
+func (e *functionEntry) callCustom(ctx CallContext, arguments []interface{}) (interface{}, error) {
+	if e.timeout <= 0 {
+		return e.callRecovered(ctx, arguments)
+	}
+	done := make(chan struct{})
+	ctx.done = done
+	type outcome struct {
+		result interface{}
+		err    error
+	}
+	// Buffered so that a function finishing after its time limit does not
+	// block forever.
+	finished := make(chan outcome, 1)
+	go func() {
+		result, err := e.callRecovered(ctx, arguments)
+		finished <- outcome{result, err}
+	}()
+	timer := time.NewTimer(e.timeout)
+	defer timer.Stop()
+	select {
+	case o := <-finished:
+		return o.result, o.err
+	case <-timer.C:
+		close(done)
+		return nil, &FunctionError{Function: ctx.name, Err: ErrFunctionTimeout}
+	}
+}
+
+func (e *functionEntry) callRecovered(ctx CallContext, arguments []interface{}) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = nil
+			err = &FunctionError{Function: ctx.name, Err: fmt.Errorf("panic: %v", r)}
+		}
+	}()
+	return e.custom(ctx, arguments)
+}
+
 func jpfAbs(arguments []interface{}) (interface{}, error) {
 	num := arguments[0].(float64)
 	return math.Abs(num), nil
@@ -410,8 +878,7 @@ func jpfLength(arguments []interface{}) (interface{}, error) {
 	if c, ok := arg.(string); ok {
 		return float64(utf8.RuneCountInString(c)), nil
 	} else if isSliceType(arg) {
-		v := reflect.ValueOf(arg)
-		return float64(v.Len()), nil
+		return float64(sliceLen(arg)), nil
 	} else if c, ok := arg.(map[string]interface{}); ok {
 		return float64(len(c)), nil
 	}
@@ -431,7 +898,8 @@ func jpfAvg(arguments []interface{}) (interface{}, error) {
 	length := float64(len(args))
 	numerator := 0.0
 	for _, n := range args {
-		numerator += n.(float64)
+		value, _ := toNumber(n)
+		numerator += value
 	}
 	return numerator / length, nil
 }
@@ -451,7 +919,7 @@ func jpfContains(arguments []interface{}) (interface{}, error) {
 	// Otherwise this is a generic contains for []interface{}
 	general := search.([]interface{})
 	for _, item := range general {
-		if item == el {
+		if objsEqual(item, el) {
 			return true, nil
 		}
 	}
@@ -468,7 +936,7 @@ func jpfFloor(arguments []interface{}) (interface{}, error) {
 }
 func jpfMap(arguments []interface{}) (interface{}, error) {
 	intr := arguments[0].(*treeInterpreter)
-	exp := arguments[1].(expRef)
+	exp := arguments[1].(ExpRef)
 	node := exp.ref
 	arr := arguments[2].([]interface{})
 	mapped := make([]interface{}, 0, len(arr))
@@ -481,6 +949,56 @@ func jpfMap(arguments []interface{}) (interface{}, error) {
 	}
 	return mapped, nil
 }
+func jpfMapValues(arguments []interface{}) (interface{}, error) {
+	intr := arguments[0].(*treeInterpreter)
+	node := arguments[1].(ExpRef).ref
+	object := arguments[2].(map[string]interface{})
+	mapped := make(map[string]interface{}, len(object))
+	capture := callsKey(node)
+	for key, value := range object {
+		each := intr
+		if capture {
+			each = intr.withKey(key)
+		}
+		current, err := each.Execute(node, value)
+		if err != nil {
+			return nil, err
+		}
+		mapped[key] = current
+	}
+	return mapped, nil
+}
+func jpfFilterValues(arguments []interface{}) (interface{}, error) {
+	intr := arguments[0].(*treeInterpreter)
+	object := arguments[1].(map[string]interface{})
+	node := arguments[2].(ExpRef).ref
+	filtered := make(map[string]interface{})
+	capture := callsKey(node)
+	for key, value := range object {
+		keep, err := intr.keepValue(node, capture, key, value)
+		if err != nil {
+			return nil, err
+		}
+		if keep {
+			filtered[key] = value
+		}
+	}
+	return filtered, nil
+}
+
+// keepValue reports whether filter_values keeps the value of key, for
+// which node evaluates to a truthy value.  key() returns key if capture
+// is set.
+func (intr *treeInterpreter) keepValue(node ASTNode, capture bool, key string, value interface{}) (bool, error) {
+	if capture {
+		intr = intr.withKey(key)
+	}
+	result, err := intr.Execute(node, value)
+	if err != nil {
+		return false, err
+	}
+	return !isFalse(result), nil
+}
 func jpfMax(arguments []interface{}) (interface{}, error) {
 	if items, ok := toArrayNum(arguments[0]); ok {
 		if len(items) == 0 {
@@ -523,61 +1041,94 @@ func jpfMerge(arguments []interface{}) (interface{}, error) {
 	}
 	return final, nil
 }
-func jpfMaxBy(arguments []interface{}) (interface{}, error) {
-	intr := arguments[0].(*treeInterpreter)
-	arr := arguments[1].([]interface{})
-	exp := arguments[2].(expRef)
-	node := exp.ref
-	if len(arr) == 0 {
-		return nil, nil
-	} else if len(arr) == 1 {
-		return arr[0], nil
+func jpfMergeDeep(arguments []interface{}) (interface{}, error) {
+	final := make(map[string]interface{})
+	for _, m := range arguments {
+		mapped, ok := m.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("invalid type, merge_deep arguments must be objects")
+		}
+		mergeDeepInto(final, mapped)
 	}
-	start, err := intr.Execute(node, arr[0])
-	if err != nil {
-		return nil, err
+	return final, nil
+}
+
+// mergeDeepInto merges src into dst, recursing into values that are
+// objects on both sides.  Nested objects in dst are copied before being
+// modified so that none of the arguments are mutated.
+func mergeDeepInto(dst, src map[string]interface{}) {
+	for key, value := range src {
+		srcMap, srcOk := value.(map[string]interface{})
+		dstMap, dstOk := dst[key].(map[string]interface{})
+		if srcOk && dstOk {
+			merged := make(map[string]interface{}, len(dstMap))
+			mergeDeepInto(merged, dstMap)
+			mergeDeepInto(merged, srcMap)
+			dst[key] = merged
+		} else {
+			dst[key] = value
+		}
 	}
-	switch t := start.(type) {
-	case float64:
-		bestVal := t
-		bestItem := arr[0]
-		for _, item := range arr[1:] {
-			result, err := intr.Execute(node, item)
-			if err != nil {
-				return nil, err
-			}
-			current, ok := result.(float64)
-			if !ok {
-				return nil, errors.New("invalid type, must be number")
-			}
-			if current > bestVal {
-				bestVal = current
-				bestItem = item
-			}
+}
+func jpfKeysDeep(arguments []interface{}) (interface{}, error) {
+	var paths []string
+	collectKeysDeep(arguments[0].(map[string]interface{}), "", &paths)
+	sort.Strings(paths)
+	collected := make([]interface{}, len(paths))
+	for i, path := range paths {
+		collected[i] = path
+	}
+	return collected, nil
+}
+
+// collectKeysDeep appends the dot separated path of every key in m,
+// including the keys of nested objects, to paths.  Keys are quoted as
+// in expressions where needed, so that the path of the key "a.b" is
+// "\"a.b\"" rather than the path of the key "b" in the object "a".
+func collectKeysDeep(m map[string]interface{}, prefix string, paths *[]string) {
+	for key, value := range m {
+		path := prefix + formatIdentifier(key)
+		*paths = append(*paths, path)
+		if nested, ok := value.(map[string]interface{}); ok {
+			collectKeysDeep(nested, path+".", paths)
 		}
-		return bestItem, nil
-	case string:
-		bestVal := t
-		bestItem := arr[0]
-		for _, item := range arr[1:] {
-			result, err := intr.Execute(node, item)
-			if err != nil {
-				return nil, err
-			}
-			current, ok := result.(string)
-			if !ok {
-				return nil, errors.New("invalid type, must be string")
-			}
-			if current > bestVal {
-				bestVal = current
-				bestItem = item
-			}
+	}
+}
+
+// jpfDeepFlatten flattens nested arrays to any depth, as repeating "[]"
+// would, except that nulls are kept.
+func jpfDeepFlatten(arguments []interface{}) (interface{}, error) {
+	return deepFlatten(arguments[0].([]interface{}), []interface{}{}), nil
+}
+
+func deepFlatten(items []interface{}, flattened []interface{}) []interface{} {
+	for _, item := range items {
+		if nested, ok := item.([]interface{}); ok {
+			flattened = deepFlatten(nested, flattened)
+		} else if isSliceType(item) {
+			flattened = deepFlatten(toInterfaceSlice(item), flattened)
+		} else {
+			flattened = append(flattened, item)
 		}
-		return bestItem, nil
-	default:
-		return nil, errors.New("invalid type, must be number of string")
 	}
+	return flattened
 }
+func jpfPick(arguments []interface{}) (interface{}, error) {
+	arg := arguments[0].(map[string]interface{})
+	keys, _ := toArrayStr(arguments[1])
+	picked := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		if value, ok := arg[key]; ok {
+			picked[key] = value
+		}
+	}
+	return picked, nil
+}
+func jpfMaxBy(arguments []interface{}) (interface{}, error) {
+	intr := arguments[0].(*treeInterpreter)
+	return intr.bestBy(arguments[2].(ExpRef).ref, arguments[1].([]interface{}), 1)
+}
+
 func jpfSum(arguments []interface{}) (interface{}, error) {
 	items, _ := toArrayNum(arguments[0])
 	sum := 0.0
@@ -621,61 +1172,12 @@ func jpfMin(arguments []interface{}) (interface{}, error) {
 
 func jpfMinBy(arguments []interface{}) (interface{}, error) {
 	intr := arguments[0].(*treeInterpreter)
-	arr := arguments[1].([]interface{})
-	exp := arguments[2].(expRef)
-	node := exp.ref
-	if len(arr) == 0 {
-		return nil, nil
-	} else if len(arr) == 1 {
-		return arr[0], nil
-	}
-	start, err := intr.Execute(node, arr[0])
-	if err != nil {
-		return nil, err
-	}
-	if t, ok := start.(float64); ok {
-		bestVal := t
-		bestItem := arr[0]
-		for _, item := range arr[1:] {
-			result, err := intr.Execute(node, item)
-			if err != nil {
-				return nil, err
-			}
-			current, ok := result.(float64)
-			if !ok {
-				return nil, errors.New("invalid type, must be number")
-			}
-			if current < bestVal {
-				bestVal = current
-				bestItem = item
-			}
-		}
-		return bestItem, nil
-	} else if t, ok := start.(string); ok {
-		bestVal := t
-		bestItem := arr[0]
-		for _, item := range arr[1:] {
-			result, err := intr.Execute(node, item)
-			if err != nil {
-				return nil, err
-			}
-			current, ok := result.(string)
-			if !ok {
-				return nil, errors.New("invalid type, must be string")
-			}
-			if current < bestVal {
-				bestVal = current
-				bestItem = item
-			}
-		}
-		return bestItem, nil
-	} else {
-		return nil, errors.New("invalid type, must be number of string")
-	}
+	return intr.bestBy(arguments[2].(ExpRef).ref, arguments[1].([]interface{}), -1)
 }
+
 func jpfType(arguments []interface{}) (interface{}, error) {
 	arg := arguments[0]
-	if _, ok := arg.(float64); ok {
+	if _, ok := toNumber(arg); ok {
 		return "number", nil
 	}
 	if _, ok := arg.(string); ok {
@@ -712,6 +1214,12 @@ func jpfValues(arguments []interface{}) (interface{}, error) {
 	return collected, nil
 }
 func jpfSort(arguments []interface{}) (interface{}, error) {
+	intr := arguments[0].(*treeInterpreter)
+	collation, err := intr.collationArg(arguments[2:])
+	if err != nil {
+		return nil, err
+	}
+	arguments = arguments[1:]
 	if items, ok := toArrayNum(arguments[0]); ok {
 		d := sort.Float64Slice(items)
 		sort.Stable(d)
@@ -724,7 +1232,13 @@ func jpfSort(arguments []interface{}) (interface{}, error) {
 	// Otherwise we're dealing with sort()'ing strings.
 	items, _ := toArrayStr(arguments[0])
 	d := sort.StringSlice(items)
-	sort.Stable(d)
+	if collation != nil {
+		sort.SliceStable(d, func(i, j int) bool {
+			return collation(d[i], d[j]) < 0
+		})
+	} else {
+		sort.Stable(d)
+	}
 	final := make([]interface{}, len(d))
 	for i, val := range d {
 		final[i] = val
@@ -733,36 +1247,13 @@ func jpfSort(arguments []interface{}) (interface{}, error) {
 }
 func jpfSortBy(arguments []interface{}) (interface{}, error) {
 	intr := arguments[0].(*treeInterpreter)
-	arr := arguments[1].([]interface{})
-	exp := arguments[2].(expRef)
-	node := exp.ref
-	if len(arr) == 0 {
-		return arr, nil
-	} else if len(arr) == 1 {
-		return arr, nil
-	}
-	start, err := intr.Execute(node, arr[0])
+	collation, err := intr.collationArg(arguments[3:])
 	if err != nil {
 		return nil, err
 	}
-	if _, ok := start.(float64); ok {
-		sortable := &byExprFloat{intr, node, arr, false}
-		sort.Stable(sortable)
-		if sortable.hasError {
-			return nil, errors.New("error in sort_by comparison")
-		}
-		return arr, nil
-	} else if _, ok := start.(string); ok {
-		sortable := &byExprString{intr, node, arr, false}
-		sort.Stable(sortable)
-		if sortable.hasError {
-			return nil, errors.New("error in sort_by comparison")
-		}
-		return arr, nil
-	} else {
-		return nil, errors.New("invalid type, must be number of string")
-	}
+	return intr.sortBy(arguments[2].(ExpRef).ref, arguments[1].([]interface{}), collation)
 }
+
 func jpfJoin(arguments []interface{}) (interface{}, error) {
 	sep := arguments[0].(string)
 	// We can't just do arguments[1].([]string), we have to
@@ -805,32 +1296,172 @@ func jpfToString(arguments []interface{}) (interface{}, error) {
 	}
 	return string(result), nil
 }
-func jpfToNumber(arguments []interface{}) (interface{}, error) {
-	arg := arguments[0]
-	if v, ok := arg.(float64); ok {
-		return v, nil
-	}
-	if v, ok := arg.(string); ok {
-		conv, err := strconv.ParseFloat(v, 64)
-		if err != nil {
-			return nil, nil
-		}
-		return conv, nil
+func jpfToJSON(arguments []interface{}) (interface{}, error) {
+	// Unlike to_string, strings are encoded too, and HTML characters are
+	// left alone as the result is not meant to be embedded in HTML.
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(arguments[0]); err != nil {
+		return nil, err
 	}
-	if _, ok := arg.([]interface{}); ok {
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
+func jpfFromJSON(arguments []interface{}) (interface{}, error) {
+	var decoded interface{}
+	if err := json.Unmarshal([]byte(arguments[0].(string)), &decoded); err != nil {
 		return nil, nil
 	}
-	if _, ok := arg.(map[string]interface{}); ok {
+	return decoded, nil
+}
+func jpfBase64Encode(arguments []interface{}) (interface{}, error) {
+	return base64.StdEncoding.EncodeToString([]byte(arguments[0].(string))), nil
+}
+func jpfBase64Decode(arguments []interface{}) (interface{}, error) {
+	decoded, err := base64.StdEncoding.DecodeString(arguments[0].(string))
+	if err != nil || !utf8.Valid(decoded) {
 		return nil, nil
 	}
-	if arg == nil {
+	return string(decoded), nil
+}
+func jpfURLEncode(arguments []interface{}) (interface{}, error) {
+	return url.QueryEscape(arguments[0].(string)), nil
+}
+func jpfURLDecode(arguments []interface{}) (interface{}, error) {
+	decoded, err := url.QueryUnescape(arguments[0].(string))
+	if err != nil {
 		return nil, nil
 	}
-	if arg == true || arg == false {
-		return nil, nil
+	return decoded, nil
+}
+func jpfToNumber(arguments []interface{}) (interface{}, error) {
+	number, _, err := convertToNumber(arguments, false)
+	return number, err
+}
+
+// jpfToNumberStrict is to_number, but fails for a value that does not
+// convert to a number instead of returning null.
+func jpfToNumberStrict(arguments []interface{}) (interface{}, error) {
+	number, ok, err := convertToNumber(arguments, false)
+	if err != nil || ok {
+		return number, err
 	}
-	return nil, errors.New("unknown type")
+	return nil, notNumberError(arguments[0])
 }
+
+// convertToNumber converts the first argument of to_number to a number and
+// reports whether it converts.  Strings are parsed as JSON numbers, or as
+// integers in the radix given by the optional second argument, without
+// surrounding whitespace.  With exact set, integers a float64 cannot
+// represent are returned as by exactNumber.
+func convertToNumber(arguments []interface{}, exact bool) (interface{}, bool, error) {
+	radix := 10
+	if len(arguments) > 1 {
+		r := arguments[1].(float64)
+		if r != math.Trunc(r) || r < 2 || r > 36 {
+			return nil, false, errors.New("invalid radix, must be an integer between 2 and 36")
+		}
+		radix = int(r)
+	}
+	s, ok := arguments[0].(string)
+	if !ok {
+		if exact {
+			number, ok := exactNumber(arguments[0])
+			return number, ok, nil
+		}
+		number, ok := toNumber(arguments[0])
+		if !ok {
+			return nil, false, nil
+		}
+		return number, true, nil
+	}
+	s = strings.Trim(s, " \t\n\r")
+	if radix == 10 {
+		if i, ok := new(big.Int).SetString(s, 10); ok && exact {
+			number, _ := exactNumber(i)
+			return number, true, nil
+		}
+		number, err := strconv.ParseFloat(s, 64)
+		// ParseFloat accepts "Inf" and "NaN", which are not JSON numbers.
+		if err != nil || math.IsInf(number, 0) || math.IsNaN(number) {
+			return nil, false, nil
+		}
+		return number, true, nil
+	}
+	i, ok := parseRadixInteger(s, radix)
+	if !ok {
+		return nil, false, nil
+	}
+	if exact {
+		number, _ := exactNumber(i)
+		return number, true, nil
+	}
+	number, _ := new(big.Float).SetInt(i).Float64()
+	return number, true, nil
+}
+
+// radixPrefixes are the prefixes integers may be written with in the
+// radixes that have one, such as "0x1f" in radix 16.
+var radixPrefixes = map[int]string{2: "0b", 8: "0o", 16: "0x"}
+
+// parseRadixInteger parses an integer in radix, with an optional sign and
+// an optional prefix from radixPrefixes.
+func parseRadixInteger(s string, radix int) (*big.Int, bool) {
+	negative := strings.HasPrefix(s, "-")
+	if negative || strings.HasPrefix(s, "+") {
+		s = s[1:]
+	}
+	if prefix, ok := radixPrefixes[radix]; ok && len(s) > len(prefix) && strings.EqualFold(s[:len(prefix)], prefix) {
+		s = s[len(prefix):]
+	}
+	// SetString would accept another sign.
+	if s == "" || s[0] == '+' || s[0] == '-' {
+		return nil, false
+	}
+	i, ok := new(big.Int).SetString(s, radix)
+	if ok && negative {
+		i.Neg(i)
+	}
+	return i, ok
+}
+
+// notNumberError is the error of to_number_strict for a value that does
+// not convert to a number.
+func notNumberError(value interface{}) error {
+	if s, ok := value.(string); ok {
+		return errors.New("cannot convert string " + strconv.Quote(s) + " to number")
+	}
+	return errors.New("cannot convert " + jsonType(value) + " to number")
+}
+
+// jpfIf returns its second argument if the first one is true and its third
+// argument, or null if there is none, otherwise.  The argument that is not
+// returned is not evaluated, see evalArgs.
+func jpfIf(arguments []interface{}) (interface{}, error) {
+	if !isFalse(arguments[0]) {
+		return arguments[1], nil
+	}
+	if len(arguments) > 2 {
+		return arguments[2], nil
+	}
+	return nil, nil
+}
+
+// jpfExists reports whether its argument is not null.  evalArgs replaces
+// an expression reference argument with true if what it refers to exists.
+func jpfExists(arguments []interface{}) (interface{}, error) {
+	return arguments[0] != nil, nil
+}
+
+// jpfIsType returns the handler of the is_ function that tests for the
+// JSON type name, as returned by type().
+func jpfIsType(name string) jpFunction {
+	return func(arguments []interface{}) (interface{}, error) {
+		t, err := jpfType(arguments)
+		return err == nil && t == name, nil
+	}
+}
+
 func jpfNotNull(arguments []interface{}) (interface{}, error) {
 	for _, arg := range arguments {
 		if arg != nil {
@@ -839,3 +1470,89 @@ func jpfNotNull(arguments []interface{}) (interface{}, error) {
 	}
 	return nil, nil
 }
+
+// sizeUnits maps the lower cased unit suffixes accepted by parse_size to
+// their size in bytes.  Decimal units are powers of 1000 and binary (IEC)
+// units are powers of 1024.
+var sizeUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"k":   1e3,
+	"kb":  1e3,
+	"m":   1e6,
+	"mb":  1e6,
+	"g":   1e9,
+	"gb":  1e9,
+	"t":   1e12,
+	"tb":  1e12,
+	"p":   1e15,
+	"pb":  1e15,
+	"e":   1e18,
+	"eb":  1e18,
+	"ki":  1 << 10,
+	"kib": 1 << 10,
+	"mi":  1 << 20,
+	"mib": 1 << 20,
+	"gi":  1 << 30,
+	"gib": 1 << 30,
+	"ti":  1 << 40,
+	"tib": 1 << 40,
+	"pi":  1 << 50,
+	"pib": 1 << 50,
+	"ei":  1 << 60,
+	"eib": 1 << 60,
+}
+
+var normalizationForms = map[string]norm.Form{
+	"NFC":  norm.NFC,
+	"NFD":  norm.NFD,
+	"NFKC": norm.NFKC,
+	"NFKD": norm.NFKD,
+}
+
+var binarySizeUnits = []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+
+func jpfParseSize(arguments []interface{}) (interface{}, error) {
+	arg := strings.TrimSpace(arguments[0].(string))
+	split := strings.IndexFunc(arg, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.' && r != '-' && r != '+'
+	})
+	if split == -1 {
+		split = len(arg)
+	}
+	number, err := strconv.ParseFloat(arg[:split], 64)
+	if err != nil {
+		return nil, nil
+	}
+	multiplier, ok := sizeUnits[strings.ToLower(strings.TrimSpace(arg[split:]))]
+	if !ok {
+		return nil, nil
+	}
+	return number * multiplier, nil
+}
+func jpfFormatSize(arguments []interface{}) (interface{}, error) {
+	size, _ := toNumber(arguments[0])
+	unit := 0
+	for math.Abs(size) >= 1024 && unit < len(binarySizeUnits)-1 {
+		size /= 1024
+		unit++
+	}
+	// Two decimal places are plenty for a human readable size, and
+	// trailing zeros are dropped so that whole numbers stay whole.
+	formatted := strconv.FormatFloat(math.Round(size*100)/100, 'f', -1, 64)
+	return formatted + binarySizeUnits[unit], nil
+}
+func jpfParseDuration(arguments []interface{}) (interface{}, error) {
+	duration, err := time.ParseDuration(strings.TrimSpace(arguments[0].(string)))
+	if err != nil {
+		return nil, nil
+	}
+	return duration.Seconds(), nil
+}
+func jpfNormalize(arguments []interface{}) (interface{}, error) {
+	form, ok := normalizationForms[strings.ToUpper(arguments[1].(string))]
+	if !ok {
+		return nil, errors.New("invalid normalization form: " + arguments[1].(string))
+	}
+	return form.String(arguments[0].(string)), nil
+}
@@ -0,0 +1,441 @@
+package jmespath
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func searchJSON(t *testing.T, expression string, document string) (interface{}, error) {
+	var data interface{}
+	if err := json.Unmarshal([]byte(document), &data); err != nil {
+		t.Fatal(err)
+	}
+	return Search(expression, data)
+}
+
+func TestMergeDeep(t *testing.T) {
+	assert := assert.New(t)
+	doc := `{"base": {"a": {"x": 1, "y": 2}, "b": 1}, "override": {"a": {"y": 3, "z": 4}, "b": {"c": 1}}}`
+	result, err := searchJSON(t, "merge_deep(base, override)", doc)
+	assert.Nil(err)
+	assert.Equal(map[string]interface{}{
+		"a": map[string]interface{}{"x": 1.0, "y": 3.0, "z": 4.0},
+		"b": map[string]interface{}{"c": 1.0},
+	}, result)
+
+	// The arguments themselves are left untouched.
+	result, err = searchJSON(t, "[merge_deep(base, override), base.a]", doc)
+	assert.Nil(err)
+	assert.Equal(map[string]interface{}{"x": 1.0, "y": 2.0}, result.([]interface{})[1])
+
+	_, err = searchJSON(t, "merge_deep(base, `1`)", doc)
+	assert.NotNil(err)
+}
+
+func TestVariadicArgumentsAreTypeChecked(t *testing.T) {
+	assert := assert.New(t)
+	_, err := searchJSON(t, "merge(a, missing)", `{"a": {}}`)
+	assert.Equal("merge(a, missing) at offset 0: invalid type for argument 2: expected object, got null", err.Error())
+}
+
+func TestKeysDeep(t *testing.T) {
+	assert := assert.New(t)
+	result, err := searchJSON(t, "keys_deep(@)", `{"b": {"d": 1, "c": {"e": []}}, "a": 1}`)
+	assert.Nil(err)
+	assert.Equal([]interface{}{"a", "b", "b.c", "b.c.e", "b.d"}, result)
+
+	result, err = searchJSON(t, "keys_deep(@)", `{"a.b": 1, "a": {"b": 1, "c d": 2}}`)
+	assert.Nil(err)
+	assert.Equal([]interface{}{"\"a.b\"", "a", "a.\"c d\"", "a.b"}, result)
+}
+
+func TestPick(t *testing.T) {
+	assert := assert.New(t)
+	result, err := searchJSON(t, "pick(@, ['a', 'c', 'missing'])", `{"a": 1, "b": 2, "c": null}`)
+	assert.Nil(err)
+	assert.Equal(map[string]interface{}{"a": 1.0, "c": nil}, result)
+
+	_, err = searchJSON(t, "pick(@, [`1`])", `{"a": 1}`)
+	assert.NotNil(err)
+}
+
+func TestParseSize(t *testing.T) {
+	assert := assert.New(t)
+	tests := map[string]interface{}{
+		"10GiB":   10.0 * (1 << 30),
+		"10 gib":  10.0 * (1 << 30),
+		"1.5KB":   1500.0,
+		"512":     512.0,
+		"2Mi":     2.0 * (1 << 20),
+		"100 B":   100.0,
+		"ten GiB": nil,
+		"10 XB":   nil,
+		"":        nil,
+	}
+	for input, expected := range tests {
+		result, err := Search("parse_size(@)", input)
+		assert.Nil(err)
+		assert.Equal(expected, result, input)
+	}
+	_, err := Search("parse_size(`10`)", nil)
+	assert.NotNil(err)
+}
+
+func TestFormatSize(t *testing.T) {
+	assert := assert.New(t)
+	tests := map[float64]string{
+		0:                "0B",
+		1023:             "1023B",
+		1536:             "1.5KiB",
+		10 * (1 << 30):   "10GiB",
+		1234567:          "1.18MiB",
+		-2048:            "-2KiB",
+		1.5 * (1 << 60):  "1.5EiB",
+		4096 * (1 << 60): "4096EiB",
+	}
+	for input, expected := range tests {
+		result, err := Search("format_size(@)", input)
+		assert.Nil(err)
+		assert.Equal(expected, result)
+	}
+	result, err := Search("format_size(parse_size('10GiB'))", nil)
+	assert.Nil(err)
+	assert.Equal("10GiB", result)
+}
+
+func TestParseSizeComparisons(t *testing.T) {
+	assert := assert.New(t)
+	result, err := searchJSON(t, "volumes[?bytes > parse_size(@.limit)].name",
+		`{"volumes": [{"name": "a", "bytes": 2000, "limit": "1KiB"}, {"name": "b", "bytes": 10, "limit": "1 KB"}]}`)
+	assert.Nil(err)
+	assert.Equal([]interface{}{"a"}, result)
+}
+
+func TestParseDuration(t *testing.T) {
+	assert := assert.New(t)
+	result, err := Search("parse_duration('1h30m')", nil)
+	assert.Nil(err)
+	assert.Equal(5400.0, result)
+	result, err = Search("parse_duration('250ms')", nil)
+	assert.Nil(err)
+	assert.Equal(0.25, result)
+	result, err = Search("parse_duration('soon')", nil)
+	assert.Nil(err)
+	assert.Nil(result)
+}
+
+func TestFromJSON(t *testing.T) {
+	assert := assert.New(t)
+	result, err := searchJSON(t, "from_json(detail.requestParameters).bucket",
+		`{"detail": {"requestParameters": "{\"bucket\": \"logs\", \"size\": 3}"}}`)
+	assert.Nil(err)
+	assert.Equal("logs", result)
+	result, err = Search("from_json('{not json')", nil)
+	assert.Nil(err)
+	assert.Nil(result)
+	_, err = Search("from_json(`1`)", nil)
+	assert.NotNil(err)
+}
+
+func TestToJSON(t *testing.T) {
+	assert := assert.New(t)
+	result, err := searchJSON(t, "to_json(@)", `{"a": [1, "<b>"]}`)
+	assert.Nil(err)
+	assert.Equal(`{"a":[1,"<b>"]}`, result)
+	result, err = Search("to_json('abc')", nil)
+	assert.Nil(err)
+	assert.Equal(`"abc"`, result)
+	result, err = searchJSON(t, "from_json(to_json(@))", `{"a": {"b": [true, null]}}`)
+	assert.Nil(err)
+	assert.Equal(map[string]interface{}{"a": map[string]interface{}{"b": []interface{}{true, nil}}}, result)
+}
+
+func TestBase64(t *testing.T) {
+	assert := assert.New(t)
+	result, err := searchJSON(t, "data.password | base64_decode(@)", `{"data": {"password": "czNjcjN0"}}`)
+	assert.Nil(err)
+	assert.Equal("s3cr3t", result)
+	result, err = Search("base64_encode('s3cr3t')", nil)
+	assert.Nil(err)
+	assert.Equal("czNjcjN0", result)
+	result, err = Search("base64_decode('not base64!')", nil)
+	assert.Nil(err)
+	assert.Nil(result)
+	// Decoded bytes that are not valid UTF-8 cannot be a string.
+	result, err = Search("base64_decode('/w==')", nil)
+	assert.Nil(err)
+	assert.Nil(result)
+}
+
+func TestURLEncoding(t *testing.T) {
+	assert := assert.New(t)
+	result, err := Search("url_encode('a b&c=d/é')", nil)
+	assert.Nil(err)
+	assert.Equal("a+b%26c%3Dd%2F%C3%A9", result)
+	result, err = Search("url_decode('a+b%26c%3Dd%2F%C3%A9')", nil)
+	assert.Nil(err)
+	assert.Equal("a b&c=d/é", result)
+	result, err = Search("url_decode('%zz')", nil)
+	assert.Nil(err)
+	assert.Nil(result)
+}
+
+func TestNormalize(t *testing.T) {
+	assert := assert.New(t)
+	composed, decomposed := "café", "café"
+	data := map[string]interface{}{"composed": composed, "decomposed": decomposed}
+	cases := []struct {
+		expression string
+		expected   interface{}
+	}{
+		{"normalize(decomposed, 'NFC')", composed},
+		{"normalize(composed, 'NFD')", decomposed},
+		{"normalize(composed, 'nfd')", decomposed},
+		{"normalize(decomposed, 'NFC') == composed", true},
+		{"decomposed == composed", false},
+		{"length(decomposed)", 5.0},
+		{"length(normalize(decomposed, 'NFC'))", 4.0},
+		{"normalize('ﬁ', 'NFKC')", "fi"},
+		{"normalize('ﬁ', 'NFC')", "ﬁ"},
+	}
+	for _, tt := range cases {
+		result, err := Search(tt.expression, data)
+		assert.Nil(err, tt.expression)
+		assert.Equal(tt.expected, result, tt.expression)
+	}
+	_, err := Search("normalize(composed, 'NFX')", data)
+	assert.NotNil(err)
+	_, err = Search("normalize(`1`, 'NFC')", data)
+	assert.NotNil(err)
+}
+
+func TestIf(t *testing.T) {
+	assert := assert.New(t)
+	data := map[string]interface{}{"a": []interface{}{1.0, 2.0}, "n": 5.0, "zero": 0.0, "empty": ""}
+	traced := NewRuntime()
+	traced.SetTracer(&recordingTracer{})
+	cases := []struct {
+		expression string
+		expected   interface{}
+	}{
+		{"if(a, 'yes', 'no')", "yes"},
+		{"if(empty, 'yes', 'no')", "no"},
+		{"if(missing, 'yes')", nil},
+		{"if(`true`, zero, 'no')", 0.0},
+		{"if(type(a) == 'array', length(a), `0`)", 2.0},
+		{"if(type(n) == 'array', length(n), `-1`)", -1.0},
+		{"if(type(n) == 'number', n, length(n))", 5.0},
+		{"a[*].if(@ > `1`, 'big', 'small')", []interface{}{"small", "big"}},
+	}
+	for _, tt := range cases {
+		result, err := Search(tt.expression, data)
+		assert.Nil(err, tt.expression)
+		assert.Equal(tt.expected, result, tt.expression)
+		jp, err := Compile(tt.expression)
+		assert.Nil(err, tt.expression)
+		result, err = jp.Search(data)
+		assert.Nil(err, tt.expression)
+		assert.Equal(tt.expected, result, tt.expression)
+		result, err = traced.Search(tt.expression, data)
+		assert.Nil(err, tt.expression)
+		assert.Equal(tt.expected, result, tt.expression)
+	}
+	_, err := Search("if(a)", data)
+	assert.NotNil(err)
+	_, err = Search("if(a, n, n, n)", data)
+	assert.NotNil(err)
+	_, err = Search("if(type(a) == 'array', length(n), `0`)", data)
+	assert.NotNil(err, "the branch that is taken is evaluated")
+}
+
+func TestCoalesceAndDefault(t *testing.T) {
+	assert := assert.New(t)
+	data := map[string]interface{}{"zero": 0.0, "no": false, "empty": "", "name": "x"}
+	cases := []struct {
+		expression string
+		expected   interface{}
+	}{
+		{"coalesce(missing, zero, name)", 0.0},
+		{"coalesce(missing, no)", false},
+		{"coalesce(missing, other)", nil},
+		{"coalesce(name)", "x"},
+		{"default(empty, 'fallback')", ""},
+		{"default(missing, 'fallback')", "fallback"},
+		{"zero || 'fallback'", 0.0},
+		{"no || 'fallback'", "fallback"},
+		{"default(no, 'fallback')", false},
+	}
+	for _, tt := range cases {
+		result, err := Search(tt.expression, data)
+		assert.Nil(err, tt.expression)
+		assert.Equal(tt.expected, result, tt.expression)
+	}
+	_, err := Search("coalesce()", data)
+	assert.NotNil(err)
+	_, err = Search("default(name)", data)
+	assert.NotNil(err)
+}
+
+func TestFirstOf(t *testing.T) {
+	assert := assert.New(t)
+	rt := NewRuntime()
+	assert.Nil(rt.RegisterFunction("fail", func(ctx CallContext, args []interface{}) (interface{}, error) {
+		return nil, errors.New("fail was evaluated")
+	}))
+	data := map[string]interface{}{
+		"zero":  0.0,
+		"name":  "x",
+		"tags":  "a,b",
+		"items": []interface{}{map[string]interface{}{"id": "a"}, map[string]interface{}{"name": "b"}},
+	}
+	cases := []struct {
+		expression string
+		expected   interface{}
+	}{
+		{"first_of(&missing, &zero, &name)", 0.0},
+		{"first_of(&missing, &other)", nil},
+		{"first_of(missing, name)", "x"},
+		{"first_of(&name, &fail(@))", "x"},
+		{"first_of(name, fail(@))", "x"},
+		{"first_of(&sum(tags), &length(tags))", 3.0},
+		{"first_of(&keys(tags), `[]`)", []interface{}{}},
+		{"items[*].first_of(&id, &name)", []interface{}{"a", "b"}},
+	}
+	for _, tt := range cases {
+		jp, err := rt.Compile(tt.expression)
+		assert.Nil(err, tt.expression)
+		result, err := jp.Search(data)
+		assert.Nil(err, tt.expression)
+		assert.Equal(tt.expected, result, tt.expression)
+		result, err = rt.Search(tt.expression, data)
+		assert.Nil(err, tt.expression)
+		assert.Equal(tt.expected, result, tt.expression)
+	}
+	_, err := rt.Search("first_of(&missing, &fail(@))", data)
+	assert.EqualError(err, "fail(@) at offset 20: fail was evaluated")
+	_, err = rt.Search("first_of(&missing, &foo(@))", data)
+	assert.NotNil(err, "an unknown function is not a type error")
+	_, err = rt.Search("first_of()", data)
+	assert.NotNil(err)
+}
+
+func TestExists(t *testing.T) {
+	assert := assert.New(t)
+	data := map[string]interface{}{
+		"email":  nil,
+		"name":   "x",
+		"tags":   []interface{}{nil, "a"},
+		"people": []interface{}{map[string]interface{}{"email": nil}, map[string]interface{}{}, map[string]interface{}{"email": "b@c"}},
+	}
+	cases := []struct {
+		expression string
+		expected   interface{}
+	}{
+		{"exists(&email)", true},
+		{"exists(&name)", true},
+		{"exists(&missing)", false},
+		{"exists(&email.domain)", false},
+		{"exists(&name.first)", false},
+		{"exists(&tags[0])", true},
+		{"exists(&tags[5])", false},
+		{"exists(&not_null(missing))", true},
+		{"exists(email)", false},
+		{"exists(name)", true},
+		{"exists(`false`)", true},
+		{"people[?exists(&email)] | length(@)", 2.0},
+		{"people[?email != null] | length(@)", 1.0},
+		{"people[*].exists(&email)", []interface{}{true, false, true}},
+	}
+	for _, tt := range cases {
+		result, err := Search(tt.expression, data)
+		assert.Nil(err, tt.expression)
+		assert.Equal(tt.expected, result, tt.expression)
+		jp, err := Compile(tt.expression)
+		assert.Nil(err, tt.expression)
+		result, err = jp.Search(data)
+		assert.Nil(err, tt.expression)
+		assert.Equal(tt.expected, result, tt.expression)
+	}
+	_, err := Search("exists()", data)
+	assert.NotNil(err)
+	_, err = Search("exists(&email, &name)", data)
+	assert.NotNil(err)
+
+	// Strict mode does not turn a missing value into an error.
+	strict := NewRuntime()
+	strict.SetStrict(true)
+	result, err := strict.Search("exists(&people[1].email)", data)
+	assert.Nil(err)
+	assert.Equal(false, result)
+	result, err = strict.Search("exists(&missing.x)", data)
+	assert.Nil(err)
+	assert.Equal(false, result)
+	_, err = strict.Search("exists(missing)", data)
+	assert.NotNil(err, "arguments that are not references are still strict")
+}
+
+func TestTypePredicates(t *testing.T) {
+	assert := assert.New(t)
+	data := map[string]interface{}{
+		"s":  "a",
+		"n":  1.0,
+		"i":  2,
+		"b":  false,
+		"a":  []string{"x"},
+		"o":  map[string]string{"k": "v"},
+		"z":  nil,
+		"om": NewOrderedMap(),
+	}
+	types := map[string][]string{
+		"string":  {"s"},
+		"number":  {"n", "i"},
+		"boolean": {"b"},
+		"array":   {"a"},
+		"object":  {"o", "om"},
+		"null":    {"z", "missing"},
+	}
+	for name, matching := range types {
+		for key := range data {
+			expected := false
+			for _, m := range matching {
+				expected = expected || m == key
+			}
+			expression := "is_" + name + "(" + key + ")"
+			result, err := Search(expression, data)
+			assert.Nil(err, expression)
+			assert.Equal(expected, result, expression)
+		}
+	}
+	result, err := Search("[?is_string(@)]", []interface{}{"a", 1.0, "b", nil})
+	assert.Nil(err)
+	assert.Equal([]interface{}{"a", "b"}, result)
+	_, err = Search("is_string()", data)
+	assert.NotNil(err)
+}
+
+type goNativeName string
+
+func TestDeepFlatten(t *testing.T) {
+	assert := assert.New(t)
+	data := map[string]interface{}{
+		"nested": decodeJSON(`[1, [2, [3, [4, null]], []], {"a": [5]}]`),
+	}
+	cases := []struct {
+		expression string
+		expected   interface{}
+	}{
+		{"deep_flatten(nested)", []interface{}{1.0, 2.0, 3.0, 4.0, nil, map[string]interface{}{"a": []interface{}{5.0}}}},
+		{"deep_flatten(`[]`)", []interface{}{}},
+		{"nested[][][][]", []interface{}{1.0, 2.0, 3.0, 4.0, map[string]interface{}{"a": []interface{}{5.0}}}},
+	}
+	for _, tt := range cases {
+		result, err := Search(tt.expression, data)
+		assert.Nil(err, tt.expression)
+		assert.Equal(tt.expected, result, tt.expression)
+	}
+	_, err := Search("deep_flatten(`1`)", data)
+	assert.NotNil(err)
+}
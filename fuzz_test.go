@@ -0,0 +1,100 @@
+//go:build go1.18
+// +build go1.18
+
+package jmespath
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fuzzSeeds returns the expressions of the go-fuzz corpus in fuzz/testdata
+// along with a few expressions that exercise every kind of token.
+func fuzzSeeds(f *testing.F) []string {
+	seeds := []string{
+		"foo.bar[0].baz",
+		"foo[*].bar | [0]",
+		"foo[?a == `1` && !b].c[]",
+		"{a: a, b: [b, c]}",
+		"sort_by(people, &age)[-1:0:-1]",
+		"'raw \\' string'",
+		"\"quoted \\u00e9\".*",
+		"`{\"a\": [1, 2]}`",
+		"a || b && c != d",
+		"@.a[:2].b[?c < :limit]",
+	}
+	files, err := filepath.Glob(filepath.Join("fuzz", "testdata", "expr-*"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, file := range files {
+		expression, err := os.ReadFile(file)
+		if err != nil {
+			f.Fatal(err)
+		}
+		seeds = append(seeds, string(expression))
+	}
+	return seeds
+}
+
+// failOnInternal fails the fuzz test if err is an InternalError, which is
+// what a panic while compiling or searching is turned into.
+func failOnInternal(t *testing.T, expression string, err error) {
+	var internalErr *InternalError
+	if errors.As(err, &internalErr) {
+		t.Fatalf("expression %q panicked: %v\n%s", expression, internalErr.Value, internalErr.Stack)
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	for _, seed := range fuzzSeeds(f) {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, expression string) {
+		ast, err := NewParser().Parse(expression)
+		if err != nil {
+			failOnInternal(t, expression, err)
+			return
+		}
+		if _, err := CompileAST(ast); err != nil {
+			failOnInternal(t, expression, err)
+			t.Errorf("parsed expression %q does not compile: %s", expression, err)
+		}
+		formatted := FormatAST(ast)
+		if _, err := NewParser().Parse(formatted); err != nil {
+			t.Errorf("formatted expression %q of %q does not parse: %s", formatted, expression, err)
+		}
+	})
+}
+
+func FuzzSearch(f *testing.F) {
+	documents := []string{
+		`{"foo": {"bar": [{"baz": 1}, {"baz": "x"}]}, "people": [{"age": 3}, {"age": 1}]}`,
+		`[1, "a", null, true, [2], {"a": {}}]`,
+		`"string"`,
+	}
+	for i, seed := range fuzzSeeds(f) {
+		f.Add(seed, documents[i%len(documents)])
+	}
+	f.Fuzz(func(t *testing.T, expression, document string) {
+		var data interface{}
+		if err := json.Unmarshal([]byte(document), &data); err != nil {
+			return
+		}
+		jp, err := Compile(expression)
+		if err != nil {
+			failOnInternal(t, expression, err)
+			return
+		}
+		// Only panics are failures, errors are expected for most inputs.
+		_, err = jp.Search(data)
+		failOnInternal(t, expression, err)
+		_, err = jp.Match(data)
+		failOnInternal(t, expression, err)
+		_, err = jp.SearchWithParams(data, map[string]interface{}{"limit": 1.0})
+		failOnInternal(t, expression, err)
+	})
+}
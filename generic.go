@@ -0,0 +1,69 @@
+//go:build go1.18
+// +build go1.18
+
+package jmespath
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// SearchAs evaluates a JMESPath expression against data, like Search, and
+// returns the result as a T.  A result that already is a T is returned as
+// it is.  Any other result is converted as encoding/json would unmarshal
+// its JSON encoding into a T, so that numbers convert to any numeric type
+// that holds them exactly, objects to structs with matching fields and
+// arrays to slices of such types:
+//
+//	names, err := jmespath.SearchAs[[]string]("people[*].name", data)
+//
+// A null result, such as a missing field, converts to the zero value of T,
+// so T should be a pointer type to tell it apart from a zero.  It is an
+// error for the result not to convert to T, such as a string for an int or
+// 1.5 for an int.
+func SearchAs[T any](expression string, data interface{}) (T, error) {
+	var converted T
+	result, err := Search(expression, data)
+	if err != nil {
+		return converted, err
+	}
+	if t, ok := result.(T); ok {
+		return t, nil
+	}
+	encoded, err := json.Marshal(result)
+	if err == nil {
+		err = json.Unmarshal(encoded, &converted)
+	}
+	if err != nil {
+		var zero T
+		return zero, fmt.Errorf("cannot convert %s result of %s to %s: %s",
+			jsonType(result), strconv.Quote(expression), fmt.Sprintf("%T", &converted)[1:], conversionError(err))
+	}
+	return converted, nil
+}
+
+// MustSearchAs is like SearchAs but panics if the expression cannot be
+// evaluated or its result cannot be converted to a T.  It simplifies
+// reading values whose type is guaranteed by the data, such as a
+// configuration that has been validated.
+func MustSearchAs[T any](expression string, data interface{}) T {
+	result, err := SearchAs[T](expression, data)
+	if err != nil {
+		panic(`jmespath: SearchAs(` + strconv.Quote(expression) + `): ` + err.Error())
+	}
+	return result
+}
+
+// conversionError describes why a result could not be unmarshaled, naming
+// the field of the result that has the wrong type, if any.
+func conversionError(err error) string {
+	if typeError, ok := err.(*json.UnmarshalTypeError); ok {
+		description := typeError.Value + " does not convert to " + typeError.Type.String()
+		if typeError.Field != "" {
+			description = "field " + typeError.Field + ": " + description
+		}
+		return description
+	}
+	return err.Error()
+}
@@ -0,0 +1,74 @@
+//go:build go1.18
+// +build go1.18
+
+package jmespath
+
+import (
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+type genericPerson struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+func TestSearchAs(t *testing.T) {
+	assert := assert.New(t)
+	data := map[string]interface{}{"people": []interface{}{
+		map[string]interface{}{"name": "Ann", "age": 31.0},
+		map[string]interface{}{"name": "Bob", "age": 42.0},
+	}}
+
+	names, err := SearchAs[[]string]("people[*].name", data)
+	assert.Nil(err)
+	assert.Equal([]string{"Ann", "Bob"}, names)
+
+	age, err := SearchAs[int]("people[0].age", data)
+	assert.Nil(err)
+	assert.Equal(31, age)
+
+	people, err := SearchAs[[]genericPerson]("people", data)
+	assert.Nil(err)
+	assert.Equal([]genericPerson{{"Ann", 31}, {"Bob", 42}}, people)
+
+	object, err := SearchAs[map[string]interface{}]("people[0]", data)
+	assert.Nil(err)
+	assert.Equal("Ann", object["name"])
+
+	missing, err := SearchAs[*int]("people[0].height", data)
+	assert.Nil(err)
+	assert.Nil(missing)
+	zero, err := SearchAs[string]("people[0].height", data)
+	assert.Nil(err)
+	assert.Equal("", zero)
+}
+
+func TestSearchAsErrors(t *testing.T) {
+	assert := assert.New(t)
+	data := map[string]interface{}{"people": []interface{}{
+		map[string]interface{}{"name": "Ann", "age": "old"},
+	}}
+
+	_, err := SearchAs[int]("people[0].name", data)
+	assert.Equal(`cannot convert string result of "people[0].name" to int: string does not convert to int`, err.Error())
+	_, err = SearchAs[[]genericPerson]("people", data)
+	// Whether the field includes the index of the element depends on the
+	// version of encoding/json.
+	assert.Contains(err.Error(), `cannot convert array result of "people" to []jmespath.genericPerson: field `)
+	assert.Contains(err.Error(), `age: string does not convert to int`)
+	_, err = SearchAs[int]("`1.5`", data)
+	assert.Contains(err.Error(), "cannot convert number result")
+	_, err = SearchAs[int]("people[", data)
+	assert.IsType(SyntaxError{}, err)
+}
+
+func TestMustSearchAs(t *testing.T) {
+	assert := assert.New(t)
+	data := map[string]interface{}{"count": 3.0}
+	assert.Equal(uint8(3), MustSearchAs[uint8]("count", data))
+	assert.PanicsWithValue(`jmespath: SearchAs("count"): cannot convert number result of "count" to string: number does not convert to string`, func() {
+		MustSearchAs[string]("count", data)
+	})
+}
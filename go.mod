@@ -1,5 +1,5 @@
 module github.com/fl183/go-jmespath
 
-go 1.14
+go 1.16
 
 require github.com/jmespath/go-jmespath/internal/testify v1.5.1
@@ -0,0 +1,167 @@
+package jmespath
+
+import "unicode"
+
+/* Grapheme clusters are found with the extended grapheme cluster rules of
+   Unicode Standard Annex #29.  The grapheme break properties of runes are
+   derived from their general categories and a few code point ranges
+   rather than from the full property tables, which is correct for combining
+   marks, Hangul, regional indicator flags and emoji sequences joined with
+   zero width joiners, the cases that matter in practice.  The Prepend
+   property is not supported.
+*/
+
+// graphemeFunctions replaces the built-in functions that operate on the
+// characters of strings when grapheme clusters are enabled.
+var graphemeFunctions = map[string]functionEntry{
+	"length": {
+		name: "length",
+		arguments: []argSpec{
+			{types: []jpType{jpString, jpArray, jpObject}},
+		},
+		handler: jpfGraphemeLength,
+	},
+	"reverse": {
+		name: "reverse",
+		arguments: []argSpec{
+			{types: []jpType{jpArray, jpString}},
+		},
+		handler: jpfGraphemeReverse,
+	},
+}
+
+func jpfGraphemeLength(arguments []interface{}) (interface{}, error) {
+	if s, ok := arguments[0].(string); ok {
+		return float64(len(graphemeClusters(s))), nil
+	}
+	return jpfLength(arguments)
+}
+
+func jpfGraphemeReverse(arguments []interface{}) (interface{}, error) {
+	s, ok := arguments[0].(string)
+	if !ok {
+		return jpfReverse(arguments)
+	}
+	clusters := graphemeClusters(s)
+	reversed := make([]byte, 0, len(s))
+	for i := len(clusters) - 1; i >= 0; i-- {
+		reversed = append(reversed, clusters[i]...)
+	}
+	return string(reversed), nil
+}
+
+type graphemeProperty int
+
+const (
+	gpOther graphemeProperty = iota
+	gpCR
+	gpLF
+	gpControl
+	gpExtend
+	gpZWJ
+	gpRegionalIndicator
+	gpSpacingMark
+	gpL
+	gpV
+	gpT
+	gpLV
+	gpLVT
+	gpPictographic
+)
+
+func graphemePropertyOf(r rune) graphemeProperty {
+	switch {
+	case r == '\r':
+		return gpCR
+	case r == '\n':
+		return gpLF
+	case r == 0x200D:
+		return gpZWJ
+	case r == 0x200C, r >= 0x1F3FB && r <= 0x1F3FF, r >= 0xE0020 && r <= 0xE007F, unicode.In(r, unicode.Mn, unicode.Me):
+		// Emoji modifiers and tag characters extend emoji.
+		return gpExtend
+	case unicode.In(r, unicode.Cc, unicode.Zl, unicode.Zp):
+		return gpControl
+	case r >= 0x1F1E6 && r <= 0x1F1FF:
+		return gpRegionalIndicator
+	case unicode.Is(unicode.Mc, r):
+		return gpSpacingMark
+	case r >= 0x1100 && r <= 0x115F, r >= 0xA960 && r <= 0xA97C:
+		return gpL
+	case r >= 0x1160 && r <= 0x11A7, r >= 0xD7B0 && r <= 0xD7C6:
+		return gpV
+	case r >= 0x11A8 && r <= 0x11FF, r >= 0xD7CB && r <= 0xD7FB:
+		return gpT
+	case r >= 0xAC00 && r <= 0xD7A3:
+		if (r-0xAC00)%28 == 0 {
+			return gpLV
+		}
+		return gpLVT
+	case r >= 0x1F000 && r <= 0x1FAFF, r >= 0x2000 && unicode.Is(unicode.So, r):
+		return gpPictographic
+	}
+	return gpOther
+}
+
+// graphemeClusters splits s into extended grapheme clusters.
+func graphemeClusters(s string) []string {
+	var clusters []string
+	start := 0
+	prev := gpOther
+	// emoji is set after a pictographic rune followed by any number of
+	// extending runes and zero width joiners, and regionalIndicators
+	// counts the regional indicators just before the current rune.
+	emoji := false
+	regionalIndicators := 0
+	for i, r := range s {
+		current := graphemePropertyOf(r)
+		if i > 0 && graphemeBreak(prev, current, emoji, regionalIndicators) {
+			clusters = append(clusters, s[start:i])
+			start = i
+		}
+		switch current {
+		case gpPictographic:
+			emoji = true
+		case gpExtend, gpZWJ:
+		default:
+			emoji = false
+		}
+		if current == gpRegionalIndicator {
+			regionalIndicators++
+		} else {
+			regionalIndicators = 0
+		}
+		prev = current
+	}
+	if start < len(s) {
+		clusters = append(clusters, s[start:])
+	}
+	return clusters
+}
+
+// graphemeBreak reports whether there is a grapheme cluster boundary
+// between runes with the properties prev and current.
+func graphemeBreak(prev, current graphemeProperty, emoji bool, regionalIndicators int) bool {
+	isControl := func(p graphemeProperty) bool {
+		return p == gpControl || p == gpCR || p == gpLF
+	}
+	switch {
+	case prev == gpCR && current == gpLF:
+		return false
+	case isControl(prev) || isControl(current):
+		return true
+	case prev == gpL && (current == gpL || current == gpV || current == gpLV || current == gpLVT):
+		return false
+	case (prev == gpLV || prev == gpV) && (current == gpV || current == gpT):
+		return false
+	case (prev == gpLVT || prev == gpT) && current == gpT:
+		return false
+	case current == gpExtend || current == gpZWJ || current == gpSpacingMark:
+		return false
+	case prev == gpZWJ && current == gpPictographic && emoji:
+		return false
+	case prev == gpRegionalIndicator && current == gpRegionalIndicator && regionalIndicators%2 == 1:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,59 @@
+package jmespath
+
+import (
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func TestGraphemeClusters(t *testing.T) {
+	assert := assert.New(t)
+	cases := []struct {
+		input    string
+		expected []string
+	}{
+		{"", nil},
+		{"abc", []string{"a", "b", "c"}},
+		{"éx", []string{"é", "x"}},
+		{"a\r\nb\n\n", []string{"a", "\r\n", "b", "\n", "\n"}},
+		{"\U0001F1E9\U0001F1EA\U0001F1EB\U0001F1F7\U0001F1EE", []string{"\U0001F1E9\U0001F1EA", "\U0001F1EB\U0001F1F7", "\U0001F1EE"}},
+		{"\U0001F468‍\U0001F469‍\U0001F467!", []string{"\U0001F468‍\U0001F469‍\U0001F467", "!"}},
+		{"\U0001F44D\U0001F3FD❤️", []string{"\U0001F44D\U0001F3FD", "❤️"}},
+		{"a‍\U0001F600", []string{"a‍", "\U0001F600"}},
+		{"각각", []string{"각", "각"}},
+		{"क्षि", []string{"क्", "षि"}},
+		{"\xffa", []string{"\xff", "a"}},
+	}
+	for _, tt := range cases {
+		assert.Equal(tt.expected, graphemeClusters(tt.input), "%q", tt.input)
+	}
+}
+
+func TestSetGraphemeClusters(t *testing.T) {
+	assert := assert.New(t)
+	data := map[string]interface{}{"word": "café", "flags": "\U0001F1E9\U0001F1EA\U0001F1EB\U0001F1F7", "list": []interface{}{1.0, 2.0}}
+	rt := NewRuntime()
+	rt.SetGraphemeClusters(true)
+	cases := []struct {
+		expression           string
+		codePoints, grapheme interface{}
+	}{
+		{"length(word)", 5.0, 4.0},
+		{"length(flags)", 4.0, 2.0},
+		{"reverse(word)", "́efac", "éfac"},
+		{"reverse(flags)", "\U0001F1F7\U0001F1EB\U0001F1EA\U0001F1E9", "\U0001F1EB\U0001F1F7\U0001F1E9\U0001F1EA"},
+		{"word[::-1]", "\u0301efac", "e\u0301fac"},
+		{"word[0:4]", "cafe", "cafe\u0301"},
+		{"length(list)", 2.0, 2.0},
+		{"reverse(list)", []interface{}{2.0, 1.0}, []interface{}{2.0, 1.0}},
+		{"length(@)", 3.0, 3.0},
+	}
+	for _, tt := range cases {
+		result, err := Search(tt.expression, data)
+		assert.Nil(err, tt.expression)
+		assert.Equal(tt.codePoints, result, tt.expression)
+		result, err = rt.Search(tt.expression, data)
+		assert.Nil(err, tt.expression)
+		assert.Equal(tt.grapheme, result, tt.expression)
+	}
+}
@@ -0,0 +1,204 @@
+package jmespath
+
+import "strings"
+
+/* An Incremental only re-evaluates what a patch can affect.  The paths a
+   patch changes are compared with the paths the expression reads, as
+   found by referencedPaths: when none of them overlap, the result cannot
+   have changed and the expression is not evaluated at all.  An expression
+   that is a multiselect is split into its branches, each with its own
+   referenced paths, so that only the branches reading a changed path are
+   evaluated again and the results of the others are reused.
+*/
+
+// Incremental holds the result of an expression for a document and keeps
+// it up to date as JSON patches are applied to the document, evaluating
+// the expression again only where a patch may affect it.  Documents are
+// never modified: applying a patch copies the objects and arrays on the
+// way to the changed locations and shares everything else with the
+// previous document.  An Incremental is not safe for concurrent use.
+type Incremental struct {
+	jp       *JMESPath
+	document interface{}
+	result   interface{}
+	// paths holds the paths the expression reads if it is not split
+	// into branches, and stale is set until the result is evaluated.
+	paths    [][]string
+	stale    bool
+	branches []incrementalBranch
+}
+
+type incrementalBranch struct {
+	key    string
+	jp     *JMESPath
+	paths  [][]string
+	result interface{}
+	// stale is set when result was not evaluated for the current
+	// document.
+	stale bool
+}
+
+// Incremental evaluates the expression against document and returns an
+// Incremental that keeps the result up to date as the document is patched.
+func (jp *JMESPath) Incremental(document interface{}) (*Incremental, error) {
+	inc := &Incremental{jp: jp}
+	root := jp.ast
+	switch root.nodeType {
+	case ASTMultiSelectHash, ASTMultiSelectList:
+		for _, child := range root.children {
+			branch := incrementalBranch{stale: true}
+			if root.nodeType == ASTMultiSelectHash {
+				branch.key = child.value.(string)
+				child = child.children[0]
+			}
+			branch.jp = newJMESPath(child, jp.intr)
+			branch.jp.expression = jp.expression
+			branch.paths = referencedPaths(child, jp.intr.fCall)
+			inc.branches = append(inc.branches, branch)
+		}
+	default:
+		inc.paths = referencedPaths(root, jp.intr.fCall)
+		inc.stale = true
+	}
+	if _, err := inc.update(document, [][]string{{}}); err != nil {
+		return nil, err
+	}
+	return inc, nil
+}
+
+// Document returns the current document.
+func (inc *Incremental) Document() interface{} {
+	return inc.document
+}
+
+// Result returns the result of the expression for the current document.
+func (inc *Incremental) Result() interface{} {
+	return inc.result
+}
+
+// ApplyPatch applies the JSON Patch (RFC 6902) patch to the document and
+// updates the result, and reports whether the result changed.  Patches are
+// applied atomically: if an operation fails, or the expression fails for
+// the patched document, an error is returned and neither the document nor
+// the result change.
+func (inc *Incremental) ApplyPatch(patch []byte) (changed bool, err error) {
+	defer recoverInternal(&err)
+	document, paths, err := inc.jp.intr.applyJSONPatch(inc.document, patch)
+	if err != nil {
+		return false, err
+	}
+	return inc.update(document, paths)
+}
+
+// ApplyMergePatch applies the JSON Merge Patch (RFC 7386) patch to the
+// document and updates the result like ApplyPatch.
+func (inc *Incremental) ApplyMergePatch(patch []byte) (changed bool, err error) {
+	defer recoverInternal(&err)
+	document, paths, err := inc.jp.intr.applyMergePatch(inc.document, patch)
+	if err != nil {
+		return false, err
+	}
+	return inc.update(document, paths)
+}
+
+// update replaces the document by document, in which the given paths
+// changed, and evaluates what they affect.
+func (inc *Incremental) update(document interface{}, changed [][]string) (bool, error) {
+	var result interface{}
+	var branches []incrementalBranch
+	if inc.branches == nil {
+		if !inc.stale && !pathsOverlap(inc.paths, changed, inc.jp.intr.foldKeys) {
+			inc.document = document
+			return false, nil
+		}
+		var err error
+		if result, err = inc.jp.Search(document); err != nil {
+			return false, err
+		}
+	} else {
+		branches = append([]incrementalBranch(nil), inc.branches...)
+		if document != nil {
+			// Multiselects evaluate to null for a null document
+			// without evaluating their branches.
+			for i := range branches {
+				branch := &branches[i]
+				if !branch.stale && !pathsOverlap(branch.paths, changed, inc.jp.intr.foldKeys) {
+					continue
+				}
+				value, err := branch.jp.Search(document)
+				if err != nil {
+					return false, err
+				}
+				branch.result, branch.stale = value, false
+			}
+		} else {
+			for i := range branches {
+				branches[i].stale = true
+			}
+		}
+		result = inc.collect(document, branches)
+	}
+	resultChanged := !objsEqual(result, inc.result)
+	inc.document, inc.result, inc.branches = document, result, branches
+	inc.stale = false
+	return resultChanged, nil
+}
+
+// collect puts the results of branches together into the result of the
+// multiselect.
+func (inc *Incremental) collect(document interface{}, branches []incrementalBranch) interface{} {
+	switch {
+	case document == nil:
+		return nil
+	case inc.jp.ast.nodeType == ASTMultiSelectList:
+		collected := make([]interface{}, len(branches))
+		for i, branch := range branches {
+			collected[i] = branch.result
+		}
+		return collected
+	case inc.jp.intr.ordered:
+		collected := NewOrderedMap()
+		for _, branch := range branches {
+			collected.Set(branch.key, branch.result)
+		}
+		return collected
+	}
+	collected := make(map[string]interface{}, len(branches))
+	for _, branch := range branches {
+		collected[branch.key] = branch.result
+	}
+	return collected
+}
+
+// pathsOverlap reports whether a change at one of the changed paths may
+// affect a value read from one of the read paths, which is when one of
+// the paths is a prefix of the other.  If fold is set, fields are compared
+// regardless of case, as fields match keys in case-insensitive mode.
+func pathsOverlap(read, changed [][]string, fold bool) bool {
+	for _, r := range read {
+		for _, c := range changed {
+			if isPathPrefix(r, c, fold) || isPathPrefix(c, r, fold) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isPathPrefix reports whether prefix is a prefix of path, where the "*"
+// of object values matches any field, comparing fields regardless of case
+// if fold is set.
+func isPathPrefix(prefix, path []string, fold bool) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i, segment := range prefix {
+		if segment == "*" || path[i] == "*" || segment == path[i] {
+			continue
+		}
+		if !fold || !strings.EqualFold(segment, path[i]) {
+			return false
+		}
+	}
+	return true
+}
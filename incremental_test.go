@@ -0,0 +1,167 @@
+package jmespath
+
+import (
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+// countingRuntime returns a runtime with a count function that counts
+// how often it is called.
+func countingRuntime(calls *int) *Runtime {
+	rt := NewRuntime()
+	rt.RegisterFunction("count", func(ctx CallContext, args []interface{}) (interface{}, error) {
+		*calls++
+		return args[0], nil
+	})
+	return rt
+}
+
+func TestIncremental(t *testing.T) {
+	assert := assert.New(t)
+	calls := 0
+	jp, err := countingRuntime(&calls).Compile("count(people[?age > `30`].name)")
+	assert.Nil(err)
+	document := decodeJSON(`{"people": [{"name": "a", "age": 20}, {"name": "b", "age": 40}], "other": 1}`)
+	inc, err := jp.Incremental(document)
+	assert.Nil(err)
+	assert.Equal([]interface{}{"b"}, inc.Result())
+	assert.Equal(1, calls)
+
+	changed, err := inc.ApplyPatch([]byte(`[{"op": "replace", "path": "/other", "value": 2}]`))
+	assert.Nil(err)
+	assert.False(changed)
+	assert.Equal(1, calls, "an unrelated change must not be evaluated")
+	assert.Equal(2.0, inc.Document().(map[string]interface{})["other"])
+
+	changed, err = inc.ApplyPatch([]byte(`[{"op": "replace", "path": "/people/0/age", "value": 50}]`))
+	assert.Nil(err)
+	assert.True(changed)
+	assert.Equal([]interface{}{"a", "b"}, inc.Result())
+	assert.Equal(2, calls)
+
+	changed, err = inc.ApplyMergePatch([]byte(`{"people": [{"name": "c", "age": 10}]}`))
+	assert.Nil(err)
+	assert.True(changed)
+	assert.Equal([]interface{}{}, inc.Result())
+
+	changed, err = inc.ApplyMergePatch([]byte(`{"people": [{"name": "d", "age": 5}]}`))
+	assert.Nil(err)
+	assert.False(changed, "the result did not change")
+
+	assert.Equal(decodeJSON(`{"people": [{"name": "a", "age": 20}, {"name": "b", "age": 40}], "other": 1}`), document)
+}
+
+func TestIncrementalReferencesToTheCurrentNode(t *testing.T) {
+	assert := assert.New(t)
+	inc, err := MustCompile("first_of(&p, &q)").Incremental(decodeJSON(`{"p": 1, "q": 2}`))
+	assert.Nil(err)
+	assert.Equal(1.0, inc.Result())
+	changed, err := inc.ApplyPatch([]byte(`[{"op": "replace", "path": "/p", "value": 2}]`))
+	assert.Nil(err)
+	assert.True(changed)
+	assert.Equal(2.0, inc.Result())
+
+	inc, err = MustCompile("exists(&a.b)").Incremental(decodeJSON(`{"a": {}}`))
+	assert.Nil(err)
+	assert.Equal(false, inc.Result())
+	changed, err = inc.ApplyPatch([]byte(`[{"op": "add", "path": "/a/b", "value": null}]`))
+	assert.Nil(err)
+	assert.True(changed)
+	assert.Equal(true, inc.Result())
+}
+
+func TestIncrementalBranches(t *testing.T) {
+	assert := assert.New(t)
+	calls := 0
+	jp, err := countingRuntime(&calls).Compile("{a: count(a), b: count(b.c), n: `1`}")
+	assert.Nil(err)
+	inc, err := jp.Incremental(decodeJSON(`{"a": 1, "b": {"c": 2}}`))
+	assert.Nil(err)
+	assert.Equal(map[string]interface{}{"a": 1.0, "b": 2.0, "n": 1.0}, inc.Result())
+	assert.Equal(2, calls)
+
+	changed, err := inc.ApplyPatch([]byte(`[{"op": "replace", "path": "/b/c", "value": 3}]`))
+	assert.Nil(err)
+	assert.True(changed)
+	assert.Equal(map[string]interface{}{"a": 1.0, "b": 3.0, "n": 1.0}, inc.Result())
+	assert.Equal(3, calls, "only the affected branch is evaluated")
+
+	changed, err = inc.ApplyPatch([]byte(`[{"op": "add", "path": "/b/d", "value": 3}]`))
+	assert.Nil(err)
+	assert.False(changed)
+	assert.Equal(3, calls)
+
+	changed, err = inc.ApplyPatch([]byte(`[{"op": "replace", "path": "", "value": null}]`))
+	assert.Nil(err)
+	assert.True(changed)
+	assert.Nil(inc.Result())
+
+	changed, err = inc.ApplyPatch([]byte(`[{"op": "replace", "path": "", "value": {"a": 5}}]`))
+	assert.Nil(err)
+	assert.True(changed)
+	assert.Equal(map[string]interface{}{"a": 5.0, "b": nil, "n": 1.0}, inc.Result())
+}
+
+func TestIncrementalMultiSelectList(t *testing.T) {
+	assert := assert.New(t)
+	jp, err := Compile("[a, b.*]")
+	assert.Nil(err)
+	inc, err := jp.Incremental(decodeJSON(`{"a": 1, "b": {"x": 2}}`))
+	assert.Nil(err)
+	assert.Equal([]interface{}{1.0, []interface{}{2.0}}, inc.Result())
+	changed, err := inc.ApplyMergePatch([]byte(`{"b": {"x": 3}}`))
+	assert.Nil(err)
+	assert.True(changed)
+	assert.Equal([]interface{}{1.0, []interface{}{3.0}}, inc.Result())
+}
+
+func TestIncrementalOrdered(t *testing.T) {
+	assert := assert.New(t)
+	rt := NewRuntime()
+	rt.SetOrderedObjects(true)
+	jp, err := rt.Compile("{z: z, a: a}")
+	assert.Nil(err)
+	document, _ := UnmarshalOrdered([]byte(`{"a": 1, "z": 2}`))
+	inc, err := jp.Incremental(document)
+	assert.Nil(err)
+	changed, err := inc.ApplyPatch([]byte(`[{"op": "replace", "path": "/a", "value": 3}]`))
+	assert.Nil(err)
+	assert.True(changed)
+	result := inc.Result().(*OrderedMap)
+	assert.Equal([]string{"z", "a"}, result.Keys())
+	assert.Equal(3.0, result.values["a"])
+}
+
+func TestIncrementalErrorsKeepState(t *testing.T) {
+	assert := assert.New(t)
+	jp, err := Compile("length(a)")
+	assert.Nil(err)
+	inc, err := jp.Incremental(decodeJSON(`{"a": "xyz"}`))
+	assert.Nil(err)
+	assert.Equal(3.0, inc.Result())
+
+	_, err = inc.ApplyPatch([]byte(`[{"op": "replace", "path": "/a", "value": 1}]`))
+	assert.NotNil(err)
+	_, err = inc.ApplyPatch([]byte(`[{"op": "replace", "path": "/a", "value": "x"}, {"op": "remove", "path": "/b"}]`))
+	assert.NotNil(err)
+	assert.Equal(decodeJSON(`{"a": "xyz"}`), inc.Document())
+	assert.Equal(3.0, inc.Result())
+
+	_, err = jp.Incremental(decodeJSON(`{"a": 1}`))
+	assert.NotNil(err)
+}
+
+func TestPathsOverlap(t *testing.T) {
+	assert := assert.New(t)
+	assert.True(pathsOverlap([][]string{{"a", "b"}}, [][]string{{"a"}}, false))
+	assert.True(pathsOverlap([][]string{{"a"}}, [][]string{{"a", "b"}}, false))
+	assert.True(pathsOverlap([][]string{{"*", "b"}}, [][]string{{"x", "b"}}, false))
+	assert.True(pathsOverlap([][]string{{}}, [][]string{{"x"}}, false))
+	assert.True(pathsOverlap([][]string{{"x"}}, [][]string{{}}, false))
+	assert.False(pathsOverlap([][]string{{"a", "b"}}, [][]string{{"a", "c"}}, false))
+	assert.False(pathsOverlap([][]string{{"*", "b"}}, [][]string{{"x", "c"}}, false))
+	assert.False(pathsOverlap(nil, [][]string{{}}, false))
+	assert.False(pathsOverlap([][]string{{"a", "name"}}, [][]string{{"a", "Name"}}, false))
+	assert.True(pathsOverlap([][]string{{"a", "name"}}, [][]string{{"a", "Name"}}, true))
+}
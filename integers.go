@@ -0,0 +1,416 @@
+package jmespath
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+// IntegerMode controls how expressions handle integers that a float64
+// cannot represent exactly, those beyond ±2^53 such as 64-bit IDs and
+// byte counts, see Runtime.SetIntegerMode.
+type IntegerMode int
+
+const (
+	// IntegersAsFloat decodes every number as a float64, as encoding/json
+	// does, which rounds integers beyond ±2^53.  This is the default.
+	IntegersAsFloat IntegerMode = iota
+	// IntegersExact keeps integers beyond ±2^53 in JSON literals and
+	// documents exact, as int64 values if they fit and *big.Int values
+	// otherwise, and returns them as such.  Other numbers are float64
+	// values as with IntegersAsFloat.
+	IntegersExact
+	// IntegersAsString is like IntegersExact, but returns the integers a
+	// float64 cannot represent exactly as decimal strings, for consumers
+	// that decode every JSON number as a double, like JavaScript.
+	IntegersAsString
+	// IntegersAsNumber is like IntegersExact, but returns the integers a
+	// float64 cannot represent exactly as json.Number values, which
+	// encoding/json encodes as numbers without losing precision.
+	IntegersAsNumber
+)
+
+// SetIntegerMode sets how expressions compiled after the call handle
+// integers that a float64 cannot represent exactly.  With any mode but
+// IntegersAsFloat, such integers are kept exact in JSON literals, as in
+// "[?id == `1234567890123456789`]", in documents decoded by SearchBytes and
+// from_json, and by comparisons and the functions abs, ceil, floor, max,
+// min, sort, sum and to_number, whose results are exact integers where the
+// arguments are.  The integer types of Go values passed to Search are
+// compared exactly in every mode.  Documents decoded by a Decoder set with
+// SetDecoder keep exact integers only if it decodes numbers as json.Number
+// values.
+func (rt *Runtime) SetIntegerMode(mode IntegerMode) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.integers = mode
+}
+
+// maxExactInteger is the magnitude up to which float64 represents every
+// integer exactly.
+const maxExactInteger = 1 << 53
+
+// exactNumber returns the number value in the representation exact
+// integer modes use: a float64 for integers a float64 represents exactly
+// and for numbers that are not integers, and an int64 or a *big.Int for
+// other integers.  It returns false if value is not a number.
+func exactNumber(value interface{}) (interface{}, bool) {
+	n := asNumber(value)
+	switch n.kind {
+	case signedNumber:
+		if n.i < -maxExactInteger || n.i > maxExactInteger {
+			return n.i, true
+		}
+		return float64(n.i), true
+	case unsignedNumber:
+		if n.u > math.MaxInt64 {
+			return new(big.Int).SetUint64(n.u), true
+		}
+		return exactNumber(int64(n.u))
+	case bigNumber:
+		if n.b.IsInt64() {
+			return exactNumber(n.b.Int64())
+		}
+		return n.b, true
+	case floatNumber:
+		return n.f, true
+	}
+	return nil, false
+}
+
+// exactInteger converts n, which must be of an integer kind, to a
+// *big.Int.
+func (n number) exactInteger() *big.Int {
+	switch n.kind {
+	case signedNumber:
+		return big.NewInt(n.i)
+	case unsignedNumber:
+		return new(big.Int).SetUint64(n.u)
+	}
+	return n.b
+}
+
+// parseExactNumber parses the JSON number text in the representation of
+// exactNumber.
+func parseExactNumber(text string) (interface{}, error) {
+	if !strings.ContainsAny(text, ".eE") {
+		if i, ok := new(big.Int).SetString(text, 10); ok {
+			n, _ := exactNumber(i)
+			return n, nil
+		}
+	}
+	return json.Number(text).Float64()
+}
+
+// unmarshalExact decodes the JSON text data like json.Unmarshal, but with
+// integers in the representation of exactNumber.
+func unmarshalExact(data []byte) (interface{}, error) {
+	value, err := unmarshalJSONNumbers(data)
+	if err != nil {
+		return nil, err
+	}
+	return exactNumbers(value)
+}
+
+// exactNumbers replaces the json.Number values in the decoded JSON value
+// with numbers in the representation of exactNumber.  Arrays and objects
+// are modified in place.
+func exactNumbers(value interface{}) (interface{}, error) {
+	var err error
+	switch v := value.(type) {
+	case json.Number:
+		return parseExactNumber(string(v))
+	case []interface{}:
+		for i, element := range v {
+			if v[i], err = exactNumbers(element); err != nil {
+				return nil, err
+			}
+		}
+	case map[string]interface{}:
+		for key, element := range v {
+			if v[key], err = exactNumbers(element); err != nil {
+				return nil, err
+			}
+		}
+	case *OrderedMap:
+		for _, key := range v.keys {
+			if v.values[key], err = exactNumbers(v.values[key]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return value, nil
+}
+
+// render returns result with the integers a float64 cannot represent
+// exactly converted as the integer mode of intr requires.  Arrays and
+// objects are copied if they contain such integers, as they may be part of
+// the searched data.
+func (intr *treeInterpreter) render(result interface{}) interface{} {
+	if intr.integers != IntegersAsString && intr.integers != IntegersAsNumber {
+		return result
+	}
+	rendered, _ := intr.renderIntegers(result)
+	return rendered
+}
+
+// renderIntegers implements render, and reports whether result was
+// changed.
+func (intr *treeInterpreter) renderIntegers(result interface{}) (interface{}, bool) {
+	switch v := result.(type) {
+	case float64, string, bool, nil:
+		return result, false
+	case []interface{}:
+		var rendered []interface{}
+		for i, element := range v {
+			if element, changed := intr.renderIntegers(element); changed {
+				if rendered == nil {
+					rendered = append([]interface{}(nil), v...)
+				}
+				rendered[i] = element
+			}
+		}
+		if rendered == nil {
+			return result, false
+		}
+		return rendered, true
+	case map[string]interface{}:
+		var rendered map[string]interface{}
+		for key, element := range v {
+			if element, changed := intr.renderIntegers(element); changed {
+				if rendered == nil {
+					rendered = make(map[string]interface{}, len(v))
+					for k, e := range v {
+						rendered[k] = e
+					}
+				}
+				rendered[key] = element
+			}
+		}
+		if rendered == nil {
+			return result, false
+		}
+		return rendered, true
+	case *OrderedMap:
+		var rendered *OrderedMap
+		for _, key := range v.keys {
+			if element, changed := intr.renderIntegers(v.values[key]); changed {
+				if rendered == nil {
+					rendered = NewOrderedMap()
+					for _, k := range v.keys {
+						rendered.Set(k, v.values[k])
+					}
+				}
+				rendered.Set(key, element)
+			}
+		}
+		if rendered == nil {
+			return result, false
+		}
+		return rendered, true
+	}
+	n := asNumber(result)
+	if n.kind != signedNumber && n.kind != unsignedNumber && n.kind != bigNumber {
+		return result, false
+	}
+	if exact, _ := exactNumber(result); !isNonFloatNumber(exact) {
+		return result, false
+	}
+	text := n.exactInteger().String()
+	if intr.integers == IntegersAsNumber {
+		return json.Number(text), true
+	}
+	return text, true
+}
+
+// exactIntegerFunctions replaces the built-in functions that compute with
+// numbers when integers are kept exact, see IntegerMode.  Their arguments
+// are in the representation of exactNumber.
+var exactIntegerFunctions = map[string]functionEntry{
+	"abs": {
+		name: "abs",
+		arguments: []argSpec{
+			{types: []jpType{jpNumber}},
+		},
+		handler: jpfExactAbs,
+	},
+	"ceil": {
+		name: "ceil",
+		arguments: []argSpec{
+			{types: []jpType{jpNumber}},
+		},
+		handler: jpfExactCeil,
+	},
+	"floor": {
+		name: "floor",
+		arguments: []argSpec{
+			{types: []jpType{jpNumber}},
+		},
+		handler: jpfExactFloor,
+	},
+	"from_json": {
+		name: "from_json",
+		arguments: []argSpec{
+			{types: []jpType{jpString}},
+		},
+		handler: jpfExactFromJSON,
+	},
+	"max": {
+		name: "max",
+		arguments: []argSpec{
+			{types: []jpType{jpArrayNumber, jpArrayString}},
+		},
+		handler: jpfExactMax,
+	},
+	"min": {
+		name: "min",
+		arguments: []argSpec{
+			{types: []jpType{jpArrayNumber, jpArrayString}},
+		},
+		handler: jpfExactMin,
+	},
+	"sort": {
+		name: "sort",
+		arguments: []argSpec{
+			{types: []jpType{jpArrayString, jpArrayNumber}},
+			{types: []jpType{jpString}, optional: true},
+		},
+		handler:   jpfExactSort,
+		hasExpRef: true,
+	},
+	"sum": {
+		name: "sum",
+		arguments: []argSpec{
+			{types: []jpType{jpArrayNumber}},
+		},
+		handler: jpfExactSum,
+	},
+	"to_number": {
+		name: "to_number",
+		arguments: []argSpec{
+			{types: []jpType{jpAny}},
+			{types: []jpType{jpNumber}, optional: true},
+		},
+		handler: jpfExactToNumber,
+	},
+	"to_number_strict": {
+		name: "to_number_strict",
+		arguments: []argSpec{
+			{types: []jpType{jpAny}},
+			{types: []jpType{jpNumber}, optional: true},
+		},
+		handler: jpfExactToNumberStrict,
+	},
+}
+
+func jpfExactAbs(arguments []interface{}) (interface{}, error) {
+	n := asNumber(arguments[0])
+	switch n.kind {
+	case signedNumber, bigNumber:
+		abs, _ := exactNumber(new(big.Int).Abs(n.exactInteger()))
+		return abs, nil
+	}
+	return jpfAbs(arguments)
+}
+
+func jpfExactCeil(arguments []interface{}) (interface{}, error) {
+	if asNumber(arguments[0]).kind != floatNumber {
+		return arguments[0], nil
+	}
+	return jpfCeil(arguments)
+}
+
+func jpfExactFloor(arguments []interface{}) (interface{}, error) {
+	if asNumber(arguments[0]).kind != floatNumber {
+		return arguments[0], nil
+	}
+	return jpfFloor(arguments)
+}
+
+func jpfExactFromJSON(arguments []interface{}) (interface{}, error) {
+	decoded, err := unmarshalExact([]byte(arguments[0].(string)))
+	if err != nil {
+		return nil, nil
+	}
+	return decoded, nil
+}
+
+// extremeNumber returns the element of items, which must all be numbers,
+// for which better returns true when compared with every other element,
+// or nil if items is empty.
+func extremeNumber(items []interface{}, better func(comparison int) bool) interface{} {
+	var best interface{}
+	for i, item := range items {
+		if comparison, ok := compareNumbers(item, best); i == 0 || ok && better(comparison) {
+			best = item
+		}
+	}
+	return best
+}
+
+func jpfExactMax(arguments []interface{}) (interface{}, error) {
+	if _, ok := toArrayNum(arguments[0]); !ok {
+		return jpfMax(arguments)
+	}
+	return extremeNumber(arguments[0].([]interface{}), func(comparison int) bool {
+		return comparison > 0
+	}), nil
+}
+
+func jpfExactMin(arguments []interface{}) (interface{}, error) {
+	if _, ok := toArrayNum(arguments[0]); !ok {
+		return jpfMin(arguments)
+	}
+	return extremeNumber(arguments[0].([]interface{}), func(comparison int) bool {
+		return comparison < 0
+	}), nil
+}
+
+func jpfExactSort(arguments []interface{}) (interface{}, error) {
+	if _, ok := toArrayNum(arguments[1]); !ok {
+		return jpfSort(arguments)
+	}
+	items := arguments[1].([]interface{})
+	sorted := make([]interface{}, len(items))
+	copy(sorted, items)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		comparison, _ := compareNumbers(sorted[i], sorted[j])
+		return comparison < 0
+	})
+	return sorted, nil
+}
+
+// jpfExactSum adds the numbers exactly if they are all integers, including
+// float64 values without a fractional part.
+func jpfExactSum(arguments []interface{}) (interface{}, error) {
+	sum := new(big.Int)
+	for _, item := range arguments[0].([]interface{}) {
+		n := asNumber(item)
+		if n.kind != floatNumber {
+			sum.Add(sum, n.exactInteger())
+			continue
+		}
+		if math.Trunc(n.f) != n.f || math.IsInf(n.f, 0) {
+			return jpfSum(arguments)
+		}
+		whole, _ := big.NewFloat(n.f).Int(nil)
+		sum.Add(sum, whole)
+	}
+	result, _ := exactNumber(sum)
+	return result, nil
+}
+
+func jpfExactToNumber(arguments []interface{}) (interface{}, error) {
+	number, _, err := convertToNumber(arguments, true)
+	return number, err
+}
+
+func jpfExactToNumberStrict(arguments []interface{}) (interface{}, error) {
+	number, ok, err := convertToNumber(arguments, true)
+	if err != nil || ok {
+		return number, err
+	}
+	return nil, notNumberError(arguments[0])
+}
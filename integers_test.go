@@ -0,0 +1,167 @@
+package jmespath
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func mustBigInt(s string) *big.Int {
+	i, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		panic("invalid integer " + s)
+	}
+	return i
+}
+
+func TestIntegersAsFloatIsDefault(t *testing.T) {
+	assert := assert.New(t)
+	result, err := SearchBytes("[id, id == `1234567890123456789`]", []byte(`{"id": 1234567890123456788}`))
+	assert.Nil(err)
+	assert.Equal([]interface{}{1234567890123456788.0, true}, result)
+}
+
+func TestIntegersExact(t *testing.T) {
+	assert := assert.New(t)
+	document := []byte(`{
+		"items": [
+			{"id": 1234567890123456789, "size": 9007199254740993},
+			{"id": 1234567890123456788, "size": 1},
+			{"id": 98765432109876543210, "size": 2.5}
+		],
+		"small": 3
+	}`)
+	rt := NewRuntime()
+	rt.SetIntegerMode(IntegersExact)
+	cases := []struct {
+		expression string
+		expected   interface{}
+	}{
+		{"items[0].id", int64(1234567890123456789)},
+		{"items[2].id", mustBigInt("98765432109876543210")},
+		{"small", 3.0},
+		{"`12345678901234567890123`", mustBigInt("12345678901234567890123")},
+		{"`[9007199254740993, 1.5]`", []interface{}{int64(9007199254740993), 1.5}},
+		{"items[?id == `1234567890123456789`].size", []interface{}{int64(9007199254740993)}},
+		{"items[?id > `1234567890123456788`].id", []interface{}{int64(1234567890123456789), mustBigInt("98765432109876543210")}},
+		{"items[?size > `9007199254740992`].id", []interface{}{int64(1234567890123456789)}},
+		{"sum(items[:2].size)", int64(9007199254740994)},
+		{"sum(items[1:].size)", 3.5},
+		{"sum(`[9007199254740992, 1]`)", int64(9007199254740993)},
+		{"sum(`[1, 2]`)", 3.0},
+		{"max(items[*].id)", mustBigInt("98765432109876543210")},
+		{"min(items[*].id)", int64(1234567890123456788)},
+		{"sort(items[*].id)", []interface{}{int64(1234567890123456788), int64(1234567890123456789), mustBigInt("98765432109876543210")}},
+		{"abs(`-9223372036854775808`)", mustBigInt("9223372036854775808")},
+		{"abs(`-2.5`)", 2.5},
+		{"ceil(items[0].id)", int64(1234567890123456789)},
+		{"floor(`2.5`)", 2.0},
+		{"to_number('1234567890123456789')", int64(1234567890123456789)},
+		{"to_number('2.5')", 2.5},
+		{"to_number(' 1234567890123456789 ')", int64(1234567890123456789)},
+		{"to_number('0xffffffffffffffff', `16`)", mustBigInt("18446744073709551615")},
+		{"to_number('-1f', `16`)", -31.0},
+		{"to_number(items[0].id)", int64(1234567890123456789)},
+		{"to_number_strict('1234567890123456789')", int64(1234567890123456789)},
+		{"max_by(items, &id).size", 2.5},
+		{"sort_by(items, &id)[*].size", []interface{}{1.0, int64(9007199254740993), 2.5}},
+		{"type(items[2].id)", "number"},
+		{"to_string(items[2].id)", "98765432109876543210"},
+		{"from_json('{\"id\": 1234567890123456789}').id", int64(1234567890123456789)},
+		{"from_json('[98765432109876543210, 2.5]')", []interface{}{mustBigInt("98765432109876543210"), 2.5}},
+		{"from_json('1 2')", nil},
+	}
+	for _, tt := range cases {
+		jp, err := rt.Compile(tt.expression)
+		if !assert.Nil(err, tt.expression) {
+			continue
+		}
+		result, err := jp.SearchBytes(document)
+		assert.Nil(err, tt.expression)
+		assert.Equal(tt.expected, result, tt.expression)
+	}
+}
+
+func TestIntegersExactOrdered(t *testing.T) {
+	assert := assert.New(t)
+	rt := NewRuntime()
+	rt.SetIntegerMode(IntegersExact)
+	rt.SetOrderedObjects(true)
+	result, err := rt.SearchBytes("@", []byte(`{"b": 1234567890123456789, "a": 1}`))
+	assert.Nil(err)
+	encoded, err := json.Marshal(result)
+	assert.Nil(err)
+	assert.Equal(`{"b":1234567890123456789,"a":1}`, string(encoded))
+}
+
+func TestIntegerRendering(t *testing.T) {
+	assert := assert.New(t)
+	document := []byte(`{"ids": [1234567890123456789, 2], "big": 98765432109876543210, "name": "x"}`)
+	for _, tt := range []struct {
+		mode     IntegerMode
+		expected interface{}
+	}{
+		{IntegersExact, map[string]interface{}{
+			"ids": []interface{}{int64(1234567890123456789), 2.0},
+			"big": mustBigInt("98765432109876543210"),
+		}},
+		{IntegersAsString, map[string]interface{}{
+			"ids": []interface{}{"1234567890123456789", 2.0},
+			"big": "98765432109876543210",
+		}},
+		{IntegersAsNumber, map[string]interface{}{
+			"ids": []interface{}{json.Number("1234567890123456789"), 2.0},
+			"big": json.Number("98765432109876543210"),
+		}},
+	} {
+		rt := NewRuntime()
+		rt.SetIntegerMode(tt.mode)
+		result, err := rt.SearchBytes("{ids: ids, big: big}", document)
+		assert.Nil(err)
+		assert.Equal(tt.expected, result)
+	}
+
+	rt := NewRuntime()
+	rt.SetIntegerMode(IntegersAsString)
+	data := map[string]interface{}{"ids": []interface{}{int64(1 << 60), int64(1)}}
+	result, err := rt.Search("ids", data)
+	assert.Nil(err)
+	assert.Equal([]interface{}{"1152921504606846976", int64(1)}, result)
+	assert.Equal(int64(1<<60), data["ids"].([]interface{})[0], "the searched data must not be modified")
+
+	jp, err := rt.Compile("[0]")
+	assert.Nil(err)
+	result, err = jp.SearchWithParams([]interface{}{uint64(1 << 63)}, nil)
+	assert.Nil(err)
+	assert.Equal("9223372036854775808", result)
+}
+
+func TestCompareBigNumbers(t *testing.T) {
+	assert := assert.New(t)
+	huge := mustBigInt("98765432109876543210")
+	cases := []struct {
+		left, right interface{}
+		expected    int
+	}{
+		{huge, huge, 0},
+		{huge, uint64(1<<64 - 1), 1},
+		{int64(-1), huge, -1},
+		{huge, 9.87654321098765e19, 1},
+		{huge, 1e20, -1},
+		{mustBigInt("-98765432109876543210"), -1e30, 1},
+		{big.NewInt(3), 3.0, 0},
+		{big.NewInt(3), 3.5, -1},
+	}
+	for _, tt := range cases {
+		result, ok := compareNumbers(tt.left, tt.right)
+		assert.True(ok, "%v %v", tt.left, tt.right)
+		assert.Equal(tt.expected, result, "%v %v", tt.left, tt.right)
+		result, _ = compareNumbers(tt.right, tt.left)
+		assert.Equal(-tt.expected, result, "%v %v", tt.right, tt.left)
+	}
+	var nilInt *big.Int
+	_, ok := compareNumbers(nilInt, 1)
+	assert.False(ok)
+}
@@ -2,17 +2,88 @@ package jmespath
 
 import (
 	"errors"
-	"reflect"
-	"unicode"
-	"unicode/utf8"
+	"strings"
+
+	"github.com/jmespath/go-jmespath/jputil"
 )
 
 /* This is a tree based interpreter.  It walks the AST and directly
    interprets the AST to search through a JSON document.
 */
 
+// treeInterpreter only holds configuration that never changes once it has
+// been created, so a single interpreter can be used by any number of
+// goroutines at once.  Any state needed while evaluating an expression
+// must be kept on the stack of the evaluating goroutine.
 type treeInterpreter struct {
-	fCall *functionCaller
+	fCall      *functionCaller
+	multiValue MultiValueMode
+	// params holds the values of named parameters.  It is only set on
+	// the per-call copy of an interpreter made by SearchWithParams.
+	params map[string]interface{}
+	// key is the key of the object value being evaluated, returned by
+	// key().  It is only set on the per-value copies of an interpreter
+	// made by withKey.
+	key interface{}
+	// ordered is set when objects are evaluated in a deterministic key
+	// order, see Runtime.SetOrderedObjects.
+	ordered bool
+	// sortedKeys is set when the keys of objects are visited in sorted
+	// order, see Runtime.SetSortedKeys.
+	sortedKeys bool
+	// strict is set when reads of missing values are errors, see
+	// Runtime.SetStrict.
+	strict bool
+	// strictBounds is set when strict mode also reports indexes and
+	// slices outside of arrays, see Runtime.SetStrictBounds.
+	strictBounds bool
+	// tracer observes evaluations, see Runtime.SetTracer.
+	tracer Tracer
+	// decoder decodes documents passed as JSON, see Runtime.SetDecoder.
+	decoder Decoder
+	// graphemes is set when string functions count grapheme clusters,
+	// see Runtime.SetGraphemeClusters.
+	graphemes bool
+	// collations holds the collations registered with
+	// Runtime.RegisterCollation, and collation is the default one, see
+	// Runtime.SetCollation.
+	collations map[string]Collation
+	collation  Collation
+	// legacyCompare is set when ordering comparators return null for
+	// strings, see Runtime.SetLegacyComparisons.
+	legacyCompare bool
+	// integers is the IntegerMode, see Runtime.SetIntegerMode.
+	integers IntegerMode
+	// numbers is how numbers are written as text, see
+	// Runtime.SetNumberFormat.
+	numbers NumberFormat
+	// workers is the number of goroutines that evaluate large
+	// projections, see Runtime.SetParallelism.
+	workers int
+	// metrics measures evaluations, see Runtime.SetMetrics.
+	metrics Metrics
+	// invalidSortKeys is how sort_by, max_by and min_by handle keys that
+	// are not comparable, see Runtime.SetInvalidSortKeys.
+	invalidSortKeys InvalidSortKeyMode
+	// keepNulls is set when projections keep null results, see
+	// Runtime.SetKeepNulls.
+	keepNulls bool
+	// foldKeys is set when fields match the keys of objects
+	// case-insensitively, see Runtime.SetCaseInsensitiveKeys.
+	foldKeys bool
+}
+
+// interpreted reports whether expressions are evaluated by Execute as
+// written, rather than optimized and compiled, for the modes the compiled
+// closures do not implement.
+func (intr *treeInterpreter) interpreted() bool {
+	return intr.strict || intr.tracer != nil || intr.keepNulls || intr.foldKeys
+}
+
+// keeps reports whether a projection keeps current, the result of its
+// right side for an element, in its result.
+func (intr *treeInterpreter) keeps(current interface{}) bool {
+	return current != nil || intr.keepNulls
 }
 
 func newInterpreter() *treeInterpreter {
@@ -21,7 +92,10 @@ func newInterpreter() *treeInterpreter {
 	return &interpreter
 }
 
-type expRef struct {
+// ExpRef is the value an expression reference (&expr) evaluates to.  It is
+// passed to functions in place of the evaluated expression so that the
+// function can decide when, and against which values, to evaluate it.
+type ExpRef struct {
 	ref ASTNode
 }
 
@@ -39,52 +113,72 @@ func (intr *treeInterpreter) Execute(node ASTNode, value interface{}) (interface
 		if err != nil {
 			return nil, err
 		}
-		switch node.value {
-		case tEQ:
-			return objsEqual(left, right), nil
-		case tNE:
-			return !objsEqual(left, right), nil
-		}
-		leftNum, ok := left.(float64)
-		if !ok {
-			return nil, nil
-		}
-		rightNum, ok := right.(float64)
-		if !ok {
-			return nil, nil
+		if left, right, err = intr.resolveOperands(left, right); err != nil {
+			return nil, err
 		}
-		switch node.value {
-		case tGT:
-			return leftNum > rightNum, nil
-		case tGTE:
-			return leftNum >= rightNum, nil
-		case tLT:
-			return leftNum < rightNum, nil
-		case tLTE:
-			return leftNum <= rightNum, nil
+		if intr.strict {
+			if err := checkOrdered(node, left, right, !intr.legacyCompare); err != nil {
+				return nil, err
+			}
 		}
+		return compare(node.value.(tokType), left, right, !intr.legacyCompare), nil
 	case ASTExpRef:
-		return expRef{ref: node.children[0]}, nil
+		return ExpRef{ref: node.children[0]}, nil
 	case ASTFunctionExpression:
-		resolvedArgs := []interface{}{}
-		for _, arg := range node.children {
-			current, err := intr.Execute(arg, value)
-			if err != nil {
+		resolvedArgs, err := evalArgs(intr, node, value, func(i int) (interface{}, error) {
+			return intr.Execute(node.children[i], value)
+		})
+		if err != nil {
+			return nil, err
+		}
+		result, err := intr.fCall.CallFunction(node.value.(string), resolvedArgs, intr)
+		return result, callError(node, err)
+	case ASTField:
+		if r, ok := value.(Resolver); ok {
+			return intr.resolverField(node, r)
+		}
+		if intr.foldKeys {
+			node.value = foldKey(node.value.(string), value)
+		}
+		if intr.strict {
+			if err := intr.checkField(node, value); err != nil {
 				return nil, err
 			}
-			resolvedArgs = append(resolvedArgs, current)
 		}
-		return intr.fCall.CallFunction(node.value.(string), resolvedArgs, intr)
-	case ASTField:
-		if m, ok := value.(map[string]interface{}); ok {
-			key := node.value.(string)
+		key := node.value.(string)
+		switch m := value.(type) {
+		case map[string]interface{}:
 			return m[key], nil
+		case map[string]string:
+			if v, ok := m[key]; ok {
+				return v, nil
+			}
+			return nil, nil
+		case *OrderedMap:
+			return m.values[key], nil
 		}
-		return intr.fieldFromStruct(node.value.(string), value)
+		if isMultiValueMap(value) {
+			return intr.multiValueField(key, value), nil
+		}
+		if isStringKeyedMap(value) {
+			return mapField(key, value), nil
+		}
+		return intr.fieldFromStruct(key, value)
 	case ASTFilterProjection:
 		left, err := intr.Execute(node.children[0], value)
 		if err != nil {
-			return nil, nil
+			return nil, intr.leftError(err)
+		}
+		if source, ok := left.(DataSource); ok {
+			return intr.querySource(node, source)
+		}
+		if left, err = intr.resolve(left); err != nil {
+			return nil, err
+		}
+		if intr.strict {
+			if err := checkArray(node, left); err != nil {
+				return nil, err
+			}
 		}
 		sliceType, ok := left.([]interface{})
 		if !ok {
@@ -94,57 +188,61 @@ func (intr *treeInterpreter) Execute(node ASTNode, value interface{}) (interface
 			return nil, nil
 		}
 		compareNode := node.children[2]
-		collected := []interface{}{}
+		collected := newCollector()
 		for _, element := range sliceType {
 			result, err := intr.Execute(compareNode, element)
 			if err != nil {
+				collected.release()
 				return nil, err
 			}
 			if !isFalse(result) {
 				current, err := intr.Execute(node.children[1], element)
 				if err != nil {
+					collected.release()
 					return nil, err
 				}
-				if current != nil {
-					collected = append(collected, current)
+				if intr.keeps(current) {
+					collected.add(current)
 				}
 			}
 		}
-		return collected, nil
+		return collected.result(), nil
 	case ASTFlatten:
 		left, err := intr.Execute(node.children[0], value)
 		if err != nil {
-			return nil, nil
+			return nil, intr.leftError(err)
 		}
-		sliceType, ok := left.([]interface{})
-		if !ok {
-			// If we can't type convert to []interface{}, there's
-			// a chance this could still work via reflection if we're
-			// dealing with user provided types.
-			if isSliceType(left) {
-				return intr.flattenWithReflection(left)
-			}
-			return nil, nil
+		// The optimizer merges consecutive flattens into a single
+		// node that flattens more than one level.
+		depth := 1
+		if d, ok := node.value.(int); ok {
+			depth = d
 		}
-		flattened := []interface{}{}
-		for _, element := range sliceType {
-			if elementSlice, ok := element.([]interface{}); ok {
-				flattened = append(flattened, elementSlice...)
-			} else if isSliceType(element) {
-				reflectFlat := []interface{}{}
-				v := reflect.ValueOf(element)
-				for i := 0; i < v.Len(); i++ {
-					reflectFlat = append(reflectFlat, v.Index(i).Interface())
-				}
-				flattened = append(flattened, reflectFlat...)
-			} else {
-				flattened = append(flattened, element)
+		if left, err = intr.resolveNested(left, depth); err != nil {
+			return nil, err
+		}
+		if intr.strict {
+			if err := checkArray(node, left); err != nil {
+				return nil, err
 			}
 		}
-		return flattened, nil
+		return intr.flattenDepth(left, depth), nil
 	case ASTIdentity, ASTCurrentNode:
 		return value, nil
 	case ASTIndex:
+		if r, ok := value.(Resolver); ok {
+			return intr.resolverIndex(node, r)
+		}
+		if intr.strict {
+			if err := checkArray(node, value); err != nil {
+				return nil, err
+			}
+			if intr.strictBounds {
+				if err := checkIndex(node, value); err != nil {
+					return nil, err
+				}
+			}
+		}
 		if sliceType, ok := value.([]interface{}); ok {
 			index := node.value.(int)
 			if index < 0 {
@@ -155,16 +253,14 @@ func (intr *treeInterpreter) Execute(node ASTNode, value interface{}) (interface
 			}
 			return nil, nil
 		}
-		// Otherwise try via reflection.
-		rv := reflect.ValueOf(value)
-		if rv.Kind() == reflect.Slice {
-			index := node.value.(int)
+		// Otherwise try other Go slices.
+		if isSliceType(value) {
+			index, length := node.value.(int), sliceLen(value)
 			if index < 0 {
-				index += rv.Len()
+				index += length
 			}
-			if index < rv.Len() && index >= 0 {
-				v := rv.Index(index)
-				return v.Interface(), nil
+			if index < length && index >= 0 {
+				return sliceIndex(value, index), nil
 			}
 		}
 		return nil, nil
@@ -172,10 +268,19 @@ func (intr *treeInterpreter) Execute(node ASTNode, value interface{}) (interface
 		return intr.Execute(node.children[0], value)
 	case ASTLiteral:
 		return node.value, nil
+	case ASTParameter:
+		name := node.value.(string)
+		if value, ok := intr.params[name]; ok {
+			return value, nil
+		}
+		return nil, errors.New("no value for parameter :" + name)
 	case ASTMultiSelectHash:
 		if value == nil {
 			return nil, nil
 		}
+		if intr.ordered {
+			return intr.orderedMultiSelectHash(node, value)
+		}
 		collected := make(map[string]interface{})
 		for _, child := range node.children {
 			current, err := intr.Execute(child, value)
@@ -190,7 +295,7 @@ func (intr *treeInterpreter) Execute(node ASTNode, value interface{}) (interface
 		if value == nil {
 			return nil, nil
 		}
-		collected := []interface{}{}
+		collected := make([]interface{}, 0, len(node.children))
 		for _, child := range node.children {
 			current, err := intr.Execute(child, value)
 			if err != nil {
@@ -244,6 +349,20 @@ func (intr *treeInterpreter) Execute(node ASTNode, value interface{}) (interface
 		if err != nil {
 			return nil, err
 		}
+		if s, ok := slicedString(node, left); ok {
+			return intr.Execute(node.children[1], s)
+		}
+		if source, ok := left.(DataSource); ok {
+			return intr.querySource(node, source)
+		}
+		if left, err = intr.resolve(left); err != nil {
+			return nil, err
+		}
+		if intr.strict {
+			if err := checkArray(node, left); err != nil {
+				return nil, err
+			}
+		}
 		sliceType, ok := left.([]interface{})
 		if !ok {
 			if isSliceType(left) {
@@ -251,18 +370,19 @@ func (intr *treeInterpreter) Execute(node ASTNode, value interface{}) (interface
 			}
 			return nil, nil
 		}
-		collected := []interface{}{}
+		collected := newCollector()
 		var current interface{}
 		for _, element := range sliceType {
 			current, err = intr.Execute(node.children[1], element)
 			if err != nil {
+				collected.release()
 				return nil, err
 			}
-			if current != nil {
-				collected = append(collected, current)
+			if intr.keeps(current) {
+				collected.add(current)
 			}
 		}
-		return collected, nil
+		return collected.result(), nil
 	case ASTSubexpression, ASTIndexExpression:
 		left, err := intr.Execute(node.children[0], value)
 		if err != nil {
@@ -270,120 +390,276 @@ func (intr *treeInterpreter) Execute(node ASTNode, value interface{}) (interface
 		}
 		return intr.Execute(node.children[1], left)
 	case ASTSlice:
-		sliceType, ok := value.([]interface{})
-		if !ok {
-			if isSliceType(value) {
-				return intr.sliceWithReflection(node, value)
+		if r, ok := value.(Resolver); ok {
+			return intr.resolverSlice(node, r)
+		}
+		s, isString := value.(string)
+		if isString && intr.graphemes {
+			return intr.sliceGraphemes(node, s)
+		}
+		if intr.strict && !isString {
+			if err := checkArray(node, value); err != nil {
+				return nil, err
 			}
-			return nil, nil
 		}
 		parts := node.value.([]*int)
-		sliceParams := make([]sliceParam, 3)
-		for i, part := range parts {
-			if part != nil {
-				sliceParams[i].Specified = true
-				sliceParams[i].N = *part
-			}
+		sliced, bounds, err := jputil.SliceAny(value, parts[0], parts[1], parts[2])
+		if err == jputil.ErrNotSliceable {
+			return nil, nil
+		}
+		if err == nil && intr.strict && intr.strictBounds && bounds.OutOfRange {
+			err = newStrictError(node, ErrOutOfRange, value)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return sliced, nil
+	case ASTDescendant:
+		left, err := intr.Execute(node.children[0], value)
+		if err != nil {
+			return nil, err
+		}
+		if left, err = intr.resolveAll(left); err != nil {
+			return nil, err
 		}
-		return slice(sliceType, sliceParams)
+		return intr.descendants(node.value.(string), left), nil
 	case ASTValueProjection:
 		left, err := intr.Execute(node.children[0], value)
 		if err != nil {
-			return nil, nil
+			return nil, intr.leftError(err)
+		}
+		if left, err = intr.resolve(left); err != nil {
+			return nil, err
 		}
-		mapType, ok := left.(map[string]interface{})
+		mapType, ok := intr.toObject(left)
 		if !ok {
+			if intr.strict {
+				return nil, newStrictError(node, ErrNotObject, left)
+			}
 			return nil, nil
 		}
-		values := make([]interface{}, len(mapType))
-		for _, value := range mapType {
-			values = append(values, value)
-		}
-		collected := []interface{}{}
-		for _, element := range values {
-			current, err := intr.Execute(node.children[1], element)
+		keys := intr.iterationKeys(left, mapType)
+		capture := callsKey(node.children[1])
+		collected := newCollector()
+		for _, key := range keys {
+			each := intr
+			if capture {
+				each = intr.withKey(key)
+			}
+			current, err := each.Execute(node.children[1], mapType[key])
 			if err != nil {
+				collected.release()
 				return nil, err
 			}
-			if current != nil {
-				collected = append(collected, current)
+			if intr.keeps(current) {
+				collected.add(current)
 			}
 		}
-		return collected, nil
+		return collected.result(), nil
 	}
 	return nil, errors.New("Unknown AST node: " + node.nodeType.String())
 }
 
-func (intr *treeInterpreter) fieldFromStruct(key string, value interface{}) (interface{}, error) {
-	rv := reflect.ValueOf(value)
-	first, n := utf8.DecodeRuneInString(key)
-	fieldName := string(unicode.ToUpper(first)) + key[n:]
-	if rv.Kind() == reflect.Struct {
-		v := rv.FieldByName(fieldName)
-		if !v.IsValid() {
-			return nil, nil
+// leftError returns the error to report when evaluating the left side of
+// a filter, flatten or object projection fails.  Such errors are ignored
+// unless in strict mode.
+func (intr *treeInterpreter) leftError(err error) error {
+	if intr.strict {
+		return err
+	}
+	return nil
+}
+
+// compare applies the comparator op to left and right.  Ordering
+// comparators return nil unless both sides are numbers, which may be of
+// any Go numeric types, or, if orderStrings is set, both are strings,
+// which are ordered by code point.  Membership operators return nil
+// unless the right side is an array.
+func compare(op tokType, left interface{}, right interface{}, orderStrings bool) interface{} {
+	switch op {
+	case tEQ:
+		return objsEqual(left, right)
+	case tNE:
+		return !objsEqual(left, right)
+	case tIn:
+		return member(left, right)
+	case tNotIn:
+		if found, ok := member(left, right).(bool); ok {
+			return !found
+		}
+		return nil
+	}
+	var comparison int
+	if l, r, ok := stringPair(left, right); ok && orderStrings {
+		comparison = strings.Compare(l, r)
+	} else if asNumber(left).kind == notNumber || asNumber(right).kind == notNumber {
+		return nil
+	} else if comparison, ok = compareNumbers(left, right); !ok {
+		// NaN is neither less than, equal to nor greater than any number.
+		return false
+	}
+	switch op {
+	case tGT:
+		return comparison > 0
+	case tGTE:
+		return comparison >= 0
+	case tLT:
+		return comparison < 0
+	case tLTE:
+		return comparison <= 0
+	}
+	return nil
+}
+
+// multiValueField looks up key in a multi-value map.  Types that provide a
+// Values method, like http.Header, use it so that their own key
+// normalization applies.
+func (intr *treeInterpreter) multiValueField(key string, value interface{}) interface{} {
+	var values []string
+	if getter, ok := value.(interface{ Values(string) []string }); ok {
+		values = getter.Values(key)
+	} else {
+		v := mapField(key, value)
+		if v == nil {
+			return nil
+		}
+		values = v.([]string)
+	}
+	return intr.multiValue.convert(values)
+}
+
+// toObject returns value as a map[string]interface{} if it is an object,
+// converting other maps with string keys, such as maps with multi-value
+// string values or of named map types, as needed.
+func (intr *treeInterpreter) toObject(value interface{}) (map[string]interface{}, bool) {
+	switch m := value.(type) {
+	case map[string]interface{}:
+		return m, true
+	case *OrderedMap:
+		return m.values, true
+	case map[string]string:
+		converted := make(map[string]interface{}, len(m))
+		for key, v := range m {
+			converted[key] = v
+		}
+		return converted, true
+	}
+	if !isStringKeyedMap(value) {
+		return nil, false
+	}
+	multiValue := isMultiValueMap(value)
+	converted := make(map[string]interface{})
+	mapEntries(value, func(key string, v interface{}) {
+		if multiValue {
+			converted[key] = intr.multiValue.convert(v.([]string))
+		} else {
+			converted[key] = v
 		}
-		return v.Interface(), nil
-	} else if rv.Kind() == reflect.Ptr {
-		// Handle multiple levels of indirection?
-		if rv.IsNil() {
-			return nil, nil
+	})
+	return converted, true
+}
+
+func (intr *treeInterpreter) fieldFromStruct(key string, value interface{}) (interface{}, error) {
+	field, _, _ := structField(key, value)
+	return field, nil
+}
+
+// flatten merges the elements of any arrays in value into a single array.
+// It returns nil if value is not an array.
+func (intr *treeInterpreter) flatten(value interface{}) interface{} {
+	sliceType, ok := value.([]interface{})
+	if !ok {
+		// If we can't type convert to []interface{}, there's
+		// a chance this could still work via reflection if we're
+		// dealing with user provided types.
+		if isSliceType(value) {
+			return intr.flattenWithReflection(value)
+		}
+		return nil
+	}
+	flattened := newCollector()
+	for _, element := range sliceType {
+		if elementSlice, ok := element.([]interface{}); ok {
+			flattened.addAll(elementSlice)
+		} else if isSliceType(element) {
+			flattened.addAll(toInterfaceSlice(element))
+		} else {
+			flattened.add(element)
 		}
-		rv = rv.Elem()
-		v := rv.FieldByName(fieldName)
-		if !v.IsValid() {
-			return nil, nil
+	}
+	return flattened.result()
+}
+
+// flattenDepth flattens value depth times, stopping early once it holds
+// no more arrays to flatten.
+func (intr *treeInterpreter) flattenDepth(value interface{}, depth int) interface{} {
+	for i := 0; i < depth && value != nil; i++ {
+		if i > 0 && !holdsArray(value) {
+			break
+		}
+		value = intr.flatten(value)
+	}
+	return value
+}
+
+// holdsArray reports whether value is an array with an array element.
+func holdsArray(value interface{}) bool {
+	elements, ok := value.([]interface{})
+	if !ok {
+		return isSliceType(value)
+	}
+	for _, element := range elements {
+		if isSliceType(element) {
+			return true
 		}
-		return v.Interface(), nil
 	}
-	return nil, nil
+	return false
 }
 
-func (intr *treeInterpreter) flattenWithReflection(value interface{}) (interface{}, error) {
-	v := reflect.ValueOf(value)
+func (intr *treeInterpreter) flattenWithReflection(value interface{}) interface{} {
 	flattened := []interface{}{}
-	for i := 0; i < v.Len(); i++ {
-		element := v.Index(i).Interface()
-		if reflect.TypeOf(element).Kind() == reflect.Slice {
+	for _, element := range toInterfaceSlice(value) {
+		if isSliceType(element) {
 			// Then insert the contents of the element
 			// slice into the flattened slice,
 			// i.e flattened = append(flattened, mySlice...)
-			elementV := reflect.ValueOf(element)
-			for j := 0; j < elementV.Len(); j++ {
-				flattened = append(
-					flattened, elementV.Index(j).Interface())
-			}
+			flattened = append(flattened, toInterfaceSlice(element)...)
 		} else {
 			flattened = append(flattened, element)
 		}
 	}
-	return flattened, nil
+	return flattened
 }
 
-func (intr *treeInterpreter) sliceWithReflection(node ASTNode, value interface{}) (interface{}, error) {
-	v := reflect.ValueOf(value)
-	parts := node.value.([]*int)
-	sliceParams := make([]sliceParam, 3)
-	for i, part := range parts {
-		if part != nil {
-			sliceParams[i].Specified = true
-			sliceParams[i].N = *part
-		}
+// sliceGraphemes returns the substring of s selected by the slice node,
+// whose bounds count grapheme clusters.
+func (intr *treeInterpreter) sliceGraphemes(node ASTNode, s string) (interface{}, error) {
+	characters := graphemeClusters(s)
+	bounds, err := intr.sliceBounds(node, s, len(characters))
+	if err != nil {
+		return nil, err
 	}
-	final := []interface{}{}
-	for i := 0; i < v.Len(); i++ {
-		element := v.Index(i).Interface()
-		final = append(final, element)
+	var sliced strings.Builder
+	for i := 0; i < bounds.Len(); i++ {
+		sliced.WriteString(characters[bounds.Index(i)])
+	}
+	return sliced.String(), nil
+}
+
+// sliceBounds normalizes the bounds of the slice node for value, an array
+// of length elements, and checks them in strict mode.
+func (intr *treeInterpreter) sliceBounds(node ASTNode, value interface{}, length int) (jputil.SliceBounds, error) {
+	bounds, err := sliceBounds(node, length)
+	if err == nil && intr.strict && intr.strictBounds && bounds.OutOfRange {
+		err = newStrictError(node, ErrOutOfRange, value)
 	}
-	return slice(final, sliceParams)
+	return bounds, err
 }
 
 func (intr *treeInterpreter) filterProjectionWithReflection(node ASTNode, value interface{}) (interface{}, error) {
 	compareNode := node.children[2]
 	collected := []interface{}{}
-	v := reflect.ValueOf(value)
-	for i := 0; i < v.Len(); i++ {
-		element := v.Index(i).Interface()
+	for _, element := range toInterfaceSlice(value) {
 		result, err := intr.Execute(compareNode, element)
 		if err != nil {
 			return nil, err
@@ -393,7 +669,7 @@ func (intr *treeInterpreter) filterProjectionWithReflection(node ASTNode, value
 			if err != nil {
 				return nil, err
 			}
-			if current != nil {
+			if intr.keeps(current) {
 				collected = append(collected, current)
 			}
 		}
@@ -403,14 +679,12 @@ func (intr *treeInterpreter) filterProjectionWithReflection(node ASTNode, value
 
 func (intr *treeInterpreter) projectWithReflection(node ASTNode, value interface{}) (interface{}, error) {
 	collected := []interface{}{}
-	v := reflect.ValueOf(value)
-	for i := 0; i < v.Len(); i++ {
-		element := v.Index(i).Interface()
+	for _, element := range toInterfaceSlice(value) {
 		result, err := intr.Execute(node.children[1], element)
 		if err != nil {
 			return nil, err
 		}
-		if result != nil {
+		if intr.keeps(result) {
 			collected = append(collected, result)
 		}
 	}
@@ -1,7 +1,9 @@
 package jmespath
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"testing"
 
 	"github.com/jmespath/go-jmespath/internal/testify/assert"
@@ -51,89 +53,6 @@ func TestCanSupportEmptyInterface(t *testing.T) {
 	assert.Equal("bar", result)
 }
 
-func TestCanSupportUserDefinedStructsValue(t *testing.T) {
-	assert := assert.New(t)
-	s := scalars{Foo: "one", Bar: "bar"}
-	result, err := Search("Foo", s)
-	assert.Nil(err)
-	assert.Equal("one", result)
-}
-
-func TestCanSupportUserDefinedStructsRef(t *testing.T) {
-	assert := assert.New(t)
-	s := scalars{Foo: "one", Bar: "bar"}
-	result, err := Search("Foo", &s)
-	assert.Nil(err)
-	assert.Equal("one", result)
-}
-
-func TestCanSupportStructWithSliceAll(t *testing.T) {
-	assert := assert.New(t)
-	data := sliceType{A: "foo", B: []scalars{{"f1", "b1"}, {"correct", "b2"}}}
-	result, err := Search("B[].Foo", data)
-	assert.Nil(err)
-	assert.Equal([]interface{}{"f1", "correct"}, result)
-}
-
-func TestCanSupportStructWithSlicingExpression(t *testing.T) {
-	assert := assert.New(t)
-	data := sliceType{A: "foo", B: []scalars{{"f1", "b1"}, {"correct", "b2"}}}
-	result, err := Search("B[:].Foo", data)
-	assert.Nil(err)
-	assert.Equal([]interface{}{"f1", "correct"}, result)
-}
-
-func TestCanSupportStructWithFilterProjection(t *testing.T) {
-	assert := assert.New(t)
-	data := sliceType{A: "foo", B: []scalars{{"f1", "b1"}, {"correct", "b2"}}}
-	result, err := Search("B[? `true` ].Foo", data)
-	assert.Nil(err)
-	assert.Equal([]interface{}{"f1", "correct"}, result)
-}
-
-func TestCanSupportStructWithSlice(t *testing.T) {
-	assert := assert.New(t)
-	data := sliceType{A: "foo", B: []scalars{{"f1", "b1"}, {"correct", "b2"}}}
-	result, err := Search("B[-1].Foo", data)
-	assert.Nil(err)
-	assert.Equal("correct", result)
-}
-
-func TestCanSupportStructWithOrExpressions(t *testing.T) {
-	assert := assert.New(t)
-	data := sliceType{A: "foo", C: nil}
-	result, err := Search("C || A", data)
-	assert.Nil(err)
-	assert.Equal("foo", result)
-}
-
-func TestCanSupportStructWithSlicePointer(t *testing.T) {
-	assert := assert.New(t)
-	data := sliceType{A: "foo", C: []*scalars{{"f1", "b1"}, {"correct", "b2"}}}
-	result, err := Search("C[-1].Foo", data)
-	assert.Nil(err)
-	assert.Equal("correct", result)
-}
-
-func TestWillAutomaticallyCapitalizeFieldNames(t *testing.T) {
-	assert := assert.New(t)
-	s := scalars{Foo: "one", Bar: "bar"}
-	// Note that there's a lower cased "foo" instead of "Foo",
-	// but it should still correspond to the Foo field in the
-	// scalars struct
-	result, err := Search("foo", &s)
-	assert.Nil(err)
-	assert.Equal("one", result)
-}
-
-func TestCanSupportStructWithSliceLowerCased(t *testing.T) {
-	assert := assert.New(t)
-	data := sliceType{A: "foo", B: []scalars{{"f1", "b1"}, {"correct", "b2"}}}
-	result, err := Search("b[-1].foo", data)
-	assert.Nil(err)
-	assert.Equal("correct", result)
-}
-
 func TestCanSupportStructWithNestedPointers(t *testing.T) {
 	assert := assert.New(t)
 	data := struct{ A *struct{ B int } }{}
@@ -142,45 +61,6 @@ func TestCanSupportStructWithNestedPointers(t *testing.T) {
 	assert.Nil(result)
 }
 
-func TestCanSupportFlattenNestedSlice(t *testing.T) {
-	assert := assert.New(t)
-	data := nestedSlice{A: []sliceType{
-		{B: []scalars{{Foo: "f1a"}, {Foo: "f1b"}}},
-		{B: []scalars{{Foo: "f2a"}, {Foo: "f2b"}}},
-	}}
-	result, err := Search("A[].B[].Foo", data)
-	assert.Nil(err)
-	assert.Equal([]interface{}{"f1a", "f1b", "f2a", "f2b"}, result)
-}
-
-func TestCanSupportFlattenNestedEmptySlice(t *testing.T) {
-	assert := assert.New(t)
-	data := nestedSlice{A: []sliceType{
-		{}, {B: []scalars{{Foo: "a"}}},
-	}}
-	result, err := Search("A[].B[].Foo", data)
-	assert.Nil(err)
-	assert.Equal([]interface{}{"a"}, result)
-}
-
-func TestCanSupportProjectionsWithStructs(t *testing.T) {
-	assert := assert.New(t)
-	data := nestedSlice{A: []sliceType{
-		{A: "first"}, {A: "second"}, {A: "third"},
-	}}
-	result, err := Search("A[*].A", data)
-	assert.Nil(err)
-	assert.Equal([]interface{}{"first", "second", "third"}, result)
-}
-
-func TestCanSupportSliceOfStructsWithFunctions(t *testing.T) {
-	assert := assert.New(t)
-	data := []scalars{scalars{"a1", "b1"}, scalars{"a2", "b2"}}
-	result, err := Search("length(@)", data)
-	assert.Nil(err)
-	assert.Equal(result.(float64), 2.0)
-}
-
 func BenchmarkInterpretSingleFieldStruct(b *testing.B) {
 	assert := assert.New(b)
 	intr := newInterpreter()
@@ -232,3 +112,252 @@ func BenchmarkInterpretNestedMaps(b *testing.B) {
 		}
 	}
 }
+
+func TestCanSupportMapOfStrings(t *testing.T) {
+	assert := assert.New(t)
+	data := map[string]interface{}{"labels": map[string]string{"app": "web", "tier": "frontend"}}
+	result, err := Search("labels.app", data)
+	assert.Nil(err)
+	assert.Equal("web", result)
+	result, err = Search("labels.missing", data)
+	assert.Nil(err)
+	assert.Nil(result)
+	result, err = Search("length(labels)", data)
+	assert.Nil(err)
+	assert.Equal(2.0, result)
+	result, err = Search("sort(labels.*)", data)
+	assert.Nil(err)
+	assert.Equal([]interface{}{"frontend", "web"}, result)
+}
+
+type namedLabels map[string]string
+
+type namedObject map[string]interface{}
+
+type namedItems []namedObject
+
+func TestValueProjectionVisitsEachValueOnce(t *testing.T) {
+	assert := assert.New(t)
+	result, err := Search("*.not_null(@, 'missing')", map[string]interface{}{"a": 1.0})
+	assert.Nil(err)
+	assert.Equal([]interface{}{1.0}, result)
+}
+
+func TestStringSlices(t *testing.T) {
+	assert := assert.New(t)
+	data := map[string]interface{}{
+		"id":    "ord-12345",
+		"word":  "café",
+		"names": []interface{}{"alpha", "beta"},
+		"n":     1.0,
+	}
+	traced := NewRuntime()
+	traced.SetTracer(&recordingTracer{})
+	strict := NewRuntime()
+	strict.SetStrict(true)
+	cases := []struct {
+		expression string
+		expected   interface{}
+	}{
+		{"id[0:3]", "ord"},
+		{"id[4:]", "12345"},
+		{"id[-2:]", "45"},
+		{"id[::2]", "od135"},
+		{"id[::-1]", "54321-dro"},
+		{"id[10:]", ""},
+		{"word[:4]", "café"},
+		{"word[3:]", "é"},
+		{"id[0:3] == 'ord'", true},
+		{"length(id[4:])", 5.0},
+		{"id[0:3].length(@)", 3.0},
+		{"names[*][0:2]", []interface{}{"al", "be"}},
+		{"names[0:1]", []interface{}{"alpha"}},
+		{"names[0:1][0:2]", []interface{}{"al"}},
+		{"n[0:1]", nil},
+		{"'abc'[:2]", "ab"},
+		{"`\"abc\"`[::-1]", "cba"},
+		{"length('x'[1:])", 0.0},
+		{"'abc' | [1:]", "bc"},
+	}
+	for _, tt := range cases {
+		result, err := Search(tt.expression, data)
+		assert.Nil(err, tt.expression)
+		assert.Equal(tt.expected, result, tt.expression)
+		jp, err := Compile(tt.expression)
+		assert.Nil(err, tt.expression)
+		result, err = jp.Search(data)
+		assert.Nil(err, tt.expression)
+		assert.Equal(tt.expected, result, tt.expression)
+		result, err = traced.Search(tt.expression, data)
+		assert.Nil(err, tt.expression)
+		assert.Equal(tt.expected, result, tt.expression)
+		if tt.expected != nil {
+			result, err = strict.Search(tt.expression, data)
+			assert.Nil(err, tt.expression)
+			assert.Equal(tt.expected, result, tt.expression)
+		}
+	}
+}
+
+func TestTypedSliceSlices(t *testing.T) {
+	assert := assert.New(t)
+	data := map[string]interface{}{
+		"tags":  []string{"a", "b", "c"},
+		"sizes": []int{1, 2, 3, 4},
+	}
+	cases := []struct {
+		expression string
+		expected   interface{}
+	}{
+		{"tags[1:]", []interface{}{"b", "c"}},
+		{"tags[::-1]", []interface{}{"c", "b", "a"}},
+		{"sizes[::2]", []interface{}{1, 3}},
+		{"sizes[1:3] | [0]", 2},
+		{"tags[1:] | [0] | [0:1]", "b"},
+	}
+	for _, tt := range cases {
+		result, err := Search(tt.expression, data)
+		assert.Nil(err, tt.expression)
+		assert.Equal(tt.expected, result, tt.expression)
+	}
+	rt := NewRuntime()
+	rt.SetStrict(true)
+	rt.SetStrictBounds(true)
+	_, err := rt.Search("tags[5:]", data)
+	assert.True(errors.Is(err, ErrOutOfRange))
+}
+
+func TestNotComposesWithAnyExpression(t *testing.T) {
+	assert := assert.New(t)
+	data := map[string]interface{}{
+		"a":     map[string]interface{}{"b": true},
+		"empty": []interface{}{},
+		"items": []interface{}{map[string]interface{}{"x": 1.0}, map[string]interface{}{"x": nil}},
+		"zero":  0.0,
+	}
+	tests := []struct {
+		expression string
+		expected   interface{}
+	}{
+		{"!(a.b)", false},
+		{"!length(empty)", false},
+		{"!contains(keys(a), 'b')", false},
+		{"!not_null(empty)", true},
+		{"!(a.b == `false`)", true},
+		{"!a.b == `false`", false},
+		{"!zero", false},
+		{"!{x: empty}", false},
+		{"![empty]", false},
+		{"!`[]`", true},
+		{"!''", true},
+		{"!@", false},
+		{"!!empty", false},
+		{"!(empty || zero)", false},
+		{"!(empty && zero)", true},
+		{"!(items | [1].x)", true},
+		{"!items[?x]", nil},
+		{"!(items[?x])", false},
+		{"items[?!x]", []interface{}{map[string]interface{}{"x": nil}}},
+		{"items[?!(x == `1`)] | length(@)", 1.0},
+		{"not_null(!a)", false},
+	}
+	for _, test := range tests {
+		result, err := Search(test.expression, data)
+		assert.Nil(err, test.expression)
+		assert.Equal(test.expected, result, test.expression)
+		jp := MustCompile(test.expression)
+		result, err = jp.Search(data)
+		assert.Nil(err, test.expression)
+		assert.Equal(test.expected, result, test.expression)
+		matched, err := jp.Match(data)
+		assert.Nil(err, test.expression)
+		assert.Equal(Truthy(test.expected), matched, test.expression)
+	}
+}
+
+func TestUnusedOperandsAreNotEvaluated(t *testing.T) {
+	assert := assert.New(t)
+	rt := NewRuntime()
+	assert.Nil(rt.RegisterFunction("heavy", func(ctx CallContext, args []interface{}) (interface{}, error) {
+		return nil, errors.New("heavy was evaluated")
+	}))
+	data := map[string]interface{}{
+		"guard": false,
+		"ok":    true,
+		"items": []interface{}{map[string]interface{}{"guard": false, "ok": true}},
+	}
+	expressions := []string{
+		"guard && heavy(@)",
+		"ok || heavy(@)",
+		"guard && items[*].heavy(@)",
+		"if(guard, heavy(@), 'no')",
+		"if(ok, 'yes', heavy(@))",
+		"if(guard, heavy(@))",
+		"items[?guard && heavy(@)]",
+		"items[?ok || heavy(@)]",
+		"{a: guard && heavy(@), b: [ok || heavy(@)]}",
+		"!(guard && sort_by(items, &heavy(@)))",
+		"first_of(&ok, &heavy(@))",
+		"first_of(missing, ok, heavy(@))",
+	}
+	for _, expression := range expressions {
+		jp, err := rt.Compile(expression)
+		assert.Nil(err, expression)
+		_, err = rt.Search(expression, data)
+		assert.Nil(err, expression)
+		_, err = jp.Match(data)
+		assert.Nil(err, expression)
+		_, err = jp.ExplainNull(data)
+		assert.Nil(err, expression)
+		_, err = NewCoverage(jp).Search(data)
+		assert.Nil(err, expression)
+		_, err = jp.SearchPaths(data)
+		assert.Nil(err, expression)
+	}
+	_, err := rt.Search("ok && heavy(@)", data)
+	assert.EqualError(err, "heavy(@) at offset 6: heavy was evaluated")
+}
+
+func TestKeepNulls(t *testing.T) {
+	assert := assert.New(t)
+	data := decodeJSON(`{"items": [{"n": 1}, {"x": 2}, {"n": 3}], "nested": [[1, null], 2, null], "obj": {"a": {"n": 1}, "b": {}}}`)
+	cases := []struct {
+		expression string
+		dropped    interface{}
+		kept       interface{}
+	}{
+		{"items[*].n", []interface{}{1.0, 3.0}, []interface{}{1.0, nil, 3.0}},
+		{"items[].n", []interface{}{1.0, 3.0}, []interface{}{1.0, nil, 3.0}},
+		{"items[?n != `3`].n", []interface{}{1.0}, []interface{}{1.0, nil}},
+		{"nested[]", []interface{}{1.0, 2.0}, []interface{}{1.0, nil, 2.0, nil}},
+		{"nested[][]", []interface{}{1.0, 2.0}, []interface{}{1.0, nil, 2.0, nil}},
+		{"`[1, null]`[*]", []interface{}{1.0}, []interface{}{1.0, nil}},
+		{"length(items[*].n)", 2.0, 3.0},
+	}
+	rt := NewRuntime()
+	rt.SetKeepNulls(true)
+	for _, tt := range cases {
+		result, err := Search(tt.expression, data)
+		assert.Nil(err, tt.expression)
+		assert.Equal(tt.dropped, result, tt.expression)
+		jp, err := rt.Compile(tt.expression)
+		assert.Nil(err, tt.expression)
+		result, err = jp.Search(data)
+		assert.Nil(err, tt.expression)
+		assert.Equal(tt.kept, result, tt.expression)
+		walked, err := NewCoverage(jp).Search(data)
+		assert.Nil(err, tt.expression)
+		assert.Equal(tt.kept, walked, tt.expression)
+	}
+	result, err := rt.Search("obj.*.n", data)
+	assert.Nil(err)
+	assert.ElementsMatch([]interface{}{1.0, nil}, result)
+	result, err = SearchWith("items[*].n", data, WithKeepNulls(true))
+	assert.Nil(err)
+	assert.Equal([]interface{}{1.0, nil, 3.0}, result)
+	jp, err := rt.Compile("items[*].n")
+	assert.Nil(err)
+	streamed, err := collectChan(jp.SearchChan(context.Background(), data))
+	assert.Nil(err)
+	assert.Equal([]interface{}{1.0, nil, 3.0}, streamed)
+}
@@ -0,0 +1,146 @@
+/*
+Package jpexpvar exports the metrics of JMESPath runtimes as expvar
+variables, which are served as JSON on /debug/vars:
+
+	metrics := jpexpvar.Publish("jmespath", false)
+	rt := jmespath.NewRuntime()
+	rt.SetMetrics(metrics)
+
+The variable is an object with the counters
+
+	compilations, compile_errors, compile_seconds, nodes,
+	evaluations, evaluation_errors, evaluation_seconds
+
+where nodes is the total number of AST nodes of the compiled expressions,
+and evaluation_histogram, the number of evaluations that took at most each
+of the durations of Buckets, keyed by the duration, or any time for
+"+Inf".  Like Prometheus histograms, the buckets are cumulative.  With
+per-expression metrics, expressions holds an object for each expression
+evaluated, keyed by the expression, with its evaluations,
+evaluation_errors and evaluation_seconds.  Runtimes that evaluate
+expressions from untrusted sources should not use per-expression metrics,
+as there is one object for every distinct expression.
+
+This package is separate from the jmespath package because importing expvar
+registers a handler with http.DefaultServeMux.
+*/
+package jpexpvar
+
+import (
+	"expvar"
+	"sync"
+	"time"
+)
+
+// Buckets are the upper bounds of the buckets of evaluation_histogram.
+var Buckets = []time.Duration{
+	10 * time.Microsecond,
+	100 * time.Microsecond,
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+}
+
+// Metrics is a jmespath.Metrics that accumulates measurements in an
+// expvar.Map.  It is safe for concurrent use, and can be shared by several
+// runtimes.
+type Metrics struct {
+	vars          *expvar.Map
+	histogram     *expvar.Map
+	perExpression bool
+	// mu serializes the creation of the maps of expressions.
+	mu          sync.Mutex
+	expressions *expvar.Map
+}
+
+// New returns Metrics that are not published, which can be published with
+// expvar.Publish(name, metrics.Map()) or read with Map.  With
+// perExpression set, evaluations are also counted per expression.
+func New(perExpression bool) *Metrics {
+	m := &Metrics{
+		vars:          new(expvar.Map).Init(),
+		histogram:     new(expvar.Map).Init(),
+		perExpression: perExpression,
+	}
+	for _, name := range []string{"compilations", "compile_errors", "nodes", "evaluations", "evaluation_errors"} {
+		m.vars.Set(name, new(expvar.Int))
+	}
+	for _, name := range []string{"compile_seconds", "evaluation_seconds"} {
+		m.vars.Set(name, new(expvar.Float))
+	}
+	for _, bound := range Buckets {
+		m.histogram.Set(bound.String(), new(expvar.Int))
+	}
+	m.histogram.Set("+Inf", new(expvar.Int))
+	m.vars.Set("evaluation_histogram", m.histogram)
+	if perExpression {
+		m.expressions = new(expvar.Map).Init()
+		m.vars.Set("expressions", m.expressions)
+	}
+	return m
+}
+
+// Publish returns new Metrics published as the expvar variable name.  Like
+// expvar.Publish, it panics if the name is already in use.
+func Publish(name string, perExpression bool) *Metrics {
+	m := New(perExpression)
+	expvar.Publish(name, m.vars)
+	return m
+}
+
+// Map returns the map holding the metrics.
+func (m *Metrics) Map() *expvar.Map {
+	return m.vars
+}
+
+// Compiled implements jmespath.Metrics.
+func (m *Metrics) Compiled(expression string, nodes int, duration time.Duration, err error) {
+	m.vars.Add("compilations", 1)
+	if err != nil {
+		m.vars.Add("compile_errors", 1)
+	}
+	m.vars.Add("nodes", int64(nodes))
+	m.vars.AddFloat("compile_seconds", duration.Seconds())
+}
+
+// Evaluated implements jmespath.Metrics.
+func (m *Metrics) Evaluated(expression string, duration time.Duration, err error) {
+	m.count(m.vars, duration, err)
+	for _, bound := range Buckets {
+		if duration <= bound {
+			m.histogram.Add(bound.String(), 1)
+		}
+	}
+	m.histogram.Add("+Inf", 1)
+	if m.perExpression {
+		m.count(m.expression(expression), duration, err)
+	}
+}
+
+// count counts an evaluation in vars.
+func (m *Metrics) count(vars *expvar.Map, duration time.Duration, err error) {
+	vars.Add("evaluations", 1)
+	if err != nil {
+		vars.Add("evaluation_errors", 1)
+	}
+	vars.AddFloat("evaluation_seconds", duration.Seconds())
+}
+
+// expression returns the map of the metrics of expression.
+func (m *Metrics) expression(expression string) *expvar.Map {
+	if vars, ok := m.expressions.Get(expression).(*expvar.Map); ok {
+		return vars
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if vars, ok := m.expressions.Get(expression).(*expvar.Map); ok {
+		return vars
+	}
+	vars := new(expvar.Map).Init()
+	vars.Set("evaluations", new(expvar.Int))
+	vars.Set("evaluation_errors", new(expvar.Int))
+	vars.Set("evaluation_seconds", new(expvar.Float))
+	m.expressions.Set(expression, vars)
+	return vars
+}
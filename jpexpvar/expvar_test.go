@@ -0,0 +1,57 @@
+package jpexpvar
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"testing"
+	"time"
+
+	"github.com/jmespath/go-jmespath"
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func decode(t *testing.T, v expvar.Var) map[string]interface{} {
+	var decoded map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(v.String()), &decoded))
+	return decoded
+}
+
+func TestMetrics(t *testing.T) {
+	assert := assert.New(t)
+	m := New(false)
+	m.Compiled("foo.bar", 3, time.Millisecond, nil)
+	m.Compiled("foo[", 0, time.Millisecond, errors.New("syntax"))
+	m.Evaluated("foo.bar", 50*time.Microsecond, nil)
+	m.Evaluated("foo.bar", 2*time.Second, errors.New("failed"))
+
+	vars := decode(t, m.Map())
+	assert.Equal(2.0, vars["compilations"])
+	assert.Equal(1.0, vars["compile_errors"])
+	assert.Equal(3.0, vars["nodes"])
+	assert.InDelta(0.002, vars["compile_seconds"], 1e-9)
+	assert.Equal(2.0, vars["evaluations"])
+	assert.Equal(1.0, vars["evaluation_errors"])
+	assert.InDelta(2.00005, vars["evaluation_seconds"], 1e-9)
+	assert.Equal(map[string]interface{}{
+		"10µs": 0.0, "100µs": 1.0, "1ms": 1.0, "10ms": 1.0, "100ms": 1.0, "1s": 1.0, "+Inf": 2.0,
+	}, vars["evaluation_histogram"])
+	assert.Nil(vars["expressions"])
+}
+
+func TestPerExpression(t *testing.T) {
+	assert := assert.New(t)
+	m := Publish("jpexpvar_test", true)
+	assert.Equal(m.Map(), expvar.Get("jpexpvar_test"))
+	rt := jmespath.NewRuntime()
+	rt.SetMetrics(m)
+	for _, expression := range []string{"a", "a", "abs(a)"} {
+		rt.Search(expression, map[string]interface{}{"a": "x"})
+	}
+	expressions := decode(t, m.Map())["expressions"].(map[string]interface{})
+	assert.Len(expressions, 2)
+	a := expressions["a"].(map[string]interface{})
+	assert.Equal(2.0, a["evaluations"])
+	assert.Equal(0.0, a["evaluation_errors"])
+	assert.Equal(1.0, expressions["abs(a)"].(map[string]interface{})["evaluation_errors"])
+}
@@ -0,0 +1,83 @@
+/*
+Package digest is a pack of hashing and checksum functions for JMESPath
+expressions, loaded into a runtime with Register:
+
+	rt := jmespath.NewRuntime()
+	if err := digest.Register(rt); err != nil {
+		...
+	}
+	result, err := rt.Search("events[*].{shard: crc32(user_id), key: sha256(user_id)}", data)
+
+The functions are:
+
+	md5(string) -> string
+	sha1(string) -> string
+	sha256(string) -> string
+	crc32(string) -> number
+
+md5, sha1 and sha256 return the digest of the UTF-8 bytes of the string in
+lowercase hexadecimal.  crc32 returns the IEEE CRC-32 checksum of the bytes
+as a number between 0 and 4294967295.  The results are stable across
+processes and platforms, so that they can be used as bucketing or sharding
+keys.
+*/
+package digest
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash/crc32"
+	"sort"
+
+	"github.com/jmespath/go-jmespath"
+)
+
+// functions are the functions of the pack by name.
+var functions = map[string]func(s string) interface{}{
+	"md5": func(s string) interface{} {
+		sum := md5.Sum([]byte(s))
+		return hex.EncodeToString(sum[:])
+	},
+	"sha1": func(s string) interface{} {
+		sum := sha1.Sum([]byte(s))
+		return hex.EncodeToString(sum[:])
+	},
+	"sha256": func(s string) interface{} {
+		sum := sha256.Sum256([]byte(s))
+		return hex.EncodeToString(sum[:])
+	},
+	"crc32": func(s string) interface{} {
+		return float64(crc32.ChecksumIEEE([]byte(s)))
+	},
+}
+
+// Register makes the functions of the pack callable from expressions
+// evaluated by rt.  It returns an error if rt already has a function with
+// the name of one of them.
+func Register(rt *jmespath.Runtime) error {
+	names := make([]string, 0, len(functions))
+	for name := range functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fn := functions[name]
+		err := rt.RegisterFunction(name, func(ctx jmespath.CallContext, arguments []interface{}) (interface{}, error) {
+			if len(arguments) != 1 {
+				return nil, errors.New("incorrect number of args")
+			}
+			s, ok := arguments[0].(string)
+			if !ok {
+				return nil, errors.New("invalid type for argument 1: expected string")
+			}
+			return fn(s), nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
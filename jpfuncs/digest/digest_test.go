@@ -0,0 +1,42 @@
+package digest
+
+import (
+	"testing"
+
+	"github.com/jmespath/go-jmespath"
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func TestFunctions(t *testing.T) {
+	assert := assert.New(t)
+	rt := jmespath.NewRuntime()
+	assert.Nil(Register(rt))
+	data := map[string]interface{}{"user_id": "hello"}
+	tests := []struct {
+		expression string
+		expected   interface{}
+	}{
+		{"md5(user_id)", "5d41402abc4b2a76b9719d911017c592"},
+		{"sha1(user_id)", "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d"},
+		{"sha256(user_id)", "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"},
+		{"crc32(user_id)", 907060870.0},
+		{"md5('')", "d41d8cd98f00b204e9800998ecf8427e"},
+		{"crc32('')", 0.0},
+	}
+	for _, test := range tests {
+		result, err := rt.Search(test.expression, data)
+		assert.Nil(err, test.expression)
+		assert.Equal(test.expected, result, test.expression)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	assert := assert.New(t)
+	rt := jmespath.NewRuntime()
+	assert.Nil(Register(rt))
+	for _, expression := range []string{"md5(`1`)", "sha1(null)", "sha256()", "crc32('a', 'b')"} {
+		_, err := rt.Search(expression, nil)
+		assert.NotNil(err, expression)
+	}
+	assert.NotNil(Register(rt))
+}
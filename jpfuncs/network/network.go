@@ -0,0 +1,148 @@
+/*
+Package network is a pack of functions on IP addresses and URLs for
+JMESPath expressions, loaded into a runtime with Register:
+
+	rt := jmespath.NewRuntime()
+	if err := network.Register(rt); err != nil {
+		...
+	}
+	result, err := rt.Search("events[?cidr_contains('10.0.0.0/8', source_ip)]", data)
+
+The functions are:
+
+	cidr_contains(string, string) -> boolean
+	ip_version(string) -> number
+	parse_url(string) -> object
+
+cidr_contains reports whether the IP address of its second argument is in
+the network of its first, in CIDR notation such as "10.0.0.0/8" or
+"2001:db8::/32".  An invalid network is an error, but an invalid address,
+as found in untrusted logs, is only not contained.  ip_version returns 4
+or 6 for an IPv4 or IPv6 address, and null for any other string.
+
+parse_url returns the components of a URL as an object:
+
+	{"scheme": "https", "user": "alice", "host": "example.com", "port": 8443,
+	 "path": "/a b", "query": {"q": "x"}, "fragment": "top"}
+
+Components that are absent are null, except for the path, which is an
+empty string, and the query, which is an empty object.  The path, query
+and fragment are unescaped.  The query maps each parameter to its first
+value.  parse_url returns null for a string that is not a URL.
+*/
+package network
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jmespath/go-jmespath"
+)
+
+// function is a function of the pack, and the number of its arguments,
+// which are all strings.
+type function struct {
+	args int
+	fn   func(arguments []string) (interface{}, error)
+}
+
+// functions are the functions of the pack by name.
+var functions = map[string]function{
+	"cidr_contains": {2, cidrContains},
+	"ip_version":    {1, ipVersion},
+	"parse_url":     {1, parseURL},
+}
+
+// Register makes the functions of the pack callable from expressions
+// evaluated by rt.  It returns an error if rt already has a function with
+// the name of one of them.
+func Register(rt *jmespath.Runtime) error {
+	names := make([]string, 0, len(functions))
+	for name := range functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		f := functions[name]
+		err := rt.RegisterFunction(name, func(ctx jmespath.CallContext, arguments []interface{}) (interface{}, error) {
+			if len(arguments) != f.args {
+				return nil, errors.New("incorrect number of args")
+			}
+			strs := make([]string, len(arguments))
+			for i, arg := range arguments {
+				s, ok := arg.(string)
+				if !ok {
+					return nil, errors.New("invalid type for argument " + strconv.Itoa(i+1) + ": expected string")
+				}
+				strs[i] = s
+			}
+			return f.fn(strs)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func cidrContains(arguments []string) (interface{}, error) {
+	_, network, err := net.ParseCIDR(arguments[0])
+	if err != nil {
+		return nil, errors.New("invalid CIDR network: " + arguments[0])
+	}
+	ip := net.ParseIP(arguments[1])
+	return ip != nil && network.Contains(ip), nil
+}
+
+func ipVersion(arguments []string) (interface{}, error) {
+	if net.ParseIP(arguments[0]) == nil {
+		return nil, nil
+	}
+	// IPv4-mapped IPv6 addresses, such as "::ffff:10.0.0.1", are written
+	// as IPv6 addresses.
+	if strings.Contains(arguments[0], ":") {
+		return 6.0, nil
+	}
+	return 4.0, nil
+}
+
+func parseURL(arguments []string) (interface{}, error) {
+	u, err := url.Parse(arguments[0])
+	if err != nil {
+		return nil, nil
+	}
+	result := map[string]interface{}{
+		"scheme":   optional(u.Scheme),
+		"user":     nil,
+		"host":     optional(u.Hostname()),
+		"port":     nil,
+		"path":     u.Path,
+		"query":    map[string]interface{}{},
+		"fragment": optional(u.Fragment),
+	}
+	if u.User != nil {
+		result["user"] = u.User.Username()
+	}
+	if port, err := strconv.Atoi(u.Port()); err == nil {
+		result["port"] = float64(port)
+	}
+	// The parameters before a malformed one are kept.
+	values, _ := url.ParseQuery(u.RawQuery)
+	query := result["query"].(map[string]interface{})
+	for name, v := range values {
+		query[name] = v[0]
+	}
+	return result, nil
+}
+
+// optional returns s, or nil if it is empty.
+func optional(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,242 @@
+/*
+Package stats is a pack of statistical functions for JMESPath expressions,
+loaded into a runtime with Register:
+
+	rt := jmespath.NewRuntime()
+	if err := stats.Register(rt); err != nil {
+		...
+	}
+	result, err := rt.Search("percentile(requests[*].latency, `99`)", data)
+
+The functions are:
+
+	mean(array[number]) -> number
+	median(array[number]) -> number
+	variance(array[number]) -> number
+	stddev(array[number]) -> number
+	percentile(array[number], number) -> number
+	mode(array[number]|array[string]) -> number|string
+
+Each of them returns null for an empty array.  variance and stddev are
+those of the population, not of a sample.  percentile takes a percentage
+between 0 and 100 and interpolates linearly between the closest ranks, so
+that the 50th percentile is the median.  mode returns the most frequent
+element, the first of them to occur in the array if several are as
+frequent.
+
+The elements of arrays may be of any Go numeric type, as with the built-in
+functions, and are computed with as float64 values.
+*/
+package stats
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+
+	"github.com/jmespath/go-jmespath"
+)
+
+// functions are the functions of the pack by name.
+var functions = map[string]func(arguments []interface{}) (interface{}, error){
+	"mean":       mean,
+	"median":     median,
+	"variance":   variance,
+	"stddev":     stddev,
+	"percentile": percentile,
+	"mode":       mode,
+}
+
+// Register makes the functions of the pack callable from expressions
+// evaluated by rt.  It returns an error if rt already has a function with
+// the name of one of them.
+func Register(rt *jmespath.Runtime) error {
+	names := make([]string, 0, len(functions))
+	for name := range functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fn := functions[name]
+		err := rt.RegisterFunction(name, func(ctx jmespath.CallContext, arguments []interface{}) (interface{}, error) {
+			return fn(arguments)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func mean(arguments []interface{}) (interface{}, error) {
+	values, err := numbers(arguments, 1)
+	if err != nil || len(values) == 0 {
+		return nil, err
+	}
+	return meanOf(values), nil
+}
+
+func median(arguments []interface{}) (interface{}, error) {
+	values, err := numbers(arguments, 1)
+	if err != nil || len(values) == 0 {
+		return nil, err
+	}
+	sort.Float64s(values)
+	return rank(values, 0.5), nil
+}
+
+func variance(arguments []interface{}) (interface{}, error) {
+	values, err := numbers(arguments, 1)
+	if err != nil || len(values) == 0 {
+		return nil, err
+	}
+	return varianceOf(values), nil
+}
+
+func stddev(arguments []interface{}) (interface{}, error) {
+	values, err := numbers(arguments, 1)
+	if err != nil || len(values) == 0 {
+		return nil, err
+	}
+	return math.Sqrt(varianceOf(values)), nil
+}
+
+func percentile(arguments []interface{}) (interface{}, error) {
+	values, err := numbers(arguments, 2)
+	if err != nil {
+		return nil, err
+	}
+	p, ok := toFloat(arguments[1])
+	if !ok {
+		return nil, errors.New("invalid type for argument 2: expected number")
+	}
+	if !(p >= 0 && p <= 100) {
+		return nil, fmt.Errorf("invalid percentile %v, must be between 0 and 100", p)
+	}
+	if len(values) == 0 {
+		return nil, nil
+	}
+	sort.Float64s(values)
+	return rank(values, p/100), nil
+}
+
+func mode(arguments []interface{}) (interface{}, error) {
+	if len(arguments) != 1 {
+		return nil, errors.New("incorrect number of args")
+	}
+	elements, ok := toSlice(arguments[0])
+	if !ok {
+		return nil, errors.New("invalid type for argument 1: expected array[number] or array[string]")
+	}
+	// Numbers are counted by their float64 value, so that 1 and 1.0 are
+	// the same element, and strings by themselves.
+	counts := make(map[interface{}]int)
+	keys := make([]interface{}, len(elements))
+	for i, element := range elements {
+		keys[i] = element
+		if f, ok := toFloat(element); ok {
+			keys[i] = f
+		} else if _, ok := element.(string); !ok {
+			return nil, errors.New("invalid type for argument 1: expected array[number] or array[string]")
+		}
+		counts[keys[i]]++
+	}
+	var result interface{}
+	best := 0
+	for i, key := range keys {
+		if counts[key] > best {
+			result, best = elements[i], counts[key]
+		}
+	}
+	return result, nil
+}
+
+// numbers checks that there are count arguments and returns the elements
+// of the first, an array of numbers.
+func numbers(arguments []interface{}, count int) ([]float64, error) {
+	if len(arguments) != count {
+		return nil, errors.New("incorrect number of args")
+	}
+	elements, ok := toSlice(arguments[0])
+	if !ok {
+		return nil, errors.New("invalid type for argument 1: expected array[number]")
+	}
+	values := make([]float64, len(elements))
+	for i, element := range elements {
+		if values[i], ok = toFloat(element); !ok {
+			return nil, errors.New("invalid type for argument 1: expected array[number]")
+		}
+	}
+	return values, nil
+}
+
+func meanOf(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+func varianceOf(values []float64) float64 {
+	m := meanOf(values)
+	sum := 0.0
+	for _, v := range values {
+		sum += (v - m) * (v - m)
+	}
+	return sum / float64(len(values))
+}
+
+// rank returns the value at fraction q, between 0 and 1, of sorted values,
+// interpolating linearly between the values around it.
+func rank(sorted []float64, q float64) float64 {
+	position := q * float64(len(sorted)-1)
+	lower := int(math.Floor(position))
+	if lower == len(sorted)-1 {
+		return sorted[lower]
+	}
+	return sorted[lower] + (position-float64(lower))*(sorted[lower+1]-sorted[lower])
+}
+
+// toSlice returns the elements of a JSON array or of a Go slice or array.
+func toSlice(value interface{}) ([]interface{}, bool) {
+	if elements, ok := value.([]interface{}); ok {
+		return elements, true
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, false
+	}
+	elements := make([]interface{}, rv.Len())
+	for i := range elements {
+		elements[i] = rv.Index(i).Interface()
+	}
+	return elements, true
+}
+
+// toFloat converts a value of any Go numeric type to a float64.
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case *big.Int:
+		if v == nil {
+			return 0, false
+		}
+		f, _ := new(big.Float).SetInt(v).Float64()
+		return f, true
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
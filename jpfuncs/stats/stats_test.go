@@ -0,0 +1,90 @@
+package stats
+
+import (
+	"testing"
+
+	"github.com/jmespath/go-jmespath"
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func newRuntime(t *testing.T) *jmespath.Runtime {
+	rt := jmespath.NewRuntime()
+	assert.Nil(t, Register(rt))
+	return rt
+}
+
+func TestFunctions(t *testing.T) {
+	assert := assert.New(t)
+	rt := newRuntime(t)
+	data := map[string]interface{}{
+		"latencies": []interface{}{2.0, 4.0, 4.0, 4.0, 5.0, 5.0, 7.0, 9.0},
+		"odd":       []interface{}{3.0, 1.0, 2.0},
+		"states":    []interface{}{"WA", "OR", "OR", "WA", "CA"},
+		"empty":     []interface{}{},
+	}
+	tests := []struct {
+		expression string
+		expected   interface{}
+	}{
+		{"mean(latencies)", 5.0},
+		{"median(latencies)", 4.5},
+		{"median(odd)", 2.0},
+		{"variance(latencies)", 4.0},
+		{"stddev(latencies)", 2.0},
+		{"percentile(latencies, `0`)", 2.0},
+		{"percentile(latencies, `50`)", 4.5},
+		{"percentile(latencies, `100`)", 9.0},
+		{"percentile(odd, `25`)", 1.5},
+		{"mode(latencies)", 4.0},
+		{"mode(states)", "WA"},
+		{"mean(empty)", nil},
+		{"median(empty)", nil},
+		{"stddev(empty)", nil},
+		{"percentile(empty, `90`)", nil},
+		{"mode(empty)", nil},
+	}
+	for _, test := range tests {
+		result, err := rt.Search(test.expression, data)
+		assert.Nil(err, test.expression)
+		assert.Equal(test.expected, result, test.expression)
+	}
+}
+
+func TestGoNumbers(t *testing.T) {
+	assert := assert.New(t)
+	rt := newRuntime(t)
+	result, err := rt.Search("mean(@)", []int{1, 2, 3, 6})
+	assert.Nil(err)
+	assert.Equal(3.0, result)
+	result, err = rt.Search("mode(@)", []interface{}{int64(1), 2.0, uint8(1)})
+	assert.Nil(err)
+	assert.Equal(1.0, result)
+	result, err = rt.Search("stddev(@)", []float32{1, 1})
+	assert.Nil(err)
+	assert.Equal(0.0, result)
+}
+
+func TestErrors(t *testing.T) {
+	assert := assert.New(t)
+	rt := newRuntime(t)
+	data := map[string]interface{}{"mixed": []interface{}{1.0, "a"}, "objects": []interface{}{map[string]interface{}{}}}
+	for _, expression := range []string{
+		"mean(mixed)", "median(`1`)", "variance()", "stddev(mixed, mixed)",
+		"percentile(mixed, `50`)", "percentile(`[1]`, 'a')", "percentile(`[1]`, `101`)",
+		"percentile(`[1]`, `-1`)", "mode(objects)", "mode(`true`)",
+	} {
+		_, err := rt.Search(expression, data)
+		assert.NotNil(err, expression)
+	}
+	_, err := rt.Search("percentile(`[1]`, `101`)", nil)
+	assert.Contains(err.Error(), "invalid percentile 101, must be between 0 and 100")
+}
+
+func TestRegisterConflict(t *testing.T) {
+	assert := assert.New(t)
+	rt := newRuntime(t)
+	assert.NotNil(Register(rt))
+	result, err := rt.Search("stddev(`[1, 3]`)", nil)
+	assert.Nil(err)
+	assert.Equal(1.0, result)
+}
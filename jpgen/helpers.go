@@ -0,0 +1,586 @@
+package jpgen
+
+// helpers is the source of the functions generated code calls, appended to
+// every generated file.  They implement the semantics of the jmespath
+// package for the values encoding/json decodes to.
+const helpers = `
+func jpField(value interface{}, key string) interface{} {
+	if object, ok := value.(map[string]interface{}); ok {
+		return object[key]
+	}
+	return nil
+}
+
+func jpIndex(value interface{}, index int) interface{} {
+	array, ok := value.([]interface{})
+	if !ok {
+		return nil
+	}
+	if index < 0 {
+		index += len(array)
+	}
+	if index < 0 || index >= len(array) {
+		return nil
+	}
+	return array[index]
+}
+
+// jpSlice slices an array, or the characters of a string, as the
+// JMESPath specification defines.
+func jpSlice(value interface{}, start, stop, step int, hasStart, hasStop bool) interface{} {
+	var length int
+	characters, isString := []rune(nil), false
+	switch v := value.(type) {
+	case []interface{}:
+		length = len(v)
+	case string:
+		characters, isString = []rune(v), true
+		length = len(characters)
+	default:
+		return nil
+	}
+	capBound := func(bound int) int {
+		if bound < 0 {
+			bound += length
+			if bound < 0 {
+				if step < 0 {
+					return -1
+				}
+				return 0
+			}
+		} else if bound >= length {
+			if step < 0 {
+				return length - 1
+			}
+			return length
+		}
+		return bound
+	}
+	if hasStart {
+		start = capBound(start)
+	} else if step < 0 {
+		start = length - 1
+	} else {
+		start = 0
+	}
+	if hasStop {
+		stop = capBound(stop)
+	} else if step < 0 {
+		stop = -1
+	} else {
+		stop = length
+	}
+	var indexes []int
+	for i := start; step > 0 && i < stop || step < 0 && i > stop; i += step {
+		indexes = append(indexes, i)
+	}
+	if isString {
+		sliced := make([]rune, len(indexes))
+		for i, index := range indexes {
+			sliced[i] = characters[index]
+		}
+		return string(sliced)
+	}
+	array := value.([]interface{})
+	sliced := make([]interface{}, len(indexes))
+	for i, index := range indexes {
+		sliced[i] = array[index]
+	}
+	return sliced
+}
+
+func jpFlatten(value interface{}) interface{} {
+	array, ok := value.([]interface{})
+	if !ok {
+		return nil
+	}
+	flattened := []interface{}{}
+	for _, element := range array {
+		if nested, ok := element.([]interface{}); ok {
+			flattened = append(flattened, nested...)
+		} else {
+			flattened = append(flattened, element)
+		}
+	}
+	return flattened
+}
+
+// jpValues returns the values of an object, in the order of their keys.
+func jpValues(object map[string]interface{}) []interface{} {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		values[i] = object[key]
+	}
+	return values
+}
+
+func jpIsFalse(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return !v
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	case string:
+		return len(v) == 0
+	case nil:
+		return true
+	}
+	return false
+}
+
+func jpNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+func jpEqual(left, right interface{}) bool {
+	if l, ok := jpNumber(left); ok {
+		r, ok := jpNumber(right)
+		return ok && l == r
+	}
+	switch l := left.(type) {
+	case []interface{}:
+		r, ok := right.([]interface{})
+		if !ok || len(l) != len(r) {
+			return false
+		}
+		for i := range l {
+			if !jpEqual(l[i], r[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		r, ok := right.(map[string]interface{})
+		if !ok || len(l) != len(r) {
+			return false
+		}
+		for key, value := range l {
+			other, found := r[key]
+			if !found || !jpEqual(value, other) {
+				return false
+			}
+		}
+		return true
+	case string, bool, nil:
+		return left == right
+	}
+	return false
+}
+
+func jpCompare(op string, left, right interface{}) interface{} {
+	switch op {
+	case "==":
+		return jpEqual(left, right)
+	case "!=":
+		return !jpEqual(left, right)
+	case "in", "not in":
+		array, ok := right.([]interface{})
+		if !ok {
+			return nil
+		}
+		found := false
+		for _, element := range array {
+			if jpEqual(left, element) {
+				found = true
+				break
+			}
+		}
+		return found == (op == "in")
+	}
+	var comparison int
+	ls, lok := left.(string)
+	rs, rok := right.(string)
+	if lok && rok {
+		comparison = strings.Compare(ls, rs)
+	} else {
+		l, ok := jpNumber(left)
+		if !ok {
+			return nil
+		}
+		r, ok := jpNumber(right)
+		if !ok {
+			return nil
+		}
+		if l != l || r != r {
+			return false
+		}
+		if l < r {
+			comparison = -1
+		} else if l > r {
+			comparison = 1
+		}
+	}
+	switch op {
+	case "<":
+		return comparison < 0
+	case "<=":
+		return comparison <= 0
+	case ">":
+		return comparison > 0
+	case ">=":
+		return comparison >= 0
+	}
+	return nil
+}
+
+func jpType(value interface{}) string {
+	if _, ok := jpNumber(value); ok {
+		return "number"
+	}
+	switch value.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	case nil:
+		return "null"
+	}
+	return "unknown"
+}
+
+// jpCheck returns an error for the argument at index i of the function
+// call if value is not of one of types, which are the names returned by
+// type() and "array[number]" and "array[string]".
+func jpCheck(call string, i int, value interface{}, types ...string) error {
+	actual := jpType(value)
+	for _, t := range types {
+		if t == actual {
+			return nil
+		}
+		if elementType := strings.TrimSuffix(strings.TrimPrefix(t, "array["), "]"); elementType != t && actual == "array" {
+			matches := true
+			for _, element := range value.([]interface{}) {
+				matches = matches && jpType(element) == elementType
+			}
+			if matches {
+				return nil
+			}
+		}
+	}
+	return errors.New(call + ": invalid type for argument " + strconv.Itoa(i+1) + ": expected " +
+		strings.Join(types, " or ") + ", got " + actual)
+}
+
+func jpNumbers(value interface{}) []float64 {
+	array := value.([]interface{})
+	numbers := make([]float64, len(array))
+	for i, element := range array {
+		numbers[i], _ = jpNumber(element)
+	}
+	return numbers
+}
+
+func jpfAbs(value interface{}) interface{} {
+	n, _ := jpNumber(value)
+	return math.Abs(n)
+}
+
+func jpfAvg(value interface{}) interface{} {
+	numbers := jpNumbers(value)
+	if len(numbers) == 0 {
+		return nil
+	}
+	sum := 0.0
+	for _, n := range numbers {
+		sum += n
+	}
+	return sum / float64(len(numbers))
+}
+
+func jpfCeil(value interface{}) interface{} {
+	n, _ := jpNumber(value)
+	return math.Ceil(n)
+}
+
+func jpfContains(subject, search interface{}) interface{} {
+	if s, ok := subject.(string); ok {
+		sub, ok := search.(string)
+		return ok && strings.Contains(s, sub)
+	}
+	for _, element := range subject.([]interface{}) {
+		if jpEqual(element, search) {
+			return true
+		}
+	}
+	return false
+}
+
+func jpfEndsWith(subject, suffix interface{}) interface{} {
+	return strings.HasSuffix(subject.(string), suffix.(string))
+}
+
+func jpfFloor(value interface{}) interface{} {
+	n, _ := jpNumber(value)
+	return math.Floor(n)
+}
+
+func jpfJoin(separator, value interface{}) interface{} {
+	array := value.([]interface{})
+	parts := make([]string, len(array))
+	for i, element := range array {
+		parts[i] = element.(string)
+	}
+	return strings.Join(parts, separator.(string))
+}
+
+func jpfKeys(value interface{}) interface{} {
+	object := value.(map[string]interface{})
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	result := make([]interface{}, len(keys))
+	for i, key := range keys {
+		result[i] = key
+	}
+	return result
+}
+
+func jpfLength(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return float64(utf8.RuneCountInString(v))
+	case []interface{}:
+		return float64(len(v))
+	case map[string]interface{}:
+		return float64(len(v))
+	}
+	return nil
+}
+
+// jpfExtreme returns the greatest element of a non-empty array of numbers
+// or strings if sign is 1, and the least if it is -1.
+func jpfExtreme(value interface{}, sign int) interface{} {
+	array := value.([]interface{})
+	if len(array) == 0 {
+		return nil
+	}
+	best := array[0]
+	for _, element := range array[1:] {
+		if jpOrder(element, best)*sign > 0 {
+			best = element
+		}
+	}
+	if n, ok := jpNumber(best); ok {
+		return n
+	}
+	return best
+}
+
+// jpOrder compares two numbers or two strings.
+func jpOrder(a, b interface{}) int {
+	if x, ok := jpNumber(a); ok {
+		y, _ := jpNumber(b)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(a.(string), b.(string))
+}
+
+func jpfMerge(objects ...interface{}) interface{} {
+	merged := map[string]interface{}{}
+	for _, object := range objects {
+		for key, value := range object.(map[string]interface{}) {
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
+func jpfNotNull(values ...interface{}) interface{} {
+	for _, value := range values {
+		if value != nil {
+			return value
+		}
+	}
+	return nil
+}
+
+func jpfReverse(value interface{}) interface{} {
+	if s, ok := value.(string); ok {
+		r := []rune(s)
+		for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+			r[i], r[j] = r[j], r[i]
+		}
+		return string(r)
+	}
+	array := value.([]interface{})
+	reversed := make([]interface{}, len(array))
+	for i, element := range array {
+		reversed[len(array)-1-i] = element
+	}
+	return reversed
+}
+
+func jpfSort(value interface{}) interface{} {
+	sorted := append([]interface{}{}, value.([]interface{})...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return jpOrder(sorted[i], sorted[j]) < 0
+	})
+	for i, element := range sorted {
+		if n, ok := jpNumber(element); ok {
+			sorted[i] = n
+		}
+	}
+	return sorted
+}
+
+func jpfStartsWith(subject, prefix interface{}) interface{} {
+	return strings.HasPrefix(subject.(string), prefix.(string))
+}
+
+func jpfSum(value interface{}) interface{} {
+	sum := 0.0
+	for _, n := range jpNumbers(value) {
+		sum += n
+	}
+	return sum
+}
+
+func jpfToArray(value interface{}) interface{} {
+	if _, ok := value.([]interface{}); ok {
+		return value
+	}
+	return []interface{}{value}
+}
+
+func jpfToNumber(value interface{}) interface{} {
+	if n, ok := jpNumber(value); ok {
+		return n
+	}
+	s, ok := value.(string)
+	if !ok {
+		return nil
+	}
+	n, err := strconv.ParseFloat(strings.Trim(s, " \t\n\r"), 64)
+	if err != nil || math.IsInf(n, 0) || math.IsNaN(n) {
+		return nil
+	}
+	return n
+}
+
+func jpfToString(value interface{}) (interface{}, error) {
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return string(encoded), nil
+}
+
+func jpfType(value interface{}) interface{} {
+	return jpType(value)
+}
+
+func jpfValues(value interface{}) interface{} {
+	return jpValues(value.(map[string]interface{}))
+}
+
+func jpfMap(fn func(interface{}) (interface{}, error), value interface{}) (interface{}, error) {
+	array := value.([]interface{})
+	mapped := make([]interface{}, len(array))
+	for i, element := range array {
+		result, err := fn(element)
+		if err != nil {
+			return nil, err
+		}
+		mapped[i] = result
+	}
+	return mapped, nil
+}
+
+// jpSortKeys evaluates the keys of the elements of array with fn, which
+// must all be numbers or all strings.
+func jpSortKeys(call string, value interface{}, fn func(interface{}) (interface{}, error)) ([]interface{}, error) {
+	array := value.([]interface{})
+	keys := make([]interface{}, len(array))
+	for i, element := range array {
+		key, err := fn(element)
+		if err != nil {
+			return nil, err
+		}
+		keyType := jpType(key)
+		expected := "number or string"
+		if i > 0 {
+			expected = jpType(keys[0])
+		}
+		if keyType != "number" && keyType != "string" || i > 0 && keyType != expected {
+			return nil, errors.New(call + ": invalid type for key of element " + strconv.Itoa(i) +
+				": expected " + expected + ", got " + keyType)
+		}
+		keys[i] = key
+	}
+	return keys, nil
+}
+
+func jpfSortBy(call string, value interface{}, fn func(interface{}) (interface{}, error)) (interface{}, error) {
+	keys, err := jpSortKeys(call, value, fn)
+	if err != nil {
+		return nil, err
+	}
+	indexes := make([]int, len(keys))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return jpOrder(keys[indexes[i]], keys[indexes[j]]) < 0
+	})
+	array := value.([]interface{})
+	sorted := make([]interface{}, len(array))
+	for i, index := range indexes {
+		sorted[i] = array[index]
+	}
+	return sorted, nil
+}
+
+func jpfExtremeBy(call string, value interface{}, fn func(interface{}) (interface{}, error), sign int) (interface{}, error) {
+	keys, err := jpSortKeys(call, value, fn)
+	if err != nil || len(keys) == 0 {
+		return nil, err
+	}
+	best := 0
+	for i := range keys[1:] {
+		if jpOrder(keys[i+1], keys[best])*sign > 0 {
+			best = i + 1
+		}
+	}
+	return value.([]interface{})[best], nil
+}
+`
@@ -0,0 +1,586 @@
+/*
+Package jpgen generates Go source code implementing JMESPath expressions,
+so that programs evaluating a fixed set of expressions, such as generated
+API clients, neither parse nor interpret them at run time:
+
+	source, err := jpgen.Generate("queries", []jpgen.Func{
+		{Name: "ActiveNames", Expression: "users[?active].name"},
+		{Name: "Total", Expression: "sum(orders[*].amount)"},
+	})
+
+generates a file of package queries with the functions
+
+	func ActiveNames(data interface{}) (interface{}, error)
+	func Total(data interface{}) (interface{}, error)
+
+which return what jmespath.Search returns for the expressions, for data
+made of the values encoding/json decodes to: map[string]interface{},
+[]interface{}, float64, string, bool and nil, where Go numbers of other
+types are accepted as well.  The generated code only imports the standard
+library.  The values of objects projected with "*", and the keys returned
+by keys(), are visited in sorted key order.
+
+The built-in functions of the JMESPath specification are supported, but
+not the extension functions of the jmespath package, their optional
+arguments, such as the collation of sort_by, or parameters and recursive
+descent.  Expressions using them are reported with an *UnsupportedError.
+Unlike the jmespath package, which reports calls with the wrong number of
+arguments when they are evaluated, Generate reports them as errors.
+
+All the expressions of a package must be generated into one file, as
+every generated file holds the helper functions the expressions call.
+The cmd/jpgen command generates a file from the command line, for use
+with go generate.
+*/
+package jpgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/token"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jmespath/go-jmespath"
+)
+
+// Func is a function to generate.
+type Func struct {
+	// Name is the name of the generated Go function.
+	Name string
+	// Expression is the JMESPath expression the function evaluates.
+	Expression string
+}
+
+// UnsupportedError is returned for valid expressions that use a feature
+// Generate does not support.
+type UnsupportedError struct {
+	Expression    string // The expression.
+	SubExpression string // The part of the expression using the feature.
+	Feature       string // Description of the feature.
+}
+
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("cannot generate Go for %q: %s in %s is not supported", e.Expression, e.Feature, e.SubExpression)
+}
+
+// Generate returns the formatted source of a Go file of package pkg with
+// a function for each of funcs.
+func Generate(pkg string, funcs []Func) ([]byte, error) {
+	if !token.IsIdentifier(pkg) {
+		return nil, fmt.Errorf("invalid package name %q", pkg)
+	}
+	var b bytes.Buffer
+	b.WriteString("// Code generated by jpgen. DO NOT EDIT.\n\n")
+	b.WriteString("package " + pkg + "\n\n")
+	b.WriteString("import (\n\t\"encoding/json\"\n\t\"errors\"\n\t\"math\"\n\t\"sort\"\n\t\"strconv\"\n\t\"strings\"\n\t\"unicode/utf8\"\n)\n")
+	names := make(map[string]bool)
+	for _, fn := range funcs {
+		if !token.IsIdentifier(fn.Name) || strings.HasPrefix(fn.Name, "jp") {
+			return nil, fmt.Errorf("invalid function name %q", fn.Name)
+		}
+		if names[fn.Name] {
+			return nil, fmt.Errorf("duplicate function name %q", fn.Name)
+		}
+		names[fn.Name] = true
+		body, err := generateFunc(fn)
+		if err != nil {
+			return nil, err
+		}
+		b.WriteString("\n" + body)
+	}
+	b.WriteString(helpers)
+	source, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated invalid Go: %s", err)
+	}
+	return source, nil
+}
+
+// node is an AST node, decoded from the JSON encoding of jmespath.ASTNode.
+type node struct {
+	Type     string          `json:"type"`
+	Value    json.RawMessage `json:"value"`
+	Children []node          `json:"children"`
+	// raw is the encoding of the node, to format it in errors.
+	raw json.RawMessage
+}
+
+func (n *node) UnmarshalJSON(data []byte) error {
+	type decoded node
+	if err := json.Unmarshal(data, (*decoded)(n)); err != nil {
+		return err
+	}
+	n.raw = append(json.RawMessage(nil), data...)
+	return nil
+}
+
+// String returns the canonical form of the expression of the node.
+func (n node) String() string {
+	var ast jmespath.ASTNode
+	if err := json.Unmarshal(n.raw, &ast); err != nil {
+		return n.Type
+	}
+	return jmespath.FormatAST(ast)
+}
+
+func generateFunc(fn Func) (string, error) {
+	ast, err := jmespath.NewParser().Parse(fn.Expression)
+	if err != nil {
+		return "", err
+	}
+	encoded, err := json.Marshal(ast)
+	if err != nil {
+		return "", err
+	}
+	var root node
+	if err := json.Unmarshal(encoded, &root); err != nil {
+		return "", err
+	}
+	g := &generator{expression: fn.Expression}
+	result, err := g.eval(root, "data")
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("// %s evaluates the JMESPath expression %s against data.\nfunc %s(data interface{}) (interface{}, error) {\n%sreturn %s, nil\n}\n",
+		fn.Name, strconv.Quote(fn.Expression), fn.Name, g.b.String(), result), nil
+}
+
+// generator generates the statements evaluating an expression.  Every node
+// is evaluated into a variable of its own, and eval returns its name.
+type generator struct {
+	expression string
+	b          strings.Builder
+	vars       int
+}
+
+func (g *generator) newVar(prefix string) string {
+	g.vars++
+	return prefix + strconv.Itoa(g.vars)
+}
+
+func (g *generator) line(format string, args ...interface{}) {
+	fmt.Fprintf(&g.b, format+"\n", args...)
+}
+
+// used reports whether the statements generated since offset from refer
+// to the variable name.
+func (g *generator) used(name string, from int) bool {
+	return regexp.MustCompile(`\b` + name + `\b`).MatchString(g.b.String()[from:])
+}
+
+func (g *generator) unsupported(n node, feature string) error {
+	return &UnsupportedError{Expression: g.expression, SubExpression: n.String(), Feature: feature}
+}
+
+func (g *generator) eval(n node, in string) (string, error) {
+	switch n.Type {
+	case "Identity", "CurrentNode":
+		return in, nil
+	case "Field":
+		var key string
+		if err := json.Unmarshal(n.Value, &key); err != nil {
+			return "", err
+		}
+		v := g.newVar("v")
+		g.line("%s := jpField(%s, %s)", v, in, strconv.Quote(key))
+		return v, nil
+	case "Index":
+		var index int
+		if err := json.Unmarshal(n.Value, &index); err != nil {
+			return "", err
+		}
+		v := g.newVar("v")
+		g.line("%s := jpIndex(%s, %d)", v, in, index)
+		return v, nil
+	case "Slice":
+		var parts [3]*int
+		if err := json.Unmarshal(n.Value, &parts); err != nil {
+			return "", err
+		}
+		start, stop, step := 0, 0, 1
+		if parts[0] != nil {
+			start = *parts[0]
+		}
+		if parts[1] != nil {
+			stop = *parts[1]
+		}
+		if parts[2] != nil {
+			step = *parts[2]
+		}
+		if step == 0 {
+			return "", errors.New(n.String() + ": slice step cannot be 0")
+		}
+		v := g.newVar("v")
+		g.line("%s := jpSlice(%s, %d, %d, %d, %t, %t)", v, in, start, stop, step, parts[0] != nil, parts[1] != nil)
+		return v, nil
+	case "Literal":
+		var value interface{}
+		if err := json.Unmarshal(n.Value, &value); err != nil {
+			return "", err
+		}
+		v := g.newVar("v")
+		g.line("%s := interface{}(%s)", v, goLiteral(value))
+		return v, nil
+	case "Subexpression", "IndexExpression", "Pipe":
+		current := in
+		for _, child := range n.Children {
+			var err error
+			if current, err = g.eval(child, current); err != nil {
+				return "", err
+			}
+		}
+		return current, nil
+	case "Comparator":
+		var op string
+		if err := json.Unmarshal(n.Value, &op); err != nil {
+			return "", err
+		}
+		left, err := g.eval(n.Children[0], in)
+		if err != nil {
+			return "", err
+		}
+		right, err := g.eval(n.Children[1], in)
+		if err != nil {
+			return "", err
+		}
+		v := g.newVar("v")
+		g.line("%s := jpCompare(%s, %s, %s)", v, strconv.Quote(op), left, right)
+		return v, nil
+	case "OrExpression", "AndExpression":
+		left, err := g.eval(n.Children[0], in)
+		if err != nil {
+			return "", err
+		}
+		v := g.newVar("v")
+		g.line("%s := %s", v, left)
+		if n.Type == "OrExpression" {
+			g.line("if jpIsFalse(%s) {", v)
+		} else {
+			g.line("if !jpIsFalse(%s) {", v)
+		}
+		right, err := g.eval(n.Children[1], in)
+		if err != nil {
+			return "", err
+		}
+		g.line("%s = %s", v, right)
+		g.line("}")
+		return v, nil
+	case "NotExpression":
+		operand, err := g.eval(n.Children[0], in)
+		if err != nil {
+			return "", err
+		}
+		v := g.newVar("v")
+		g.line("%s := interface{}(jpIsFalse(%s))", v, operand)
+		return v, nil
+	case "MultiSelectList", "MultiSelectHash":
+		v := g.newVar("v")
+		g.line("var %s interface{}", v)
+		g.line("if %s != nil {", in)
+		elements := make([]string, len(n.Children))
+		for i, child := range n.Children {
+			element := child
+			if n.Type == "MultiSelectHash" {
+				var key string
+				if err := json.Unmarshal(child.Value, &key); err != nil {
+					return "", err
+				}
+				element = child.Children[0]
+				elements[i] = strconv.Quote(key) + ": "
+			}
+			result, err := g.eval(element, in)
+			if err != nil {
+				return "", err
+			}
+			elements[i] += result
+		}
+		if n.Type == "MultiSelectHash" {
+			g.line("%s = map[string]interface{}{%s}", v, strings.Join(elements, ", "))
+		} else {
+			g.line("%s = []interface{}{%s}", v, strings.Join(elements, ", "))
+		}
+		g.line("}")
+		return v, nil
+	case "Flatten":
+		current, err := g.eval(n.Children[0], in)
+		if err != nil {
+			return "", err
+		}
+		depth := 1
+		if len(n.Value) > 0 {
+			if err := json.Unmarshal(n.Value, &depth); err != nil {
+				return "", err
+			}
+		}
+		for i := 0; i < depth; i++ {
+			v := g.newVar("v")
+			g.line("%s := jpFlatten(%s)", v, current)
+			current = v
+		}
+		return current, nil
+	case "Projection", "FilterProjection", "ValueProjection":
+		return g.projection(n, in)
+	case "FunctionExpression":
+		return g.call(n, in)
+	case "ExpRef":
+		return "", g.unsupported(n, "an expression reference outside of a function argument")
+	case "Parameter":
+		return "", g.unsupported(n, "a parameter")
+	case "Descendant":
+		return "", g.unsupported(n, "recursive descent")
+	}
+	return "", g.unsupported(n, "a "+n.Type+" node")
+}
+
+// projection generates a projection, which collects the non-null results
+// of its right side for the elements of its left side.  The right side of
+// a slice of a string applies to the sliced string instead, as in the
+// jmespath package.
+func (g *generator) projection(n node, in string) (string, error) {
+	left, err := g.eval(n.Children[0], in)
+	if err != nil {
+		return "", err
+	}
+	v := g.newVar("v")
+	g.line("var %s interface{}", v)
+	if slicesString(n.Children[0]) {
+		s := g.newVar("s")
+		g.line("if %s, ok := %s.(string); ok {", s, left)
+		from := g.b.Len()
+		right, err := g.eval(n.Children[1], s)
+		if err != nil {
+			return "", err
+		}
+		if !g.used(s, from) {
+			g.line("_ = %s", s)
+		}
+		g.line("%s = %s", v, right)
+		g.b.WriteString("} else ")
+	}
+	elements, element, collected := g.newVar("a"), g.newVar("e"), g.newVar("c")
+	if n.Type == "ValueProjection" {
+		g.line("if %s, ok := %s.(map[string]interface{}); ok {", elements, left)
+		g.line("%s := []interface{}{}", collected)
+		g.line("for _, %s := range jpValues(%s) {", element, elements)
+	} else {
+		g.line("if %s, ok := %s.([]interface{}); ok {", elements, left)
+		g.line("%s := []interface{}{}", collected)
+		g.line("for _, %s := range %s {", element, elements)
+	}
+	from := g.b.Len()
+	if n.Type == "FilterProjection" {
+		condition, err := g.eval(n.Children[2], element)
+		if err != nil {
+			return "", err
+		}
+		g.line("if !jpIsFalse(%s) {", condition)
+	}
+	right, err := g.eval(n.Children[1], element)
+	if err != nil {
+		return "", err
+	}
+	if !g.used(element, from) {
+		g.line("_ = %s", element)
+	}
+	g.line("if %s != nil {", right)
+	g.line("%s = append(%s, %s)", collected, collected, right)
+	g.line("}")
+	if n.Type == "FilterProjection" {
+		g.line("}")
+	}
+	g.line("}")
+	g.line("%s = %s", v, collected)
+	g.line("}")
+	return v, nil
+}
+
+// slicesString reports whether the left side of a projection is a slice,
+// which may slice a string.
+func slicesString(left node) bool {
+	if left.Type == "IndexExpression" {
+		left = left.Children[len(left.Children)-1]
+	}
+	return left.Type == "Slice"
+}
+
+// function describes how a call to a built-in function is generated.
+type function struct {
+	// call returns the call of the helper implementing the function,
+	// given the Go expressions of the arguments and call, the quoted
+	// expression of the function call.
+	call func(call string, args []string) string
+	// fails is set for helpers that return an error as well.
+	fails bool
+	// maxArgs is the greatest number of arguments supported, or -1 for
+	// variadic functions.
+	maxArgs int
+}
+
+func helper(name string) func(call string, args []string) string {
+	return func(call string, args []string) string {
+		return name + "(" + strings.Join(args, ", ") + ")"
+	}
+}
+
+func helperWith(name string, extra ...string) func(call string, args []string) string {
+	return func(call string, args []string) string {
+		return name + "(" + strings.Join(append(args[:len(args):len(args)], extra...), ", ") + ")"
+	}
+}
+
+func helperOfCall(name string, extra ...string) func(call string, args []string) string {
+	return func(call string, args []string) string {
+		return name + "(" + strings.Join(append(append([]string{call}, args...), extra...), ", ") + ")"
+	}
+}
+
+var functions = map[string]function{
+	"abs":         {call: helper("jpfAbs"), maxArgs: 1},
+	"avg":         {call: helper("jpfAvg"), maxArgs: 1},
+	"ceil":        {call: helper("jpfCeil"), maxArgs: 1},
+	"contains":    {call: helper("jpfContains"), maxArgs: 2},
+	"ends_with":   {call: helper("jpfEndsWith"), maxArgs: 2},
+	"floor":       {call: helper("jpfFloor"), maxArgs: 1},
+	"join":        {call: helper("jpfJoin"), maxArgs: 2},
+	"keys":        {call: helper("jpfKeys"), maxArgs: 1},
+	"length":      {call: helper("jpfLength"), maxArgs: 1},
+	"map":         {call: helper("jpfMap"), fails: true, maxArgs: 2},
+	"max":         {call: helperWith("jpfExtreme", "1"), maxArgs: 1},
+	"max_by":      {call: helperOfCall("jpfExtremeBy", "1"), fails: true, maxArgs: 2},
+	"merge":       {call: helper("jpfMerge"), maxArgs: -1},
+	"min":         {call: helperWith("jpfExtreme", "-1"), maxArgs: 1},
+	"min_by":      {call: helperOfCall("jpfExtremeBy", "-1"), fails: true, maxArgs: 2},
+	"not_null":    {call: helper("jpfNotNull"), maxArgs: -1},
+	"reverse":     {call: helper("jpfReverse"), maxArgs: 1},
+	"sort":        {call: helper("jpfSort"), maxArgs: 1},
+	"sort_by":     {call: helperOfCall("jpfSortBy"), fails: true, maxArgs: 2},
+	"starts_with": {call: helper("jpfStartsWith"), maxArgs: 2},
+	"sum":         {call: helper("jpfSum"), maxArgs: 1},
+	"to_array":    {call: helper("jpfToArray"), maxArgs: 1},
+	"to_number":   {call: helper("jpfToNumber"), maxArgs: 1},
+	"to_string":   {call: helper("jpfToString"), fails: true, maxArgs: 1},
+	"type":        {call: helper("jpfType"), maxArgs: 1},
+	"values":      {call: helper("jpfValues"), maxArgs: 1},
+}
+
+var signatures = func() map[string]jmespath.FunctionSignature {
+	signatures := make(map[string]jmespath.FunctionSignature)
+	for _, signature := range jmespath.Functions() {
+		signatures[signature.Name] = signature
+	}
+	return signatures
+}()
+
+// call generates a function call.  The arguments are evaluated and then
+// type checked, as the jmespath package does, and expression references
+// become Go closures.
+func (g *generator) call(n node, in string) (string, error) {
+	var name string
+	if err := json.Unmarshal(n.Value, &name); err != nil {
+		return "", err
+	}
+	fn, ok := functions[name]
+	signature, known := signatures[name]
+	if !ok || !known {
+		return "", g.unsupported(n, "function "+name)
+	}
+	if len(n.Children) < signature.MinArgs() || signature.MaxArgs() >= 0 && len(n.Children) > signature.MaxArgs() {
+		return "", fmt.Errorf("%s: invalid number of arguments for %s", n, signature)
+	}
+	if fn.maxArgs >= 0 && len(n.Children) > fn.maxArgs {
+		return "", g.unsupported(n, "the optional arguments of "+name)
+	}
+	call := strconv.Quote(n.String())
+	args := make([]string, len(n.Children))
+	for i, child := range n.Children {
+		spec := signature.Arguments[len(signature.Arguments)-1]
+		if i < len(signature.Arguments) {
+			spec = signature.Arguments[i]
+		}
+		isRef := len(spec.Types) == 1 && spec.Types[0] == "expref"
+		if isRef != (child.Type == "ExpRef") {
+			return "", fmt.Errorf("%s: invalid type for argument %d: expected %s", n, i+1, strings.Join(spec.Types, " or "))
+		}
+		if isRef {
+			f, param := g.newVar("f"), g.newVar("e")
+			g.line("%s := func(%s interface{}) (interface{}, error) {", f, param)
+			result, err := g.eval(child.Children[0], param)
+			if err != nil {
+				return "", err
+			}
+			g.line("return %s, nil", result)
+			g.line("}")
+			args[i] = f
+			continue
+		}
+		arg, err := g.eval(child, in)
+		if err != nil {
+			return "", err
+		}
+		args[i] = arg
+	}
+	for i, child := range n.Children {
+		spec := signature.Arguments[len(signature.Arguments)-1]
+		if i < len(signature.Arguments) {
+			spec = signature.Arguments[i]
+		}
+		if child.Type == "ExpRef" || len(spec.Types) == 1 && spec.Types[0] == "any" {
+			continue
+		}
+		types := make([]string, len(spec.Types))
+		for j, t := range spec.Types {
+			types[j] = strconv.Quote(t)
+		}
+		g.line("if err := jpCheck(%s, %d, %s, %s); err != nil {", call, i, args[i], strings.Join(types, ", "))
+		g.line("return nil, err")
+		g.line("}")
+	}
+	v := g.newVar("v")
+	if fn.fails {
+		g.line("%s, err := %s", v, fn.call(call, args))
+		g.line("if err != nil {")
+		g.line("return nil, err")
+		g.line("}")
+	} else {
+		g.line("%s := %s", v, fn.call(call, args))
+	}
+	return v, nil
+}
+
+// goLiteral returns the Go expression of a JSON value.
+func goLiteral(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "nil"
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return "float64(" + strconv.FormatFloat(v, 'g', -1, 64) + ")"
+	case string:
+		return strconv.Quote(v)
+	case []interface{}:
+		elements := make([]string, len(v))
+		for i, element := range v {
+			elements[i] = goLiteral(element)
+		}
+		return "[]interface{}{" + strings.Join(elements, ", ") + "}"
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		fields := make([]string, len(keys))
+		for i, key := range keys {
+			fields[i] = strconv.Quote(key) + ": " + goLiteral(v[key])
+		}
+		return "map[string]interface{}{" + strings.Join(fields, ", ") + "}"
+	}
+	panic(fmt.Sprintf("jpgen: unexpected literal of type %T", value))
+}
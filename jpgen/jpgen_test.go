@@ -0,0 +1,199 @@
+package jpgen
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jmespath/go-jmespath"
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func TestGenerate(t *testing.T) {
+	assert := assert.New(t)
+	source, err := Generate("queries", []Func{
+		{Name: "ActiveNames", Expression: "users[?active].name"},
+		{Name: "Total", Expression: "sum(orders[*].amount)"},
+	})
+	assert.Nil(err)
+	file, err := parser.ParseFile(token.NewFileSet(), "queries.go", source, parser.ImportsOnly)
+	if assert.Nil(err) {
+		assert.Equal("queries", file.Name.Name)
+		for _, spec := range file.Imports {
+			assert.False(strings.Contains(spec.Path.Value, "jmespath"), spec.Path.Value)
+		}
+	}
+	assert.True(strings.HasPrefix(string(source), "// Code generated by jpgen. DO NOT EDIT.\n"))
+	assert.Contains(string(source), "// ActiveNames evaluates the JMESPath expression \"users[?active].name\" against data.\n"+
+		"func ActiveNames(data interface{}) (interface{}, error) {\n")
+	assert.Contains(string(source), "func Total(data interface{}) (interface{}, error) {\n")
+}
+
+func TestGenerateErrors(t *testing.T) {
+	assert := assert.New(t)
+	unsupported := []struct {
+		expression    string
+		subExpression string
+	}{
+		{"first_of(a, b)", "first_of(a, b)"},
+		{"items[*].sort_by(tags, &name, 'natural')", "sort_by(tags, &name, 'natural')"},
+		{"&a", "&a"},
+		{"to_number(a, `16`)", "to_number(a, `16`)"},
+	}
+	for _, tt := range unsupported {
+		_, err := Generate("queries", []Func{{Name: "F", Expression: tt.expression}})
+		if unsupportedErr, ok := err.(*UnsupportedError); assert.True(ok, tt.expression) {
+			assert.Equal(tt.expression, unsupportedErr.Expression)
+			assert.Equal(tt.subExpression, unsupportedErr.SubExpression)
+		}
+	}
+	invalid := []struct {
+		pkg   string
+		funcs []Func
+	}{
+		{"queries", []Func{{Name: "F", Expression: "a."}}},
+		{"queries", []Func{{Name: "F", Expression: "length()"}}},
+		{"queries", []Func{{Name: "F", Expression: "length(a, b)"}}},
+		{"queries", []Func{{Name: "F", Expression: "map(a, b)"}}},
+		{"queries", []Func{{Name: "F", Expression: "length(&a)"}}},
+		{"queries", []Func{{Name: "F", Expression: "a[::0]"}}},
+		{"queries", []Func{{Name: "F", Expression: "a"}, {Name: "F", Expression: "b"}}},
+		{"queries", []Func{{Name: "not valid", Expression: "a"}}},
+		{"queries", []Func{{Name: "jpField", Expression: "a"}}},
+		{"not-valid", []Func{{Name: "F", Expression: "a"}}},
+	}
+	for _, tt := range invalid {
+		_, err := Generate(tt.pkg, tt.funcs)
+		assert.NotNil(err, tt.funcs[0].Expression)
+		_, unsupported := err.(*UnsupportedError)
+		assert.False(unsupported, tt.funcs[0].Expression)
+	}
+}
+
+// TestGeneratedCode builds and runs the code generated for expressions
+// and checks that it returns what jmespath.Search returns.
+func TestGeneratedCode(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a program")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+	assert := assert.New(t)
+	document := `{
+		"users": [
+			{"name": "ann", "age": 31, "active": true, "tags": ["a", "b"], "manager": {"name": "eve"}},
+			{"name": "bob", "age": 25, "active": false, "tags": [], "manager": null},
+			{"name": "cy", "age": 42, "active": true, "tags": ["c"]}
+		],
+		"matrix": [[1, 2], [3, [4, 5]]],
+		"labels": {"b": 2, "a": 1, "c": 3},
+		"title": "héllo world"
+	}`
+	expressions := []string{
+		"users[?active].name",
+		"users[?age > `30` && active].{name: name, boss: manager.name}",
+		"users[*].manager.name",
+		"users[].tags[]",
+		"matrix[][]",
+		"labels.*",
+		"sort(keys(labels))",
+		"users | [0].name",
+		"users[-1].name",
+		"users[1:].name",
+		"users[::-1].name",
+		"title[0:5]",
+		"title[::-1]",
+		"length(title)",
+		"users[?contains(tags, 'b')].name",
+		"sort_by(users, &age)[*].name",
+		"max_by(users, &age).name",
+		"min_by(users, &name).name",
+		"map(&length(tags), users)",
+		"sum(users[*].age)",
+		"avg(users[*].age)",
+		"max(users[*].name)",
+		"[length(users), abs(`-3`), floor(`2.5`), ceil(`2.5`)]",
+		"join(', ', users[*].name)",
+		"not_null(missing, users[2].manager, `\"default\"`)",
+		"to_string(matrix)",
+		"to_number('12.5')",
+		"type(labels)",
+		"reverse(users[*].name)",
+		"merge(labels, `{\"d\": 4}`)",
+		"to_array(title)",
+		"values(labels)",
+		"starts_with(title, 'hé') && ends_with(title, 'world')",
+		"!users[1].active",
+		"users[?!active] | [0].name",
+		"missing || `\"fallback\"`",
+		"users[0].name == 'ann'",
+		"users[0].age in `[25, 31]`",
+		"users[?name >= 'b'].name",
+		"[title < 'i', title > `1`]",
+		"`{\"a\": [1, 2.5, null, true]}`",
+		"@.title",
+		"[missing, title]",
+		"missing.[a, b]",
+		"sum(users[*].name)",
+		"sort_by(users, &manager)",
+	}
+	funcs := make([]Func, len(expressions))
+	for i, expression := range expressions {
+		funcs[i] = Func{Name: fmt.Sprintf("Expression%d", i), Expression: expression}
+	}
+	source, err := Generate("main", funcs)
+	if !assert.Nil(err) {
+		return
+	}
+	var program strings.Builder
+	program.WriteString("package main\n\nimport (\n\t\"encoding/json\"\n\t\"fmt\"\n\t\"os\"\n)\n\n")
+	program.WriteString("var functions = []func(interface{}) (interface{}, error){\n")
+	for _, fn := range funcs {
+		program.WriteString("\t" + fn.Name + ",\n")
+	}
+	program.WriteString("}\n\nfunc main() {\n\tvar data interface{}\n\tjson.NewDecoder(os.Stdin).Decode(&data)\n")
+	program.WriteString("\tfor _, fn := range functions {\n\t\tresult, err := fn(data)\n\t\tencoded, _ := json.Marshal(result)\n")
+	program.WriteString("\t\tfmt.Printf(\"%s %t\\n\", encoded, err != nil)\n\t}\n}\n")
+
+	dir, err := ioutil.TempDir("", "jpgen")
+	if !assert.Nil(err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	assert.Nil(ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte("module jpgentest\n\ngo 1.14\n"), 0644))
+	assert.Nil(ioutil.WriteFile(filepath.Join(dir, "generated.go"), source, 0644))
+	assert.Nil(ioutil.WriteFile(filepath.Join(dir, "main.go"), []byte(program.String()), 0644))
+	cmd := exec.Command(goTool, "run", ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=", "GO111MODULE=on")
+	cmd.Stdin = strings.NewReader(document)
+	output, err := cmd.CombinedOutput()
+	if !assert.Nil(err, string(output)) {
+		return
+	}
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if !assert.Equal(len(expressions), len(lines)) {
+		return
+	}
+	var data interface{}
+	assert.Nil(json.Unmarshal([]byte(document), &data))
+	for i, expression := range expressions {
+		expected, err := jmespath.Search(expression, data)
+		encoded, _ := json.Marshal(expected)
+		if expression == "values(labels)" || expression == "labels.*" {
+			// The jmespath package visits the values of objects in map
+			// order.
+			encoded = []byte("[1,2,3]")
+		}
+		assert.Equal(fmt.Sprintf("%s %t", encoded, err != nil), lines[i], expression)
+	}
+}
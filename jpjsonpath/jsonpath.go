@@ -0,0 +1,596 @@
+/*
+Package jpjsonpath translates JSONPath expressions into equivalent JMESPath
+expressions, so that rules written in JSONPath can be evaluated with the
+jmespath package:
+
+	expression, err := jpjsonpath.Translate("$.jobs[?(@.status == 'failed')].name")
+	// expression is "jobs[?status == 'failed'].name"
+
+The supported subset of JSONPath is
+
+	$                 the document
+	.name ['name']    child by name, quoted with ' or "
+	['a','b']         union of names
+	[0] [-1]          child by index
+	[0,2]             union of indexes
+	[start:end:step]  slice
+	[*] .*            wildcard over an array, or the values of an object
+	..name            recursive descent to a name
+	[?(filter)]       filter, with the parentheses optional
+
+where filters compare paths relative to the current element (@) and JSON
+literals with ==, !=, <, <=, >, >=, in and nin, and combine them with &&,
+|| and !.  A path on its own tests for existence, which is translated to a
+comparison with null, so a value of null counts as absent.
+
+Anything else, such as script expressions, functions, regular expressions
+or references to the root ($) in filters, is reported with an
+*UntranslatableError rather than translated into an expression that would
+behave differently.
+
+JSONPath always evaluates to a list of matches, whereas a translated path
+that selects at most one value, such as $.a.b, evaluates to that value, or
+to null if there is no match, as in JMESPath.  [*] only applies to arrays
+and .* only to objects, since JMESPath distinguishes the two, and ordering
+comparisons follow JMESPath rules.  Recursive descent requires a runtime
+with recursive descent enabled, see jmespath.Runtime.SetRecursiveDescent;
+Compile and TranslateAST accept it regardless.
+*/
+package jpjsonpath
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/jmespath/go-jmespath"
+)
+
+// SyntaxError is returned for paths that are not valid JSONPath.
+type SyntaxError struct {
+	Path   string // The JSONPath expression.
+	Offset int    // Location of the error in Path.
+	Msg    string // Description of the error.
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("invalid JSONPath %q at offset %d: %s", e.Path, e.Offset, e.Msg)
+}
+
+// UntranslatableError is returned for valid JSONPath expressions that use
+// a feature with no JMESPath equivalent.
+type UntranslatableError struct {
+	Path    string // The JSONPath expression.
+	Offset  int    // Location of the feature in Path.
+	Feature string // Description of the feature.
+}
+
+func (e *UntranslatableError) Error() string {
+	return fmt.Sprintf("untranslatable JSONPath %q at offset %d: %s cannot be translated to JMESPath", e.Path, e.Offset, e.Feature)
+}
+
+// Translate returns the JMESPath expression equivalent to the JSONPath
+// expression path.
+func Translate(path string) (string, error) {
+	t := &translator{path: path}
+	t.skipSpace()
+	if !t.consume("$") {
+		return "", t.syntaxError("expected $")
+	}
+	if err := t.segments(); err != nil {
+		return "", err
+	}
+	if t.skipSpace(); t.pos < len(t.path) {
+		return "", t.syntaxError(fmt.Sprintf("unexpected %q", t.path[t.pos]))
+	}
+	return t.expression(), nil
+}
+
+// TranslateAST is like Translate, but returns the AST of the expression.
+func TranslateAST(path string) (jmespath.ASTNode, error) {
+	jp, err := Compile(jmespath.NewRuntime(), path)
+	if err != nil {
+		return jmespath.ASTNode{}, err
+	}
+	return jp.AST(), nil
+}
+
+// Compile translates path and compiles the expression with rt, as by
+// jmespath.Runtime.Compile, except that recursive descent is accepted even
+// if it is not enabled in rt.
+func Compile(rt *jmespath.Runtime, path string) (*jmespath.JMESPath, error) {
+	expression, err := Translate(path)
+	if err != nil {
+		return nil, err
+	}
+	descent := jmespath.NewRuntime()
+	descent.SetRecursiveDescent(true)
+	jp, err := descent.Compile(expression)
+	if err != nil {
+		return nil, fmt.Errorf("translation of JSONPath %q is invalid: %s", path, err)
+	}
+	return rt.CompileAST(jp.AST())
+}
+
+// translator translates a path one segment at a time.  Once a segment
+// selecting any number of values, such as a wildcard, has been translated,
+// the expression is a projection and later segments apply to each of the
+// selected values.
+type translator struct {
+	path       string
+	pos        int
+	out        strings.Builder
+	projecting bool
+}
+
+func (t *translator) expression() string {
+	if t.out.Len() == 0 {
+		return "@"
+	}
+	return t.out.String()
+}
+
+// emit appends the translation of a segment, which is separated from the
+// segments before it by sep.  A segment selecting many values from each
+// value of a projection selects a list of values for each of them, which
+// is flattened into a single list, as JSONPath results are.
+func (t *translator) emit(sep string, segment string, many bool) {
+	if t.out.Len() > 0 {
+		t.out.WriteString(sep)
+	}
+	t.out.WriteString(segment)
+	if many && t.projecting {
+		t.out.WriteString("[]")
+	}
+	t.projecting = t.projecting || many
+}
+
+// segments translates the segments following $ or @.
+func (t *translator) segments() error {
+	for {
+		t.skipSpace()
+		var err error
+		switch {
+		case t.consume(".."):
+			err = t.descent()
+		case t.consume("."):
+			err = t.dot()
+		case t.consume("["):
+			err = t.bracket()
+		default:
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (t *translator) descent() error {
+	start := t.pos - 2
+	name := t.name()
+	if name == "" {
+		return t.untranslatable(start, "recursive descent to anything but a name")
+	}
+	t.emit("", ".."+identifier(name), true)
+	return nil
+}
+
+func (t *translator) dot() error {
+	if t.consume("*") {
+		t.emit(".", "*", true)
+		return nil
+	}
+	start := t.pos
+	name := t.name()
+	if name == "" {
+		return t.syntaxError("expected a name or * after .")
+	}
+	if t.skipSpace(); t.peek() == '(' {
+		return t.untranslatable(start, fmt.Sprintf("the function %s()", name))
+	}
+	t.emit(".", identifier(name), false)
+	return nil
+}
+
+func (t *translator) bracket() error {
+	start := t.pos - 1
+	t.skipSpace()
+	switch c := t.peek(); {
+	case c == '*':
+		t.pos++
+		t.emit("", "[*]", true)
+	case c == '?':
+		t.pos++
+		condition, err := t.filter()
+		if err != nil {
+			return err
+		}
+		t.emit("", "[?"+condition+"]", true)
+	case c == '(':
+		return t.untranslatable(start, "a script expression")
+	case c == '\'' || c == '"':
+		first, err := t.str()
+		if err != nil {
+			return err
+		}
+		names, err := t.list(first, t.str)
+		if err != nil {
+			return err
+		}
+		if len(names) == 1 {
+			t.emit(".", names[0], false)
+		} else {
+			t.emit(".", "["+strings.Join(names, ", ")+"][*]", true)
+		}
+	case c == '-' || c == ':' || (c >= '0' && c <= '9'):
+		if err := t.indexes(); err != nil {
+			return err
+		}
+	default:
+		return t.syntaxError("expected *, ?, a quoted name, an index or a slice after [")
+	}
+	t.skipSpace()
+	if !t.consume("]") {
+		return t.syntaxError("expected ]")
+	}
+	return nil
+}
+
+// indexes translates an index, a union of indexes or a slice.
+func (t *translator) indexes() error {
+	var bounds []string
+	for i := 0; i < 3; i++ {
+		t.skipSpace()
+		bound := t.integer()
+		if t.skipSpace(); t.peek() == ',' && i == 0 && bound != "" {
+			indexes, err := t.list(bound, t.index)
+			if err != nil {
+				return err
+			}
+			for j := range indexes {
+				indexes[j] = "[" + indexes[j] + "]"
+			}
+			t.emit(".", "["+strings.Join(indexes, ", ")+"][*]", true)
+			return nil
+		}
+		bounds = append(bounds, bound)
+		if i == 2 || !t.consume(":") {
+			break
+		}
+	}
+	if len(bounds) == 1 {
+		if bounds[0] == "" {
+			return t.syntaxError("expected an index")
+		}
+		t.emit("", "["+bounds[0]+"]", false)
+		return nil
+	}
+	t.emit("", "["+strings.Join(bounds, ":")+"]", true)
+	return nil
+}
+
+// list parses the items following first, each preceded by a comma.
+func (t *translator) list(first string, item func() (string, error)) ([]string, error) {
+	items := []string{first}
+	for t.skipSpace(); t.consume(","); t.skipSpace() {
+		t.skipSpace()
+		value, err := item()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, value)
+	}
+	return items, nil
+}
+
+// str parses a quoted name and returns it as a JMESPath identifier.
+func (t *translator) str() (string, error) {
+	s, err := t.quoted()
+	if err != nil {
+		return "", err
+	}
+	return identifier(s), nil
+}
+
+func (t *translator) index() (string, error) {
+	if i := t.integer(); i != "" {
+		return i, nil
+	}
+	return "", t.syntaxError("expected an index")
+}
+
+func (t *translator) integer() string {
+	start := t.pos
+	t.consume("-")
+	for t.pos < len(t.path) && t.path[t.pos] >= '0' && t.path[t.pos] <= '9' {
+		t.pos++
+	}
+	if _, err := strconv.Atoi(t.path[start:t.pos]); err != nil {
+		t.pos = start
+		return ""
+	}
+	return t.path[start:t.pos]
+}
+
+// filter translates the condition of a filter, leaving out the
+// parentheses that usually enclose it.
+func (t *translator) filter() (string, error) {
+	t.skipSpace()
+	if start := t.pos; t.consume("(") {
+		condition, err := t.or()
+		if t.skipSpace(); err == nil && t.consume(")") {
+			if t.skipSpace(); t.peek() == ']' {
+				return condition, nil
+			}
+		}
+		t.pos = start
+	}
+	return t.or()
+}
+
+func (t *translator) or() (string, error) {
+	return t.binary("||", t.and)
+}
+
+func (t *translator) and() (string, error) {
+	return t.binary("&&", t.not)
+}
+
+func (t *translator) binary(op string, operand func() (string, error)) (string, error) {
+	left, err := operand()
+	if err != nil {
+		return "", err
+	}
+	for t.skipSpace(); t.consume(op); t.skipSpace() {
+		right, err := operand()
+		if err != nil {
+			return "", err
+		}
+		left += " " + op + " " + right
+	}
+	return left, nil
+}
+
+func (t *translator) not() (string, error) {
+	t.skipSpace()
+	if t.peek() == '!' && !strings.HasPrefix(t.path[t.pos:], "!=") {
+		t.pos++
+		operand, err := t.not()
+		if err != nil {
+			return "", err
+		}
+		if !strings.HasPrefix(operand, "(") || !strings.HasSuffix(operand, ")") {
+			operand = "(" + operand + ")"
+		}
+		return "!" + operand, nil
+	}
+	if t.consume("(") {
+		condition, err := t.or()
+		if err != nil {
+			return "", err
+		}
+		if t.skipSpace(); !t.consume(")") {
+			return "", t.syntaxError("expected )")
+		}
+		return "(" + condition + ")", nil
+	}
+	return t.comparison()
+}
+
+var comparators = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+var wordOperators = regexp.MustCompile(`^[a-z]+`)
+
+func (t *translator) comparison() (string, error) {
+	left, isPath, err := t.operand()
+	if err != nil {
+		return "", err
+	}
+	t.skipSpace()
+	start := t.pos
+	op := ""
+	for _, c := range comparators {
+		if t.consume(c) {
+			op = c
+			break
+		}
+	}
+	if op == "" {
+		switch word := wordOperators.FindString(t.path[t.pos:]); word {
+		case "in":
+			op = "in"
+		case "nin":
+			op = "not in"
+		case "":
+			if strings.HasPrefix(t.path[t.pos:], "=~") {
+				return "", t.untranslatable(start, "regular expression matching (=~)")
+			}
+			if isPath {
+				return left + " != `null`", nil
+			}
+			return left, nil
+		default:
+			return "", t.untranslatable(start, fmt.Sprintf("the %s operator", word))
+		}
+		t.pos += len(wordOperators.FindString(t.path[t.pos:]))
+	}
+	right, _, err := t.operand()
+	if err != nil {
+		return "", err
+	}
+	return left + " " + op + " " + right, nil
+}
+
+// operand translates a path relative to the current element or a JSON
+// literal, and reports whether it was a path.
+func (t *translator) operand() (string, bool, error) {
+	t.skipSpace()
+	start := t.pos
+	switch c := t.peek(); {
+	case c == '@':
+		t.pos++
+		relative := &translator{path: t.path, pos: t.pos}
+		if err := relative.segments(); err != nil {
+			return "", false, err
+		}
+		if relative.projecting {
+			return "", false, t.untranslatable(start, "a path selecting more than one value in a filter")
+		}
+		t.pos = relative.pos
+		return relative.expression(), true, nil
+	case c == '$':
+		return "", false, t.untranslatable(start, "a reference to the root ($) in a filter")
+	}
+	value, err := t.literal()
+	if err != nil {
+		return "", false, err
+	}
+	if s, ok := value.(string); ok {
+		return stringLiteral(s), false, nil
+	}
+	encoded, _ := json.Marshal(value)
+	return jsonLiteral(string(encoded)), false, nil
+}
+
+// literal parses a string, number, true, false, null, or an array of
+// them.  Unlike in JSON, strings may be quoted with ' as well as ".
+func (t *translator) literal() (interface{}, error) {
+	t.skipSpace()
+	switch t.peek() {
+	case '\'', '"':
+		return t.quoted()
+	case '[':
+		t.pos++
+		values := []interface{}{}
+		for t.skipSpace(); !t.consume("]"); t.skipSpace() {
+			if len(values) > 0 && !t.consume(",") {
+				return nil, t.syntaxError("expected , or ]")
+			}
+			value, err := t.literal()
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	}
+	var value interface{}
+	decoder := json.NewDecoder(strings.NewReader(t.path[t.pos:]))
+	if err := decoder.Decode(&value); err != nil {
+		return nil, t.syntaxError("expected @, a string, a number, true, false, null or an array")
+	}
+	if _, ok := value.(map[string]interface{}); ok {
+		return nil, t.syntaxError("expected @, a string, a number, true, false, null or an array")
+	}
+	t.pos += int(decoder.InputOffset())
+	return value, nil
+}
+
+// name returns the name following a dot, or "" if there is none.
+func (t *translator) name() string {
+	start := t.pos
+	for _, r := range t.path[t.pos:] {
+		if r != '_' && r != '-' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			break
+		}
+		t.pos += len(string(r))
+	}
+	return t.path[start:t.pos]
+}
+
+// quoted parses a string quoted with ' or ", in which the quote and
+// backslash are escaped with a backslash, as are the JSON escapes.
+func (t *translator) quoted() (string, error) {
+	quote := t.path[t.pos]
+	var s strings.Builder
+	for i := t.pos + 1; i < len(t.path); i++ {
+		c := t.path[i]
+		if c == quote {
+			t.pos = i + 1
+			return s.String(), nil
+		}
+		if c != '\\' {
+			s.WriteByte(c)
+			continue
+		}
+		if i++; i == len(t.path) {
+			break
+		}
+		switch c := t.path[i]; c {
+		case '\'', '"', '\\', '/':
+			s.WriteByte(c)
+		case 'b', 'f', 'n', 'r', 't', 'u':
+			escape := t.path[i-1 : i+1]
+			if c == 'u' && i+5 <= len(t.path) {
+				escape = t.path[i-1 : i+5]
+			}
+			var unescaped string
+			if err := json.Unmarshal([]byte(`"`+escape+`"`), &unescaped); err != nil {
+				return "", &SyntaxError{Path: t.path, Offset: i - 1, Msg: "invalid escape " + escape}
+			}
+			s.WriteString(unescaped)
+			i += len(escape) - 2
+		default:
+			return "", &SyntaxError{Path: t.path, Offset: i - 1, Msg: `invalid escape \` + string(c)}
+		}
+	}
+	return "", t.syntaxError("unterminated string")
+}
+
+func (t *translator) skipSpace() {
+	for t.pos < len(t.path) && strings.IndexByte(" \t\n\r", t.path[t.pos]) >= 0 {
+		t.pos++
+	}
+}
+
+func (t *translator) peek() byte {
+	if t.pos < len(t.path) {
+		return t.path[t.pos]
+	}
+	return 0
+}
+
+func (t *translator) consume(s string) bool {
+	if strings.HasPrefix(t.path[t.pos:], s) {
+		t.pos += len(s)
+		return true
+	}
+	return false
+}
+
+func (t *translator) syntaxError(msg string) error {
+	return &SyntaxError{Path: t.path, Offset: t.pos, Msg: msg}
+}
+
+func (t *translator) untranslatable(offset int, feature string) error {
+	return &UntranslatableError{Path: t.path, Offset: offset, Feature: feature}
+}
+
+var unquotedIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// identifier returns name as a JMESPath identifier, quoted if necessary.
+func identifier(name string) string {
+	if unquotedIdentifier.MatchString(name) {
+		return name
+	}
+	encoded, _ := json.Marshal(name)
+	return string(encoded)
+}
+
+// stringLiteral returns s as a JMESPath raw string literal, or as a JSON
+// literal if it contains characters that would need escaping.
+func stringLiteral(s string) string {
+	if !strings.ContainsAny(s, `'\`) {
+		return "'" + s + "'"
+	}
+	encoded, _ := json.Marshal(s)
+	return jsonLiteral(string(encoded))
+}
+
+func jsonLiteral(encoded string) string {
+	return "`" + strings.Replace(encoded, "`", "\\`", -1) + "`"
+}
@@ -0,0 +1,120 @@
+package jpjsonpath
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jmespath/go-jmespath"
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func TestTranslate(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		path       string
+		expression string
+	}{
+		{"$", "@"},
+		{"$.store.book", "store.book"},
+		{"$['store'][\"book\"]", "store.book"},
+		{"$['first name']", `"first name"`},
+		{"$.store.book[0]", "store.book[0]"},
+		{"$.store.book[-1].title", "store.book[-1].title"},
+		{"$.store.book[*].author", "store.book[*].author"},
+		{"$.store.book[1:3]", "store.book[1:3]"},
+		{"$.store.book[::2]", "store.book[::2]"},
+		{"$.store.*", "store.*"},
+		{"$..author", "..author"},
+		{"$.store..price", "store..price"},
+		{"$['a','b']", "[a, b][*]"},
+		{"$.store.book[0,2].title", "store.book.[[0], [2]][*].title"},
+		{"$.shelves[*].books[*]", "shelves[*].books[*][]"},
+		{"$.store.book[?(@.price < 10)].title", "store.book[?price < `10`].title"},
+		{"$.store.book[?@.isbn]", "store.book[?isbn != `null`]"},
+		{"$.store.book[?(!@.isbn)]", "store.book[?!(isbn != `null`)]"},
+		{"$.store.book[?(@.category == 'fiction' && @.price > 10)]", "store.book[?category == 'fiction' && price > `10`]"},
+		{"$.store.book[?(@.category == \"it's\" || (@.price >= 8.5))]", "store.book[?category == `\"it's\"` || (price >= `8.5`)]"},
+		{"$.store.book[?(@.category in ['fiction', 'poetry'])]", "store.book[?category in `[\"fiction\",\"poetry\"]`]"},
+		{"$.store.book[?(@.category nin ['fiction'])]", "store.book[?category not in `[\"fiction\"]`]"},
+		{"$.store.book[?(@.author.name == null)]", "store.book[?author.name == `null`]"},
+		{"$.numbers[?(@ > 2)]", "numbers[?@ > `2`]"},
+		{"$.store.book[?(@.price)] [0]", "store.book[?price != `null`][0]"},
+	}
+	for _, test := range tests {
+		expression, err := Translate(test.path)
+		assert.Nil(err, test.path)
+		assert.Equal(test.expression, expression, test.path)
+		_, err = TranslateAST(test.path)
+		assert.Nil(err, test.path)
+	}
+}
+
+const store = `{
+  "store": {
+    "book": [
+      {"category": "reference", "author": "Nigel Rees", "title": "Sayings of the Century", "price": 8.95},
+      {"category": "fiction", "author": "Evelyn Waugh", "title": "Sword of Honour", "price": 12.99},
+      {"category": "fiction", "author": "Herman Melville", "title": "Moby Dick", "isbn": "0-553-21311-3", "price": 8.99},
+      {"category": "fiction", "author": "J. R. R. Tolkien", "title": "The Lord of the Rings", "isbn": "0-395-19395-8", "price": 22.99}
+    ],
+    "bicycle": {"color": "red", "price": 19.95}
+  },
+  "shelves": [{"books": [["a"], "b"]}, {"books": ["c"]}]
+}`
+
+func TestCompile(t *testing.T) {
+	assert := assert.New(t)
+	var data interface{}
+	assert.Nil(json.Unmarshal([]byte(store), &data))
+	tests := []struct {
+		path     string
+		expected interface{}
+	}{
+		{"$.store.bicycle.color", "red"},
+		{"$.store.book[?(@.price < 10)].title", []interface{}{"Sayings of the Century", "Moby Dick"}},
+		{"$.store.book[?(@.isbn)].author", []interface{}{"Herman Melville", "J. R. R. Tolkien"}},
+		{"$.store.book[0,2,9].price", []interface{}{8.95, 8.99}},
+		{"$.store.book[-2:].title", []interface{}{"Moby Dick", "The Lord of the Rings"}},
+		{"$.shelves[*].books[*]", []interface{}{[]interface{}{"a"}, "b", "c"}},
+		{"$.store.book[?(@.category nin ['fiction'])].price", []interface{}{8.95}},
+		{"$..color", []interface{}{"red"}},
+	}
+	for _, test := range tests {
+		jp, err := Compile(jmespath.NewRuntime(), test.path)
+		assert.Nil(err, test.path)
+		result, err := jp.Search(data)
+		assert.Nil(err, test.path)
+		assert.Equal(test.expected, result, test.path)
+	}
+}
+
+func TestTranslateErrors(t *testing.T) {
+	assert := assert.New(t)
+	untranslatable := []struct {
+		path    string
+		offset  int
+		feature string
+	}{
+		{"$.book[(@.length-1)]", 6, "a script expression"},
+		{"$.book.length()", 7, "the function length()"},
+		{"$.book[?(@.author =~ /.*Rees/)]", 18, "regular expression matching (=~)"},
+		{"$.book[?(@.price < $.max)]", 19, "a reference to the root ($) in a filter"},
+		{"$.book[?(@.tags[*] == 'a')]", 9, "a path selecting more than one value in a filter"},
+		{"$.book[?(@.tags anyof ['a'])]", 16, "the anyof operator"},
+		{"$..[0]", 1, "recursive descent to anything but a name"},
+	}
+	for _, test := range untranslatable {
+		_, err := Translate(test.path)
+		assert.Equal(&UntranslatableError{Path: test.path, Offset: test.offset, Feature: test.feature}, err, test.path)
+	}
+	_, err := Translate("$.book[?(@.price < 10)")
+	assert.EqualError(err, `invalid JSONPath "$.book[?(@.price < 10)" at offset 22: expected ]`)
+	_, err = Translate("store.book")
+	assert.EqualError(err, `invalid JSONPath "store.book" at offset 0: expected $`)
+	_, err = Translate("$.book[0")
+	assert.EqualError(err, `invalid JSONPath "$.book[0" at offset 8: expected ]`)
+	_, err = Translate("$['a")
+	assert.EqualError(err, `invalid JSONPath "$['a" at offset 2: unterminated string`)
+	_, err = Compile(jmespath.NewRuntime(), "$.book[(@.length-1)]")
+	assert.EqualError(err, `untranslatable JSONPath "$.book[(@.length-1)]" at offset 6: a script expression cannot be translated to JMESPath`)
+}
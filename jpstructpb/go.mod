@@ -0,0 +1,11 @@
+module github.com/jmespath/go-jmespath/jpstructpb
+
+go 1.16
+
+require (
+	github.com/jmespath/go-jmespath v0.4.0
+	github.com/jmespath/go-jmespath/internal/testify v1.5.1
+	google.golang.org/protobuf v1.28.1
+)
+
+replace github.com/jmespath/go-jmespath => ../
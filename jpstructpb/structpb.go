@@ -0,0 +1,268 @@
+/*
+Package jpstructpb evaluates JMESPath expressions against the protocol
+buffer well-known types google.protobuf.Struct, Value and ListValue
+without converting whole messages into map[string]interface{} and
+[]interface{} values first:
+
+	result, err := jpstructpb.Search("spec.replicas", msg)
+
+An expression is compiled along with the paths of the document it reads,
+as told by jmespath.ReferencedPaths, and only the fields on those paths
+are converted before the expression is evaluated, so that looking up a
+few fields of a large message does not pay for converting all of it.
+Values that are read as a whole, such as the object selected by "spec" or
+the argument of a function, are converted completely, into the types
+encoding/json decodes to.
+
+This package is a separate module so that the jmespath package does not
+depend on google.golang.org/protobuf.
+*/
+package jpstructpb
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jmespath/go-jmespath"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Expression is a JMESPath expression compiled to search structpb
+// messages.  It is safe for concurrent use by multiple goroutines.
+type Expression struct {
+	jp    *jmespath.JMESPath
+	reads *readSet
+}
+
+// Compile compiles a JMESPath expression to search structpb messages.
+func Compile(expression string) (*Expression, error) {
+	jp, err := jmespath.Compile(expression)
+	if err != nil {
+		return nil, err
+	}
+	paths, err := jmespath.ReferencedPaths(expression)
+	if err != nil {
+		return nil, err
+	}
+	reads := &readSet{}
+	for _, path := range paths {
+		segments, err := splitPath(path)
+		if err != nil {
+			return nil, err
+		}
+		reads.add(segments)
+	}
+	return &Expression{jp: jp, reads: reads}, nil
+}
+
+// MustCompile is like Compile but panics if the expression cannot be
+// parsed.
+func MustCompile(expression string) *Expression {
+	e, err := Compile(expression)
+	if err != nil {
+		panic(`jpstructpb: Compile(` + expression + `): ` + err.Error())
+	}
+	return e
+}
+
+// Search evaluates the expression against msg, which is a
+// *structpb.Struct, *structpb.Value or *structpb.ListValue, and returns the
+// result in the types encoding/json decodes to.  Any other msg is searched
+// as it is.
+func (e *Expression) Search(msg interface{}) (interface{}, error) {
+	var data interface{}
+	switch m := msg.(type) {
+	case *structpb.Struct:
+		data = e.reads.convertStruct(m)
+	case *structpb.ListValue:
+		data = e.reads.convertList(m)
+	case *structpb.Value:
+		data = e.reads.convert(m)
+	default:
+		data = msg
+	}
+	return e.jp.Search(data)
+}
+
+// Search compiles expression and evaluates it against msg, see
+// Expression.Search.
+func Search(expression string, msg interface{}) (interface{}, error) {
+	e, err := Compile(expression)
+	if err != nil {
+		return nil, err
+	}
+	return e.Search(msg)
+}
+
+// readSet is the part of a value an expression reads: all of it, or the
+// fields in fields along with those in values for every field.  The same
+// parts are read from every element of an array, as the paths of
+// jmespath.ReferencedPaths have no array indexes.
+type readSet struct {
+	whole  bool
+	fields map[string]*readSet
+	values *readSet
+}
+
+// add records that the path made of segments is read.  An empty path is
+// the whole value.
+func (r *readSet) add(segments []segment) {
+	if r.whole {
+		return
+	}
+	if len(segments) == 0 {
+		*r = readSet{whole: true}
+		return
+	}
+	next := r.values
+	if !segments[0].wildcard {
+		next = r.fields[segments[0].key]
+	}
+	if next == nil {
+		next = &readSet{}
+		if segments[0].wildcard {
+			r.values = next
+		} else {
+			if r.fields == nil {
+				r.fields = make(map[string]*readSet)
+			}
+			r.fields[segments[0].key] = next
+		}
+	}
+	next.add(segments[1:])
+}
+
+// field returns the part read of the field key, or nil if it is not read.
+func (r *readSet) field(key string) *readSet {
+	named, values := r.fields[key], r.values
+	switch {
+	case named == nil:
+		return values
+	case values == nil:
+		return named
+	}
+	return union(named, values)
+}
+
+// union returns the parts read by either a or b.
+func union(a, b *readSet) *readSet {
+	if a.whole || b.whole {
+		return &readSet{whole: true}
+	}
+	u := &readSet{fields: make(map[string]*readSet)}
+	for key, read := range a.fields {
+		u.fields[key] = read
+	}
+	for key, read := range b.fields {
+		if existing, ok := u.fields[key]; ok {
+			read = union(existing, read)
+		}
+		u.fields[key] = read
+	}
+	switch {
+	case a.values == nil:
+		u.values = b.values
+	case b.values == nil:
+		u.values = a.values
+	default:
+		u.values = union(a.values, b.values)
+	}
+	return u
+}
+
+// convert returns the parts of v that are read in the types encoding/json
+// decodes to.  Nil and unset values are null.
+func (r *readSet) convert(v *structpb.Value) interface{} {
+	switch kind := v.GetKind().(type) {
+	case *structpb.Value_NumberValue:
+		return kind.NumberValue
+	case *structpb.Value_StringValue:
+		return kind.StringValue
+	case *structpb.Value_BoolValue:
+		return kind.BoolValue
+	case *structpb.Value_StructValue:
+		return r.convertStruct(kind.StructValue)
+	case *structpb.Value_ListValue:
+		return r.convertList(kind.ListValue)
+	}
+	return nil
+}
+
+func (r *readSet) convertStruct(s *structpb.Struct) interface{} {
+	if r.whole {
+		return s.AsMap()
+	}
+	m := make(map[string]interface{}, len(r.fields))
+	for key, v := range s.GetFields() {
+		if read := r.field(key); read != nil {
+			m[key] = read.convert(v)
+		}
+	}
+	return m
+}
+
+func (r *readSet) convertList(l *structpb.ListValue) interface{} {
+	if r.whole {
+		return l.AsSlice()
+	}
+	values := l.GetValues()
+	elements := make([]interface{}, len(values))
+	for i, v := range values {
+		elements[i] = r.convert(v)
+	}
+	return elements
+}
+
+// segment is a segment of a path: the field key, or every field if
+// wildcard is set.
+type segment struct {
+	key      string
+	wildcard bool
+}
+
+// splitPath returns the segments of a path returned by
+// jmespath.ReferencedPaths: field names separated by dots, which are
+// quoted as JSON strings if they are not identifiers, "*" for every field,
+// and "@" for the whole document, which has no segments.
+func splitPath(path string) ([]segment, error) {
+	if path == "@" {
+		return nil, nil
+	}
+	var segments []segment
+	for path != "" {
+		end := strings.IndexByte(path, '.')
+		if path[0] == '"' {
+			end = quotedEnd(path)
+		}
+		if end < 0 {
+			end = len(path)
+		}
+		s := segment{key: path[:end]}
+		switch {
+		case s.key == "*":
+			s.wildcard = true
+		case s.key[0] == '"':
+			if err := json.Unmarshal([]byte(path[:end]), &s.key); err != nil {
+				return nil, fmt.Errorf("invalid path %q: %s", path, err)
+			}
+		}
+		segments = append(segments, s)
+		path = strings.TrimPrefix(path[end:], ".")
+	}
+	return segments, nil
+}
+
+// quotedEnd returns the index just past the JSON string at the start of
+// path, or -1 if it is not terminated.
+func quotedEnd(path string) int {
+	for i := 1; i < len(path); i++ {
+		switch path[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return -1
+}
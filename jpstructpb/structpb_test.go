@@ -0,0 +1,106 @@
+package jpstructpb
+
+import (
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func newStruct(t *testing.T, fields map[string]interface{}) *structpb.Struct {
+	s, err := structpb.NewStruct(fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestSearch(t *testing.T) {
+	assert := assert.New(t)
+	msg := newStruct(t, map[string]interface{}{
+		"name": "web",
+		"spec": map[string]interface{}{"replicas": 3.0, "paused": false},
+		"pods": []interface{}{
+			map[string]interface{}{"name": "a", "ready": true},
+			map[string]interface{}{"name": "b", "ready": false},
+		},
+		"owner": nil,
+	})
+	for _, tt := range []struct {
+		expression string
+		expected   interface{}
+	}{
+		{"name", "web"},
+		{"spec.replicas", 3.0},
+		{"spec", map[string]interface{}{"replicas": 3.0, "paused": false}},
+		{"pods[?ready].name", []interface{}{"a"}},
+		{"pods[-1]", map[string]interface{}{"name": "b", "ready": false}},
+		{"length(pods)", 2.0},
+		{"sort(keys(spec))", []interface{}{"paused", "replicas"}},
+		{"owner", nil},
+		{"missing.field", nil},
+		{"spec.replicas > `2`", true},
+	} {
+		result, err := Search(tt.expression, msg)
+		if assert.Nil(err, tt.expression) {
+			assert.Equal(tt.expected, result, tt.expression)
+		}
+	}
+}
+
+func TestSearchValues(t *testing.T) {
+	assert := assert.New(t)
+	list, err := structpb.NewList([]interface{}{1.0, "a", true})
+	assert.Nil(err)
+	result, err := Search("[1]", list)
+	assert.Nil(err)
+	assert.Equal("a", result)
+
+	result, err = Search("@", structpb.NewStringValue("x"))
+	assert.Nil(err)
+	assert.Equal("x", result)
+	result, err = Search("a", structpb.NewStructValue(newStruct(t, map[string]interface{}{"a": 1.0})))
+	assert.Nil(err)
+	assert.Equal(1.0, result)
+	result, err = Search("@", structpb.NewNullValue())
+	assert.Nil(err)
+	assert.Nil(result)
+}
+
+func TestSearchConvertsReadFields(t *testing.T) {
+	assert := assert.New(t)
+	msg := newStruct(t, map[string]interface{}{
+		"a":   map[string]interface{}{"b": 1.0, "c": 2.0},
+		"d":   []interface{}{map[string]interface{}{"e": 3.0, "f": 4.0}},
+		"g.h": 5.0,
+		"i":   map[string]interface{}{"j": map[string]interface{}{"k": 6.0, "l": 7.0}, "m": 8.0},
+	})
+	for _, tt := range []struct {
+		expression string
+		expected   interface{}
+	}{
+		{"@", msg.AsMap()},
+		{"a.b", map[string]interface{}{"a": map[string]interface{}{"b": 1.0}}},
+		{"a", map[string]interface{}{"a": map[string]interface{}{"b": 1.0, "c": 2.0}}},
+		{"d[0].e", map[string]interface{}{"d": []interface{}{map[string]interface{}{"e": 3.0}}}},
+		{`"g.h"`, map[string]interface{}{"g.h": 5.0}},
+		{"i.*.k", map[string]interface{}{"i": map[string]interface{}{
+			"j": map[string]interface{}{"k": 6.0}, "m": 8.0}}},
+		{"[i.*.k, i.j.l]", map[string]interface{}{"i": map[string]interface{}{
+			"j": map[string]interface{}{"k": 6.0, "l": 7.0}, "m": 8.0}}},
+	} {
+		e, err := Compile(tt.expression)
+		if assert.Nil(err, tt.expression) {
+			assert.Equal(tt.expected, e.reads.convertStruct(msg), tt.expression)
+		}
+	}
+}
+
+func TestCompileError(t *testing.T) {
+	assert := assert.New(t)
+	_, err := Compile("a.")
+	assert.NotNil(err)
+	_, err = Search("a.", newStruct(t, nil))
+	assert.NotNil(err)
+	assert.Panics(func() { MustCompile("a.") })
+}
@@ -0,0 +1,59 @@
+package jputil
+
+// NaturalCompare compares the strings a and b in natural order, in which
+// runs of decimal digits compare by their numeric value, so that "item2"
+// sorts before "item10".  Other characters compare byte-wise, as strings
+// do in Go.  Runs of digits with the same value but a different number of
+// leading zeros, as in "a1" and "a01", sort by the number of leading zeros
+// so that only equal strings compare equal.  It returns a negative number
+// if a sorts before b, zero if a equals b and a positive number otherwise.
+func NaturalCompare(a, b string) int {
+	zeros := 0
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if !isDigit(a[i]) || !isDigit(b[j]) {
+			if a[i] != b[j] {
+				return int(a[i]) - int(b[j])
+			}
+			i++
+			j++
+			continue
+		}
+		// Compare the runs of digits starting at i and j without
+		// their leading zeros: the longer run is greater, and runs of
+		// equal length compare like strings.
+		startA, startB := i, j
+		for i < len(a) && a[i] == '0' {
+			i++
+		}
+		for j < len(b) && b[j] == '0' {
+			j++
+		}
+		if zeros == 0 {
+			zeros = (i - startA) - (j - startB)
+		}
+		digitsA, digitsB := i, j
+		for i < len(a) && isDigit(a[i]) {
+			i++
+		}
+		for j < len(b) && isDigit(b[j]) {
+			j++
+		}
+		if lengthA, lengthB := i-digitsA, j-digitsB; lengthA != lengthB {
+			return lengthA - lengthB
+		}
+		for k := 0; k < i-digitsA; k++ {
+			if a[digitsA+k] != b[digitsB+k] {
+				return int(a[digitsA+k]) - int(b[digitsB+k])
+			}
+		}
+	}
+	if remaining := (len(a) - i) - (len(b) - j); remaining != 0 {
+		return remaining
+	}
+	return zeros
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
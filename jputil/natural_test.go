@@ -0,0 +1,53 @@
+package jputil
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func TestNaturalCompare(t *testing.T) {
+	assert := assert.New(t)
+	sorted := []string{
+		"",
+		"0",
+		"00",
+		"1",
+		"01",
+		"2",
+		"10",
+		"a",
+		"a1",
+		"a01",
+		"a2",
+		"a10",
+		"a10b",
+		"a10b2",
+		"a10b10",
+		"b",
+		"item2",
+		"item10",
+		"item18446744073709551616",
+		"item18446744073709551617",
+		"itemx",
+	}
+	for i := range sorted {
+		for j := range sorted {
+			result := NaturalCompare(sorted[i], sorted[j])
+			switch {
+			case i < j:
+				assert.True(result < 0, "%q < %q", sorted[i], sorted[j])
+			case i > j:
+				assert.True(result > 0, "%q > %q", sorted[i], sorted[j])
+			default:
+				assert.Equal(0, result, "%q == %q", sorted[i], sorted[j])
+			}
+		}
+	}
+	shuffled := []string{"item10", "item2", "item1", "Item3"}
+	sort.Slice(shuffled, func(i, j int) bool {
+		return NaturalCompare(shuffled[i], shuffled[j]) < 0
+	})
+	assert.Equal([]string{"Item3", "item1", "item2", "item10"}, shuffled)
+}
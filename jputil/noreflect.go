@@ -0,0 +1,27 @@
+//go:build jmespath_noreflect
+// +build jmespath_noreflect
+
+package jputil
+
+// sliceElements returns the length of value and a function returning its
+// elements if value is a slice of one of the types expressions support
+// without reflection.
+func sliceElements(value interface{}) (int, func(i int) interface{}, bool) {
+	switch s := value.(type) {
+	case []interface{}:
+		return len(s), func(i int) interface{} { return s[i] }, true
+	case []string:
+		return len(s), func(i int) interface{} { return s[i] }, true
+	case []float64:
+		return len(s), func(i int) interface{} { return s[i] }, true
+	case []int:
+		return len(s), func(i int) interface{} { return s[i] }, true
+	case []int64:
+		return len(s), func(i int) interface{} { return s[i] }, true
+	case []bool:
+		return len(s), func(i int) interface{} { return s[i] }, true
+	case []map[string]interface{}:
+		return len(s), func(i int) interface{} { return s[i] }, true
+	}
+	return 0, nil, false
+}
@@ -0,0 +1,16 @@
+//go:build !jmespath_noreflect
+// +build !jmespath_noreflect
+
+package jputil
+
+import "reflect"
+
+// sliceElements returns the length of value and a function returning its
+// elements if value is a slice of any type.
+func sliceElements(value interface{}) (int, func(i int) interface{}, bool) {
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice {
+		return 0, nil, false
+	}
+	return rv.Len(), func(i int) interface{} { return rv.Index(i).Interface() }, true
+}
@@ -0,0 +1,167 @@
+/*
+Package jputil exposes parts of the JMESPath implementation that are useful
+outside of evaluating expressions, so that code working alongside
+expressions can behave exactly as they do.
+*/
+package jputil
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrZeroStep is returned by Slice for a step of zero.
+var ErrZeroStep = errors.New("Invalid slice, step cannot be 0")
+
+// ErrNotSliceable is returned by SliceAny for a value that is neither a
+// slice nor a string.
+var ErrNotSliceable = errors.New("value is not an array or a string")
+
+// SliceBounds are the normalized bounds of a slice expression
+// [start:stop:step] for an array of a given length.  The slice selects the
+// elements at indexes Start, Start+Step, Start+2*Step and so on, up to but
+// not including Stop.
+type SliceBounds struct {
+	// Start is the index of the first selected element, if any.  It is
+	// between 0 and the length of the array for a positive step, and
+	// between -1 and the length minus one for a negative step.
+	Start int
+	// Stop is the index the slice stops before, within the same range
+	// as Start.  It is -1 when a slice with a negative step runs up to
+	// and including the first element.
+	Stop int
+	// Step is the distance between selected indexes, 1 if it was
+	// omitted.  It is never zero.
+	Step int
+	// OutOfRange is set when a start or stop was given that is less
+	// than minus the length or greater than the length of the array, and
+	// was therefore moved to the nearest end of the array.
+	OutOfRange bool
+}
+
+// Len returns the number of elements the slice selects.
+func (b SliceBounds) Len() int {
+	if b.Step > 0 && b.Start < b.Stop {
+		return (b.Stop - b.Start + b.Step - 1) / b.Step
+	}
+	if b.Step < 0 && b.Start > b.Stop {
+		return (b.Start - b.Stop - b.Step - 1) / -b.Step
+	}
+	return 0
+}
+
+// Index returns the index in the array of the i-th selected element, for
+// i from 0 to Len()-1.
+func (b SliceBounds) Index(i int) int {
+	return b.Start + i*b.Step
+}
+
+// Slice normalizes the bounds of the slice expression [start:stop:step] for
+// an array of length elements, as defined by the JMESPath specification.
+// A nil start, stop or step stands for an omitted one.  Negative start and
+// stop values count from the end of the array, and values outside the
+// array are moved to its nearest end, so that slicing never fails except
+// for a step of zero.
+func Slice(length int, start, stop, step *int) (SliceBounds, error) {
+	bounds := SliceBounds{Step: 1}
+	if step != nil {
+		if *step == 0 {
+			return SliceBounds{}, ErrZeroStep
+		}
+		bounds.Step = *step
+	}
+	if start == nil {
+		if bounds.Step < 0 {
+			bounds.Start = length - 1
+		}
+	} else {
+		bounds.Start = capSlice(length, *start, bounds.Step)
+		bounds.OutOfRange = outOfRange(length, *start)
+	}
+	if stop == nil {
+		if bounds.Step < 0 {
+			bounds.Stop = -1
+		} else {
+			bounds.Stop = length
+		}
+	} else {
+		bounds.Stop = capSlice(length, *stop, bounds.Step)
+		bounds.OutOfRange = bounds.OutOfRange || outOfRange(length, *stop)
+	}
+	return bounds, nil
+}
+
+func capSlice(length int, actual int, step int) int {
+	if actual < 0 {
+		actual += length
+		if actual < 0 {
+			if step < 0 {
+				actual = -1
+			} else {
+				actual = 0
+			}
+		}
+	} else if actual >= length {
+		if step < 0 {
+			actual = length - 1
+		} else {
+			actual = length
+		}
+	}
+	return actual
+}
+
+func outOfRange(length int, bound int) bool {
+	return bound < -length || bound > length
+}
+
+// SliceAny applies the slice expression [start:stop:step] to value as
+// expressions do, and returns the result along with the bounds it applied,
+// see Slice.  A string is sliced by code points and gives a string.  A
+// slice of any type, such as a []interface{} decoded by encoding/json or a
+// []string, gives a []interface{} of the selected elements.  Builds with
+// the jmespath_noreflect tag only slice the slice types expressions
+// support without reflection.  For other values, whose slices evaluate to
+// null, SliceAny returns ErrNotSliceable.
+func SliceAny(value interface{}, start, stop, step *int) (interface{}, SliceBounds, error) {
+	switch v := value.(type) {
+	case string:
+		return sliceString(v, start, stop, step)
+	case []interface{}:
+		bounds, err := Slice(len(v), start, stop, step)
+		if err != nil {
+			return nil, SliceBounds{}, err
+		}
+		sliced := make([]interface{}, bounds.Len())
+		for i := range sliced {
+			sliced[i] = v[bounds.Index(i)]
+		}
+		return sliced, bounds, nil
+	}
+	length, element, ok := sliceElements(value)
+	if !ok {
+		return nil, SliceBounds{}, ErrNotSliceable
+	}
+	bounds, err := Slice(length, start, stop, step)
+	if err != nil {
+		return nil, SliceBounds{}, err
+	}
+	sliced := make([]interface{}, bounds.Len())
+	for i := range sliced {
+		sliced[i] = element(bounds.Index(i))
+	}
+	return sliced, bounds, nil
+}
+
+func sliceString(s string, start, stop, step *int) (interface{}, SliceBounds, error) {
+	runes := []rune(s)
+	bounds, err := Slice(len(runes), start, stop, step)
+	if err != nil {
+		return nil, SliceBounds{}, err
+	}
+	var sliced strings.Builder
+	for i := 0; i < bounds.Len(); i++ {
+		sliced.WriteRune(runes[bounds.Index(i)])
+	}
+	return sliced.String(), bounds, nil
+}
@@ -0,0 +1,89 @@
+package jputil
+
+import (
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func intPtr(n int) *int {
+	return &n
+}
+
+func TestSlice(t *testing.T) {
+	assert := assert.New(t)
+	cases := []struct {
+		start, stop, step *int
+		expected          SliceBounds
+		indexes           []int
+	}{
+		{nil, nil, nil, SliceBounds{0, 5, 1, false}, []int{0, 1, 2, 3, 4}},
+		{intPtr(1), intPtr(3), nil, SliceBounds{1, 3, 1, false}, []int{1, 2}},
+		{nil, nil, intPtr(2), SliceBounds{0, 5, 2, false}, []int{0, 2, 4}},
+		{nil, nil, intPtr(-1), SliceBounds{4, -1, -1, false}, []int{4, 3, 2, 1, 0}},
+		{intPtr(-2), nil, nil, SliceBounds{3, 5, 1, false}, []int{3, 4}},
+		{intPtr(3), intPtr(1), nil, SliceBounds{3, 1, 1, false}, []int{}},
+		{intPtr(-1), intPtr(-4), intPtr(-2), SliceBounds{4, 1, -2, false}, []int{4, 2}},
+		{intPtr(-5), intPtr(5), nil, SliceBounds{0, 5, 1, false}, []int{0, 1, 2, 3, 4}},
+		{intPtr(-6), nil, nil, SliceBounds{0, 5, 1, true}, []int{0, 1, 2, 3, 4}},
+		{nil, intPtr(10), nil, SliceBounds{0, 5, 1, true}, []int{0, 1, 2, 3, 4}},
+		{intPtr(10), nil, intPtr(-3), SliceBounds{4, -1, -3, true}, []int{4, 1}},
+	}
+	for _, tt := range cases {
+		bounds, err := Slice(5, tt.start, tt.stop, tt.step)
+		assert.Nil(err)
+		assert.Equal(tt.expected, bounds)
+		indexes := []int{}
+		for i := 0; i < bounds.Len(); i++ {
+			indexes = append(indexes, bounds.Index(i))
+		}
+		assert.Equal(tt.indexes, indexes, "%+v", bounds)
+	}
+}
+
+func TestSliceZeroStep(t *testing.T) {
+	assert := assert.New(t)
+	_, err := Slice(5, nil, nil, intPtr(0))
+	assert.Equal(ErrZeroStep, err)
+}
+
+func TestSliceEmptyArray(t *testing.T) {
+	assert := assert.New(t)
+	bounds, err := Slice(0, nil, nil, intPtr(-1))
+	assert.Nil(err)
+	assert.Equal(0, bounds.Len())
+	bounds, err = Slice(0, intPtr(0), intPtr(0), nil)
+	assert.Nil(err)
+	assert.Equal(SliceBounds{0, 0, 1, false}, bounds)
+}
+
+func TestSliceAny(t *testing.T) {
+	assert := assert.New(t)
+	cases := []struct {
+		value             interface{}
+		start, stop, step *int
+		expected          interface{}
+	}{
+		{[]interface{}{"a", 1.0, true}, intPtr(1), nil, nil, []interface{}{1.0, true}},
+		{[]string{"a", "b", "c"}, nil, nil, intPtr(-1), []interface{}{"c", "b", "a"}},
+		{[]int{1, 2, 3, 4}, nil, nil, intPtr(2), []interface{}{1, 3}},
+		{[]string{}, nil, nil, nil, []interface{}{}},
+		{"héllo", intPtr(1), intPtr(3), nil, "él"},
+		{"héllo", nil, nil, intPtr(-1), "olléh"},
+		{"", nil, nil, nil, ""},
+	}
+	for _, tt := range cases {
+		sliced, _, err := SliceAny(tt.value, tt.start, tt.stop, tt.step)
+		assert.Nil(err)
+		assert.Equal(tt.expected, sliced)
+	}
+	_, bounds, err := SliceAny([]float64{1, 2}, intPtr(-5), nil, nil)
+	assert.Nil(err)
+	assert.True(bounds.OutOfRange)
+	for _, value := range []interface{}{nil, 1.0, map[string]interface{}{}, [2]int{1, 2}} {
+		_, _, err = SliceAny(value, nil, nil, nil)
+		assert.Equal(ErrNotSliceable, err)
+	}
+	_, _, err = SliceAny("abc", nil, nil, intPtr(0))
+	assert.Equal(ErrZeroStep, err)
+}
@@ -0,0 +1,152 @@
+/*
+Package jpxml converts XML documents into the map[string]interface{} and
+[]interface{} values that JMESPath expressions are evaluated against.
+
+Each element becomes a key in its parent object.  Attributes are stored as
+keys prefixed with "@", repeated elements are collected into arrays, and
+text content is stored either as the element's value or, for elements that
+also have attributes or children, under the "#text" key:
+
+	<feed lang="en"><item>a</item><item>b</item></feed>
+
+becomes
+
+	{"feed": {"@lang": "en", "item": ["a", "b"]}}
+
+XML has no types, so all attribute values and text are strings.
+*/
+package jpxml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+)
+
+// TextPolicy controls how the text content of an element is mapped.
+type TextPolicy int
+
+const (
+	// TextAuto maps an element with only text content to a string, and
+	// stores the text of any other element under Options.TextKey.
+	TextAuto TextPolicy = iota
+	// TextAlways always maps an element to an object and stores its text
+	// under Options.TextKey, even when it has no attributes or children.
+	TextAlways
+	// TextIgnoreMixed behaves like TextAuto, except that the text of an
+	// element with attributes or children is discarded.
+	TextIgnoreMixed
+)
+
+// Options configures how XML is mapped.  The zero value uses "@" as the
+// attribute prefix, "#text" as the text key and the TextAuto policy.
+type Options struct {
+	AttrPrefix string     // Prefix added to attribute names.
+	TextKey    string     // Key text content is stored under.
+	Text       TextPolicy // How text content is mapped.
+	// ForceArray lists element names that are always mapped to an array,
+	// even when they occur only once, so that expressions like
+	// "channel.item[0]" work regardless of the number of items.
+	ForceArray []string
+}
+
+type element struct {
+	name     string
+	fields   map[string]interface{}
+	text     strings.Builder
+	hasAttrs bool
+}
+
+// Unmarshal converts the XML document in data using the given options.  A
+// nil opts uses the defaults.
+func Unmarshal(data []byte, opts *Options) (interface{}, error) {
+	return Decode(bytes.NewReader(data), opts)
+}
+
+// Decode reads an XML document from r and converts it using the given
+// options.  A nil opts uses the defaults.
+func Decode(r io.Reader, opts *Options) (interface{}, error) {
+	o := Options{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.AttrPrefix == "" {
+		o.AttrPrefix = "@"
+	}
+	if o.TextKey == "" {
+		o.TextKey = "#text"
+	}
+	forced := make(map[string]bool, len(o.ForceArray))
+	for _, name := range o.ForceArray {
+		forced[name] = true
+	}
+
+	decoder := xml.NewDecoder(r)
+	root := &element{fields: make(map[string]interface{})}
+	stack := []*element{root}
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		current := stack[len(stack)-1]
+		switch t := tok.(type) {
+		case xml.StartElement:
+			el := &element{name: t.Name.Local, fields: make(map[string]interface{})}
+			for _, attr := range t.Attr {
+				if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
+					continue
+				}
+				el.fields[o.AttrPrefix+attr.Name.Local] = attr.Value
+				el.hasAttrs = true
+			}
+			stack = append(stack, el)
+		case xml.CharData:
+			current.text.Write(t)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+			parent := stack[len(stack)-1]
+			addChild(parent.fields, current.name, o.value(current), forced[current.name])
+		}
+	}
+	if len(root.fields) == 0 {
+		return nil, errors.New("jpxml: no root element")
+	}
+	return root.fields, nil
+}
+
+func (o *Options) value(el *element) interface{} {
+	text := strings.TrimSpace(el.text.String())
+	if len(el.fields) == 0 && o.Text != TextAlways {
+		if text == "" {
+			return nil
+		}
+		return text
+	}
+	if text != "" && (o.Text != TextIgnoreMixed || len(el.fields) == 0) {
+		el.fields[o.TextKey] = text
+	}
+	return el.fields
+}
+
+func addChild(fields map[string]interface{}, name string, value interface{}, forceArray bool) {
+	existing, ok := fields[name]
+	if !ok {
+		if forceArray {
+			fields[name] = []interface{}{value}
+		} else {
+			fields[name] = value
+		}
+		return
+	}
+	if items, ok := existing.([]interface{}); ok {
+		fields[name] = append(items, value)
+		return
+	}
+	fields[name] = []interface{}{existing, value}
+}
@@ -0,0 +1,72 @@
+package jpxml
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jmespath/go-jmespath"
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+const rss = `<?xml version="1.0"?>
+<rss version="2.0">
+  <channel>
+    <title>News</title>
+    <item id="1"><title>First</title></item>
+    <item id="2"><title>Second</title><enclosure url="a.mp3"/></item>
+    <note lang="en">mixed</note>
+    <empty/>
+  </channel>
+</rss>`
+
+func TestDecodeDefaults(t *testing.T) {
+	assert := assert.New(t)
+	doc, err := Decode(strings.NewReader(rss), nil)
+	assert.Nil(err)
+	channel := doc.(map[string]interface{})["rss"].(map[string]interface{})["channel"].(map[string]interface{})
+	assert.Equal("News", channel["title"])
+	assert.Equal(map[string]interface{}{"@lang": "en", "#text": "mixed"}, channel["note"])
+	assert.Nil(channel["empty"])
+
+	result, err := jmespath.Search("rss.channel.item[?\"@id\" == '2'].title", doc)
+	assert.Nil(err)
+	assert.Equal([]interface{}{"Second"}, result)
+	result, err = jmespath.Search("rss.\"@version\"", doc)
+	assert.Nil(err)
+	assert.Equal("2.0", result)
+}
+
+func TestDecodeForceArray(t *testing.T) {
+	assert := assert.New(t)
+	doc, err := Unmarshal([]byte(`<a><b>1</b></a>`), &Options{ForceArray: []string{"b"}})
+	assert.Nil(err)
+	assert.Equal(map[string]interface{}{"a": map[string]interface{}{"b": []interface{}{"1"}}}, doc)
+}
+
+func TestDecodeTextPolicies(t *testing.T) {
+	assert := assert.New(t)
+	input := []byte(`<a x="1">text<b>leaf</b></a>`)
+
+	doc, err := Unmarshal(input, &Options{Text: TextAlways, AttrPrefix: "-", TextKey: "_"})
+	assert.Nil(err)
+	assert.Equal(map[string]interface{}{"a": map[string]interface{}{
+		"-x": "1",
+		"_":  "text",
+		"b":  map[string]interface{}{"_": "leaf"},
+	}}, doc)
+
+	doc, err = Unmarshal(input, &Options{Text: TextIgnoreMixed})
+	assert.Nil(err)
+	assert.Equal(map[string]interface{}{"a": map[string]interface{}{
+		"@x": "1",
+		"b":  "leaf",
+	}}, doc)
+}
+
+func TestDecodeErrors(t *testing.T) {
+	assert := assert.New(t)
+	_, err := Unmarshal([]byte(`<a><b></a>`), nil)
+	assert.NotNil(err)
+	_, err = Unmarshal([]byte(``), nil)
+	assert.NotNil(err)
+}
@@ -0,0 +1,30 @@
+package jmespath
+
+// withKey returns a copy of intr on which key() returns key, to evaluate
+// an expression against the value of key of an object.
+func (intr *treeInterpreter) withKey(key string) *treeInterpreter {
+	keyed := *intr
+	keyed.key = key
+	return &keyed
+}
+
+// callsKey reports whether node calls key().  Object projections only copy
+// the interpreter with withKey for every value if their right side does.
+func callsKey(node ASTNode) bool {
+	if node.nodeType == ASTFunctionExpression && node.value == "key" {
+		return true
+	}
+	for _, child := range node.children {
+		if callsKey(child) {
+			return true
+		}
+	}
+	return false
+}
+
+// jpfKey returns the key of the value an object projection, such as
+// "*.{name: key(), size: size}", map_values or filter_values is
+// evaluating, or null outside of them.
+func jpfKey(arguments []interface{}) (interface{}, error) {
+	return arguments[0].(*treeInterpreter).key, nil
+}
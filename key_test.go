@@ -0,0 +1,62 @@
+package jmespath
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func TestKey(t *testing.T) {
+	assert := assert.New(t)
+	data := decodeJSON(`{
+		"services": {
+			"web": {"port": 80, "hosts": {"a": 1, "b": 2}},
+			"db": {"port": 5432, "hosts": {"c": 3}}
+		},
+		"list": [{"port": 1}]
+	}`)
+	ordered := NewRuntime()
+	ordered.SetOrderedObjects(true)
+	traced := NewRuntime()
+	traced.SetOrderedObjects(true)
+	traced.SetTracer(&recordingTracer{})
+	cases := []struct {
+		expression string
+		expected   interface{}
+	}{
+		{"services.*.key()", []interface{}{"db", "web"}},
+		{"services.*.merge({name: key()}, @) | [*].[name, port]", []interface{}{[]interface{}{"db", 5432.0}, []interface{}{"web", 80.0}}},
+		{"services.*.values(map_values(&join('/', [key(), to_string(@)]), hosts))", []interface{}{[]interface{}{"c/3"}, []interface{}{"a/1", "b/2"}}},
+		{"services.*.{service: key(), hosts: hosts.*.key()}", []interface{}{
+			map[string]interface{}{"service": "db", "hosts": []interface{}{"c"}},
+			map[string]interface{}{"service": "web", "hosts": []interface{}{"a", "b"}},
+		}},
+		{"services.*[key(), key()]", []interface{}{[]interface{}{"db", "db"}, []interface{}{"web", "web"}}},
+		{"services.*.map(&key(), keys(hosts))", []interface{}{[]interface{}{"db"}, []interface{}{"web", "web"}}},
+		{"values(map_values(&key(), services))", []interface{}{"db", "web"}},
+		{"list[*].key()", []interface{}{}},
+		{"key()", nil},
+		{"services.* | [0].key()", nil},
+	}
+	for _, tt := range cases {
+		for _, rt := range []*Runtime{ordered, traced} {
+			result, err := rt.Search(tt.expression, data)
+			assert.Nil(err, tt.expression)
+			assert.Equal(tt.expected, unordered(result), tt.expression)
+		}
+	}
+	_, err := Search("key('a')", data)
+	assert.NotNil(err)
+}
+
+func TestKeyPathsAndStreams(t *testing.T) {
+	assert := assert.New(t)
+	data := decodeJSON(`{"a": {"x": 1}, "b": {"x": 2}}`)
+	located, err := SearchPaths("*.[key() == 'b' && x][]", data)
+	assert.Nil(err)
+	assert.Equal([]LocatedValue{{"/b/x", "b.x", 2.0}}, located)
+	values, err := collectChan(SearchChan(context.Background(), "*.key()", data))
+	assert.Nil(err)
+	assert.Equal([]interface{}{"a", "b"}, values)
+}
@@ -26,6 +26,7 @@ type Lexer struct {
 	currentPos int          // The current position in the string.
 	lastWidth  int          // The width of the current rune.  This
 	buf        bytes.Buffer // Internal buffer used for building up values.
+	previous   tokType      // The type of the last token returned by NextToken.
 }
 
 // SyntaxError is the main error used whenever a lexing or parsing error occurs.
@@ -53,6 +54,7 @@ const (
 	tUnknown tokType = iota
 	tStar
 	tDot
+	tDotDot
 	tFilter
 	tFlatten
 	tLparen
@@ -74,6 +76,8 @@ const (
 	tGTE
 	tEQ
 	tNE
+	tIn
+	tNotIn
 	tJSONLiteral
 	tStringLiteral
 	tCurrent
@@ -81,10 +85,10 @@ const (
 	tAnd
 	tNot
 	tEOF
+	tMacro
 )
 
 var basicTokens = map[rune]tokType{
-	'.': tDot,
 	'*': tStar,
 	',': tComma,
 	':': tColon,
@@ -105,7 +109,7 @@ const identifierStartBits uint64 = 576460745995190270
 var identifierTrailingBits = [2]uint64{287948901175001088, 576460745995190270}
 
 var whiteSpace = map[rune]bool{
-	' ': true, '\t': true, '\n': true, '\r': true, '-': true
+	' ': true, '\t': true, '\n': true, '\r': true,
 }
 
 func (t token) String() string {
@@ -146,73 +150,69 @@ func (lexer *Lexer) tokenize(expression string) ([]token, error) {
 	lexer.expression = expression
 	lexer.currentPos = 0
 	lexer.lastWidth = 0
-loop:
+	for {
+		t, err := lexer.nextToken()
+		if err != nil {
+			return tokens, err
+		}
+		tokens = append(tokens, t)
+		if t.tokenType == tEOF {
+			return tokens, nil
+		}
+	}
+}
+
+// nextToken consumes the whitespace and the token at the current position
+// and returns the token, or a tEOF token at the end of the expression.
+func (lexer *Lexer) nextToken() (t token, err error) {
 	for {
 		r := lexer.next()
 		if identifierStartBits&(1<<(uint64(r)-64)) > 0 {
-			t := lexer.consumeUnquotedIdentifier()
-			tokens = append(tokens, t)
+			t = lexer.consumeUnquotedIdentifier()
 		} else if val, ok := basicTokens[r]; ok {
 			// Basic single char token.
-			t := token{
+			t = token{
 				tokenType: val,
 				value:     string(r),
 				position:  lexer.currentPos - lexer.lastWidth,
 				length:    1,
 			}
-			tokens = append(tokens, t)
-		} else if r >= '0' && r <= '9' {
-			t := lexer.consumeNumber()
-			tokens = append(tokens, t)
+		} else if r == '-' || (r >= '0' && r <= '9') {
+			t = lexer.consumeNumber()
 		} else if r == '[' {
-			t := lexer.consumeLBracket()
-			tokens = append(tokens, t)
+			t = lexer.consumeLBracket()
 		} else if r == '"' {
-			t, err := lexer.consumeQuotedIdentifier()
-			if err != nil {
-				return tokens, err
-			}
-			tokens = append(tokens, t)
+			t, err = lexer.consumeQuotedIdentifier()
 		} else if r == '\'' {
-			t, err := lexer.consumeRawStringLiteral()
-			if err != nil {
-				return tokens, err
-			}
-			tokens = append(tokens, t)
+			t, err = lexer.consumeRawStringLiteral()
 		} else if r == '`' {
-			t, err := lexer.consumeLiteral()
-			if err != nil {
-				return tokens, err
-			}
-			tokens = append(tokens, t)
+			t, err = lexer.consumeLiteral()
+		} else if r == '.' {
+			t = lexer.matchOrElse(r, '.', tDotDot, tDot)
 		} else if r == '|' {
-			t := lexer.matchOrElse(r, '|', tOr, tPipe)
-			tokens = append(tokens, t)
+			t = lexer.matchOrElse(r, '|', tOr, tPipe)
 		} else if r == '<' {
-			t := lexer.matchOrElse(r, '=', tLTE, tLT)
-			tokens = append(tokens, t)
+			t = lexer.matchOrElse(r, '=', tLTE, tLT)
 		} else if r == '>' {
-			t := lexer.matchOrElse(r, '=', tGTE, tGT)
-			tokens = append(tokens, t)
+			t = lexer.matchOrElse(r, '=', tGTE, tGT)
 		} else if r == '!' {
-			t := lexer.matchOrElse(r, '=', tNE, tNot)
-			tokens = append(tokens, t)
+			t = lexer.matchOrElse(r, '=', tNE, tNot)
 		} else if r == '=' {
-			t := lexer.matchOrElse(r, '=', tEQ, tUnknown)
-			tokens = append(tokens, t)
+			t = lexer.matchOrElse(r, '=', tEQ, tUnknown)
 		} else if r == '&' {
-			t := lexer.matchOrElse(r, '&', tAnd, tExpref)
-			tokens = append(tokens, t)
+			t = lexer.matchOrElse(r, '&', tAnd, tExpref)
+		} else if r == '#' {
+			t, err = lexer.consumeMacro()
 		} else if r == eof {
-			break loop
+			t = token{tEOF, "", len(lexer.expression), 0}
 		} else if _, ok := whiteSpace[r]; ok {
 			// Ignore whitespace
+			continue
 		} else {
-			return tokens, lexer.syntaxError(fmt.Sprintf("Unknown char: %s", strconv.QuoteRuneToASCII(r)))
+			err = lexer.syntaxError(fmt.Sprintf("Unknown char: %s", strconv.QuoteRuneToASCII(r)))
 		}
+		return t, err
 	}
-	tokens = append(tokens, token{tEOF, "", len(lexer.expression), 0})
-	return tokens, nil
 }
 
 // Consume characters until the ending rune "r" is reached.
@@ -386,7 +386,7 @@ func (lexer *Lexer) consumeUnquotedIdentifier() token {
 	start := lexer.currentPos - lexer.lastWidth
 	for {
 		r := lexer.next()
-		if r < 0 || r > 128 || identifierTrailingBits[uint64(r)/64]&(1<<(uint64(r)%64)) == 0 {
+		if r < 0 || r >= 128 || identifierTrailingBits[uint64(r)/64]&(1<<(uint64(r)%64)) == 0 {
 			lexer.back()
 			break
 		}
@@ -400,6 +400,23 @@ func (lexer *Lexer) consumeUnquotedIdentifier() token {
 	}
 }
 
+// consumeMacro consumes a reference to a macro, "#" followed by the name
+// of the macro, see Runtime.DefineMacro.
+func (lexer *Lexer) consumeMacro() (token, error) {
+	start := lexer.currentPos - lexer.lastWidth
+	if identifierStartBits&(1<<(uint64(lexer.next())-64)) == 0 {
+		lexer.back()
+		return token{}, lexer.syntaxError("Expected macro name after '#'")
+	}
+	name := lexer.consumeUnquotedIdentifier()
+	return token{
+		tokenType: tMacro,
+		value:     name.value,
+		position:  start,
+		length:    lexer.currentPos - start,
+	}, nil
+}
+
 func (lexer *Lexer) consumeNumber() token {
 	// Consume runes until we reach something that's not a number.
 	start := lexer.currentPos - lexer.lastWidth
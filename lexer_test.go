@@ -28,6 +28,7 @@ var lexingTests = []struct {
 	{"0", []token{{tNumber, "0", 0, 1}}},
 	{"-20", []token{{tNumber, "-20", 0, 3}}},
 	{"foo", []token{{tUnquotedIdentifier, "foo", 0, 3}}},
+	{"#foo", []token{{tMacro, "foo", 0, 4}}},
 	{`"bar"`, []token{{tQuotedIdentifier, "bar", 0, 3}}},
 	// Escaping the delimiter
 	{`"bar\"baz"`, []token{{tQuotedIdentifier, `bar"baz`, 0, 7}}},
@@ -96,6 +97,7 @@ var lexingErrorTests = []struct {
 }{
 	{"'foo", "Missing closing single quote"},
 	{"[?foo==bar?]", "Unknown char '?'"},
+	{"a[?#]", "Expected macro name after '#'"},
 }
 
 func TestLexingErrors(t *testing.T) {
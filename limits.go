@@ -0,0 +1,72 @@
+package jmespath
+
+import "fmt"
+
+// Limits bound the size of the expressions a Runtime compiles, so that
+// compiling expressions from untrusted sources takes bounded time and
+// memory, see Runtime.SetLimits.  A limit of zero means no limit.
+type Limits struct {
+	// MaxLength is the maximum length of an expression in bytes.
+	MaxLength int
+	// MaxTokens is the maximum number of tokens in an expression.
+	MaxTokens int
+	// MaxDepth is the maximum depth to which sub-expressions are nested,
+	// counting parenthesized expressions as well as the levels of the
+	// AST.  Expressions nested more than 1000 levels deep are always
+	// rejected, with a SyntaxError.
+	MaxDepth int
+}
+
+// LimitError is returned when an expression exceeds one of the Limits of
+// the Runtime compiling it.
+type LimitError struct {
+	Expression string // The expression that was rejected.
+	Limit      string // The limit that was exceeded: "length", "tokens" or "depth".
+	Max        int    // The value of the limit.
+}
+
+func (e *LimitError) Error() string {
+	switch e.Limit {
+	case "length":
+		return fmt.Sprintf("expression is longer than %d bytes", e.Max)
+	case "tokens":
+		return fmt.Sprintf("expression has more than %d tokens", e.Max)
+	}
+	return fmt.Sprintf("expression is nested more than %d levels deep", e.Max)
+}
+
+// SetLimits bounds the size of expressions compiled after the call.
+// Expressions that exceed a limit are rejected with a *LimitError before
+// they are parsed in full: the length is checked before the expression is
+// tokenized, the number of tokens before it is parsed, and the depth as it
+// is parsed.
+func (rt *Runtime) SetLimits(limits Limits) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.limits = limits
+}
+
+// checkLength and checkTokens return a *LimitError if the expression being
+// parsed exceeds the length or token limits of p.
+func (p *Parser) checkLength() error {
+	if max := p.limits.MaxLength; max > 0 && len(p.expression) > max {
+		return &LimitError{Expression: p.expression, Limit: "length", Max: max}
+	}
+	return nil
+}
+
+func (p *Parser) checkTokens() error {
+	// The last token always marks the end of the expression.
+	if max := p.limits.MaxTokens; max > 0 && len(p.tokens)-1 > max {
+		return &LimitError{Expression: p.expression, Limit: "tokens", Max: max}
+	}
+	return nil
+}
+
+// depthError returns a *LimitError if depth exceeds the depth limit of p.
+func (p *Parser) depthError(depth int) error {
+	if max := p.limits.MaxDepth; max > 0 && depth > max {
+		return &LimitError{Expression: p.expression, Limit: "depth", Max: max}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package jmespath
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func TestLimits(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		limits     Limits
+		expression string
+		message    string
+	}{
+		{Limits{MaxLength: 5}, "foo.bar", "expression is longer than 5 bytes"},
+		{Limits{MaxTokens: 2}, "foo.bar", "expression has more than 2 tokens"},
+		{Limits{MaxDepth: 2}, "a.b.c", "expression is nested more than 2 levels deep"},
+		{Limits{MaxDepth: 3}, "((((a))))", "expression is nested more than 3 levels deep"},
+		{Limits{MaxDepth: 3}, strings.Repeat("(", 1<<20) + "a" + strings.Repeat(")", 1<<20), "expression is nested more than 3 levels deep"},
+		{Limits{MaxDepth: 3}, "length(length(length(a)))", "expression is nested more than 3 levels deep"},
+	}
+	for _, test := range tests {
+		rt := NewRuntime()
+		rt.SetLimits(test.limits)
+		_, err := rt.Compile(test.expression)
+		var limitErr *LimitError
+		if assert.True(errors.As(err, &limitErr), test.expression) {
+			assert.Equal(test.message, limitErr.Error())
+			assert.Equal(test.expression, limitErr.Expression)
+		}
+	}
+}
+
+func TestWithinLimits(t *testing.T) {
+	assert := assert.New(t)
+	rt := NewRuntime()
+	assert.Nil(rt.Configure(WithLimits(Limits{MaxLength: 7, MaxTokens: 3, MaxDepth: 2})))
+	result, err := rt.Search("foo.bar", map[string]interface{}{"foo": map[string]interface{}{"bar": 1.0}})
+	assert.Nil(err)
+	assert.Equal(1.0, result)
+	// Limits only apply to the runtime they are set on.
+	_, err = Compile("foo.bar.baz")
+	assert.Nil(err)
+}
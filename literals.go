@@ -0,0 +1,52 @@
+package jmespath
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// SetLegacyLiterals sets whether expressions compiled after the call accept
+// the historic JSON literals with elided quotes, such as `foo`, which
+// JMESPath used to read as the string "foo".  When enabled, a JSON literal
+// that is not valid JSON is read as a string of its content without
+// surrounding whitespace, as if it was quoted, as in `"foo"`, unless it
+// starts like a JSON array, object or string, in which case it is still a
+// syntax error.  When
+// disabled, which is the default required by the JMESPath specification,
+// such a literal is a syntax error, which says whether it would be
+// accepted in legacy mode.  New expressions should use raw string
+// literals, such as 'foo', instead.
+func (rt *Runtime) SetLegacyLiterals(legacy bool) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.legacy = legacy
+}
+
+// parseJSONLiteral returns the value of a tJSONLiteral token.
+func (p *Parser) parseJSONLiteral(t token) (interface{}, error) {
+	parsed, err := p.unmarshalLiteral(t.value)
+	if err == nil {
+		return parsed, nil
+	}
+	// Elided quotes are the only legacy form, the content is quoted as
+	// it is, so that escapes such as \n are still read as JSON escapes.
+	content := strings.TrimSpace(t.value)
+	legacy, legacyErr := p.unmarshalLiteral(`"` + content + `"`)
+	switch {
+	case legacyErr != nil, content != "" && strings.ContainsRune(`[{"`, rune(content[0])):
+		return nil, p.syntaxErrorToken("Invalid JSON literal: "+err.Error(), t)
+	case !p.legacyLiterals:
+		return nil, p.syntaxErrorToken("Invalid JSON literal: "+err.Error()+
+			" (strings in JSON literals must be quoted, as in `\"foo\"`, unless legacy literals are enabled)", t)
+	}
+	return legacy, nil
+}
+
+func (p *Parser) unmarshalLiteral(value string) (interface{}, error) {
+	if p.exactIntegers {
+		return unmarshalExact([]byte(value))
+	}
+	var parsed interface{}
+	err := json.Unmarshal([]byte(value), &parsed)
+	return parsed, err
+}
@@ -0,0 +1,68 @@
+package jmespath
+
+import (
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func TestLegacyLiteralsDisabledByDefault(t *testing.T) {
+	assert := assert.New(t)
+	_, err := Compile("`foo`")
+	assert.NotNil(err)
+	syntaxError, ok := err.(SyntaxError)
+	assert.True(ok)
+	assert.Equal(1, syntaxError.Offset)
+	assert.Contains(err.Error(), "Invalid JSON literal: ")
+	assert.Contains(err.Error(), "unless legacy literals are enabled")
+
+	result, err := NewRuntime().Search("`\"foo\"`", nil)
+	assert.Nil(err)
+	assert.Equal("foo", result)
+}
+
+func TestLegacyLiterals(t *testing.T) {
+	assert := assert.New(t)
+	rt := NewRuntime()
+	rt.SetLegacyLiterals(true)
+	data := map[string]interface{}{"name": "foo bar"}
+	tests := []struct {
+		expression string
+		expected   interface{}
+	}{
+		{"`foo`", "foo"},
+		{"` foo bar `", "foo bar"},
+		{"name == `foo bar`", true},
+		{"`a\\nb`", "a\nb"},
+		{"`\"foo\"`", "foo"},
+		{"`[1, 2]`", []interface{}{1.0, 2.0}},
+		{"`true`", true},
+	}
+	for _, test := range tests {
+		result, err := rt.Search(test.expression, data)
+		assert.Nil(err, test.expression)
+		assert.Equal(test.expected, result, test.expression)
+	}
+
+	// Content that cannot be quoted is an error without the hint.
+	_, err := rt.Compile("name == `[1, 2`")
+	assert.NotNil(err)
+	assert.Equal(9, err.(SyntaxError).Offset)
+	assert.NotContains(err.Error(), "legacy")
+	_, err = rt.Compile("`a\"b`")
+	assert.NotNil(err)
+
+	rt.SetLegacyLiterals(false)
+	_, err = rt.Compile("`foo`")
+	assert.NotNil(err)
+}
+
+func TestLegacyLiteralsExactIntegers(t *testing.T) {
+	assert := assert.New(t)
+	rt := NewRuntime()
+	rt.SetIntegerMode(IntegersExact)
+	rt.SetLegacyLiterals(true)
+	result, err := rt.Search("[`9007199254740993`, `foo`]", map[string]interface{}{})
+	assert.Nil(err)
+	assert.Equal([]interface{}{int64(9007199254740993), "foo"}, result)
+}
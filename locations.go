@@ -0,0 +1,340 @@
+package jmespath
+
+import (
+	"strconv"
+	"strings"
+)
+
+/* Locations are found by evaluating the expression on located values,
+   which carry the location of the value in the searched document along
+   with it.  Fields, indexes, slices, recursive descents, projections and
+   flattens select values of the document and extend the location, while
+   every other node is evaluated by the interpreter and computes a value
+   without a location.  Arrays collected by projections, slices, recursive
+   descents and flattens keep the located elements they were collected
+   from, so that indexing or projecting them again still knows where their
+   elements came from.
+*/
+
+// LocatedValue is a value of a search result together with its location
+// in the searched document.
+type LocatedValue struct {
+	// Pointer is the location as a JSON Pointer (RFC 6901), for example
+	// "/people/1/name", or "" for the whole document.
+	Pointer string
+	// Path is the location as an expression, for example
+	// "people[1].name", or "@" for the whole document.
+	Path string
+	// Value is the value at the location.
+	Value interface{}
+}
+
+// SearchPaths evaluates the expression against data like Search, but
+// returns the values it selects from data together with their locations
+// in data, so that the caller can modify or annotate them afterwards.  If
+// the result is an array collected by a projection, slice or flatten, as
+// in "people[?age > `30`]", or a multiselect list, each element is
+// returned with its own location, in the order of the result.  Otherwise
+// the result itself is returned if it is a value of data.  Values computed
+// by the expression, such as the results of functions, comparisons or
+// multiselect hashes, have no location and are left out, as are nulls.
+//
+// The elements of objects projected with "*" are returned in key order
+// unless data contains OrderedMaps, and SearchPaths is slower than Search.
+func (jp *JMESPath) SearchPaths(data interface{}) (_ []LocatedValue, err error) {
+	defer recoverInternal(&err)
+	result, err := jp.intr.locate(jp.ast, located{value: data, path: []interface{}{}})
+	if err != nil {
+		return nil, locateError(jp.expression, err)
+	}
+	return appendLocated([]LocatedValue{}, result), nil
+}
+
+// SearchPaths evaluates a JMESPath expression against data and returns
+// the values it selects from data together with their locations, see
+// JMESPath.SearchPaths.
+func SearchPaths(expression string, data interface{}) ([]LocatedValue, error) {
+	jp, err := Compile(expression)
+	if err != nil {
+		return nil, err
+	}
+	return jp.SearchPaths(data)
+}
+
+type located struct {
+	value interface{}
+	// path is the location of value as object keys and array indexes,
+	// or nil if value is not a value of the document.
+	path []interface{}
+	// elements are the located elements of an array collected by the
+	// expression, and nil for any other value.
+	elements []located
+}
+
+// collect returns the array of the values of elements.
+func collect(elements []located) located {
+	values := make([]interface{}, len(elements))
+	for i, element := range elements {
+		values[i] = element.value
+	}
+	return located{value: values, elements: elements}
+}
+
+// child returns the location of the element key of l, which is a string
+// for objects and an int for arrays.
+func (l located) child(key interface{}) []interface{} {
+	if l.path == nil {
+		return nil
+	}
+	return append(l.path[:len(l.path):len(l.path)], key)
+}
+
+// item returns the located element i of the array l.
+func (l located) item(i int, value interface{}) located {
+	if l.elements != nil {
+		return l.elements[i]
+	}
+	return located{value: value, path: l.child(i)}
+}
+
+// items returns the located elements of l if it is an array.
+func (l located) items() ([]located, bool) {
+	if l.elements != nil {
+		return l.elements, true
+	}
+	if !isSliceType(l.value) {
+		return nil, false
+	}
+	values := toInterfaceSlice(l.value)
+	items := make([]located, len(values))
+	for i, value := range values {
+		items[i] = l.item(i, value)
+	}
+	return items, true
+}
+
+// locate evaluates node against current like Execute, keeping track of
+// the location of the result.
+func (intr *treeInterpreter) locate(node ASTNode, current located) (located, error) {
+	switch node.nodeType {
+	case ASTCurrentNode, ASTIdentity:
+		return current, nil
+	case ASTField:
+		key, err := intr.fieldKey(node.value.(string), current.value)
+		if err != nil {
+			return located{}, err
+		}
+		value, err := intr.Execute(node, current.value)
+		return located{value: value, path: current.child(key)}, err
+	case ASTIndex:
+		value, err := intr.Execute(node, current.value)
+		if err != nil || value == nil {
+			return located{value: value}, err
+		}
+		index := node.value.(int)
+		if index < 0 {
+			index += sliceLen(current.value)
+		}
+		return current.item(index, value), nil
+	case ASTSlice:
+		value, err := intr.Execute(node, current.value)
+		if _, ok := value.(string); err != nil || value == nil || ok {
+			// Substrings are computed values without a location.
+			return located{value: value}, err
+		}
+		bounds, err := sliceBounds(node, sliceLen(current.value))
+		if err != nil {
+			return located{}, err
+		}
+		values := value.([]interface{})
+		elements := make([]located, len(values))
+		for i := range elements {
+			elements[i] = current.item(bounds.Index(i), values[i])
+		}
+		return located{value: value, elements: elements}, nil
+	case ASTSubexpression, ASTIndexExpression, ASTPipe:
+		var err error
+		for _, child := range node.children {
+			if current, err = intr.locate(child, current); err != nil {
+				return located{}, err
+			}
+		}
+		return current, nil
+	case ASTProjection, ASTFilterProjection, ASTValueProjection:
+		return intr.locateProjection(node, current)
+	case ASTDescendant:
+		left, err := intr.locate(node.children[0], current)
+		if err != nil || left.value == nil {
+			return located{}, err
+		}
+		return collect(intr.descend(node.value.(string), left, []located{})), nil
+	case ASTFlatten:
+		left, err := intr.locate(node.children[0], current)
+		if err != nil {
+			return located{}, intr.leftError(err)
+		}
+		if intr.strict {
+			if err := checkArray(node, left.value); err != nil {
+				return located{}, err
+			}
+		}
+		for i := 0; i < flattenDepth(node) && left.value != nil; i++ {
+			left = flattenLocated(left)
+		}
+		return left, nil
+	case ASTOrExpression, ASTAndExpression:
+		left, err := intr.locate(node.children[0], current)
+		if err != nil {
+			return located{}, err
+		}
+		if isFalse(left.value) == (node.nodeType == ASTAndExpression) {
+			return left, nil
+		}
+		return intr.locate(node.children[1], current)
+	case ASTMultiSelectList:
+		if current.value == nil {
+			return located{}, nil
+		}
+		elements := make([]located, len(node.children))
+		for i, child := range node.children {
+			element, err := intr.locate(child, current)
+			if err != nil {
+				return located{}, err
+			}
+			elements[i] = element
+		}
+		return collect(elements), nil
+	}
+	value, err := intr.Execute(node, current.value)
+	return located{value: value}, err
+}
+
+func (intr *treeInterpreter) locateProjection(node ASTNode, current located) (located, error) {
+	left, err := intr.locate(node.children[0], current)
+	if err != nil {
+		if node.nodeType == ASTProjection {
+			return located{}, err
+		}
+		return located{}, intr.leftError(err)
+	}
+	if s, ok := slicedString(node, left.value); node.nodeType == ASTProjection && ok {
+		return intr.locate(node.children[1], located{value: s})
+	}
+	var items []located
+	// keys are the keys of the items of an object projection.
+	var keys []string
+	if node.nodeType == ASTValueProjection {
+		object, ok := intr.toObject(left.value)
+		if !ok {
+			if intr.strict {
+				return located{}, newStrictError(node, ErrNotObject, left.value)
+			}
+			return located{}, nil
+		}
+		keys = objectKeys(left.value, object)
+		for _, key := range keys {
+			items = append(items, located{value: object[key], path: left.child(key)})
+		}
+	} else {
+		var ok bool
+		if items, ok = left.items(); !ok {
+			if intr.strict {
+				return located{}, newStrictError(node, ErrNotArray, left.value)
+			}
+			return located{}, nil
+		}
+	}
+	capture := keys != nil && callsKey(node.children[1])
+	elements := []located{}
+	for i, item := range items {
+		if node.nodeType == ASTFilterProjection {
+			matched, err := intr.Execute(node.children[2], item.value)
+			if err != nil {
+				return located{}, err
+			}
+			if isFalse(matched) {
+				continue
+			}
+		}
+		each := intr
+		if capture {
+			each = intr.withKey(keys[i])
+		}
+		element, err := each.locate(node.children[1], item)
+		if err != nil {
+			return located{}, err
+		}
+		if intr.keeps(element.value) {
+			elements = append(elements, element)
+		}
+	}
+	return collect(elements), nil
+}
+
+// flattenLocated merges the elements of any arrays in l into a single
+// array, like treeInterpreter.flatten.
+func flattenLocated(l located) located {
+	items, ok := l.items()
+	if !ok {
+		return located{}
+	}
+	elements := []located{}
+	for _, item := range items {
+		if nested, ok := item.items(); ok {
+			elements = append(elements, nested...)
+		} else {
+			elements = append(elements, item)
+		}
+	}
+	return collect(elements)
+}
+
+// appendLocated appends the values of l that have a location to values.
+func appendLocated(values []LocatedValue, l located) []LocatedValue {
+	switch {
+	case l.value == nil:
+		return values
+	case l.path != nil:
+		return append(values, LocatedValue{Pointer: formatPointer(l.path), Path: formatLocation(l.path), Value: l.value})
+	}
+	for _, element := range l.elements {
+		values = appendLocated(values, element)
+	}
+	return values
+}
+
+// formatPointer formats path as a JSON Pointer.
+func formatPointer(path []interface{}) string {
+	var b strings.Builder
+	for _, key := range path {
+		b.WriteByte('/')
+		switch k := key.(type) {
+		case string:
+			b.WriteString(strings.Replace(strings.Replace(k, "~", "~0", -1), "/", "~1", -1))
+		case int:
+			b.WriteString(strconv.Itoa(k))
+		}
+	}
+	return b.String()
+}
+
+// formatLocation formats path as an expression, in the form used by
+// NullCause.Location.
+func formatLocation(path []interface{}) string {
+	if len(path) == 0 {
+		return "@"
+	}
+	var b strings.Builder
+	for i, key := range path {
+		switch k := key.(type) {
+		case string:
+			if i > 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString(formatIdentifier(k))
+		case int:
+			b.WriteString("[" + strconv.Itoa(k) + "]")
+		}
+	}
+	return b.String()
+}
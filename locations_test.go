@@ -0,0 +1,95 @@
+package jmespath
+
+import (
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func TestSearchPaths(t *testing.T) {
+	assert := assert.New(t)
+	data := decodeJSON(`{
+		"people": [
+			{"name": "a", "age": 20, "tags": ["x", "y"]},
+			{"name": "b", "age": 40, "tags": ["z"]},
+			{"name": "c", "age": 50, "tags": []}
+		],
+		"a/b": {"~c": 1, "d": 2},
+		"empty": null
+	}`)
+	cases := []struct {
+		expression string
+		pointers   []string
+		paths      []string
+	}{
+		{"people[0].name", []string{"/people/0/name"}, []string{"people[0].name"}},
+		{"people[-1].age", []string{"/people/2/age"}, []string{"people[2].age"}},
+		{"@", []string{""}, []string{"@"}},
+		{"people[?age > `30`]", []string{"/people/1", "/people/2"}, []string{"people[1]", "people[2]"}},
+		{"people[?age > `30`].name", []string{"/people/1/name", "/people/2/name"}, []string{"people[1].name", "people[2].name"}},
+		{"people[?age > `30`] | [1].name", []string{"/people/2/name"}, []string{"people[2].name"}},
+		{"people[1:].tags[0]", []string{"/people/1/tags/0"}, []string{"people[1].tags[0]"}},
+		{"people[::-2].name", []string{"/people/2/name", "/people/0/name"}, []string{"people[2].name", "people[0].name"}},
+		{"people[].tags[]", []string{"/people/0/tags/0", "/people/0/tags/1", "/people/1/tags/0"}, []string{"people[0].tags[0]", "people[0].tags[1]", "people[1].tags[0]"}},
+		{"people[*].tags", []string{"/people/0/tags", "/people/1/tags", "/people/2/tags"}, []string{"people[0].tags", "people[1].tags", "people[2].tags"}},
+		{`"a/b".*`, []string{"/a~1b/d", "/a~1b/~0c"}, []string{`"a/b".d`, `"a/b"."~c"`}},
+		{"[people[0].name, missing, people[1].age]", []string{"/people/0/name", "/people/1/age"}, []string{"people[0].name", "people[1].age"}},
+		{"missing || people[1].name", []string{"/people/1/name"}, []string{"people[1].name"}},
+		{"people[*].length(name)", []string{}, []string{}},
+		{"length(people)", []string{}, []string{}},
+		{"{n: people[0].name}", []string{}, []string{}},
+		{"empty", []string{}, []string{}},
+		{"people[0].name[0:1]", []string{}, []string{}},
+		{"people[*].name[::-1]", []string{}, []string{}},
+	}
+	for _, tt := range cases {
+		located, err := SearchPaths(tt.expression, data)
+		if !assert.Nil(err, tt.expression) {
+			continue
+		}
+		pointers, paths := []string{}, []string{}
+		for _, l := range located {
+			pointers = append(pointers, l.Pointer)
+			paths = append(paths, l.Path)
+			lookedUp, err := lookupPointer(data, mustParsePointer(l.Pointer))
+			assert.Nil(err)
+			assert.Equal(lookedUp, l.Value, tt.expression)
+			searched, err := Search(l.Path, data)
+			assert.Nil(err)
+			assert.Equal(searched, l.Value, tt.expression)
+		}
+		assert.Equal(tt.pointers, pointers, tt.expression)
+		assert.Equal(tt.paths, paths, tt.expression)
+	}
+}
+
+func mustParsePointer(pointer string) []string {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		panic(err)
+	}
+	return tokens
+}
+
+func TestSearchPathsTypedSlices(t *testing.T) {
+	assert := assert.New(t)
+	data := map[string]interface{}{"ids": []int{1, 2, 3}}
+	located, err := SearchPaths("ids[1:]", data)
+	assert.Nil(err)
+	assert.Equal([]LocatedValue{{"/ids/1", "ids[1]", 2}, {"/ids/2", "ids[2]", 3}}, located)
+}
+
+func TestSearchPathsErrors(t *testing.T) {
+	assert := assert.New(t)
+	_, err := SearchPaths("foo[", nil)
+	assert.IsType(SyntaxError{}, err)
+	_, err = SearchPaths("abs(foo)", decodeJSON(`{"foo": "x"}`))
+	assert.NotNil(err)
+
+	rt := NewRuntime()
+	rt.SetStrict(true)
+	jp, err := rt.Compile("foo[*].bar")
+	assert.Nil(err)
+	_, err = jp.SearchPaths(decodeJSON(`{"foo": {}}`))
+	assert.NotNil(err)
+}
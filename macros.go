@@ -0,0 +1,91 @@
+package jmespath
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefineMacro defines a macro, a named expression that the expressions
+// compiled by this runtime after the call can refer to as "#name", so that
+// a fragment used in many expressions is written once:
+//
+//	rt.DefineMacro("running", "state == 'running'")
+//	rt.Search("instances[?#running && size == 'large'].id", data)
+//
+// A reference is replaced by the expression of the macro when an
+// expression is compiled, and evaluates like that expression in
+// parentheses, against the current node.  A macro may refer to macros
+// defined later, but not to itself, directly or through other macros,
+// which is reported as a SyntaxError when an expression using it is
+// compiled.  The name must be an unquoted identifier.  It is an error to
+// define a macro with the same name as an existing macro, and a
+// SyntaxError to define one whose expression is not valid.
+func (rt *Runtime) DefineMacro(name, expression string) error {
+	if !unquotedIdentifier.MatchString(name) {
+		return fmt.Errorf("invalid macro name: %q", name)
+	}
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	if _, ok := rt.macros[name]; ok {
+		return errors.New("macro already defined: " + name)
+	}
+	// The expression is checked on its own, as the macros it refers to
+	// may not be defined yet.
+	parser := NewParser()
+	parser.recursiveDescent = rt.descent
+	parser.legacyLiterals = rt.legacy
+	parser.anyMacro = true
+	if _, err := parser.Parse(expression); err != nil {
+		return err
+	}
+	macros := make(map[string]string, len(rt.macros)+1)
+	for n, e := range rt.macros {
+		macros[n] = e
+	}
+	macros[name] = expression
+	rt.macros = macros
+	return nil
+}
+
+// Macros returns the sorted names of the macros defined in the runtime.
+func (rt *Runtime) Macros() []string {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	names := make([]string, 0, len(rt.macros))
+	for name := range rt.macros {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// expandMacro returns the AST of the expression of the macro token refers
+// to.
+func (p *Parser) expandMacro(t token) (ASTNode, error) {
+	name := t.value
+	if p.anyMacro {
+		return ASTNode{nodeType: ASTCurrentNode}, nil
+	}
+	expression, ok := p.macros[name]
+	if !ok {
+		return ASTNode{}, p.syntaxErrorToken("Unknown macro: #"+name, t)
+	}
+	for i, expanding := range p.expanding {
+		if expanding == name {
+			cycle := append(append([]string{}, p.expanding[i:]...), name)
+			return ASTNode{}, p.syntaxErrorToken("Macro refers to itself: #"+strings.Join(cycle, " -> #"), t)
+		}
+	}
+	expansion := *p
+	expansion.expanding = append(append([]string{}, p.expanding...), name)
+	ast, err := expansion.Parse(expression)
+	if err != nil {
+		if syntaxError, ok := err.(SyntaxError); ok {
+			return ASTNode{}, p.syntaxErrorToken(syntaxError.msg, t)
+		}
+		return ASTNode{}, err
+	}
+	return ast, nil
+}
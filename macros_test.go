@@ -0,0 +1,81 @@
+package jmespath
+
+import (
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func TestMacros(t *testing.T) {
+	assert := assert.New(t)
+	data := map[string]interface{}{
+		"instances": []interface{}{
+			map[string]interface{}{"id": "i-1", "state": "running", "size": "large"},
+			map[string]interface{}{"id": "i-2", "state": "stopped", "size": "large"},
+			map[string]interface{}{"id": "i-3", "state": "running", "size": "small"},
+		},
+	}
+	rt := NewRuntime()
+	assert.Nil(rt.DefineMacro("running", "state == 'running'"))
+	// A macro may refer to macros defined after it.
+	assert.Nil(rt.DefineMacro("active", "[?#running] | [*].id"))
+	assert.Nil(rt.DefineMacro("ident", "id"))
+	assert.Nil(rt.DefineMacro("pair", "a || b"))
+	cases := []struct {
+		expression string
+		expected   interface{}
+	}{
+		{"instances[?#running && size == 'large'].id", []interface{}{"i-1"}},
+		{"instances[?!#running].id", []interface{}{"i-2"}},
+		{"instances | #active", []interface{}{"i-1", "i-3"}},
+		{"instances[0] | #ident", "i-1"},
+		{"length(instances[?#running])", 2.0},
+		{"`{\"a\": false, \"b\": 1}` | #pair == `1`", true},
+	}
+	for _, tt := range cases {
+		result, err := rt.Search(tt.expression, data)
+		assert.Nil(err, tt.expression)
+		assert.Equal(tt.expected, result, tt.expression)
+	}
+	assert.Equal([]string{"active", "ident", "pair", "running"}, rt.Macros())
+
+	_, err := Search("instances[?#running]", data)
+	assert.NotNil(err, "macros are defined per runtime")
+	result, err := SearchWith("instances[?#big].id", data, WithMacro("big", "size == 'large'"))
+	assert.Nil(err)
+	assert.Equal([]interface{}{"i-1", "i-2"}, result)
+}
+
+func TestMacroErrors(t *testing.T) {
+	assert := assert.New(t)
+	rt := NewRuntime()
+	assert.Nil(rt.DefineMacro("a", "foo | #b"))
+	assert.Nil(rt.DefineMacro("b", "bar | #a"))
+	assert.Nil(rt.DefineMacro("self", "[#self]"))
+
+	cases := []struct {
+		expression string
+		msg        string
+		offset     int
+	}{
+		{"#a", "Macro refers to itself: #a -> #b -> #a", 0},
+		{"x | #self", "Macro refers to itself: #self -> #self", 4},
+		{"foo[?#missing]", "Unknown macro: #missing", 5},
+	}
+	for _, tt := range cases {
+		_, err := rt.Compile(tt.expression)
+		if syntaxError, ok := err.(SyntaxError); assert.True(ok, tt.expression) {
+			assert.Equal(tt.msg, syntaxError.msg, tt.expression)
+			assert.Equal(tt.offset, syntaxError.Offset, tt.expression)
+		}
+	}
+
+	assert.NotNil(rt.DefineMacro("a", "baz"), "already defined")
+	assert.NotNil(rt.DefineMacro("not a name", "baz"))
+	assert.NotNil(rt.DefineMacro("", "baz"))
+	_, ok := rt.DefineMacro("broken", "foo[").(SyntaxError)
+	assert.True(ok)
+	assert.Equal([]string{"a", "b", "self"}, rt.Macros())
+	_, err := SearchWith("foo", nil, WithMacro("a", "foo"), WithMacro("a", "bar"))
+	assert.NotNil(err)
+}
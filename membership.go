@@ -0,0 +1,64 @@
+package jmespath
+
+// member reports whether value equals an element of array, or returns nil
+// if array is not an array, for the membership operators "in" and
+// "not in".
+func member(value, array interface{}) interface{} {
+	elements, ok := array.([]interface{})
+	if !ok {
+		if !isSliceType(array) {
+			return nil
+		}
+		elements = toInterfaceSlice(array)
+	}
+	for _, element := range elements {
+		if objsEqual(value, element) {
+			return true
+		}
+	}
+	return false
+}
+
+// valueSet is a set of strings, float64 numbers, booleans and nulls, used
+// for membership tests against the literal arrays of expressions such as
+// "status in `["active", "pending"]`" without comparing the value with
+// every element.
+type valueSet map[interface{}]struct{}
+
+// newValueSet returns the set of the elements of array, or false if array
+// is not an array or has an element of any other type.
+func newValueSet(array interface{}) (valueSet, bool) {
+	elements, ok := array.([]interface{})
+	if !ok {
+		return nil, false
+	}
+	set := make(valueSet, len(elements))
+	for _, element := range elements {
+		switch element.(type) {
+		case string, float64, bool, nil:
+			set[element] = struct{}{}
+		default:
+			return nil, false
+		}
+	}
+	return set, true
+}
+
+// contains reports whether value equals an element of the set, as member
+// does.
+func (s valueSet) contains(value interface{}) bool {
+	switch value.(type) {
+	case string, float64, bool, nil:
+	default:
+		// Other numbers equal a float64 element only if float64
+		// represents them exactly, and arrays and objects never equal an
+		// element.
+		exact, ok := exactNumber(value)
+		if _, isFloat := exact.(float64); !ok || !isFloat {
+			return false
+		}
+		value = exact
+	}
+	_, ok := s[value]
+	return ok
+}
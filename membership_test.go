@@ -0,0 +1,131 @@
+package jmespath
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func TestMembershipOperators(t *testing.T) {
+	assert := assert.New(t)
+	data := map[string]interface{}{
+		"people": []interface{}{
+			map[string]interface{}{"name": "a", "status": "active", "age": 30.0},
+			map[string]interface{}{"name": "b", "status": "closed", "age": 40.0},
+			map[string]interface{}{"name": "c", "status": "pending", "age": 50.0},
+		},
+		"allowed": []interface{}{"active", "pending"},
+		"in":      "field",
+		"not":     map[string]interface{}{"in": "nested"},
+		"ints":    []int{1, 2},
+	}
+	interpreted := NewRuntime()
+	interpreted.SetStrict(true)
+	cases := []struct {
+		expression string
+		expected   interface{}
+	}{
+		{"people[?status in `[\"active\", \"pending\"]`].name", []interface{}{"a", "c"}},
+		{"people[?status not in `[\"active\", \"pending\"]`].name", []interface{}{"b"}},
+		{"people[0].status in allowed", true},
+		{"people[?age in `[40, 50.0]`].name", []interface{}{"b", "c"}},
+		{"people[?name in ['a', 'b'] && age > `30`].name", []interface{}{"b"}},
+		{"people[?!(status in ['active', 'pending'])].name", []interface{}{"b"}},
+		{"!(people[1].status in allowed)", true},
+		{"'x' in `[]`", false},
+		{"`null` in `[null]`", true},
+		{"`[1]` in `[[1], 2]`", true},
+		{"`{\"a\": 1}` in `[{\"a\": 1}]`", true},
+		{"`2` in ints", true},
+		{"`3` not in ints", true},
+		{"in", "field"},
+		{"not.in", "nested"},
+		{"in in ['field']", true},
+		{"not.in not in ['nested']", false},
+		{"people[0].name in allowed", false},
+	}
+	for _, tt := range cases {
+		result, err := Search(tt.expression, data)
+		assert.Nil(err, tt.expression)
+		assert.Equal(tt.expected, result, tt.expression)
+		jp, err := Compile(tt.expression)
+		assert.Nil(err, tt.expression)
+		result, err = jp.Search(data)
+		assert.Nil(err, tt.expression)
+		assert.Equal(tt.expected, result, tt.expression)
+		result, err = interpreted.Search(tt.expression, data)
+		assert.Nil(err, tt.expression)
+		assert.Equal(tt.expected, result, tt.expression)
+	}
+
+	for _, expression := range []string{"'a' in 'abc'", "'a' not in people[0].name"} {
+		result, err := Search(expression, data)
+		assert.Nil(err, expression)
+		assert.Nil(result, expression)
+		_, err = interpreted.Search(expression, data)
+		assert.True(errors.Is(err, ErrNotArray), expression)
+	}
+
+	for _, expression := range []string{"in ['a']", "a in", "a not", "a not in", "[in]"} {
+		_, err := Compile(expression)
+		if expression == "[in]" {
+			assert.Nil(err, expression)
+		} else {
+			assert.NotNil(err, expression)
+		}
+	}
+}
+
+func TestValueSetMatchesMember(t *testing.T) {
+	assert := assert.New(t)
+	var array interface{}
+	assert.Nil(json.Unmarshal([]byte(`["a", 1, 2.5, true, null, 0]`), &array))
+	set, ok := newValueSet(array)
+	assert.True(ok)
+	values := []interface{}{
+		"a", "b", 1.0, 1, uint8(1), int64(1<<53 + 1), 2.5, float32(2.5), 3.0,
+		true, false, nil, -0.0, []interface{}{"a"}, map[string]interface{}{},
+	}
+	for _, value := range values {
+		assert.Equal(member(value, array), set.contains(value), "%T(%v)", value, value)
+	}
+	_, ok = newValueSet([]interface{}{"a", []interface{}{}})
+	assert.False(ok)
+	_, ok = newValueSet("a")
+	assert.False(ok)
+}
+
+func TestFormatMembership(t *testing.T) {
+	assert := assert.New(t)
+	for expression, expected := range map[string]string{
+		"a in b":             "a in b",
+		"a not in `[1, 2]`":  "a not in `[1,2]`",
+		"(a in b) == `true`": "a in b == `true`",
+		"a[?b not in c].d":   "a[?b not in c].d",
+		"a in (b || `[]`)":   "a in (b || `[]`)",
+	} {
+		formatted, err := Format(expression)
+		assert.Nil(err, expression)
+		assert.Equal(expected, formatted)
+
+		ast, err := NewParser().Parse(expression)
+		assert.Nil(err)
+		encoded, err := json.Marshal(ast)
+		assert.Nil(err)
+		var decoded ASTNode
+		assert.Nil(json.Unmarshal(encoded, &decoded), string(encoded))
+		assert.Equal(ast, decoded)
+	}
+}
+
+func TestExplainNullMembership(t *testing.T) {
+	assert := assert.New(t)
+	cause, err := MustCompile("a in b").ExplainNull(map[string]interface{}{"a": 1.0, "b": "x"})
+	assert.Nil(err)
+	if assert.NotNil(cause) {
+		assert.Equal("a in b", cause.SubExpression)
+		assert.Equal("right side of in is not an array", cause.Reason)
+	}
+}
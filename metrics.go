@@ -0,0 +1,58 @@
+package jmespath
+
+import "time"
+
+// Metrics receives measurements of the compilation and evaluation of
+// expressions, to export them to a monitoring system such as Prometheus,
+// OpenTelemetry or expvar, see Runtime.SetMetrics and the jpexpvar
+// package.  Its methods are called synchronously, and from several
+// goroutines at once if expressions are compiled or searched concurrently,
+// so they should be cheap and safe for concurrent use.  The expression is
+// passed as written, so that costs can be reported per expression, but
+// using it as a label is only advisable when the set of expressions is
+// bounded.
+type Metrics interface {
+	// Compiled is called after an expression has been compiled, with the
+	// number of nodes of its optimized AST, zero if it failed to compile
+	// with err.
+	Compiled(expression string, nodes int, duration time.Duration, err error)
+	// Evaluated is called after an expression has been evaluated by
+	// Search, SearchBytes, SearchWithParams, SearchWithTransformer or
+	// Match, with the error the evaluation failed with, if any.
+	Evaluated(expression string, duration time.Duration, err error)
+}
+
+// SetMetrics sets the Metrics that measure the compilation of expressions
+// by this runtime and the evaluation of the expressions compiled after the
+// call, or removes them if metrics is nil.  Expressions compiled without
+// metrics are not slowed down at all.
+func (rt *Runtime) SetMetrics(metrics Metrics) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.metrics = metrics
+}
+
+// observeCompile reports the compilation of expression that started at
+// start, deferred by Runtime.Compile once the result is known.
+func observeCompile(metrics Metrics, expression string, start time.Time, jp **JMESPath, err *error) {
+	nodes := 0
+	if *err == nil {
+		nodes = countNodes((*jp).ast)
+	}
+	metrics.Compiled(expression, nodes, time.Since(start), *err)
+}
+
+// observe reports an evaluation of jp that started at start, deferred by
+// the methods that evaluate jp once the result is known.
+func (jp *JMESPath) observe(start time.Time, err *error) {
+	jp.intr.metrics.Evaluated(jp.expression, time.Since(start), *err)
+}
+
+// countNodes returns the number of nodes of the AST rooted at node.
+func countNodes(node ASTNode) int {
+	count := 1
+	for _, child := range node.children {
+		count += countNodes(child)
+	}
+	return count
+}
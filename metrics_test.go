@@ -0,0 +1,94 @@
+package jmespath
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+type recordedMeasure struct {
+	expression string
+	nodes      int
+	failed     bool
+}
+
+type recordingMetrics struct {
+	mu          sync.Mutex
+	compiled    []recordedMeasure
+	evaluated   []recordedMeasure
+	negativeDur bool
+}
+
+func (m *recordingMetrics) Compiled(expression string, nodes int, duration time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.compiled = append(m.compiled, recordedMeasure{expression, nodes, err != nil})
+	m.negativeDur = m.negativeDur || duration < 0
+}
+
+func (m *recordingMetrics) Evaluated(expression string, duration time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.evaluated = append(m.evaluated, recordedMeasure{expression, 0, err != nil})
+	m.negativeDur = m.negativeDur || duration < 0
+}
+
+func TestMetrics(t *testing.T) {
+	assert := assert.New(t)
+	metrics := &recordingMetrics{}
+	rt := NewRuntime()
+	rt.SetMetrics(metrics)
+
+	_, err := rt.Search("foo.bar", map[string]interface{}{})
+	assert.Nil(err)
+	_, err = rt.Compile("foo[")
+	assert.NotNil(err)
+	jp, err := rt.Compile("abs(foo)")
+	assert.Nil(err)
+	_, err = jp.Search(map[string]interface{}{"foo": "a"})
+	assert.NotNil(err)
+	_, err = jp.Match(map[string]interface{}{"foo": 1.0})
+	assert.Nil(err)
+	number := map[string]interface{}{"foo": -1.0}
+	_, err = jp.SearchWithParams(number, nil)
+	assert.Nil(err)
+	_, err = jp.SearchWithTransformer(number, func(v interface{}) (interface{}, error) { return v, nil })
+	assert.Nil(err)
+	_, err = jp.SearchBytes([]byte(`{"foo": -1}`))
+	assert.Nil(err)
+
+	assert.Equal([]recordedMeasure{
+		{"foo.bar", 3, false},
+		{"foo[", 0, true},
+		{"abs(foo)", 2, false},
+	}, metrics.compiled)
+	assert.Equal([]recordedMeasure{
+		{"foo.bar", 0, false},
+		{"abs(foo)", 0, true},
+		{"abs(foo)", 0, false},
+		{"abs(foo)", 0, false},
+		{"abs(foo)", 0, false},
+		{"abs(foo)", 0, false},
+	}, metrics.evaluated)
+	assert.False(metrics.negativeDur)
+
+	// Expressions compiled before the metrics are removed keep them.
+	rt.SetMetrics(nil)
+	_, err = rt.Search("foo", nil)
+	assert.Nil(err)
+	_, err = jp.Search(number)
+	assert.Nil(err)
+	assert.Len(metrics.compiled, 3)
+	assert.Len(metrics.evaluated, 7)
+}
+
+func TestMetricsOption(t *testing.T) {
+	assert := assert.New(t)
+	metrics := &recordingMetrics{}
+	_, err := SearchWith("a || b", nil, WithMetrics(metrics))
+	assert.Nil(err)
+	assert.Equal([]recordedMeasure{{"a || b", 3, false}}, metrics.compiled)
+	assert.Len(metrics.evaluated, 1)
+}
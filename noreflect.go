@@ -0,0 +1,256 @@
+//go:build jmespath_noreflect
+// +build jmespath_noreflect
+
+package jmespath
+
+import "fmt"
+
+/* This file replaces reflect.go in builds with the jmespath_noreflect tag,
+   for compilers such as TinyGo whose support for reflection is limited.
+   Without reflection, expressions can only be evaluated against the
+   types encoding/json decodes to, OrderedMaps, and slices of strings,
+   numbers, booleans and objects, maps from strings to strings and string
+   slices, and pointers to strings, slices and objects.  Structs, arrays,
+   named types and other slice and map types are treated as scalars that
+   have no fields or elements.
+*/
+
+func isSliceType(v interface{}) bool {
+	switch v.(type) {
+	case []interface{}, []string, []float64, []int, []int64, []bool, []map[string]interface{}:
+		return true
+	}
+	return false
+}
+
+// isArrayType reports whether v is a Go array, which cannot be told
+// without reflection.
+func isArrayType(v interface{}) bool {
+	return false
+}
+
+// namedString returns v as a string if it is of a named string type,
+// which cannot be told without reflection.
+func namedString(v interface{}) (string, bool) {
+	return "", false
+}
+
+// dereference returns the value v points to if it is a non-nil pointer to
+// a slice, map or string of a supported type, which functions treat as
+// the value, and v otherwise.
+func dereference(v interface{}) interface{} {
+	switch p := v.(type) {
+	case *string:
+		if p != nil {
+			return *p
+		}
+	case *[]interface{}:
+		if p != nil {
+			return *p
+		}
+	case *[]string:
+		if p != nil {
+			return *p
+		}
+	case *map[string]interface{}:
+		if p != nil {
+			return *p
+		}
+	}
+	return v
+}
+
+// toInterfaceSlice copies the elements of a supported slice into a
+// []interface{}.
+func toInterfaceSlice(v interface{}) []interface{} {
+	switch s := v.(type) {
+	case []interface{}:
+		return append([]interface{}{}, s...)
+	case []string:
+		return convertSlice(len(s), func(i int) interface{} { return s[i] })
+	case []float64:
+		return convertSlice(len(s), func(i int) interface{} { return s[i] })
+	case []int:
+		return convertSlice(len(s), func(i int) interface{} { return s[i] })
+	case []int64:
+		return convertSlice(len(s), func(i int) interface{} { return s[i] })
+	case []bool:
+		return convertSlice(len(s), func(i int) interface{} { return s[i] })
+	case []map[string]interface{}:
+		return convertSlice(len(s), func(i int) interface{} { return s[i] })
+	}
+	return nil
+}
+
+func convertSlice(n int, element func(i int) interface{}) []interface{} {
+	result := make([]interface{}, n)
+	for i := range result {
+		result[i] = element(i)
+	}
+	return result
+}
+
+// sliceLen returns the length of a supported slice.
+func sliceLen(v interface{}) int {
+	switch s := v.(type) {
+	case []interface{}:
+		return len(s)
+	case []string:
+		return len(s)
+	case []float64:
+		return len(s)
+	case []int:
+		return len(s)
+	case []int64:
+		return len(s)
+	case []bool:
+		return len(s)
+	case []map[string]interface{}:
+		return len(s)
+	}
+	return 0
+}
+
+// sliceIndex returns the element at index i of a supported slice.
+func sliceIndex(v interface{}, i int) interface{} {
+	if s, ok := v.([]interface{}); ok {
+		return s[i]
+	}
+	return toInterfaceSlice(v)[i]
+}
+
+// isMultiValueMap reports whether value is a map from strings to string
+// slices.  Named types such as url.Values cannot be told without
+// reflection.
+func isMultiValueMap(value interface{}) bool {
+	_, ok := value.(map[string][]string)
+	return ok
+}
+
+// isStringKeyedMap reports whether value is a map with string keys of a
+// supported type.
+func isStringKeyedMap(value interface{}) bool {
+	switch value.(type) {
+	case map[string]interface{}, map[string]string, map[string][]string:
+		return true
+	}
+	return false
+}
+
+// mapField looks up key in a map with string keys of a supported type.
+func mapField(key string, value interface{}) interface{} {
+	var v interface{}
+	var found bool
+	switch m := value.(type) {
+	case map[string]interface{}:
+		v, found = m[key]
+	case map[string]string:
+		v, found = m[key]
+	case map[string][]string:
+		v, found = m[key]
+	}
+	if !found {
+		return nil
+	}
+	return v
+}
+
+// mapEntries calls fn with each key and value of a map with string keys
+// of a supported type.
+func mapEntries(value interface{}, fn func(key string, v interface{})) {
+	switch m := value.(type) {
+	case map[string]interface{}:
+		for key, v := range m {
+			fn(key, v)
+		}
+	case map[string]string:
+		for key, v := range m {
+			fn(key, v)
+		}
+	case map[string][]string:
+		for key, v := range m {
+			fn(key, v)
+		}
+	}
+}
+
+// structField reports that value is not a struct, as structs cannot be
+// read without reflection.
+func structField(key string, value interface{}) (field interface{}, found bool, isStruct bool) {
+	return nil, false, false
+}
+
+// isGoContainer reports whether value is a supported slice or map, or
+// nil.
+func isGoContainer(value interface{}) bool {
+	return value == nil || isSliceType(value) || isStringKeyedMap(value)
+}
+
+// isFalseGo reports whether a Go value of a type isFalse does not handle
+// itself is false: an empty supported slice or map, or a nil or false
+// supported pointer.
+func isFalseGo(value interface{}) bool {
+	if isSliceType(value) {
+		return sliceLen(value) == 0
+	}
+	switch v := value.(type) {
+	case map[string]string:
+		return len(v) == 0
+	case map[string][]string:
+		return len(v) == 0
+	case *string, *[]interface{}, *[]string, *map[string]interface{}:
+		deref := dereference(v)
+		return deref == value || isFalse(deref)
+	}
+	return false
+}
+
+// sameContainer reports whether a and b are the same array or object.
+func sameContainer(a, b interface{}) bool {
+	switch x := a.(type) {
+	case []interface{}:
+		y, ok := b.([]interface{})
+		return ok && len(x) == len(y) && len(x) > 0 && &x[0] == &y[0]
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		return ok && fmt.Sprintf("%p", x) == fmt.Sprintf("%p", y)
+	case *OrderedMap:
+		return x == b
+	}
+	return false
+}
+
+// deepEqual reports whether a and b are deeply equal, as
+// reflect.DeepEqual does for the supported types.
+func deepEqual(a, b interface{}) bool {
+	switch x := a.(type) {
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !deepEqual(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for key, v := range x {
+			if w, found := y[key]; !found || !deepEqual(v, w) {
+				return false
+			}
+		}
+		return true
+	case nil, bool, string, float64, int, int64:
+		return a == b
+	}
+	if isSliceType(a) && isSliceType(b) {
+		return deepEqual(toInterfaceSlice(a), toInterfaceSlice(b))
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+//go:build jmespath_noreflect
+// +build jmespath_noreflect
+
+package jmespath
+
+import (
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func TestNoReflectSupportedTypes(t *testing.T) {
+	assert := assert.New(t)
+	name := "x"
+	data := map[string]interface{}{
+		"strings": []string{"b", "a"},
+		"numbers": []float64{1, 2},
+		"ints":    []int{3, 4},
+		"items":   []map[string]interface{}{{"id": "a"}, {"id": "b"}},
+		"labels":  map[string]string{"env": "prod"},
+		"headers": map[string][]string{"Accept": {"text/plain"}},
+		"name":    &name,
+	}
+	cases := []struct {
+		expression string
+		expected   interface{}
+	}{
+		{"sort(strings)", []interface{}{"a", "b"}},
+		{"strings[-1]", "a"},
+		{"numbers[::-1]", []interface{}{2.0, 1.0}},
+		{"length(ints)", 2.0},
+		{"items[?id == 'b'].id", []interface{}{"b"}},
+		{"[strings, ints][]", []interface{}{"b", "a", 3, 4}},
+		{"labels.env", "prod"},
+		{"headers.Accept", []interface{}{"text/plain"}},
+		{"length(name)", 1.0},
+		{"!missing_list && !`[]`", true},
+	}
+	for _, tt := range cases {
+		result, err := Search(tt.expression, data)
+		assert.Nil(err, tt.expression)
+		assert.Equal(tt.expected, result, tt.expression)
+	}
+}
+
+func TestNoReflectUnsupportedTypesAreScalars(t *testing.T) {
+	assert := assert.New(t)
+	type person struct{ Name string }
+	data := map[string]interface{}{"person": person{Name: "x"}, "array": [2]int{1, 2}}
+	result, err := Search("person.name", data)
+	assert.Nil(err)
+	assert.Nil(result)
+	result, err = Search("array[0]", data)
+	assert.Nil(err)
+	assert.Nil(result)
+}
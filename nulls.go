@@ -0,0 +1,255 @@
+package jmespath
+
+import (
+	"fmt"
+	"strconv"
+)
+
+/* Null causes are found by walking the expression and remembering, for
+   every null a node evaluates to, the node that originally produced it.
+   Nodes such as subexpressions or fields of null pass a null on from the
+   node evaluated before them, so they keep the cause of that null, while
+   nodes such as a field missing from an object start a new one.
+*/
+
+// NullCause explains why an expression evaluated to null.
+type NullCause struct {
+	// SubExpression is the part of the expression that first produced
+	// the null, in canonical form.
+	SubExpression string
+	// Reason describes why it produced null, for example "key 'foo'
+	// not found".
+	Reason string
+	// Location is the location in the searched document of the value
+	// the sub-expression was evaluated against, for example
+	// "instances[3]".  It is empty if the value is not an array or
+	// object of the document, as other values cannot be told apart from
+	// equal values elsewhere in the document.
+	Location string
+}
+
+func (c *NullCause) String() string {
+	s := c.SubExpression + ": " + c.Reason
+	if c.Location != "" {
+		s += " at " + c.Location
+	}
+	return s
+}
+
+// ExplainNull evaluates the expression against data, like Search, and if
+// the result is null returns the cause of the null.  It returns a nil
+// NullCause if the result is not null.  ExplainNull is much slower than
+// Search and is intended for debugging expressions.
+func (jp *JMESPath) ExplainNull(data interface{}) (_ *NullCause, err error) {
+	defer recoverInternal(&err)
+	e := nullExplainer{intr: jp.intr}
+	w := walker{intr: jp.intr, exit: e.exit}
+	ast := jp.ast
+	result, err := w.eval(&ast, data)
+	if err != nil {
+		return nil, locateError(jp.expression, err)
+	}
+	if result != nil {
+		return nil, nil
+	}
+	if e.cause == nil {
+		return &NullCause{SubExpression: FormatAST(jp.ast), Reason: "the document is null", Location: "@"}, nil
+	}
+	cause := &NullCause{SubExpression: FormatAST(e.cause.node), Reason: e.cause.reason}
+	if e.cause.value != nil {
+		cause.Location, _ = locateValue(data, e.cause.value, "@")
+	}
+	return cause, nil
+}
+
+// Lookup is like Search, but also reports whether the result was found in
+// the document, which tells a key whose value is null apart from a key
+// that is missing.  found is false if the result is null because the
+// expression reads a key or index missing from the document, reads a key,
+// index or slice of a value that is not an object or array, or reads
+// through a null, as a.b does if a is null.  It is true for any other
+// result, including nulls of the document and nulls produced by the
+// expression, such as by a function or a comparison.  Finding out why a
+// result is null is much slower than evaluating the expression, which
+// Lookup only does for null results.
+func (jp *JMESPath) Lookup(data interface{}) (value interface{}, found bool, err error) {
+	value, err = jp.Search(data)
+	if err != nil || value != nil {
+		return value, err == nil, err
+	}
+	defer recoverInternal(&err)
+	found, err = jp.intr.found(jp.ast, data)
+	return nil, found, locateError(jp.expression, err)
+}
+
+// Lookup compiles the expression and looks it up in data, see
+// JMESPath.Lookup.
+func Lookup(expression string, data interface{}) (interface{}, bool, error) {
+	jp, err := Compile(expression)
+	if err != nil {
+		return nil, false, err
+	}
+	return jp.Lookup(data)
+}
+
+// found reports whether the result of node evaluated against value was
+// found in the document, as Lookup tells.
+func (intr *treeInterpreter) found(node ASTNode, value interface{}) (bool, error) {
+	e := nullExplainer{intr: intr}
+	w := walker{intr: intr, exit: e.exit}
+	result, err := w.eval(&node, value)
+	if err != nil {
+		return false, err
+	}
+	return result != nil || !e.navigated && (e.cause == nil || !e.cause.absent), nil
+}
+
+type nullExplainer struct {
+	intr *treeInterpreter
+	// cause is the cause of the most recent null.
+	cause *pendingNullCause
+	// navigated is set when a null has been passed on by a node reading
+	// from it since the most recent cause, such as a field of null.
+	navigated bool
+	// last is the result of the most recently evaluated node.
+	last interface{}
+}
+
+// pendingNullCause is a null cause whose location is only looked up if it
+// turns out to be the cause of the result.
+type pendingNullCause struct {
+	node   ASTNode
+	reason string
+	value  interface{}
+	// absent is set when the null is not a value of the document, such
+	// as for a missing key.
+	absent bool
+}
+
+func (e *nullExplainer) exit(node *ASTNode, value, result interface{}, err error) (interface{}, error) {
+	last := e.last
+	e.last = result
+	if err == nil && result == nil {
+		if cause := e.explain(*node, value, last); cause != nil {
+			e.cause, e.navigated = cause, false
+		} else if readsFrom(*node) {
+			e.navigated = true
+		}
+	}
+	return result, err
+}
+
+// explain returns the cause of node evaluating to null against value, or
+// nil if node only passed on an earlier null.  last is the result of the
+// node evaluated before node finished, which for projections is their left
+// side.
+func (e *nullExplainer) explain(node ASTNode, value, last interface{}) *pendingNullCause {
+	cause := func(reason string, value interface{}) *pendingNullCause {
+		return &pendingNullCause{node: node, reason: reason, value: value}
+	}
+	missing := func(reason string, value interface{}) *pendingNullCause {
+		return &pendingNullCause{node: node, reason: reason, value: value, absent: true}
+	}
+	switch node.nodeType {
+	case ASTField:
+		key := node.value.(string)
+		if value == nil {
+			return nil
+		}
+		if object, ok := e.intr.toObject(value); ok {
+			if _, found := object[key]; found {
+				return cause("key '"+key+"' is null", value)
+			}
+			return missing("key '"+key+"' not found", value)
+		}
+		return missing("cannot read key '"+key+"' of "+jsonType(value), value)
+	case ASTIndex:
+		if value == nil {
+			return nil
+		}
+		if !isSliceType(value) {
+			return missing("cannot index "+jsonType(value), value)
+		}
+		index, length := node.value.(int), sliceLen(value)
+		if index < -length || index >= length {
+			return missing(fmt.Sprintf("index %d out of range for array of length %d", index, length), value)
+		}
+		return cause("element "+strconv.Itoa(index)+" is null", value)
+	case ASTSlice:
+		if value == nil {
+			return nil
+		}
+		return missing("cannot slice "+jsonType(value), value)
+	case ASTProjection, ASTFilterProjection, ASTFlatten:
+		if last == nil {
+			return nil
+		}
+		return missing(jsonType(last)+" is not an array", last)
+	case ASTValueProjection:
+		if last == nil {
+			return nil
+		}
+		return missing(jsonType(last)+" is not an object", last)
+	case ASTComparator:
+		if op := node.value.(tokType); op == tIn || op == tNotIn {
+			return cause("right side of "+comparatorSymbol(op)+" is not an array", nil)
+		}
+		return cause("compared values are not both numbers", nil)
+	case ASTFunctionExpression:
+		return cause("function "+node.value.(string)+" returned null", nil)
+	case ASTLiteral:
+		return cause("literal null", nil)
+	case ASTParameter:
+		return cause("parameter :"+node.value.(string)+" is null", nil)
+	}
+	return nil
+}
+
+// readsFrom reports whether node reads a key, index or element of the
+// value it is evaluated against, or of its left side.
+func readsFrom(node ASTNode) bool {
+	switch node.nodeType {
+	case ASTField, ASTIndex, ASTSlice, ASTProjection, ASTFilterProjection, ASTFlatten, ASTValueProjection:
+		return true
+	}
+	return false
+}
+
+// locateValue returns the location of target within value, which is at
+// location path, comparing arrays and objects by identity.  Other values
+// cannot be located.
+func locateValue(value, target interface{}, path string) (string, bool) {
+	if sameContainer(value, target) {
+		return path, true
+	}
+	child := func(suffix string) string {
+		if path == "@" {
+			if suffix[0] == '.' {
+				return suffix[1:]
+			}
+			return suffix
+		}
+		return path + suffix
+	}
+	switch v := value.(type) {
+	case []interface{}:
+		for i, element := range v {
+			if location, ok := locateValue(element, target, child("["+strconv.Itoa(i)+"]")); ok {
+				return location, true
+			}
+		}
+	case map[string]interface{}:
+		for key, element := range v {
+			if location, ok := locateValue(element, target, child("."+formatIdentifier(key))); ok {
+				return location, true
+			}
+		}
+	case *OrderedMap:
+		for _, key := range v.keys {
+			if location, ok := locateValue(v.values[key], target, child("."+formatIdentifier(key))); ok {
+				return location, true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,110 @@
+package jmespath
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func TestExplainNull(t *testing.T) {
+	assert := assert.New(t)
+	var data interface{}
+	err := json.Unmarshal([]byte(`{
+		"instances": [
+			{"id": "a", "tags": {"env": "prod"}},
+			{"id": "b", "tags": {"env": null}},
+			{"id": "c", "tags": "none"}
+		],
+		"count": 3
+	}`), &data)
+	assert.Nil(err)
+	cases := []struct {
+		expression string
+		expected   string
+	}{
+		{"missing.foo.bar", "missing: key 'missing' not found at @"},
+		{"instances[0].tags.owner", "owner: key 'owner' not found at instances[0].tags"},
+		{"instances[1].tags.env", "env: key 'env' is null at instances[1].tags"},
+		{"instances[2].tags.env", "env: cannot read key 'env' of string"},
+		{"instances[5].id", "[5]: index 5 out of range for array of length 3 at instances"},
+		{"count[0]", "[0]: cannot index number"},
+		{"instances[0].tags[*].env", "tags[*].env: object is not an array at instances[0].tags"},
+		{"count.*", "count.*: number is not an object"},
+		{"max(instances[].id) > `1`", "max(instances[].id) > `1`: compared values are not both numbers"},
+		{"not_null(missing)", "not_null(missing): function not_null returned null"},
+		{"missing || instances[9]", "[9]: index 9 out of range for array of length 3 at instances"},
+		{"instances[?id == 'z'] | [0]", "[0]: index 0 out of range for array of length 0"},
+	}
+	for _, tt := range cases {
+		cause, err := MustCompile(tt.expression).ExplainNull(data)
+		assert.Nil(err, tt.expression)
+		if assert.NotNil(cause, tt.expression) {
+			assert.Equal(tt.expected, cause.String(), tt.expression)
+		}
+	}
+}
+
+func TestExplainNullNotNull(t *testing.T) {
+	assert := assert.New(t)
+	cause, err := MustCompile("a").ExplainNull(map[string]interface{}{"a": false})
+	assert.Nil(err)
+	assert.Nil(cause)
+
+	cause, err = MustCompile("@").ExplainNull(nil)
+	assert.Nil(err)
+	assert.Equal(&NullCause{SubExpression: "@", Reason: "the document is null", Location: "@"}, cause)
+
+	_, err = MustCompile("length(a)").ExplainNull(nil)
+	assert.NotNil(err)
+}
+
+func TestLookup(t *testing.T) {
+	assert := assert.New(t)
+	var data interface{}
+	err := json.Unmarshal([]byte(`{
+		"name": "a",
+		"owner": null,
+		"tags": ["x", null],
+		"spec": {"replicas": 0}
+	}`), &data)
+	assert.Nil(err)
+	cases := []struct {
+		expression string
+		value      interface{}
+		found      bool
+	}{
+		{"name", "a", true},
+		{"spec.replicas", 0.0, true},
+		{"owner", nil, true},
+		{"tags[1]", nil, true},
+		{"missing", nil, false},
+		{"spec.missing", nil, false},
+		{"tags[5]", nil, false},
+		{"name.first", nil, false},
+		{"owner.name", nil, false},
+		{"missing[0].name", nil, false},
+		{"spec[*]", nil, false},
+		{"missing[*].name", nil, false},
+		{"owner || missing", nil, false},
+		{"missing || owner", nil, true},
+		{"not_null(missing)", nil, true},
+		{"`null`", nil, true},
+		{"tags[?@ == 'y']", []interface{}{}, true},
+	}
+	for _, tt := range cases {
+		value, found, err := Lookup(tt.expression, data)
+		assert.Nil(err, tt.expression)
+		assert.Equal(tt.value, value, tt.expression)
+		assert.Equal(tt.found, found, tt.expression)
+	}
+	_, found, err := Lookup("@", nil)
+	assert.Nil(err)
+	assert.True(found)
+	_, found, err = Lookup("a", nil)
+	assert.Nil(err)
+	assert.False(found)
+	_, found, err = Lookup("abs(name)", data)
+	assert.NotNil(err)
+	assert.False(found)
+}
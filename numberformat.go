@@ -0,0 +1,173 @@
+package jmespath
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// NumberFormat controls how numbers are written as text by to_string,
+// to_json and templates, see Runtime.SetNumberFormat, and by an Encoder,
+// see Encoder.SetNumberFormat.  The zero value writes numbers as
+// encoding/json does, with an exponent for very large and very small
+// numbers, as in "1e+21" and "1e-7".
+type NumberFormat struct {
+	// NoExponent writes numbers in plain decimal notation, 1e21 as
+	// "1000000000000000000000" and 1e-7 as "0.0000001".
+	NoExponent bool
+	// MaxDecimals, if positive, rounds numbers to at most this many digits
+	// after the decimal point, so that 0.1+0.2 is written as "0.3" rather
+	// than "0.30000000000000004" with a MaxDecimals of 2.  Trailing zeros
+	// are not written.
+	MaxDecimals int
+	// JSONNumbers keeps the numbers of the documents decoded by
+	// SearchBytes, SearchReader and SearchEachNDJSON as json.Number
+	// values, which are returned as they are when an expression selects
+	// them, so that numbers such as 1.10 or 1e3 are written as they were
+	// read, and are not formatted.  They are numbers for comparisons and
+	// functions, whose results are formatted.  It has no effect on an
+	// Encoder.
+	JSONNumbers bool
+}
+
+// SetNumberFormat sets how expressions compiled after the call write
+// numbers as text, see NumberFormat.  Values of the json.Number type are
+// numbers whatever the format.
+func (rt *Runtime) SetNumberFormat(format NumberFormat) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.numbers = format
+}
+
+// formats reports whether f writes numbers differently than
+// encoding/json.
+func (f NumberFormat) formats() bool {
+	return f.NoExponent || f.MaxDecimals > 0
+}
+
+// format returns the text of the float64 v in format f.
+func (f NumberFormat) format(v float64) string {
+	if f.MaxDecimals > 0 && !math.IsInf(v, 0) && !math.IsNaN(v) {
+		v, _ = strconv.ParseFloat(strconv.FormatFloat(v, 'f', f.MaxDecimals, 64), 64)
+		if v == 0 {
+			// Small negative numbers round to -0.
+			v = 0
+		}
+	}
+	if f.NoExponent {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return string(encoded)
+}
+
+// formatNumbers returns a copy of value with the floating point numbers in
+// it replaced by json.Number values of their text in format f, which
+// encoding/json writes as they are.  Integers of other types are written
+// without exponents or decimals already, and json.Number values are kept
+// as they are.
+func (f NumberFormat) formatNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		formatted := make([]interface{}, len(v))
+		for i, element := range v {
+			formatted[i] = f.formatNumbers(element)
+		}
+		return formatted
+	case map[string]interface{}:
+		formatted := make(map[string]interface{}, len(v))
+		for key, element := range v {
+			formatted[key] = f.formatNumbers(element)
+		}
+		return formatted
+	case *OrderedMap:
+		formatted := NewOrderedMap()
+		for _, key := range v.keys {
+			formatted.Set(key, f.formatNumbers(v.values[key]))
+		}
+		return formatted
+	case json.Number:
+		return v
+	}
+	if n := asNumber(value); n.kind == floatNumber {
+		return json.Number(f.format(n.f))
+	}
+	return value
+}
+
+// numberFormatFunctions replaces the built-in functions that write numbers
+// as text when a NumberFormat is set.
+var numberFormatFunctions = map[string]functionEntry{
+	"to_string": {
+		name: "to_string",
+		arguments: []argSpec{
+			{types: []jpType{jpAny}},
+		},
+		handler:   jpfFormattedToString,
+		hasExpRef: true,
+	},
+	"to_json": {
+		name: "to_json",
+		arguments: []argSpec{
+			{types: []jpType{jpAny}},
+		},
+		handler:   jpfFormattedToJSON,
+		hasExpRef: true,
+	},
+}
+
+func jpfFormattedToString(arguments []interface{}) (interface{}, error) {
+	intr := arguments[0].(*treeInterpreter)
+	if v, ok := arguments[1].(string); ok {
+		return v, nil
+	}
+	return jpfToString([]interface{}{intr.numbers.formatNumbers(arguments[1])})
+}
+
+func jpfFormattedToJSON(arguments []interface{}) (interface{}, error) {
+	intr := arguments[0].(*treeInterpreter)
+	return jpfToJSON([]interface{}{intr.numbers.formatNumbers(arguments[1])})
+}
+
+// unmarshalJSONNumbers decodes the JSON text data like json.Unmarshal, but
+// with numbers as json.Number values.
+func unmarshalJSONNumbers(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, errors.New("invalid JSON: data after top-level value")
+	}
+	return value, nil
+}
+
+// parseJSONNumber returns the json.Number n as a number: an integer as an
+// int64 if it fits or a *big.Int otherwise, and other numbers as a
+// float64.  It returns false if n is not a valid number.
+func parseJSONNumber(n json.Number) (number, bool) {
+	text := string(n)
+	if !strings.ContainsAny(text, ".eE") {
+		if i, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return number{kind: signedNumber, i: i}, true
+		}
+		if b, ok := new(big.Int).SetString(text, 10); ok {
+			return number{kind: bigNumber, b: b}, true
+		}
+		return number{}, false
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return number{}, false
+	}
+	return number{kind: floatNumber, f: f}, true
+}
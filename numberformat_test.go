@@ -0,0 +1,130 @@
+package jmespath
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func TestNumberFormat(t *testing.T) {
+	assert := assert.New(t)
+	cases := []struct {
+		format   NumberFormat
+		value    float64
+		expected string
+	}{
+		{NumberFormat{}, 1e6, "1000000"},
+		{NumberFormat{}, 1e21, "1e+21"},
+		{NumberFormat{}, 1e-7, "1e-7"},
+		{NumberFormat{NoExponent: true}, 1e21, "1000000000000000000000"},
+		{NumberFormat{NoExponent: true}, 1e-7, "0.0000001"},
+		{NumberFormat{NoExponent: true}, -2.5, "-2.5"},
+		{NumberFormat{MaxDecimals: 2}, 0.1 + 0.2, "0.3"},
+		{NumberFormat{MaxDecimals: 2}, 2.0 / 3, "0.67"},
+		{NumberFormat{MaxDecimals: 2}, 5, "5"},
+		{NumberFormat{MaxDecimals: 2}, -0.001, "0"},
+		{NumberFormat{MaxDecimals: 2}, 1e-7, "0"},
+		{NumberFormat{MaxDecimals: 3, NoExponent: true}, 1e21 + 0.5, "1000000000000000000000"},
+	}
+	for _, tt := range cases {
+		assert.Equal(tt.expected, tt.format.format(tt.value), tt.expected)
+	}
+}
+
+func TestToStringNumberFormat(t *testing.T) {
+	assert := assert.New(t)
+	data := map[string]interface{}{
+		"big":    1e21,
+		"small":  1e-7,
+		"ratio":  2.0 / 3,
+		"count":  int64(3),
+		"nested": map[string]interface{}{"values": []interface{}{1e21, "1e21", 0.5}},
+	}
+	result, err := Search("[to_string(big), to_string(small)]", data)
+	assert.Nil(err)
+	assert.Equal([]interface{}{"1e+21", "1e-7"}, result)
+
+	rt := NewRuntime()
+	rt.SetNumberFormat(NumberFormat{NoExponent: true, MaxDecimals: 2})
+	result, err = rt.Search("[to_string(big), to_string(small), to_string(ratio), to_string(count), to_string('x')]", data)
+	assert.Nil(err)
+	assert.Equal([]interface{}{"1000000000000000000000", "0", "0.67", "3", "x"}, result)
+	result, err = rt.Search("to_json(nested)", data)
+	assert.Nil(err)
+	assert.Equal(`{"values":[1000000000000000000000,"1e21",0.5]}`, result)
+	result, err = rt.Search("to_string(nested)", data)
+	assert.Nil(err)
+	assert.Equal(`{"values":[1000000000000000000000,"1e21",0.5]}`, result)
+	// Other results are not formatted.
+	result, err = rt.Search("ratio", data)
+	assert.Nil(err)
+	assert.Equal(2.0/3, result)
+
+	result, err = SearchWith("to_string(big)", data, WithNumberFormat(NumberFormat{NoExponent: true}))
+	assert.Nil(err)
+	assert.Equal("1000000000000000000000", result)
+
+	template, err := rt.CompileTemplate("{big} {ratio}")
+	assert.Nil(err)
+	rendered, err := template.Render(data)
+	assert.Nil(err)
+	assert.Equal("1000000000000000000000 0.67", rendered)
+}
+
+func TestEncoderNumberFormat(t *testing.T) {
+	assert := assert.New(t)
+	var b bytes.Buffer
+	enc := NewEncoder(&b)
+	enc.SetNumberFormat(NumberFormat{NoExponent: true})
+	assert.Nil(enc.Encode(map[string]interface{}{"n": 1e21, "s": "x", "i": uint64(1 << 63), "j": json.Number("1.10")}))
+	assert.Nil(enc.Encode(1e-7))
+	assert.Equal("{\"i\":9223372036854775808,\"j\":1.10,\"n\":1000000000000000000000,\"s\":\"x\"}\n0.0000001\n", b.String())
+}
+
+func TestJSONNumbers(t *testing.T) {
+	assert := assert.New(t)
+	document := []byte(`{"price": 1.10, "qty": 1e3, "id": 12345678901234567890, "items": [{"n": 2.50}, {"n": 10}]}`)
+	rt := NewRuntime()
+	rt.SetNumberFormat(NumberFormat{JSONNumbers: true})
+	result, err := rt.SearchBytes("{price: price, qty: qty, id: id}", document)
+	assert.Nil(err)
+	encoded, err := json.Marshal(result)
+	assert.Nil(err)
+	assert.Equal(`{"id":12345678901234567890,"price":1.10,"qty":1e3}`, string(encoded))
+
+	cases := []struct {
+		expression string
+		expected   interface{}
+	}{
+		{"price > `1.05`", true},
+		{"qty == `1000`", true},
+		{"id > `1`", true},
+		{"items[?n > `2`].n", []interface{}{json.Number("2.50"), json.Number("10")}},
+		{"sum(items[*].n)", 12.5},
+		{"type(price)", "number"},
+		{"to_string(price)", "1.1"},
+		{"max_by(items, &n).n", json.Number("10")},
+	}
+	for _, tt := range cases {
+		result, err := rt.SearchBytes(tt.expression, document)
+		assert.Nil(err, tt.expression)
+		assert.Equal(tt.expected, result, tt.expression)
+	}
+
+	rt.SetOrderedObjects(true)
+	result, err = rt.SearchBytes("@", document)
+	assert.Nil(err)
+	encoded, err = json.Marshal(result)
+	assert.Nil(err)
+	assert.Equal(`{"price":1.10,"qty":1e3,"id":12345678901234567890,"items":[{"n":2.50},{"n":10}]}`, string(encoded))
+}
+
+func TestJSONNumbersInSearch(t *testing.T) {
+	assert := assert.New(t)
+	data := map[string]interface{}{"a": json.Number("2"), "b": json.Number("1.5")}
+	result, err := Search("[a > b, abs(b), sort([a, b])]", data)
+	assert.Nil(err)
+	assert.Equal([]interface{}{true, 1.5, []interface{}{1.5, 2.0}}, result)
+}
@@ -0,0 +1,294 @@
+package jmespath
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+)
+
+/* JSON numbers are decoded as float64, but Go values searched directly can
+   hold any of the Go numeric types, and exact integer modes add int64 and
+   *big.Int values, see IntegerMode.  Comparisons between numbers of
+   different types are exact: an int64 or uint64 that cannot be represented
+   as a float64 is not rounded before it is compared, so that, for example,
+   uint64(1<<63) is greater than int64(1<<63 - 1).  Functions that take
+   numbers are passed float64 values, as they compute in floating point.
+*/
+
+// numberKind classifies the Go numeric types by how they are compared.
+type numberKind int
+
+const (
+	notNumber numberKind = iota
+	signedNumber
+	unsignedNumber
+	floatNumber
+	// bigNumber is a *big.Int, for the integers beyond the range of int64
+	// and uint64 in exact integer modes, see IntegerMode.
+	bigNumber
+)
+
+// The bounds of the int64 and uint64 ranges, which are exactly
+// representable as float64 values.
+const (
+	maxInt64Bound  = 1 << 63
+	maxUint64Bound = 1 << 64
+)
+
+// number holds a number of any Go numeric type in the field for its kind.
+type number struct {
+	kind numberKind
+	i    int64
+	u    uint64
+	f    float64
+	b    *big.Int
+}
+
+// asNumber returns value as a number, whose kind is notNumber if value is
+// not of a Go numeric type or a valid json.Number.
+func asNumber(value interface{}) number {
+	switch v := value.(type) {
+	case float64:
+		return number{kind: floatNumber, f: v}
+	case float32:
+		return number{kind: floatNumber, f: float64(v)}
+	case int:
+		return number{kind: signedNumber, i: int64(v)}
+	case int8:
+		return number{kind: signedNumber, i: int64(v)}
+	case int16:
+		return number{kind: signedNumber, i: int64(v)}
+	case int32:
+		return number{kind: signedNumber, i: int64(v)}
+	case int64:
+		return number{kind: signedNumber, i: v}
+	case uint:
+		return number{kind: unsignedNumber, u: uint64(v)}
+	case uint8:
+		return number{kind: unsignedNumber, u: uint64(v)}
+	case uint16:
+		return number{kind: unsignedNumber, u: uint64(v)}
+	case uint32:
+		return number{kind: unsignedNumber, u: uint64(v)}
+	case uint64:
+		return number{kind: unsignedNumber, u: v}
+	case uintptr:
+		return number{kind: unsignedNumber, u: uint64(v)}
+	case *big.Int:
+		if v != nil {
+			return number{kind: bigNumber, b: v}
+		}
+	case json.Number:
+		if n, ok := parseJSONNumber(v); ok {
+			return n
+		}
+	}
+	return number{}
+}
+
+// toNumber converts a value of any Go numeric type to a float64.
+func toNumber(value interface{}) (float64, bool) {
+	if f, ok := value.(float64); ok {
+		return f, true
+	}
+	n := asNumber(value)
+	switch n.kind {
+	case signedNumber:
+		return float64(n.i), true
+	case unsignedNumber:
+		return float64(n.u), true
+	case floatNumber:
+		return n.f, true
+	case bigNumber:
+		f, _ := new(big.Float).SetInt(n.b).Float64()
+		return f, true
+	}
+	return 0, false
+}
+
+// isNonFloatNumber reports whether value is of a Go numeric type other than
+// float64.
+func isNonFloatNumber(value interface{}) bool {
+	if _, ok := value.(float64); ok {
+		return false
+	}
+	return asNumber(value).kind != notNumber
+}
+
+// compareNumbers compares left and right, which may be of any Go numeric
+// types, and returns -1, 0 or 1 as left is less than, equal to or greater
+// than right.  It returns false if either is not a number or is NaN.
+func compareNumbers(left, right interface{}) (int, bool) {
+	l, r := asNumber(left), asNumber(right)
+	if l.kind == notNumber || r.kind == notNumber {
+		return 0, false
+	}
+	if l.kind == floatNumber && math.IsNaN(l.f) || r.kind == floatNumber && math.IsNaN(r.f) {
+		return 0, false
+	}
+	if l.kind == bigNumber || r.kind == bigNumber {
+		return compareBig(l, r), true
+	}
+	if l.kind > r.kind {
+		return -r.compare(l), true
+	}
+	return l.compare(r), true
+}
+
+// compareBig compares l and r, at least one of which is a *big.Int.
+func compareBig(l, r number) int {
+	if l.kind != floatNumber && r.kind != floatNumber {
+		return l.exactInteger().Cmp(r.exactInteger())
+	}
+	// big.Float values created from integers and float64 values are
+	// exact, including infinities.
+	toFloat := func(n number) *big.Float {
+		if n.kind == floatNumber {
+			return big.NewFloat(n.f)
+		}
+		return new(big.Float).SetInt(n.exactInteger())
+	}
+	return toFloat(l).Cmp(toFloat(r))
+}
+
+// compare compares n with other, whose kind is not less than that of n.
+func (n number) compare(other number) int {
+	switch {
+	case n.kind == signedNumber && other.kind == signedNumber:
+		return compareOrdered(n.i < other.i, n.i > other.i)
+	case n.kind == signedNumber && other.kind == unsignedNumber:
+		if n.i < 0 {
+			return -1
+		}
+		return compareOrdered(uint64(n.i) < other.u, uint64(n.i) > other.u)
+	case n.kind == unsignedNumber && other.kind == unsignedNumber:
+		return compareOrdered(n.u < other.u, n.u > other.u)
+	case n.kind == floatNumber:
+		return compareOrdered(n.f < other.f, n.f > other.f)
+	}
+	// Compare the integer with the integer part of the float, which is
+	// exact once the float is known to be in the range of the integer
+	// type, and then with its fractional part.
+	f := other.f
+	whole := math.Trunc(f)
+	fraction := compareOrdered(0 < f-whole, 0 > f-whole)
+	if n.kind == signedNumber {
+		if f >= maxInt64Bound {
+			return -1
+		}
+		if f < -maxInt64Bound {
+			return 1
+		}
+		if i := int64(whole); n.i != i {
+			return compareOrdered(n.i < i, n.i > i)
+		}
+		return fraction
+	}
+	if f < 0 {
+		return 1
+	}
+	if f >= maxUint64Bound {
+		return -1
+	}
+	if u := uint64(whole); n.u != u {
+		return compareOrdered(n.u < u, n.u > u)
+	}
+	return fraction
+}
+
+func compareOrdered(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	}
+	return 0
+}
+
+// valuesEqual reports whether left and right are equal, where numbers of
+// different Go numeric types are equal if their values are, in arrays and
+// objects as well.
+func valuesEqual(left, right interface{}) bool {
+	// Go slices of other types, like []int, are compared element by
+	// element as arrays.
+	if _, ok := left.([]interface{}); !ok && isSliceType(left) {
+		left = toInterfaceSlice(left)
+	}
+	if _, ok := right.([]interface{}); !ok && isSliceType(right) {
+		right = toInterfaceSlice(right)
+	}
+	// Ordered objects are equal regardless of the order of their keys.
+	if m, ok := left.(*OrderedMap); ok {
+		left = m.values
+	}
+	if m, ok := right.(*OrderedMap); ok {
+		right = m.values
+	}
+	switch l := left.(type) {
+	case []interface{}:
+		r, ok := right.([]interface{})
+		if !ok || len(l) != len(r) {
+			return false
+		}
+		for i := range l {
+			if !valuesEqual(l[i], r[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		r, ok := right.(map[string]interface{})
+		if !ok || len(l) != len(r) {
+			return false
+		}
+		for key, value := range l {
+			other, ok := r[key]
+			if !ok || !valuesEqual(value, other) {
+				return false
+			}
+		}
+		return true
+	}
+	if comparison, ok := compareNumbers(left, right); ok {
+		return comparison == 0
+	}
+	return deepEqual(left, right)
+}
+
+// normalizeNumber converts a value of any Go numeric type to a float64,
+// or to the representation of exactNumber if exact is set.  It returns
+// false if value is not a number.
+func normalizeNumber(value interface{}, exact bool) (interface{}, bool) {
+	if exact {
+		return exactNumber(value)
+	}
+	return toNumber(value)
+}
+
+// normalizeElements returns elements with the numbers of Go numeric types
+// other than float64 converted by normalizeNumber and the strings of named
+// string types converted to string.  It returns elements itself if none of
+// them needs converting.
+func normalizeElements(elements []interface{}, exact bool) []interface{} {
+	var normalized []interface{}
+	for i, element := range elements {
+		s, isNamed := namedString(element)
+		if !isNamed && !isNonFloatNumber(element) {
+			continue
+		}
+		if normalized == nil {
+			normalized = make([]interface{}, len(elements))
+			copy(normalized, elements)
+		}
+		if isNamed {
+			normalized[i] = s
+		} else {
+			normalized[i], _ = normalizeNumber(element, exact)
+		}
+	}
+	if normalized == nil {
+		return elements
+	}
+	return normalized
+}
@@ -0,0 +1,61 @@
+package jmespath
+
+import (
+	"math"
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func TestCompareNumbers(t *testing.T) {
+	assert := assert.New(t)
+	cases := []struct {
+		left, right interface{}
+		expected    int
+	}{
+		{1, 1.0, 0},
+		{int8(-3), uint8(3), -1},
+		{uint16(7), int64(7), 0},
+		{float32(1.5), 1.5, 0},
+		{float32(0.1), 0.1, 1},
+		{2, 1.5, 1},
+		{1, 1.5, -1},
+		{-1, -1.5, 1},
+		{-2, -1.5, -1},
+		{int64(math.MaxInt64), uint64(1 << 63), -1},
+		{int64(math.MaxInt64), float64(1 << 63), -1},
+		{int64(math.MinInt64), float64(-1 << 63), 0},
+		{int64(math.MinInt64), -1e19, 1},
+		{int64(1<<53 + 1), float64(1 << 53), 1},
+		{uint64(math.MaxUint64), float64(1 << 64), -1},
+		{uint64(1<<53 + 1), float64(1 << 53), 1},
+		{uint64(0), -0.5, 1},
+		{uint(3), math.Inf(1), -1},
+		{math.Inf(-1), int64(math.MinInt64), -1},
+		{-1, uint64(math.MaxUint64), -1},
+	}
+	for _, tt := range cases {
+		result, ok := compareNumbers(tt.left, tt.right)
+		assert.True(ok, "%T(%v) %T(%v)", tt.left, tt.left, tt.right, tt.right)
+		assert.Equal(tt.expected, result, "%T(%v) %T(%v)", tt.left, tt.left, tt.right, tt.right)
+		result, _ = compareNumbers(tt.right, tt.left)
+		assert.Equal(-tt.expected, result, "%T(%v) %T(%v)", tt.right, tt.right, tt.left, tt.left)
+	}
+	for _, pair := range [][2]interface{}{{math.NaN(), 1}, {1.0, "1"}, {nil, 0}, {true, 1}} {
+		_, ok := compareNumbers(pair[0], pair[1])
+		assert.False(ok, "%v %v", pair[0], pair[1])
+	}
+}
+
+func TestObjsEqualMixedNumbers(t *testing.T) {
+	assert := assert.New(t)
+	assert.True(objsEqual(3, 3.0))
+	assert.True(objsEqual(uint8(3), int64(3)))
+	assert.False(objsEqual(3, 3.5))
+	assert.False(objsEqual(uint64(1<<53+1), float64(1<<53)))
+	assert.True(objsEqual([]interface{}{1, "a"}, []interface{}{1.0, "a"}))
+	assert.True(objsEqual(map[string]interface{}{"a": []interface{}{int32(2)}}, map[string]interface{}{"a": []interface{}{2.0}}))
+	assert.False(objsEqual(map[string]interface{}{"a": 1}, map[string]interface{}{"b": 1.0}))
+	assert.False(objsEqual(1, "1"))
+	assert.False(objsEqual(math.NaN(), math.NaN()))
+}
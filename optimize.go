@@ -0,0 +1,191 @@
+package jmespath
+
+/* The optimizer rewrites a parsed AST into an equivalent AST that is
+   cheaper to evaluate.  It is run once by Compile so that the cost is
+   amortized over every evaluation of the compiled expression.
+*/
+
+// optimize returns an AST equivalent to node, for expressions evaluated by
+// intr, with the following rewrites applied bottom up:
+//
+//   - Subexpressions and pipes that only depend on literals are evaluated
+//     once by intr and replaced by a literal.
+//   - Subexpressions and pipes with the current node on either side are
+//     replaced by the other side, e.g. "@.foo" becomes "foo".
+//   - Nested pipes are collapsed into a single pipe with more stages.
+//   - Consecutive flattens, e.g. "foo[][]", are merged into a single
+//     flatten that flattens several levels.
+//   - "&&" and "||" with a literal on the left are replaced by whichever
+//     side they would return.
+func optimize(node ASTNode, intr *treeInterpreter) ASTNode {
+	if len(node.children) > 0 {
+		children := make([]ASTNode, len(node.children))
+		for i, child := range node.children {
+			children[i] = optimize(child, intr)
+		}
+		node.children = children
+	}
+	switch node.nodeType {
+	case ASTSubexpression, ASTIndexExpression, ASTPipe:
+		node = collapseCurrentNode(node)
+		if node.nodeType == ASTPipe {
+			node = collapsePipes(node)
+		}
+	case ASTProjection:
+		node = mergeFlattens(node)
+	case ASTAndExpression, ASTOrExpression:
+		node = shortCircuit(node)
+	}
+	if node.nodeType != ASTLiteral && !isSlice(node) && isConstant(node) {
+		if folded, err := intr.Execute(node, nil); err == nil {
+			return ASTNode{nodeType: ASTLiteral, value: folded}
+		}
+	}
+	return node
+}
+
+// isSlice reports whether node is a slice, alone or at the end of an index
+// expression.  Slices are folded along with the projection they are the
+// left side of, as the projection applies to a sliced string instead of
+// projecting only if its left side is a slice, see slicedString.
+func isSlice(node ASTNode) bool {
+	if node.nodeType == ASTIndexExpression {
+		node = node.children[len(node.children)-1]
+	}
+	return node.nodeType == ASTSlice
+}
+
+// shortCircuit replaces a logical expression whose left side is a literal
+// with the side it evaluates to.
+func shortCircuit(node ASTNode) ASTNode {
+	left := node.children[0]
+	if left.nodeType != ASTLiteral {
+		return node
+	}
+	if isFalse(left.value) == (node.nodeType == ASTAndExpression) {
+		return left
+	}
+	return node.children[1]
+}
+
+func isCurrentNode(node ASTNode) bool {
+	return node.nodeType == ASTCurrentNode || node.nodeType == ASTIdentity
+}
+
+// collapseCurrentNode removes a current node from either side of a
+// subexpression or pipe, as evaluating it returns its input unchanged.
+func collapseCurrentNode(node ASTNode) ASTNode {
+	var kept []ASTNode
+	for _, child := range node.children {
+		if !isCurrentNode(child) {
+			kept = append(kept, child)
+		}
+	}
+	switch len(kept) {
+	case 0:
+		return ASTNode{nodeType: ASTCurrentNode}
+	case 1:
+		return kept[0]
+	}
+	node.children = kept
+	return node
+}
+
+// collapsePipes flattens left nested pipes, "(a | b) | c", into a single
+// pipe with the stages a, b and c.
+func collapsePipes(node ASTNode) ASTNode {
+	if node.nodeType != ASTPipe || node.children[0].nodeType != ASTPipe {
+		return node
+	}
+	stages := append([]ASTNode{}, node.children[0].children...)
+	node.children = append(stages, node.children[1:]...)
+	return node
+}
+
+// mergeFlattens rewrites a projection over the flatten of another plain
+// flatten projection into a single projection flattening both levels.
+// Only projections whose right side evaluates null to null are merged:
+// the nulls the inner projection drops then evaluate to nulls the outer
+// one drops, so the results are the same.
+func mergeFlattens(node ASTNode) ASTNode {
+	left, right := node.children[0], node.children[1]
+	if left.nodeType != ASTFlatten || !keepsNull(right) {
+		return node
+	}
+	inner := left.children[0]
+	if inner.nodeType != ASTProjection || !isCurrentNode(inner.children[1]) ||
+		inner.children[0].nodeType != ASTFlatten {
+		return node
+	}
+	innerFlatten := inner.children[0]
+	depth := flattenDepth(left) + flattenDepth(innerFlatten)
+	merged := ASTNode{nodeType: ASTFlatten, value: depth, children: innerFlatten.children}
+	return ASTNode{nodeType: ASTProjection, children: []ASTNode{merged, right}}
+}
+
+// keepsNull reports whether node is a field, the current node or a
+// subexpression of them, which evaluate null to null.
+func keepsNull(node ASTNode) bool {
+	switch node.nodeType {
+	case ASTField, ASTCurrentNode, ASTIdentity:
+		return true
+	case ASTSubexpression:
+		for _, child := range node.children {
+			if !keepsNull(child) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func flattenDepth(node ASTNode) int {
+	if depth, ok := node.value.(int); ok {
+		return depth
+	}
+	return 1
+}
+
+// isConstant reports whether node evaluates to the same value regardless
+// of the input.  Function calls are never considered constant as user
+// registered functions may not be deterministic.
+func isConstant(node ASTNode) bool {
+	switch node.nodeType {
+	case ASTLiteral:
+		return true
+	case ASTSubexpression, ASTIndexExpression, ASTPipe,
+		ASTProjection, ASTValueProjection, ASTFilterProjection:
+		// Only the leftmost child is evaluated against the input, the
+		// rest are evaluated against its result.
+		if !isConstant(node.children[0]) {
+			return false
+		}
+		for _, child := range node.children[1:] {
+			if hasFunctionCall(child) {
+				return false
+			}
+		}
+		return true
+	case ASTFlatten, ASTComparator, ASTOrExpression, ASTAndExpression, ASTNotExpression:
+		for _, child := range node.children {
+			if !isConstant(child) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func hasFunctionCall(node ASTNode) bool {
+	if node.nodeType == ASTFunctionExpression {
+		return true
+	}
+	for _, child := range node.children {
+		if hasFunctionCall(child) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package jmespath
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+var optimizerTests = []struct {
+	expression string
+	expected   string
+}{
+	{"@.foo", "foo"},
+	{"foo | @", "foo"},
+	{"@ | @", "@"},
+	{"`[1, 2]`[0]", "`1`"},
+	{"`{\"a\": {\"b\": 1}}`.a.b", "`1`"},
+	{"`1` == `1`", "`true`"},
+	{"!`false` && 'x'", "'x'"},
+	{"`[[1], [2]]`[]", "`[1,2]`"},
+}
+
+func TestOptimizer(t *testing.T) {
+	assert := assert.New(t)
+	parser := NewParser()
+	for _, tt := range optimizerTests {
+		actual, err := parser.Parse(tt.expression)
+		assert.Nil(err)
+		expected, err := parser.Parse(tt.expected)
+		assert.Nil(err)
+		assert.Equal(expected.String(), optimize(actual, newInterpreter()).String(), tt.expression)
+	}
+}
+
+func TestOptimizerCollapsesPipes(t *testing.T) {
+	assert := assert.New(t)
+	jp := MustCompile("a | b | @ | c")
+	expected := ASTNode{nodeType: ASTPipe, children: []ASTNode{
+		{nodeType: ASTField, value: "a"},
+		{nodeType: ASTField, value: "b"},
+		{nodeType: ASTField, value: "c"},
+	}}
+	assert.Equal(expected, jp.ast)
+}
+
+func TestOptimizerMergesFlattens(t *testing.T) {
+	assert := assert.New(t)
+	jp := MustCompile("foo[][][].bar")
+	assert.Equal(ASTProjection, jp.ast.nodeType)
+	flatten := jp.ast.children[0]
+	assert.Equal(ASTFlatten, flatten.nodeType)
+	assert.Equal(3, flatten.value)
+
+	data := map[string]interface{}{"foo": []interface{}{
+		[]interface{}{[]interface{}{map[string]interface{}{"bar": 1.0}}, nil},
+		[]interface{}{[]interface{}{map[string]interface{}{"bar": 2.0}, []interface{}{}}},
+		nil,
+	}}
+	result, err := jp.Search(data)
+	assert.Nil(err)
+	assert.Equal([]interface{}{1.0, 2.0}, result)
+}
+
+func TestOptimizerKeepsFlattensWithNullResults(t *testing.T) {
+	assert := assert.New(t)
+	var data interface{}
+	assert.Nil(json.Unmarshal([]byte(`{"foo": [[null, 1], [2, null], null, [[3], null]]}`), &data))
+	for _, expression := range []string{
+		"foo[][].to_string(@)",
+		"foo[][].not_null(@, 'x')",
+		"foo[][].[@]",
+		"foo[][].{v: @}",
+		"foo[][].a.b",
+		"foo[][]",
+	} {
+		expected, err := Search(expression, data)
+		assert.Nil(err, expression)
+		compiled, err := MustCompile(expression).Search(data)
+		assert.Nil(err, expression)
+		assert.Equal(expected, compiled, expression)
+	}
+	jp := MustCompile("foo[][].to_string(@)")
+	assert.Equal(ASTFlatten, jp.ast.children[0].nodeType)
+	assert.Equal(ASTProjection, jp.ast.children[0].children[0].nodeType)
+}
+
+func TestOptimizerDoesNotFoldFunctionCalls(t *testing.T) {
+	assert := assert.New(t)
+	jp := MustCompile("length(`[1, 2]`)")
+	assert.Equal(ASTFunctionExpression, jp.ast.nodeType)
+	jp = MustCompile("`[\"a\"]`[?length(@) > `0`]")
+	assert.Equal(ASTFilterProjection, jp.ast.nodeType)
+}
+
+func TestOptimizerShortCircuitsLiterals(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(ASTNode{nodeType: ASTField, value: "b"}, MustCompile("`true` && b").ast)
+	assert.Equal(ASTNode{nodeType: ASTLiteral, value: "a"}, MustCompile("'a' || b").ast)
+	assert.Equal(ASTNode{nodeType: ASTLiteral, value: []interface{}{}}, MustCompile("`[]` && b").ast)
+	assert.Equal(ASTNode{nodeType: ASTField, value: "b"}, MustCompile("`null` || b").ast)
+}
@@ -0,0 +1,273 @@
+package jmespath
+
+import "time"
+
+// Option configures a Runtime, see CompileWith, SearchWith and
+// Runtime.Configure.  Each option corresponds to a method of Runtime and
+// is documented there.
+//
+// Options are taken by CompileWith and SearchWith rather than by variadic
+// Compile and Search, as adding a parameter to those would break the
+// programs that use them as function values, such as
+// compliance.Test(t, jmespath.Search).
+type Option func(rt *Runtime) error
+
+// CompileWith is like Compile, but compiles the expression with a new
+// Runtime configured with opts, as by Runtime.Configure.  It is an error
+// for an option to fail, such as WithFunction registering a function with
+// the name of a built-in function.
+func CompileWith(expression string, opts ...Option) (*JMESPath, error) {
+	rt := NewRuntime()
+	if err := rt.Configure(opts...); err != nil {
+		return nil, err
+	}
+	return rt.Compile(expression)
+}
+
+// SearchWith is like Search, but evaluates the expression with a new
+// Runtime configured with opts, as by Runtime.Configure.  Expressions that
+// are evaluated more than once should be compiled with CompileWith, or
+// with a configured Runtime, instead.
+func SearchWith(expression string, data interface{}, opts ...Option) (interface{}, error) {
+	rt := NewRuntime()
+	if err := rt.Configure(opts...); err != nil {
+		return nil, err
+	}
+	return rt.Search(expression, data)
+}
+
+// Configure applies opts to the runtime in order, and returns the error of
+// the first option that fails, if any, without applying the rest.  Like
+// the methods the options correspond to, it only affects expressions
+// compiled after the call.
+func (rt *Runtime) Configure(opts ...Option) error {
+	for _, opt := range opts {
+		if err := opt(rt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WithFunction registers a user-defined function, see
+// Runtime.RegisterFunction.
+func WithFunction(name string, fn Function) Option {
+	return func(rt *Runtime) error {
+		return rt.RegisterFunction(name, fn)
+	}
+}
+
+// WithFunctionTimeout limits how long a user-registered function may run
+// for, see Runtime.SetFunctionTimeout.  It must follow the WithFunction
+// option registering the function.
+func WithFunctionTimeout(name string, timeout time.Duration) Option {
+	return func(rt *Runtime) error {
+		return rt.SetFunctionTimeout(name, timeout)
+	}
+}
+
+// WithFunctionOverride replaces a built-in or registered function, see
+// Runtime.OverrideFunction.
+func WithFunctionOverride(name string, fn Function) Option {
+	return func(rt *Runtime) error {
+		return rt.OverrideFunction(name, fn)
+	}
+}
+
+// WithDisabledFunctions removes built-in or registered functions, see
+// Runtime.DisableFunction.
+func WithDisabledFunctions(names ...string) Option {
+	return func(rt *Runtime) error {
+		return rt.DisableFunction(names...)
+	}
+}
+
+// WithMacro defines a macro, see Runtime.DefineMacro.
+func WithMacro(name, expression string) Option {
+	return func(rt *Runtime) error {
+		return rt.DefineMacro(name, expression)
+	}
+}
+
+// WithCollation registers a named collation, see Runtime.RegisterCollation.
+func WithCollation(name string, collation Collation) Option {
+	return func(rt *Runtime) error {
+		return rt.RegisterCollation(name, collation)
+	}
+}
+
+// WithDefaultCollation sets the collation strings are sorted with by
+// default, see Runtime.SetCollation.
+func WithDefaultCollation(collation Collation) Option {
+	return func(rt *Runtime) error {
+		rt.SetCollation(collation)
+		return nil
+	}
+}
+
+// WithStrict sets strict evaluation, see Runtime.SetStrict.
+func WithStrict(strict bool) Option {
+	return func(rt *Runtime) error {
+		rt.SetStrict(strict)
+		return nil
+	}
+}
+
+// WithKeepNulls sets whether projections keep null results, see
+// Runtime.SetKeepNulls.
+func WithKeepNulls(keep bool) Option {
+	return func(rt *Runtime) error {
+		rt.SetKeepNulls(keep)
+		return nil
+	}
+}
+
+// WithCaseInsensitiveKeys sets whether fields match keys regardless of
+// case, see Runtime.SetCaseInsensitiveKeys.
+func WithCaseInsensitiveKeys(fold bool) Option {
+	return func(rt *Runtime) error {
+		rt.SetCaseInsensitiveKeys(fold)
+		return nil
+	}
+}
+
+// WithStrictBounds sets strict array bounds, see Runtime.SetStrictBounds.
+func WithStrictBounds(strictBounds bool) Option {
+	return func(rt *Runtime) error {
+		rt.SetStrictBounds(strictBounds)
+		return nil
+	}
+}
+
+// WithIntegerMode sets how integers beyond ±2^53 are handled, see
+// Runtime.SetIntegerMode.
+func WithIntegerMode(mode IntegerMode) Option {
+	return func(rt *Runtime) error {
+		rt.SetIntegerMode(mode)
+		return nil
+	}
+}
+
+// WithMultiValueMode sets how multi-value maps are exposed, see
+// Runtime.SetMultiValueMode.
+func WithMultiValueMode(mode MultiValueMode) Option {
+	return func(rt *Runtime) error {
+		rt.SetMultiValueMode(mode)
+		return nil
+	}
+}
+
+// WithOrderedObjects sets deterministic key order, see
+// Runtime.SetOrderedObjects.
+func WithOrderedObjects(ordered bool) Option {
+	return func(rt *Runtime) error {
+		rt.SetOrderedObjects(ordered)
+		return nil
+	}
+}
+
+// WithSortedKeys visits the keys of objects in sorted order, see
+// Runtime.SetSortedKeys.
+func WithSortedKeys(sorted bool) Option {
+	return func(rt *Runtime) error {
+		rt.SetSortedKeys(sorted)
+		return nil
+	}
+}
+
+// WithInvalidSortKeys sets how sort_by, max_by and min_by handle keys that
+// are not comparable, see Runtime.SetInvalidSortKeys.
+func WithInvalidSortKeys(mode InvalidSortKeyMode) Option {
+	return func(rt *Runtime) error {
+		rt.SetInvalidSortKeys(mode)
+		return nil
+	}
+}
+
+// WithRecursiveDescent enables the ".." operator, see
+// Runtime.SetRecursiveDescent.
+func WithRecursiveDescent(descent bool) Option {
+	return func(rt *Runtime) error {
+		rt.SetRecursiveDescent(descent)
+		return nil
+	}
+}
+
+// WithLegacyLiterals accepts JSON literals with elided quotes, see
+// Runtime.SetLegacyLiterals.
+func WithLegacyLiterals(legacy bool) Option {
+	return func(rt *Runtime) error {
+		rt.SetLegacyLiterals(legacy)
+		return nil
+	}
+}
+
+// WithLegacyComparisons only orders numbers with the ordering
+// comparators, see Runtime.SetLegacyComparisons.
+func WithLegacyComparisons(legacy bool) Option {
+	return func(rt *Runtime) error {
+		rt.SetLegacyComparisons(legacy)
+		return nil
+	}
+}
+
+// WithNumberFormat sets how numbers are written as text, see
+// Runtime.SetNumberFormat.
+func WithNumberFormat(format NumberFormat) Option {
+	return func(rt *Runtime) error {
+		rt.SetNumberFormat(format)
+		return nil
+	}
+}
+
+// WithLimits bounds the size of the expressions compiled, see
+// Runtime.SetLimits.
+func WithLimits(limits Limits) Option {
+	return func(rt *Runtime) error {
+		rt.SetLimits(limits)
+		return nil
+	}
+}
+
+// WithGraphemeClusters makes string functions operate on grapheme
+// clusters, see Runtime.SetGraphemeClusters.
+func WithGraphemeClusters(graphemes bool) Option {
+	return func(rt *Runtime) error {
+		rt.SetGraphemeClusters(graphemes)
+		return nil
+	}
+}
+
+// WithParallelism sets the number of goroutines evaluating large
+// projections, see Runtime.SetParallelism.
+func WithParallelism(workers int) Option {
+	return func(rt *Runtime) error {
+		rt.SetParallelism(workers)
+		return nil
+	}
+}
+
+// WithTracer sets the Tracer observing evaluation, see Runtime.SetTracer.
+func WithTracer(tracer Tracer) Option {
+	return func(rt *Runtime) error {
+		rt.SetTracer(tracer)
+		return nil
+	}
+}
+
+// WithDecoder sets the Decoder of SearchBytes, see Runtime.SetDecoder.
+func WithDecoder(decoder Decoder) Option {
+	return func(rt *Runtime) error {
+		rt.SetDecoder(decoder)
+		return nil
+	}
+}
+
+// WithMetrics sets the Metrics measuring compilation and evaluation, see
+// Runtime.SetMetrics.
+func WithMetrics(metrics Metrics) Option {
+	return func(rt *Runtime) error {
+		rt.SetMetrics(metrics)
+		return nil
+	}
+}
@@ -0,0 +1,94 @@
+package jmespath
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func TestSearchWith(t *testing.T) {
+	assert := assert.New(t)
+	data := map[string]interface{}{"name": "Ann", "id": 9007199254740993.0}
+
+	result, err := SearchWith("upper(name)", data, WithFunction("upper", func(ctx CallContext, args []interface{}) (interface{}, error) {
+		return strings.ToUpper(args[0].(string)), nil
+	}))
+	assert.Nil(err)
+	assert.Equal("ANN", result)
+
+	result, err = SearchWith("length(name)", data, WithFunctionOverride("length", func(ctx CallContext, args []interface{}) (interface{}, error) {
+		return 0.0, nil
+	}))
+	assert.Nil(err)
+	assert.Equal(0.0, result)
+	_, err = SearchWith("length(name)", data, WithDisabledFunctions("length"))
+	assert.NotNil(err)
+
+	_, err = SearchWith("missing", data, WithStrict(true))
+	assert.NotNil(err)
+	result, err = SearchWith("missing", data)
+	assert.Nil(err)
+	assert.Nil(result)
+
+	result, err = SearchWith("`foo`", data, WithLegacyLiterals(true), WithRecursiveDescent(true))
+	assert.Nil(err)
+	assert.Equal("foo", result)
+}
+
+func TestCompileWith(t *testing.T) {
+	assert := assert.New(t)
+	jp, err := CompileWith("`9007199254740993`", WithIntegerMode(IntegersExact))
+	assert.Nil(err)
+	result, err := jp.Search(nil)
+	assert.Nil(err)
+	assert.Equal(int64(9007199254740993), result)
+
+	jp, err = CompileWith("sort(@)", WithDefaultCollation(func(a, b string) int {
+		return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+	}))
+	assert.Nil(err)
+	result, err = jp.Search([]interface{}{"b", "A", "c"})
+	assert.Nil(err)
+	assert.Equal([]interface{}{"A", "b", "c"}, result)
+}
+
+func TestOptionErrors(t *testing.T) {
+	assert := assert.New(t)
+	fn := func(ctx CallContext, args []interface{}) (interface{}, error) { return nil, nil }
+
+	_, err := CompileWith("abs(@)", WithFunction("abs", fn))
+	assert.Equal("function already defined: abs", err.Error())
+	_, err = SearchWith("slow(@)", nil, WithFunctionTimeout("slow", time.Second))
+	assert.NotNil(err)
+	_, err = CompileWith("slow(@)", WithFunction("slow", fn), WithFunctionTimeout("slow", time.Second))
+	assert.Nil(err)
+	_, err = CompileWith("abs(@)", WithFunctionOverride("absolute", fn))
+	assert.Equal("unknown function: absolute", err.Error())
+	_, err = CompileWith("abs(@)", WithDisabledFunctions("abs", "absolute"))
+	assert.Equal("unknown function: absolute", err.Error())
+
+	// Options after a failing option are not applied.
+	failed := errors.New("failed")
+	applied := false
+	rt := NewRuntime()
+	err = rt.Configure(func(*Runtime) error { return failed }, func(*Runtime) error {
+		applied = true
+		return nil
+	})
+	assert.Equal(failed, err)
+	assert.False(applied)
+}
+
+func TestConfigure(t *testing.T) {
+	assert := assert.New(t)
+	rt := NewRuntime()
+	assert.Nil(rt.Configure(WithStrictBounds(true), WithStrict(true), WithOrderedObjects(true)))
+	_, err := rt.Search("tags[2]", map[string]interface{}{"tags": []interface{}{"a"}})
+	assert.NotNil(err)
+	result, err := rt.Search("{b: `1`, a: `2`}", map[string]interface{}{})
+	assert.Nil(err)
+	assert.IsType(&OrderedMap{}, result)
+}
@@ -0,0 +1,424 @@
+package jmespath
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"sort"
+)
+
+// OrderedMap is a JSON object that remembers the order its keys were
+// inserted in.  Expressions can be searched against data containing
+// OrderedMaps, which are treated like any other object.  With ordered
+// objects enabled on a Runtime, multiselect hashes also evaluate to
+// OrderedMaps, and keys(), values() and object projections follow the key
+// order of an OrderedMap, or sorted key order for any other object.
+type OrderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// NewOrderedMap returns an empty OrderedMap.
+func NewOrderedMap() *OrderedMap {
+	return &OrderedMap{values: make(map[string]interface{})}
+}
+
+// Set sets the value of key.  A new key is added after the existing keys,
+// an existing key keeps its position.
+func (m *OrderedMap) Set(key string, value interface{}) {
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+// Get returns the value of key and whether it is present.
+func (m *OrderedMap) Get(key string) (interface{}, bool) {
+	value, ok := m.values[key]
+	return value, ok
+}
+
+// remove removes key, keeping the order of the other keys.
+func (m *OrderedMap) remove(key string) {
+	if _, ok := m.values[key]; !ok {
+		return
+	}
+	delete(m.values, key)
+	for i, k := range m.keys {
+		if k == key {
+			m.keys = append(m.keys[:i:i], m.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// Keys returns the keys in insertion order.  The returned slice must not be
+// modified.
+func (m *OrderedMap) Keys() []string {
+	return m.keys
+}
+
+// Len returns the number of keys.
+func (m *OrderedMap) Len() int {
+	return len(m.keys)
+}
+
+// MarshalJSON encodes the map as a JSON object with its keys in order.
+func (m *OrderedMap) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	// HTML characters are left for the caller to escape: json.Marshal
+	// escapes them in the result, while an Encoder with SetEscapeHTML(false)
+	// does not.
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	b.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		if err := encoder.Encode(key); err != nil {
+			return nil, err
+		}
+		b.Truncate(b.Len() - 1) // The newline written by Encode.
+		b.WriteByte(':')
+		if err := encoder.Encode(m.values[key]); err != nil {
+			return nil, err
+		}
+		b.Truncate(b.Len() - 1)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// UnmarshalOrdered decodes JSON like json.Unmarshal into an interface{},
+// except that objects are decoded as *OrderedMap with their keys in the
+// order they appear in data.
+func UnmarshalOrdered(data []byte) (interface{}, error) {
+	return unmarshalOrdered(data, false, false)
+}
+
+// unmarshalOrdered implements UnmarshalOrdered, and decodes numbers as
+// json.Number values if keepNumbers is set, or else integers in the
+// representation of exactNumber if exact is set.
+func unmarshalOrdered(data []byte, exact, keepNumbers bool) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if exact || keepNumbers {
+		decoder.UseNumber()
+	}
+	value, err := decodeOrdered(decoder)
+	if err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, errors.New("invalid JSON: data after top-level value")
+	}
+	if exact && !keepNumbers {
+		return exactNumbers(value)
+	}
+	return value, nil
+}
+
+func decodeOrdered(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch token {
+	case json.Delim('{'):
+		object := NewOrderedMap()
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeOrdered(decoder)
+			if err != nil {
+				return nil, err
+			}
+			object.Set(key.(string), value)
+		}
+		_, err = decoder.Token()
+		return object, err
+	case json.Delim('['):
+		array := []interface{}{}
+		for decoder.More() {
+			value, err := decodeOrdered(decoder)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		_, err = decoder.Token()
+		return array, err
+	}
+	return token, nil
+}
+
+func (intr *treeInterpreter) orderedMultiSelectHash(node ASTNode, value interface{}) (interface{}, error) {
+	collected := NewOrderedMap()
+	for _, child := range node.children {
+		current, err := intr.Execute(child, value)
+		if err != nil {
+			return nil, err
+		}
+		collected.Set(child.value.(string), current)
+	}
+	return collected, nil
+}
+
+// plainObject returns the map holding the values of an object that is
+// either a map[string]interface{} or an *OrderedMap.
+func plainObject(value interface{}) map[string]interface{} {
+	if m, ok := value.(*OrderedMap); ok {
+		return m.values
+	}
+	return value.(map[string]interface{})
+}
+
+// objectKeys returns the keys of object, the converted form of value, in a
+// deterministic order: insertion order if value is an *OrderedMap and
+// sorted order otherwise.
+func objectKeys(value interface{}, object map[string]interface{}) []string {
+	if m, ok := value.(*OrderedMap); ok {
+		return m.keys
+	}
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// iterationKeys returns the keys of object, the object value, in the order
+// object projections visit them: the order of objectKeys with ordered
+// objects or sorted keys, and map iteration order otherwise.
+func (intr *treeInterpreter) iterationKeys(value interface{}, object map[string]interface{}) []string {
+	if intr.ordered || intr.sortedKeys {
+		return objectKeys(value, object)
+	}
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// orderedFunctions replaces the built-in functions whose results depend on
+// key order when ordered objects are enabled.  They are passed *OrderedMap
+// arguments as is rather than converted to map[string]interface{}.
+var orderedFunctions map[string]functionEntry
+
+// sortedKeyFunctions replaces the built-in functions whose results or
+// evaluation order depend on key order when sorted keys are enabled and
+// ordered objects are not.  Their object arguments are always
+// map[string]interface{} values, whose keys objectKeys sorts.
+var sortedKeyFunctions map[string]functionEntry
+
+// orderedFunctions and sortedKeyFunctions are assigned in init as
+// map_values and filter_values evaluate expressions, which look them up.
+func init() {
+	orderedFunctions = map[string]functionEntry{
+		"keys": {
+			name: "keys",
+			arguments: []argSpec{
+				{types: []jpType{jpObject}},
+			},
+			handler: jpfOrderedKeys,
+		},
+		"values": {
+			name: "values",
+			arguments: []argSpec{
+				{types: []jpType{jpObject}},
+			},
+			handler: jpfOrderedValues,
+		},
+		"to_json": {
+			name: "to_json",
+			arguments: []argSpec{
+				{types: []jpType{jpAny}},
+			},
+			handler: jpfToJSON,
+		},
+		"map_values": {
+			name: "map_values",
+			arguments: []argSpec{
+				{types: []jpType{jpExpref}},
+				{types: []jpType{jpObject}},
+			},
+			handler:   jpfOrderedMapValues,
+			hasExpRef: true,
+		},
+		"filter_values": {
+			name: "filter_values",
+			arguments: []argSpec{
+				{types: []jpType{jpObject}},
+				{types: []jpType{jpExpref}},
+			},
+			handler:   jpfOrderedFilterValues,
+			hasExpRef: true,
+		},
+	}
+	sortedKeyFunctions = map[string]functionEntry{
+		"keys":   orderedFunctions["keys"],
+		"values": orderedFunctions["values"],
+		"map_values": {
+			name: "map_values",
+			arguments: []argSpec{
+				{types: []jpType{jpExpref}},
+				{types: []jpType{jpObject}},
+			},
+			handler:   jpfSortedMapValues,
+			hasExpRef: true,
+		},
+		"filter_values": {
+			name: "filter_values",
+			arguments: []argSpec{
+				{types: []jpType{jpObject}},
+				{types: []jpType{jpExpref}},
+			},
+			handler:   jpfSortedFilterValues,
+			hasExpRef: true,
+		},
+	}
+}
+
+func jpfOrderedKeys(arguments []interface{}) (interface{}, error) {
+	object := plainObject(arguments[0])
+	keys := objectKeys(arguments[0], object)
+	collected := make([]interface{}, len(keys))
+	for i, key := range keys {
+		collected[i] = key
+	}
+	return collected, nil
+}
+
+func jpfOrderedValues(arguments []interface{}) (interface{}, error) {
+	object := plainObject(arguments[0])
+	keys := objectKeys(arguments[0], object)
+	collected := make([]interface{}, len(keys))
+	for i, key := range keys {
+		collected[i] = object[key]
+	}
+	return collected, nil
+}
+
+func jpfOrderedMapValues(arguments []interface{}) (interface{}, error) {
+	intr := arguments[0].(*treeInterpreter)
+	node := arguments[1].(ExpRef).ref
+	object := plainObject(arguments[2])
+	mapped := NewOrderedMap()
+	capture := callsKey(node)
+	for _, key := range objectKeys(arguments[2], object) {
+		each := intr
+		if capture {
+			each = intr.withKey(key)
+		}
+		current, err := each.Execute(node, object[key])
+		if err != nil {
+			return nil, err
+		}
+		mapped.Set(key, current)
+	}
+	return mapped, nil
+}
+
+func jpfSortedMapValues(arguments []interface{}) (interface{}, error) {
+	intr := arguments[0].(*treeInterpreter)
+	node := arguments[1].(ExpRef).ref
+	object := arguments[2].(map[string]interface{})
+	mapped := make(map[string]interface{}, len(object))
+	capture := callsKey(node)
+	for _, key := range objectKeys(object, object) {
+		each := intr
+		if capture {
+			each = intr.withKey(key)
+		}
+		current, err := each.Execute(node, object[key])
+		if err != nil {
+			return nil, err
+		}
+		mapped[key] = current
+	}
+	return mapped, nil
+}
+
+func jpfOrderedFilterValues(arguments []interface{}) (interface{}, error) {
+	intr := arguments[0].(*treeInterpreter)
+	object := plainObject(arguments[1])
+	node := arguments[2].(ExpRef).ref
+	filtered := NewOrderedMap()
+	capture := callsKey(node)
+	for _, key := range objectKeys(arguments[1], object) {
+		keep, err := intr.keepValue(node, capture, key, object[key])
+		if err != nil {
+			return nil, err
+		}
+		if keep {
+			filtered.Set(key, object[key])
+		}
+	}
+	return filtered, nil
+}
+
+func jpfSortedFilterValues(arguments []interface{}) (interface{}, error) {
+	intr := arguments[0].(*treeInterpreter)
+	object := arguments[1].(map[string]interface{})
+	node := arguments[2].(ExpRef).ref
+	filtered := make(map[string]interface{})
+	capture := callsKey(node)
+	for _, key := range objectKeys(object, object) {
+		keep, err := intr.keepValue(node, capture, key, object[key])
+		if err != nil {
+			return nil, err
+		}
+		if keep {
+			filtered[key] = object[key]
+		}
+	}
+	return filtered, nil
+}
+
+// containsOrderedMap reports whether value is or contains an *OrderedMap.
+func containsOrderedMap(value interface{}) bool {
+	switch v := value.(type) {
+	case *OrderedMap:
+		return true
+	case []interface{}:
+		for _, element := range v {
+			if containsOrderedMap(element) {
+				return true
+			}
+		}
+	case map[string]interface{}:
+		for _, element := range v {
+			if containsOrderedMap(element) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// unordered returns a copy of value with every *OrderedMap in it replaced
+// by a map[string]interface{}, so that objects compare equal regardless of
+// key order.
+func unordered(value interface{}) interface{} {
+	switch v := value.(type) {
+	case *OrderedMap:
+		return unordered(v.values)
+	case []interface{}:
+		converted := make([]interface{}, len(v))
+		for i, element := range v {
+			converted[i] = unordered(element)
+		}
+		return converted
+	case map[string]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, element := range v {
+			converted[key] = unordered(element)
+		}
+		return converted
+	}
+	return value
+}
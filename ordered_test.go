@@ -0,0 +1,154 @@
+package jmespath
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func orderedRuntime() *Runtime {
+	rt := NewRuntime()
+	rt.SetOrderedObjects(true)
+	return rt
+}
+
+func TestOrderedMap(t *testing.T) {
+	assert := assert.New(t)
+	m := NewOrderedMap()
+	m.Set("b", 1.0)
+	m.Set("a", 2.0)
+	m.Set("b", 3.0)
+	assert.Equal([]string{"b", "a"}, m.Keys())
+	assert.Equal(2, m.Len())
+	value, ok := m.Get("b")
+	assert.True(ok)
+	assert.Equal(3.0, value)
+	_, ok = m.Get("c")
+	assert.False(ok)
+	encoded, err := json.Marshal(m)
+	assert.Nil(err)
+	assert.Equal(`{"b":3,"a":2}`, string(encoded))
+}
+
+func TestUnmarshalOrdered(t *testing.T) {
+	assert := assert.New(t)
+	data, err := UnmarshalOrdered([]byte(`{"z": [{"y": 1, "x": null}], "a": "s"}`))
+	assert.Nil(err)
+	encoded, err := json.Marshal(data)
+	assert.Nil(err)
+	assert.Equal(`{"z":[{"y":1,"x":null}],"a":"s"}`, string(encoded))
+	_, err = UnmarshalOrdered([]byte(`{"a": 1} {}`))
+	assert.NotNil(err)
+	_, err = UnmarshalOrdered([]byte(`{"a": }`))
+	assert.NotNil(err)
+}
+
+func TestOrderedMultiSelectHash(t *testing.T) {
+	assert := assert.New(t)
+	jp, err := orderedRuntime().Compile("{zeta: a, alpha: b, mid: {y: a, x: b}}")
+	assert.Nil(err)
+	result, err := jp.Search(map[string]interface{}{"a": 1.0, "b": 2.0})
+	assert.Nil(err)
+	encoded, err := json.Marshal(result)
+	assert.Nil(err)
+	assert.Equal(`{"zeta":1,"alpha":2,"mid":{"y":1,"x":2}}`, string(encoded))
+}
+
+func TestOrderedKeysAndProjections(t *testing.T) {
+	assert := assert.New(t)
+	rt := orderedRuntime()
+	data, err := UnmarshalOrdered([]byte(`{"c": {"n": 1}, "a": {"n": 2}, "b": {"n": 3}}`))
+	assert.Nil(err)
+	for _, tt := range []struct {
+		expression string
+		expected   interface{}
+	}{
+		{"keys(@)", []interface{}{"c", "a", "b"}},
+		{"values(@)[].n", []interface{}{1.0, 2.0, 3.0}},
+		{"*.n", []interface{}{1.0, 2.0, 3.0}},
+		{"c.n", 1.0},
+		{"keys({b: c, a: a})", []interface{}{"b", "a"}},
+		{"to_json({b: c.n, a: a.n})", `{"b":1,"a":2}`},
+		{"to_json(map_values(&n, @))", `{"c":1,"a":2,"b":3}`},
+		{"to_json(filter_values(@, &n != `2`))", `{"c":{"n":1},"b":{"n":3}}`},
+		{"{b: c.n, a: a.n} == {a: a.n, b: c.n}", true},
+		{"[{b: c, a: a}] == [{a: a, b: c}]", true},
+		{"@ == `{\"a\": {\"n\": 2}, \"b\": {\"n\": 3}, \"c\": {\"n\": 1}}`", true},
+		{"contains([c, a], {n: `2`})", true},
+		{"length(@)", 3.0},
+		{"type(@)", "object"},
+	} {
+		result, err := rt.Search(tt.expression, data)
+		if assert.Nil(err, tt.expression) {
+			assert.Equal(tt.expected, result, tt.expression)
+		}
+	}
+	// Other objects use sorted key order.
+	result, err := rt.Search("keys(@)", map[string]interface{}{"b": 1.0, "c": 2.0, "a": 3.0})
+	assert.Nil(err)
+	assert.Equal([]interface{}{"a", "b", "c"}, result)
+}
+
+func TestOrderedMapWithoutOrderedObjects(t *testing.T) {
+	assert := assert.New(t)
+	data, err := UnmarshalOrdered([]byte(`{"b": {"n": 1}, "a": {}}`))
+	assert.Nil(err)
+	result, err := Search("sort(keys(@))", data)
+	assert.Nil(err)
+	assert.Equal([]interface{}{"a", "b"}, result)
+	result, err = Search("a || b.n", data)
+	assert.Nil(err)
+	assert.Equal(1.0, result)
+	result, err = Search("{a: b.n}", data)
+	assert.Nil(err)
+	assert.Equal(map[string]interface{}{"a": 1.0}, result)
+}
+
+func TestSortedKeys(t *testing.T) {
+	assert := assert.New(t)
+	rt := NewRuntime()
+	rt.SetSortedKeys(true)
+	var visited []interface{}
+	assert.Nil(rt.RegisterFunction("visit", func(ctx CallContext, args []interface{}) (interface{}, error) {
+		visited = append(visited, args[0])
+		return args[0], nil
+	}))
+	data := map[string]interface{}{}
+	var sorted []interface{}
+	for _, key := range []string{"e", "b", "h", "a", "g", "c", "f", "d"} {
+		data[key] = map[string]interface{}{"n": key}
+	}
+	for _, key := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		sorted = append(sorted, key)
+	}
+	// Map iteration order varies, so a single evaluation could be sorted
+	// by chance.
+	for i := 0; i < 20; i++ {
+		for _, expression := range []string{"keys(@)", "values(@)[].n", "*.n", "*.key()"} {
+			result, err := rt.Search(expression, data)
+			assert.Nil(err, expression)
+			assert.Equal(sorted, result, expression)
+		}
+		visited = nil
+		_, err := rt.Search("map_values(&visit(key()), @)", data)
+		assert.Nil(err)
+		assert.Equal(sorted, visited)
+		visited = nil
+		_, err = rt.Search("filter_values(@, &visit(key()))", data)
+		assert.Nil(err)
+		assert.Equal(sorted, visited)
+	}
+	// Results are otherwise unchanged.
+	result, err := rt.Search("{b: a, a: b}", data)
+	assert.Nil(err)
+	assert.IsType(map[string]interface{}{}, result)
+
+	// Ordered objects take precedence.
+	rt.SetOrderedObjects(true)
+	ordered, err := UnmarshalOrdered([]byte(`{"c": 1, "a": 2, "b": 3}`))
+	assert.Nil(err)
+	result, err = rt.Search("keys(@)", ordered)
+	assert.Nil(err)
+	assert.Equal([]interface{}{"c", "a", "b"}, result)
+}
@@ -0,0 +1,89 @@
+package jmespath
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// MergePages evaluates itemsExpr against each page of a paginated response
+// and concatenates the resulting arrays, in page order.  Pages for which
+// itemsExpr evaluates to null contribute no items; any other non-array
+// result is an error.
+func MergePages(pages []interface{}, itemsExpr string) ([]interface{}, error) {
+	return mergePages(pages, itemsExpr, "")
+}
+
+// MergePagesBy is like MergePages, but drops items for which keyExpr
+// evaluates to the same value as an earlier item, which is common when a
+// listing changes while it is being paginated.  Items whose key is null are
+// always kept.
+func MergePagesBy(pages []interface{}, itemsExpr string, keyExpr string) ([]interface{}, error) {
+	if keyExpr == "" {
+		return nil, fmt.Errorf("key expression cannot be empty")
+	}
+	return mergePages(pages, itemsExpr, keyExpr)
+}
+
+func mergePages(pages []interface{}, itemsExpr string, keyExpr string) ([]interface{}, error) {
+	items, err := Compile(itemsExpr)
+	if err != nil {
+		return nil, err
+	}
+	var key *JMESPath
+	if keyExpr != "" {
+		if key, err = Compile(keyExpr); err != nil {
+			return nil, err
+		}
+	}
+	merged := []interface{}{}
+	seen := make(map[interface{}]bool)
+	for i, page := range pages {
+		result, err := items.Search(page)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %s", i, err)
+		}
+		if result == nil {
+			continue
+		}
+		pageItems, ok := result.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("page %d: items expression %q must evaluate to an array", i, itemsExpr)
+		}
+		if key == nil {
+			merged = append(merged, pageItems...)
+			continue
+		}
+		for _, item := range pageItems {
+			k, err := key.Search(item)
+			if err != nil {
+				return nil, fmt.Errorf("page %d: %s", i, err)
+			}
+			if k != nil {
+				id, err := dedupKey(k)
+				if err != nil {
+					return nil, fmt.Errorf("page %d: %s", i, err)
+				}
+				if seen[id] {
+					continue
+				}
+				seen[id] = true
+			}
+			merged = append(merged, item)
+		}
+	}
+	return merged, nil
+}
+
+// dedupKey returns a comparable value identifying k.  Arrays and objects
+// are identified by their JSON encoding.
+func dedupKey(k interface{}) (interface{}, error) {
+	switch k.(type) {
+	case string, float64, bool:
+		return k, nil
+	}
+	encoded, err := json.Marshal(k)
+	if err != nil {
+		return nil, err
+	}
+	return "json:" + string(encoded), nil
+}
@@ -0,0 +1,58 @@
+package jmespath
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func loadPages(t *testing.T, pages ...string) []interface{} {
+	loaded := make([]interface{}, len(pages))
+	for i, page := range pages {
+		if err := json.Unmarshal([]byte(page), &loaded[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return loaded
+}
+
+func TestMergePages(t *testing.T) {
+	assert := assert.New(t)
+	pages := loadPages(t,
+		`{"Reservations": [{"Instances": [{"Id": "a"}, {"Id": "b"}]}], "NextToken": "1"}`,
+		`{"Reservations": [{"Instances": [{"Id": "c"}]}, {"Instances": [{"Id": "b"}]}], "NextToken": "2"}`,
+		`{"Reservations": []}`,
+		`{}`,
+	)
+	merged, err := MergePages(pages, "Reservations[].Instances[]")
+	assert.Nil(err)
+	assert.Len(merged, 4)
+
+	merged, err = MergePagesBy(pages, "Reservations[].Instances[]", "Id")
+	assert.Nil(err)
+	assert.Equal([]interface{}{
+		map[string]interface{}{"Id": "a"},
+		map[string]interface{}{"Id": "b"},
+		map[string]interface{}{"Id": "c"},
+	}, merged)
+}
+
+func TestMergePagesByKeepsNullKeys(t *testing.T) {
+	assert := assert.New(t)
+	pages := loadPages(t, `{"items": [{"k": [1]}, {}, {"k": [1]}, {}]}`)
+	merged, err := MergePagesBy(pages, "items", "k")
+	assert.Nil(err)
+	assert.Len(merged, 3)
+}
+
+func TestMergePagesErrors(t *testing.T) {
+	assert := assert.New(t)
+	pages := loadPages(t, `{"items": "not an array"}`)
+	_, err := MergePages(pages, "items")
+	assert.NotNil(err)
+	_, err = MergePages(pages, "items[")
+	assert.NotNil(err)
+	_, err = MergePagesBy(pages, "items", "")
+	assert.NotNil(err)
+}
@@ -0,0 +1,94 @@
+package jmespath
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// minParallelElements is the number of elements below which projections
+// are evaluated sequentially, as starting workers would cost more than
+// it saves.
+const minParallelElements = 1024
+
+// parallel reports whether a projection over n elements is evaluated in
+// parallel.
+func (intr *treeInterpreter) parallel(n int) bool {
+	return intr.workers > 1 && n >= minParallelElements
+}
+
+// projectParallel evaluates project for each element on intr.workers
+// goroutines, each of which evaluates a contiguous chunk of the elements,
+// and returns the results that are not null in the order of the elements.
+// If project fails, the error for the first failing element is returned,
+// as it would be by a sequential evaluation.
+func (intr *treeInterpreter) projectParallel(elements []interface{}, project evalFunc) ([]interface{}, error) {
+	// Nested projections are evaluated sequentially by the workers.
+	sequential := *intr
+	sequential.workers = 0
+	workers := intr.workers
+	chunkSize := (len(elements) + workers - 1) / workers
+	results := make([][]interface{}, workers)
+	errs := make([]error, workers)
+	// firstFailed is the index of the first chunk that failed.  Chunks
+	// after it stop early, as their results are not needed.
+	firstFailed := int32(workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		start, end := w*chunkSize, (w+1)*chunkSize
+		if start > len(elements) {
+			start = len(elements)
+		}
+		if end > len(elements) {
+			end = len(elements)
+		}
+		wg.Add(1)
+		go func(w int, chunk []interface{}) {
+			defer wg.Done()
+			errs[w] = projectChunk(&sequential, chunk, project, &results[w], func() bool {
+				return atomic.LoadInt32(&firstFailed) < int32(w)
+			})
+			if errs[w] != nil {
+				for {
+					failed := atomic.LoadInt32(&firstFailed)
+					if failed <= int32(w) || atomic.CompareAndSwapInt32(&firstFailed, failed, int32(w)) {
+						break
+					}
+				}
+			}
+		}(w, elements[start:end])
+	}
+	wg.Wait()
+	if failed := atomic.LoadInt32(&firstFailed); failed < int32(workers) {
+		return nil, errs[failed]
+	}
+	n := 0
+	for _, result := range results {
+		n += len(result)
+	}
+	collected := make([]interface{}, 0, n)
+	for _, result := range results {
+		collected = append(collected, result...)
+	}
+	return collected, nil
+}
+
+// projectChunk evaluates project for the elements of chunk and stores the
+// results that are not null in collected, unless stop returns true.
+// Panics are returned as errors so that they do not crash the program
+// from a goroutine the caller's recoverInternal does not run on.
+func projectChunk(intr *treeInterpreter, chunk []interface{}, project evalFunc, collected *[]interface{}, stop func() bool) (err error) {
+	defer recoverInternal(&err)
+	for _, element := range chunk {
+		if stop() {
+			return nil
+		}
+		current, err := project(intr, element)
+		if err != nil {
+			return err
+		}
+		if current != nil {
+			*collected = append(*collected, current)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package jmespath
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func parallelRecords(n int) []interface{} {
+	records := make([]interface{}, n)
+	for i := range records {
+		records[i] = map[string]interface{}{
+			"id":    float64(i),
+			"score": float64(i % 7),
+			"tags":  []interface{}{fmt.Sprint(i), nil},
+		}
+	}
+	return records
+}
+
+func TestParallelProjections(t *testing.T) {
+	assert := assert.New(t)
+	rt := NewRuntime()
+	rt.SetParallelism(4)
+	data := map[string]interface{}{"records": parallelRecords(5000)}
+	for _, expression := range []string{
+		"records[*].id",
+		"records[?score > `3`].id",
+		"records[?score == `0`].tags[*]",
+		"records[*].missing",
+		"length(records[?id > `4000`])",
+	} {
+		expected, err := Search(expression, data)
+		assert.Nil(err)
+		jp, err := rt.Compile(expression)
+		assert.Nil(err)
+		result, err := jp.Search(data)
+		assert.Nil(err)
+		assert.Equal(expected, result, expression)
+	}
+}
+
+func TestParallelProjectionsBelowThreshold(t *testing.T) {
+	assert := assert.New(t)
+	var calls int32
+	rt := NewRuntime()
+	rt.SetParallelism(4)
+	rt.RegisterFunction("count", func(ctx CallContext, args []interface{}) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return true, nil
+	})
+	jp, err := rt.Compile("[?count(@)]")
+	assert.Nil(err)
+	for _, n := range []int{10, 4000} {
+		atomic.StoreInt32(&calls, 0)
+		result, err := jp.Search(parallelRecords(n))
+		assert.Nil(err)
+		assert.Len(result, n)
+		assert.Equal(int32(n), atomic.LoadInt32(&calls))
+	}
+}
+
+func TestParallelProjectionsReturnFirstError(t *testing.T) {
+	assert := assert.New(t)
+	rt := NewRuntime()
+	rt.SetParallelism(8)
+	rt.RegisterFunction("check", func(ctx CallContext, args []interface{}) (interface{}, error) {
+		id := args[0].(float64)
+		if id == 100 || id == 3000 {
+			return nil, fmt.Errorf("bad record %v", id)
+		}
+		if id == 2000 {
+			panic("broken record")
+		}
+		return true, nil
+	})
+	jp, err := rt.Compile("[?check(id)]")
+	assert.Nil(err)
+	for i := 0; i < 20; i++ {
+		_, err = jp.Search(parallelRecords(4000))
+		if assert.NotNil(err) {
+			assert.Contains(err.Error(), "bad record 100")
+		}
+	}
+
+	_, err = jp.Search(parallelRecords(4000)[1000:])
+	var fnErr *FunctionError
+	if assert.True(errors.As(err, &fnErr)) {
+		assert.Contains(err.Error(), "broken record")
+	}
+}
@@ -0,0 +1,59 @@
+package jmespath
+
+import (
+	"sort"
+	"time"
+)
+
+// SearchWithParams is like Search, but supplies the values of the named
+// parameters used in the expression.  A parameter is written as a colon
+// followed by its name, for example "people[?name == :name]", and
+// evaluates to params["name"].  Evaluating a parameter that has no value
+// in params is an error.  Directly after an opening bracket a colon starts
+// a slice, so "[:n]" is not a parameter.
+//
+// Parameters are never parsed as part of the expression, so unlike
+// formatting values into the expression string they cannot change its
+// meaning.  Values should be of the types produced by encoding/json.
+func (jp *JMESPath) SearchWithParams(data interface{}, params map[string]interface{}) (result interface{}, err error) {
+	if jp.intr.metrics != nil {
+		defer jp.observe(time.Now(), &err)
+	}
+	defer recoverInternal(&err)
+	intr := *jp.intr
+	intr.params = params
+	result, err = jp.eval(&intr, data)
+	return intr.render(result), locateError(jp.expression, err)
+}
+
+// SearchWithParams evaluates a JMESPath expression with named parameters
+// against input data and returns the result.
+func SearchWithParams(expression string, data interface{}, params map[string]interface{}) (interface{}, error) {
+	jp, err := Compile(expression)
+	if err != nil {
+		return nil, err
+	}
+	return jp.SearchWithParams(data, params)
+}
+
+// Params returns the sorted names of the parameters used in the
+// expression.
+func (jp *JMESPath) Params() []string {
+	seen := make(map[string]bool)
+	collectParams(jp.ast, seen)
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func collectParams(node ASTNode, seen map[string]bool) {
+	if node.nodeType == ASTParameter {
+		seen[node.value.(string)] = true
+	}
+	for _, child := range node.children {
+		collectParams(child, seen)
+	}
+}
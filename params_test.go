@@ -0,0 +1,53 @@
+package jmespath
+
+import (
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func TestSearchWithParams(t *testing.T) {
+	assert := assert.New(t)
+	data := map[string]interface{}{
+		"people": []interface{}{
+			map[string]interface{}{"name": "a", "age": 20.0},
+			map[string]interface{}{"name": "b", "age": 30.0},
+			map[string]interface{}{"name": "c", "age": 40.0},
+		},
+	}
+	jp, err := Compile("people[?age >= :min && name != :skip].name")
+	assert.Nil(err)
+	assert.Equal([]string{"min", "skip"}, jp.Params())
+
+	result, err := jp.SearchWithParams(data, map[string]interface{}{"min": 25.0, "skip": "c"})
+	assert.Nil(err)
+	assert.Equal([]interface{}{"b"}, result)
+
+	result, err = jp.SearchWithParams(data, map[string]interface{}{"min": 0.0, "skip": "a"})
+	assert.Nil(err)
+	assert.Equal([]interface{}{"b", "c"}, result)
+}
+
+func TestSearchWithParamsIsNotParsed(t *testing.T) {
+	assert := assert.New(t)
+	data := map[string]interface{}{"a": "x", "b": "y"}
+	result, err := SearchWithParams("{key: :key, a: a}", data, map[string]interface{}{"key": "b || a"})
+	assert.Nil(err)
+	assert.Equal(map[string]interface{}{"key": "b || a", "a": "x"}, result)
+}
+
+func TestSearchWithParamsMissing(t *testing.T) {
+	assert := assert.New(t)
+	_, err := SearchWithParams("foo == :value", map[string]interface{}{}, nil)
+	assert.NotNil(err)
+	_, err = Search("foo == :value", map[string]interface{}{})
+	assert.NotNil(err)
+}
+
+func TestParameterSyntaxErrors(t *testing.T) {
+	assert := assert.New(t)
+	for _, expression := range []string{":", "foo == :", ":`1`", "foo[:name]"} {
+		_, err := Compile(expression)
+		assert.NotNil(err, expression)
+	}
+}
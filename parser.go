@@ -1,7 +1,6 @@
 package jmespath
 
 import (
-	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
@@ -34,6 +33,8 @@ const (
 	ASTSubexpression
 	ASTSlice
 	ASTValueProjection
+	ASTParameter
+	ASTDescendant
 )
 
 // ASTNode represents the abstract syntax tree of a JMESPath expression.
@@ -89,6 +90,7 @@ var bindingPowers = map[tokType]int{
 	tCurrent:            0,
 	tExpref:             0,
 	tColon:              0,
+	tMacro:              0,
 	tPipe:               1,
 	tOr:                 2,
 	tAnd:                3,
@@ -98,21 +100,63 @@ var bindingPowers = map[tokType]int{
 	tGT:                 5,
 	tGTE:                5,
 	tNE:                 5,
+	tIn:                 5,
+	tNotIn:              5,
 	tFlatten:            9,
 	tStar:               20,
 	tFilter:             21,
 	tDot:                40,
+	tDotDot:             40,
 	tNot:                45,
 	tLbrace:             50,
 	tLbracket:           55,
 	tLparen:             60,
 }
 
+// maxNesting is the maximum depth of the AST of an expression.  ASTs are
+// parsed, compiled and evaluated recursively, so deeper ASTs could exhaust
+// the stack, which unlike a panic cannot be recovered from.
+const maxNesting = 1000
+
 // Parser holds state about the current expression being parsed.
 type Parser struct {
 	expression string
 	tokens     []token
 	index      int
+	// depth is the number of parseExpression calls in progress.
+	depth int
+	// calls are the function calls in the expression, used to locate
+	// evaluation errors.
+	calls []callSpan
+	// names are the offsets of the identifiers parsed as field or function
+	// names, in the order they appear in the expression, used to locate
+	// schema issues.
+	names []int
+	// exactIntegers is set to parse the integers in JSON literals in the
+	// representation of exactNumber.
+	exactIntegers bool
+	// recursiveDescent is set to accept the recursive descent operator
+	// "..", see Runtime.SetRecursiveDescent.
+	recursiveDescent bool
+	// legacyLiterals is set to accept JSON literals with elided quotes,
+	// see Runtime.SetLegacyLiterals.
+	legacyLiterals bool
+	// limits bound the size of the expression, see Runtime.SetLimits.
+	limits Limits
+	// macros are the expressions of the macros that "#name" refers to,
+	// see Runtime.DefineMacro, and expanding the names of the macros being
+	// expanded, to detect macros that refer to themselves.
+	macros    map[string]string
+	expanding []string
+	// anyMacro is set to parse references to any macro as the current
+	// node, to check the expression of a macro on its own.
+	anyMacro bool
+}
+
+// callSpan is the location of a function call in an expression.
+type callSpan struct {
+	name       string
+	start, end int
 }
 
 // NewParser creates a new JMESPath parser.
@@ -122,15 +166,25 @@ func NewParser() *Parser {
 }
 
 // Parse will compile a JMESPath expression.
-func (p *Parser) Parse(expression string) (ASTNode, error) {
+func (p *Parser) Parse(expression string) (_ ASTNode, err error) {
+	defer recoverInternal(&err)
 	lexer := NewLexer()
 	p.expression = expression
 	p.index = 0
+	p.depth = 0
+	p.calls = nil
+	p.names = nil
+	if err := p.checkLength(); err != nil {
+		return ASTNode{}, err
+	}
 	tokens, err := lexer.tokenize(expression)
 	if err != nil {
 		return ASTNode{}, err
 	}
-	p.tokens = tokens
+	p.tokens = membershipOperators(tokens)
+	if err := p.checkTokens(); err != nil {
+		return ASTNode{}, err
+	}
 	parsed, err := p.parseExpression(0)
 	if err != nil {
 		return ASTNode{}, err
@@ -139,10 +193,79 @@ func (p *Parser) Parse(expression string) (ASTNode, error) {
 		return ASTNode{}, p.syntaxError(fmt.Sprintf(
 			"Unexpected token at the end of the expression: %s", p.current()))
 	}
+	if tooDeep(parsed, maxNesting) {
+		return ASTNode{}, p.syntaxErrorToken("Expression is nested too deeply.", p.tokens[0])
+	}
+	if max := p.limits.MaxDepth; max > 0 && tooDeep(parsed, max) {
+		return ASTNode{}, p.depthError(max + 1)
+	}
 	return parsed, nil
 }
 
+// endsExpression reports whether a token of type t may be the last token
+// of an expression.
+func endsExpression(t tokType) bool {
+	switch t {
+	case tUnquotedIdentifier, tQuotedIdentifier, tRbracket, tRparen, tRbrace,
+		tJSONLiteral, tStringLiteral, tCurrent, tStar:
+		return true
+	}
+	return false
+}
+
+// membershipOperators returns tokens with the identifiers "in" and "not in"
+// that follow the end of an expression, as in
+// "status in `["active", "pending"]`", replaced by membership operators.
+// Elsewhere they remain identifiers, so that "in" and "not" can still be
+// used as field names.
+func membershipOperators(tokens []token) []token {
+	endsAt := func(i int) bool {
+		return i >= 0 && endsExpression(tokens[i].tokenType)
+	}
+	isWord := func(i int, word string) bool {
+		return i < len(tokens) && tokens[i].tokenType == tUnquotedIdentifier && tokens[i].value == word
+	}
+	result := tokens[:0:0]
+	for i := 0; i < len(tokens); i++ {
+		current := tokens[i]
+		if endsAt(i - 1) {
+			switch {
+			case isWord(i, "in"):
+				current.tokenType = tIn
+			case isWord(i, "not") && isWord(i+1, "in"):
+				current.tokenType = tNotIn
+				current.value = "not in"
+				current.length = tokens[i+1].position + tokens[i+1].length - current.position
+				i++
+			}
+		}
+		result = append(result, current)
+	}
+	return result
+}
+
+// tooDeep reports whether node is nested more than limit levels deep.
+func tooDeep(node ASTNode, limit int) bool {
+	if limit == 0 {
+		return true
+	}
+	for _, child := range node.children {
+		if tooDeep(child, limit-1) {
+			return true
+		}
+	}
+	return false
+}
+
 func (p *Parser) parseExpression(bindingPower int) (ASTNode, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > maxNesting {
+		return ASTNode{}, p.syntaxError("Expression is nested too deeply.")
+	}
+	if err := p.depthError(p.depth); err != nil {
+		return ASTNode{}, err
+	}
 	var err error
 	leftToken := p.lookaheadToken(0)
 	p.advance()
@@ -233,6 +356,8 @@ func (p *Parser) led(tokenType tokType, node ASTNode) (ASTNode, error) {
 			nodeType: ASTValueProjection,
 			children: []ASTNode{node, right},
 		}, err
+	case tDotDot:
+		return p.parseDescendant(node)
 	case tPipe:
 		right, err := p.parseExpression(bindingPowers[tPipe])
 		return ASTNode{nodeType: ASTPipe, children: []ASTNode{node, right}}, err
@@ -243,7 +368,11 @@ func (p *Parser) led(tokenType tokType, node ASTNode) (ASTNode, error) {
 		right, err := p.parseExpression(bindingPowers[tAnd])
 		return ASTNode{nodeType: ASTAndExpression, children: []ASTNode{node, right}}, err
 	case tLparen:
-		name := node.value
+		if node.nodeType != ASTField {
+			return ASTNode{}, p.syntaxErrorToken("Invalid function name.", p.tokens[p.index-1])
+		}
+		name := node.value.(string)
+		start := p.tokens[p.index-2].position
 		var args []ASTNode
 		for p.current() != tRparen {
 			expression, err := p.parseExpression(0)
@@ -260,6 +389,7 @@ func (p *Parser) led(tokenType tokType, node ASTNode) (ASTNode, error) {
 		if err := p.match(tRparen); err != nil {
 			return ASTNode{}, err
 		}
+		p.calls = append(p.calls, callSpan{name, start, p.tokens[p.index-1].position + 1})
 		return ASTNode{
 			nodeType: ASTFunctionExpression,
 			value:    name,
@@ -274,7 +404,7 @@ func (p *Parser) led(tokenType tokType, node ASTNode) (ASTNode, error) {
 			nodeType: ASTProjection,
 			children: []ASTNode{left, right},
 		}, err
-	case tEQ, tNE, tGT, tGTE, tLT, tLTE:
+	case tEQ, tNE, tGT, tGTE, tLT, tLTE, tIn, tNotIn:
 		right, err := p.parseExpression(bindingPowers[tokenType])
 		if err != nil {
 			return ASTNode{}, err
@@ -317,8 +447,7 @@ func (p *Parser) led(tokenType tokType, node ASTNode) (ASTNode, error) {
 func (p *Parser) nud(token token) (ASTNode, error) {
 	switch token.tokenType {
 	case tJSONLiteral:
-		var parsed interface{}
-		err := json.Unmarshal([]byte(token.value), &parsed)
+		parsed, err := p.parseJSONLiteral(token)
 		if err != nil {
 			return ASTNode{}, err
 		}
@@ -326,11 +455,13 @@ func (p *Parser) nud(token token) (ASTNode, error) {
 	case tStringLiteral:
 		return ASTNode{nodeType: ASTLiteral, value: token.value}, nil
 	case tUnquotedIdentifier:
+		p.names = append(p.names, token.position)
 		return ASTNode{
 			nodeType: ASTField,
 			value:    token.value,
 		}, nil
 	case tQuotedIdentifier:
+		p.names = append(p.names, token.position)
 		node := ASTNode{nodeType: ASTField, value: token.value}
 		if p.current() == tLparen {
 			return ASTNode{}, p.syntaxErrorToken("Can't have quoted identifier as function name.", token)
@@ -348,6 +479,8 @@ func (p *Parser) nud(token token) (ASTNode, error) {
 		return ASTNode{nodeType: ASTValueProjection, children: []ASTNode{left, right}}, err
 	case tFilter:
 		return p.parseFilter(ASTNode{nodeType: ASTIdentity})
+	case tDotDot:
+		return p.parseDescendant(ASTNode{nodeType: ASTIdentity})
 	case tLbrace:
 		return p.parseMultiSelectHash()
 	case tFlatten:
@@ -366,7 +499,7 @@ func (p *Parser) nud(token token) (ASTNode, error) {
 		if tokenType == tNumber || tokenType == tColon {
 			right, err := p.parseIndexExpression()
 			if err != nil {
-				return ASTNode{}, nil
+				return ASTNode{}, err
 			}
 			return p.projectIfSlice(ASTNode{nodeType: ASTIdentity}, right)
 		} else if tokenType == tStar && p.lookahead(1) == tRbracket {
@@ -385,6 +518,16 @@ func (p *Parser) nud(token token) (ASTNode, error) {
 		}
 	case tCurrent:
 		return ASTNode{nodeType: ASTCurrentNode}, nil
+	case tMacro:
+		return p.expandMacro(token)
+	case tColon:
+		// A named parameter, ":name", whose value is supplied when the
+		// expression is evaluated.
+		nameToken := p.lookaheadToken(0)
+		if err := p.match(tUnquotedIdentifier); err != nil {
+			return ASTNode{}, p.syntaxErrorToken("Expected parameter name after ':'", nameToken)
+		}
+		return ASTNode{nodeType: ASTParameter, value: nameToken.value}, nil
 	case tExpref:
 		expression, err := p.parseExpression(bindingPowers[tExpref])
 		if err != nil {
@@ -521,6 +664,26 @@ func (p *Parser) parseFilter(node ASTNode) (ASTNode, error) {
 	}, nil
 }
 
+// parseDescendant parses the key following "..", the recursive descent
+// operator, into a projection over the values of the key at any depth of
+// node, as in "..spec.containers[]".
+func (p *Parser) parseDescendant(node ASTNode) (ASTNode, error) {
+	if !p.recursiveDescent {
+		return ASTNode{}, p.syntaxErrorToken("Recursive descent (..) is not enabled.", p.tokens[p.index-1])
+	}
+	key := p.lookaheadToken(0)
+	if key.tokenType != tUnquotedIdentifier && key.tokenType != tQuotedIdentifier {
+		return ASTNode{}, p.syntaxError("Expected identifier after .., received: " + p.current().String())
+	}
+	p.advance()
+	left := ASTNode{nodeType: ASTDescendant, value: key.value, children: []ASTNode{node}}
+	right, err := p.parseProjectionRHS(bindingPowers[tStar])
+	return ASTNode{
+		nodeType: ASTProjection,
+		children: []ASTNode{left, right},
+	}, err
+}
+
 func (p *Parser) parseDotRHS(bindingPower int) (ASTNode, error) {
 	lookahead := p.current()
 	if tokensOneOf([]tokType{tQuotedIdentifier, tUnquotedIdentifier, tStar}, lookahead) {
@@ -553,6 +716,9 @@ func (p *Parser) parseProjectionRHS(bindingPower int) (ASTNode, error) {
 			return ASTNode{}, err
 		}
 		return p.parseDotRHS(bindingPower)
+	} else if current == tDotDot {
+		p.advance()
+		return p.parseDescendant(ASTNode{nodeType: ASTIdentity})
 	} else {
 		return ASTNode{}, p.syntaxError("Error")
 	}
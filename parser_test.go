@@ -2,6 +2,7 @@ package jmespath
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/jmespath/go-jmespath/internal/testify/assert"
@@ -22,6 +23,12 @@ var parsingErrorTests = []struct {
 	{`{foo bar}`, "Invalid"},
 	{`[foo bar]`, "Invalid"},
 	{`foo@`, "Invalid"},
+	{"[0", "Incomplete index expression"},
+	{"[:", "Incomplete slice expression"},
+	{"`1`(a)", "Invalid function name"},
+	{"a[0](b)", "Invalid function name"},
+	{"'abc", "Unterminated raw string"},
+	{"a\u0080", "Invalid character"},
 	{`&&&&&&&&&&&&t(`, "Invalid"},
 	{`[*][`, "Invalid"},
 }
@@ -35,6 +42,27 @@ func TestParsingErrors(t *testing.T) {
 	}
 }
 
+func TestParsingDeeplyNestedExpressions(t *testing.T) {
+	assert := assert.New(t)
+	parser := NewParser()
+	n := 100000
+	for _, expression := range []string{
+		strings.Repeat("(", n) + "a" + strings.Repeat(")", n),
+		strings.Repeat("!", n) + "a",
+		strings.Repeat("[", n),
+		strings.Repeat("{a: ", n) + "a" + strings.Repeat("}", n),
+		"a" + strings.Repeat(".a", n),
+		strings.Repeat("a | ", n) + "a",
+	} {
+		_, err := parser.Parse(expression)
+		if assert.IsType(SyntaxError{}, err) {
+			assert.Equal("Expression is nested too deeply.", err.(SyntaxError).msg)
+		}
+	}
+	_, err := parser.Parse(strings.Repeat("(", maxNesting-1) + "a" + strings.Repeat(")", maxNesting-1))
+	assert.Nil(err)
+}
+
 var prettyPrinted = `ASTProjection {
   children: {
     ASTField {
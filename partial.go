@@ -0,0 +1,105 @@
+package jmespath
+
+import "errors"
+
+// ErrReadsDocument is returned by Specialize for expressions that read the
+// document as a whole, such as "@", "*.limit", "..name" or "keys(@)", as
+// their results would depend on the known fields the residual document
+// lacks.
+var ErrReadsDocument = errors.New("expression reads the whole document")
+
+// Specialize partially evaluates the expression for documents whose
+// top-level fields in known have the given values, and returns the residual
+// expression.  Every reference to one of those fields is replaced by its
+// value, and any part of the expression that then only depends on literals
+// is evaluated once, up front.
+//
+// The residual expression must be searched with the rest of the document,
+// which is expected to be an object.  The values in known take precedence
+// over fields of the same name in that document.  Expressions that read
+// the document as a whole rather than its fields cannot be specialized
+// and return ErrReadsDocument.
+//
+// For example, specializing "items[?size > config.limit]" for a known
+// "config" of {"limit": 10} gives the equivalent of
+// "items[?size > `10`]".
+func (jp *JMESPath) Specialize(known map[string]interface{}) (*JMESPath, error) {
+	residual, err := bindFields(jp.ast, known, jp.intr.fCall)
+	if err != nil {
+		return nil, err
+	}
+	return newJMESPath(residual, jp.intr), nil
+}
+
+// bindFields replaces the fields of known that node reads from the value it
+// is evaluated against with literals.  Calls are resolved with functions.
+// It returns ErrReadsDocument if node reads that value as a whole.
+func bindFields(node ASTNode, known map[string]interface{}, functions *functionCaller) (ASTNode, error) {
+	switch node.nodeType {
+	case ASTField:
+		if value, ok := known[node.value.(string)]; ok {
+			return ASTNode{nodeType: ASTLiteral, value: value}, nil
+		}
+		return node, nil
+	case ASTCurrentNode, ASTIdentity:
+		return node, ErrReadsDocument
+	case ASTSubexpression, ASTIndexExpression, ASTPipe,
+		ASTProjection, ASTValueProjection, ASTFilterProjection:
+		// Only the leftmost child is evaluated against the same value
+		// as node, the rest see values derived from it.  A current node
+		// passes that value on to the next child, as in "@.a".
+		children := append([]ASTNode{}, node.children...)
+		first := 0
+		if node.nodeType == ASTSubexpression || node.nodeType == ASTIndexExpression || node.nodeType == ASTPipe {
+			for first < len(children)-1 && isCurrentNode(children[first]) {
+				first++
+			}
+		}
+		bound, err := bindFields(children[first], known, functions)
+		if err != nil {
+			return node, err
+		}
+		children[first] = bound
+		node.children = children
+		return node, nil
+	case ASTExpRef:
+		// Expression references are applied to values chosen by the
+		// function they are passed to.
+		return node, nil
+	case ASTFunctionExpression:
+		// Except for the functions that apply them to the same value as
+		// the call.
+		if functions.functionTable[node.value.(string)].refersToCurrent() {
+			children := make([]ASTNode, len(node.children))
+			for i, child := range node.children {
+				target := child
+				if child.nodeType == ASTExpRef {
+					target = child.children[0]
+				}
+				bound, err := bindFields(target, known, functions)
+				if err != nil {
+					return node, err
+				}
+				if child.nodeType == ASTExpRef {
+					child.children = []ASTNode{bound}
+					bound = child
+				}
+				children[i] = bound
+			}
+			node.children = children
+			return node, nil
+		}
+	}
+	if len(node.children) > 0 {
+		children := make([]ASTNode, len(node.children))
+		for i, child := range node.children {
+			bound, err := bindFields(child, known, functions)
+			if err != nil {
+				return node, err
+			}
+			children[i] = bound
+		}
+		node.children = children
+	}
+	return node, nil
+}
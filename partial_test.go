@@ -0,0 +1,102 @@
+package jmespath
+
+import (
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func TestSpecialize(t *testing.T) {
+	assert := assert.New(t)
+	jp := MustCompile("{big: items[?size > `10`].name, limit: config.limit, label: join('-', [config.prefix, name])}")
+	known := map[string]interface{}{"config": map[string]interface{}{"limit": 10.0, "prefix": "p"}}
+	residual, err := jp.Specialize(known)
+	assert.Nil(err)
+
+	expected := MustCompile("{big: items[?size > `10`].name, limit: `10`, label: join('-', ['p', name])}")
+	assert.Equal(expected.ast, residual.ast)
+
+	data := map[string]interface{}{"name": "n", "items": []interface{}{
+		map[string]interface{}{"name": "small", "size": 5.0},
+		map[string]interface{}{"name": "big", "size": 50.0},
+	}}
+	result, err := residual.Search(data)
+	assert.Nil(err)
+	assert.Equal(map[string]interface{}{
+		"big":   []interface{}{"big"},
+		"limit": 10.0,
+		"label": "p-n",
+	}, result)
+}
+
+func TestSpecializeFoldsKnownSubexpressions(t *testing.T) {
+	assert := assert.New(t)
+	jp := MustCompile("config.enabled && config.mode == 'fast' || fallback")
+	residual, err := jp.Specialize(map[string]interface{}{
+		"config": map[string]interface{}{"enabled": true, "mode": "fast"},
+	})
+	assert.Nil(err)
+	assert.Equal(ASTLiteral, residual.ast.nodeType)
+	assert.Equal(true, residual.ast.value)
+
+	residual, err = jp.Specialize(map[string]interface{}{
+		"config": map[string]interface{}{"enabled": false},
+	})
+	assert.Nil(err)
+	result, err := residual.Search(map[string]interface{}{"fallback": "yes"})
+	assert.Nil(err)
+	assert.Equal("yes", result)
+}
+
+func TestSpecializeBindsReferencesToTheCurrentNode(t *testing.T) {
+	assert := assert.New(t)
+	// first_of applies its expression references to the current node,
+	jp := MustCompile("first_of(&p, &q)")
+	residual, err := jp.Specialize(map[string]interface{}{"p": 5.0})
+	assert.Nil(err)
+	result, err := residual.Search(map[string]interface{}{"p": 1.0, "q": 2.0})
+	assert.Nil(err)
+	assert.Equal(5.0, result)
+
+	// So does exists.
+	residual, err = MustCompile("exists(&p)").Specialize(map[string]interface{}{"p": 5.0})
+	assert.Nil(err)
+	result, err = residual.Search(map[string]interface{}{})
+	assert.Nil(err)
+	assert.Equal(true, result)
+}
+
+func TestSpecializeOnlyBindsTopLevelFields(t *testing.T) {
+	assert := assert.New(t)
+	// Inside filters, projections and expression references fields are
+	// relative to the current element, not the document.
+	jp := MustCompile("a.config || items[*].config || items[?config] || sort_by(items, &config)")
+	residual, err := jp.Specialize(map[string]interface{}{"config": "known"})
+	assert.Nil(err)
+	assert.Equal(jp.ast, residual.ast)
+}
+
+func TestSpecializeRejectsReadsOfTheDocument(t *testing.T) {
+	assert := assert.New(t)
+	known := map[string]interface{}{"config": map[string]interface{}{"limit": 10.0}}
+	for _, expression := range []string{
+		"@",
+		"*.limit",
+		"keys(@)",
+		"length(@)",
+		"{doc: @, name: name}",
+		"name || @",
+		"first_of(&name, &@)",
+		"@ | keys(@)",
+	} {
+		_, err := MustCompile(expression).Specialize(known)
+		assert.Equal(ErrReadsDocument, err, expression)
+	}
+	// The current node at the start of a subexpression only passes the
+	// document on to the field.
+	residual, err := MustCompile("@.config.limit").Specialize(known)
+	assert.Nil(err)
+	result, err := residual.Search(map[string]interface{}{})
+	assert.Nil(err)
+	assert.Equal(10.0, result)
+}
@@ -0,0 +1,386 @@
+package jmespath
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/* Patches are applied without modifying the patched document: only the
+   objects and arrays on the way to a changed location are copied, and the
+   rest of the document is shared with the patched one.  Besides the
+   patched document, applying a patch returns the paths of the locations it
+   changed, in the form of referencedPaths, so that they can be compared
+   with the paths an expression reads.  As referenced paths do not include
+   array indexes, the indexes of changed locations are left out as well.
+*/
+
+// ErrPatchTestFailed is returned when a "test" operation of a JSON Patch
+// finds a different value than expected.
+var ErrPatchTestFailed = errors.New("test operation failed")
+
+// applyJSONPatch applies the JSON Patch (RFC 6902) patch to document.
+func (intr *treeInterpreter) applyJSONPatch(document interface{}, patch []byte) (interface{}, [][]string, error) {
+	var operations []map[string]json.RawMessage
+	if err := json.Unmarshal(patch, &operations); err != nil {
+		return nil, nil, fmt.Errorf("invalid JSON Patch: %s", err)
+	}
+	var changed [][]string
+	for i, operation := range operations {
+		var err error
+		document, changed, err = intr.applyOperation(document, operation, changed)
+		if err != nil {
+			return nil, nil, fmt.Errorf("JSON Patch operation %d: %s", i, err)
+		}
+	}
+	return document, changed, nil
+}
+
+func (intr *treeInterpreter) applyOperation(document interface{}, operation map[string]json.RawMessage, changed [][]string) (interface{}, [][]string, error) {
+	var op string
+	if err := json.Unmarshal(operation["op"], &op); err != nil {
+		return nil, nil, errors.New(`invalid or missing "op"`)
+	}
+	path, err := operationPointer(operation, "path")
+	if err != nil {
+		return nil, nil, err
+	}
+	var value interface{}
+	switch op {
+	case "add", "replace", "test":
+		raw, ok := operation["value"]
+		if !ok {
+			return nil, nil, errors.New(`missing "value"`)
+		}
+		if value, err = intr.decode(raw); err != nil {
+			return nil, nil, err
+		}
+	case "move", "copy":
+		from, err := operationPointer(operation, "from")
+		if err != nil {
+			return nil, nil, err
+		}
+		if value, err = lookupPointer(document, from); err != nil {
+			return nil, nil, err
+		}
+		if op == "move" {
+			if len(from) < len(path) && isPointerPrefix(from, path) {
+				return nil, nil, errors.New("cannot move a value into itself")
+			}
+			changed = append(changed, changedPath(document, from))
+			if document, err = removePointer(document, from); err != nil {
+				return nil, nil, err
+			}
+		}
+	case "remove":
+	default:
+		return nil, nil, fmt.Errorf("unknown op %q", op)
+	}
+	switch op {
+	case "test":
+		current, err := lookupPointer(document, path)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !objsEqual(current, value) {
+			return nil, nil, ErrPatchTestFailed
+		}
+		return document, changed, nil
+	case "remove":
+		changed = append(changed, changedPath(document, path))
+		document, err = removePointer(document, path)
+	case "replace":
+		if _, err := lookupPointer(document, path); err != nil {
+			return nil, nil, err
+		}
+		changed = append(changed, changedPath(document, path))
+		document, err = setPointer(document, path, value, false)
+	default:
+		changed = append(changed, changedPath(document, path))
+		document, err = setPointer(document, path, value, true)
+	}
+	return document, changed, err
+}
+
+func operationPointer(operation map[string]json.RawMessage, name string) ([]string, error) {
+	var pointer string
+	if err := json.Unmarshal(operation[name], &pointer); err != nil {
+		return nil, fmt.Errorf("invalid or missing %q", name)
+	}
+	return parsePointer(pointer)
+}
+
+// parsePointer splits a JSON Pointer (RFC 6901) into its reference
+// tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if pointer[0] != '/' {
+		return nil, fmt.Errorf("invalid JSON Pointer %q", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.Replace(strings.Replace(token, "~1", "/", -1), "~0", "~", -1)
+	}
+	return tokens, nil
+}
+
+func isPointerPrefix(prefix, tokens []string) bool {
+	for i := range prefix {
+		if prefix[i] != tokens[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// changedPath returns the path of the location tokens refers to in
+// document, leaving out array indexes.
+func changedPath(document interface{}, tokens []string) []string {
+	path := []string{}
+	current := document
+	for _, token := range tokens {
+		if array, ok := current.([]interface{}); ok {
+			current = nil
+			if index, err := arrayIndex(token, len(array)-1); err == nil {
+				current = array[index]
+			}
+			continue
+		}
+		path = append(path, formatIdentifier(token))
+		current, _ = childValue(current, token)
+	}
+	return path
+}
+
+// arrayIndex parses the array index token, which must not be greater
+// than max.
+func arrayIndex(token string, max int) (int, error) {
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || (len(token) > 1 && token[0] == '0') || token[0] == '+' {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	if index > max {
+		return 0, fmt.Errorf("array index %d out of range", index)
+	}
+	return index, nil
+}
+
+// childValue returns the element token of the object or array value.
+func childValue(value interface{}, token string) (interface{}, bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		child, ok := v[token]
+		return child, ok
+	case *OrderedMap:
+		return v.Get(token)
+	case []interface{}:
+		index, err := arrayIndex(token, len(v)-1)
+		if err != nil {
+			return nil, false
+		}
+		return v[index], true
+	}
+	return nil, false
+}
+
+func lookupPointer(document interface{}, tokens []string) (interface{}, error) {
+	current := document
+	for i, token := range tokens {
+		child, ok := childValue(current, token)
+		if !ok {
+			return nil, fmt.Errorf("path %q not found", "/"+strings.Join(tokens[:i+1], "/"))
+		}
+		current = child
+	}
+	return current, nil
+}
+
+// updateParent returns a copy of document in which the container holding
+// the location tokens refers to is replaced by the result of update.
+func updateParent(document interface{}, tokens []string, update func(container interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(tokens) == 1 {
+		return update(document, tokens[0])
+	}
+	child, ok := childValue(document, tokens[0])
+	if !ok {
+		return nil, fmt.Errorf("path element %q not found", tokens[0])
+	}
+	updated, err := updateParent(child, tokens[1:], update)
+	if err != nil {
+		return nil, err
+	}
+	return withChild(document, tokens[0], updated, false)
+}
+
+// setPointer returns a copy of document with value at the location tokens
+// refers to.  If insert is set, the location may be a new key of an
+// object, and value is inserted into an array rather than replacing an
+// element.
+func setPointer(document interface{}, tokens []string, value interface{}, insert bool) (interface{}, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	return updateParent(document, tokens, func(container interface{}, token string) (interface{}, error) {
+		return withChild(container, token, value, insert)
+	})
+}
+
+func removePointer(document interface{}, tokens []string) (interface{}, error) {
+	if len(tokens) == 0 {
+		return nil, errors.New("cannot remove the whole document")
+	}
+	return updateParent(document, tokens, withoutChild)
+}
+
+// withChild returns a copy of the object or array container with the
+// element token set to value.
+func withChild(container interface{}, token string, value interface{}, insert bool) (interface{}, error) {
+	switch c := container.(type) {
+	case map[string]interface{}:
+		if _, ok := c[token]; !ok && !insert {
+			return nil, fmt.Errorf("key %q not found", token)
+		}
+		copied := make(map[string]interface{}, len(c)+1)
+		for key, element := range c {
+			copied[key] = element
+		}
+		copied[token] = value
+		return copied, nil
+	case *OrderedMap:
+		if _, ok := c.values[token]; !ok && !insert {
+			return nil, fmt.Errorf("key %q not found", token)
+		}
+		copied := NewOrderedMap()
+		for _, key := range c.keys {
+			copied.Set(key, c.values[key])
+		}
+		copied.Set(token, value)
+		return copied, nil
+	case []interface{}:
+		if !insert {
+			index, err := arrayIndex(token, len(c)-1)
+			if err != nil {
+				return nil, err
+			}
+			copied := append([]interface{}(nil), c...)
+			copied[index] = value
+			return copied, nil
+		}
+		index := len(c)
+		if token != "-" {
+			var err error
+			if index, err = arrayIndex(token, len(c)); err != nil {
+				return nil, err
+			}
+		}
+		copied := make([]interface{}, 0, len(c)+1)
+		copied = append(copied, c[:index]...)
+		copied = append(copied, value)
+		return append(copied, c[index:]...), nil
+	}
+	return nil, fmt.Errorf("cannot set %q of %s", token, jsonType(container))
+}
+
+// withoutChild returns a copy of the object or array container without
+// the element token.
+func withoutChild(container interface{}, token string) (interface{}, error) {
+	switch c := container.(type) {
+	case map[string]interface{}:
+		if _, ok := c[token]; !ok {
+			return nil, fmt.Errorf("key %q not found", token)
+		}
+		copied := make(map[string]interface{}, len(c))
+		for key, element := range c {
+			if key != token {
+				copied[key] = element
+			}
+		}
+		return copied, nil
+	case *OrderedMap:
+		if _, ok := c.values[token]; !ok {
+			return nil, fmt.Errorf("key %q not found", token)
+		}
+		copied := NewOrderedMap()
+		for _, key := range c.keys {
+			if key != token {
+				copied.Set(key, c.values[key])
+			}
+		}
+		return copied, nil
+	case []interface{}:
+		index, err := arrayIndex(token, len(c)-1)
+		if err != nil {
+			return nil, err
+		}
+		copied := make([]interface{}, 0, len(c)-1)
+		copied = append(copied, c[:index]...)
+		return append(copied, c[index+1:]...), nil
+	}
+	return nil, fmt.Errorf("cannot remove %q of %s", token, jsonType(container))
+}
+
+// applyMergePatch applies the JSON Merge Patch (RFC 7386) patch to
+// document.
+func (intr *treeInterpreter) applyMergePatch(document interface{}, patch []byte) (interface{}, [][]string, error) {
+	decoded, err := intr.decode(patch)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid JSON Merge Patch: %s", err)
+	}
+	var changed [][]string
+	return mergePatch(document, decoded, []string{}, &changed), changed, nil
+}
+
+// mergePatch returns a copy of target with patch merged into it, and adds
+// the paths it changes below path to changed.
+func mergePatch(target, patch interface{}, path []string, changed *[][]string) interface{} {
+	var keys []string
+	var values map[string]interface{}
+	switch p := patch.(type) {
+	case map[string]interface{}:
+		values = p
+		for key := range p {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+	case *OrderedMap:
+		keys, values = p.keys, p.values
+	default:
+		*changed = append(*changed, path)
+		return patch
+	}
+	merged := NewOrderedMap()
+	switch t := target.(type) {
+	case map[string]interface{}:
+		for key, value := range t {
+			merged.Set(key, value)
+		}
+	case *OrderedMap:
+		for _, key := range t.keys {
+			merged.Set(key, t.values[key])
+		}
+	default:
+		// The target is replaced by an object.
+		*changed = append(*changed, path)
+	}
+	for _, key := range keys {
+		childPath := append(path[:len(path):len(path)], formatIdentifier(key))
+		if values[key] == nil {
+			if _, ok := merged.values[key]; ok {
+				merged.remove(key)
+				*changed = append(*changed, childPath)
+			}
+			continue
+		}
+		current, _ := merged.Get(key)
+		merged.Set(key, mergePatch(current, values[key], childPath, changed))
+	}
+	if _, ok := target.(*OrderedMap); ok {
+		return merged
+	}
+	return merged.values
+}
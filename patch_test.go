@@ -0,0 +1,124 @@
+package jmespath
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func decodeJSON(data string) interface{} {
+	var value interface{}
+	if err := json.Unmarshal([]byte(data), &value); err != nil {
+		panic(err)
+	}
+	return value
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	assert := assert.New(t)
+	cases := []struct {
+		document, patch, expected string
+		changed                   [][]string
+	}{
+		{`{"a": 1}`, `[{"op": "add", "path": "/b", "value": 2}]`, `{"a": 1, "b": 2}`, [][]string{{"b"}}},
+		{`{"a": [1, 2]}`, `[{"op": "add", "path": "/a/1", "value": 3}]`, `{"a": [1, 3, 2]}`, [][]string{{"a"}}},
+		{`{"a": [1, 2]}`, `[{"op": "add", "path": "/a/-", "value": 3}]`, `{"a": [1, 2, 3]}`, [][]string{{"a"}}},
+		{`{"a": 1}`, `[{"op": "add", "path": "", "value": [1]}]`, `[1]`, [][]string{{}}},
+		{`{"a": 1, "b": 2}`, `[{"op": "remove", "path": "/a"}]`, `{"b": 2}`, [][]string{{"a"}}},
+		{`[{"a": 1}, {"a": 2}]`, `[{"op": "remove", "path": "/0/a"}]`, `[{}, {"a": 2}]`, [][]string{{"a"}}},
+		{`{"a": {"b": 1}}`, `[{"op": "replace", "path": "/a/b", "value": null}]`, `{"a": {"b": null}}`, [][]string{{"a", "b"}}},
+		{`{"a": {"b": 1}}`, `[{"op": "move", "from": "/a/b", "path": "/c"}]`, `{"a": {}, "c": 1}`, [][]string{{"a", "b"}, {"c"}}},
+		{`{"a": [1]}`, `[{"op": "copy", "from": "/a", "path": "/a/0"}]`, `{"a": [[1], 1]}`, [][]string{{"a"}}},
+		{`{"a": 1}`, `[{"op": "test", "path": "/a", "value": 1}]`, `{"a": 1}`, nil},
+		{`{"a/b": {"~": 1}}`, `[{"op": "replace", "path": "/a~1b/~0", "value": 2}]`, `{"a/b": {"~": 2}}`, [][]string{{`"a/b"`, `"~"`}}},
+	}
+	intr := newInterpreter()
+	for _, tt := range cases {
+		document := decodeJSON(tt.document)
+		original := decodeJSON(tt.document)
+		patched, changed, err := intr.applyJSONPatch(document, []byte(tt.patch))
+		if assert.Nil(err, tt.patch) {
+			assert.Equal(decodeJSON(tt.expected), patched, tt.patch)
+			assert.Equal(tt.changed, changed, tt.patch)
+		}
+		assert.Equal(original, document, "the document must not be modified")
+	}
+}
+
+func TestApplyJSONPatchErrors(t *testing.T) {
+	assert := assert.New(t)
+	patches := []string{
+		`{}`,
+		`[{"path": "/a"}]`,
+		`[{"op": "frobnicate", "path": "/a"}]`,
+		`[{"op": "add", "path": "a", "value": 1}]`,
+		`[{"op": "add", "path": "/a"}]`,
+		`[{"op": "add", "path": "/x/y", "value": 1}]`,
+		`[{"op": "add", "path": "/b/3", "value": 1}]`,
+		`[{"op": "add", "path": "/b/01", "value": 1}]`,
+		`[{"op": "remove", "path": "/x"}]`,
+		`[{"op": "remove", "path": ""}]`,
+		`[{"op": "replace", "path": "/x", "value": 1}]`,
+		`[{"op": "move", "from": "/a", "path": "/a/x"}]`,
+		`[{"op": "copy", "from": "/x", "path": "/a"}]`,
+		`[{"op": "test", "path": "/a", "value": 2}]`,
+		`[{"op": "add", "path": "/c", "value": 1}, {"op": "remove", "path": "/x"}]`,
+	}
+	intr := newInterpreter()
+	for _, patch := range patches {
+		_, _, err := intr.applyJSONPatch(decodeJSON(`{"a": {}, "b": [1, 2]}`), []byte(patch))
+		assert.NotNil(err, patch)
+	}
+	_, _, err := intr.applyJSONPatch(decodeJSON(`{"a": 1}`), []byte(`[{"op": "test", "path": "/a", "value": 2}]`))
+	assert.Contains(err.Error(), ErrPatchTestFailed.Error())
+}
+
+func TestApplyMergePatch(t *testing.T) {
+	assert := assert.New(t)
+	cases := []struct {
+		document, patch, expected string
+		changed                   [][]string
+	}{
+		{`{"a": 1, "b": 2}`, `{"a": null, "c": 3}`, `{"b": 2, "c": 3}`, [][]string{{"a"}, {"c"}}},
+		{`{"a": {"b": 1, "c": 2}}`, `{"a": {"b": [1]}}`, `{"a": {"b": [1], "c": 2}}`, [][]string{{"a", "b"}}},
+		{`{"a": 1}`, `{"a": {"b": 1}}`, `{"a": {"b": 1}}`, [][]string{{"a"}, {"a", "b"}}},
+		{`{"a": 1}`, `{"x": null}`, `{"a": 1}`, nil},
+		{`{"a": 1}`, `[1]`, `[1]`, [][]string{{}}},
+	}
+	intr := newInterpreter()
+	for _, tt := range cases {
+		document := decodeJSON(tt.document)
+		original := decodeJSON(tt.document)
+		patched, changed, err := intr.applyMergePatch(document, []byte(tt.patch))
+		if assert.Nil(err, tt.patch) {
+			assert.Equal(decodeJSON(tt.expected), patched, tt.patch)
+			assert.Equal(tt.changed, changed, tt.patch)
+		}
+		assert.Equal(original, document, "the document must not be modified")
+	}
+	_, _, err := intr.applyMergePatch(nil, []byte(`{`))
+	assert.NotNil(err)
+}
+
+func TestApplyPatchToOrderedMap(t *testing.T) {
+	assert := assert.New(t)
+	rt := NewRuntime()
+	rt.SetOrderedObjects(true)
+	intr := rt.newInterpreter()
+	document, err := UnmarshalOrdered([]byte(`{"b": 1, "a": 2, "c": 3}`))
+	assert.Nil(err)
+
+	patched, _, err := intr.applyJSONPatch(document, []byte(`[{"op": "remove", "path": "/a"}, {"op": "add", "path": "/d", "value": {"z": 1, "y": 2}}]`))
+	assert.Nil(err)
+	encoded, _ := json.Marshal(patched)
+	assert.Equal(`{"b":1,"c":3,"d":{"z":1,"y":2}}`, string(encoded))
+
+	patched, _, err = intr.applyMergePatch(document, []byte(`{"c": null, "a": 4, "e": 5}`))
+	assert.Nil(err)
+	encoded, _ = json.Marshal(patched)
+	assert.Equal(`{"b":1,"a":4,"e":5}`, string(encoded))
+
+	encoded, _ = json.Marshal(document)
+	assert.Equal(`{"b":1,"a":2,"c":3}`, string(encoded))
+}
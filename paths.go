@@ -0,0 +1,165 @@
+package jmespath
+
+import (
+	"sort"
+	"strings"
+)
+
+/* Referenced paths are found by evaluating the AST abstractly: instead of
+   a value, each node evaluates to the set of document paths its value is
+   exactly the value of.  When a value is used as a whole, for example
+   compared, passed to a function or put into a multiselect, the paths it
+   came from are recorded as read and the constructed value does not come
+   from any path.  This over-approximates what the expression reads, but
+   never misses a path.
+*/
+
+// ReferencedPaths returns the paths of the document an expression may read
+// while it is evaluated, including through projections, filters and
+// function arguments.  Reading a path includes reading everything below
+// it, and paths below another returned path are omitted.
+//
+// Paths are written as expressions on the document: field names are
+// separated by dots and quoted when needed, "*" stands for every value of
+// an object, and "@" for the whole document.  Array indexes are not part
+// of paths, so "people[0].name" and "people[*].name" both read
+// "people.name".
+func ReferencedPaths(expression string) (_ []string, err error) {
+	defer recoverInternal(&err)
+	parser := NewParser()
+	ast, err := parser.Parse(expression)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, path := range referencedPaths(ast, newFunctionCaller()) {
+		if len(path) == 0 {
+			return []string{"@"}, nil
+		}
+		paths = append(paths, strings.Join(path, "."))
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// referencedPaths returns the paths ast may read, see ReferencedPaths, as
+// lists of segments.  Field names are formatted with formatIdentifier, so
+// that they cannot be confused with the "*" of object values, and the
+// whole document is the empty path.  Calls are resolved with functions.
+func referencedPaths(ast ASTNode, functions *functionCaller) [][]string {
+	r := pathReferences{read: make(map[string][]string), functions: functions}
+	r.use(r.eval(ast, [][]string{{}}))
+	return r.paths()
+}
+
+type pathReferences struct {
+	// read holds the read paths by their segments joined with dots.
+	read map[string][]string
+	// functions are the functions calls are made to.
+	functions *functionCaller
+}
+
+// use records the paths of a value used as a whole as read.
+func (r *pathReferences) use(paths [][]string) {
+	for _, path := range paths {
+		r.read[strings.Join(path, ".")] = path
+	}
+}
+
+// eval returns the paths of the value node evaluates to when current is
+// the value at the given paths.
+func (r *pathReferences) eval(node ASTNode, current [][]string) [][]string {
+	switch node.nodeType {
+	case ASTField:
+		return appendSegment(current, formatIdentifier(node.value.(string)))
+	case ASTCurrentNode, ASTIdentity, ASTIndex, ASTSlice:
+		return current
+	case ASTSubexpression, ASTIndexExpression, ASTPipe:
+		for _, child := range node.children {
+			current = r.eval(child, current)
+		}
+		return current
+	case ASTDescendant:
+		// The key may be found anywhere below the current node.
+		r.use(r.eval(node.children[0], current))
+		return nil
+	case ASTKeyValPair, ASTFlatten:
+		return r.eval(node.children[0], current)
+	case ASTProjection:
+		return r.eval(node.children[1], r.eval(node.children[0], current))
+	case ASTFilterProjection:
+		elements := r.eval(node.children[0], current)
+		r.use(r.eval(node.children[2], elements))
+		return r.eval(node.children[1], elements)
+	case ASTValueProjection:
+		return r.eval(node.children[1], appendSegment(r.eval(node.children[0], current), "*"))
+	case ASTOrExpression, ASTAndExpression:
+		// The left side is also used for its truthiness.
+		left := r.eval(node.children[0], current)
+		r.use(left)
+		return append(left, r.eval(node.children[1], current)...)
+	case ASTFunctionExpression:
+		// Expression references are applied to the elements of the other
+		// arguments by most built-in functions, to the current node by
+		// first_of, and to either by user-registered functions.
+		var args [][]string
+		for _, child := range node.children {
+			if child.nodeType != ASTExpRef {
+				paths := r.eval(child, current)
+				r.use(paths)
+				args = append(args, paths...)
+			}
+		}
+		entry, ok := r.functions.functionTable[node.value.(string)]
+		switch {
+		case ok && entry.refersToCurrent():
+			args = current
+		case !ok || entry.custom != nil:
+			args = append(args, current...)
+		}
+		for _, child := range node.children {
+			if child.nodeType == ASTExpRef {
+				r.use(r.eval(child.children[0], args))
+			}
+		}
+		return nil
+	}
+	// The remaining nodes either read nothing from the document or use
+	// their children as a whole.
+	for _, child := range node.children {
+		r.use(r.eval(child, current))
+	}
+	return nil
+}
+
+// appendSegment returns paths with segment appended to each of them.
+func appendSegment(paths [][]string, segment string) [][]string {
+	appended := make([][]string, len(paths))
+	for i, path := range paths {
+		appended[i] = append(path[:len(path):len(path)], segment)
+	}
+	return appended
+}
+
+// paths returns the read paths that are not below another read path.
+func (r *pathReferences) paths() [][]string {
+	if root, ok := r.read[""]; ok {
+		return [][]string{root}
+	}
+	var paths [][]string
+	for _, path := range r.read {
+		if !r.readAbove(path) {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+func (r *pathReferences) readAbove(path []string) bool {
+	for i := 1; i < len(path); i++ {
+		if _, ok := r.read[strings.Join(path[:i], ".")]; ok {
+			return true
+		}
+	}
+	return false
+}
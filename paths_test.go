@@ -0,0 +1,65 @@
+package jmespath
+
+import (
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func TestReferencedPaths(t *testing.T) {
+	assert := assert.New(t)
+	cases := []struct {
+		expression string
+		expected   []string
+	}{
+		{"foo.bar", []string{"foo.bar"}},
+		{"foo.bar.baz || foo", []string{"foo"}},
+		{"@", []string{"@"}},
+		{"`{\"a\": 1}`.a", nil},
+		{"people[*].name", []string{"people.name"}},
+		{"people[0].name", []string{"people.name"}},
+		{"people[?age > `20`].name", []string{"people.age", "people.name"}},
+		{"people[?age > :min].[name, id]", []string{"people.age", "people.id", "people.name"}},
+		{"users.*.email", []string{"users.*.email"}},
+		{"a[].b[].c", []string{"a.b.c"}},
+		{"a.b | c", []string{"a.b.c"}},
+		{"{x: a.x, y: b} | x.z", []string{"a.x", "b"}},
+		{"length(people)", []string{"people"}},
+		{"sort_by(people, &age)[0].name", []string{"people"}},
+		{"max_by(people, &age)", []string{"people"}},
+		{"map(&name, people)", []string{"people"}},
+		{"first_of(&secret, &b)", []string{"b", "secret"}},
+		{"a.first_of(&p, &q)", []string{"a.p", "a.q"}},
+		{"first_of(a, &b.c)", []string{"a", "b.c"}},
+		{"exists(&secret.x)", []string{"secret.x"}},
+		{"a && b.c", []string{"a", "b.c"}},
+		{"!(a.b)", []string{"a.b"}},
+		{"\"foo.bar\".baz", []string{"\"foo.bar\".baz"}},
+		{"\"foo.bar\".baz && foo", []string{"\"foo.bar\".baz", "foo"}},
+	}
+	for _, tt := range cases {
+		paths, err := ReferencedPaths(tt.expression)
+		assert.Nil(err, tt.expression)
+		assert.Equal(tt.expected, paths, tt.expression)
+	}
+}
+
+func TestReferencedPathsOfUserFunctions(t *testing.T) {
+	assert := assert.New(t)
+	rt := NewRuntime()
+	assert.Nil(rt.RegisterFunction("apply", func(ctx CallContext, args []interface{}) (interface{}, error) {
+		return nil, nil
+	}))
+	jp, err := rt.Compile("apply(&p, items)")
+	assert.Nil(err)
+	// User-registered functions may apply expression references to the
+	// current node or to their other arguments.
+	paths := referencedPaths(jp.ast, rt.fCall)
+	assert.ElementsMatch([][]string{{"p"}, {"items"}}, paths)
+}
+
+func TestReferencedPathsSyntaxError(t *testing.T) {
+	assert := assert.New(t)
+	_, err := ReferencedPaths("foo.")
+	assert.NotNil(err)
+}
@@ -0,0 +1,64 @@
+package jmespath
+
+import "sync"
+
+/* Projections and flattens do not know how many values they will collect,
+   so appending to a fresh slice reallocates it every time it grows, and
+   all but the last of those arrays become garbage at once.  Instead they
+   collect into a buffer taken from a pool and copy the values into a slice
+   of the exact size when they finish, returning the buffer to the pool.
+   The results themselves are never pooled, as they may be kept by the
+   caller or be part of a larger result.
+*/
+
+// maxPooledBuffer is the capacity beyond which buffers are not returned to
+// the pool, so that one large projection does not keep its buffer alive.
+const maxPooledBuffer = 1 << 16
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		buffer := make([]interface{}, 0, 32)
+		return &buffer
+	},
+}
+
+// collector accumulates the values collected by a projection or flatten.
+// The zero value is not usable, see newCollector.
+type collector struct {
+	buffer *[]interface{}
+}
+
+func newCollector() collector {
+	return collector{buffer: bufferPool.Get().(*[]interface{})}
+}
+
+func (c collector) add(value interface{}) {
+	*c.buffer = append(*c.buffer, value)
+}
+
+func (c collector) addAll(values []interface{}) {
+	*c.buffer = append(*c.buffer, values...)
+}
+
+// result returns the collected values and releases the buffer.  The
+// collector must not be used afterwards.
+func (c collector) result() []interface{} {
+	collected := make([]interface{}, len(*c.buffer))
+	copy(collected, *c.buffer)
+	c.release()
+	return collected
+}
+
+// release returns the buffer to the pool without using its values, as
+// when a projection fails.
+func (c collector) release() {
+	buffer := *c.buffer
+	if cap(buffer) > maxPooledBuffer {
+		return
+	}
+	for i := range buffer {
+		buffer[i] = nil
+	}
+	*c.buffer = buffer[:0]
+	bufferPool.Put(c.buffer)
+}
@@ -0,0 +1,66 @@
+package jmespath
+
+import (
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func TestCollectorResultsAreNotShared(t *testing.T) {
+	assert := assert.New(t)
+	first := newCollector()
+	first.add(1.0)
+	first.addAll([]interface{}{2.0, 3.0})
+	result := first.result()
+	assert.Equal([]interface{}{1.0, 2.0, 3.0}, result)
+
+	second := newCollector()
+	assert.Equal(0, len(*second.buffer))
+	second.add("x")
+	assert.Equal([]interface{}{"x"}, second.result())
+	assert.Equal([]interface{}{1.0, 2.0, 3.0}, result)
+
+	empty := newCollector().result()
+	assert.NotNil(empty)
+	assert.Equal([]interface{}{}, empty)
+}
+
+func TestProjectionResultsSurviveLaterSearches(t *testing.T) {
+	assert := assert.New(t)
+	jp := MustCompile("{names: items[*].name, tags: items[].tags[], kept: items[?keep].name}")
+	first, err := jp.Search(map[string]interface{}{"items": []interface{}{
+		map[string]interface{}{"name": "a", "tags": []interface{}{"x"}, "keep": true},
+		map[string]interface{}{"name": "b", "tags": []interface{}{"y", "z"}},
+	}})
+	assert.Nil(err)
+	for i := 0; i < 10; i++ {
+		_, err := jp.Search(map[string]interface{}{"items": []interface{}{
+			map[string]interface{}{"name": "c", "tags": []interface{}{"w"}, "keep": true},
+		}})
+		assert.Nil(err)
+	}
+	assert.Equal(map[string]interface{}{
+		"names": []interface{}{"a", "b"},
+		"tags":  []interface{}{"x", "y", "z"},
+		"kept":  []interface{}{"a"},
+	}, first)
+}
+
+func TestProjectionAllocations(t *testing.T) {
+	items := make([]interface{}, 1000)
+	for i := range items {
+		items[i] = map[string]interface{}{"name": "item"}
+	}
+	data := map[string]interface{}{"items": items}
+	jp := MustCompile("items[*].name")
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, err := jp.Search(data); err != nil {
+			t.Fatal(err)
+		}
+	})
+	// The result itself is the only allocation proportional to the
+	// number of elements; growing it by appending would take a dozen.
+	if allocs > 4 {
+		t.Errorf("projecting 1000 elements took %v allocations", allocs)
+	}
+}
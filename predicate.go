@@ -0,0 +1,174 @@
+package jmespath
+
+import (
+	"sync"
+	"time"
+)
+
+/* Predicates are compiled separately from the closures in compile.go.
+   When only the truthiness of the result is needed, projections never
+   have to build the intermediate arrays, so that evaluating a typical
+   filter expression does not allocate at all.  They also stop at the
+   first element that produces a value, unless evaluating the remaining
+   elements could fail, as Search would then report the error.
+*/
+
+type predFunc func(intr *treeInterpreter, value interface{}) (bool, error)
+
+// lazyPredicate compiles the predicate for an AST on first use, so that
+// expressions that are only searched never pay for it.
+type lazyPredicate struct {
+	once sync.Once
+	ast  ASTNode
+	pred predFunc
+}
+
+func (p *lazyPredicate) get() predFunc {
+	p.once.Do(func() {
+		if p.pred == nil {
+			p.pred = compilePredicate(p.ast)
+		}
+	})
+	return p.pred
+}
+
+// Match evaluates the expression against data and reports whether the
+// result is truthy, that is anything other than false, null, or an empty
+// string, array or object.  It gives the same answer as calling Search and
+// checking the result, but avoids building intermediate results where
+// possible, so it is the preferred way to use an expression as a filter.
+func (jp *JMESPath) Match(data interface{}) (matched bool, err error) {
+	if jp.intr.metrics != nil {
+		defer jp.observe(time.Now(), &err)
+	}
+	defer recoverInternal(&err)
+	matched, err = jp.pred.get()(jp.intr, data)
+	return matched, locateError(jp.expression, err)
+}
+
+// compilePredicate returns a closure that reports whether node evaluates to
+// a truthy value.
+func compilePredicate(node ASTNode) predFunc {
+	switch node.nodeType {
+	case ASTAndExpression:
+		left, right := compilePredicate(node.children[0]), compilePredicate(node.children[1])
+		return func(intr *treeInterpreter, value interface{}) (bool, error) {
+			matched, err := left(intr, value)
+			if err != nil || !matched {
+				return false, err
+			}
+			return right(intr, value)
+		}
+	case ASTOrExpression:
+		left, right := compilePredicate(node.children[0]), compilePredicate(node.children[1])
+		return func(intr *treeInterpreter, value interface{}) (bool, error) {
+			matched, err := left(intr, value)
+			if err != nil || matched {
+				return matched, err
+			}
+			return right(intr, value)
+		}
+	case ASTNotExpression:
+		child := compilePredicate(node.children[0])
+		return func(intr *treeInterpreter, value interface{}) (bool, error) {
+			matched, err := child(intr, value)
+			return !matched, err
+		}
+	case ASTProjection:
+		// A projection is truthy if any element projects to a non-null
+		// value, as it is then a non-empty array.
+		left, right := compileNode(node.children[0]), compileNode(node.children[1])
+		eval := compileProjection(node, left, right)
+		exhaustive := mayFail(node.children[1])
+		return func(intr *treeInterpreter, value interface{}) (bool, error) {
+			l, err := left(intr, value)
+			if err != nil {
+				return false, err
+			}
+			sliceType, ok := l.([]interface{})
+			if !ok {
+				return evalTruthy(eval, intr, value)
+			}
+			matched := false
+			for _, element := range sliceType {
+				current, err := right(intr, element)
+				if err != nil {
+					return false, err
+				}
+				if current != nil && !exhaustive {
+					return true, nil
+				}
+				matched = matched || current != nil
+			}
+			return matched, nil
+		}
+	case ASTFilterProjection:
+		left, right := compileNode(node.children[0]), compileNode(node.children[1])
+		condition := compilePredicate(node.children[2])
+		eval := compileFilterProjection(node, left, right, condition)
+		exhaustive := mayFail(node.children[1]) || mayFail(node.children[2])
+		return func(intr *treeInterpreter, value interface{}) (bool, error) {
+			l, err := left(intr, value)
+			if err != nil {
+				return false, nil
+			}
+			sliceType, ok := l.([]interface{})
+			if !ok {
+				return evalTruthy(eval, intr, value)
+			}
+			found := false
+			for _, element := range sliceType {
+				matched, err := condition(intr, element)
+				if err != nil {
+					return false, err
+				}
+				if !matched {
+					continue
+				}
+				current, err := right(intr, element)
+				if err != nil {
+					return false, err
+				}
+				if current != nil && !exhaustive {
+					return true, nil
+				}
+				found = found || current != nil
+			}
+			return found, nil
+		}
+	}
+	eval := compileNode(node)
+	return func(intr *treeInterpreter, value interface{}) (bool, error) {
+		return evalTruthy(eval, intr, value)
+	}
+}
+
+// mayFail reports whether evaluating node may return an error, in which
+// case a projection it is part of cannot stop at the first element that
+// produces a value.  Function calls may fail for any element, as may the
+// nodes the closures leave to the tree interpreter.
+func mayFail(node ASTNode) bool {
+	switch node.nodeType {
+	case ASTLiteral, ASTIdentity, ASTCurrentNode, ASTField, ASTIndex, ASTSlice,
+		ASTSubexpression, ASTIndexExpression, ASTPipe, ASTComparator,
+		ASTOrExpression, ASTAndExpression, ASTNotExpression, ASTProjection,
+		ASTFilterProjection, ASTValueProjection, ASTFlatten,
+		ASTMultiSelectList, ASTMultiSelectHash, ASTKeyValPair:
+	default:
+		return true
+	}
+	for _, child := range node.children {
+		if mayFail(child) {
+			return true
+		}
+	}
+	return false
+}
+
+func evalTruthy(eval evalFunc, intr *treeInterpreter, value interface{}) (bool, error) {
+	result, err := eval(intr, value)
+	if err != nil {
+		return false, err
+	}
+	return !isFalse(result), nil
+}
@@ -0,0 +1,99 @@
+package jmespath
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+const predicateDocument = `{
+	"state": "running",
+	"tags": [{"key": "env", "value": "prod"}, {"key": "team", "value": null}],
+	"ports": [80, 443],
+	"empty": []
+}`
+
+var predicateTests = []string{
+	"state == 'running'",
+	"state == 'stopped'",
+	"tags[?key == 'env']",
+	"tags[?key == 'owner']",
+	"tags[*].value",
+	"tags[?key == 'team'].value",
+	"!empty",
+	"empty || ports[?@ > `100`]",
+	"state && missing",
+	"ports[*]",
+	"missing[*]",
+	"state[*]",
+}
+
+func TestMatchAgreesWithSearch(t *testing.T) {
+	assert := assert.New(t)
+	var data interface{}
+	assert.Nil(json.Unmarshal([]byte(predicateDocument), &data))
+	for _, expression := range predicateTests {
+		jp := MustCompile(expression)
+		result, err := jp.Search(data)
+		assert.Nil(err)
+		matched, err := jp.Match(data)
+		assert.Nil(err)
+		assert.Equal(!isFalse(result), matched, expression)
+	}
+}
+
+func TestMatchReportsErrorsOfLaterElements(t *testing.T) {
+	assert := assert.New(t)
+	var data interface{}
+	assert.Nil(json.Unmarshal([]byte(`{"x": [[{"b": [1]}, 1]]}`), &data))
+	for _, expression := range []string{
+		"x[?@[?length(b)]]",
+		"x[0][?length(b)]",
+		"x[0][*].length(b)",
+		"x[?@[*].length(b)]",
+	} {
+		_, err := Search(expression, data)
+		assert.NotNil(err, expression)
+		jp := MustCompile(expression)
+		_, compiledErr := jp.Search(data)
+		assert.Equal(err, compiledErr, expression)
+		_, matchErr := jp.Match(data)
+		assert.Equal(err, matchErr, expression)
+	}
+}
+
+func TestCompileNestedFilters(t *testing.T) {
+	assert := assert.New(t)
+	// Each filter used to compile its condition twice, so compile time
+	// doubled with every level of nesting.
+	expression := strings.Repeat("a[?", 30) + "b" + strings.Repeat("]", 30)
+	done := make(chan error, 1)
+	go func() {
+		jp, err := Compile(expression)
+		if err == nil {
+			_, err = jp.Match(map[string]interface{}{})
+		}
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		assert.Nil(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("compiling nested filters did not finish in time")
+	}
+}
+
+func BenchmarkMatchFilter(b *testing.B) {
+	var data interface{}
+	json.Unmarshal([]byte(predicateDocument), &data)
+	jp := MustCompile("state == 'running' && tags[?key == 'env' && value == 'prod']")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := jp.Match(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package jmespath
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// ReaderOptions bound the size of the documents read by SearchReader, so
+// that documents from untrusted sources can be searched without buffering
+// and validating them first.  A limit of zero means no limit.
+type ReaderOptions struct {
+	// MaxBytes is the maximum length of a document in bytes.  No more
+	// than MaxBytes+1 bytes are read from the reader.
+	MaxBytes int64
+	// MaxDepth is the maximum depth to which arrays and objects are
+	// nested in a document: "[1]" has a depth of 1 and `{"a": [1]}` a
+	// depth of 2.
+	MaxDepth int
+}
+
+// DocumentLimitError is returned by SearchReader when a document exceeds
+// one of its ReaderOptions.
+type DocumentLimitError struct {
+	Limit  string // The limit that was exceeded: "bytes" or "depth".
+	Max    int64  // The value of the limit.
+	Offset int64  // The offset in the document at which the limit was exceeded.
+}
+
+func (e *DocumentLimitError) Error() string {
+	if e.Limit == "bytes" {
+		return fmt.Sprintf("document is longer than %d bytes", e.Max)
+	}
+	return fmt.Sprintf("document is nested more than %d levels deep at offset %d", e.Max, e.Offset)
+}
+
+// SearchReader reads a JSON document from r, decodes it like SearchBytes
+// and evaluates the expression against it.  Documents that exceed the
+// limits of opts are rejected with a *DocumentLimitError before they are
+// decoded.  A nil opts sets no limits.
+func (jp *JMESPath) SearchReader(r io.Reader, opts *ReaderOptions) (interface{}, error) {
+	var o ReaderOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.MaxBytes > 0 {
+		r = io.LimitReader(r, o.MaxBytes+1)
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if o.MaxBytes > 0 && int64(len(data)) > o.MaxBytes {
+		return nil, &DocumentLimitError{Limit: "bytes", Max: o.MaxBytes, Offset: o.MaxBytes}
+	}
+	if o.MaxDepth > 0 {
+		if offset := exceedsDepth(data, o.MaxDepth); offset >= 0 {
+			return nil, &DocumentLimitError{Limit: "depth", Max: int64(o.MaxDepth), Offset: int64(offset)}
+		}
+	}
+	return jp.SearchBytes(data)
+}
+
+// SearchReader compiles a JMESPath expression and evaluates it against the
+// JSON document read from r, see JMESPath.SearchReader.
+func SearchReader(expression string, r io.Reader, opts *ReaderOptions) (interface{}, error) {
+	jp, err := Compile(expression)
+	if err != nil {
+		return nil, err
+	}
+	return jp.SearchReader(r, opts)
+}
+
+// SearchReader compiles a JMESPath expression with this runtime and
+// evaluates it against the JSON document read from r, see
+// JMESPath.SearchReader.
+func (rt *Runtime) SearchReader(expression string, r io.Reader, opts *ReaderOptions) (interface{}, error) {
+	jp, err := rt.Compile(expression)
+	if err != nil {
+		return nil, err
+	}
+	return jp.SearchReader(r, opts)
+}
+
+// exceedsDepth returns the offset of the first bracket in the JSON text
+// data that nests arrays and objects more than max levels deep, or -1.
+// Invalid JSON is left for the decoder to report.
+func exceedsDepth(data []byte, max int) int {
+	depth := 0
+	inString, escaped := false, false
+	for i, c := range data {
+		switch {
+		case inString:
+			if escaped {
+				escaped = false
+			} else if c == '\\' {
+				escaped = true
+			} else if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '[' || c == '{':
+			depth++
+			if depth > max {
+				return i
+			}
+		case c == ']' || c == '}':
+			depth--
+		}
+	}
+	return -1
+}
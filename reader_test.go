@@ -0,0 +1,63 @@
+package jmespath
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func TestSearchReader(t *testing.T) {
+	assert := assert.New(t)
+	result, err := SearchReader("foo[1]", strings.NewReader(`{"foo": [1, 2]}`), nil)
+	assert.Nil(err)
+	assert.Equal(2.0, result)
+
+	_, err = SearchReader("foo", strings.NewReader(`{"foo": `), nil)
+	assert.NotNil(err)
+	_, err = SearchReader("foo[", strings.NewReader(`{}`), nil)
+	assert.IsType(SyntaxError{}, err)
+}
+
+func TestSearchReaderMaxBytes(t *testing.T) {
+	assert := assert.New(t)
+	document := `{"foo": "bar"}`
+	result, err := SearchReader("foo", strings.NewReader(document), &ReaderOptions{MaxBytes: int64(len(document))})
+	assert.Nil(err)
+	assert.Equal("bar", result)
+
+	reader := strings.NewReader(document + strings.Repeat(" ", 100))
+	_, err = SearchReader("foo", reader, &ReaderOptions{MaxBytes: int64(len(document))})
+	assert.Equal(&DocumentLimitError{Limit: "bytes", Max: 14, Offset: 14}, err)
+	assert.Equal("document is longer than 14 bytes", err.Error())
+	// No more than one byte past the limit is read.
+	assert.Equal(100-1, reader.Len())
+}
+
+func TestSearchReaderMaxDepth(t *testing.T) {
+	assert := assert.New(t)
+	opts := &ReaderOptions{MaxDepth: 2}
+	result, err := SearchReader("a[0]", strings.NewReader(`{"a": [1], "b": {"c": "[[[{{"}}`), opts)
+	assert.Nil(err)
+	assert.Equal(1.0, result)
+	result, err = SearchReader("@", strings.NewReader(`"[[[\"[["`), opts)
+	assert.Nil(err)
+	assert.Equal(`[[["[[`, result)
+
+	_, err = SearchReader("a", strings.NewReader(`{"a": [[1]]}`), opts)
+	assert.Equal(&DocumentLimitError{Limit: "depth", Max: 2, Offset: 7}, err)
+	assert.Equal("document is nested more than 2 levels deep at offset 7", err.Error())
+	_, err = SearchReader("a", strings.NewReader(strings.Repeat("[", 100000)), opts)
+	var limitErr *DocumentLimitError
+	assert.True(errors.As(err, &limitErr))
+}
+
+func TestRuntimeSearchReader(t *testing.T) {
+	assert := assert.New(t)
+	rt := NewRuntime()
+	rt.SetOrderedObjects(true)
+	result, err := rt.SearchReader("keys(@)", strings.NewReader(`{"b": 1, "a": 2}`), &ReaderOptions{MaxBytes: 100, MaxDepth: 1})
+	assert.Nil(err)
+	assert.Equal([]interface{}{"b", "a"}, result)
+}
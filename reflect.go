@@ -0,0 +1,186 @@
+//go:build !jmespath_noreflect
+// +build !jmespath_noreflect
+
+package jmespath
+
+import (
+	"encoding/json"
+	"reflect"
+	"unicode"
+	"unicode/utf8"
+)
+
+/* This file holds all the reflection the package uses, to evaluate
+   expressions against Go values other than the ones encoding/json decodes
+   to, such as structs, typed slices and maps with string keys of any
+   type.  Building with the jmespath_noreflect tag replaces it with
+   noreflect.go, which only handles the types encoding/json decodes to and
+   a few common slice and map types without using reflection, for
+   compilers such as TinyGo whose support for reflection is limited.
+*/
+
+func isSliceType(v interface{}) bool {
+	if v == nil {
+		return false
+	}
+	return reflect.TypeOf(v).Kind() == reflect.Slice
+}
+
+// isArrayType reports whether v is a Go array, such as a [3]int, which
+// functions treat as an array like a slice.
+func isArrayType(v interface{}) bool {
+	return v != nil && reflect.TypeOf(v).Kind() == reflect.Array
+}
+
+// namedString returns v as a string if it is of a named string type, such
+// as a type Name string, which functions treat as a string.  A
+// json.Number is a number.
+func namedString(v interface{}) (string, bool) {
+	switch v.(type) {
+	case nil, string, json.Number:
+		return "", false
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.String {
+		return "", false
+	}
+	return rv.String(), true
+}
+
+// dereference returns the value v points to if it is a non-nil pointer to
+// a slice, array, map or string, which functions treat as the value, and
+// v otherwise.
+func dereference(v interface{}) interface{} {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return v
+	}
+	switch rv.Elem().Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.String:
+		return rv.Elem().Interface()
+	}
+	return v
+}
+
+// toInterfaceSlice copies the elements of a slice or array of any type into
+// a []interface{}.
+func toInterfaceSlice(v interface{}) []interface{} {
+	rv := reflect.ValueOf(v)
+	result := make([]interface{}, rv.Len())
+	for i := range result {
+		result[i] = rv.Index(i).Interface()
+	}
+	return result
+}
+
+// sliceLen returns the length of a slice or array of any type.
+func sliceLen(v interface{}) int {
+	return reflect.ValueOf(v).Len()
+}
+
+// sliceIndex returns the element at index i of a slice or array of any
+// type.
+func sliceIndex(v interface{}, i int) interface{} {
+	return reflect.ValueOf(v).Index(i).Interface()
+}
+
+var stringSliceType = reflect.TypeOf([]string(nil))
+
+// isMultiValueMap reports whether value is a map from strings to string
+// slices, such as url.Values or http.Header.
+func isMultiValueMap(value interface{}) bool {
+	rt := reflect.TypeOf(value)
+	return rt != nil && rt.Kind() == reflect.Map && rt.Key().Kind() == reflect.String &&
+		rt.Elem() == stringSliceType
+}
+
+// isStringKeyedMap reports whether value is a map with string keys, of any
+// type including named types such as "type Labels map[string]string".
+func isStringKeyedMap(value interface{}) bool {
+	rt := reflect.TypeOf(value)
+	return rt != nil && rt.Kind() == reflect.Map && rt.Key().Kind() == reflect.String
+}
+
+// mapField looks up key in a map with string keys of any type.
+func mapField(key string, value interface{}) interface{} {
+	rv := reflect.ValueOf(value)
+	v := rv.MapIndex(reflect.ValueOf(key).Convert(rv.Type().Key()))
+	if !v.IsValid() {
+		return nil
+	}
+	return v.Interface()
+}
+
+// mapEntries calls fn with each key and value of a map with string keys
+// of any type.
+func mapEntries(value interface{}, fn func(key string, v interface{})) {
+	iter := reflect.ValueOf(value).MapRange()
+	for iter.Next() {
+		fn(iter.Key().String(), iter.Value().Interface())
+	}
+}
+
+// structField looks up the exported field for key, with its first letter
+// upper cased, in value if it is a struct or a non-nil pointer to one,
+// which isStruct reports.
+func structField(key string, value interface{}) (field interface{}, found bool, isStruct bool) {
+	rv := reflect.Indirect(reflect.ValueOf(value))
+	if rv.Kind() != reflect.Struct {
+		return nil, false, false
+	}
+	first, n := utf8.DecodeRuneInString(key)
+	v := rv.FieldByName(string(unicode.ToUpper(first)) + key[n:])
+	if !v.IsValid() {
+		return nil, false, true
+	}
+	return v.Interface(), true, true
+}
+
+// isGoContainer reports whether value is a Go slice, array, map or struct,
+// or a pointer to one, or a nil pointer.
+func isGoContainer(value interface{}) bool {
+	switch reflect.Indirect(reflect.ValueOf(value)).Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Struct, reflect.Invalid:
+		return true
+	}
+	return false
+}
+
+// isFalseGo reports whether a Go value of a type isFalse does not handle
+// itself is false: an empty slice or map, a nil pointer or a pointer to a
+// false value.  Structs are never false, even if all of their fields are
+// zero.
+func isFalseGo(value interface{}) bool {
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Map:
+		return rv.Len() == 0
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return true
+		}
+		return isFalse(rv.Elem().Interface())
+	}
+	return false
+}
+
+// sameContainer reports whether a and b are the same array or object.
+func sameContainer(a, b interface{}) bool {
+	ra, rb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if !ra.IsValid() || !rb.IsValid() || ra.Type() != rb.Type() {
+		return false
+	}
+	switch ra.Kind() {
+	case reflect.Map, reflect.Ptr:
+		return ra.Pointer() == rb.Pointer()
+	case reflect.Slice:
+		return ra.Pointer() == rb.Pointer() && ra.Len() == rb.Len()
+	}
+	return false
+}
+
+// deepEqual reports whether a and b are deeply equal, as
+// reflect.DeepEqual does.
+func deepEqual(a, b interface{}) bool {
+	return reflect.DeepEqual(a, b)
+}
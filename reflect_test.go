@@ -0,0 +1,395 @@
+//go:build !jmespath_noreflect
+// +build !jmespath_noreflect
+
+package jmespath
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func TestCanSupportUserDefinedStructsValue(t *testing.T) {
+	assert := assert.New(t)
+	s := scalars{Foo: "one", Bar: "bar"}
+	result, err := Search("Foo", s)
+	assert.Nil(err)
+	assert.Equal("one", result)
+}
+
+func TestCanSupportUserDefinedStructsRef(t *testing.T) {
+	assert := assert.New(t)
+	s := scalars{Foo: "one", Bar: "bar"}
+	result, err := Search("Foo", &s)
+	assert.Nil(err)
+	assert.Equal("one", result)
+}
+
+func TestCanSupportStructWithSliceAll(t *testing.T) {
+	assert := assert.New(t)
+	data := sliceType{A: "foo", B: []scalars{{"f1", "b1"}, {"correct", "b2"}}}
+	result, err := Search("B[].Foo", data)
+	assert.Nil(err)
+	assert.Equal([]interface{}{"f1", "correct"}, result)
+}
+
+func TestCanSupportStructWithSlicingExpression(t *testing.T) {
+	assert := assert.New(t)
+	data := sliceType{A: "foo", B: []scalars{{"f1", "b1"}, {"correct", "b2"}}}
+	result, err := Search("B[:].Foo", data)
+	assert.Nil(err)
+	assert.Equal([]interface{}{"f1", "correct"}, result)
+}
+
+func TestCanSupportStructWithFilterProjection(t *testing.T) {
+	assert := assert.New(t)
+	data := sliceType{A: "foo", B: []scalars{{"f1", "b1"}, {"correct", "b2"}}}
+	result, err := Search("B[? `true` ].Foo", data)
+	assert.Nil(err)
+	assert.Equal([]interface{}{"f1", "correct"}, result)
+}
+
+func TestCanSupportStructWithSlice(t *testing.T) {
+	assert := assert.New(t)
+	data := sliceType{A: "foo", B: []scalars{{"f1", "b1"}, {"correct", "b2"}}}
+	result, err := Search("B[-1].Foo", data)
+	assert.Nil(err)
+	assert.Equal("correct", result)
+}
+
+func TestCanSupportStructWithOrExpressions(t *testing.T) {
+	assert := assert.New(t)
+	data := sliceType{A: "foo", C: nil}
+	result, err := Search("C || A", data)
+	assert.Nil(err)
+	assert.Equal("foo", result)
+}
+
+func TestCanSupportStructWithSlicePointer(t *testing.T) {
+	assert := assert.New(t)
+	data := sliceType{A: "foo", C: []*scalars{{"f1", "b1"}, {"correct", "b2"}}}
+	result, err := Search("C[-1].Foo", data)
+	assert.Nil(err)
+	assert.Equal("correct", result)
+}
+
+func TestWillAutomaticallyCapitalizeFieldNames(t *testing.T) {
+	assert := assert.New(t)
+	s := scalars{Foo: "one", Bar: "bar"}
+	// Note that there's a lower cased "foo" instead of "Foo",
+	// but it should still correspond to the Foo field in the
+	// scalars struct
+	result, err := Search("foo", &s)
+	assert.Nil(err)
+	assert.Equal("one", result)
+}
+
+func TestCanSupportStructWithSliceLowerCased(t *testing.T) {
+	assert := assert.New(t)
+	data := sliceType{A: "foo", B: []scalars{{"f1", "b1"}, {"correct", "b2"}}}
+	result, err := Search("b[-1].foo", data)
+	assert.Nil(err)
+	assert.Equal("correct", result)
+}
+
+func TestCanSupportFlattenNestedSlice(t *testing.T) {
+	assert := assert.New(t)
+	data := nestedSlice{A: []sliceType{
+		{B: []scalars{{Foo: "f1a"}, {Foo: "f1b"}}},
+		{B: []scalars{{Foo: "f2a"}, {Foo: "f2b"}}},
+	}}
+	result, err := Search("A[].B[].Foo", data)
+	assert.Nil(err)
+	assert.Equal([]interface{}{"f1a", "f1b", "f2a", "f2b"}, result)
+}
+
+func TestCanSupportFlattenNestedEmptySlice(t *testing.T) {
+	assert := assert.New(t)
+	data := nestedSlice{A: []sliceType{
+		{}, {B: []scalars{{Foo: "a"}}},
+	}}
+	result, err := Search("A[].B[].Foo", data)
+	assert.Nil(err)
+	assert.Equal([]interface{}{"a"}, result)
+}
+
+func TestCanSupportProjectionsWithStructs(t *testing.T) {
+	assert := assert.New(t)
+	data := nestedSlice{A: []sliceType{
+		{A: "first"}, {A: "second"}, {A: "third"},
+	}}
+	result, err := Search("A[*].A", data)
+	assert.Nil(err)
+	assert.Equal([]interface{}{"first", "second", "third"}, result)
+}
+
+func TestCanSupportSliceOfStructsWithFunctions(t *testing.T) {
+	assert := assert.New(t)
+	data := []scalars{scalars{"a1", "b1"}, scalars{"a2", "b2"}}
+	result, err := Search("length(@)", data)
+	assert.Nil(err)
+	assert.Equal(result.(float64), 2.0)
+}
+
+func TestCompiledEngineFallsBackForStructs(t *testing.T) {
+	assert := assert.New(t)
+	data := sliceType{A: "foo", B: []scalars{{"f1", "b1"}, {"correct", "b2"}}}
+	result, err := MustCompile("B[?Bar == 'b2'].Foo | [0]").Search(data)
+	assert.Nil(err)
+	assert.Equal("correct", result)
+}
+
+func TestRecursiveDescentGoValues(t *testing.T) {
+	assert := assert.New(t)
+	ordered := NewOrderedMap()
+	ordered.Set("z", map[string]interface{}{"id": 1.0})
+	ordered.Set("a", map[string]interface{}{"id": 2.0})
+	data := map[string]interface{}{
+		"ordered": ordered,
+		"typed":   map[string]int{"id": 3},
+		"lists":   [][]map[string]interface{}{{{"id": 4.0}}},
+	}
+	rt := NewRuntime()
+	rt.SetRecursiveDescent(true)
+	result, err := rt.Search("ordered..id", data)
+	assert.Nil(err)
+	assert.Equal([]interface{}{1.0, 2.0}, result)
+	result, err = rt.Search("typed..id", data)
+	assert.Nil(err)
+	assert.Equal([]interface{}{3}, result)
+	result, err = rt.Search("lists..id", data)
+	assert.Nil(err)
+	assert.Equal([]interface{}{4.0}, result)
+}
+
+func TestFunctionsOnGoValues(t *testing.T) {
+	assert := assert.New(t)
+	list := []string{"a", "b"}
+	data := map[string]interface{}{
+		"strings":   list,
+		"counts":    map[string]int{"x": 1, "y": 2},
+		"name":      goNativeName("héllo"),
+		"names":     []goNativeName{"a", "b"},
+		"array":     [3]int{1, 2, 3},
+		"pointer":   &list,
+		"counts_p":  &map[string]int{"x": 1},
+		"name_p":    func() *string { s := "abc"; return &s }(),
+		"nil_slice": []string(nil),
+	}
+	tests := []struct {
+		expression string
+		expected   interface{}
+	}{
+		{"length(strings)", 2.0},
+		{"contains(strings, 'a')", true},
+		{"sort(keys(counts))", []interface{}{"x", "y"}},
+		{"sort(values(counts))", []interface{}{1.0, 2.0}},
+		{"length(counts)", 2.0},
+		{"length(name)", 5.0},
+		{"contains(name, 'll')", true},
+		{"starts_with(name, 'h')", true},
+		{"type(name)", "string"},
+		{"contains(names, 'a')", true},
+		{"join(',', names)", "a,b"},
+		{"length(array)", 3.0},
+		{"contains(array, `2`)", true},
+		{"max(array)", 3.0},
+		{"length(pointer)", 2.0},
+		{"contains(pointer, 'b')", true},
+		{"keys(counts_p)", []interface{}{"x"}},
+		{"length(name_p)", 3.0},
+		{"length(nil_slice)", 0.0},
+	}
+	for _, test := range tests {
+		result, err := Search(test.expression, data)
+		assert.Nil(err, test.expression)
+		assert.Equal(test.expected, result, test.expression)
+	}
+}
+
+func TestDeepFlattenGoValues(t *testing.T) {
+	assert := assert.New(t)
+	result, err := Search("deep_flatten(@)", [][]int{{1, 2}, {3}})
+	assert.Nil(err)
+	assert.Equal([]interface{}{1, 2, 3}, result)
+}
+
+func TestCanSupportNamedMapAndSliceTypes(t *testing.T) {
+	assert := assert.New(t)
+	data := namedObject{
+		"labels": namedLabels{"app": "web", "tier": "frontend"},
+		"items":  namedItems{{"name": "a", "size": 1.0}, {"name": "b", "size": 3.0}},
+		"plain":  []map[string]interface{}{{"name": "c"}},
+		"counts": map[string]float64{"x": 2},
+	}
+	for _, tt := range []struct {
+		expression string
+		expected   interface{}
+	}{
+		{"labels.app", "web"},
+		{"sort(labels.*)", []interface{}{"frontend", "web"}},
+		{"sort(keys(labels))", []interface{}{"app", "tier"}},
+		{"type(labels)", "object"},
+		{"items[*].name", []interface{}{"a", "b"}},
+		{"items[?size > `2`].name", []interface{}{"b"}},
+		{"items[-1].name", "b"},
+		{"sort_by(items, &size)[0].name", "a"},
+		{"length(items)", 2.0},
+		{"plain[0].name", "c"},
+		{"counts.x", 2.0},
+		{"sum(values(counts))", 2.0},
+		{"map_values(&@, labels).app", "web"},
+	} {
+		for _, search := range []func(string, interface{}) (interface{}, error){Search, strictRuntime().Search} {
+			result, err := search(tt.expression, data)
+			if assert.Nil(err, tt.expression) {
+				assert.Equal(tt.expected, result, tt.expression)
+			}
+		}
+		jp := MustCompile(tt.expression)
+		result, err := jp.Search(data)
+		if assert.Nil(err, tt.expression) {
+			assert.Equal(tt.expected, result, tt.expression)
+		}
+	}
+	result, err := Search("labels.missing", data)
+	assert.Nil(err)
+	assert.Nil(result)
+	_, err = strictRuntime().Search("labels.missing", data)
+	assert.True(errors.Is(err, ErrMissingKey))
+}
+
+func TestCanSupportURLValuesAndHeaders(t *testing.T) {
+	assert := assert.New(t)
+	query := url.Values{"tag": {"a", "b"}, "page": {"2"}}
+	header := http.Header{}
+	header.Add("Content-Type", "application/json")
+	data := map[string]interface{}{"query": query, "header": header}
+
+	result, err := Search("query.tag", data)
+	assert.Nil(err)
+	assert.Equal([]interface{}{"a", "b"}, result)
+	result, err = Search("join(',', query.tag)", data)
+	assert.Nil(err)
+	assert.Equal("a,b", result)
+	// http.Header keys are canonicalized on lookup.
+	result, err = Search(`header."content-type"[0]`, data)
+	assert.Nil(err)
+	assert.Equal("application/json", result)
+	result, err = Search("keys(query) | sort(@)", data)
+	assert.Nil(err)
+	assert.Equal([]interface{}{"page", "tag"}, result)
+
+	rt := NewRuntime()
+	rt.SetMultiValueMode(MultiValueFirst)
+	result, err = rt.Search("[query.tag, query.page, query.missing]", data)
+	assert.Nil(err)
+	assert.Equal([]interface{}{"a", "2", nil}, result)
+}
+
+func TestSearchGoNumbers(t *testing.T) {
+	assert := assert.New(t)
+	type item struct {
+		Name  string
+		Count int
+		Size  uint64
+		Ratio float32
+	}
+	data := map[string]interface{}{
+		"items": []item{
+			{"a", 3, 1 << 63, 0.5},
+			{"b", -1, 10, 2},
+			{"c", 7, 0, 1.25},
+		},
+		"ints":   []int{3, 1, 2},
+		"limit":  int64(2),
+		"big":    uint64(math.MaxUint64),
+		"float":  2.0,
+		"counts": map[string]interface{}{"x": int8(1), "y": uint32(1)},
+	}
+	cases := []struct {
+		expression string
+		expected   interface{}
+	}{
+		{"items[?Count > `2`].Name", []interface{}{"a", "c"}},
+		{"items[?Count == `7`].Name", []interface{}{"c"}},
+		{"items[?Size > `10`].Name", []interface{}{"a"}},
+		{"items[?Ratio <= `2`].Name", []interface{}{"a", "b", "c"}},
+		{"items[?Ratio == `1.25`].Name", []interface{}{"c"}},
+		{"limit == float", true},
+		{"counts.x == counts.y", true},
+		{"big > `1e19`", true},
+		{"big < `1e20`", true},
+		{"ints == `[3, 1, 2]`", true},
+		{"ints == `[3, 1]`", false},
+		{"sum(ints)", 6.0},
+		{"avg(ints)", 2.0},
+		{"max(ints)", 3.0},
+		{"sort(ints)", []interface{}{1.0, 2.0, 3.0}},
+		{"abs(items[1].Count)", 1.0},
+		{"max_by(items, &Count).Name", "c"},
+		{"min_by(items, &Ratio).Name", "a"},
+		{"sort_by(items, &Count)[*].Name", []interface{}{"b", "a", "c"}},
+		{"contains(ints, `2`)", true},
+		{"type(limit)", "number"},
+		{"to_number(limit)", 2.0},
+	}
+	for _, tt := range cases {
+		result, err := Search(tt.expression, data)
+		assert.Nil(err, tt.expression)
+		assert.Equal(tt.expected, result, tt.expression)
+	}
+}
+
+func TestMatchWithStructs(t *testing.T) {
+	assert := assert.New(t)
+	data := sliceType{A: "foo", B: []scalars{{"f1", "b1"}, {"correct", "b2"}}}
+	matched, err := MustCompile("B[?Foo == 'correct']").Match(data)
+	assert.Nil(err)
+	assert.True(matched)
+}
+
+func TestIsFalseWithUserDefinedStructs(t *testing.T) {
+	assert := assert.New(t)
+	type nilStructType struct {
+		SliceOfPointers []*string
+	}
+	nilStruct := nilStructType{SliceOfPointers: nil}
+	assert.True(isFalse(nilStruct.SliceOfPointers))
+
+	// A user defined struct will never be false though,
+	// even if it's fields are the zero type.
+	assert.False(isFalse(nilStruct))
+}
+
+func TestIsFalseWithNilInterface(t *testing.T) {
+	assert := assert.New(t)
+	var a *int
+	var nilInterface interface{} = a
+	assert.True(isFalse(nilInterface))
+}
+
+func TestIsFalseWithMapOfUserStructs(t *testing.T) {
+	assert := assert.New(t)
+	type foo struct {
+		Bar string
+		Baz string
+	}
+	m := make(map[int]foo)
+	assert.True(isFalse(m))
+}
+
+func TestStrictStructs(t *testing.T) {
+	assert := assert.New(t)
+	rt := strictRuntime()
+	data := struct{ Name string }{"x"}
+	result, err := rt.Search("name", data)
+	assert.Nil(err)
+	assert.Equal("x", result)
+	_, err = rt.Search("email", data)
+	assert.True(errors.Is(err, ErrMissingKey))
+}
@@ -0,0 +1,339 @@
+package jmespath
+
+import "fmt"
+
+/* A Resolver stands in for an object or an array whose fields and
+   elements are only resolved when an expression accesses them, such as a
+   document loaded from a database one nested object at a time, or values
+   computed on demand.  Fields and indexes, as in "a.b" and "a[0]", resolve
+   only the field or element they name, and slices resolve only the
+   elements they select.  Any other use of a resolver resolves it: a
+   projection, filter or flatten of an array resolver resolves each of its
+   elements, and an object projection resolves each of its fields, but
+   elements and fields that are resolvers themselves stay unresolved until
+   they are accessed.  Flattens also resolve the nested arrays they
+   flatten.  Descendant expressions, comparisons and function arguments
+   need whole values, so they resolve the resolvers they apply to
+   completely, see Resolve, which requires object resolvers to list their
+   keys.
+*/
+
+// Resolver is an object or an array whose fields and elements are
+// resolved as expressions access them.  A resolver is an array if Len
+// returns zero or more, and an object otherwise.  The values it returns
+// may be resolvers themselves.  A resolver reached by an expression but
+// not accessed, as in the result of "a" for a resolver a, is returned as
+// it is; Resolve resolves such results.
+type Resolver interface {
+	// GetField returns the value of the field key of an object, or nil
+	// if it has no such field.  It is not called for arrays.
+	GetField(key string) (interface{}, error)
+	// Index returns the element at index i of an array, for i from 0 to
+	// Len()-1.  It is not called for objects.
+	Index(i int) (interface{}, error)
+	// Len returns the number of elements of an array, or -1 for an
+	// object.
+	Len() int
+}
+
+// KeyedResolver is an object Resolver that can list its keys, so that it
+// can be resolved as a whole, as an object projection or the keys
+// function require.  Resolving an object Resolver that does not list its
+// keys is an error.
+type KeyedResolver interface {
+	Resolver
+	// Keys returns the keys of the fields of the object.
+	Keys() ([]string, error)
+}
+
+// Resolve returns value with every Resolver in it resolved, at any depth,
+// into arrays and objects of the types encoding/json decodes to.  Arrays
+// and objects without resolvers are returned as they are.
+func Resolve(value interface{}) (interface{}, error) {
+	return newInterpreter().resolveAll(value)
+}
+
+// resolve resolves value one level if it is a Resolver: an array resolver
+// into the array of its elements and an object resolver into an object of
+// its fields, whose values are not resolved.  Other values are returned as
+// they are.
+func (intr *treeInterpreter) resolve(value interface{}) (interface{}, error) {
+	r, ok := value.(Resolver)
+	if !ok {
+		return value, nil
+	}
+	if n := r.Len(); n >= 0 {
+		elements := make([]interface{}, n)
+		for i := range elements {
+			element, err := r.Index(i)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = element
+		}
+		return elements, nil
+	}
+	keyed, ok := r.(KeyedResolver)
+	if !ok {
+		return nil, fmt.Errorf("resolver %T does not list its keys", r)
+	}
+	keys, err := keyed.Keys()
+	if err != nil {
+		return nil, err
+	}
+	var ordered *OrderedMap
+	object := make(map[string]interface{}, len(keys))
+	if intr.ordered {
+		ordered = NewOrderedMap()
+	}
+	for _, key := range keys {
+		field, err := r.GetField(key)
+		if err != nil {
+			return nil, err
+		}
+		if ordered != nil {
+			ordered.Set(key, field)
+		} else {
+			object[key] = field
+		}
+	}
+	if ordered != nil {
+		return ordered, nil
+	}
+	return object, nil
+}
+
+// resolveAll resolves every Resolver in value, at any depth.  Arrays and
+// objects are only copied if they hold resolvers.
+func (intr *treeInterpreter) resolveAll(value interface{}) (interface{}, error) {
+	resolved, _, err := intr.resolveDeep(value)
+	return resolved, err
+}
+
+// resolveDeep implements resolveAll, and reports whether value held any
+// resolvers.
+func (intr *treeInterpreter) resolveDeep(value interface{}) (interface{}, bool, error) {
+	_, changed := value.(Resolver)
+	value, err := intr.resolve(value)
+	if err != nil {
+		return nil, false, err
+	}
+	switch v := value.(type) {
+	case []interface{}:
+		var resolved []interface{}
+		for i, element := range v {
+			current, ok, err := intr.resolveDeep(element)
+			if err != nil {
+				return nil, false, err
+			}
+			if ok && resolved == nil {
+				resolved = append([]interface{}{}, v...)
+			}
+			if ok {
+				resolved[i] = current
+			}
+		}
+		if resolved != nil {
+			return resolved, true, nil
+		}
+	case map[string]interface{}:
+		var resolved map[string]interface{}
+		for key, field := range v {
+			current, ok, err := intr.resolveDeep(field)
+			if err != nil {
+				return nil, false, err
+			}
+			if ok && resolved == nil {
+				resolved = make(map[string]interface{}, len(v))
+				for k, f := range v {
+					resolved[k] = f
+				}
+			}
+			if ok {
+				resolved[key] = current
+			}
+		}
+		if resolved != nil {
+			return resolved, true, nil
+		}
+	case *OrderedMap:
+		var resolved *OrderedMap
+		for _, key := range v.keys {
+			current, ok, err := intr.resolveDeep(v.values[key])
+			if err != nil {
+				return nil, false, err
+			}
+			if ok && resolved == nil {
+				resolved = NewOrderedMap()
+				for _, k := range v.keys {
+					resolved.Set(k, v.values[k])
+				}
+			}
+			if ok {
+				resolved.Set(key, current)
+			}
+		}
+		if resolved != nil {
+			return resolved, true, nil
+		}
+	}
+	return value, changed, nil
+}
+
+// resolverIndex evaluates the index node against the array resolver r.
+func (intr *treeInterpreter) resolverIndex(node ASTNode, r Resolver) (interface{}, error) {
+	length := r.Len()
+	if length < 0 {
+		if intr.strict {
+			return nil, newStrictError(node, ErrNotArray, r)
+		}
+		return nil, nil
+	}
+	index := node.value.(int)
+	if index < 0 {
+		index += length
+	}
+	if index < 0 || index >= length {
+		if intr.strict && intr.strictBounds {
+			return nil, newStrictError(node, ErrOutOfRange, r)
+		}
+		return nil, nil
+	}
+	return r.Index(index)
+}
+
+// resolveOperands resolves the operands of a comparison completely.
+func (intr *treeInterpreter) resolveOperands(left, right interface{}) (interface{}, interface{}, error) {
+	left, err := intr.resolveAll(left)
+	if err != nil {
+		return nil, nil, err
+	}
+	right, err = intr.resolveAll(right)
+	if err != nil {
+		return nil, nil, err
+	}
+	return left, right, nil
+}
+
+// resolverField evaluates the field node against the resolver r.  In
+// strict mode, a field r returns nil for is missing.
+func (intr *treeInterpreter) resolverField(node ASTNode, r Resolver) (interface{}, error) {
+	if r.Len() >= 0 {
+		if intr.strict {
+			return nil, newStrictError(node, ErrNotObject, r)
+		}
+		return nil, nil
+	}
+	key, err := intr.fieldKey(node.value.(string), r)
+	if err != nil {
+		return nil, err
+	}
+	field, err := r.GetField(key)
+	if err == nil && field == nil && intr.strict {
+		err = newStrictError(node, ErrMissingKey, r)
+	}
+	return field, err
+}
+
+// resolverSlice evaluates the slice node against the resolver r, resolving
+// only the elements the slice selects.
+func (intr *treeInterpreter) resolverSlice(node ASTNode, r Resolver) (interface{}, error) {
+	length := r.Len()
+	if length < 0 {
+		if intr.strict {
+			return nil, newStrictError(node, ErrNotArray, r)
+		}
+		return nil, nil
+	}
+	bounds, err := intr.sliceBounds(node, r, length)
+	if err != nil {
+		return nil, err
+	}
+	sliced := make([]interface{}, bounds.Len())
+	for i := range sliced {
+		if sliced[i], err = r.Index(bounds.Index(i)); err != nil {
+			return nil, err
+		}
+	}
+	return sliced, nil
+}
+
+// resolveArgument resolves a function argument completely if it is a
+// Resolver or an array holding resolvers, such as the result of a
+// projection of an array resolver.  Resolvers nested deeper in other
+// values are only resolved where the function accesses them through an
+// expression reference, as sort_by does.
+func (intr *treeInterpreter) resolveArgument(arg interface{}) (interface{}, error) {
+	switch v := arg.(type) {
+	case Resolver:
+		return intr.resolveAll(v)
+	case []interface{}:
+		for _, element := range v {
+			if _, ok := element.(Resolver); ok {
+				return intr.resolveAll(v)
+			}
+		}
+	}
+	return arg, nil
+}
+
+// isFalseResolver reports whether the resolver r is an empty array, or an
+// object that lists no keys.
+func isFalseResolver(r Resolver) bool {
+	if n := r.Len(); n >= 0 {
+		return n == 0
+	}
+	if keyed, ok := r.(KeyedResolver); ok {
+		keys, err := keyed.Keys()
+		return err == nil && len(keys) == 0
+	}
+	return false
+}
+
+// resolveNested resolves value one level, and the array resolvers among
+// its elements down to depth levels, as flattening value depth times
+// requires.  Object resolvers among the elements are left unresolved.
+func (intr *treeInterpreter) resolveNested(value interface{}, depth int) (interface{}, error) {
+	value, err := intr.resolve(value)
+	if err != nil || depth == 0 {
+		return value, err
+	}
+	elements, ok := value.([]interface{})
+	if !ok {
+		return value, nil
+	}
+	var resolved []interface{}
+	for i, element := range elements {
+		if r, ok := element.(Resolver); ok && r.Len() < 0 {
+			continue
+		} else if !ok && !nestsResolver(element) {
+			continue
+		}
+		current, err := intr.resolveNested(element, depth-1)
+		if err != nil {
+			return nil, err
+		}
+		if resolved == nil {
+			resolved = append([]interface{}{}, elements...)
+		}
+		resolved[i] = current
+	}
+	if resolved != nil {
+		return resolved, nil
+	}
+	return elements, nil
+}
+
+// nestsResolver reports whether value is an array holding resolvers.
+func nestsResolver(value interface{}) bool {
+	elements, ok := value.([]interface{})
+	if !ok {
+		return false
+	}
+	for _, element := range elements {
+		if _, ok := element.(Resolver); ok || nestsResolver(element) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,232 @@
+package jmespath
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+// lazyValue resolves a value decoded by encoding/json as it is accessed,
+// and records the fields and indexes that were resolved.  Its objects do
+// not list their keys, but those it resolves to do.
+type lazyValue struct {
+	value    interface{}
+	path     string
+	resolved *[]string
+}
+
+// newLazyValue returns a resolver for value, and the paths it resolves.
+func newLazyValue(value interface{}) (Resolver, *[]string) {
+	resolved := &[]string{}
+	return lazy(value, "", resolved), resolved
+}
+
+func lazy(value interface{}, path string, resolved *[]string) Resolver {
+	v := &lazyValue{value: value, path: path, resolved: resolved}
+	if _, ok := value.(map[string]interface{}); ok {
+		return keyedLazyValue{v}
+	}
+	return v
+}
+
+func (v *lazyValue) wrap(value interface{}, path string) interface{} {
+	*v.resolved = append(*v.resolved, path)
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		return lazy(value, path, v.resolved)
+	}
+	return value
+}
+
+func (v *lazyValue) GetField(key string) (interface{}, error) {
+	if key == "broken" {
+		return nil, errors.New("cannot load broken")
+	}
+	return v.wrap(v.value.(map[string]interface{})[key], v.path+"."+key), nil
+}
+
+func (v *lazyValue) Index(i int) (interface{}, error) {
+	return v.wrap(v.value.([]interface{})[i], v.path+"["+strconv.Itoa(i)+"]"), nil
+}
+
+func (v *lazyValue) Len() int {
+	if elements, ok := v.value.([]interface{}); ok {
+		return len(elements)
+	}
+	return -1
+}
+
+// keyedLazyValue is a lazyValue that lists its keys.
+type keyedLazyValue struct {
+	*lazyValue
+}
+
+func (v keyedLazyValue) Keys() ([]string, error) {
+	var keys []string
+	for key := range v.value.(map[string]interface{}) {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func resolverDocument() map[string]interface{} {
+	return map[string]interface{}{
+		"name": "store",
+		"orders": []interface{}{
+			map[string]interface{}{"id": 1.0, "total": 30.0, "items": []interface{}{"a", "b"}},
+			map[string]interface{}{"id": 2.0, "total": 5.0, "items": []interface{}{"c"}},
+			map[string]interface{}{"id": 3.0, "total": 12.0, "items": []interface{}{}},
+		},
+		"owner": map[string]interface{}{"name": "ada", "email": "ada@example.com"},
+		"empty": []interface{}{},
+	}
+}
+
+func TestResolver(t *testing.T) {
+	assert := assert.New(t)
+	traced := NewRuntime()
+	traced.SetTracer(&recordingTracer{})
+	cases := []struct {
+		expression string
+		expected   interface{}
+	}{
+		{"name", "store"},
+		{"owner.name", "ada"},
+		{"orders[0].id", 1.0},
+		{"orders[-1].id", 3.0},
+		{"orders[5]", nil},
+		{"orders[1:].id", []interface{}{2.0, 3.0}},
+		{"orders[::-1] | [0].id", 3.0},
+		{"orders[*].id", []interface{}{1.0, 2.0, 3.0}},
+		{"orders[?total > `10`].id", []interface{}{1.0, 3.0}},
+		{"orders[].items[]", []interface{}{"a", "b", "c"}},
+		{"orders[*].items | []", []interface{}{"a", "b", "c"}},
+		{"length(orders)", 3.0},
+		{"sum(orders[*].total)", 47.0},
+		{"max_by(orders, &total).id", 1.0},
+		{"orders[0].items == ['a', 'b']", true},
+		{"orders[0].items == `[\"a\", \"b\"]`", true},
+		{"'c' in orders[1].items", true},
+		{"to_json(orders[2])", `{"id":3,"items":[],"total":12}`},
+		{"empty || 'none'", "none"},
+		{"!empty", true},
+		{"missing", nil},
+		{"name[0]", nil},
+		{"orders.id", nil},
+		{"orders[0][0]", nil},
+	}
+	for _, tt := range cases {
+		document, _ := newLazyValue(resolverDocument())
+		result, err := Search(tt.expression, document)
+		assert.Nil(err, tt.expression)
+		assert.Equal(tt.expected, result, tt.expression)
+		jp, err := Compile(tt.expression)
+		assert.Nil(err, tt.expression)
+		result, err = jp.Search(document)
+		assert.Nil(err, tt.expression)
+		assert.Equal(tt.expected, result, tt.expression)
+		result, err = traced.Search(tt.expression, document)
+		assert.Nil(err, tt.expression)
+		assert.Equal(tt.expected, result, tt.expression)
+	}
+}
+
+func TestResolverResolvesOnlyWhatIsAccessed(t *testing.T) {
+	assert := assert.New(t)
+	cases := []struct {
+		expression string
+		resolved   []string
+	}{
+		{"owner.name", []string{".owner", ".owner.name"}},
+		{"orders[1].id", []string{".orders", ".orders[1]", ".orders[1].id"}},
+		{"orders[1:2].total", []string{".orders", ".orders[1]", ".orders[1].total"}},
+		{"orders[?id == `2`].total", []string{
+			".orders", ".orders[0]", ".orders[1]", ".orders[2]",
+			".orders[0].id", ".orders[1].id", ".orders[1].total", ".orders[2].id",
+		}},
+		{"owner", []string{".owner"}},
+	}
+	for _, tt := range cases {
+		document, resolved := newLazyValue(resolverDocument())
+		_, err := Search(tt.expression, document)
+		assert.Nil(err, tt.expression)
+		assert.Equal(tt.resolved, *resolved, tt.expression)
+	}
+}
+
+func TestResolverObjects(t *testing.T) {
+	assert := assert.New(t)
+	owner := resolverDocument()["owner"]
+	document := map[string]interface{}{
+		"owner": lazy(owner, "", &[]string{}),
+		"plain": &lazyValue{value: owner, resolved: &[]string{}},
+	}
+	result, err := Search("sort(keys(owner))", document)
+	assert.Nil(err)
+	assert.Equal([]interface{}{"email", "name"}, result)
+	result, err = Search("sort(owner.*)", document)
+	assert.Nil(err)
+	assert.Equal([]interface{}{"ada", "ada@example.com"}, result)
+	result, err = Search("owner == {name: 'ada', email: 'ada@example.com'}", document)
+	assert.Nil(err)
+	assert.Equal(true, result)
+	result, err = Search("plain.name", document)
+	assert.Nil(err)
+	assert.Equal("ada", result)
+	_, err = Search("keys(plain)", document)
+	assert.NotNil(err)
+
+	rt := NewRuntime()
+	rt.SetOrderedObjects(true)
+	result, err = rt.Search("to_json(owner)", document)
+	assert.Nil(err)
+	assert.Equal(`{"email":"ada@example.com","name":"ada"}`, result)
+}
+
+func TestResolverErrors(t *testing.T) {
+	assert := assert.New(t)
+	document, _ := newLazyValue(map[string]interface{}{"a": 1.0, "broken": 2.0})
+	for _, expression := range []string{"broken", "[broken]", "length(@)", "@ == `{}`", "*"} {
+		_, err := Search(expression, document)
+		assert.NotNil(err, expression)
+	}
+	result, err := Search("a", document)
+	assert.Nil(err)
+	assert.Equal(1.0, result)
+
+	rt := NewRuntime()
+	rt.SetStrict(true)
+	rt.SetStrictBounds(true)
+	strictDocument, _ := newLazyValue(resolverDocument())
+	for _, expression := range []string{"missing", "orders.id", "name[0]", "orders[5]", "owner[0:1]"} {
+		_, err := rt.Search(expression, strictDocument)
+		assert.NotNil(err, expression)
+	}
+	result, err = rt.Search("orders[0].id", strictDocument)
+	assert.Nil(err)
+	assert.Equal(1.0, result)
+}
+
+func TestResolve(t *testing.T) {
+	assert := assert.New(t)
+	document, _ := newLazyValue(resolverDocument())
+	result, err := Search("{owner: owner, first: orders[0]}", document)
+	assert.Nil(err)
+	_, isResolver := result.(map[string]interface{})["owner"].(Resolver)
+	assert.True(isResolver)
+	resolved, err := Resolve(result)
+	assert.Nil(err)
+	assert.Equal(map[string]interface{}{
+		"owner": map[string]interface{}{"name": "ada", "email": "ada@example.com"},
+		"first": map[string]interface{}{"id": 1.0, "total": 30.0, "items": []interface{}{"a", "b"}},
+	}, resolved)
+
+	plain := []interface{}{1.0, map[string]interface{}{"a": "b"}}
+	resolved, err = Resolve(plain)
+	assert.Nil(err)
+	assert.Equal(plain, resolved)
+}
@@ -0,0 +1,376 @@
+package jmespath
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Runtime holds the configuration shared by the expressions compiled with it,
+// such as user-registered functions.  A Runtime is safe for concurrent use.
+// Expressions take a snapshot of the configuration when they are compiled,
+// so changing a Runtime never affects expressions that were already
+// compiled with it.
+//
+// All configuration is held by Runtime values, the package has no mutable
+// global state.  Configuring a Runtime never affects other Runtimes or the
+// package level functions such as Compile and Search, which behave as if
+// they used a new Runtime.
+type Runtime struct {
+	mu sync.Mutex
+	// fCall is never modified once it is shared with an interpreter, it is
+	// replaced by a modified copy instead.
+	fCall        *functionCaller
+	multiValue   MultiValueMode
+	ordered      bool
+	strict       bool
+	strictBounds bool
+	tracer       Tracer
+	decoder      Decoder
+	workers      int
+	graphemes    bool
+	// collations is never modified once it is shared with an
+	// interpreter, it is replaced by a modified copy instead.
+	collations      map[string]Collation
+	collation       Collation
+	integers        IntegerMode
+	descent         bool
+	legacy          bool
+	legacyCompare   bool
+	metrics         Metrics
+	sortedKeys      bool
+	invalidSortKeys InvalidSortKeyMode
+	limits          Limits
+	keepNulls       bool
+	foldKeys        bool
+	numbers         NumberFormat
+	// macros is never modified once it is shared with a parser, it is
+	// replaced by a modified copy instead.
+	macros map[string]string
+}
+
+// MultiValueMode controls how the values of maps from strings to string
+// slices, such as url.Values and http.Header, are exposed to expressions.
+// Maps of this kind, along with map[string]string, are treated as objects.
+type MultiValueMode int
+
+const (
+	// MultiValueAll exposes every value of a key as an array of strings.
+	MultiValueAll MultiValueMode = iota
+	// MultiValueFirst exposes only the first value of a key as a string,
+	// like url.Values.Get and http.Header.Get.
+	MultiValueFirst
+)
+
+func (mode MultiValueMode) convert(values []string) interface{} {
+	if mode == MultiValueFirst {
+		if len(values) == 0 {
+			return nil
+		}
+		return values[0]
+	}
+	converted := make([]interface{}, len(values))
+	for i, v := range values {
+		converted[i] = v
+	}
+	return converted
+}
+
+// NewRuntime creates a Runtime with only the built-in JMESPath functions
+// available.
+func NewRuntime() *Runtime {
+	return &Runtime{fCall: newFunctionCaller()}
+}
+
+// RegisterFunction makes fn callable from expressions evaluated by this
+// runtime under the given name.  The arguments are not type checked, fn is
+// responsible for validating them.  A panic in fn is returned as a
+// FunctionError.  It is an error to register a function with the same name
+// as an existing function.
+func (rt *Runtime) RegisterFunction(name string, fn Function) error {
+	if name == "" {
+		return errors.New("function name cannot be empty")
+	}
+	if fn == nil {
+		return errors.New("function cannot be nil: " + name)
+	}
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	if _, ok := rt.fCall.functionTable[name]; ok {
+		return errors.New("function already defined: " + name)
+	}
+	rt.fCall = rt.fCall.with(functionEntry{name: name, custom: fn})
+	return nil
+}
+
+// OverrideFunction replaces the function name, built-in or registered,
+// with fn in expressions compiled after the call, for example to make a
+// function deterministic in tests.  Like a registered function, fn is
+// responsible for validating its arguments.  It is an error to override a
+// function that does not exist, use RegisterFunction to add one.
+func (rt *Runtime) OverrideFunction(name string, fn Function) error {
+	if fn == nil {
+		return errors.New("function cannot be nil: " + name)
+	}
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	if _, ok := rt.fCall.functionTable[name]; !ok {
+		return errors.New("unknown function: " + name)
+	}
+	rt.fCall = rt.fCall.with(functionEntry{name: name, custom: fn})
+	return nil
+}
+
+// DisableFunction removes the functions names, built-in or registered,
+// from expressions compiled after the call, which fail when they call one
+// of them as if it did not exist, for example to evaluate untrusted
+// expressions in a sandbox.  It is an error to disable a function that
+// does not exist, in which case no function is disabled.
+func (rt *Runtime) DisableFunction(names ...string) error {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	for _, name := range names {
+		if _, ok := rt.fCall.functionTable[name]; !ok {
+			return errors.New("unknown function: " + name)
+		}
+	}
+	for _, name := range names {
+		rt.fCall = rt.fCall.without(name)
+	}
+	return nil
+}
+
+// SetFunctionTimeout limits how long the user-registered function name may
+// run when called from expressions compiled after the call.  Calls that
+// exceed it fail with a FunctionError wrapping ErrFunctionTimeout, while the
+// function keeps running until it returns or CallContext.Done is closed.  A
+// timeout of zero removes the limit.
+func (rt *Runtime) SetFunctionTimeout(name string, timeout time.Duration) error {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	entry, ok := rt.fCall.functionTable[name]
+	if !ok || entry.custom == nil {
+		return errors.New("not a user-registered function: " + name)
+	}
+	entry.timeout = timeout
+	rt.fCall = rt.fCall.with(entry)
+	return nil
+}
+
+// SetMultiValueMode sets how multi-value maps such as url.Values and
+// http.Header are exposed to expressions compiled after the call.  The
+// default is MultiValueAll.
+func (rt *Runtime) SetMultiValueMode(mode MultiValueMode) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.multiValue = mode
+}
+
+// SetOrderedObjects sets whether expressions compiled after the call
+// evaluate objects in a deterministic key order.  When enabled,
+// multiselect hashes evaluate to *OrderedMap values with their keys in the
+// order they are written in the expression, and keys(), values() and object
+// projections such as "foo.*" follow the key order of *OrderedMap inputs,
+// or sorted key order for other objects.  The default is false.
+func (rt *Runtime) SetOrderedObjects(ordered bool) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.ordered = ordered
+}
+
+// SetSortedKeys sets whether expressions compiled after the call visit the
+// keys of objects in sorted order, so that keys(), values() and object
+// projections such as "foo.*" return their results in a deterministic
+// order, and map_values() and filter_values() evaluate their expressions
+// for the keys in that order.  Unlike ordered objects, sorted keys do not
+// change the results of expressions otherwise.  When ordered objects are
+// enabled, they take precedence and the keys of *OrderedMap values are
+// visited in their order.  The default is false, for which keys are visited
+// in Go's map iteration order, which varies from one evaluation to the
+// next.
+func (rt *Runtime) SetSortedKeys(sorted bool) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.sortedKeys = sorted
+}
+
+// SetStrict sets whether expressions compiled after the call are evaluated
+// in strict mode.  In strict mode reading a field that is missing from an
+// object, reading a field of a value that is not an object, indexing,
+// slicing, flattening or projecting a value that is not an array, and
+// ordering values that are not both numbers are errors instead of
+// evaluating to null.  The errors are returned as a *StrictError, so that
+// a missing field can be told apart from a field that is null.  Strict
+// expressions are evaluated by a slower, unoptimized interpreter.  The
+// default is false.
+func (rt *Runtime) SetStrict(strict bool) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.strict = strict
+}
+
+// SetKeepNulls sets whether projections in expressions compiled after the
+// call keep the null results of their right side.  By default they are
+// dropped, as the JMESPath specification requires, so that "a[*].b" and
+// "a[].b" skip the elements without a b and the result may be shorter than
+// a.  When nulls are kept the result has one value for each element
+// projected, or each element that matched the filter of a filter
+// projection, as in tools such as jq.  Flattens never drop nulls of their
+// own, "[`1`, null][]" only evaluates to [1] because "[]" is itself a
+// projection.  Expressions that keep nulls are evaluated by the slower,
+// unoptimized interpreter.  The default is false.
+func (rt *Runtime) SetKeepNulls(keep bool) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.keepNulls = keep
+}
+
+// SetCaseInsensitiveKeys sets whether the fields of expressions compiled
+// after the call match the keys of objects regardless of case, so that
+// "name" selects the value of a "Name" or "NAME" key, for data whose key
+// casing varies, such as the output of some CSV converters.  A key that
+// matches exactly always wins, otherwise the smallest of the keys that
+// match in byte order, whatever the order of the keys in the object.  It
+// applies to maps and object resolvers, which must list their keys to be
+// matched case-insensitively, but not to the fields of structs, whose
+// first letter is always matched regardless of case.  Other expressions,
+// such as keys() and multi-select hashes, are not affected.  The paths
+// returned by SearchPaths, the keys Set and Delete update, the changes
+// Incremental re-evaluates for and the properties CompileWithSchema checks
+// are matched the same way.  Expressions with case-insensitive keys are
+// evaluated by the slower, unoptimized interpreter.  The default is false.
+func (rt *Runtime) SetCaseInsensitiveKeys(fold bool) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.foldKeys = fold
+}
+
+// SetStrictBounds sets whether strict mode, see SetStrict, also reports
+// indexes and slices that reach outside of arrays for expressions compiled
+// after the call.  An index is outside of an array of length n unless it
+// is between -n and n-1, and a slice start or stop unless it is between -n
+// and n, see jputil.Slice.  The errors wrap ErrOutOfRange.  It has no
+// effect on expressions that are not strict.  The default is false.
+func (rt *Runtime) SetStrictBounds(strictBounds bool) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.strictBounds = strictBounds
+}
+
+// SetRecursiveDescent sets whether expressions compiled after the call may
+// use the recursive descent operator "..", which searches for a key at any
+// depth of the document.  "..key" and "expr..key" project the values of
+// key found in the current node or in expr, and in every array and object
+// nested in it, in document order, as in "..spec.containers[]".  The keys
+// of objects are visited in sorted order unless they are *OrderedMap
+// values.  The operator is not part of the JMESPath specification, which
+// requires "foo..bar" to be a syntax error, so the default is false.
+func (rt *Runtime) SetRecursiveDescent(descent bool) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.descent = descent
+}
+
+// SetTracer sets the Tracer that observes the evaluation of expressions
+// compiled after the call, or removes it if tracer is nil.  Traced
+// expressions are evaluated by a slower, unoptimized interpreter so that
+// the traced nodes match the expression as written.  Expressions compiled
+// without a tracer are not slowed down at all.
+func (rt *Runtime) SetTracer(tracer Tracer) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.tracer = tracer
+}
+
+// SetParallelism sets the number of goroutines that evaluate the elements
+// of large array projections and filters, such as "records[?score(@) >
+// `0.5`]", in expressions compiled after the call.  Projections over fewer
+// than 1024 elements, nested projections and strict or traced expressions
+// are always evaluated by the calling goroutine.  The results are the same
+// as without parallelism, but functions registered with RegisterFunction
+// may be called concurrently.  A number of workers below 2 disables
+// parallel evaluation, which is the default.
+func (rt *Runtime) SetParallelism(workers int) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.workers = workers
+}
+
+// SetGraphemeClusters sets whether length(), reverse() and slices such as
+// "name[0:3]" treat strings as sequences of grapheme clusters, the
+// characters a reader perceives, in expressions compiled after the call.
+// By default they operate on Unicode code points, so that the length of
+// "e\u0301" (an e with a combining accent) is 2, and reversing it moves the
+// accent onto the preceding character.  With grapheme clusters its length
+// is 1.  The default is false.
+func (rt *Runtime) SetGraphemeClusters(graphemes bool) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.graphemes = graphemes
+}
+
+// Compile parses a JMESPath expression and returns a JMESPath object that is
+// evaluated with the functions available in this runtime.
+func (rt *Runtime) Compile(expression string) (jp *JMESPath, err error) {
+	intr := rt.newInterpreter()
+	if intr.metrics != nil {
+		defer observeCompile(intr.metrics, expression, time.Now(), &jp, &err)
+	}
+	defer recoverInternal(&err)
+	ast, err := rt.newParser(intr).Parse(expression)
+	if err != nil {
+		return nil, err
+	}
+	jp = newJMESPath(ast, intr)
+	jp.expression = expression
+	return jp, nil
+}
+
+// Search evaluates a JMESPath expression against input data using the
+// functions available in this runtime.
+func (rt *Runtime) Search(expression string, data interface{}) (interface{}, error) {
+	jp, err := rt.Compile(expression)
+	if err != nil {
+		return nil, err
+	}
+	return jp.Search(data)
+}
+
+// newParser returns a parser for the expressions evaluated by intr.
+func (rt *Runtime) newParser(intr *treeInterpreter) *Parser {
+	parser := NewParser()
+	parser.exactIntegers = intr.integers != IntegersAsFloat
+	rt.mu.Lock()
+	parser.recursiveDescent = rt.descent
+	parser.legacyLiterals = rt.legacy
+	parser.limits = rt.limits
+	parser.macros = rt.macros
+	rt.mu.Unlock()
+	return parser
+}
+
+func (rt *Runtime) newInterpreter() *treeInterpreter {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	return &treeInterpreter{
+		fCall:           rt.fCall,
+		multiValue:      rt.multiValue,
+		ordered:         rt.ordered,
+		strict:          rt.strict,
+		strictBounds:    rt.strictBounds,
+		tracer:          rt.tracer,
+		decoder:         rt.decoder,
+		workers:         rt.workers,
+		graphemes:       rt.graphemes,
+		collations:      rt.collations,
+		collation:       rt.collation,
+		integers:        rt.integers,
+		numbers:         rt.numbers,
+		legacyCompare:   rt.legacyCompare,
+		metrics:         rt.metrics,
+		sortedKeys:      rt.sortedKeys,
+		invalidSortKeys: rt.invalidSortKeys,
+		keepNulls:       rt.keepNulls,
+		foldKeys:        rt.foldKeys,
+	}
+}
@@ -0,0 +1,221 @@
+package jmespath
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func TestRuntimeCanCallRegisteredFunction(t *testing.T) {
+	assert := assert.New(t)
+	rt := NewRuntime()
+	err := rt.RegisterFunction("double", func(ctx CallContext, args []interface{}) (interface{}, error) {
+		n, ok := args[0].(float64)
+		if !ok {
+			return nil, errors.New("double: expected a number")
+		}
+		return n * 2, nil
+	})
+	assert.Nil(err)
+	result, err := rt.Search("double(foo)", map[string]interface{}{"foo": 21.0})
+	assert.Nil(err)
+	assert.Equal(42.0, result)
+}
+
+func TestRuntimeRegisteredFunctionCanApplyExpRef(t *testing.T) {
+	assert := assert.New(t)
+	rt := NewRuntime()
+	// count_by(&expr, array) groups the array by the string key expr
+	// evaluates to and counts each group.
+	err := rt.RegisterFunction("count_by", func(ctx CallContext, args []interface{}) (interface{}, error) {
+		ref, ok := args[0].(ExpRef)
+		if !ok {
+			return nil, errors.New("count_by: expected an expression reference")
+		}
+		counts := make(map[string]interface{})
+		for _, item := range args[1].([]interface{}) {
+			key, err := ctx.Apply(ref, item)
+			if err != nil {
+				return nil, err
+			}
+			k, _ := key.(string)
+			n, _ := counts[k].(float64)
+			counts[k] = n + 1
+		}
+		return counts, nil
+	})
+	assert.Nil(err)
+	data := map[string]interface{}{"people": []interface{}{
+		map[string]interface{}{"state": "WA"},
+		map[string]interface{}{"state": "OR"},
+		map[string]interface{}{"state": "WA"},
+	}}
+	result, err := rt.Search("count_by(&state, people)", data)
+	assert.Nil(err)
+	assert.Equal(map[string]interface{}{"WA": 2.0, "OR": 1.0}, result)
+}
+
+func TestRuntimeRegisterFunctionErrors(t *testing.T) {
+	assert := assert.New(t)
+	rt := NewRuntime()
+	noop := func(ctx CallContext, args []interface{}) (interface{}, error) { return nil, nil }
+	assert.NotNil(rt.RegisterFunction("", noop))
+	assert.NotNil(rt.RegisterFunction("noop", nil))
+	assert.NotNil(rt.RegisterFunction("length", noop))
+	assert.Nil(rt.RegisterFunction("noop", noop))
+	assert.NotNil(rt.RegisterFunction("noop", noop))
+}
+
+func TestRuntimeFunctionsAreNotShared(t *testing.T) {
+	assert := assert.New(t)
+	rt := NewRuntime()
+	err := rt.RegisterFunction("answer", func(ctx CallContext, args []interface{}) (interface{}, error) {
+		return 42.0, nil
+	})
+	assert.Nil(err)
+	_, err = NewRuntime().Search("answer()", nil)
+	assert.NotNil(err)
+	_, err = Search("answer()", nil)
+	assert.NotNil(err)
+}
+
+func TestRuntimeOverrideFunction(t *testing.T) {
+	assert := assert.New(t)
+	rt := NewRuntime()
+	before, err := rt.Compile("to_string(secret)")
+	assert.Nil(err)
+	err = rt.OverrideFunction("to_string", func(ctx CallContext, args []interface{}) (interface{}, error) {
+		return "<redacted>", nil
+	})
+	assert.Nil(err)
+	data := map[string]interface{}{"secret": "hunter2"}
+	result, err := rt.Search("to_string(secret)", data)
+	assert.Nil(err)
+	assert.Equal("<redacted>", result)
+	result, err = before.Search(data)
+	assert.Nil(err)
+	assert.Equal("hunter2", result, "expressions compiled before are not affected")
+	result, err = Search("to_string(secret)", data)
+	assert.Nil(err)
+	assert.Equal("hunter2", result)
+
+	// Overrides replace the variants of functions for the runtime's modes.
+	rt.SetOrderedObjects(true)
+	assert.Nil(rt.OverrideFunction("keys", func(ctx CallContext, args []interface{}) (interface{}, error) {
+		return []interface{}{"fixed"}, nil
+	}))
+	result, err = rt.Search("keys(@)", data)
+	assert.Nil(err)
+	assert.Equal([]interface{}{"fixed"}, result)
+
+	noop := func(ctx CallContext, args []interface{}) (interface{}, error) { return nil, nil }
+	assert.NotNil(rt.OverrideFunction("nope", noop))
+	assert.NotNil(rt.OverrideFunction("length", nil))
+	assert.Nil(rt.RegisterFunction("mine", noop))
+	assert.Nil(rt.OverrideFunction("mine", noop))
+}
+
+func TestRuntimeOverrideLazyFunctions(t *testing.T) {
+	assert := assert.New(t)
+	data := map[string]interface{}{"a": 1.0, "b": 2.0}
+	var passed []interface{}
+	record := func(ctx CallContext, args []interface{}) (interface{}, error) {
+		passed = args
+		return nil, nil
+	}
+	rt := NewRuntime()
+	for _, name := range []string{"first_of", "if", "exists"} {
+		assert.Nil(rt.OverrideFunction(name, record))
+	}
+
+	_, err := rt.Search("first_of(a, b, a)", data)
+	assert.Nil(err)
+	assert.Equal([]interface{}{1.0, 2.0, 1.0}, passed)
+	_, err = rt.Search("if(a, b, a)", data)
+	assert.Nil(err)
+	assert.Equal([]interface{}{1.0, 2.0, 1.0}, passed)
+	_, err = rt.Search("exists(&a)", data)
+	assert.Nil(err)
+	if assert.Len(passed, 1) {
+		_, ok := passed[0].(ExpRef)
+		assert.True(ok, "exists is passed the expression reference")
+	}
+}
+
+func TestRuntimeDisableFunction(t *testing.T) {
+	assert := assert.New(t)
+	rt := NewRuntime()
+	assert.Nil(rt.RegisterFunction("answer", func(ctx CallContext, args []interface{}) (interface{}, error) {
+		return 42.0, nil
+	}))
+	assert.Nil(rt.DisableFunction("to_string", "answer"))
+	_, err := rt.Search("to_string(`1`)", nil)
+	assert.NotNil(err)
+	_, err = rt.Search("answer()", nil)
+	assert.NotNil(err)
+	result, err := rt.Search("length('abc')", nil)
+	assert.Nil(err)
+	assert.Equal(3.0, result)
+	for _, signature := range rt.Functions() {
+		assert.NotEqual("to_string", signature.Name)
+	}
+
+	assert.NotNil(rt.DisableFunction("length", "nope"))
+	_, err = rt.Search("length('abc')", nil)
+	assert.Nil(err, "no function is disabled if one does not exist")
+	assert.NotNil(rt.DisableFunction("to_string"))
+	_, err = NewRuntime().Search("to_string(`1`)", nil)
+	assert.Nil(err)
+}
+
+func TestRuntimeRegisteredFunctionPanicsAreErrors(t *testing.T) {
+	assert := assert.New(t)
+	rt := NewRuntime()
+	err := rt.RegisterFunction("boom", func(ctx CallContext, args []interface{}) (interface{}, error) {
+		var m map[string]int
+		m["x"] = 1
+		return nil, nil
+	})
+	assert.Nil(err)
+	_, err = rt.Search("boom()", nil)
+	if assert.IsType(&FunctionError{}, err) {
+		assert.Equal("boom", err.(*FunctionError).Function)
+	}
+}
+
+func TestRuntimeFunctionTimeout(t *testing.T) {
+	assert := assert.New(t)
+	rt := NewRuntime()
+	stopped := make(chan struct{})
+	err := rt.RegisterFunction("hang", func(ctx CallContext, args []interface{}) (interface{}, error) {
+		<-ctx.Done()
+		close(stopped)
+		return nil, nil
+	})
+	assert.Nil(err)
+	assert.Nil(rt.SetFunctionTimeout("hang", 10*time.Millisecond))
+	_, err = rt.Search("hang()", nil)
+	assert.True(errors.Is(err, ErrFunctionTimeout))
+	if assert.IsType(&FunctionError{}, err) {
+		assert.Equal("hang", err.(*FunctionError).Function)
+	}
+	<-stopped
+
+	assert.NotNil(rt.SetFunctionTimeout("length", time.Second))
+	assert.NotNil(rt.SetFunctionTimeout("missing", time.Second))
+}
+
+func TestRuntimeFunctionWithinTimeout(t *testing.T) {
+	assert := assert.New(t)
+	rt := NewRuntime()
+	err := rt.RegisterFunction("quick", func(ctx CallContext, args []interface{}) (interface{}, error) {
+		return "done", nil
+	})
+	assert.Nil(err)
+	assert.Nil(rt.SetFunctionTimeout("quick", time.Minute))
+	result, err := rt.Search("quick()", nil)
+	assert.Nil(err)
+	assert.Equal("done", result)
+}
@@ -0,0 +1,772 @@
+package jmespath
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/* Expressions are checked against a schema by evaluating the AST
+   abstractly, as ReferencedPaths does: instead of a value, each node
+   evaluates to the schemas its value matches one of, and a field, index or
+   function argument that none of them allows is reported.  The check is
+   lenient: a value the schema does not describe, such as the result of a
+   function, may be anything, and nothing is reported about a value
+   computed from one that was already reported.
+*/
+
+// maxSchemaDepth bounds the number of references and nested anyOf, oneOf
+// and allOf followed to find the alternatives of a schema, so that
+// recursive schemas terminate.
+const maxSchemaDepth = 32
+
+// Schema is a JSON Schema of the documents expressions are evaluated
+// against, to check the expressions with CompileWithSchema.  The keywords
+// describing the shape of documents are understood: type, properties,
+// patternProperties, additionalProperties, items, prefixItems, enum,
+// const, anyOf, oneOf, allOf and $ref references within the schema.  Other
+// keywords, such as required or minimum, are ignored.
+type Schema struct {
+	root interface{}
+}
+
+// NewSchema parses a JSON Schema.
+func NewSchema(data []byte) (*Schema, error) {
+	var root interface{}
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	switch root.(type) {
+	case map[string]interface{}, bool:
+		return &Schema{root: root}, nil
+	}
+	return nil, errors.New("a schema must be an object or a boolean")
+}
+
+// SchemaIssue is a problem found checking an expression against a schema:
+// a field the schema does not declare, or a field, index, projection or
+// function argument applied to a value of the wrong type.
+type SchemaIssue struct {
+	Expression    string // Expression being checked.
+	SubExpression string // Part of the expression at fault, in canonical form.
+	Offset        int    // Location of the issue in Expression, or -1 if it is unknown.
+	Message       string // Description of the issue.
+}
+
+func (i SchemaIssue) String() string {
+	location := i.SubExpression
+	if i.Offset >= 0 {
+		location += " at offset " + strconv.Itoa(i.Offset)
+	}
+	return location + ": " + i.Message
+}
+
+// HighlightLocation shows where the issue is in the expression, as
+// SyntaxError.HighlightLocation does.  It returns SubExpression if the
+// location is unknown.
+func (i SchemaIssue) HighlightLocation() string {
+	if i.Offset < 0 {
+		return i.SubExpression
+	}
+	return i.Expression + "\n" + strings.Repeat(" ", i.Offset) + "^"
+}
+
+// SchemaError is returned by CompileWithSchema when an expression does not
+// match the schema of the documents it is meant for.
+type SchemaError struct {
+	Issues []SchemaIssue // Issues in the order they appear in the expression.
+}
+
+func (e *SchemaError) Error() string {
+	issues := make([]string, len(e.Issues))
+	for i, issue := range e.Issues {
+		issues[i] = issue.String()
+	}
+	return strings.Join(issues, "; ")
+}
+
+// CompileWithSchema is like Compile, but also checks the expression against
+// the schema of the documents it will be evaluated against, and returns a
+// *SchemaError listing every issue found if they do not match.
+//
+// Fields are reported when an object schema lists properties but not the
+// field, unless additionalProperties or patternProperties allow it.  JSON
+// Schema allows any property by default, but this catches misspelt names
+// such as "instance.stat" for "instance.state".  Fields of values that
+// cannot be objects, and indexes, slices and projections of values that
+// cannot be arrays, are reported too, as well as arguments of the wrong
+// type for built-in functions.
+func CompileWithSchema(expression string, schema *Schema) (*JMESPath, error) {
+	return NewRuntime().CompileWithSchema(expression, schema)
+}
+
+// CompileWithSchema is like Compile, but also checks the expression against
+// a schema as the package-level CompileWithSchema does.
+func (rt *Runtime) CompileWithSchema(expression string, schema *Schema) (_ *JMESPath, err error) {
+	defer recoverInternal(&err)
+	intr := rt.newInterpreter()
+	parser := rt.newParser(intr)
+	ast, err := parser.Parse(expression)
+	if err != nil {
+		return nil, err
+	}
+	c := schemaChecker{
+		expression: expression,
+		schema:     schema,
+		functions:  intr.fCall,
+		offsets:    make(map[string]int),
+		foldKeys:   intr.foldKeys,
+	}
+	nameOffsets(ast, "", parser.names, c.offsets)
+	c.check(ast, "", c.alternatives(schema.root, 0))
+	if len(c.issues) > 0 {
+		sort.SliceStable(c.issues, func(i, j int) bool {
+			return uint(c.issues[i].Offset) < uint(c.issues[j].Offset)
+		})
+		return nil, &SchemaError{Issues: c.issues}
+	}
+	jp := newJMESPath(ast, intr)
+	jp.expression = expression
+	return jp, nil
+}
+
+// schemaShape holds the schemas a value matches one of, as JSON objects,
+// or is nil if the value may be anything.  The checker also builds schemas
+// for the values it computes, whose items and properties may be
+// schemaShapes instead of JSON values.
+type schemaShape []map[string]interface{}
+
+type schemaChecker struct {
+	expression string
+	schema     *Schema
+	functions  *functionCaller
+	// offsets maps the paths of field and function nodes, see childPath,
+	// to the offsets of their names.
+	offsets map[string]int
+	issues  []SchemaIssue
+	// onField, if set, is called with the shape of the value each field is
+	// read from.
+	onField func(name string, current schemaShape)
+	// foldKeys is set when fields match properties regardless of case,
+	// see Runtime.SetCaseInsensitiveKeys.
+	foldKeys bool
+}
+
+// childPath returns the path of the i-th child of the node at path.
+func childPath(path string, i int) string {
+	return path + "/" + strconv.Itoa(i)
+}
+
+// nameOffsets records the offsets of the names of the field and function
+// nodes below node, which are given in the order they appear in the
+// expression, and returns the offsets left.
+func nameOffsets(node ASTNode, path string, names []int, offsets map[string]int) []int {
+	if (node.nodeType == ASTField || node.nodeType == ASTFunctionExpression) && len(names) > 0 {
+		offsets[path] = names[0]
+		names = names[1:]
+	}
+	order := []int{0, 1, 2}
+	if node.nodeType == ASTFilterProjection {
+		// The condition is written before the right side.
+		order = []int{0, 2, 1}
+	}
+	for i := range node.children {
+		if len(node.children) == 3 {
+			i = order[i]
+		}
+		names = nameOffsets(node.children[i], childPath(path, i), names, offsets)
+	}
+	return names
+}
+
+// offset returns the offset of the name of the node at path, or of the
+// last name below it for other nodes, or -1 if there is none.
+func (c *schemaChecker) offset(path string) int {
+	if offset, ok := c.offsets[path]; ok {
+		return offset
+	}
+	last := -1
+	for p, offset := range c.offsets {
+		if strings.HasPrefix(p, path+"/") && offset > last {
+			last = offset
+		}
+	}
+	return last
+}
+
+func (c *schemaChecker) report(node ASTNode, offset int, message string) {
+	c.issues = append(c.issues, SchemaIssue{
+		Expression:    c.expression,
+		SubExpression: FormatAST(node),
+		Offset:        offset,
+		Message:       message,
+	})
+}
+
+// check returns the shape of the value node evaluates to when current is
+// the shape of the current node, and reports the issues found in node.
+func (c *schemaChecker) check(node ASTNode, path string, current schemaShape) schemaShape {
+	switch node.nodeType {
+	case ASTField:
+		name := node.value.(string)
+		if c.onField != nil {
+			c.onField(name, current)
+		}
+		result, message := c.field(name, current)
+		if message != "" {
+			c.report(node, c.offset(path), message)
+		}
+		return result
+	case ASTCurrentNode, ASTIdentity:
+		return current
+	case ASTLiteral:
+		return typedShape(jsonType(node.value))
+	case ASTIndex, ASTSlice:
+		return c.index(node, node, -1, current)
+	case ASTIndexExpression:
+		left := c.check(node.children[0], childPath(path, 0), current)
+		switch node.children[1].nodeType {
+		case ASTIndex, ASTSlice:
+			return c.index(node, node.children[1], c.offset(childPath(path, 0)), left)
+		}
+		return c.check(node.children[1], childPath(path, 1), left)
+	case ASTSubexpression, ASTPipe:
+		left := c.check(node.children[0], childPath(path, 0), current)
+		return c.check(node.children[1], childPath(path, 1), left)
+	case ASTProjection, ASTFilterProjection:
+		left := c.check(node.children[0], childPath(path, 0), current)
+		// A sliced string is not projected, see slicedString.
+		_, sliced := slicedString(node, "")
+		elements, ok := c.items(left, node.nodeType == ASTProjection && sliced)
+		if !ok {
+			c.report(node, c.offset(childPath(path, 0)), "expected array, got "+describeShape(left))
+			return nil
+		}
+		if node.nodeType == ASTFilterProjection {
+			c.check(node.children[2], childPath(path, 2), elements)
+		}
+		return arrayShape(c.check(node.children[1], childPath(path, 1), elements))
+	case ASTValueProjection:
+		left := c.check(node.children[0], childPath(path, 0), current)
+		values, ok := c.values(left)
+		if !ok {
+			c.report(node, c.offset(childPath(path, 0)), "expected object, got "+describeShape(left))
+			return nil
+		}
+		return arrayShape(c.check(node.children[1], childPath(path, 1), values))
+	case ASTFlatten:
+		operand := c.check(node.children[0], childPath(path, 0), current)
+		elements, ok := c.items(operand, false)
+		if !ok {
+			c.report(node, c.offset(childPath(path, 0)), "expected array, got "+describeShape(operand))
+			return nil
+		}
+		return arrayShape(c.flatten(elements))
+	case ASTComparator, ASTNotExpression:
+		for i, child := range node.children {
+			c.check(child, childPath(path, i), current)
+		}
+		return typedShape("boolean")
+	case ASTOrExpression, ASTAndExpression:
+		left := c.check(node.children[0], childPath(path, 0), current)
+		right := c.check(node.children[1], childPath(path, 1), current)
+		if left == nil || right == nil {
+			return nil
+		}
+		return append(left[:len(left):len(left)], right...)
+	case ASTMultiSelectList:
+		for i, child := range node.children {
+			c.check(child, childPath(path, i), current)
+		}
+		return typedShape("array")
+	case ASTMultiSelectHash:
+		properties := make(map[string]interface{}, len(node.children))
+		for i, child := range node.children {
+			properties[child.value.(string)] = c.check(child, childPath(path, i), current)
+		}
+		return schemaShape{{"type": "object", "properties": properties}}
+	case ASTKeyValPair:
+		return c.check(node.children[0], childPath(path, 0), current)
+	case ASTFunctionExpression:
+		c.function(node, path, current)
+		return nil
+	}
+	// The remaining nodes evaluate to values the schema does not describe.
+	for i, child := range node.children {
+		c.check(child, childPath(path, i), current)
+	}
+	return nil
+}
+
+// function checks the arguments of the function call node against the
+// types the function accepts.  Expression references are applied to the
+// elements of the other arguments.
+func (c *schemaChecker) function(node ASTNode, path string, current schemaShape) {
+	var args []schemaShape
+	elements := schemaShape{}
+	for i, child := range node.children {
+		if child.nodeType == ASTExpRef {
+			args = append(args, nil)
+			continue
+		}
+		arg := c.check(child, childPath(path, i), current)
+		args = append(args, arg)
+		if items, ok := c.items(arg, false); ok && elements != nil {
+			if items == nil {
+				elements = nil
+			} else {
+				elements = append(elements, items...)
+			}
+		}
+	}
+	if len(elements) == 0 {
+		elements = nil
+	}
+	for i, child := range node.children {
+		if child.nodeType == ASTExpRef {
+			c.check(child.children[0], childPath(childPath(path, i), 0), elements)
+		}
+	}
+	entry, ok := c.functions.functionTable[node.value.(string)]
+	if !ok || entry.custom != nil || len(entry.arguments) == 0 {
+		return
+	}
+	last := len(entry.arguments) - 1
+	for i, arg := range args {
+		spec := entry.arguments[last]
+		if i < last {
+			spec = entry.arguments[i]
+		}
+		if arg == nil || c.matches(arg, spec.types) {
+			continue
+		}
+		expected := make([]string, len(spec.types))
+		for j, t := range spec.types {
+			expected[j] = string(t)
+		}
+		c.report(node, c.offset(path), fmt.Sprintf("invalid type for argument %d: expected %s, got %s",
+			i+1, strings.Join(expected, " or "), describeShape(arg)))
+	}
+}
+
+// matches reports whether a value of the given shape may have one of the
+// types.  The element types of arrays are not checked.
+func (c *schemaChecker) matches(shape schemaShape, types []jpType) bool {
+	for _, t := range types {
+		name := string(t)
+		switch t {
+		case jpAny, jpExpref:
+			return true
+		case jpArrayNumber, jpArrayString:
+			name = "array"
+		}
+		for _, schema := range shape {
+			if allowsType(schema, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// field returns the shape of the value of the field name of a value of
+// the given shape, or a message describing why the field cannot be read.
+func (c *schemaChecker) field(name string, current schemaShape) (schemaShape, string) {
+	if current == nil {
+		return nil, ""
+	}
+	var result schemaShape
+	var objects []map[string]interface{}
+	for _, schema := range current {
+		if !allowsType(schema, "object") {
+			continue
+		}
+		objects = append(objects, schema)
+		value, declared := c.property(schema, name)
+		if !declared {
+			continue
+		}
+		if value == nil {
+			return nil, ""
+		}
+		result = append(result, value...)
+	}
+	if len(objects) == 0 {
+		return nil, "expected object, got " + describeShape(current)
+	}
+	if result == nil {
+		message := fmt.Sprintf("unknown property %q", name)
+		if suggestion := suggestProperty(name, objects); suggestion != "" {
+			message += fmt.Sprintf(", did you mean %q?", suggestion)
+		}
+		return nil, message
+	}
+	return result, ""
+}
+
+// property returns the shape of the property name of the object schema,
+// and whether the schema allows the property.
+func (c *schemaChecker) property(schema map[string]interface{}, name string) (schemaShape, bool) {
+	if properties, ok := schema["properties"].(map[string]interface{}); ok {
+		key := name
+		if c.foldKeys {
+			key = foldKey(name, properties)
+		}
+		if value, ok := properties[key]; ok {
+			return c.alternatives(value, 0), true
+		}
+	}
+	if patterns, ok := schema["patternProperties"].(map[string]interface{}); ok {
+		for pattern, value := range patterns {
+			if c.foldKeys {
+				pattern = "(?i)" + pattern
+			}
+			if re, err := regexp.Compile(pattern); err == nil && re.MatchString(name) {
+				return c.alternatives(value, 0), true
+			}
+		}
+	}
+	additional, ok := schema["additionalProperties"]
+	if !ok {
+		_, listed := schema["properties"]
+		_, patterns := schema["patternProperties"]
+		return nil, !listed && !patterns
+	}
+	if allowed, ok := additional.(bool); ok {
+		return nil, allowed
+	}
+	return c.alternatives(additional, 0), true
+}
+
+// values returns the shape of the values of an object of the given shape,
+// and whether a value of the shape may be an object.
+func (c *schemaChecker) values(current schemaShape) (schemaShape, bool) {
+	if current == nil {
+		return nil, true
+	}
+	var result schemaShape
+	objects := false
+	for _, schema := range current {
+		if !allowsType(schema, "object") {
+			continue
+		}
+		objects = true
+		var values []interface{}
+		for _, keyword := range []string{"properties", "patternProperties"} {
+			if properties, ok := schema[keyword].(map[string]interface{}); ok {
+				for _, value := range properties {
+					values = append(values, value)
+				}
+			}
+		}
+		if additional, ok := schema["additionalProperties"]; ok {
+			if additional != false {
+				values = append(values, additional)
+			}
+		} else if values == nil {
+			return nil, true
+		}
+		for _, value := range values {
+			shape := c.alternatives(value, 0)
+			if shape == nil {
+				return nil, true
+			}
+			result = append(result, shape...)
+		}
+	}
+	if len(result) == 0 {
+		result = nil
+	}
+	return result, objects
+}
+
+// items returns the shape of the elements of an array of the given shape,
+// and whether a value of the shape may be an array.  Strings are their own
+// elements if sliced is set, as sliced strings are not projected.
+func (c *schemaChecker) items(current schemaShape, sliced bool) (schemaShape, bool) {
+	if current == nil {
+		return nil, true
+	}
+	var result schemaShape
+	arrays := false
+	for _, schema := range current {
+		if sliced && allowsType(schema, "string") {
+			arrays = true
+			result = append(result, typedShape("string")...)
+		}
+		if !allowsType(schema, "array") {
+			continue
+		}
+		arrays = true
+		var items []interface{}
+		if prefix, ok := schema["prefixItems"].([]interface{}); ok {
+			items = append(items, prefix...)
+		}
+		switch value := schema["items"].(type) {
+		case nil:
+			if items == nil {
+				return nil, true
+			}
+		case []interface{}:
+			items = append(items, value...)
+		default:
+			items = append(items, value)
+		}
+		for _, item := range items {
+			shape := c.alternatives(item, 0)
+			if shape == nil {
+				return nil, true
+			}
+			result = append(result, shape...)
+		}
+	}
+	if len(result) == 0 {
+		result = nil
+	}
+	return result, arrays
+}
+
+// flatten returns the shape of the values of the flattened array of
+// elements of the given shape.
+func (c *schemaChecker) flatten(elements schemaShape) schemaShape {
+	var result schemaShape
+	for _, schema := range elements {
+		if !allowsType(schema, "array") {
+			result = append(result, schema)
+			continue
+		}
+		items, _ := c.items(schemaShape{schema}, false)
+		if items == nil {
+			return nil
+		}
+		result = append(result, items...)
+		if len(schemaTypes(schema)) > 1 {
+			result = append(result, schema)
+		}
+	}
+	return result
+}
+
+// index returns the shape of the value of the index or slice node
+// applied to a value of the given shape, reporting the issue at the
+// expression at fault otherwise.
+func (c *schemaChecker) index(at ASTNode, node ASTNode, offset int, current schemaShape) schemaShape {
+	if node.nodeType == ASTSlice {
+		_, ok := c.items(current, true)
+		if !ok {
+			c.report(at, offset, "expected array or string, got "+describeShape(current))
+			return nil
+		}
+		return current
+	}
+	items, ok := c.items(current, false)
+	if !ok {
+		c.report(at, offset, "expected array, got "+describeShape(current))
+	}
+	return items
+}
+
+// alternatives returns the schemas a value matching schema matches one of,
+// with references followed and anyOf, oneOf and allOf flattened, or nil if
+// the value may be anything.  Flattening allOf into alternatives is lenient:
+// a field is allowed if any of the schemas allows it.
+func (c *schemaChecker) alternatives(schema interface{}, depth int) schemaShape {
+	if shape, ok := schema.(schemaShape); ok {
+		return shape
+	}
+	node, ok := schema.(map[string]interface{})
+	if !ok || depth > maxSchemaDepth {
+		return nil
+	}
+	if ref, ok := node["$ref"].(string); ok {
+		resolved, ok := c.resolve(ref)
+		if !ok {
+			return nil
+		}
+		return c.alternatives(resolved, depth+1)
+	}
+	var result schemaShape
+	for _, keyword := range []string{"anyOf", "oneOf", "allOf"} {
+		list, ok := node[keyword].([]interface{})
+		if !ok {
+			continue
+		}
+		for _, alternative := range list {
+			shape := c.alternatives(alternative, depth+1)
+			if shape == nil {
+				if keyword == "allOf" {
+					continue
+				}
+				return nil
+			}
+			result = append(result, shape...)
+		}
+	}
+	if len(schemaTypes(node)) > 0 {
+		result = append(result, node)
+	}
+	return result
+}
+
+// resolve returns the schema a "#/..." JSON pointer reference points to in
+// the checked schema.  References to other documents are not resolved.
+func (c *schemaChecker) resolve(ref string) (interface{}, bool) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, false
+	}
+	current := c.schema.root
+	for _, token := range strings.Split(strings.TrimPrefix(ref, "#"), "/")[1:] {
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		switch value := current.(type) {
+		case map[string]interface{}:
+			next, ok := value[token]
+			if !ok {
+				return nil, false
+			}
+			current = next
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(value) {
+				return nil, false
+			}
+			current = value[i]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// schemaTypes returns the JSON types the schema allows, with integer
+// counted as number, or nil if it allows any type.  Schemas without a type
+// keyword have the types of their enum or const values, or the type
+// implied by their keywords.
+func schemaTypes(schema map[string]interface{}) []string {
+	var types []string
+	switch value := schema["type"].(type) {
+	case string:
+		types = []string{value}
+	case []interface{}:
+		for _, t := range value {
+			if name, ok := t.(string); ok {
+				types = append(types, name)
+			}
+		}
+	default:
+		if value, ok := schema["const"]; ok {
+			return []string{jsonType(value)}
+		}
+		if values, ok := schema["enum"].([]interface{}); ok {
+			for _, value := range values {
+				types = append(types, jsonType(value))
+			}
+			return types
+		}
+		for _, keyword := range []string{"properties", "patternProperties", "additionalProperties"} {
+			if _, ok := schema[keyword]; ok {
+				return []string{"object"}
+			}
+		}
+		for _, keyword := range []string{"items", "prefixItems"} {
+			if _, ok := schema[keyword]; ok {
+				return []string{"array"}
+			}
+		}
+	}
+	for i, t := range types {
+		if t == "integer" {
+			types[i] = "number"
+		}
+	}
+	return types
+}
+
+func allowsType(schema map[string]interface{}, name string) bool {
+	for _, t := range schemaTypes(schema) {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
+
+// describeShape returns the types a value of the given shape may have,
+// such as "string or null".
+func describeShape(shape schemaShape) string {
+	seen := make(map[string]bool)
+	var types []string
+	for _, schema := range shape {
+		for _, t := range schemaTypes(schema) {
+			if !seen[t] {
+				seen[t] = true
+				types = append(types, t)
+			}
+		}
+	}
+	sort.Strings(types)
+	return strings.Join(types, " or ")
+}
+
+func typedShape(name string) schemaShape {
+	return schemaShape{{"type": name}}
+}
+
+// arrayShape returns the shape of an array of elements of the given shape.
+func arrayShape(elements schemaShape) schemaShape {
+	return schemaShape{{"type": "array", "items": elements}}
+}
+
+// suggestProperty returns the property of the object schemas closest to
+// name, if it is close enough to be a misspelling of it.
+func suggestProperty(name string, objects []map[string]interface{}) string {
+	var candidates []string
+	for _, schema := range objects {
+		if properties, ok := schema["properties"].(map[string]interface{}); ok {
+			for property := range properties {
+				candidates = append(candidates, property)
+			}
+		}
+	}
+	sort.Strings(candidates)
+	best, bestDistance := "", len([]rune(name))/3+1
+	if bestDistance > 3 {
+		bestDistance = 3
+	}
+	for _, candidate := range candidates {
+		if distance := editDistance(name, candidate); distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+	return best
+}
+
+// editDistance returns the number of insertions, deletions, substitutions
+// and transpositions of adjacent code points needed to turn a into b.
+func editDistance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	d := make([][]int, len(s)+1)
+	for i := range d {
+		d[i] = make([]int, len(t)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(min(d[i-1][j]+1, d[i][j-1]+1), d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(s)][len(t)]
+}
@@ -0,0 +1,168 @@
+package jmespath
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+const testSchema = `{
+	"type": "object",
+	"properties": {
+		"instance": {"$ref": "#/$defs/instance"},
+		"instances": {"type": "array", "items": {"$ref": "#/$defs/instance"}},
+		"tags": {"type": "object", "additionalProperties": {"type": "string"}},
+		"labels": {"type": "object"},
+		"count": {"type": "integer"},
+		"owner": {"anyOf": [{"type": "string"}, {"properties": {"name": {"type": "string"}}}]},
+		"nullable": {"type": ["string", "null"]},
+		"config": {
+			"patternProperties": {"^x-": {"type": "number"}},
+			"properties": {"mode": {"enum": ["a", "b"]}}
+		},
+		"pair": {"type": "array", "prefixItems": [{"type": "string"}, {"type": "number"}]},
+		"tree": {"$ref": "#/$defs/tree"}
+	},
+	"$defs": {
+		"instance": {
+			"type": "object",
+			"properties": {
+				"state": {"type": "string"},
+				"id": {"type": "string"},
+				"ports": {"type": "array", "items": {"type": "integer"}}
+			}
+		},
+		"tree": {
+			"type": "object",
+			"properties": {"value": {"type": "string"}, "child": {"$ref": "#/$defs/tree"}}
+		}
+	}
+}`
+
+func TestCompileWithSchema(t *testing.T) {
+	assert := assert.New(t)
+	schema, err := NewSchema([]byte(testSchema))
+	assert.Nil(err)
+	for _, expression := range []string{
+		"instance.state",
+		"instances[*].state",
+		"instances[?state == 'running'].id",
+		"instances[].ports[]",
+		"instances[0].ports[0]",
+		"tags.anything",
+		"labels.anything.deeper[0]",
+		"count > `1`",
+		"owner.name",
+		"owner[0:2]",
+		"config.\"x-rate\"",
+		"config.mode",
+		"{s: instance.state}.s",
+		"instance.state[0:3]",
+		"pair[0]",
+		"instance | state",
+		"length(instance.state)",
+		"sort_by(instances, &id)[0].state",
+		"max(instance.ports)",
+		"tree.child.child.value",
+		"length(@).anything",
+	} {
+		jp, err := CompileWithSchema(expression, schema)
+		assert.Nil(err, expression)
+		assert.NotNil(jp, expression)
+	}
+}
+
+func TestCompileWithSchemaIssues(t *testing.T) {
+	assert := assert.New(t)
+	schema, err := NewSchema([]byte(testSchema))
+	assert.Nil(err)
+	cases := []struct {
+		expression    string
+		subExpression string
+		offset        int
+		message       string
+	}{
+		{"instance.stat", "stat", 9, `unknown property "stat", did you mean "state"?`},
+		{"instances[*].stat", "stat", 13, `unknown property "stat", did you mean "state"?`},
+		{"instances[?stat == 'x'].id", "stat", 11, `unknown property "stat", did you mean "state"?`},
+		{"sort_by(instances, &stat)", "stat", 20, `unknown property "stat", did you mean "state"?`},
+		{"instance.\"stat\"", "stat", 9, `unknown property "stat", did you mean "state"?`},
+		{"owner.nmae", "nmae", 6, `unknown property "nmae", did you mean "name"?`},
+		{"config.rate", "rate", 7, `unknown property "rate"`},
+		{"{s: instance.state}.t", "t", 20, `unknown property "t"`},
+		{"tree.child.child.valeu", "valeu", 17, `unknown property "valeu", did you mean "value"?`},
+		{"instance.state.length", "length", 15, "expected object, got string"},
+		{"nullable.x", "x", 9, "expected object, got null or string"},
+		{"count[0]", "count[0]", 0, "expected array, got number"},
+		{"count[1:]", "count[1:]", 0, "expected array or string, got number"},
+		{"instance[*].id", "instance[*].id", 0, "expected array, got object"},
+		{"instance.id[]", "instance.id[]", 9, "expected array, got string"},
+		{"instances.*", "instances.*", 0, "expected object, got array"},
+		{"abs(instance.state)", "abs(instance.state)", 0, "invalid type for argument 1: expected number, got string"},
+	}
+	for _, tt := range cases {
+		_, err := CompileWithSchema(tt.expression, schema)
+		var schemaErr *SchemaError
+		if !assert.True(errors.As(err, &schemaErr), tt.expression) {
+			continue
+		}
+		if assert.Len(schemaErr.Issues, 1, tt.expression) {
+			issue := schemaErr.Issues[0]
+			assert.Equal(tt.expression, issue.Expression)
+			assert.Equal(tt.subExpression, issue.SubExpression, tt.expression)
+			assert.Equal(tt.offset, issue.Offset, tt.expression)
+			assert.Equal(tt.message, issue.Message, tt.expression)
+		}
+	}
+}
+
+func TestSchemaErrorListsIssues(t *testing.T) {
+	assert := assert.New(t)
+	schema, err := NewSchema([]byte(testSchema))
+	assert.Nil(err)
+	_, err = CompileWithSchema("[count.x, instance.stat.deeper]", schema)
+	var schemaErr *SchemaError
+	if assert.True(errors.As(err, &schemaErr)) {
+		assert.Len(schemaErr.Issues, 2)
+		assert.Equal(`x at offset 7: expected object, got number; stat at offset 19: unknown property "stat", did you mean "state"?`, err.Error())
+		assert.Equal("[count.x, instance.stat.deeper]\n                   ^", schemaErr.Issues[1].HighlightLocation())
+	}
+}
+
+func TestCompileWithSchemaRuntime(t *testing.T) {
+	assert := assert.New(t)
+	schema, err := NewSchema([]byte(testSchema))
+	assert.Nil(err)
+	rt := NewRuntime()
+	assert.Nil(rt.RegisterFunction("shout", func(ctx CallContext, args []interface{}) (interface{}, error) {
+		return args[0], nil
+	}))
+	_, err = rt.CompileWithSchema("shout(count)", schema)
+	assert.Nil(err)
+	_, err = rt.CompileWithSchema("instance..state", schema)
+	assert.NotNil(err)
+	rt.SetRecursiveDescent(true)
+	jp, err := rt.CompileWithSchema("instance..state", schema)
+	assert.Nil(err)
+	result, err := jp.Search(decodeJSON(`{"instance": {"state": "running"}}`))
+	assert.Nil(err)
+	assert.Equal([]interface{}{"running"}, result)
+
+	anything, err := NewSchema([]byte("true"))
+	assert.Nil(err)
+	_, err = CompileWithSchema("a.b[0].c | length(@)", anything)
+	assert.Nil(err)
+	recursive, err := NewSchema([]byte(`{"$ref": "#"}`))
+	assert.Nil(err)
+	_, err = CompileWithSchema("a.b", recursive)
+	assert.Nil(err)
+}
+
+func TestNewSchemaErrors(t *testing.T) {
+	assert := assert.New(t)
+	_, err := NewSchema([]byte("{"))
+	assert.NotNil(err)
+	_, err = NewSchema([]byte("[]"))
+	assert.NotNil(err)
+}
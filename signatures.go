@@ -0,0 +1,116 @@
+package jmespath
+
+import (
+	"sort"
+	"strings"
+)
+
+// FunctionSignature describes a function that expressions can call, for
+// documentation generators and validators.
+type FunctionSignature struct {
+	Name string
+	// Arguments are the arguments the function is declared with.  It is
+	// nil for functions registered with RegisterFunction, which check
+	// their own arguments.
+	Arguments []ArgumentSignature
+	// Registered is set for functions registered with RegisterFunction.
+	Registered bool
+}
+
+// ArgumentSignature describes an argument of a function.
+type ArgumentSignature struct {
+	// Types are the names of the types the argument accepts: "number",
+	// "string", "array", "object", "array[number]", "array[string]",
+	// "expref" for expression references, or "any".
+	Types []string
+	// Variadic is set on the last argument of functions that accept it
+	// any number of times, and at least once.
+	Variadic bool
+	// Optional is set on trailing arguments that may be omitted.
+	Optional bool
+}
+
+// MinArgs returns the least number of arguments the function accepts.
+func (s FunctionSignature) MinArgs() int {
+	n := 0
+	for _, arg := range s.Arguments {
+		if !arg.Optional {
+			n++
+		}
+	}
+	return n
+}
+
+// MaxArgs returns the greatest number of arguments the function accepts,
+// or -1 if it accepts any number of them, as variadic and registered
+// functions do.
+func (s FunctionSignature) MaxArgs() int {
+	if s.Registered || len(s.Arguments) > 0 && s.Arguments[len(s.Arguments)-1].Variadic {
+		return -1
+	}
+	return len(s.Arguments)
+}
+
+// String returns the signature in the form used by the JMESPath
+// specification, such as "sort_by(array, expref)", "not_null(any...)" or
+// "join(string, [array[string]])" for an optional argument.  Registered
+// functions have the signature "name(...)".
+func (s FunctionSignature) String() string {
+	if s.Registered {
+		return s.Name + "(...)"
+	}
+	args := make([]string, len(s.Arguments))
+	for i, arg := range s.Arguments {
+		args[i] = strings.Join(arg.Types, "|")
+		if arg.Variadic {
+			args[i] += "..."
+		}
+		if arg.Optional {
+			args[i] = "[" + args[i] + "]"
+		}
+	}
+	return s.Name + "(" + strings.Join(args, ", ") + ")"
+}
+
+// Functions returns the signatures of the built-in functions, sorted by
+// name.
+func Functions() []FunctionSignature {
+	return newFunctionCaller().signatures()
+}
+
+// Functions returns the signatures of the functions available in this
+// runtime, the built-in functions and those registered with
+// RegisterFunction, sorted by name.
+func (rt *Runtime) Functions() []FunctionSignature {
+	rt.mu.Lock()
+	caller := rt.fCall
+	rt.mu.Unlock()
+	return caller.signatures()
+}
+
+func (f *functionCaller) signatures() []FunctionSignature {
+	signatures := make([]FunctionSignature, 0, len(f.functionTable))
+	for name, entry := range f.functionTable {
+		signatures = append(signatures, entry.signature(name))
+	}
+	sort.Slice(signatures, func(i, j int) bool {
+		return signatures[i].Name < signatures[j].Name
+	})
+	return signatures
+}
+
+// signature returns the signature of the entry for the function name.
+func (e functionEntry) signature(name string) FunctionSignature {
+	if e.custom != nil {
+		return FunctionSignature{Name: name, Registered: true}
+	}
+	signature := FunctionSignature{Name: name, Arguments: make([]ArgumentSignature, len(e.arguments))}
+	for i, spec := range e.arguments {
+		types := make([]string, len(spec.types))
+		for j, t := range spec.types {
+			types[j] = string(t)
+		}
+		signature.Arguments[i] = ArgumentSignature{Types: types, Variadic: spec.variadic, Optional: spec.optional}
+	}
+	return signature
+}
@@ -0,0 +1,67 @@
+package jmespath
+
+import (
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func TestFunctions(t *testing.T) {
+	assert := assert.New(t)
+	signatures := Functions()
+	byName := map[string]FunctionSignature{}
+	for i, signature := range signatures {
+		if i > 0 {
+			assert.True(signatures[i-1].Name < signature.Name, signature.Name)
+		}
+		assert.False(signature.Registered, signature.Name)
+		byName[signature.Name] = signature
+	}
+	assert.Equal(len(newFunctionCaller().functionTable), len(signatures))
+
+	sortBy := byName["sort_by"]
+	assert.Equal([]ArgumentSignature{
+		{Types: []string{"array"}},
+		{Types: []string{"expref"}},
+		{Types: []string{"string"}, Optional: true},
+	}, sortBy.Arguments)
+	assert.Equal("sort_by(array, expref, [string])", sortBy.String())
+	assert.Equal(2, sortBy.MinArgs())
+	assert.Equal(3, sortBy.MaxArgs())
+
+	assert.Equal("map(expref, array)", byName["map"].String())
+	assert.Equal("length(string|array|object)", byName["length"].String())
+
+	notNull := byName["not_null"]
+	assert.Equal("not_null(any...)", notNull.String())
+	assert.Equal(1, notNull.MinArgs())
+	assert.Equal(-1, notNull.MaxArgs())
+
+	ifSignature := byName["if"]
+	assert.Equal("if(any, any, [any])", ifSignature.String())
+	assert.Equal(2, ifSignature.MinArgs())
+	assert.Equal(3, ifSignature.MaxArgs())
+}
+
+func TestRuntimeFunctions(t *testing.T) {
+	assert := assert.New(t)
+	rt := NewRuntime()
+	before := rt.Functions()
+	assert.Equal(Functions(), before)
+	assert.Nil(rt.RegisterFunction("answer", func(ctx CallContext, args []interface{}) (interface{}, error) {
+		return 42.0, nil
+	}))
+	after := rt.Functions()
+	assert.Equal(len(before)+1, len(after))
+	var answer FunctionSignature
+	for _, signature := range after {
+		if signature.Name == "answer" {
+			answer = signature
+		}
+	}
+	assert.Equal(FunctionSignature{Name: "answer", Registered: true}, answer)
+	assert.Equal("answer(...)", answer.String())
+	assert.Equal(0, answer.MinArgs())
+	assert.Equal(-1, answer.MaxArgs())
+	assert.Equal(len(before), len(Functions()))
+}
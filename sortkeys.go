@@ -0,0 +1,147 @@
+package jmespath
+
+import (
+	"fmt"
+	"sort"
+)
+
+// InvalidSortKeyMode controls how sort_by, max_by and min_by handle
+// elements whose key, the value of their expression reference, cannot be
+// compared with the keys of the other elements, see
+// Runtime.SetInvalidSortKeys.  Keys are comparable if they are all numbers
+// or all strings.
+type InvalidSortKeyMode int
+
+const (
+	// InvalidSortKeysError makes an element with an invalid key an
+	// error, which gives the index of the first such element.  This is
+	// the default, as the JMESPath specification requires.
+	InvalidSortKeysError InvalidSortKeyMode = iota
+	// InvalidSortKeysSkip leaves elements with invalid keys out: sort_by
+	// drops them from its result and max_by and min_by ignore them.
+	InvalidSortKeysSkip
+	// InvalidSortKeysLast sorts elements with invalid keys after all
+	// the others, in their original order, as if their keys were null.
+	// max_by and min_by ignore them.
+	InvalidSortKeysLast
+)
+
+// SetInvalidSortKeys sets how sort_by, max_by and min_by handle elements
+// whose keys are not comparable with the others in expressions compiled
+// after the call.  The type of the keys is that of the first element's
+// key, or with a mode other than InvalidSortKeysError, of the first key
+// that is a number or a string.
+func (rt *Runtime) SetInvalidSortKeys(mode InvalidSortKeyMode) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.invalidSortKeys = mode
+}
+
+// sortKey is the key of an element of the array sorted by sort_by or
+// searched by max_by and min_by.
+type sortKey struct {
+	index int
+	key   interface{}
+}
+
+// sortKeys evaluates node against each element of items and returns the
+// keys that are comparable, in element order, along with whether they are
+// strings, and the indexes of the elements whose keys are not.  It
+// returns an error for the first invalid key unless invalid keys are
+// skipped or sorted last.
+func (intr *treeInterpreter) sortKeys(node ASTNode, items []interface{}) (keys []sortKey, byString bool, invalid []int, err error) {
+	keys = make([]sortKey, 0, len(items))
+	typ := ""
+	first := -1
+	for i, item := range items {
+		key, err := intr.Execute(node, item)
+		if err != nil {
+			return nil, false, nil, err
+		}
+		keyType := jsonType(key)
+		if keyType != "number" && keyType != "string" {
+			keyType = ""
+		}
+		if typ == "" && keyType != "" {
+			typ, first = keyType, i
+		}
+		if keyType == "" || keyType != typ {
+			if intr.invalidSortKeys == InvalidSortKeysError {
+				return nil, false, nil, invalidSortKey(i, key, typ, first)
+			}
+			invalid = append(invalid, i)
+			continue
+		}
+		keys = append(keys, sortKey{index: i, key: key})
+	}
+	return keys, typ == "string", invalid, nil
+}
+
+func invalidSortKey(index int, key interface{}, typ string, first int) error {
+	expected := "number or string"
+	if typ != "" {
+		expected = fmt.Sprintf("%s, as for element %d", typ, first)
+	}
+	return &EvalError{
+		ValueType: jsonType(key),
+		Err:       fmt.Errorf("invalid type for key of element %d: expected %s, got %s", index, expected, jsonType(key)),
+	}
+}
+
+// compareSortKeys compares two keys of the same type, strings with
+// collation if it is not nil.
+func compareSortKeys(a, b interface{}, byString bool, collation Collation) int {
+	if !byString {
+		comparison, _ := compareNumbers(a, b)
+		return comparison
+	}
+	if collation != nil {
+		return collation(a.(string), b.(string))
+	}
+	switch x, y := a.(string), b.(string); {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+// sortBy returns items sorted by the keys node evaluates to, for sort_by.
+// The sort is stable.
+func (intr *treeInterpreter) sortBy(node ASTNode, items []interface{}, collation Collation) ([]interface{}, error) {
+	keys, byString, invalid, err := intr.sortKeys(node, items)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		return compareSortKeys(keys[i].key, keys[j].key, byString, collation) < 0
+	})
+	sorted := make([]interface{}, 0, len(items))
+	for _, key := range keys {
+		sorted = append(sorted, items[key.index])
+	}
+	if intr.invalidSortKeys == InvalidSortKeysLast {
+		for _, i := range invalid {
+			sorted = append(sorted, items[i])
+		}
+	}
+	return sorted, nil
+}
+
+// bestBy returns the first element of items whose key is the greatest if
+// sign is 1, or the least if sign is -1, for max_by and min_by.  It
+// returns nil if no element has a comparable key.
+func (intr *treeInterpreter) bestBy(node ASTNode, items []interface{}, sign int) (interface{}, error) {
+	keys, byString, _, err := intr.sortKeys(node, items)
+	if err != nil || len(keys) == 0 {
+		return nil, err
+	}
+	best := keys[0]
+	for _, key := range keys[1:] {
+		if compareSortKeys(key.key, best.key, byString, nil)*sign > 0 {
+			best = key
+		}
+	}
+	return items[best.index], nil
+}
@@ -0,0 +1,76 @@
+package jmespath
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+var sortKeyItems = []interface{}{
+	map[string]interface{}{"name": "c", "age": 30.0},
+	map[string]interface{}{"name": "a", "age": 20.0},
+	map[string]interface{}{"name": "d"},
+	map[string]interface{}{"name": "b", "age": "40"},
+	map[string]interface{}{"name": "e", "age": 20.0},
+}
+
+func TestInvalidSortKeysError(t *testing.T) {
+	assert := assert.New(t)
+	data := map[string]interface{}{"items": sortKeyItems}
+	tests := []struct {
+		expression string
+		message    string
+		valueType  string
+	}{
+		{"sort_by(items, &age)", "invalid type for key of element 2: expected number, as for element 0, got null", "null"},
+		{"max_by(items, &age)", "invalid type for key of element 2: expected number, as for element 0, got null", "null"},
+		{"min_by(items[3:], &age)", "invalid type for key of element 1: expected string, as for element 0, got number", "number"},
+		{"sort_by(items[2:], &age)", "invalid type for key of element 0: expected number or string, got null", "null"},
+		{"max_by(items[2:3], &age)", "invalid type for key of element 0: expected number or string, got null", "null"},
+	}
+	for _, test := range tests {
+		_, err := Search(test.expression, data)
+		var evalErr *EvalError
+		if assert.True(errors.As(err, &evalErr), test.expression) {
+			assert.Equal(test.message, evalErr.Err.Error(), test.expression)
+			assert.Equal(test.valueType, evalErr.ValueType, test.expression)
+			assert.Equal(test.expression[:6], evalErr.Function[:6], test.expression)
+		}
+	}
+}
+
+func TestInvalidSortKeysModes(t *testing.T) {
+	assert := assert.New(t)
+	data := map[string]interface{}{"items": sortKeyItems}
+	tests := []struct {
+		mode       InvalidSortKeyMode
+		expression string
+		expected   interface{}
+	}{
+		{InvalidSortKeysSkip, "sort_by(items, &age)[*].name", []interface{}{"a", "e", "c"}},
+		{InvalidSortKeysLast, "sort_by(items, &age)[*].name", []interface{}{"a", "e", "c", "d", "b"}},
+		{InvalidSortKeysSkip, "max_by(items, &age).name", "c"},
+		{InvalidSortKeysLast, "min_by(items, &age).name", "a"},
+		{InvalidSortKeysSkip, "sort_by(items[2:], &age)[*].name", []interface{}{"b"}},
+		{InvalidSortKeysLast, "sort_by(items[2:], &age)[*].name", []interface{}{"b", "d", "e"}},
+		{InvalidSortKeysSkip, "max_by(items[2:3], &age)", nil},
+		{InvalidSortKeysSkip, "sort_by(items, &missing)", []interface{}{}},
+	}
+	for _, test := range tests {
+		rt := NewRuntime()
+		rt.SetInvalidSortKeys(test.mode)
+		result, err := rt.Search(test.expression, data)
+		assert.Nil(err, test.expression)
+		assert.Equal(test.expected, result, test.expression)
+	}
+}
+
+func TestSortByDoesNotModifyItsArgument(t *testing.T) {
+	assert := assert.New(t)
+	items := []interface{}{3.0, 1.0, 2.0}
+	result, err := Search("sort_by(@, &@)", items)
+	assert.Nil(err)
+	assert.Equal([]interface{}{1.0, 2.0, 3.0}, result)
+	assert.Equal([]interface{}{3.0, 1.0, 2.0}, items)
+}
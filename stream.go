@@ -0,0 +1,159 @@
+package jmespath
+
+import "context"
+
+// SearchChan evaluates the expression against data and sends the elements
+// of the result on the returned value channel as they are produced, so
+// that a consumer can start on the first results of a large projection or
+// filter before the rest are evaluated.  If the expression is a
+// projection, such as "items[?size > `10`].name" or "*.id", its elements
+// are sent one at a time without building the result array; otherwise
+// its result is sent as a single value.  Null elements of projections are
+// only sent by runtimes that keep them, see Runtime.SetKeepNulls, and
+// null results of other expressions are never sent.
+//
+// The value channel is closed when the evaluation finishes.  The error
+// channel then receives the error that stopped it, if any, and is closed
+// as well.  Cancelling ctx stops the evaluation with ctx.Err(); a consumer
+// that stops receiving values must cancel ctx so that the evaluation does
+// not block forever.
+func (jp *JMESPath) SearchChan(ctx context.Context, data interface{}) (<-chan interface{}, <-chan error) {
+	values := make(chan interface{})
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		err := jp.stream(ctx, data, func(value interface{}) error {
+			select {
+			case values <- jp.intr.render(value):
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		close(values)
+		if err != nil {
+			errs <- err
+		}
+	}()
+	return values, errs
+}
+
+// SearchChan compiles a JMESPath expression and streams the elements of
+// its result for data, see JMESPath.SearchChan.
+func SearchChan(ctx context.Context, expression string, data interface{}) (<-chan interface{}, <-chan error) {
+	jp, err := Compile(expression)
+	if err != nil {
+		values := make(chan interface{})
+		errs := make(chan error, 1)
+		close(values)
+		errs <- err
+		close(errs)
+		return values, errs
+	}
+	return jp.SearchChan(ctx, data)
+}
+
+// stream evaluates the expression against data and calls send with each
+// element of the result.
+func (jp *JMESPath) stream(ctx context.Context, data interface{}, send func(value interface{}) error) (err error) {
+	defer recoverInternal(&err)
+	defer func() {
+		err = locateError(jp.expression, err)
+	}()
+	intr, node := jp.intr, jp.ast
+	switch node.nodeType {
+	case ASTProjection, ASTFilterProjection, ASTValueProjection:
+	default:
+		result, err := jp.eval(intr, data)
+		if err != nil || result == nil {
+			return err
+		}
+		return send(result)
+	}
+	left, err := jp.streamEval(node.children[0])(intr, data)
+	if err != nil {
+		if node.nodeType == ASTProjection {
+			return err
+		}
+		return intr.leftError(err)
+	}
+	if s, ok := slicedString(node, left); node.nodeType == ASTProjection && ok {
+		result, err := jp.streamEval(node.children[1])(intr, s)
+		if err != nil || result == nil {
+			return err
+		}
+		return send(result)
+	}
+	var elements []interface{}
+	// keys are the keys of the elements of an object projection.
+	var keys []string
+	if node.nodeType == ASTValueProjection {
+		object, ok := intr.toObject(left)
+		if !ok {
+			if intr.strict {
+				return newStrictError(node, ErrNotObject, left)
+			}
+			return nil
+		}
+		keys = objectKeys(left, object)
+		for _, key := range keys {
+			elements = append(elements, object[key])
+		}
+	} else if array, ok := left.([]interface{}); ok {
+		elements = array
+	} else if isSliceType(left) {
+		elements = toInterfaceSlice(left)
+	} else if intr.strict {
+		return newStrictError(node, ErrNotArray, left)
+	} else {
+		return nil
+	}
+	project := jp.streamEval(node.children[1])
+	var filter evalFunc
+	if node.nodeType == ASTFilterProjection {
+		filter = jp.streamEval(node.children[2])
+	}
+	capture := keys != nil && callsKey(node.children[1])
+	done := ctx.Done()
+	for i, element := range elements {
+		select {
+		case <-done:
+			return ctx.Err()
+		default:
+		}
+		if filter != nil {
+			matched, err := filter(intr, element)
+			if err != nil {
+				return err
+			}
+			if isFalse(matched) {
+				continue
+			}
+		}
+		each := intr
+		if capture {
+			each = intr.withKey(keys[i])
+		}
+		current, err := project(each, element)
+		if err != nil {
+			return err
+		}
+		if intr.keeps(current) {
+			if err := send(current); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// streamEval returns the function that evaluates node, a part of the
+// expression, the same way the expression is evaluated.
+func (jp *JMESPath) streamEval(node ASTNode) evalFunc {
+	if jp.intr.interpreted() {
+		return func(intr *treeInterpreter, value interface{}) (interface{}, error) {
+			return intr.Execute(node, value)
+		}
+	}
+	return compileNode(node)
+}
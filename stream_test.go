@@ -0,0 +1,91 @@
+package jmespath
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func collectChan(values <-chan interface{}, errs <-chan error) ([]interface{}, error) {
+	collected := []interface{}{}
+	for value := range values {
+		collected = append(collected, value)
+	}
+	return collected, <-errs
+}
+
+func TestSearchChan(t *testing.T) {
+	assert := assert.New(t)
+	data := decodeJSON(`{"items": [{"n": 1}, {"n": 5}, {"x": 2}, {"n": 7}], "obj": {"b": 2, "a": 1}, "id": "ord-1"}`)
+	cases := []struct {
+		expression string
+		expected   []interface{}
+	}{
+		{"items[?n > `2`].n", []interface{}{5.0, 7.0}},
+		{"items[*].n", []interface{}{1.0, 5.0, 7.0}},
+		{"items[].n", []interface{}{1.0, 5.0, 7.0}},
+		{"obj.*", []interface{}{1.0, 2.0}},
+		{"length(items)", []interface{}{4.0}},
+		{"missing[*]", []interface{}{}},
+		{"missing", []interface{}{}},
+		{"id[0:3]", []interface{}{"ord"}},
+		{"items[0:2].n", []interface{}{1.0, 5.0}},
+	}
+	for _, tt := range cases {
+		result, err := collectChan(SearchChan(context.Background(), tt.expression, data))
+		assert.Nil(err, tt.expression)
+		assert.Equal(tt.expected, result, tt.expression)
+	}
+}
+
+func TestSearchChanKeepNulls(t *testing.T) {
+	assert := assert.New(t)
+	rt := NewRuntime()
+	rt.SetKeepNulls(true)
+	data := decodeJSON(`{"items": [{"n": 1}, {"x": 2}, {"n": 3}]}`)
+	for expression, expected := range map[string][]interface{}{
+		"items[*].n":         {1.0, nil, 3.0},
+		"items[?n != `1`].n": {nil, 3.0},
+		"missing":            {},
+	} {
+		jp, err := rt.Compile(expression)
+		assert.Nil(err)
+		result, err := collectChan(jp.SearchChan(context.Background(), data))
+		assert.Nil(err, expression)
+		assert.Equal(expected, result, expression)
+	}
+}
+
+func TestSearchChanErrors(t *testing.T) {
+	assert := assert.New(t)
+	result, err := collectChan(SearchChan(context.Background(), "foo[", nil))
+	assert.Empty(result)
+	assert.IsType(SyntaxError{}, err)
+
+	data := decodeJSON(`{"items": [1, "x", 3]}`)
+	result, err = collectChan(SearchChan(context.Background(), "items[*].abs(@)", data))
+	assert.Equal([]interface{}{1.0}, result)
+	assert.NotNil(err)
+
+	rt := NewRuntime()
+	rt.SetStrict(true)
+	jp, _ := rt.Compile("items[*]")
+	_, err = collectChan(jp.SearchChan(context.Background(), map[string]interface{}{"items": "x"}))
+	assert.NotNil(err)
+}
+
+func TestSearchChanCancel(t *testing.T) {
+	assert := assert.New(t)
+	items := make([]interface{}, 1000)
+	for i := range items {
+		items[i] = float64(i)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	values, errs := SearchChan(ctx, "[*]", items)
+	assert.Equal(0.0, <-values)
+	cancel()
+	for range values {
+	}
+	assert.Equal(context.Canceled, <-errs)
+}
@@ -0,0 +1,127 @@
+package jmespath
+
+import "errors"
+
+/* In strict mode the interpreter reports the reads that otherwise quietly
+   evaluate to null.  Strict expressions are evaluated by the tree
+   interpreter without optimizing them, see newInterpretedJMESPath, as the
+   compiled closures and the constant folding of the optimizer implement
+   the permissive behavior, so that strict mode costs nothing when it is
+   not used.
+*/
+
+// Errors wrapped by a StrictError.
+var (
+	// ErrMissingKey is reported for a field that is not in an object.
+	ErrMissingKey = errors.New("missing key")
+	// ErrNotObject is reported for a field or object projection of a
+	// value that is not an object.
+	ErrNotObject = errors.New("not an object")
+	// ErrNotArray is reported for an index, slice, flatten or projection
+	// of a value that is not an array.
+	ErrNotArray = errors.New("not an array")
+	// ErrIncomparable is reported for an ordering comparison, such as
+	// "a < b", of values that are not both numbers or both strings.
+	ErrIncomparable = errors.New("cannot be ordered")
+	// ErrOutOfRange is reported for an index or slice bound outside of
+	// an array, if enabled with Runtime.SetStrictBounds.
+	ErrOutOfRange = errors.New("index out of range")
+)
+
+// StrictError is returned by expressions compiled in strict mode, see
+// Runtime.SetStrict, when they read a value that is not there.
+type StrictError struct {
+	SubExpression string // Part of the expression that failed, in canonical form.
+	ValueType     string // JSON type of the offending value, empty for ErrMissingKey and ErrOutOfRange.
+	Err           error  // ErrMissingKey, ErrNotObject, ErrNotArray, ErrIncomparable or ErrOutOfRange.
+}
+
+func (e *StrictError) Error() string {
+	if e.ValueType == "" {
+		return e.SubExpression + ": " + e.Err.Error()
+	}
+	return e.SubExpression + ": " + e.Err.Error() + ", got " + e.ValueType
+}
+
+// Unwrap returns the underlying error.
+func (e *StrictError) Unwrap() error {
+	return e.Err
+}
+
+func newStrictError(node ASTNode, err error, value interface{}) *StrictError {
+	e := &StrictError{SubExpression: FormatAST(node), Err: err}
+	if err != ErrMissingKey && err != ErrOutOfRange {
+		e.ValueType = jsonType(value)
+	}
+	return e
+}
+
+// checkField returns the strict mode error for looking up the field node
+// in value.
+func (intr *treeInterpreter) checkField(node ASTNode, value interface{}) error {
+	key := node.value.(string)
+	var found bool
+	switch m := value.(type) {
+	case map[string]interface{}:
+		_, found = m[key]
+	case map[string]string:
+		_, found = m[key]
+	case *OrderedMap:
+		_, found = m.values[key]
+	default:
+		if object, ok := intr.toObject(value); ok {
+			_, found = object[key]
+			break
+		}
+		var isStruct bool
+		if _, found, isStruct = structField(key, value); !isStruct {
+			return newStrictError(node, ErrNotObject, value)
+		}
+	}
+	if !found {
+		return newStrictError(node, ErrMissingKey, value)
+	}
+	return nil
+}
+
+// checkArray returns the strict mode error for evaluating node, which
+// requires an array, against value.
+func checkArray(node ASTNode, value interface{}) error {
+	if !isSliceType(value) {
+		return newStrictError(node, ErrNotArray, value)
+	}
+	return nil
+}
+
+// checkIndex returns the strict mode error for the index node reaching
+// outside of the array value.
+func checkIndex(node ASTNode, value interface{}) error {
+	index, length := node.value.(int), sliceLen(value)
+	if index < -length || index >= length {
+		return newStrictError(node, ErrOutOfRange, value)
+	}
+	return nil
+}
+
+// checkOrdered returns the strict mode error for comparing left and right
+// with the comparator node.  Strings can be ordered if orderStrings is set.
+func checkOrdered(node ASTNode, left, right interface{}, orderStrings bool) error {
+	switch node.value.(tokType) {
+	case tEQ, tNE:
+		return nil
+	case tIn, tNotIn:
+		if !isSliceType(right) {
+			return newStrictError(node, ErrNotArray, right)
+		}
+		return nil
+	}
+	if _, _, ok := stringPair(left, right); ok && orderStrings {
+		return nil
+	}
+	for _, value := range []interface{}{left, right} {
+		if asNumber(value).kind == notNumber {
+			return newStrictError(node, ErrIncomparable, value)
+		}
+	}
+	return nil
+}
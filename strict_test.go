@@ -0,0 +1,125 @@
+package jmespath
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func strictRuntime() *Runtime {
+	rt := NewRuntime()
+	rt.SetStrict(true)
+	return rt
+}
+
+func TestStrictErrors(t *testing.T) {
+	assert := assert.New(t)
+	data := map[string]interface{}{
+		"name":   nil,
+		"tags":   []interface{}{"a", "b"},
+		"owner":  map[string]interface{}{"id": 1.0},
+		"people": []interface{}{map[string]interface{}{"age": 20.0}, map[string]interface{}{}},
+	}
+	cases := []struct {
+		expression    string
+		err           error
+		subExpression string
+		valueType     string
+	}{
+		{"missing", ErrMissingKey, "missing", ""},
+		{"owner.email", ErrMissingKey, "email", ""},
+		{"name.first", ErrNotObject, "first", "null"},
+		{"owner[0]", ErrNotArray, "[0]", "object"},
+		{"owner[:1]", ErrNotArray, "[:1]", "object"},
+		{"owner[*].id", ErrNotArray, "owner[*].id", "object"},
+		{"owner[]", ErrNotArray, "owner[]", "object"},
+		{"owner[?id]", ErrNotArray, "owner[?id]", "object"},
+		{"tags.*", ErrNotObject, "tags.*", "array"},
+		{"tags[0] < `1`", ErrIncomparable, "tags[0] < `1`", "string"},
+		{"people[*].age", ErrMissingKey, "age", ""},
+		{"people[?age > `10`]", ErrMissingKey, "age", ""},
+		{"missing[?a].b", ErrMissingKey, "missing", ""},
+	}
+	for _, tt := range cases {
+		_, err := strictRuntime().Search(tt.expression, data)
+		assert.True(errors.Is(err, tt.err), tt.expression)
+		var strictErr *StrictError
+		if assert.True(errors.As(err, &strictErr), tt.expression) {
+			assert.Equal(tt.subExpression, strictErr.SubExpression, tt.expression)
+			assert.Equal(tt.valueType, strictErr.ValueType, tt.expression)
+		}
+
+		// Permissive mode is unchanged.
+		_, err = Search(tt.expression, data)
+		assert.Nil(err, tt.expression)
+	}
+}
+
+func TestStrictAllowsPresentValues(t *testing.T) {
+	assert := assert.New(t)
+	data := map[string]interface{}{
+		"name": nil,
+		"tags": []interface{}{"a", "b"},
+		"age":  30.0,
+	}
+	cases := []struct {
+		expression string
+		expected   interface{}
+	}{
+		{"name", nil},
+		{"tags[5]", nil},
+		{"tags[*]", []interface{}{"a", "b"}},
+		{"age > `20`", true},
+		{"name == `null`", true},
+		{"tags[0] == `1`", false},
+		{"{n: name, t: tags[0]}", map[string]interface{}{"n": nil, "t": "a"}},
+	}
+	for _, tt := range cases {
+		result, err := strictRuntime().Search(tt.expression, data)
+		assert.Nil(err, tt.expression)
+		assert.Equal(tt.expected, result, tt.expression)
+	}
+}
+
+func TestStrictBounds(t *testing.T) {
+	assert := assert.New(t)
+	rt := strictRuntime()
+	rt.SetStrictBounds(true)
+	data := map[string]interface{}{"tags": []interface{}{"a", "b"}, "ids": []int{1, 2}}
+	for _, expression := range []string{"tags[2]", "tags[-3]", "tags[:3]", "tags[-3:]", "tags[5::-1]", "ids[2]", "ids[:3]"} {
+		_, err := rt.Search(expression, data)
+		assert.True(errors.Is(err, ErrOutOfRange), expression)
+		var strictErr *StrictError
+		if assert.True(errors.As(err, &strictErr), expression) {
+			assert.Equal("", strictErr.ValueType, expression)
+		}
+	}
+	_, err := rt.Search("tags[2]", data)
+	assert.Equal("[2]: index out of range", err.Error())
+
+	for _, expression := range []string{"tags[1]", "tags[-2]", "tags[:2]", "tags[-2:]", "tags[2:]", "tags[::-1]", "ids[:2]"} {
+		_, err := rt.Search(expression, data)
+		assert.Nil(err, expression)
+	}
+
+	// Without strict mode the option has no effect.
+	permissive := NewRuntime()
+	permissive.SetStrictBounds(true)
+	result, err := permissive.Search("tags[5]", data)
+	assert.Nil(err)
+	assert.Nil(result)
+}
+
+func TestStrictMatch(t *testing.T) {
+	assert := assert.New(t)
+	rt := strictRuntime()
+	jp, err := rt.Compile("a > `1`")
+	assert.Nil(err)
+	_, err = jp.Match(map[string]interface{}{})
+	assert.True(errors.Is(err, ErrMissingKey))
+	assert.Equal("a: missing key", err.Error())
+	matched, err := jp.Match(map[string]interface{}{"a": 2.0})
+	assert.Nil(err)
+	assert.True(matched)
+}
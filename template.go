@@ -0,0 +1,164 @@
+package jmespath
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// Template is a string with embedded JMESPath expressions, written in
+// braces as in "Hello {name.first}, you have {length(messages)} messages".
+// The expressions are compiled once, when the template is compiled, and
+// every Render evaluates them against the given data.  Literal braces are
+// written doubled, as "{{" and "}}".  Braces inside an expression, as in
+// multiselect hashes and string literals, are matched, so that
+// "{ {a: a}.a }" embeds the expression "{a: a}.a".
+//
+// Strings are rendered as they are, null as the empty string and any other
+// result as JSON, as to_json would encode it.  A Template is safe for
+// concurrent use by multiple goroutines.
+type Template struct {
+	text string
+	// literals holds the text before each expression, and after the last.
+	literals    []string
+	expressions []*JMESPath
+}
+
+// CompileTemplate parses a template and compiles the expressions embedded
+// in it.  Syntax errors are SyntaxErrors whose Expression is the template
+// and whose Offset is relative to it.
+func CompileTemplate(text string) (*Template, error) {
+	return compileTemplate(text, Compile)
+}
+
+// MustCompileTemplate is like CompileTemplate but panics if the template
+// cannot be parsed.
+func MustCompileTemplate(text string) *Template {
+	t, err := CompileTemplate(text)
+	if err != nil {
+		panic(`jmespath: CompileTemplate(` + strconv.Quote(text) + `): ` + err.Error())
+	}
+	return t
+}
+
+// CompileTemplate parses a template and compiles the expressions embedded
+// in it with the functions and options of this runtime.
+func (rt *Runtime) CompileTemplate(text string) (*Template, error) {
+	return compileTemplate(text, rt.Compile)
+}
+
+// Interpolate renders a template against data.  Templates that are rendered
+// repeatedly should be compiled once with CompileTemplate instead.
+func Interpolate(text string, data interface{}) (string, error) {
+	t, err := CompileTemplate(text)
+	if err != nil {
+		return "", err
+	}
+	return t.Render(data)
+}
+
+// String returns the template the Template was compiled from.
+func (t *Template) String() string {
+	return t.text
+}
+
+// Render evaluates the expressions of the template against data and
+// returns the template with each of them replaced by its result.
+func (t *Template) Render(data interface{}) (string, error) {
+	var b strings.Builder
+	for i, jp := range t.expressions {
+		b.WriteString(t.literals[i])
+		result, err := jp.Search(data)
+		if err != nil {
+			return "", err
+		}
+		if jp.intr.numbers.formats() {
+			result = jp.intr.numbers.formatNumbers(result)
+		}
+		if err := renderTemplateValue(&b, result); err != nil {
+			return "", err
+		}
+	}
+	b.WriteString(t.literals[len(t.expressions)])
+	return b.String(), nil
+}
+
+func renderTemplateValue(b *strings.Builder, value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		b.WriteString(v)
+		return nil
+	}
+	var encoded bytes.Buffer
+	encoder := json.NewEncoder(&encoded)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return err
+	}
+	b.Write(bytes.TrimSuffix(encoded.Bytes(), []byte("\n")))
+	return nil
+}
+
+func compileTemplate(text string, compile func(string) (*JMESPath, error)) (*Template, error) {
+	t := &Template{text: text}
+	var literal strings.Builder
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case c == '{' && strings.HasPrefix(text[i:], "{{"), c == '}' && strings.HasPrefix(text[i:], "}}"):
+			literal.WriteByte(c)
+			i++
+		case c == '}':
+			return nil, SyntaxError{msg: "Unmatched } in template, use }} for a literal brace", Expression: text, Offset: i}
+		case c == '{':
+			end := templateExpressionEnd(text, i+1)
+			if end < 0 {
+				return nil, SyntaxError{msg: "Unclosed { in template", Expression: text, Offset: i}
+			}
+			jp, err := compile(text[i+1 : end])
+			if err != nil {
+				if syntaxError, ok := err.(SyntaxError); ok {
+					syntaxError.Expression = text
+					syntaxError.Offset += i + 1
+					return nil, syntaxError
+				}
+				return nil, err
+			}
+			t.literals = append(t.literals, literal.String())
+			t.expressions = append(t.expressions, jp)
+			literal.Reset()
+			i = end
+		default:
+			literal.WriteByte(c)
+		}
+	}
+	t.literals = append(t.literals, literal.String())
+	return t, nil
+}
+
+// templateExpressionEnd returns the offset of the brace closing the
+// expression starting at start, skipping nested braces and the contents of
+// quoted identifiers and literals, or -1 if there is none.
+func templateExpressionEnd(text string, start int) int {
+	depth := 0
+	for i := start; i < len(text); i++ {
+		switch c := text[i]; c {
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		case '"', '\'', '`':
+			for i++; i < len(text) && text[i] != c; i++ {
+				if text[i] == '\\' {
+					i++
+				}
+			}
+		}
+	}
+	return -1
+}
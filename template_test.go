@@ -0,0 +1,88 @@
+package jmespath
+
+import (
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+func TestInterpolate(t *testing.T) {
+	assert := assert.New(t)
+	data := map[string]interface{}{
+		"name":     map[string]interface{}{"first": "Ada", "last": "Lovelace"},
+		"messages": []interface{}{"a", "b", "c"},
+		"tags":     []interface{}{"x", "<y>"},
+		"ratio":    0.5,
+	}
+	cases := []struct {
+		template string
+		expected string
+	}{
+		{"Hello {name.first}, you have {length(messages)} messages", "Hello Ada, you have 3 messages"},
+		{"no expressions", "no expressions"},
+		{"", ""},
+		{"{name.first}{name.last}", "AdaLovelace"},
+		{"missing: [{nope}]", "missing: []"},
+		{"tags: {tags}", `tags: ["x","<y>"]`},
+		{"{ratio} {`true`}", "0.5 true"},
+		{"{{literal}} {{{name.first}}}", "{literal} {Ada}"},
+		{"{ {first: name.first}.first }", "Ada"},
+		{"{join('}', tags)}", "x}<y>"},
+		{"{\"name\".\"first\"}", "Ada"},
+		{"{`\"}\"`}", "}"},
+	}
+	for _, tc := range cases {
+		result, err := Interpolate(tc.template, data)
+		if assert.Nil(err, tc.template) {
+			assert.Equal(tc.expected, result, tc.template)
+		}
+	}
+}
+
+func TestCompileTemplateErrors(t *testing.T) {
+	assert := assert.New(t)
+	cases := []struct {
+		template string
+		offset   int
+	}{
+		{"Hello {name", 6},
+		{"Hello }", 6},
+		{"Hello {name.}", 12},
+		{"{a} and {foo[}", 13},
+	}
+	for _, tc := range cases {
+		_, err := CompileTemplate(tc.template)
+		if syntaxError, ok := err.(SyntaxError); assert.True(ok, tc.template) {
+			assert.Equal(tc.template, syntaxError.Expression, tc.template)
+			assert.Equal(tc.offset, syntaxError.Offset, tc.template)
+		}
+	}
+	assert.Panics(func() { MustCompileTemplate("{") })
+}
+
+func TestTemplateRender(t *testing.T) {
+	assert := assert.New(t)
+	tmpl := MustCompileTemplate("{id}: {status} ({length(status)})")
+	assert.Equal("{id}: {status} ({length(status)})", tmpl.String())
+	for _, id := range []string{"a", "b"} {
+		result, err := tmpl.Render(map[string]interface{}{"id": id, "status": "ok"})
+		assert.Nil(err)
+		assert.Equal(id+": ok (2)", result)
+	}
+	_, err := tmpl.Render(map[string]interface{}{"id": "a", "status": 1.0})
+	assert.NotNil(err)
+}
+
+func TestRuntimeCompileTemplate(t *testing.T) {
+	assert := assert.New(t)
+	rt := NewRuntime()
+	err := rt.RegisterFunction("greet", func(ctx CallContext, args []interface{}) (interface{}, error) {
+		return "hi " + args[0].(string), nil
+	})
+	assert.Nil(err)
+	tmpl, err := rt.CompileTemplate("{greet(name)}!")
+	assert.Nil(err)
+	result, err := tmpl.Render(map[string]interface{}{"name": "Ada"})
+	assert.Nil(err)
+	assert.Equal("hi Ada!", result)
+}
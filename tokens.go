@@ -0,0 +1,174 @@
+package jmespath
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// TokenKind is the kind of a Token.
+type TokenKind int
+
+//go:generate stringer -type=TokenKind -trimprefix=Token
+const (
+	// TokenInvalid is text that is not a token, such as an unknown
+	// character or an unclosed literal.  Its Err field says why.
+	TokenInvalid TokenKind = iota
+	// TokenEOF marks the end of the expression.
+	TokenEOF
+	// TokenIdentifier is an unquoted identifier, such as foo.
+	TokenIdentifier
+	// TokenQuotedIdentifier is a quoted identifier, such as "foo bar".
+	TokenQuotedIdentifier
+	// TokenNumber is a number in an index or slice, such as the 1 of [1].
+	TokenNumber
+	// TokenStringLiteral is a raw string literal, such as 'foo'.
+	TokenStringLiteral
+	// TokenJSONLiteral is a JSON literal, such as `{"a": 1}`.
+	TokenJSONLiteral
+	TokenStar       // *
+	TokenDot        // .
+	TokenDotDot     // ..
+	TokenFilter     // [?
+	TokenFlatten    // []
+	TokenLbracket   // [
+	TokenRbracket   // ]
+	TokenLbrace     // {
+	TokenRbrace     // }
+	TokenLparen     // (
+	TokenRparen     // )
+	TokenComma      // ,
+	TokenColon      // :
+	TokenPipe       // |
+	TokenOr         // ||
+	TokenAnd        // &&
+	TokenNot        // !
+	TokenCurrent    // @
+	TokenExpref     // &
+	TokenComparator // ==, !=, <, <=, > or >=
+	// TokenIn is the membership operator "in".  Like the parser, the
+	// lexer only returns it after the end of an expression, elsewhere
+	// "in" is an identifier.
+	TokenIn
+	// TokenNotIn is the membership operator "not in", which may contain
+	// whitespace between its words.
+	TokenNotIn
+	// TokenMacro is a reference to a macro, such as #active, see
+	// Runtime.DefineMacro.
+	TokenMacro
+)
+
+// tokenKinds maps the types of the tokens of the parser to the kinds
+// returned by NextToken.
+var tokenKinds = map[tokType]TokenKind{
+	tUnknown:            TokenInvalid,
+	tEOF:                TokenEOF,
+	tUnquotedIdentifier: TokenIdentifier,
+	tQuotedIdentifier:   TokenQuotedIdentifier,
+	tNumber:             TokenNumber,
+	tStringLiteral:      TokenStringLiteral,
+	tJSONLiteral:        TokenJSONLiteral,
+	tStar:               TokenStar,
+	tDot:                TokenDot,
+	tDotDot:             TokenDotDot,
+	tFilter:             TokenFilter,
+	tFlatten:            TokenFlatten,
+	tLbracket:           TokenLbracket,
+	tRbracket:           TokenRbracket,
+	tLbrace:             TokenLbrace,
+	tRbrace:             TokenRbrace,
+	tLparen:             TokenLparen,
+	tRparen:             TokenRparen,
+	tComma:              TokenComma,
+	tColon:              TokenColon,
+	tPipe:               TokenPipe,
+	tOr:                 TokenOr,
+	tAnd:                TokenAnd,
+	tNot:                TokenNot,
+	tCurrent:            TokenCurrent,
+	tExpref:             TokenExpref,
+	tLT:                 TokenComparator,
+	tLTE:                TokenComparator,
+	tGT:                 TokenComparator,
+	tGTE:                TokenComparator,
+	tEQ:                 TokenComparator,
+	tNE:                 TokenComparator,
+	tIn:                 TokenIn,
+	tNotIn:              TokenNotIn,
+	tMacro:              TokenMacro,
+}
+
+// Token is a token of an expression, as returned by Lexer.NextToken.
+type Token struct {
+	Kind  TokenKind
+	Text  string // Source text of the token, including quotes and delimiters.
+	Start int    // Byte offset of the token in the expression.
+	End   int    // Byte offset just past the token in the expression.
+	Err   error  // Why Text is not a token, for TokenInvalid tokens.
+}
+
+// Reset makes the lexer tokenize expression from its start with
+// NextToken.
+func (lexer *Lexer) Reset(expression string) {
+	lexer.expression = expression
+	lexer.currentPos = 0
+	lexer.lastWidth = 0
+	lexer.previous = tEOF
+	lexer.buf.Reset()
+}
+
+// NextToken returns the next token of the expression passed to Reset, for
+// syntax highlighting and language servers.  It returns TokenEOF tokens
+// once the whole expression has been returned.  Incomplete or invalid
+// input, such as an unclosed literal, is returned as a TokenInvalid token
+// and tokenizing resumes after it, so every byte of the expression that
+// is not whitespace is part of a returned token.
+func (lexer *Lexer) NextToken() Token {
+	// A literal that failed to tokenize may have left a partial value.
+	lexer.buf.Reset()
+	start := lexer.skipWhitespace()
+	t, err := lexer.nextToken()
+	if err == nil && t.tokenType == tUnknown {
+		err = lexer.syntaxError(fmt.Sprintf("Unknown char: %s", strconv.QuoteRuneToASCII(rune(t.value[0]))))
+	}
+	if err != nil {
+		lexer.previous = tUnknown
+		return Token{Kind: TokenInvalid, Text: lexer.expression[start:lexer.currentPos],
+			Start: start, End: lexer.currentPos, Err: err}
+	}
+	if t.tokenType == tUnquotedIdentifier && endsExpression(lexer.previous) {
+		t.tokenType = lexer.membershipOperator(t.value)
+	}
+	lexer.previous = t.tokenType
+	return Token{Kind: tokenKinds[t.tokenType], Text: lexer.expression[start:lexer.currentPos],
+		Start: start, End: lexer.currentPos}
+}
+
+// skipWhitespace consumes the whitespace at the current position and
+// returns the position after it.
+func (lexer *Lexer) skipWhitespace() int {
+	for {
+		r := lexer.next()
+		if _, ok := whiteSpace[r]; !ok {
+			lexer.back()
+			return lexer.currentPos
+		}
+	}
+}
+
+// membershipOperator returns the type of an identifier following the end
+// of an expression, consuming the "in" of "not in", as membershipOperators
+// does for the parser.
+func (lexer *Lexer) membershipOperator(identifier string) tokType {
+	switch identifier {
+	case "in":
+		return tIn
+	case "not":
+		pos, width := lexer.currentPos, lexer.lastWidth
+		lexer.skipWhitespace()
+		if next, err := lexer.nextToken(); err == nil && next.tokenType == tUnquotedIdentifier && next.value == "in" {
+			return tNotIn
+		}
+		lexer.currentPos, lexer.lastWidth = pos, width
+	}
+	return tUnquotedIdentifier
+}
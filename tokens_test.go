@@ -0,0 +1,97 @@
+package jmespath
+
+import (
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+// lexAll returns the tokens NextToken returns for expression, up to and
+// including the first TokenEOF.
+func lexAll(expression string) []Token {
+	lexer := NewLexer()
+	lexer.Reset(expression)
+	var tokens []Token
+	for {
+		t := lexer.NextToken()
+		t.Err = nil
+		tokens = append(tokens, t)
+		if t.Kind == TokenEOF {
+			return tokens
+		}
+	}
+}
+
+func TestNextToken(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal([]Token{
+		{TokenIdentifier, "foo", 0, 3, nil},
+		{TokenFilter, "[?", 3, 5, nil},
+		{TokenQuotedIdentifier, `"a b"`, 5, 10, nil},
+		{TokenComparator, "==", 11, 13, nil},
+		{TokenStringLiteral, `'it\'s'`, 14, 21, nil},
+		{TokenRbracket, "]", 21, 22, nil},
+		{TokenDot, ".", 22, 23, nil},
+		{TokenIdentifier, "bar", 23, 26, nil},
+		{TokenPipe, "|", 27, 28, nil},
+		{TokenIdentifier, "length", 29, 35, nil},
+		{TokenLparen, "(", 35, 36, nil},
+		{TokenCurrent, "@", 36, 37, nil},
+		{TokenRparen, ")", 37, 38, nil},
+		{TokenComparator, ">=", 39, 41, nil},
+		{TokenJSONLiteral, "`1`", 42, 45, nil},
+		{TokenEOF, "", 45, 45, nil},
+	}, lexAll(`foo[?"a b" == 'it\'s'].bar | length(@) >= `+"`1`"))
+}
+
+func TestNextTokenKinds(t *testing.T) {
+	assert := assert.New(t)
+	cases := map[string][]TokenKind{
+		"a[0:1]":         {TokenIdentifier, TokenLbracket, TokenNumber, TokenColon, TokenNumber, TokenRbracket},
+		"a[*].b[]":       {TokenIdentifier, TokenLbracket, TokenStar, TokenRbracket, TokenDot, TokenIdentifier, TokenFlatten},
+		"{k: a, l: b}":   {TokenLbrace, TokenIdentifier, TokenColon, TokenIdentifier, TokenComma, TokenIdentifier, TokenColon, TokenIdentifier, TokenRbrace},
+		"!a || b && c":   {TokenNot, TokenIdentifier, TokenOr, TokenIdentifier, TokenAnd, TokenIdentifier},
+		"sort_by(a, &b)": {TokenIdentifier, TokenLparen, TokenIdentifier, TokenComma, TokenExpref, TokenIdentifier, TokenRparen},
+		"..a":            {TokenDotDot, TokenIdentifier},
+		"a in b":         {TokenIdentifier, TokenIn, TokenIdentifier},
+		"a not  in b":    {TokenIdentifier, TokenNotIn, TokenIdentifier},
+		"a not b":        {TokenIdentifier, TokenIdentifier, TokenIdentifier},
+		"in.not":         {TokenIdentifier, TokenDot, TokenIdentifier},
+		"a = b":          {TokenIdentifier, TokenInvalid, TokenIdentifier},
+		"a[?#running]":   {TokenIdentifier, TokenFilter, TokenMacro, TokenRbracket},
+	}
+	for expression, expected := range cases {
+		var kinds []TokenKind
+		for _, token := range lexAll(expression) {
+			kinds = append(kinds, token.Kind)
+		}
+		assert.Equal(append(expected, TokenEOF), kinds, expression)
+	}
+	tokens := lexAll("a not  in b")
+	assert.Equal("not  in", tokens[1].Text)
+	assert.Equal("NotIn", TokenNotIn.String())
+}
+
+func TestNextTokenIncompleteInput(t *testing.T) {
+	assert := assert.New(t)
+	lexer := NewLexer()
+	lexer.Reset("foo.'bar")
+	assert.Equal(Token{TokenIdentifier, "foo", 0, 3, nil}, lexer.NextToken())
+	assert.Equal(Token{TokenDot, ".", 3, 4, nil}, lexer.NextToken())
+	invalid := lexer.NextToken()
+	assert.Equal(TokenInvalid, invalid.Kind)
+	assert.Equal("'bar", invalid.Text)
+	assert.Equal(4, invalid.Start)
+	assert.Equal(8, invalid.End)
+	_, ok := invalid.Err.(SyntaxError)
+	assert.True(ok)
+	assert.Equal(Token{TokenEOF, "", 8, 8, nil}, lexer.NextToken())
+	assert.Equal(Token{TokenEOF, "", 8, 8, nil}, lexer.NextToken())
+
+	lexer.Reset(`a # "b\q" 'c'`)
+	var texts []string
+	for t := lexer.NextToken(); t.Kind != TokenEOF; t = lexer.NextToken() {
+		texts = append(texts, t.Kind.String()+" "+t.Text)
+	}
+	assert.Equal([]string{"Identifier a", "Invalid #", `Invalid "b\q"`, "StringLiteral 'c'"}, texts)
+}
@@ -11,39 +11,43 @@ func _() {
 	_ = x[tUnknown-0]
 	_ = x[tStar-1]
 	_ = x[tDot-2]
-	_ = x[tFilter-3]
-	_ = x[tFlatten-4]
-	_ = x[tLparen-5]
-	_ = x[tRparen-6]
-	_ = x[tLbracket-7]
-	_ = x[tRbracket-8]
-	_ = x[tLbrace-9]
-	_ = x[tRbrace-10]
-	_ = x[tOr-11]
-	_ = x[tPipe-12]
-	_ = x[tNumber-13]
-	_ = x[tUnquotedIdentifier-14]
-	_ = x[tQuotedIdentifier-15]
-	_ = x[tComma-16]
-	_ = x[tColon-17]
-	_ = x[tLT-18]
-	_ = x[tLTE-19]
-	_ = x[tGT-20]
-	_ = x[tGTE-21]
-	_ = x[tEQ-22]
-	_ = x[tNE-23]
-	_ = x[tJSONLiteral-24]
-	_ = x[tStringLiteral-25]
-	_ = x[tCurrent-26]
-	_ = x[tExpref-27]
-	_ = x[tAnd-28]
-	_ = x[tNot-29]
-	_ = x[tEOF-30]
+	_ = x[tDotDot-3]
+	_ = x[tFilter-4]
+	_ = x[tFlatten-5]
+	_ = x[tLparen-6]
+	_ = x[tRparen-7]
+	_ = x[tLbracket-8]
+	_ = x[tRbracket-9]
+	_ = x[tLbrace-10]
+	_ = x[tRbrace-11]
+	_ = x[tOr-12]
+	_ = x[tPipe-13]
+	_ = x[tNumber-14]
+	_ = x[tUnquotedIdentifier-15]
+	_ = x[tQuotedIdentifier-16]
+	_ = x[tComma-17]
+	_ = x[tColon-18]
+	_ = x[tLT-19]
+	_ = x[tLTE-20]
+	_ = x[tGT-21]
+	_ = x[tGTE-22]
+	_ = x[tEQ-23]
+	_ = x[tNE-24]
+	_ = x[tIn-25]
+	_ = x[tNotIn-26]
+	_ = x[tJSONLiteral-27]
+	_ = x[tStringLiteral-28]
+	_ = x[tCurrent-29]
+	_ = x[tExpref-30]
+	_ = x[tAnd-31]
+	_ = x[tNot-32]
+	_ = x[tEOF-33]
+	_ = x[tMacro-34]
 }
 
-const _tokType_name = "tUnknowntStartDottFiltertFlattentLparentRparentLbrackettRbrackettLbracetRbracetOrtPipetNumbertUnquotedIdentifiertQuotedIdentifiertCommatColontLTtLTEtGTtGTEtEQtNEtJSONLiteraltStringLiteraltCurrenttExpreftAndtNottEOF"
+const _tokType_name = "tUnknowntStartDottDotDottFiltertFlattentLparentRparentLbrackettRbrackettLbracetRbracetOrtPipetNumbertUnquotedIdentifiertQuotedIdentifiertCommatColontLTtLTEtGTtGTEtEQtNEtIntNotIntJSONLiteraltStringLiteraltCurrenttExpreftAndtNottEOFtMacro"
 
-var _tokType_index = [...]uint8{0, 8, 13, 17, 24, 32, 39, 46, 55, 64, 71, 78, 81, 86, 93, 112, 129, 135, 141, 144, 148, 151, 155, 158, 161, 173, 187, 195, 202, 206, 210, 214}
+var _tokType_index = [...]uint8{0, 8, 13, 17, 24, 31, 39, 46, 53, 62, 71, 78, 85, 88, 93, 100, 119, 136, 142, 148, 151, 155, 158, 162, 165, 168, 171, 177, 189, 203, 211, 218, 222, 226, 230, 236}
 
 func (i tokType) String() string {
 	if i < 0 || i >= tokType(len(_tokType_index)-1) {
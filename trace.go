@@ -0,0 +1,35 @@
+package jmespath
+
+// Tracer observes the evaluation of expressions, for example to build a
+// step debugger or to visualize how a result was computed.  Enter and
+// Exit are called for every node of the expression in evaluation order,
+// and are called from several goroutines at once if the expression is
+// searched concurrently.  The nodes within expression references, "&expr",
+// are evaluated by the functions they are passed to and are not traced.
+type Tracer interface {
+	// Enter is called before node is evaluated against value.  depth is
+	// the depth of node in the expression, zero for the root.
+	Enter(node ASTNode, depth int, value interface{})
+	// Exit is called after node has been evaluated, with its result or
+	// the error evaluating it failed with.
+	Exit(node ASTNode, depth int, result interface{}, err error)
+}
+
+// newTraceWalker returns a walker that reports the evaluation of each node
+// to the tracer of intr.
+func newTraceWalker(intr *treeInterpreter) *walker {
+	tracer := intr.tracer
+	depth := 0
+	return &walker{
+		intr: intr,
+		enter: func(node *ASTNode, value interface{}) {
+			tracer.Enter(*node, depth, value)
+			depth++
+		},
+		exit: func(node *ASTNode, value, result interface{}, err error) (interface{}, error) {
+			depth--
+			tracer.Exit(*node, depth, result, err)
+			return result, err
+		},
+	}
+}
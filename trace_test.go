@@ -0,0 +1,78 @@
+package jmespath
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+// recordingTracer records every event as an indented line.
+type recordingTracer struct {
+	events []string
+}
+
+func (r *recordingTracer) Enter(node ASTNode, depth int, value interface{}) {
+	r.events = append(r.events, fmt.Sprintf("%senter %s with %v", strings.Repeat("  ", depth), FormatAST(node), value))
+}
+
+func (r *recordingTracer) Exit(node ASTNode, depth int, result interface{}, err error) {
+	r.events = append(r.events, fmt.Sprintf("%sexit %s with %v, %v", strings.Repeat("  ", depth), FormatAST(node), result, err))
+}
+
+func TestTracer(t *testing.T) {
+	assert := assert.New(t)
+	tracer := &recordingTracer{}
+	rt := NewRuntime()
+	rt.SetTracer(tracer)
+	jp, err := rt.Compile("@.a[?b > `1`].b")
+	assert.Nil(err)
+	data := map[string]interface{}{"a": []interface{}{
+		map[string]interface{}{"b": 1.0},
+		map[string]interface{}{"b": 2.0},
+	}}
+	result, err := jp.Search(data)
+	assert.Nil(err)
+	assert.Equal([]interface{}{2.0}, result)
+	assert.Equal([]string{
+		"enter @.a[?b > `1`].b with map[a:[map[b:1] map[b:2]]]",
+		"  enter @.a with map[a:[map[b:1] map[b:2]]]",
+		"    enter @ with map[a:[map[b:1] map[b:2]]]",
+		"    exit @ with map[a:[map[b:1] map[b:2]]], <nil>",
+		"    enter a with map[a:[map[b:1] map[b:2]]]",
+		"    exit a with [map[b:1] map[b:2]], <nil>",
+		"  exit @.a with [map[b:1] map[b:2]], <nil>",
+		"  enter b > `1` with map[b:1]",
+		"    enter b with map[b:1]",
+		"    exit b with 1, <nil>",
+		"    enter `1` with map[b:1]",
+		"    exit `1` with 1, <nil>",
+		"  exit b > `1` with false, <nil>",
+		"  enter b > `1` with map[b:2]",
+		"    enter b with map[b:2]",
+		"    exit b with 2, <nil>",
+		"    enter `1` with map[b:2]",
+		"    exit `1` with 1, <nil>",
+		"  exit b > `1` with true, <nil>",
+		"  enter b with map[b:2]",
+		"  exit b with 2, <nil>",
+		"exit @.a[?b > `1`].b with [2], <nil>",
+	}, tracer.events)
+}
+
+func TestTracerErrors(t *testing.T) {
+	assert := assert.New(t)
+	tracer := &recordingTracer{}
+	rt := NewRuntime()
+	rt.SetTracer(tracer)
+	_, err := rt.Search("abs(a)", map[string]interface{}{"a": "x"})
+	assert.NotNil(err)
+	assert.Equal("exit abs(a) with <nil>, abs(a): invalid type for argument 1: expected number, got string", tracer.events[len(tracer.events)-1])
+
+	// Expressions compiled without a tracer are compiled as usual.
+	rt.SetTracer(nil)
+	jp, err := rt.Compile("@.a")
+	assert.Nil(err)
+	assert.Equal(ASTNode{nodeType: ASTField, value: "a"}, jp.ast)
+}
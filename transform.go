@@ -0,0 +1,59 @@
+package jmespath
+
+import (
+	"time"
+)
+
+// ValueTransformer transforms a scalar value read from the searched
+// document, for example to decrypt a sealed string or to resolve a
+// reference to a secret.  Returning an error aborts the search with that
+// error.
+type ValueTransformer func(value interface{}) (interface{}, error)
+
+// SearchWithTransformer is like Search, but passes every scalar read from
+// data through transform before the expression uses it.  A value is read
+// when it is selected by a field or an index, as in "user.password" or
+// "keys[0]", or when it is an element a projection iterates over, as in
+// "keys[*]" or "user.*".  Arrays and objects are passed through unchanged,
+// so their contents are only transformed when they are read themselves:
+// "join(',', keys[*])" joins transformed keys but "join(',', keys)" does
+// not.  Nulls are never transformed.
+//
+// The transformer applies to this evaluation only and does not slow down
+// other searches with the same JMESPath, but the evaluation itself is
+// slower than Search.
+func (jp *JMESPath) SearchWithTransformer(data interface{}, transform ValueTransformer) (result interface{}, err error) {
+	if jp.intr.metrics != nil {
+		defer jp.observe(time.Now(), &err)
+	}
+	defer recoverInternal(&err)
+	read := func(value interface{}) (interface{}, error) {
+		if !isScalar(value) {
+			return value, nil
+		}
+		return transform(value)
+	}
+	w := walker{
+		intr: jp.intr,
+		exit: func(node *ASTNode, value, result interface{}, err error) (interface{}, error) {
+			if err != nil || (node.nodeType != ASTField && node.nodeType != ASTIndex) {
+				return result, err
+			}
+			return read(result)
+		},
+		element: read,
+	}
+	ast := jp.ast
+	result, err = w.eval(&ast, data)
+	return result, locateError(jp.expression, err)
+}
+
+// isScalar reports whether value is a string, number or boolean, or any
+// other value that is neither null, an array nor an object.
+func isScalar(value interface{}) bool {
+	switch value.(type) {
+	case nil, []interface{}, map[string]interface{}, *OrderedMap:
+		return false
+	}
+	return !isGoContainer(value)
+}
@@ -0,0 +1,75 @@
+package jmespath
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jmespath/go-jmespath/internal/testify/assert"
+)
+
+// unseal reverses strings prefixed with "sealed:".
+func unseal(value interface{}) (interface{}, error) {
+	s, ok := value.(string)
+	if !ok || !strings.HasPrefix(s, "sealed:") {
+		return value, nil
+	}
+	runes := []rune(strings.TrimPrefix(s, "sealed:"))
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes), nil
+}
+
+func TestSearchWithTransformer(t *testing.T) {
+	assert := assert.New(t)
+	data := map[string]interface{}{
+		"user": map[string]interface{}{"name": "ann", "token": "sealed:cba"},
+		"keys": []interface{}{"sealed:1k", "k2", nil},
+	}
+	cases := []struct {
+		expression string
+		expected   interface{}
+	}{
+		{"user.token", "abc"},
+		{"user.name", "ann"},
+		{"keys[0]", "k1"},
+		{"keys[*]", []interface{}{"k1", "k2"}},
+		{"user.*", []interface{}{"abc"}},
+		{"user.token == 'abc'", true},
+		{"keys[?@ == 'k1']", []interface{}{"k1"}},
+		{"join(',', keys[:2])", "k1,k2"},
+		{"length(user.token)", 3.0},
+		{"user", map[string]interface{}{"name": "ann", "token": "sealed:cba"}},
+	}
+	for _, tt := range cases {
+		jp, err := Compile(tt.expression)
+		assert.Nil(err)
+		result, err := jp.SearchWithTransformer(data, unseal)
+		assert.Nil(err, tt.expression)
+		if tt.expression == "user.*" {
+			assert.Contains(result, "abc", tt.expression)
+			continue
+		}
+		assert.Equal(tt.expected, result, tt.expression)
+	}
+
+	// The transformer only applies to the evaluation it is passed to.
+	result, err := MustCompile("user.token").Search(data)
+	assert.Nil(err)
+	assert.Equal("sealed:cba", result)
+}
+
+func TestSearchWithTransformerError(t *testing.T) {
+	assert := assert.New(t)
+	denied := errors.New("access denied")
+	data := map[string]interface{}{"a": []interface{}{"x"}, "b": nil}
+	deny := func(value interface{}) (interface{}, error) {
+		return nil, denied
+	}
+	_, err := MustCompile("a[*]").SearchWithTransformer(data, deny)
+	assert.Equal(denied, err)
+	result, err := MustCompile("b").SearchWithTransformer(data, deny)
+	assert.Nil(err)
+	assert.Nil(result)
+}
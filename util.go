@@ -1,10 +1,24 @@
 package jmespath
 
 import (
-	"errors"
-	"reflect"
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/jmespath/go-jmespath/jputil"
 )
 
+// Truthy reports whether value is true when used as a condition, as by a
+// filter or the &&, || and ! operators.
+// The false values are false, null, and empty strings, arrays and objects,
+// including empty Go slices and maps and nil pointers; every other value,
+// including 0 and structs, is true.  Custom functions can use it to treat
+// values the same way as the interpreter does.
+func Truthy(value interface{}) bool {
+	return !isFalse(value)
+}
+
 // IsFalse determines if an object is false based on the JMESPath spec.
 // JMESPath defines false values to be any of:
 // - An empty string array, or hash.
@@ -18,123 +32,57 @@ func isFalse(value interface{}) bool {
 		return len(v) == 0
 	case map[string]interface{}:
 		return len(v) == 0
+	case *OrderedMap:
+		return v.Len() == 0
 	case string:
 		return len(v) == 0
 	case nil:
 		return true
+	case Resolver:
+		return isFalseResolver(v)
 	}
-	// Try the reflection cases before returning false.
-	rv := reflect.ValueOf(value)
-	switch rv.Kind() {
-	case reflect.Struct:
-		// A struct type will never be false, even if
-		// all of its values are the zero type.
-		return false
-	case reflect.Slice, reflect.Map:
-		return rv.Len() == 0
-	case reflect.Ptr:
-		if rv.IsNil() {
-			return true
-		}
-		// If it's a pointer type, we'll try to deref the pointer
-		// and evaluate the pointer value for isFalse.
-		element := rv.Elem()
-		return isFalse(element.Interface())
-	}
-	return false
+	return isFalseGo(value)
 }
 
 // ObjsEqual is a generic object equality check.
 // It will take two arbitrary objects and recursively determine
-// if they are equal.
+// if they are equal.  Numbers of different Go numeric types are equal
+// if their values are.
 func objsEqual(left interface{}, right interface{}) bool {
-	return reflect.DeepEqual(left, right)
+	return valuesEqual(left, right)
 }
 
-// SliceParam refers to a single part of a slice.
-// A slice consists of a start, a stop, and a step, similar to
-// python slices.
-type sliceParam struct {
-	N         int
-	Specified bool
+// sliceBounds normalizes the bounds of the slice node for an array of
+// length elements.
+func sliceBounds(node ASTNode, length int) (jputil.SliceBounds, error) {
+	parts := node.value.([]*int)
+	return jputil.Slice(length, parts[0], parts[1], parts[2])
 }
 
-// Slice supports [start:stop:step] style slicing that's supported in JMESPath.
-func slice(slice []interface{}, parts []sliceParam) ([]interface{}, error) {
-	computed, err := computeSliceParams(len(slice), parts)
-	if err != nil {
-		return nil, err
-	}
-	start, stop, step := computed[0], computed[1], computed[2]
-	result := []interface{}{}
-	if step > 0 {
-		for i := start; i < stop; i += step {
-			result = append(result, slice[i])
-		}
-	} else {
-		for i := start; i > stop; i += step {
-			result = append(result, slice[i])
-		}
+// slice returns the elements of slice selected by bounds.
+func slice(slice []interface{}, bounds jputil.SliceBounds) []interface{} {
+	result := make([]interface{}, bounds.Len())
+	for i := range result {
+		result[i] = slice[bounds.Index(i)]
 	}
-	return result, nil
+	return result
 }
 
-func computeSliceParams(length int, parts []sliceParam) ([]int, error) {
-	var start, stop, step int
-	if !parts[2].Specified {
-		step = 1
-	} else if parts[2].N == 0 {
-		return nil, errors.New("Invalid slice, step cannot be 0")
-	} else {
-		step = parts[2].N
-	}
-	var stepValueNegative bool
-	if step < 0 {
-		stepValueNegative = true
-	} else {
-		stepValueNegative = false
-	}
-
-	if !parts[0].Specified {
-		if stepValueNegative {
-			start = length - 1
-		} else {
-			start = 0
-		}
-	} else {
-		start = capSlice(length, parts[0].N, step)
-	}
-
-	if !parts[1].Specified {
-		if stepValueNegative {
-			stop = -1
-		} else {
-			stop = length
-		}
-	} else {
-		stop = capSlice(length, parts[1].N, step)
+// slicedString returns left as a string if it is the string a slice at
+// the left of the projection node evaluated to.  The right side of such a
+// projection applies to the substring instead of projecting, as in
+// "name[0:3]" or "name[::-1]".
+func slicedString(node ASTNode, left interface{}) (string, bool) {
+	s, ok := left.(string)
+	if !ok {
+		return "", false
 	}
-	return []int{start, stop, step}, nil
-}
-
-func capSlice(length int, actual int, step int) int {
-	if actual < 0 {
-		actual += length
-		if actual < 0 {
-			if step < 0 {
-				actual = -1
-			} else {
-				actual = 0
-			}
-		}
-	} else if actual >= length {
-		if step < 0 {
-			actual = length - 1
-		} else {
-			actual = length
-		}
+	sliced := node.children[0]
+	if sliced.nodeType == ASTIndexExpression {
+		// The optimizer may have collapsed "@[0:3]" into the slice alone.
+		sliced = sliced.children[len(sliced.children)-1]
 	}
-	return actual
+	return s, sliced.nodeType == ASTSlice
 }
 
 // ToArrayNum converts an empty interface type to a slice of float64.
@@ -145,7 +93,7 @@ func toArrayNum(data interface{}) ([]float64, bool) {
 	if d, ok := data.([]interface{}); ok {
 		result := make([]float64, len(d))
 		for i, el := range d {
-			item, ok := el.(float64)
+			item, ok := toNumber(el)
 			if !ok {
 				return nil, false
 			}
@@ -177,9 +125,24 @@ func toArrayStr(data interface{}) ([]string, bool) {
 	return nil, false
 }
 
-func isSliceType(v interface{}) bool {
-	if v == nil {
-		return false
+var unquotedIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// formatIdentifier returns name as an identifier in an expression, quoted
+// if it is not a valid unquoted identifier.
+func formatIdentifier(name string) string {
+	if unquotedIdentifier.MatchString(name) {
+		return name
+	}
+	return encodeJSON(name)
+}
+
+// encodeJSON encodes value as JSON without escaping HTML characters.
+func encodeJSON(value interface{}) string {
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return "null"
 	}
-	return reflect.TypeOf(v).Kind() == reflect.Slice
+	return strings.TrimSuffix(b.String(), "\n")
 }
@@ -14,9 +14,10 @@ func TestSlicePositiveStep(t *testing.T) {
 	input[2] = 2
 	input[3] = 3
 	input[4] = 4
-	result, err := slice(input, []sliceParam{{0, true}, {3, true}, {1, true}})
+	start, stop, step := 0, 3, 1
+	bounds, err := sliceBounds(ASTNode{nodeType: ASTSlice, value: []*int{&start, &stop, &step}}, len(input))
 	assert.Nil(err)
-	assert.Equal(input[:3], result)
+	assert.Equal(input[:3], slice(input, bounds))
 }
 
 func TestIsFalseJSONTypes(t *testing.T) {
@@ -31,34 +32,16 @@ func TestIsFalseJSONTypes(t *testing.T) {
 
 }
 
-func TestIsFalseWithUserDefinedStructs(t *testing.T) {
+func TestTruthy(t *testing.T) {
 	assert := assert.New(t)
-	type nilStructType struct {
-		SliceOfPointers []*string
+	var nilPointer *string
+	for _, value := range []interface{}{false, nil, "", []interface{}{}, map[string]interface{}{}, []string{}, map[string]string{}, nilPointer, NewOrderedMap()} {
+		assert.False(Truthy(value), "%#v", value)
 	}
-	nilStruct := nilStructType{SliceOfPointers: nil}
-	assert.True(isFalse(nilStruct.SliceOfPointers))
-
-	// A user defined struct will never be false though,
-	// even if it's fields are the zero type.
-	assert.False(isFalse(nilStruct))
-}
-
-func TestIsFalseWithNilInterface(t *testing.T) {
-	assert := assert.New(t)
-	var a *int
-	var nilInterface interface{} = a
-	assert.True(isFalse(nilInterface))
-}
-
-func TestIsFalseWithMapOfUserStructs(t *testing.T) {
-	assert := assert.New(t)
-	type foo struct {
-		Bar string
-		Baz string
+	zero := 0
+	for _, value := range []interface{}{true, 0.0, 0, "a", []interface{}{nil}, map[string]interface{}{"a": nil}, struct{}{}, &zero} {
+		assert.True(Truthy(value), "%#v", value)
 	}
-	m := make(map[int]foo)
-	assert.True(isFalse(m))
 }
 
 func TestObjsEqual(t *testing.T) {
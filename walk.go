@@ -0,0 +1,239 @@
+package jmespath
+
+/* The walker evaluates an AST the same way as the tree interpreter, but
+   calls hooks as it goes so that callers can observe or adjust the
+   evaluation.  It is much slower than the compiled closures and is only
+   used by the features that need it, so expressions evaluated without
+   them pay nothing for the hooks.
+*/
+
+// walker evaluates ASTs with hooks.  The hooks are identified by the
+// address of the node in the AST, which must not be copied while it is
+// being walked.
+type walker struct {
+	intr *treeInterpreter
+	// enter, if set, is called before each node is evaluated against
+	// value.
+	enter func(node *ASTNode, value interface{})
+	// exit, if set, is called after each node is evaluated against value
+	// and returns the result of the node in place of the result passed
+	// to it.
+	exit func(node *ASTNode, value, result interface{}, err error) (interface{}, error)
+	// element, if set, is called on each element a projection iterates
+	// over and returns the element to project in its place.
+	element func(value interface{}) (interface{}, error)
+}
+
+// eval evaluates node the same way as treeInterpreter.Execute, calling
+// the hooks of w for node and its descendants.
+func (w *walker) eval(node *ASTNode, value interface{}) (interface{}, error) {
+	if w.enter != nil {
+		w.enter(node, value)
+	}
+	result, err := w.visit(node, value)
+	if w.exit != nil {
+		return w.exit(node, value, result, err)
+	}
+	return result, err
+}
+
+func (w *walker) visit(node *ASTNode, value interface{}) (interface{}, error) {
+	children := node.children
+	switch node.nodeType {
+	case ASTSubexpression, ASTIndexExpression, ASTPipe:
+		result := value
+		var err error
+		for i := range children {
+			result, err = w.eval(&children[i], result)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	case ASTComparator:
+		left, err := w.eval(&children[0], value)
+		if err != nil {
+			return nil, err
+		}
+		right, err := w.eval(&children[1], value)
+		if err != nil {
+			return nil, err
+		}
+		if left, right, err = w.intr.resolveOperands(left, right); err != nil {
+			return nil, err
+		}
+		if w.intr.strict {
+			if err := checkOrdered(*node, left, right, !w.intr.legacyCompare); err != nil {
+				return nil, err
+			}
+		}
+		return compare(node.value.(tokType), left, right, !w.intr.legacyCompare), nil
+	case ASTOrExpression:
+		matched, err := w.eval(&children[0], value)
+		if err != nil {
+			return nil, err
+		}
+		if !isFalse(matched) {
+			return matched, nil
+		}
+		return w.eval(&children[1], value)
+	case ASTAndExpression:
+		matched, err := w.eval(&children[0], value)
+		if err != nil {
+			return nil, err
+		}
+		if isFalse(matched) {
+			return matched, nil
+		}
+		return w.eval(&children[1], value)
+	case ASTNotExpression:
+		matched, err := w.eval(&children[0], value)
+		if err != nil {
+			return nil, err
+		}
+		return isFalse(matched), nil
+	case ASTKeyValPair:
+		return w.eval(&children[0], value)
+	case ASTMultiSelectList, ASTMultiSelectHash:
+		if value == nil {
+			return nil, nil
+		}
+		list := []interface{}{}
+		hash := make(map[string]interface{})
+		for i := range children {
+			current, err := w.eval(&children[i], value)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, current)
+			if node.nodeType == ASTMultiSelectHash {
+				hash[children[i].value.(string)] = current
+			}
+		}
+		if node.nodeType == ASTMultiSelectHash && w.intr.ordered {
+			ordered := NewOrderedMap()
+			for i, current := range list {
+				ordered.Set(children[i].value.(string), current)
+			}
+			return ordered, nil
+		}
+		if node.nodeType == ASTMultiSelectHash {
+			return hash, nil
+		}
+		return list, nil
+	case ASTFunctionExpression:
+		name := node.value.(string)
+		// Like other expression references, the contents of those that
+		// evalArgs applies itself are evaluated rather than walked.
+		resolvedArgs, err := evalArgs(w.intr, *node, value, func(i int) (interface{}, error) {
+			return w.eval(&children[i], value)
+		})
+		if err != nil {
+			return nil, err
+		}
+		result, err := w.intr.fCall.CallFunction(name, resolvedArgs, w.intr)
+		return result, callError(*node, err)
+	case ASTFlatten:
+		left, err := w.eval(&children[0], value)
+		if err != nil {
+			return nil, w.intr.leftError(err)
+		}
+		if left, err = w.intr.resolveNested(left, flattenDepth(*node)); err != nil {
+			return nil, err
+		}
+		if w.intr.strict {
+			if err := checkArray(*node, left); err != nil {
+				return nil, err
+			}
+		}
+		for i := 0; i < flattenDepth(*node) && left != nil; i++ {
+			left = w.intr.flatten(left)
+		}
+		return left, nil
+	case ASTProjection, ASTFilterProjection, ASTValueProjection:
+		return w.project(node, value)
+	case ASTDescendant:
+		left, err := w.eval(&children[0], value)
+		if err != nil {
+			return nil, err
+		}
+		if left, err = w.intr.resolveAll(left); err != nil {
+			return nil, err
+		}
+		return w.intr.descendants(node.value.(string), left), nil
+	}
+	return w.intr.Execute(*node, value)
+}
+
+func (w *walker) project(node *ASTNode, value interface{}) (interface{}, error) {
+	children := node.children
+	left, err := w.eval(&children[0], value)
+	if err != nil {
+		if node.nodeType == ASTProjection {
+			return nil, err
+		}
+		return nil, w.intr.leftError(err)
+	}
+	if s, ok := slicedString(*node, left); node.nodeType == ASTProjection && ok {
+		return w.eval(&children[1], s)
+	}
+	if left, err = w.intr.resolve(left); err != nil {
+		return nil, err
+	}
+	var elements []interface{}
+	// keys are the keys of the elements of an object projection.
+	var keys []string
+	if node.nodeType == ASTValueProjection {
+		object, ok := w.intr.toObject(left)
+		if !ok {
+			if w.intr.strict {
+				return nil, newStrictError(*node, ErrNotObject, left)
+			}
+			return nil, nil
+		}
+		keys = w.intr.iterationKeys(left, object)
+		for _, key := range keys {
+			elements = append(elements, object[key])
+		}
+	} else if w.intr.strict && !isSliceType(left) {
+		return nil, newStrictError(*node, ErrNotArray, left)
+	} else if sliceType, ok := left.([]interface{}); ok {
+		elements = sliceType
+	} else if isSliceType(left) {
+		elements = toInterfaceSlice(left)
+	} else {
+		return nil, nil
+	}
+	capture := keys != nil && callsKey(children[1])
+	collected := []interface{}{}
+	for i, element := range elements {
+		if w.element != nil {
+			if element, err = w.element(element); err != nil {
+				return nil, err
+			}
+		}
+		if node.nodeType == ASTFilterProjection {
+			matched, err := w.eval(&children[2], element)
+			if err != nil {
+				return nil, err
+			}
+			if isFalse(matched) {
+				continue
+			}
+		}
+		each := w
+		if capture {
+			keyed := *w
+			keyed.intr = w.intr.withKey(keys[i])
+			each = &keyed
+		}
+		current, err := each.eval(&children[1], element)
+		if err != nil {
+			return nil, err
+		}
+		if w.intr.keeps(current) {
+			collected = append(collected, current)
+		}
+	}
+	return collected, nil
+}